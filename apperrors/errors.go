@@ -0,0 +1,168 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type Kind string
+
+var Kinds = &struct {
+	Validation          Kind
+	Unauthorized        Kind
+	Forbidden           Kind
+	NotFound            Kind
+	Conflict            Kind
+	Unprocessable       Kind
+	InsufficientFunds   Kind
+	LimitExceeded       Kind
+	ProviderUnavailable Kind
+	Internal            Kind
+}{
+	Validation:          "VALIDATION",
+	Unauthorized:        "UNAUTHORIZED",
+	Forbidden:           "FORBIDDEN",
+	NotFound:            "NOT_FOUND",
+	Conflict:            "CONFLICT",
+	Unprocessable:       "UNPROCESSABLE",
+	InsufficientFunds:   "INSUFFICIENT_FUNDS",
+	LimitExceeded:       "LIMIT_EXCEEDED",
+	ProviderUnavailable: "PROVIDER_UNAVAILABLE",
+	Internal:            "INTERNAL",
+}
+
+var kindStatuses = map[Kind]int{
+	Kinds.Validation:          http.StatusBadRequest,
+	Kinds.Unauthorized:        http.StatusUnauthorized,
+	Kinds.Forbidden:           http.StatusForbidden,
+	Kinds.NotFound:            http.StatusNotFound,
+	Kinds.Conflict:            http.StatusConflict,
+	Kinds.Unprocessable:       http.StatusUnprocessableEntity,
+	Kinds.InsufficientFunds:   http.StatusUnprocessableEntity,
+	Kinds.LimitExceeded:       http.StatusUnprocessableEntity,
+	Kinds.ProviderUnavailable: http.StatusBadGateway,
+	Kinds.Internal:            http.StatusInternalServerError,
+}
+
+// Status is the HTTP status code a failure of this kind is reported with.
+func (k Kind) Status() int {
+	if status, ok := kindStatuses[k]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is a failure the client can act on. Code is a stable, machine-readable
+// identifier such as "insufficient_funds"; Message is meant for humans and may
+// change between releases.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func New(kind Kind, code string, format string, args ...any) error {
+	return &Error{Kind: kind, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func Validation(code string, format string, args ...any) error {
+	return New(Kinds.Validation, code, format, args...)
+}
+
+func Unauthorized(code string, format string, args ...any) error {
+	return New(Kinds.Unauthorized, code, format, args...)
+}
+
+func Forbidden(code string, format string, args ...any) error {
+	return New(Kinds.Forbidden, code, format, args...)
+}
+
+func NotFound(code string, format string, args ...any) error {
+	return New(Kinds.NotFound, code, format, args...)
+}
+
+func Conflict(code string, format string, args ...any) error {
+	return New(Kinds.Conflict, code, format, args...)
+}
+
+func Unprocessable(code string, format string, args ...any) error {
+	return New(Kinds.Unprocessable, code, format, args...)
+}
+
+func InsufficientFunds(format string, args ...any) error {
+	return New(Kinds.InsufficientFunds, "insufficient_funds", format, args...)
+}
+
+func LimitExceeded(code string, format string, args ...any) error {
+	return New(Kinds.LimitExceeded, code, format, args...)
+}
+
+// ProviderUnavailable reports that an external provider failed. The cause is
+// kept for logging but not shown to the client.
+func ProviderUnavailable(provider string, err error) error {
+	return &Error{
+		Kind:    Kinds.ProviderUnavailable,
+		Code:    "provider_unavailable",
+		Message: fmt.Sprintf("%s is currently unavailable", provider),
+		Err:     err,
+	}
+}
+
+// afterCommitError marks a failure that happened after the request changed
+// state.
+type afterCommitError struct {
+	error
+}
+
+func (e afterCommitError) Unwrap() error {
+	return e.error
+}
+
+// AfterCommit marks err as a failure that happened after the request committed
+// a change, such as a provider failing once the transaction was created. Such
+// a request cannot simply be retried, so its response is kept for replay.
+func AfterCommit(err error) error {
+	return afterCommitError{err}
+}
+
+// IsAfterCommit reports whether err was marked by AfterCommit.
+func IsAfterCommit(err error) bool {
+	var marked afterCommitError
+	return errors.As(err, &marked)
+}
+
+// As returns the domain error in err's chain, or nil if err is unexpected.
+func As(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return nil
+}
+
+// KindOf returns the kind of the domain error in err's chain. Errors that are
+// not domain errors are Internal.
+func KindOf(err error) Kind {
+	if appErr := As(err); appErr != nil {
+		return appErr.Kind
+	}
+	return Kinds.Internal
+}
+
+// CodeOf returns the machine-readable code of the domain error in err's chain.
+func CodeOf(err error) string {
+	if appErr := As(err); appErr != nil {
+		return appErr.Code
+	}
+	return "internal_error"
+}
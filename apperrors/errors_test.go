@@ -0,0 +1,68 @@
+package apperrors_test
+
+import (
+	"banking-system/apperrors"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKindOf_WrappedDomainError(t *testing.T) {
+	err := fmt.Errorf("withdraw: %w", apperrors.InsufficientFunds("balance %s is too low", "10.00"))
+
+	assert.Equal(t, apperrors.Kinds.InsufficientFunds, apperrors.KindOf(err))
+	assert.Equal(t, "insufficient_funds", apperrors.CodeOf(err))
+}
+
+func TestKindOf_UnexpectedError(t *testing.T) {
+	err := errors.New("connection reset")
+
+	assert.Equal(t, apperrors.Kinds.Internal, apperrors.KindOf(err))
+	assert.Equal(t, "internal_error", apperrors.CodeOf(err))
+	assert.Nil(t, apperrors.As(err))
+}
+
+func TestKind_Status(t *testing.T) {
+	tests := []struct {
+		kind   apperrors.Kind
+		status int
+	}{
+		{apperrors.Kinds.Validation, http.StatusBadRequest},
+		{apperrors.Kinds.Unauthorized, http.StatusUnauthorized},
+		{apperrors.Kinds.Forbidden, http.StatusForbidden},
+		{apperrors.Kinds.NotFound, http.StatusNotFound},
+		{apperrors.Kinds.Conflict, http.StatusConflict},
+		{apperrors.Kinds.Unprocessable, http.StatusUnprocessableEntity},
+		{apperrors.Kinds.InsufficientFunds, http.StatusUnprocessableEntity},
+		{apperrors.Kinds.LimitExceeded, http.StatusUnprocessableEntity},
+		{apperrors.Kinds.ProviderUnavailable, http.StatusBadGateway},
+		{apperrors.Kinds.Internal, http.StatusInternalServerError},
+		{apperrors.Kind("UNKNOWN"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			assert.Equal(t, tt.status, tt.kind.Status())
+		})
+	}
+}
+
+func TestProviderUnavailable_HidesCause(t *testing.T) {
+	cause := errors.New("dial tcp: connection refused")
+	err := apperrors.ProviderUnavailable("payment provider 'AnyPay'", cause)
+
+	assert.NotContains(t, err.Error(), "connection refused")
+	assert.ErrorIs(t, err, cause)
+}
+
+func TestAfterCommit_KeepsDomainError(t *testing.T) {
+	err := apperrors.AfterCommit(apperrors.ProviderUnavailable("payment provider 'AnyPay'", errors.New("connection reset")))
+
+	assert.True(t, apperrors.IsAfterCommit(fmt.Errorf("deposit: %w", err)))
+	assert.Equal(t, apperrors.Kinds.ProviderUnavailable, apperrors.KindOf(err))
+	assert.Equal(t, "provider_unavailable", apperrors.CodeOf(err))
+	assert.False(t, apperrors.IsAfterCommit(apperrors.ProviderUnavailable("FX rate source", nil)))
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	userIDKey    = "auth_user_id"
+	sessionIDKey = "auth_session_id"
+)
+
+// SetUserID records the authenticated user for the rest of the request.
+func SetUserID(c *gin.Context, userID uint) {
+	c.Set(userIDKey, userID)
+}
+
+// UserID returns the authenticated user of the request, if any.
+func UserID(c *gin.Context) (uint, bool) {
+	value, ok := c.Get(userIDKey)
+	if !ok {
+		return 0, false
+	}
+
+	userID, ok := value.(uint)
+	return userID, ok
+}
+
+// SetSessionID records the login session the request was authenticated with.
+func SetSessionID(c *gin.Context, sessionID uuid.UUID) {
+	c.Set(sessionIDKey, sessionID)
+}
+
+// SessionID returns the login session of the request. Requests accepted from a
+// trusted gateway have none.
+func SessionID(c *gin.Context) (uuid.UUID, bool) {
+	value, ok := c.Get(sessionIDKey)
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	sessionID, ok := value.(uuid.UUID)
+	return sessionID, ok
+}
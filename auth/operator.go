@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const operatorIDKey = "auth_operator_id"
+
+// OperatorKeys holds the API key of each back-office operator, by operator ID.
+type OperatorKeys map[string][]byte
+
+// LoadOperatorKeys reads OPERATOR_API_KEYS, a comma-separated list of
+// operator=key pairs such as "alice=k3y,bob=0th3r".
+func LoadOperatorKeys() OperatorKeys {
+	keys := OperatorKeys{}
+
+	value := os.Getenv("OPERATOR_API_KEYS")
+	if value == "" {
+		return keys
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		operatorID, key, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || operatorID == "" || key == "" {
+			log.Panicf("Invalid OPERATOR_API_KEYS entry '%s': expected operator=key", pair)
+		}
+		keys[operatorID] = []byte(key)
+	}
+
+	return keys
+}
+
+// Authenticate returns the operator whose key matches, comparing against
+// every key in constant time.
+func (keys OperatorKeys) Authenticate(key string) (string, bool) {
+	var found string
+	for operatorID, expected := range keys {
+		if subtle.ConstantTimeCompare(expected, []byte(key)) == 1 {
+			found = operatorID
+		}
+	}
+	return found, found != ""
+}
+
+// SetOperatorID records the authenticated operator for the rest of the
+// request.
+func SetOperatorID(c *gin.Context, operatorID string) {
+	c.Set(operatorIDKey, operatorID)
+}
+
+// OperatorID returns the authenticated operator of the request, if any.
+func OperatorID(c *gin.Context) (string, bool) {
+	value, ok := c.Get(operatorIDKey)
+	if !ok {
+		return "", false
+	}
+
+	operatorID, ok := value.(string)
+	return operatorID, ok
+}
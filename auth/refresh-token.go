@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// NewRefreshToken creates an opaque refresh token for a session. The token
+// names its session so that a stale, rotated token can be traced back to it;
+// only the hash of the secret part is ever stored.
+func NewRefreshToken(sessionID uuid.UUID) (token string, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+	return sessionID.String() + "." + encoded, HashRefreshSecret(encoded), nil
+}
+
+// ParseRefreshToken splits a refresh token into its session and the hash of
+// its secret.
+func ParseRefreshToken(token string) (sessionID uuid.UUID, hash string, err error) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok || secret == "" {
+		return uuid.Nil, "", ErrInvalidRefreshToken
+	}
+
+	sessionID, err = uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, "", ErrInvalidRefreshToken
+	}
+
+	return sessionID, HashRefreshSecret(secret), nil
+}
+
+func HashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,37 @@
+package auth_test
+
+import (
+	"banking-system/auth"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshToken_RoundTrip(t *testing.T) {
+	sessionID := uuid.New()
+	token, hash, err := auth.NewRefreshToken(sessionID)
+	assert.Nil(t, err)
+
+	parsedSessionID, parsedHash, err := auth.ParseRefreshToken(token)
+
+	assert.Nil(t, err)
+	assert.Equal(t, sessionID, parsedSessionID)
+	assert.Equal(t, hash, parsedHash)
+	assert.NotContains(t, token, hash, "Only the hash may be stored, never the token")
+}
+
+func TestRefreshToken_IsRandom(t *testing.T) {
+	sessionID := uuid.New()
+	first, _, _ := auth.NewRefreshToken(sessionID)
+	second, _, _ := auth.NewRefreshToken(sessionID)
+
+	assert.NotEqual(t, first, second)
+}
+
+func TestParseRefreshToken_Malformed(t *testing.T) {
+	for _, token := range []string{"", "no-dot", "not-a-uuid.secret", uuid.NewString() + "."} {
+		_, _, err := auth.ParseRefreshToken(token)
+		assert.ErrorIs(t, err, auth.ErrInvalidRefreshToken, token)
+	}
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	DEFAULT_ISSUER    = "banking-system"
+	DEFAULT_AUDIENCE  = "banking-system-api"
+	ACCESS_TOKEN_TTL  = 15 * time.Minute
+	REFRESH_TOKEN_TTL = 30 * 24 * time.Hour
+	AUTH_COOKIE_NAME  = "authorization"
+	REFRESH_COOKIE    = "refresh_token"
+	trustedGatewayEnv = "AUTH_TRUSTED_GATEWAY"
+)
+
+var (
+	ErrInvalidToken  = errors.New("invalid or expired token")
+	ErrMissingSecret = errors.New("USER_TOKEN_SECRET_KEY environment variable not set")
+)
+
+// Config describes how access tokens are signed and verified. It is read from
+// the environment so that tests and deployments can change it without code.
+type Config struct {
+	Secret   []byte
+	Issuer   string
+	Audience string
+
+	// TrustedGateway makes the API trust the X-User-ID header set by an
+	// upstream gateway instead of verifying tokens itself. It must only be
+	// enabled when the service is unreachable except through that gateway.
+	TrustedGateway bool
+}
+
+func LoadConfig() Config {
+	cfg := Config{
+		Secret:         []byte(os.Getenv("USER_TOKEN_SECRET_KEY")),
+		Issuer:         os.Getenv("JWT_ISSUER"),
+		Audience:       os.Getenv("JWT_AUDIENCE"),
+		TrustedGateway: os.Getenv(trustedGatewayEnv) == "y",
+	}
+
+	if cfg.Issuer == "" {
+		cfg.Issuer = DEFAULT_ISSUER
+	}
+	if cfg.Audience == "" {
+		cfg.Audience = DEFAULT_AUDIENCE
+	}
+
+	return cfg
+}
+
+// AccessClaims identify the user and the login session an access token was
+// issued for.
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	SessionID uuid.UUID `json:"sid"`
+}
+
+// IssueAccessToken signs a short-lived HS256 token for a user's session.
+func (cfg Config) IssueAccessToken(userID uint, sessionID uuid.UUID) (string, error) {
+	if len(cfg.Secret) == 0 {
+		return "", ErrMissingSecret
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			Issuer:    cfg.Issuer,
+			Audience:  jwt.ClaimStrings{cfg.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ACCESS_TOKEN_TTL)),
+		},
+		SessionID: sessionID,
+	})
+
+	tokenString, err := token.SignedString(cfg.Secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return tokenString, nil
+}
+
+// ParseAccessToken verifies the signature, expiry, issuer and audience of a
+// token and returns the user and session it was issued to.
+func (cfg Config) ParseAccessToken(tokenString string) (userID uint, sessionID uuid.UUID, err error) {
+	if len(cfg.Secret) == 0 {
+		return 0, uuid.Nil, ErrMissingSecret
+	}
+
+	var claims AccessClaims
+	_, err = jwt.ParseWithClaims(tokenString, &claims, func(*jwt.Token) (any, error) {
+		return cfg.Secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithAudience(cfg.Audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return 0, uuid.Nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	subject, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || subject == 0 {
+		return 0, uuid.Nil, fmt.Errorf("%w: invalid subject", ErrInvalidToken)
+	}
+
+	if claims.SessionID == uuid.Nil {
+		return 0, uuid.Nil, fmt.Errorf("%w: missing session", ErrInvalidToken)
+	}
+
+	return uint(subject), claims.SessionID, nil
+}
@@ -0,0 +1,131 @@
+package auth_test
+
+import (
+	"banking-system/auth"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var sessionID = uuid.New()
+
+var cfg = auth.Config{
+	Secret:   []byte("test-secret"),
+	Issuer:   auth.DEFAULT_ISSUER,
+	Audience: auth.DEFAULT_AUDIENCE,
+}
+
+func TestAccessToken_RoundTrip(t *testing.T) {
+	token, err := cfg.IssueAccessToken(42, sessionID)
+	assert.Nil(t, err)
+
+	userID, parsedSessionID, err := cfg.ParseAccessToken(token)
+
+	assert.Nil(t, err)
+	assert.Equal(t, uint(42), userID)
+	assert.Equal(t, sessionID, parsedSessionID)
+}
+
+func TestParseAccessToken_WrongSecret(t *testing.T) {
+	other := cfg
+	other.Secret = []byte("another-secret")
+	token, _ := other.IssueAccessToken(42, sessionID)
+
+	_, _, err := cfg.ParseAccessToken(token)
+
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestParseAccessToken_WrongIssuer(t *testing.T) {
+	other := cfg
+	other.Issuer = "someone-else"
+	token, _ := other.IssueAccessToken(42, sessionID)
+
+	_, _, err := cfg.ParseAccessToken(token)
+
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestParseAccessToken_WrongAudience(t *testing.T) {
+	other := cfg
+	other.Audience = "another-api"
+	token, _ := other.IssueAccessToken(42, sessionID)
+
+	_, _, err := cfg.ParseAccessToken(token)
+
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestParseAccessToken_Expired(t *testing.T) {
+	token := signClaims(auth.AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "42",
+			Issuer:    cfg.Issuer,
+			Audience:  jwt.ClaimStrings{cfg.Audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		},
+		SessionID: sessionID,
+	}, jwt.SigningMethodHS256)
+
+	_, _, err := cfg.ParseAccessToken(token)
+
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestParseAccessToken_MissingExpiry(t *testing.T) {
+	token := signClaims(auth.AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:  "42",
+			Issuer:   cfg.Issuer,
+			Audience: jwt.ClaimStrings{cfg.Audience},
+		},
+		SessionID: sessionID,
+	}, jwt.SigningMethodHS256)
+
+	_, _, err := cfg.ParseAccessToken(token)
+
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestParseAccessToken_RejectsOtherAlgorithms(t *testing.T) {
+	token := signClaims(auth.AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "42",
+			Issuer:    cfg.Issuer,
+			Audience:  jwt.ClaimStrings{cfg.Audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+		SessionID: sessionID,
+	}, jwt.SigningMethodHS512)
+
+	_, _, err := cfg.ParseAccessToken(token)
+
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestParseAccessToken_MissingSession(t *testing.T) {
+	token := signClaims(jwt.RegisteredClaims{
+		Subject:   "42",
+		Issuer:    cfg.Issuer,
+		Audience:  jwt.ClaimStrings{cfg.Audience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}, jwt.SigningMethodHS256)
+
+	_, _, err := cfg.ParseAccessToken(token)
+
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestIssueAccessToken_MissingSecret(t *testing.T) {
+	_, err := auth.Config{}.IssueAccessToken(42, sessionID)
+
+	assert.ErrorIs(t, err, auth.ErrMissingSecret)
+}
+
+func signClaims(claims jwt.Claims, method jwt.SigningMethod) string {
+	token, _ := jwt.NewWithClaims(method, claims).SignedString(cfg.Secret)
+	return token
+}
@@ -0,0 +1,51 @@
+package blobs_test
+
+import (
+	"banking-system/blobs"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore_PutAndGet(t *testing.T) {
+	store, err := blobs.NewLocalStore(t.TempDir())
+	assert.Nil(t, err)
+
+	assert.Nil(t, store.Put("statements/7/2026-09.pdf", strings.NewReader("statement")))
+
+	r, err := store.Get("statements/7/2026-09.pdf")
+	assert.Nil(t, err)
+	defer r.Close()
+
+	data, _ := io.ReadAll(r)
+	assert.Equal(t, "statement", string(data))
+}
+
+func TestLocalStore_NeverReplacesABlob(t *testing.T) {
+	root := t.TempDir()
+	store, _ := blobs.NewLocalStore(root)
+
+	assert.Nil(t, store.Put("a.pdf", strings.NewReader("first")))
+	assert.ErrorIs(t, store.Put("a.pdf", strings.NewReader("second")), blobs.ErrExists)
+
+	data, _ := os.ReadFile(filepath.Join(root, "a.pdf"))
+	assert.Equal(t, "first", string(data))
+
+	entries, _ := os.ReadDir(root)
+	assert.Len(t, entries, 1, "no temporary files are left behind")
+}
+
+func TestLocalStore_InvalidKeys(t *testing.T) {
+	store, _ := blobs.NewLocalStore(t.TempDir())
+
+	for _, key := range []string{"", "../outside.pdf", "/etc/passwd", "a/../../b"} {
+		assert.ErrorIs(t, store.Put(key, strings.NewReader("x")), blobs.ErrInvalidKey, key)
+	}
+
+	_, err := store.Get("missing.pdf")
+	assert.ErrorIs(t, err, blobs.ErrNotFound)
+}
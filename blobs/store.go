@@ -0,0 +1,49 @@
+// Package blobs stores documents, such as issued statements, that must not
+// change once written.
+package blobs
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//go:generate mockgen -source=store.go -destination=mock/store.go
+
+// Store keeps blobs by key. A key is written once; blobs are never replaced.
+// Keys are slash-separated paths such as "statements/7/2026-09.pdf".
+type Store interface {
+	Put(key string, data io.Reader) error
+	Get(key string) (io.ReadCloser, error)
+}
+
+var (
+	// ErrExists is returned when writing a key that was already written.
+	ErrExists = errors.New("blob already exists")
+
+	// ErrNotFound is returned when reading a key that was never written.
+	ErrNotFound = errors.New("blob not found")
+
+	// ErrInvalidKey is returned for keys that are empty or leave the store.
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// DEFAULT_BLOB_DIR is where blobs are kept when BLOB_STORE_DIR is not set.
+const DEFAULT_BLOB_DIR = "data/blobs"
+
+// NewStore returns a store on the local filesystem in the directory
+// BLOB_STORE_DIR.
+func NewStore() Store {
+	dir := os.Getenv("BLOB_STORE_DIR")
+	if dir == "" {
+		dir = DEFAULT_BLOB_DIR
+	}
+
+	store, err := NewLocalStore(dir)
+	if err != nil {
+		log.Panicf("Failed to open blob store in '%s': %v", dir, err)
+	}
+	return store
+}
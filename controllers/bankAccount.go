@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"banking-system/apperrors"
 	"banking-system/entities"
 	"banking-system/models"
 	"banking-system/services"
@@ -34,24 +35,25 @@ func NewBankAccountController(bankAccountSrv services.BankAccountService) BankAc
 // @Description  Creates a new bank account for the authenticated user
 // @Tags         bank-accounts
 // @Accept       json
-// @Param        X-User-ID header string true "User ID"
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
 // @Param        request body models.CreateBankAccountRequest true "Bank account creation details"
 // @Success      201  {object}  models.BankAccountResponse  "Bank account created successfully"
-// @Response     400  {object}  object  "Bad request - validation error"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - validation error"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
 // @Router       /bank-accounts [post]
 func (ctrl *bankAccountController) Create(c *gin.Context) {
 	var req models.CreateBankAccountRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid request body or missing field: " + err.Error(),
-		})
+		c.Error(invalidRequestBody(err))
 		return
 	}
 
-	userID, err := getUserIDFromHeader(c)
+	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"message": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -59,9 +61,7 @@ func (ctrl *bankAccountController) Create(c *gin.Context) {
 
 	response, err := ctrl.bankAccountSrv.Create(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -72,39 +72,32 @@ func (ctrl *bankAccountController) Create(c *gin.Context) {
 // @Description  Retrieves a specific bank account by ID for the authenticated user
 // @Tags         bank-accounts
 // @Accept       json
-// @Param        X-User-ID header string true "User ID"
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
 // @Param        id path int true "Bank Account ID"
 // @Success      200  {object}  models.BankAccountResponse  "Bank account retrieved successfully"
-// @Response     400  {object}  object  "Bad request"
-// @Response     404  {object}  object  "Bank account not found"
+// @Response     400  {object}  models.ProblemResponse  "Bad request"
+// @Response     404  {object}  models.ProblemResponse  "Bank account not found"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
 // @Router       /bank-accounts/{id} [get]
 func (ctrl *bankAccountController) GetByID(c *gin.Context) {
-	userID, err := getUserIDFromHeader(c)
+	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"message": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid bank account ID",
-		})
+		c.Error(apperrors.Validation("invalid_bank_account_id", "Invalid bank account ID"))
 		return
 	}
 
 	response, err := ctrl.bankAccountSrv.GetByID(uint(id), userID)
 	if err != nil {
-		if err.Error() == "bank account not found" {
-			c.JSON(http.StatusNotFound, gin.H{
-				"message": err.Error(),
-			})
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"message": err.Error(),
-			})
-		}
+		c.Error(err)
 		return
 	}
 
@@ -115,21 +108,22 @@ func (ctrl *bankAccountController) GetByID(c *gin.Context) {
 // @Description  Retrieves all bank accounts for the authenticated user
 // @Tags         bank-accounts
 // @Accept       json
-// @Param        X-User-ID header string true "User ID"
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
 // @Success      200  {array}  models.BankAccountResponse  "Bank accounts retrieved successfully"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
 // @Router       /bank-accounts [get]
 func (ctrl *bankAccountController) GetAll(c *gin.Context) {
-	userID, err := getUserIDFromHeader(c)
+	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"message": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	responses, err := ctrl.bankAccountSrv.GetByUserID(userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -140,17 +134,31 @@ func (ctrl *bankAccountController) GetAll(c *gin.Context) {
 // @Description  Retrieves all bank accounts for a specific user by user ID
 // @Tags         bank-accounts
 // @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
 // @Param        userId path int true "User ID"
 // @Success      200  {array}  models.BankAccountResponse  "Bank accounts retrieved successfully"
-// @Response     400  {object}  object  "Bad request"
+// @Response     400  {object}  models.ProblemResponse  "Bad request"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     403  {object}  models.ProblemResponse  "Forbidden - not the authenticated user"
 // @Router       /bank-accounts/user/{userId} [get]
 func (ctrl *bankAccountController) GetByUserID(c *gin.Context) {
 	userIDStr := c.Param("userId")
 	userID, err := strconv.Atoi(userIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid user ID",
-		})
+		c.Error(apperrors.Validation("invalid_user_id", "Invalid user ID"))
+		return
+	}
+
+	callerID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if uint(userID) != callerID {
+		c.Error(apperrors.Forbidden("bank_account_forbidden", "cannot view another user's bank accounts"))
 		return
 	}
 
@@ -166,48 +174,39 @@ func (ctrl *bankAccountController) GetByUserID(c *gin.Context) {
 // @Description  Updates a specific bank account by ID for the authenticated user
 // @Tags         bank-accounts
 // @Accept       json
-// @Param        X-User-ID header string true "User ID"
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
 // @Param        id path int true "Bank Account ID"
 // @Param        request body models.UpdateBankAccountRequest true "Bank account update details"
 // @Success      200  {object}  models.BankAccountResponse  "Bank account updated successfully"
-// @Response     400  {object}  object  "Bad request"
-// @Response     404  {object}  object  "Bank account not found"
+// @Response     400  {object}  models.ProblemResponse  "Bad request"
+// @Response     404  {object}  models.ProblemResponse  "Bank account not found"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
 // @Router       /bank-accounts/{id} [put]
 func (ctrl *bankAccountController) Update(c *gin.Context) {
-	userID, err := getUserIDFromHeader(c)
+	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"message": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid bank account ID",
-		})
+		c.Error(apperrors.Validation("invalid_bank_account_id", "Invalid bank account ID"))
 		return
 	}
 
 	var req models.UpdateBankAccountRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid request body or missing field: " + err.Error(),
-		})
+		c.Error(invalidRequestBody(err))
 		return
 	}
 
 	response, err := ctrl.bankAccountSrv.Update(uint(id), userID, &req)
 	if err != nil {
-		if err.Error() == "bank account not found" {
-			c.JSON(http.StatusNotFound, gin.H{
-				"message": err.Error(),
-			})
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"message": err.Error(),
-			})
-		}
+		c.Error(err)
 		return
 	}
 
@@ -218,39 +217,32 @@ func (ctrl *bankAccountController) Update(c *gin.Context) {
 // @Description  Deletes a specific bank account by ID for the authenticated user
 // @Tags         bank-accounts
 // @Accept       json
-// @Param        X-User-ID header string true "User ID"
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
 // @Param        id path int true "Bank Account ID"
 // @Success      200  {object}  nil  "Bank account deleted successfully"
-// @Response     400  {object}  object  "Bad request"
-// @Response     404  {object}  object  "Bank account not found"
+// @Response     400  {object}  models.ProblemResponse  "Bad request"
+// @Response     404  {object}  models.ProblemResponse  "Bank account not found"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
 // @Router       /bank-accounts/{id} [delete]
 func (ctrl *bankAccountController) Delete(c *gin.Context) {
-	userID, err := getUserIDFromHeader(c)
+	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"message": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid bank account ID",
-		})
+		c.Error(apperrors.Validation("invalid_bank_account_id", "Invalid bank account ID"))
 		return
 	}
 
 	err = ctrl.bankAccountSrv.Delete(uint(id), userID)
 	if err != nil {
-		if err.Error() == "bank account not found" {
-			c.JSON(http.StatusNotFound, gin.H{
-				"message": err.Error(),
-			})
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"message": err.Error(),
-			})
-		}
+		c.Error(err)
 		return
 	}
 
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"banking-system/models"
+	"banking-system/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FeeController interface {
+	Quote(c *gin.Context)
+}
+
+type feeController struct {
+	feeSrv services.FeeService
+}
+
+func NewFeeController(feeSrv services.FeeService) FeeController {
+	return &feeController{
+		feeSrv: feeSrv,
+	}
+}
+
+// @Summary      Preview a fee
+// @Description  Calculates the fee the user would be charged for a deposit, withdrawal or transfer of the given amount
+// @Tags         fees
+// @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        request body models.FeeQuoteRequest true "Operation, amount and payment method"
+// @Success      200  {object}  models.FeeQuoteResponse  "Fee calculated"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - validation error or unsupported currency"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Router       /fees/quotes [post]
+func (ctrl *feeController) Quote(c *gin.Context) {
+	var req models.FeeQuoteRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequestBody(err))
+		return
+	}
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	req.UserID = userID
+
+	quote, err := ctrl.feeSrv.Quote(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"banking-system/models"
+	"banking-system/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FXController interface {
+	Quote(c *gin.Context)
+	Convert(c *gin.Context)
+}
+
+type fxController struct {
+	fxSrv services.FXService
+}
+
+func NewFXController(fxSrv services.FXService) FXController {
+	return &fxController{
+		fxSrv: fxSrv,
+	}
+}
+
+// @Summary      Request an FX quote
+// @Description  Locks a conversion rate between two of the user's wallets for a limited time
+// @Tags         fx
+// @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        request body models.FXQuoteRequest true "Currencies and amount to convert"
+// @Success      201  {object}  models.FXQuoteResponse  "Quote issued"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - validation error or unknown currency pair"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Router       /fx/quotes [post]
+func (ctrl *fxController) Quote(c *gin.Context) {
+	var req models.FXQuoteRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequestBody(err))
+		return
+	}
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	req.UserID = userID
+
+	quote, err := ctrl.fxSrv.Quote(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, quote)
+}
+
+// @Summary      Convert between the user's own wallets
+// @Description  Executes a locked FX quote atomically, debiting one wallet and crediting the other with linked transactions
+// @Tags         payments
+// @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        Idempotency-Key header string false "Replays the original response when the same key is sent again"
+// @Param        request body models.ConvertRequest true "Quote to execute"
+// @Response     200  {object}  nil  "Conversion completed successfully"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - validation error"
+// @Response     409  {object}  models.ProblemResponse  "Conflict - duplicate transaction, quote no longer available or Idempotency-Key in progress"
+// @Response     422  {object}  models.ProblemResponse  "Insufficient funds or Idempotency-Key reused with a different request body"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Router       /payments/convert [post]
+func (ctrl *fxController) Convert(c *gin.Context) {
+	var req models.ConvertRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequestBody(err))
+		return
+	}
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	req.UserID = userID
+
+	if err := ctrl.fxSrv.Convert(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
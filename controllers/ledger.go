@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"banking-system/apperrors"
+	"banking-system/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LedgerController interface {
+	Reconcile(c *gin.Context)
+	GetEntriesByWalletID(c *gin.Context)
+}
+
+type ledgerController struct {
+	ledgerSrv services.LedgerService
+}
+
+func NewLedgerController(ledgerSrv services.LedgerService) LedgerController {
+	return &ledgerController{
+		ledgerSrv: ledgerSrv,
+	}
+}
+
+// @Summary      Reconcile the general ledger
+// @Description  Verifies that every ledger account and wallet balance matches the journal postings and that the ledger sums to zero
+// @Tags         ledger
+// @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        X-Operator-Key header string true "Operator API key"
+// @Success      200  {object}  models.LedgerReconciliationResponse  "Reconciliation report"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token or operator key"
+// @Router       /ledger/reconciliation [get]
+func (ctrl *ledgerController) Reconcile(c *gin.Context) {
+	report, err := ctrl.ledgerSrv.Reconcile()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary      Get journal entries of a wallet
+// @Description  Retrieves every journal entry that changed the balance of a wallet, oldest first
+// @Tags         ledger
+// @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        X-Operator-Key header string true "Operator API key"
+// @Param        wallet_id path int true "Wallet ID"
+// @Success      200  {array}  models.JournalEntryResponse  "List of journal entries"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid wallet ID"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token or operator key"
+// @Router       /ledger/wallets/{wallet_id}/entries [get]
+func (ctrl *ledgerController) GetEntriesByWalletID(c *gin.Context) {
+	walletID, err := strconv.ParseUint(c.Param("wallet_id"), 10, 64)
+	if err != nil {
+		c.Error(apperrors.Validation("invalid_wallet_id", "Invalid wallet ID"))
+		return
+	}
+
+	entries, err := ctrl.ledgerSrv.GetEntriesByWalletID(uint(walletID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
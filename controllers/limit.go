@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"banking-system/apperrors"
+	"banking-system/auth"
+	"banking-system/models"
+	"banking-system/services"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LimitController interface {
+	GetLimits(c *gin.Context)
+	SetOverride(c *gin.Context)
+}
+
+type limitController struct {
+	limitSrv services.LimitService
+}
+
+func NewLimitController(limitSrv services.LimitService) LimitController {
+	return &limitController{
+		limitSrv: limitSrv,
+	}
+}
+
+// @Summary      Get transaction limits
+// @Description  Returns the user's per-transaction, daily and monthly limits for deposits, withdrawals and transfers in each wallet currency, with the allowance left today and this month. Periods reset at midnight UTC.
+// @Tags         limits
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Success      200  {array}   models.LimitResponse  "Limits and remaining allowance"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Router       /limits [get]
+func (ctrl *limitController) GetLimits(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res, err := ctrl.limitSrv.GetByUserID(userID)
+	if err != nil {
+		c.Error(fmt.Errorf("failed to get limits for user %d: %w", userID, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary      Override a user's limits
+// @Description  Operator-only. Replaces the given limits of one user for one transaction type and currency; limits left out keep those of the user's tier. A request without limits removes the override.
+// @Tags         limits
+// @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Param        X-Operator-Key header string true "Operator API key"
+// @Param        user_id path int true "User ID"
+// @Param        request body models.LimitOverrideRequest true "Limits to override"
+// @Response     204  {object}  nil  "Override saved"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - validation error"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid operator key"
+// @Response     404  {object}  models.ProblemResponse  "User not found"
+// @Router       /limits/users/{user_id} [put]
+func (ctrl *limitController) SetOverride(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.Error(apperrors.Validation("invalid_user_id", "Invalid user ID"))
+		return
+	}
+
+	var req models.LimitOverrideRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequestBody(err))
+		return
+	}
+
+	operatorID, ok := auth.OperatorID(c)
+	if !ok {
+		c.Error(apperrors.Unauthorized("missing_operator_key", "operator authentication required"))
+		return
+	}
+
+	req.UserID = uint(userID)
+	req.OperatorID = operatorID
+
+	if err := ctrl.limitSrv.SetOverride(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
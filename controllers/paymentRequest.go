@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"banking-system/apperrors"
+	"banking-system/models"
+	"banking-system/services"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentRequestController interface {
+	Create(c *gin.Context)
+	GetIncoming(c *gin.Context)
+	GetOutgoing(c *gin.Context)
+	Accept(c *gin.Context)
+	Decline(c *gin.Context)
+}
+
+type paymentRequestController struct {
+	paymentRequestSrv services.PaymentRequestService
+}
+
+func NewPaymentRequestController(paymentRequestSrv services.PaymentRequestService) PaymentRequestController {
+	return &paymentRequestController{
+		paymentRequestSrv: paymentRequestSrv,
+	}
+}
+
+// @Summary      Request a payment
+// @Description  Asks another user to pay the authenticated user an amount, with an optional memo. The request expires at expires_at, at most 30 days ahead, or after 7 days if it is left out. The payer accepts it with a transfer or declines it.
+// @Tags         payment-requests
+// @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        request body models.CreatePaymentRequestRequest true "Payer, amount, memo and expiry"
+// @Success      201  {object}  models.PaymentRequestResponse  "Payment requested"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid amount, currency, expiry or payer"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     404  {object}  models.ProblemResponse  "Payer or requester wallet not found"
+// @Router       /payment-requests [post]
+func (ctrl *paymentRequestController) Create(c *gin.Context) {
+	var req models.CreatePaymentRequestRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequestBody(err))
+		return
+	}
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	req.UserID = userID
+
+	res, err := ctrl.paymentRequestSrv.Create(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, res)
+}
+
+// @Summary      List incoming payment requests
+// @Description  Returns the latest payment requests sent to the authenticated user, the most recent first
+// @Tags         payment-requests
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        status  query  string  false  "Request status"  Enums(PENDING, ACCEPTED, DECLINED, EXPIRED)
+// @Success      200  {array}   models.PaymentRequestResponse  "Incoming payment requests"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid status"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Router       /payment-requests/incoming [get]
+func (ctrl *paymentRequestController) GetIncoming(c *gin.Context) {
+	ctrl.list(c, ctrl.paymentRequestSrv.GetIncoming)
+}
+
+// @Summary      List outgoing payment requests
+// @Description  Returns the latest payment requests the authenticated user sent, the most recent first
+// @Tags         payment-requests
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        status  query  string  false  "Request status"  Enums(PENDING, ACCEPTED, DECLINED, EXPIRED)
+// @Success      200  {array}   models.PaymentRequestResponse  "Outgoing payment requests"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid status"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Router       /payment-requests/outgoing [get]
+func (ctrl *paymentRequestController) GetOutgoing(c *gin.Context) {
+	ctrl.list(c, ctrl.paymentRequestSrv.GetOutgoing)
+}
+
+func (ctrl *paymentRequestController) list(c *gin.Context, get func(*models.PaymentRequestQuery) ([]models.PaymentRequestResponse, error)) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var query models.PaymentRequestQuery
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(apperrors.Validation("invalid_query", "Invalid query parameter: %v", err))
+		return
+	}
+
+	query.UserID = userID
+
+	res, err := get(&query)
+	if err != nil {
+		c.Error(fmt.Errorf("failed to get payment requests of user %d: %w", userID, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary      Accept a payment request
+// @Description  Pays a pending payment request sent to the authenticated user with a transfer to the requester, under the same fees, limits and balance checks as any transfer. The request is accepted together with the transfer, and links to it.
+// @Tags         payment-requests
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        id path string true "Payment request ID"
+// @Success      200  {object}  models.PaymentRequestResponse  "Payment request accepted"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid payment request ID"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     403  {object}  models.ProblemResponse  "Forbidden - the request was sent to another user"
+// @Response     404  {object}  models.ProblemResponse  "Payment request not found"
+// @Response     409  {object}  models.ProblemResponse  "Conflict - payment request is no longer pending"
+// @Response     422  {object}  models.ProblemResponse  "Insufficient funds or limit exceeded"
+// @Router       /payment-requests/{id}/accept [post]
+func (ctrl *paymentRequestController) Accept(c *gin.Context) {
+	ctrl.close(c, ctrl.paymentRequestSrv.Accept)
+}
+
+// @Summary      Decline a payment request
+// @Description  Closes a pending payment request sent to the authenticated user without paying it
+// @Tags         payment-requests
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        id path string true "Payment request ID"
+// @Success      200  {object}  models.PaymentRequestResponse  "Payment request declined"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid payment request ID"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     403  {object}  models.ProblemResponse  "Forbidden - the request was sent to another user"
+// @Response     404  {object}  models.ProblemResponse  "Payment request not found"
+// @Response     409  {object}  models.ProblemResponse  "Conflict - payment request is no longer pending"
+// @Router       /payment-requests/{id}/decline [post]
+func (ctrl *paymentRequestController) Decline(c *gin.Context) {
+	ctrl.close(c, ctrl.paymentRequestSrv.Decline)
+}
+
+func (ctrl *paymentRequestController) close(c *gin.Context, closeRequest func(userID uint, id string) (*models.PaymentRequestResponse, error)) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res, err := closeRequest(userID, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
@@ -1,12 +1,13 @@
 package controllers
 
 import (
+	"banking-system/apperrors"
+	"banking-system/auth"
+	"banking-system/middleware"
 	"banking-system/models"
 	"banking-system/psp"
 	"banking-system/services"
-	"errors"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,6 +18,8 @@ type PaymentController interface {
 	Transfer(c *gin.Context)
 	Confirm(c *gin.Context)
 	Cancel(c *gin.Context)
+	Refund(c *gin.Context)
+	Reverse(c *gin.Context)
 }
 
 type paymentController struct {
@@ -33,23 +36,29 @@ func NewPaymentController(paymentSrv services.PaymentService) PaymentController
 // @Description  Creates a new PENDING transaction and returns the redirect URL to the Payment Service Provider (PSP) for payment completion.
 // @Tags         payments
 // @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        Idempotency-Key header string false "Replays the original response when the same key is sent again"
 // @Param        request body models.DepositRequest true "Deposit initiation details"
 // @Success      200  {object}  object{redirect_url=string}  "Deposit initiated successfully with PSP redirect URL"
-// @Response     400  {object}  object  "Bad request - validation error"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - validation error"
+// @Response     409  {object}  models.ProblemResponse  "Conflict - duplicate transaction or Idempotency-Key in progress"
+// @Response     422  {object}  models.ProblemResponse  "Insufficient funds, amount limit exceeded or Idempotency-Key reused with a different request body"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     404  {object}  models.ProblemResponse  "Wallet not found"
 // @Router       /payments/deposit [post]
 func (ctrl *paymentController) Deposit(c *gin.Context) {
 	var req models.DepositRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid request body or missing field: " + err.Error(),
-		})
+		c.Error(invalidRequestBody(err))
 		return
 	}
 
-	userID, err := getUserIDFromHeader(c)
+	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"message": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -57,9 +66,7 @@ func (ctrl *paymentController) Deposit(c *gin.Context) {
 
 	redirectUrl, err := ctrl.paymentSrv.Deposit(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -69,35 +76,40 @@ func (ctrl *paymentController) Deposit(c *gin.Context) {
 }
 
 // @Summary      Initiate a Withdrawal Transaction
-// @Description  Creates a new PENDING withdrawal transaction, deducts the amount from wallet balance, and sends request to PSP for processing.
+// @Description  Creates a new PENDING withdrawal transaction, deducts the amount from wallet balance, and asks the PSP to pay it out to one of the user's bank accounts.
 // @Tags         payments
 // @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        Idempotency-Key header string false "Replays the original response when the same key is sent again"
 // @Param        request body models.WithdrawRequest true "Withdrawal initiation details"
 // @Response     200  {object}  nil  "Withdrawal initiated successfully"
-// @Response     400  {object}  object  "Bad request - validation error or insufficient balance"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - validation error"
+// @Response     409  {object}  models.ProblemResponse  "Conflict - duplicate transaction or Idempotency-Key in progress"
+// @Response     422  {object}  models.ProblemResponse  "Insufficient funds, amount limit exceeded or Idempotency-Key reused with a different request body"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     403  {object}  models.ProblemResponse  "Forbidden - bank account belongs to another user"
+// @Response     404  {object}  models.ProblemResponse  "Wallet or bank account not found"
 // @Router       /payments/withdraw [post]
 func (ctrl *paymentController) Withdraw(c *gin.Context) {
 	var req models.WithdrawRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid request body or missing field: " + err.Error(),
-		})
+		c.Error(invalidRequestBody(err))
 		return
 	}
 
-	userID, err := getUserIDFromHeader(c)
+	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"message": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	req.UserID = userID
 
 	if err := ctrl.paymentSrv.Withdraw(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -108,32 +120,36 @@ func (ctrl *paymentController) Withdraw(c *gin.Context) {
 // @Description  Transfers funds from one user's wallet to another user's wallet atomically.
 // @Tags         payments
 // @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        Idempotency-Key header string false "Replays the original response when the same key is sent again"
 // @Param        request body models.TransferRequest true "Transfer details"
 // @Response     200  {object}  nil  "Transfer completed successfully"
-// @Response     400  {object}  object  "Bad request - validation error or insufficient balance"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - validation error"
+// @Response     409  {object}  models.ProblemResponse  "Conflict - duplicate transaction or Idempotency-Key in progress"
+// @Response     422  {object}  models.ProblemResponse  "Insufficient funds, amount limit exceeded or Idempotency-Key reused with a different request body"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     404  {object}  models.ProblemResponse  "Recipient or wallet not found"
 // @Router       /payments/transfer [post]
 func (ctrl *paymentController) Transfer(c *gin.Context) {
 	var req models.TransferRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid request body or missing field: " + err.Error(),
-		})
+		c.Error(invalidRequestBody(err))
 		return
 	}
 
-	userID, err := getUserIDFromHeader(c)
+	userID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"message": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	req.SenderUserID = userID
 
 	if err := ctrl.paymentSrv.Transfer(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -144,25 +160,30 @@ func (ctrl *paymentController) Transfer(c *gin.Context) {
 // @Description  Handles the confirmation callback from the Payment Service Provider (PSP) after a successful deposit.
 // @Tags         payments
 // @Accept       json
-// @Param        X-PSP-API-Key header string true "PSP API Key"
-// @Param        request body psp.PayInResponse true "Confirmation callback from PSP"
+// @Produce      json
+// @Produce      application/problem+json
+// @Param        X-PSP-ID header string true "Provider that sent the callback, e.g. FakePay"
+// @Param        X-PSP-Timestamp header int true "Unix time the callback was signed at"
+// @Param        X-PSP-Nonce header string true "Unique value per callback"
+// @Param        X-PSP-Signature header string true "Hex HMAC-SHA256 of timestamp.nonce.body with the provider's secret"
+// @Param        request body psp.ConfirmRequest true "Confirmation callback from PSP"
 // @Response     200  {string}  string	"Deposit confirmed successfully"
-// @Response     401  {object}  object	"Unauthorized - Invalid API key"
+// @Response     400  {object}  models.ProblemResponse	"Bad request - invalid callback body"
+// @Response     401  {object}  models.ProblemResponse	"Unauthorized - invalid, stale or replayed callback signature"
+// @Response     404  {object}  models.ProblemResponse	"Not found - no deposit of the calling provider with this ID"
+// @Response     409  {object}  models.ProblemResponse	"Conflict - the transaction cannot be confirmed in its current status"
 // @Router       /payments/confirm [post]
 func (ctrl *paymentController) Confirm(c *gin.Context) {
 	var req psp.ConfirmRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid request body or missing field: " + err.Error(),
-		})
+		c.Error(invalidRequestBody(err))
 		return
 	}
 
+	req.Provider = middleware.GetPSPProvider(c)
 	if err := ctrl.paymentSrv.Confirm(&req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -173,41 +194,129 @@ func (ctrl *paymentController) Confirm(c *gin.Context) {
 // @Description  Handles the cancellation callback from the Payment Service Provider (PSP) when a deposit is cancelled.
 // @Tags         payments
 // @Accept       json
-// @Param        X-PSP-API-Key header string true "PSP API Key"
+// @Produce      json
+// @Produce      application/problem+json
+// @Param        X-PSP-ID header string true "Provider that sent the callback, e.g. FakePay"
+// @Param        X-PSP-Timestamp header int true "Unix time the callback was signed at"
+// @Param        X-PSP-Nonce header string true "Unique value per callback"
+// @Param        X-PSP-Signature header string true "Hex HMAC-SHA256 of timestamp.nonce.body with the provider's secret"
 // @Param        request body psp.CancelRequest true "Cancellation callback from PSP"
 // @Response     200  {string}  string	"Deposit cancelled successfully"
-// @Response     401  {object}  object	"Unauthorized - Invalid API key"
+// @Response     400  {object}  models.ProblemResponse	"Bad request - invalid callback body"
+// @Response     401  {object}  models.ProblemResponse	"Unauthorized - invalid, stale or replayed callback signature"
+// @Response     404  {object}  models.ProblemResponse	"Not found - no deposit of the calling provider with this ID"
+// @Response     409  {object}  models.ProblemResponse	"Conflict - the transaction cannot be canceled in its current status"
 // @Router       /payments/cancel [post]
 func (ctrl *paymentController) Cancel(c *gin.Context) {
 	var req psp.CancelRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid request body or missing field: " + err.Error(),
-		})
+		c.Error(invalidRequestBody(err))
 		return
 	}
 
+	req.Provider = middleware.GetPSPProvider(c)
 	if err := ctrl.paymentSrv.Cancel(&req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
 	c.Status(http.StatusOK)
 }
 
-func getUserIDFromHeader(c *gin.Context) (uint, error) {
-	userIDStr := c.GetHeader("X-User-ID")
-	if userIDStr == "" {
-		return 0, errors.New("authentication context missing (No X-User-ID header)")
+// @Summary      Refund a Deposit
+// @Description  Returns all or part of a completed deposit to the payer through the deposit's PSP. The refund is a new REFUND transaction related to the deposit; refunds of a deposit can never add up to more than its amount.
+// @Tags         payments
+// @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        Idempotency-Key header string false "Replays the original response when the same key is sent again"
+// @Param        request body models.RefundRequest true "Deposit to refund and optional partial amount"
+// @Success      200  {object}  models.TransactionResponse  "Refund completed"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - validation error"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     403  {object}  models.ProblemResponse  "Forbidden - deposit belongs to another user"
+// @Response     404  {object}  models.ProblemResponse  "Deposit not found"
+// @Response     409  {object}  models.ProblemResponse  "Conflict - duplicate transaction or Idempotency-Key in progress"
+// @Response     422  {object}  models.ProblemResponse  "Not a completed deposit, refunds exceed the deposit amount or insufficient funds"
+// @Response     502  {object}  models.ProblemResponse  "PSP failed to make the refund"
+// @Router       /payments/refund [post]
+func (ctrl *paymentController) Refund(c *gin.Context) {
+	var req models.RefundRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequestBody(err))
+		return
+	}
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
 	}
 
-	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+	req.UserID = userID
+
+	res, err := ctrl.paymentSrv.Refund(&req)
 	if err != nil {
-		return 0, errors.New("invalid user ID format from gateway")
+		c.Error(err)
+		return
 	}
 
-	return uint(userID), nil
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary      Reverse a Transfer
+// @Description  Operator-initiated reversal of a completed transfer. Moves the amount back from the recipient to the sender as a new REVERSAL_OUT/REVERSAL_IN pair related to the transfer, and records the operator and reason in the status history.
+// @Tags         payments
+// @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Param        X-Operator-Key header string true "Operator API key"
+// @Param        request body models.ReversalRequest true "Transfer to reverse, by either of its transactions"
+// @Success      200  {object}  models.TransactionResponse  "The REVERSAL_IN transaction crediting the sender"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - validation error"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid operator key"
+// @Response     404  {object}  models.ProblemResponse  "Transfer not found"
+// @Response     409  {object}  models.ProblemResponse  "Conflict - transfer already reversed or duplicate transaction"
+// @Response     422  {object}  models.ProblemResponse  "Not a completed transfer or recipient balance too low"
+// @Router       /payments/reverse [post]
+func (ctrl *paymentController) Reverse(c *gin.Context) {
+	var req models.ReversalRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequestBody(err))
+		return
+	}
+
+	operatorID, ok := auth.OperatorID(c)
+	if !ok {
+		c.Error(apperrors.Unauthorized("missing_operator_key", "operator authentication required"))
+		return
+	}
+
+	req.OperatorID = operatorID
+
+	res, err := ctrl.paymentSrv.Reverse(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// getUserID returns the user authenticated by the auth middleware.
+func getUserID(c *gin.Context) (uint, error) {
+	userID, ok := auth.UserID(c)
+	if !ok {
+		return 0, apperrors.Unauthorized("missing_token", "authentication required")
+	}
+
+	return userID, nil
+}
+
+func invalidRequestBody(err error) error {
+	return apperrors.Validation("invalid_request", "Invalid request body or missing field: %v", err)
 }
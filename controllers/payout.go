@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"banking-system/middleware"
+	"banking-system/psp"
+	"banking-system/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PayOutController interface {
+	UpdateStatus(c *gin.Context)
+}
+
+type payOutController struct {
+	payOutSrv services.PayOutService
+}
+
+func NewPayOutController(payOutSrv services.PayOutService) PayOutController {
+	return &payOutController{
+		payOutSrv: payOutSrv,
+	}
+}
+
+// @Summary      Update a payout's status
+// @Description  Handles the status callback the Payment Service Provider (PSP) sends whenever a withdrawal payout changes status. A REJECTED or RETURNED payout refunds the wallet. Repeated and out-of-order callbacks are ignored.
+// @Tags         payments
+// @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Param        X-PSP-ID header string true "Provider that sent the callback, e.g. FakePay"
+// @Param        X-PSP-Timestamp header int true "Unix time the callback was signed at"
+// @Param        X-PSP-Nonce header string true "Unique value per callback"
+// @Param        X-PSP-Signature header string true "Hex HMAC-SHA256 of timestamp.nonce.body with the provider's secret"
+// @Param        request body psp.PayOutStatusCallback true "Payout status callback from PSP"
+// @Response     200  {string}  string  "Payout status updated"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - unknown status or invalid transaction ID"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - invalid, stale or replayed callback signature"
+// @Response     404  {object}  models.ProblemResponse  "No payout of this provider with that transaction ID"
+// @Response     409  {object}  models.ProblemResponse  "Conflict - the payout cannot move to that status"
+// @Router       /payments/payout-status [post]
+func (ctrl *payOutController) UpdateStatus(c *gin.Context) {
+	var req psp.PayOutStatusCallback
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequestBody(err))
+		return
+	}
+
+	req.Provider = middleware.GetPSPProvider(c)
+	if err := ctrl.payOutSrv.UpdateStatus(&req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
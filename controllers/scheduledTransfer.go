@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"banking-system/models"
+	"banking-system/services"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduledTransferController interface {
+	Create(c *gin.Context)
+	GetAll(c *gin.Context)
+	GetOccurrences(c *gin.Context)
+	Pause(c *gin.Context)
+	Resume(c *gin.Context)
+	Cancel(c *gin.Context)
+}
+
+type scheduledTransferController struct {
+	scheduledTransferSrv services.ScheduledTransferService
+}
+
+func NewScheduledTransferController(scheduledTransferSrv services.ScheduledTransferService) ScheduledTransferController {
+	return &scheduledTransferController{
+		scheduledTransferSrv: scheduledTransferSrv,
+	}
+}
+
+// @Summary      Schedule a transfer
+// @Description  Sets up a transfer from the authenticated user on start_date, once (ONCE) or repeated DAILY, WEEKLY (on the weekday of start_date), MONTHLY (on day_of_month, or the last day of shorter months) or on the LAST_BUSINESS_DAY of each month. A repeated schedule ends after end_date or after count occurrences, whichever comes first. Dates are YYYY-MM-DD in UTC; the occurrence of a day is made once the day has started. An occurrence that fails, such as for insufficient funds, is recorded as failed and notified with a scheduled_transfer.failed event; the schedule goes on.
+// @Tags         scheduled-transfers
+// @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        request body models.CreateScheduledTransferRequest true "Recipient, amount and recurrence"
+// @Success      201  {object}  models.ScheduledTransferResponse  "Transfer scheduled"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid recurrence, dates, amount or recipient"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     404  {object}  models.ProblemResponse  "Recipient or sender wallet not found"
+// @Router       /scheduled-transfers [post]
+func (ctrl *scheduledTransferController) Create(c *gin.Context) {
+	var req models.CreateScheduledTransferRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequestBody(err))
+		return
+	}
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	req.UserID = userID
+
+	res, err := ctrl.scheduledTransferSrv.Create(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, res)
+}
+
+// @Summary      List scheduled transfers
+// @Description  Returns the scheduled transfers of the authenticated user, the most recently created first
+// @Tags         scheduled-transfers
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Success      200  {array}   models.ScheduledTransferResponse  "Scheduled transfers"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Router       /scheduled-transfers [get]
+func (ctrl *scheduledTransferController) GetAll(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res, err := ctrl.scheduledTransferSrv.GetAll(userID)
+	if err != nil {
+		c.Error(fmt.Errorf("failed to get scheduled transfers of user %d: %w", userID, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary      List occurrences of a scheduled transfer
+// @Description  Returns the latest occurrences of a scheduled transfer of the authenticated user, the most recent first, with the transfer made for each and why it failed
+// @Tags         scheduled-transfers
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        id path string true "Scheduled transfer ID"
+// @Success      200  {array}   models.ScheduledTransferOccurrenceResponse  "Occurrences"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid scheduled transfer ID"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     403  {object}  models.ProblemResponse  "Forbidden - another user's scheduled transfer"
+// @Response     404  {object}  models.ProblemResponse  "Scheduled transfer not found"
+// @Router       /scheduled-transfers/{id}/occurrences [get]
+func (ctrl *scheduledTransferController) GetOccurrences(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res, err := ctrl.scheduledTransferSrv.GetOccurrences(userID, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary      Pause a scheduled transfer
+// @Description  Stops an active scheduled transfer of the authenticated user. The occurrences that fall before it is resumed are skipped.
+// @Tags         scheduled-transfers
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        id path string true "Scheduled transfer ID"
+// @Success      200  {object}  models.ScheduledTransferResponse  "Scheduled transfer paused"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid scheduled transfer ID"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     403  {object}  models.ProblemResponse  "Forbidden - another user's scheduled transfer"
+// @Response     404  {object}  models.ProblemResponse  "Scheduled transfer not found"
+// @Response     409  {object}  models.ProblemResponse  "Conflict - scheduled transfer is not active"
+// @Router       /scheduled-transfers/{id}/pause [post]
+func (ctrl *scheduledTransferController) Pause(c *gin.Context) {
+	ctrl.updateStatus(c, ctrl.scheduledTransferSrv.Pause)
+}
+
+// @Summary      Resume a scheduled transfer
+// @Description  Continues a paused scheduled transfer of the authenticated user with its first occurrence from today on
+// @Tags         scheduled-transfers
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        id path string true "Scheduled transfer ID"
+// @Success      200  {object}  models.ScheduledTransferResponse  "Scheduled transfer resumed"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid scheduled transfer ID"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     403  {object}  models.ProblemResponse  "Forbidden - another user's scheduled transfer"
+// @Response     404  {object}  models.ProblemResponse  "Scheduled transfer not found"
+// @Response     409  {object}  models.ProblemResponse  "Conflict - scheduled transfer is not paused"
+// @Router       /scheduled-transfers/{id}/resume [post]
+func (ctrl *scheduledTransferController) Resume(c *gin.Context) {
+	ctrl.updateStatus(c, ctrl.scheduledTransferSrv.Resume)
+}
+
+// @Summary      Cancel a scheduled transfer
+// @Description  Ends an active or paused scheduled transfer of the authenticated user for good
+// @Tags         scheduled-transfers
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        id path string true "Scheduled transfer ID"
+// @Success      200  {object}  models.ScheduledTransferResponse  "Scheduled transfer canceled"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid scheduled transfer ID"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     403  {object}  models.ProblemResponse  "Forbidden - another user's scheduled transfer"
+// @Response     404  {object}  models.ProblemResponse  "Scheduled transfer not found"
+// @Response     409  {object}  models.ProblemResponse  "Conflict - scheduled transfer has ended"
+// @Router       /scheduled-transfers/{id}/cancel [post]
+func (ctrl *scheduledTransferController) Cancel(c *gin.Context) {
+	ctrl.updateStatus(c, ctrl.scheduledTransferSrv.Cancel)
+}
+
+func (ctrl *scheduledTransferController) updateStatus(c *gin.Context, update func(userID uint, id string) (*models.ScheduledTransferResponse, error)) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res, err := update(userID, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"banking-system/apperrors"
+	"banking-system/models"
+	"banking-system/services"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+type StatementController interface {
+	Export(c *gin.Context)
+	GetIssued(c *gin.Context)
+	Download(c *gin.Context)
+}
+
+type statementController struct {
+	statementSrv services.StatementService
+}
+
+func NewStatementController(statementSrv services.StatementService) StatementController {
+	return &statementController{
+		statementSrv: statementSrv,
+	}
+}
+
+// @Summary      Export an account statement
+// @Description  Returns the statement of one wallet with the opening balance, every balance change with the running balance, the closing balance and totals per transaction type. Days count in UTC; both from and to are included. The statement is streamed as it is read.
+// @Tags         statements
+// @Produce      text/csv
+// @Produce      application/pdf
+// @Produce      application/x-ofx
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        from      query  string  true   "First day (YYYY-MM-DD)"
+// @Param        to        query  string  true   "Last day (YYYY-MM-DD)"
+// @Param        format    query  string  true   "Statement format"  Enums(csv, pdf, ofx)
+// @Param        currency  query  string  false  "Wallet currency"  default(TWD)
+// @Success      200  {file}    file  "The statement"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid period or format"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     404  {object}  models.ProblemResponse  "Wallet not found"
+// @Router       /statements [get]
+func (ctrl *statementController) Export(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req models.StatementRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(apperrors.Validation("invalid_query", "Invalid query parameter: %v", err))
+		return
+	}
+
+	req.UserID = userID
+
+	export, err := ctrl.statementSrv.Open(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Type", export.Format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.Filename))
+	c.Status(http.StatusOK)
+
+	if err := export.Write(c.Writer); err != nil {
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			c.Error(err)
+			return
+		}
+
+		// Once streaming has begun the status is sent, so a failure can only
+		// cut the statement short
+		log.Errorf("Failed to write statement for user %d: %v", userID, err)
+		c.Abort()
+	}
+}
+
+// @Summary      List monthly statements
+// @Description  Returns the monthly statements issued to the authenticated user, the latest first. A statement is issued for every wallet shortly after each calendar month (UTC) closes and never changes afterwards.
+// @Tags         statements
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Success      200  {array}   models.IssuedStatementResponse  "Issued statements"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Router       /statements/monthly [get]
+func (ctrl *statementController) GetIssued(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res, err := ctrl.statementSrv.GetIssued(userID)
+	if err != nil {
+		c.Error(fmt.Errorf("failed to get statements of user %d: %w", userID, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary      Download a monthly statement
+// @Description  Returns the document of an issued monthly statement exactly as it was issued. Its SHA-256 is sent as the ETag.
+// @Tags         statements
+// @Produce      application/pdf
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Statement ID"
+// @Success      200  {file}    file  "The statement"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid statement ID"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     403  {object}  models.ProblemResponse  "Forbidden - another user's statement"
+// @Response     404  {object}  models.ProblemResponse  "Statement not found"
+// @Router       /statements/{id}/download [get]
+func (ctrl *statementController) Download(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	document, err := ctrl.statementSrv.Download(userID, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, document.Filename))
+	c.Header("ETag", `"`+document.ContentHash+`"`)
+	c.Data(http.StatusOK, document.ContentType, document.Data)
+}
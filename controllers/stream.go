@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"banking-system/outbox"
+	"banking-system/services"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	LAST_EVENT_ID_HEADER = "Last-Event-ID"
+
+	// heartbeatInterval keeps idle streams from being closed by proxies
+	heartbeatInterval = 15 * time.Second
+
+	// reconnectDelay is how long clients wait before reconnecting
+	reconnectDelay = 3 * time.Second
+)
+
+type StreamController interface {
+	Stream(c *gin.Context)
+}
+
+type streamController struct {
+	streamSrv services.StreamService
+}
+
+func NewStreamController(streamSrv services.StreamService) StreamController {
+	return &streamController{
+		streamSrv: streamSrv,
+	}
+}
+
+// @Summary      Stream account events
+// @Description  Pushes the events of the authenticated user as Server-Sent Events: transaction.completed, transaction.canceled, transaction.failed, transfer.received, wallet.balance_changed and scheduled_transfer.failed. The event name is the event type and the data is the event as JSON, with its id for dropping duplicates. A comment is sent as a heartbeat every 15 seconds. A client that reconnects with the Last-Event-ID header first receives the events it missed; if it missed too many, it receives a "reset" event and should reload its balances. The stream may be closed at any time, after which the client reconnects.
+// @Tags         stream
+// @Produce      text/event-stream
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        Last-Event-ID header string false "ID of the last event received"
+// @Success      200  {string}  string  "Event stream"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid Last-Event-ID"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Router       /stream [get]
+func (ctrl *streamController) Stream(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	s, err := ctrl.streamSrv.Open(userID, c.GetHeader(LAST_EVENT_ID_HEADER))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer s.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	fmt.Fprintf(w, "retry: %d\n\n", reconnectDelay.Milliseconds())
+	if s.Reset {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, msg := range s.Replay {
+		if s.Cursor.Advance(msg) {
+			writeStreamEvent(w, s.Cursor.String(), msg)
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case msg, ok := <-s.C:
+			if !ok {
+				// Fell behind; the client reconnects and replays
+				return
+			}
+			if s.Cursor.Advance(msg) {
+				writeStreamEvent(w, s.Cursor.String(), msg)
+				w.Flush()
+			}
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			w.Flush()
+		}
+	}
+}
+
+// writeStreamEvent sends msg with the cursor after it as its ID, so that a
+// client reconnecting with it continues after msg.
+func writeStreamEvent(w io.Writer, id string, msg *outbox.Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Errorf("Failed to encode event '%s' for stream: %v", msg.ID, err)
+		return
+	}
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, msg.Type, data)
+}
@@ -1,16 +1,22 @@
 package controllers
 
 import (
+	"banking-system/apperrors"
+	"banking-system/models"
 	"banking-system/services"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	log "github.com/sirupsen/logrus"
 )
 
+// legacyHistoryMonths is how far back the unpaged history endpoint looks.
+const legacyHistoryMonths = 6
+
 type TransactionController interface {
+	GetHistory(c *gin.Context)
 	GetByUserID(c *gin.Context)
 }
 
@@ -24,32 +30,87 @@ func NewTransactionController(transactionSrv services.TransactionService) Transa
 	}
 }
 
+// @Summary      Get transaction history
+// @Description  Returns one page of the authenticated user's transactions, newest first by default. Pass next_cursor back as cursor to get the following page.
+// @Tags         transactions
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        from            query  string  false  "Created at or after (RFC 3339)"
+// @Param        to              query  string  false  "Created before (RFC 3339)"
+// @Param        type            query  []string  false  "Transaction types"  collectionFormat(csv)
+// @Param        status          query  []string  false  "Transaction statuses"  collectionFormat(csv)
+// @Param        payment_method  query  []string  false  "Payment methods"  collectionFormat(csv)
+// @Param        currency        query  string  false  "Currency"
+// @Param        min_amount      query  string  false  "Minimum amount, inclusive"
+// @Param        max_amount      query  string  false  "Maximum amount, inclusive"
+// @Param        order           query  string  false  "Sort order by creation time"  Enums(asc, desc)
+// @Param        limit           query  int     false  "Page size, at most 100"  default(20)
+// @Param        cursor          query  string  false  "next_cursor of the previous page"
+// @Success      200  {object}  models.TransactionPageResponse  "One page of transactions"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid filter or cursor"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Router       /transactions [get]
+func (ctrl *transactionController) GetHistory(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var query models.TransactionQuery
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(apperrors.Validation("invalid_query", "Invalid query parameter: %v", err))
+		return
+	}
+
+	query.UserID = userID
+
+	page, err := ctrl.transactionSrv.GetHistory(&query)
+	if err != nil {
+		c.Error(fmt.Errorf("failed to get transactions for user %d: %w", userID, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
 // @Summary      Get user transactions
-// @Description  Retrieves all transactions for a user within the last 6 months
+// @Description  Returns all of the authenticated user's transactions of the last 6 months, newest first, in one unpaged list. Kept for existing clients; use GET /transactions instead.
 // @Tags         transactions
-// @Accept       json
-// @Param        user_id path int true "User ID"
-// @Success      200  {array}  models.TransactionResponse  "List of transactions"
-// @Response     400  {object}  object  "Bad request - invalid user ID"
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        user_id path int true "User ID; must be the authenticated user"
+// @Success      200  {array}   models.TransactionResponse  "List of transactions"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid user ID"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     403  {object}  models.ProblemResponse  "Forbidden - another user's transactions"
+// @Deprecated
 // @Router       /transactions/user/{user_id} [get]
 func (ctrl *transactionController) GetByUserID(c *gin.Context) {
-	userIDStr := c.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.Error(apperrors.Validation("invalid_user_id", "Invalid user ID"))
+		return
+	}
+
+	callerID, err := getUserID(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid user ID",
-		})
+		c.Error(err)
+		return
+	}
+
+	if uint(userID) != callerID {
+		c.Error(apperrors.Forbidden("transactions_forbidden", "cannot view another user's transactions"))
 		return
 	}
 
-	const months = 6
-	cutoffDate := time.Now().AddDate(0, -months, 0)
-	transactions, err := ctrl.transactionSrv.GetByUserID(uint(userID), cutoffDate)
+	since := time.Now().AddDate(0, -legacyHistoryMonths, 0)
+	transactions, err := ctrl.transactionSrv.GetSince(callerID, since)
 	if err != nil {
-		log.Errorf("Failed to get transactions for user %d: %v", userID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": "Failed to retrieve transactions",
-		})
+		c.Error(fmt.Errorf("failed to get transactions for user %d: %w", callerID, err))
 		return
 	}
 
@@ -1,18 +1,17 @@
 package controllers
 
 import (
-	"banking-system/entities"
+	"banking-system/apperrors"
+	"banking-system/auth"
 	"banking-system/models"
 	"banking-system/services"
 	"net/http"
 	"os"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
-	"github.com/golang-jwt/jwt/v5"
-	log "github.com/sirupsen/logrus"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -20,15 +19,26 @@ type UserController interface {
 	Register(c *gin.Context)
 	Login(c *gin.Context)
 	GetByID(c *gin.Context)
+	OpenWallet(c *gin.Context)
+	RefreshToken(c *gin.Context)
+	Logout(c *gin.Context)
+	GetSessions(c *gin.Context)
+	RevokeSession(c *gin.Context)
 }
 
+var errInvalidCredentials = apperrors.Unauthorized("invalid_credentials", "Invalid username or password")
+
+const refreshCookiePath = "/api/v1/user/token"
+
 type userController struct {
-	userSrv services.UserService
+	userSrv    services.UserService
+	sessionSrv services.SessionService
 }
 
-func NewUserController(userSrv services.UserService) UserController {
+func NewUserController(userSrv services.UserService, sessionSrv services.SessionService) UserController {
 	return &userController{
-		userSrv: userSrv,
+		userSrv:    userSrv,
+		sessionSrv: sessionSrv,
 	}
 }
 
@@ -36,24 +46,23 @@ func NewUserController(userSrv services.UserService) UserController {
 // @Description  Creates a new user with hashed password and an associated wallet with initial balance of 0
 // @Tags         users
 // @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
 // @Param        request body models.RegisterRequest true "User registration details"
 // @Response     201  {object}  nil  "User created successfully"
-// @Response     400  {object}  object  "Bad request - validation error or user already exists"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - validation error"
+// @Response     409  {object}  models.ProblemResponse  "Conflict - username already taken"
 // @Router       /user [post]
 func (ctrl *userController) Register(c *gin.Context) {
 	var req models.RegisterRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid request body or missing field: " + err.Error(),
-		})
+		c.Error(invalidRequestBody(err))
 		return
 	}
 
 	if err := ctrl.userSrv.Register(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -61,52 +70,57 @@ func (ctrl *userController) Register(c *gin.Context) {
 }
 
 // @Summary      User Login
-// @Description  Authenticates a user with username and password, and sets an authorization cookie
+// @Description  Authenticates a user with username and password, starts a new session, and sets the access and refresh token cookies
 // @Tags         users
 // @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
 // @Param        request body models.LoginRequest true "Login form data"
-// @Response     200  	  {object}  nil  "User logged in successfully"
-// @Failure      400      {object}  object  "Bad Request (e.g., invalid body or validation error)"
-// @Failure      401      {object}  object  "Unauthorized (Invalid username or password)"
+// @Success      200      {object}  models.TokenResponse  "User logged in successfully"
+// @Failure      400      {object}  models.ProblemResponse  "Bad Request (e.g., invalid body or validation error)"
+// @Failure      401      {object}  models.ProblemResponse  "Unauthorized (Invalid username or password)"
 // @Router       /user/login [post]
 func (ctrl *userController) Login(c *gin.Context) {
 	// Retrieve req from request
 	var req models.LoginRequest
-	if err := c.Bind(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apperrors.Validation("invalid_request", "Invalid request"))
 		return
 	}
 
 	// validate input
 	if !ctrl.validateRequest(&req) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid username or password"})
+		c.Error(apperrors.Validation("invalid_credentials", "Invalid username or password"))
 		return
 	}
 
 	// Validate username
-	valid, foundUser := ctrl.validateExistence(req.Username)
-	if !valid {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+	foundUser, err := ctrl.userSrv.GetByUsername(req.Username)
+	if apperrors.KindOf(err) == apperrors.Kinds.NotFound {
+		c.Error(errInvalidCredentials)
+		return
+	}
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
 	// validate password
 	if !ctrl.validatePassword(&req, foundUser.PasswordHash) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+		c.Error(errInvalidCredentials)
 		return
 	}
 
-	// Generate JWT token
-	tokenString := ctrl.generateToken(foundUser)
-
-	// Write token to response cookie
-	cookieDomain := ""
-	if os.Getenv("APP_ENV") == "production" {
-		cookieDomain = ".up.railway.app"
+	// Start a session for this device
+	tokens, err := ctrl.sessionSrv.Start(foundUser.ID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.Error(err)
+		return
 	}
-	c.SetCookie("authorization", tokenString, 60*60*24*7, "/", cookieDomain, true, true)
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.Status(http.StatusOK)
+
+	// Clients that cannot use cookies send the same tokens in requests
+	setTokenCookies(c, tokens)
+	c.JSON(http.StatusOK, tokens)
 }
 
 func (*userController) validateRequest(req any) bool {
@@ -114,15 +128,6 @@ func (*userController) validateRequest(req any) bool {
 	return validate.Struct(req) == nil
 }
 
-func (ctrl *userController) validateExistence(username string) (bool, *entities.User) {
-	foundUser, err := ctrl.userSrv.GetByUsername(username)
-	if err != nil {
-		return false, nil
-	}
-
-	return foundUser != nil, foundUser
-}
-
 func (*userController) validatePassword(req *models.LoginRequest, correctPassword string) bool {
 	if err := bcrypt.CompareHashAndPassword([]byte(correctPassword), []byte(req.Password)); err != nil {
 		return false
@@ -130,54 +135,228 @@ func (*userController) validatePassword(req *models.LoginRequest, correctPasswor
 	return true
 }
 
-func (*userController) generateToken(user *entities.User) string {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": user.ID,
-		"exp": time.Now().Add(time.Hour * 72).Unix(),
-	})
-
-	key := os.Getenv("USER_TOKEN_SECRET_KEY")
-	if key == "" {
-		log.Panic("USER_TOKEN_SECRET_KEY environment variable not set")
-	}
+// setTokenCookies writes the access token for API requests and the refresh
+// token, which is only sent to the refresh endpoint.
+func setTokenCookies(c *gin.Context, tokens *models.TokenResponse) {
+	cookieDomain := tokenCookieDomain()
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(auth.AUTH_COOKIE_NAME, tokens.AccessToken, int(auth.ACCESS_TOKEN_TTL.Seconds()), "/", cookieDomain, true, true)
+	c.SetCookie(auth.REFRESH_COOKIE, tokens.RefreshToken, int(auth.REFRESH_TOKEN_TTL.Seconds()), refreshCookiePath, cookieDomain, true, true)
+}
 
-	tokenString, err := token.SignedString([]byte(key))
-	if err != nil {
-		log.Panicf("Failed to sign token: %v", err)
+func tokenCookieDomain() string {
+	if os.Getenv("APP_ENV") == "production" {
+		return ".up.railway.app"
 	}
+	return ""
+}
 
-	return tokenString
+func clearTokenCookies(c *gin.Context) {
+	cookieDomain := tokenCookieDomain()
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(auth.AUTH_COOKIE_NAME, "", -1, "/", cookieDomain, true, true)
+	c.SetCookie(auth.REFRESH_COOKIE, "", -1, refreshCookiePath, cookieDomain, true, true)
 }
 
 // @Summary      Get user information
-// @Description  Retrieves user information including username and balance by user ID
+// @Description  Retrieves the authenticated user's information including username and the balance of every wallet
 // @Tags         users
 // @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
 // @Param        user_id path int true "User ID"
 // @Success      200  {object}  models.UserInfoResponse  "User information retrieved successfully"
-// @Response     400  {object}  object  "Bad request - invalid user ID"
-// @Response     404  {object}  object  "User not found"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid user ID"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     403  {object}  models.ProblemResponse  "Forbidden - not the authenticated user"
+// @Response     404  {object}  models.ProblemResponse  "User not found"
 // @Router       /user/{user_id} [get]
 func (ctrl *userController) GetByID(c *gin.Context) {
 	userIDStr := c.Param("user_id")
 	userID, err := strconv.ParseUint(userIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid user ID",
-		})
+		c.Error(apperrors.Validation("invalid_user_id", "Invalid user ID"))
+		return
+	}
+
+	callerID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if uint(userID) != callerID {
+		c.Error(apperrors.Forbidden("user_forbidden", "cannot view another user's information"))
 		return
 	}
 
 	userInfo, err := ctrl.userSrv.GetByID(uint(userID))
 	if err != nil {
-		if err.Error() == "user not found" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"message": err.Error(),
-			})
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, userInfo)
+}
+
+// @Summary      Open a wallet
+// @Description  Opens a new wallet with a zero balance in the given currency for the authenticated user
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        request body models.OpenWalletRequest true "Wallet currency"
+// @Success      201  {object}  models.WalletResponse  "Wallet opened successfully"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - unsupported currency or wallet already exists"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Router       /user/wallets [post]
+func (ctrl *userController) OpenWallet(c *gin.Context) {
+	var req models.OpenWalletRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequestBody(err))
+		return
+	}
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	req.UserID = userID
+
+	wallet, err := ctrl.userSrv.OpenWallet(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, wallet)
+}
+
+// @Summary      Refresh access token
+// @Description  Exchanges a refresh token, from the body or the refresh cookie, for a new access token and a new refresh token. A refresh token can only be used once; reusing one revokes its session.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Param        request body models.RefreshTokenRequest false "Refresh token"
+// @Success      200  {object}  models.TokenResponse  "Tokens rotated successfully"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - invalid, reused or revoked refresh token"
+// @Router       /user/token/refresh [post]
+func (ctrl *userController) RefreshToken(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(invalidRequestBody(err))
 			return
 		}
-		log.Panicf("Failed to get user info: %v", err)
 	}
 
-	c.JSON(http.StatusOK, userInfo)
+	if req.RefreshToken == "" {
+		req.RefreshToken, _ = c.Cookie(auth.REFRESH_COOKIE)
+	}
+
+	if req.RefreshToken == "" {
+		c.Error(apperrors.Unauthorized("missing_refresh_token", "refresh token required"))
+		return
+	}
+
+	tokens, err := ctrl.sessionSrv.Refresh(req.RefreshToken, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	setTokenCookies(c, tokens)
+	c.JSON(http.StatusOK, tokens)
+}
+
+// @Summary      Log out
+// @Description  Revokes the session of the access token, so that neither its access tokens nor its refresh token are accepted any more
+// @Tags         users
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Response     204  {object}  nil  "Logged out successfully"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Router       /user/logout [post]
+func (ctrl *userController) Logout(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if sessionID, ok := auth.SessionID(c); ok {
+		if err := ctrl.sessionSrv.Logout(userID, sessionID); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	clearTokenCookies(c)
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary      List sessions
+// @Description  Lists the active sessions (signed-in devices) of the authenticated user
+// @Tags         users
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Success      200  {array}   models.SessionResponse  "Active sessions"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Router       /user/sessions [get]
+func (ctrl *userController) GetSessions(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	currentSessionID, _ := auth.SessionID(c)
+	sessions, err := ctrl.sessionSrv.List(userID, currentSessionID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// @Summary      Revoke a session
+// @Description  Signs out one of the authenticated user's devices
+// @Tags         users
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        session_id path string true "Session ID"
+// @Response     204  {object}  nil  "Session revoked successfully"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid session ID"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     404  {object}  models.ProblemResponse  "Session not found"
+// @Router       /user/sessions/{session_id} [delete]
+func (ctrl *userController) RevokeSession(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		c.Error(apperrors.Validation("invalid_session_id", "Invalid session ID"))
+		return
+	}
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := ctrl.sessionSrv.Revoke(userID, sessionID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
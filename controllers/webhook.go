@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"banking-system/apperrors"
+	"banking-system/models"
+	"banking-system/services"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookController interface {
+	Register(c *gin.Context)
+	GetEndpoints(c *gin.Context)
+	DeleteEndpoint(c *gin.Context)
+	GetDeadLetters(c *gin.Context)
+	Redeliver(c *gin.Context)
+}
+
+type webhookController struct {
+	webhookSrv services.WebhookService
+}
+
+func NewWebhookController(webhookSrv services.WebhookService) WebhookController {
+	return &webhookController{
+		webhookSrv: webhookSrv,
+	}
+}
+
+// @Summary      Register a webhook endpoint
+// @Description  Registers a URL to receive the given events about the authenticated user's account: transaction.completed, transaction.canceled, transaction.failed, transfer.received, wallet.balance_changed and scheduled_transfer.failed. Each delivery is a POST of the event as JSON with the headers X-Webhook-ID (the event ID), X-Webhook-Event, X-Webhook-Timestamp and X-Webhook-Signature, the hex HMAC-SHA256 of "timestamp.event_id.body" with the endpoint's secret. The secret is only returned here. Deliveries that are not answered with 2xx are retried with exponential backoff and dead-lettered after the last attempt. An event may be delivered more than once.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        request body models.CreateWebhookEndpointRequest true "Endpoint URL and events"
+// @Success      201  {object}  models.WebhookEndpointResponse  "Endpoint registered, with its secret"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid URL or event type"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     422  {object}  models.ProblemResponse  "Too many endpoints"
+// @Router       /webhooks [post]
+func (ctrl *webhookController) Register(c *gin.Context) {
+	var req models.CreateWebhookEndpointRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequestBody(err))
+		return
+	}
+
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	req.UserID = userID
+
+	res, err := ctrl.webhookSrv.Register(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, res)
+}
+
+// @Summary      List webhook endpoints
+// @Description  Returns the webhook endpoints of the authenticated user, without their secrets
+// @Tags         webhooks
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Success      200  {array}   models.WebhookEndpointResponse  "Registered endpoints"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Router       /webhooks [get]
+func (ctrl *webhookController) GetEndpoints(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res, err := ctrl.webhookSrv.GetEndpoints(userID)
+	if err != nil {
+		c.Error(fmt.Errorf("failed to get webhook endpoints of user %d: %w", userID, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary      Delete a webhook endpoint
+// @Description  Stops deliveries to an endpoint of the authenticated user. Deliveries still waiting to be sent to it are dead-lettered.
+// @Tags         webhooks
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        id path int true "Endpoint ID"
+// @Response     204  {object}  nil  "Endpoint deleted"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid endpoint ID"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     403  {object}  models.ProblemResponse  "Forbidden - another user's endpoint"
+// @Response     404  {object}  models.ProblemResponse  "Endpoint not found"
+// @Router       /webhooks/{id} [delete]
+func (ctrl *webhookController) DeleteEndpoint(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperrors.Validation("invalid_webhook_endpoint_id", "Invalid webhook endpoint ID"))
+		return
+	}
+
+	if err := ctrl.webhookSrv.DeleteEndpoint(userID, uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary      List dead-lettered deliveries
+// @Description  Returns the latest deliveries to the authenticated user's endpoints that failed on every attempt, the most recent first
+// @Tags         webhooks
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Success      200  {array}   models.WebhookDeliveryResponse  "Dead-lettered deliveries"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Router       /webhooks/dead-letters [get]
+func (ctrl *webhookController) GetDeadLetters(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res, err := ctrl.webhookSrv.GetDeadLetters(userID)
+	if err != nil {
+		c.Error(fmt.Errorf("failed to get dead-lettered deliveries of user %d: %w", userID, err))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary      Redeliver a dead-lettered delivery
+// @Description  Sends a dead-lettered delivery again with the same event ID and body, retrying with backoff as for a new delivery
+// @Tags         webhooks
+// @Produce      json
+// @Produce      application/problem+json
+// @Security     BearerAuth
+// @Param        id path string true "Delivery ID"
+// @Success      202  {object}  models.WebhookDeliveryResponse  "Delivery scheduled"
+// @Response     400  {object}  models.ProblemResponse  "Bad request - invalid delivery ID"
+// @Response     401  {object}  models.ProblemResponse  "Unauthorized - missing or invalid access token"
+// @Response     403  {object}  models.ProblemResponse  "Forbidden - another user's delivery"
+// @Response     404  {object}  models.ProblemResponse  "Delivery not found"
+// @Response     409  {object}  models.ProblemResponse  "Conflict - delivery is not dead-lettered"
+// @Router       /webhooks/dead-letters/{id}/redeliver [post]
+func (ctrl *webhookController) Redeliver(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res, err := ctrl.webhookSrv.Redeliver(userID, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, res)
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"banking-system/entities"
+	"fmt"
 	"os"
 
 	log "github.com/sirupsen/logrus"
@@ -29,12 +30,51 @@ func connect(dsn string) *gorm.DB {
 		log.Panicf("Failed to connect to database: %v", err)
 	}
 
-	err = db.AutoMigrate(&entities.User{}, &entities.Wallet{}, &entities.Transaction{}, &entities.BankAccount{})
+	// Wallets used to be one per user; a user now holds one wallet per currency
+	if db.Migrator().HasConstraint(&entities.Wallet{}, "uni_wallets_user_id") {
+		if err := db.Migrator().DropConstraint(&entities.Wallet{}, "uni_wallets_user_id"); err != nil {
+			log.Panicf("Failed to drop single wallet constraint: %v", err)
+		}
+	}
+
+	err = db.AutoMigrate(&entities.User{}, &entities.Wallet{}, &entities.Transaction{}, &entities.BankAccount{},
+		&entities.LedgerAccount{}, &entities.JournalEntry{}, &entities.Posting{}, &entities.FXQuote{},
+		&entities.IdempotencyKey{}, &entities.Session{}, &entities.TransactionStatusChange{}, &entities.JobLease{},
+		&entities.LimitOverride{}, &entities.Statement{}, &entities.WebhookEndpoint{}, &entities.WebhookDelivery{},
+		&entities.OutboxEvent{}, &entities.ScheduledTransfer{}, &entities.ScheduledTransferOccurrence{}, &entities.PaymentRequest{},
+		&entities.PSPNonce{})
 	if err != nil {
 		log.Panicf("Failed to run database migration: %v", err)
 	}
 
+	if err := migrateHouseUser(db); err != nil {
+		log.Panicf("Failed to migrate house user: %v", err)
+	}
+
 	log.Info("Database connection established and migration complete!")
 
 	return db
 }
+
+// migrateHouseUser marks the house user, which used to be found by its
+// username, and refuses to start while an ordinary user holds the house
+// username, so that it never collects the fees. The house user is told apart
+// by having no password.
+func migrateHouseUser(db *gorm.DB) error {
+	if err := db.Model(&entities.User{}).
+		Where("username = ? AND password_hash = '' AND NOT is_house", entities.HouseUsername).
+		Update("is_house", true).Error; err != nil {
+		return err
+	}
+
+	var ordinary int64
+	if err := db.Model(&entities.User{}).
+		Where("username = ? AND NOT is_house", entities.HouseUsername).
+		Count(&ordinary).Error; err != nil {
+		return err
+	}
+	if ordinary > 0 {
+		return fmt.Errorf("username '%s' belongs to an ordinary user; rename the user, the username is reserved for the house user", entities.HouseUsername)
+	}
+	return nil
+}
@@ -17,23 +17,23 @@ const docTemplate = `{
     "paths": {
         "/bank-accounts": {
             "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "description": "Retrieves all bank accounts for the authenticated user",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
                 "tags": [
                     "bank-accounts"
                 ],
                 "summary": "Get all bank accounts",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "X-User-ID",
-                        "in": "header",
-                        "required": true
-                    }
-                ],
                 "responses": {
                     "200": {
                         "description": "Bank accounts retrieved successfully",
@@ -43,26 +43,34 @@ const docTemplate = `{
                                 "$ref": "#/definitions/models.BankAccountResponse"
                             }
                         }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
                     }
                 }
             },
             "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "description": "Creates a new bank account for the authenticated user",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
                 "tags": [
                     "bank-accounts"
                 ],
                 "summary": "Create a new bank account",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "X-User-ID",
-                        "in": "header",
-                        "required": true
-                    },
                     {
                         "description": "Bank account creation details",
                         "name": "request",
@@ -83,7 +91,13 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad request - validation error",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     }
                 }
@@ -91,10 +105,19 @@ const docTemplate = `{
         },
         "/bank-accounts/user/{userId}": {
             "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "description": "Retrieves all bank accounts for a specific user by user ID",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
                 "tags": [
                     "bank-accounts"
                 ],
@@ -121,7 +144,19 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - not the authenticated user",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     }
                 }
@@ -129,22 +164,24 @@ const docTemplate = `{
         },
         "/bank-accounts/{id}": {
             "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "description": "Retrieves a specific bank account by ID for the authenticated user",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
                 "tags": [
                     "bank-accounts"
                 ],
                 "summary": "Get a bank account by ID",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "X-User-ID",
-                        "in": "header",
-                        "required": true
-                    },
                     {
                         "type": "integer",
                         "description": "Bank Account ID",
@@ -163,34 +200,42 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     },
                     "404": {
                         "description": "Bank account not found",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     }
                 }
             },
             "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "description": "Updates a specific bank account by ID for the authenticated user",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
                 "tags": [
                     "bank-accounts"
                 ],
                 "summary": "Update a bank account",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "X-User-ID",
-                        "in": "header",
-                        "required": true
-                    },
                     {
                         "type": "integer",
                         "description": "Bank Account ID",
@@ -218,34 +263,42 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     },
                     "404": {
                         "description": "Bank account not found",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     }
                 }
             },
             "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "description": "Deletes a specific bank account by ID for the authenticated user",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
                 "tags": [
                     "bank-accounts"
                 ],
                 "summary": "Delete a bank account",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "X-User-ID",
-                        "in": "header",
-                        "required": true
-                    },
                     {
                         "type": "integer",
                         "description": "Bank Account ID",
@@ -261,422 +314,3281 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     },
                     "404": {
                         "description": "Bank account not found",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     }
                 }
             }
         },
-        "/payments/cancel": {
+        "/fees/quotes": {
             "post": {
-                "description": "Handles the cancellation callback from the Payment Service Provider (PSP) when a deposit is cancelled.",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Calculates the fee the user would be charged for a deposit, withdrawal or transfer of the given amount",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
                 "tags": [
-                    "payments"
+                    "fees"
                 ],
-                "summary": "Cancel a Deposit Transaction",
+                "summary": "Preview a fee",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "PSP API Key",
-                        "name": "X-PSP-API-Key",
-                        "in": "header",
-                        "required": true
-                    },
-                    {
-                        "description": "Cancellation callback from PSP",
+                        "description": "Operation, amount and payment method",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/psp.CancelRequest"
+                            "$ref": "#/definitions/models.FeeQuoteRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Deposit cancelled successfully",
+                        "description": "Fee calculated",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.FeeQuoteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - validation error or unsupported currency",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     },
                     "401": {
-                        "description": "Unauthorized - Invalid API key",
+                        "description": "Unauthorized - missing or invalid access token",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     }
                 }
             }
         },
-        "/payments/confirm": {
+        "/fx/quotes": {
             "post": {
-                "description": "Handles the confirmation callback from the Payment Service Provider (PSP) after a successful deposit.",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Locks a conversion rate between two of the user's wallets for a limited time",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
                 "tags": [
-                    "payments"
+                    "fx"
                 ],
-                "summary": "Confirm a Deposit Transaction",
+                "summary": "Request an FX quote",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "PSP API Key",
-                        "name": "X-PSP-API-Key",
-                        "in": "header",
-                        "required": true
-                    },
-                    {
-                        "description": "Confirmation callback from PSP",
+                        "description": "Currencies and amount to convert",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/psp.PayInResponse"
+                            "$ref": "#/definitions/models.FXQuoteRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Deposit confirmed successfully",
+                    "201": {
+                        "description": "Quote issued",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.FXQuoteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - validation error or unknown currency pair",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     },
                     "401": {
-                        "description": "Unauthorized - Invalid API key",
+                        "description": "Unauthorized - missing or invalid access token",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     }
                 }
             }
         },
-        "/payments/deposit": {
-            "post": {
-                "description": "Creates a new PENDING transaction and returns the redirect URL to the Payment Service Provider (PSP) for payment completion.",
+        "/ledger/reconciliation": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Verifies that every ledger account and wallet balance matches the journal postings and that the ledger sums to zero",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
                 "tags": [
-                    "payments"
+                    "ledger"
                 ],
-                "summary": "Initiate a Deposit Transaction",
+                "summary": "Reconcile the general ledger",
                 "parameters": [
                     {
-                        "description": "Deposit initiation details",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.DepositRequest"
-                        }
+                        "type": "string",
+                        "description": "Operator API key",
+                        "name": "X-Operator-Key",
+                        "in": "header",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Deposit initiated successfully with PSP redirect URL",
+                        "description": "Reconciliation report",
                         "schema": {
-                            "type": "object",
-                            "properties": {
-                                "redirect_url": {
-                                    "type": "string"
-                                }
-                            }
+                            "$ref": "#/definitions/models.LedgerReconciliationResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad request - validation error",
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token or operator key",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     }
                 }
             }
         },
-        "/payments/transfer": {
-            "post": {
-                "description": "Transfers funds from one user's wallet to another user's wallet atomically.",
+        "/ledger/wallets/{wallet_id}/entries": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves every journal entry that changed the balance of a wallet, oldest first",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
                 "tags": [
-                    "payments"
+                    "ledger"
                 ],
-                "summary": "Transfer Funds Between Users",
+                "summary": "Get journal entries of a wallet",
                 "parameters": [
                     {
-                        "description": "Transfer details",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.TransferRequest"
-                        }
+                        "type": "string",
+                        "description": "Operator API key",
+                        "name": "X-Operator-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Wallet ID",
+                        "name": "wallet_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Transfer completed successfully"
+                        "description": "List of journal entries",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.JournalEntryResponse"
+                            }
+                        }
                     },
                     "400": {
-                        "description": "Bad request - validation error or insufficient balance",
+                        "description": "Bad request - invalid wallet ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token or operator key",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     }
                 }
             }
         },
-        "/payments/withdraw": {
-            "post": {
-                "description": "Creates a new PENDING withdrawal transaction, deducts the amount from wallet balance, and sends request to PSP for processing.",
-                "consumes": [
-                    "application/json"
-                ],
-                "tags": [
-                    "payments"
-                ],
-                "summary": "Initiate a Withdrawal Transaction",
-                "parameters": [
+        "/limits": {
+            "get": {
+                "security": [
                     {
-                        "description": "Withdrawal initiation details",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.WithdrawRequest"
-                        }
+                        "BearerAuth": []
                     }
                 ],
+                "description": "Returns the user's per-transaction, daily and monthly limits for deposits, withdrawals and transfers in each wallet currency, with the allowance left today and this month. Periods reset at midnight UTC.",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "limits"
+                ],
+                "summary": "Get transaction limits",
                 "responses": {
                     "200": {
-                        "description": "Withdrawal initiated successfully"
+                        "description": "Limits and remaining allowance",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.LimitResponse"
+                            }
+                        }
                     },
-                    "400": {
-                        "description": "Bad request - validation error or insufficient balance",
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     }
                 }
             }
         },
-        "/transactions/user/{user_id}": {
-            "get": {
-                "description": "Retrieves all transactions for a user within the last 6 months",
+        "/limits/users/{user_id}": {
+            "put": {
+                "description": "Operator-only. Replaces the given limits of one user for one transaction type and currency; limits left out keep those of the user's tier. A request without limits removes the override.",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
                 "tags": [
-                    "transactions"
+                    "limits"
                 ],
-                "summary": "Get user transactions",
+                "summary": "Override a user's limits",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Operator API key",
+                        "name": "X-Operator-Key",
+                        "in": "header",
+                        "required": true
+                    },
                     {
                         "type": "integer",
                         "description": "User ID",
                         "name": "user_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Limits to override",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LimitOverrideRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "List of transactions",
+                    "204": {
+                        "description": "Override saved"
+                    },
+                    "400": {
+                        "description": "Bad request - validation error",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.TransactionResponse"
-                            }
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad request - invalid user ID",
+                    "401": {
+                        "description": "Unauthorized - missing or invalid operator key",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     }
                 }
             }
         },
-        "/user": {
+        "/payment-requests": {
             "post": {
-                "description": "Creates a new user with hashed password and an associated wallet with initial balance of 0",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Asks another user to pay the authenticated user an amount, with an optional memo. The request expires at expires_at, at most 30 days ahead, or after 7 days if it is left out. The payer accepts it with a transfer or declines it.",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
                 "tags": [
-                    "users"
+                    "payment-requests"
                 ],
-                "summary": "Register a new user",
+                "summary": "Request a payment",
                 "parameters": [
                     {
-                        "description": "User registration details",
+                        "description": "Payer, amount, memo and expiry",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.RegisterRequest"
+                            "$ref": "#/definitions/models.CreatePaymentRequestRequest"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "User created successfully"
+                        "description": "Payment requested",
+                        "schema": {
+                            "$ref": "#/definitions/models.PaymentRequestResponse"
+                        }
                     },
                     "400": {
-                        "description": "Bad request - validation error or user already exists",
+                        "description": "Bad request - invalid amount, currency, expiry or payer",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Payer or requester wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     }
                 }
             }
         },
-        "/user/login": {
+        "/payment-requests/incoming": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the latest payment requests sent to the authenticated user, the most recent first",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "List incoming payment requests",
+                "parameters": [
+                    {
+                        "enum": [
+                            "PENDING",
+                            "ACCEPTED",
+                            "DECLINED",
+                            "EXPIRED"
+                        ],
+                        "type": "string",
+                        "description": "Request status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Incoming payment requests",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PaymentRequestResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid status",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-requests/outgoing": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the latest payment requests the authenticated user sent, the most recent first",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "List outgoing payment requests",
+                "parameters": [
+                    {
+                        "enum": [
+                            "PENDING",
+                            "ACCEPTED",
+                            "DECLINED",
+                            "EXPIRED"
+                        ],
+                        "type": "string",
+                        "description": "Request status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Outgoing payment requests",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PaymentRequestResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid status",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-requests/{id}/accept": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Pays a pending payment request sent to the authenticated user with a transfer to the requester, under the same fees, limits and balance checks as any transfer. The request is accepted together with the transfer, and links to it.",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Accept a payment request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Payment request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Payment request accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.PaymentRequestResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid payment request ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - the request was sent to another user",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment request not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - payment request is no longer pending",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Insufficient funds or limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payment-requests/{id}/decline": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Closes a pending payment request sent to the authenticated user without paying it",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "payment-requests"
+                ],
+                "summary": "Decline a payment request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Payment request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Payment request declined",
+                        "schema": {
+                            "$ref": "#/definitions/models.PaymentRequestResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid payment request ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - the request was sent to another user",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Payment request not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - payment request is no longer pending",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/cancel": {
             "post": {
-                "description": "Authenticates a user with username and password, and sets an authorization cookie",
+                "description": "Handles the cancellation callback from the Payment Service Provider (PSP) when a deposit is cancelled.",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
                 "tags": [
-                    "users"
+                    "payments"
                 ],
-                "summary": "User Login",
+                "summary": "Cancel a Deposit Transaction",
                 "parameters": [
                     {
-                        "description": "Login form data",
+                        "type": "string",
+                        "description": "Provider that sent the callback, e.g. FakePay",
+                        "name": "X-PSP-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Unix time the callback was signed at",
+                        "name": "X-PSP-Timestamp",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique value per callback",
+                        "name": "X-PSP-Nonce",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Hex HMAC-SHA256 of timestamp.nonce.body with the provider's secret",
+                        "name": "X-PSP-Signature",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Cancellation callback from PSP",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.LoginRequest"
+                            "$ref": "#/definitions/psp.CancelRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "User logged in successfully"
+                        "description": "Deposit cancelled successfully",
+                        "schema": {
+                            "type": "string"
+                        }
                     },
                     "400": {
-                        "description": "Bad Request (e.g., invalid body or validation error)",
+                        "description": "Bad request - invalid callback body",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     },
                     "401": {
-                        "description": "Unauthorized (Invalid username or password)",
+                        "description": "Unauthorized - invalid, stale or replayed callback signature",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - no deposit of the calling provider with this ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - the transaction cannot be canceled in its current status",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     }
                 }
             }
         },
-        "/user/{user_id}": {
-            "get": {
-                "description": "Retrieves user information including username and balance by user ID",
+        "/payments/confirm": {
+            "post": {
+                "description": "Handles the confirmation callback from the Payment Service Provider (PSP) after a successful deposit.",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
                 "tags": [
-                    "users"
+                    "payments"
                 ],
-                "summary": "Get user information",
+                "summary": "Confirm a Deposit Transaction",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider that sent the callback, e.g. FakePay",
+                        "name": "X-PSP-ID",
+                        "in": "header",
+                        "required": true
+                    },
                     {
                         "type": "integer",
-                        "description": "User ID",
-                        "name": "user_id",
-                        "in": "path",
+                        "description": "Unix time the callback was signed at",
+                        "name": "X-PSP-Timestamp",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique value per callback",
+                        "name": "X-PSP-Nonce",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Hex HMAC-SHA256 of timestamp.nonce.body with the provider's secret",
+                        "name": "X-PSP-Signature",
+                        "in": "header",
                         "required": true
+                    },
+                    {
+                        "description": "Confirmation callback from PSP",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/psp.ConfirmRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "User information retrieved successfully",
+                        "description": "Deposit confirmed successfully",
                         "schema": {
-                            "$ref": "#/definitions/models.UserInfoResponse"
+                            "type": "string"
                         }
                     },
                     "400": {
-                        "description": "Bad request - invalid user ID",
+                        "description": "Bad request - invalid callback body",
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ProblemResponse"
                         }
                     },
-                    "404": {
-                        "description": "User not found",
+                    "401": {
+                        "description": "Unauthorized - invalid, stale or replayed callback signature",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - no deposit of the calling provider with this ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - the transaction cannot be confirmed in its current status",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/convert": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Executes a locked FX quote atomically, debiting one wallet and crediting the other with linked transactions",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Convert between the user's own wallets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Replays the original response when the same key is sent again",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Quote to execute",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ConvertRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Conversion completed successfully"
+                    },
+                    "400": {
+                        "description": "Bad request - validation error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - duplicate transaction, quote no longer available or Idempotency-Key in progress",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Insufficient funds or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/deposit": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a new PENDING transaction and returns the redirect URL to the Payment Service Provider (PSP) for payment completion.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Initiate a Deposit Transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Replays the original response when the same key is sent again",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Deposit initiation details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.DepositRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Deposit initiated successfully with PSP redirect URL",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "redirect_url": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - validation error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - duplicate transaction or Idempotency-Key in progress",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Insufficient funds, amount limit exceeded or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/payout-status": {
+            "post": {
+                "description": "Handles the status callback the Payment Service Provider (PSP) sends whenever a withdrawal payout changes status. A REJECTED or RETURNED payout refunds the wallet. Repeated and out-of-order callbacks are ignored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Update a payout's status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider that sent the callback, e.g. FakePay",
+                        "name": "X-PSP-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Unix time the callback was signed at",
+                        "name": "X-PSP-Timestamp",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique value per callback",
+                        "name": "X-PSP-Nonce",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Hex HMAC-SHA256 of timestamp.nonce.body with the provider's secret",
+                        "name": "X-PSP-Signature",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Payout status callback from PSP",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/psp.PayOutStatusCallback"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Payout status updated",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - unknown status or invalid transaction ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - invalid, stale or replayed callback signature",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No payout of this provider with that transaction ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - the payout cannot move to that status",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/refund": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns all or part of a completed deposit to the payer through the deposit's PSP. The refund is a new REFUND transaction related to the deposit; refunds of a deposit can never add up to more than its amount.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Refund a Deposit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Replays the original response when the same key is sent again",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Deposit to refund and optional partial amount",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RefundRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Refund completed",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransactionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - validation error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - deposit belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Deposit not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - duplicate transaction or Idempotency-Key in progress",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Not a completed deposit, refunds exceed the deposit amount or insufficient funds",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "PSP failed to make the refund",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/reverse": {
+            "post": {
+                "description": "Operator-initiated reversal of a completed transfer. Moves the amount back from the recipient to the sender as a new REVERSAL_OUT/REVERSAL_IN pair related to the transfer, and records the operator and reason in the status history.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Reverse a Transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Operator API key",
+                        "name": "X-Operator-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Transfer to reverse, by either of its transactions",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
                         "schema": {
-                            "type": "object"
+                            "$ref": "#/definitions/models.ReversalRequest"
                         }
                     }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The REVERSAL_IN transaction crediting the sender",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransactionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - validation error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid operator key",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Transfer not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - transfer already reversed or duplicate transaction",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Not a completed transfer or recipient balance too low",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/transfer": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Transfers funds from one user's wallet to another user's wallet atomically.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Transfer Funds Between Users",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Replays the original response when the same key is sent again",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Transfer details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transfer completed successfully"
+                    },
+                    "400": {
+                        "description": "Bad request - validation error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Recipient or wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - duplicate transaction or Idempotency-Key in progress",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Insufficient funds, amount limit exceeded or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/withdraw": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a new PENDING withdrawal transaction, deducts the amount from wallet balance, and asks the PSP to pay it out to one of the user's bank accounts.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "payments"
+                ],
+                "summary": "Initiate a Withdrawal Transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Replays the original response when the same key is sent again",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Withdrawal initiation details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.WithdrawRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Withdrawal initiated successfully"
+                    },
+                    "400": {
+                        "description": "Bad request - validation error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - bank account belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet or bank account not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - duplicate transaction or Idempotency-Key in progress",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Insufficient funds, amount limit exceeded or Idempotency-Key reused with a different request body",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scheduled-transfers": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the scheduled transfers of the authenticated user, the most recently created first",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "scheduled-transfers"
+                ],
+                "summary": "List scheduled transfers",
+                "responses": {
+                    "200": {
+                        "description": "Scheduled transfers",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ScheduledTransferResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Sets up a transfer from the authenticated user on start_date, once (ONCE) or repeated DAILY, WEEKLY (on the weekday of start_date), MONTHLY (on day_of_month, or the last day of shorter months) or on the LAST_BUSINESS_DAY of each month. A repeated schedule ends after end_date or after count occurrences, whichever comes first. Dates are YYYY-MM-DD in UTC; the occurrence of a day is made once the day has started. An occurrence that fails, such as for insufficient funds, is recorded as failed and notified with a scheduled_transfer.failed event; the schedule goes on.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "scheduled-transfers"
+                ],
+                "summary": "Schedule a transfer",
+                "parameters": [
+                    {
+                        "description": "Recipient, amount and recurrence",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateScheduledTransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Transfer scheduled",
+                        "schema": {
+                            "$ref": "#/definitions/models.ScheduledTransferResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid recurrence, dates, amount or recipient",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Recipient or sender wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scheduled-transfers/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Ends an active or paused scheduled transfer of the authenticated user for good",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "scheduled-transfers"
+                ],
+                "summary": "Cancel a scheduled transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scheduled transfer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Scheduled transfer canceled",
+                        "schema": {
+                            "$ref": "#/definitions/models.ScheduledTransferResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid scheduled transfer ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - another user's scheduled transfer",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Scheduled transfer not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - scheduled transfer has ended",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scheduled-transfers/{id}/occurrences": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the latest occurrences of a scheduled transfer of the authenticated user, the most recent first, with the transfer made for each and why it failed",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "scheduled-transfers"
+                ],
+                "summary": "List occurrences of a scheduled transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scheduled transfer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Occurrences",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ScheduledTransferOccurrenceResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid scheduled transfer ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - another user's scheduled transfer",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Scheduled transfer not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scheduled-transfers/{id}/pause": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stops an active scheduled transfer of the authenticated user. The occurrences that fall before it is resumed are skipped.",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "scheduled-transfers"
+                ],
+                "summary": "Pause a scheduled transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scheduled transfer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Scheduled transfer paused",
+                        "schema": {
+                            "$ref": "#/definitions/models.ScheduledTransferResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid scheduled transfer ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - another user's scheduled transfer",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Scheduled transfer not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - scheduled transfer is not active",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scheduled-transfers/{id}/resume": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Continues a paused scheduled transfer of the authenticated user with its first occurrence from today on",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "scheduled-transfers"
+                ],
+                "summary": "Resume a scheduled transfer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Scheduled transfer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Scheduled transfer resumed",
+                        "schema": {
+                            "$ref": "#/definitions/models.ScheduledTransferResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid scheduled transfer ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - another user's scheduled transfer",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Scheduled transfer not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - scheduled transfer is not paused",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/statements": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the statement of one wallet with the opening balance, every balance change with the running balance, the closing balance and totals per transaction type. Days count in UTC; both from and to are included. The statement is streamed as it is read.",
+                "produces": [
+                    "text/csv",
+                    "application/pdf",
+                    "application/x-ofx",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "statements"
+                ],
+                "summary": "Export an account statement",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "pdf",
+                            "ofx"
+                        ],
+                        "type": "string",
+                        "description": "Statement format",
+                        "name": "format",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "TWD",
+                        "description": "Wallet currency",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The statement",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid period or format",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Wallet not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/statements/monthly": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the monthly statements issued to the authenticated user, the latest first. A statement is issued for every wallet shortly after each calendar month (UTC) closes and never changes afterwards.",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "statements"
+                ],
+                "summary": "List monthly statements",
+                "responses": {
+                    "200": {
+                        "description": "Issued statements",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.IssuedStatementResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/statements/{id}/download": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the document of an issued monthly statement exactly as it was issued. Its SHA-256 is sent as the ETag.",
+                "produces": [
+                    "application/pdf",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "statements"
+                ],
+                "summary": "Download a monthly statement",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Statement ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The statement",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid statement ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - another user's statement",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Statement not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/stream": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Pushes the events of the authenticated user as Server-Sent Events: transaction.completed, transaction.canceled, transaction.failed, transfer.received, wallet.balance_changed and scheduled_transfer.failed. The event name is the event type and the data is the event as JSON, with its id for dropping duplicates. A comment is sent as a heartbeat every 15 seconds. A client that reconnects with the Last-Event-ID header first receives the events it missed; if it missed too many, it receives a \"reset\" event and should reload its balances. The stream may be closed at any time, after which the client reconnects.",
+                "produces": [
+                    "text/event-stream",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "stream"
+                ],
+                "summary": "Stream account events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the last event received",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event stream",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid Last-Event-ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns one page of the authenticated user's transactions, newest first by default. Pass next_cursor back as cursor to get the following page.",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get transaction history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Created at or after (RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before (RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Transaction types",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Transaction statuses",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Payment methods",
+                        "name": "payment_method",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Minimum amount, inclusive",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum amount, inclusive",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort order by creation time",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "One page of transactions",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransactionPageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid filter or cursor",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions/user/{user_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns all of the authenticated user's transactions of the last 6 months, newest first, in one unpaged list. Kept for existing clients; use GET /transactions instead.",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get user transactions",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID; must be the authenticated user",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of transactions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.TransactionResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid user ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - another user's transactions",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user": {
+            "post": {
+                "description": "Creates a new user with hashed password and an associated wallet with initial balance of 0",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Register a new user",
+                "parameters": [
+                    {
+                        "description": "User registration details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "User created successfully"
+                    },
+                    "400": {
+                        "description": "Bad request - validation error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - username already taken",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/login": {
+            "post": {
+                "description": "Authenticates a user with username and password, starts a new session, and sets the access and refresh token cookies",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "User Login",
+                "parameters": [
+                    {
+                        "description": "Login form data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User logged in successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request (e.g., invalid body or validation error)",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized (Invalid username or password)",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/logout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revokes the session of the access token, so that neither its access tokens nor its refresh token are accepted any more",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Log out",
+                "responses": {
+                    "204": {
+                        "description": "Logged out successfully"
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the active sessions (signed-in devices) of the authenticated user",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "List sessions",
+                "responses": {
+                    "200": {
+                        "description": "Active sessions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SessionResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/sessions/{session_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Signs out one of the authenticated user's devices",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Revoke a session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "session_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Session revoked successfully"
+                    },
+                    "400": {
+                        "description": "Bad request - invalid session ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/token/refresh": {
+            "post": {
+                "description": "Exchanges a refresh token, from the body or the refresh cookie, for a new access token and a new refresh token. A refresh token can only be used once; reusing one revokes its session.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Refresh access token",
+                "parameters": [
+                    {
+                        "description": "Refresh token",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.RefreshTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tokens rotated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.TokenResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - invalid, reused or revoked refresh token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/wallets": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Opens a new wallet with a zero balance in the given currency for the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Open a wallet",
+                "parameters": [
+                    {
+                        "description": "Wallet currency",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenWalletRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Wallet opened successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.WalletResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - unsupported currency or wallet already exists",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{user_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves the authenticated user's information including username and the balance of every wallet",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user information",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User information retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserInfoResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid user ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - not the authenticated user",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the webhook endpoints of the authenticated user, without their secrets",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhook endpoints",
+                "responses": {
+                    "200": {
+                        "description": "Registered endpoints",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookEndpointResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Registers a URL to receive the given events about the authenticated user's account: transaction.completed, transaction.canceled, transaction.failed, transfer.received, wallet.balance_changed and scheduled_transfer.failed. Each delivery is a POST of the event as JSON with the headers X-Webhook-ID (the event ID), X-Webhook-Event, X-Webhook-Timestamp and X-Webhook-Signature, the hex HMAC-SHA256 of \"timestamp.event_id.body\" with the endpoint's secret. The secret is only returned here. Deliveries that are not answered with 2xx are retried with exponential backoff and dead-lettered after the last attempt. An event may be delivered more than once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Register a webhook endpoint",
+                "parameters": [
+                    {
+                        "description": "Endpoint URL and events",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateWebhookEndpointRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Endpoint registered, with its secret",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookEndpointResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid URL or event type",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Too many endpoints",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/dead-letters": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the latest deliveries to the authenticated user's endpoints that failed on every attempt, the most recent first",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List dead-lettered deliveries",
+                "responses": {
+                    "200": {
+                        "description": "Dead-lettered deliveries",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookDeliveryResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/dead-letters/{id}/redeliver": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Sends a dead-lettered delivery again with the same event ID and body, retrying with backoff as for a new delivery",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Redeliver a dead-lettered delivery",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Delivery scheduled",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookDeliveryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid delivery ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - another user's delivery",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Delivery not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - delivery is not dead-lettered",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stops deliveries to an endpoint of the authenticated user. Deliveries still waiting to be sent to it are dead-lettered.",
+                "produces": [
+                    "application/json",
+                    "application/problem+json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete a webhook endpoint",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Endpoint ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Endpoint deleted"
+                    },
+                    "400": {
+                        "description": "Bad request - invalid endpoint ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing or invalid access token",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - another user's endpoint",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Endpoint not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProblemResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.AmountAllowance": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "remaining": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "used": {
+                    "$ref": "#/definitions/money.Money"
+                }
+            }
+        },
+        "models.BankAccountResponse": {
+            "type": "object",
+            "properties": {
+                "account_number": {
+                    "type": "string"
+                },
+                "bank_code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ConvertRequest": {
+            "type": "object",
+            "required": [
+                "quote_id",
+                "uuid"
+            ],
+            "properties": {
+                "quote_id": {
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CountAllowance": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "remaining": {
+                    "type": "integer"
+                },
+                "used": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CreateBankAccountRequest": {
+            "type": "object",
+            "required": [
+                "account_number",
+                "bank_code"
+            ],
+            "properties": {
+                "account_number": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "bank_code": {
+                    "type": "string",
+                    "maxLength": 50
+                }
+            }
+        },
+        "models.CreatePaymentRequestRequest": {
+            "type": "object",
+            "required": [
+                "payer_username"
+            ],
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "memo": {
+                    "type": "string",
+                    "maxLength": 140
+                },
+                "payer_username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateScheduledTransferRequest": {
+            "type": "object",
+            "required": [
+                "frequency",
+                "recipient_username",
+                "start_date"
+            ],
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "count": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "day_of_month": {
+                    "type": "integer",
+                    "maximum": 31,
+                    "minimum": 1
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "frequency": {
+                    "type": "string"
+                },
+                "recipient_username": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateWebhookEndpointRequest": {
+            "type": "object",
+            "required": [
+                "events",
+                "url"
+            ],
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string",
+                    "maxLength": 2048
+                }
+            }
+        },
+        "models.DepositRequest": {
+            "type": "object",
+            "required": [
+                "uuid"
+            ],
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "payment_method": {
+                    "type": "string"
+                },
+                "userID": {
+                    "type": "integer"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FXQuoteRequest": {
+            "type": "object",
+            "required": [
+                "from_currency",
+                "to_currency"
+            ],
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "from_currency": {
+                    "type": "string"
+                },
+                "to_currency": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FXQuoteResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "from_amount": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "from_currency": {
+                    "type": "string"
+                },
+                "quote_id": {
+                    "type": "string"
+                },
+                "rate": {
+                    "type": "string"
+                },
+                "to_amount": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "to_currency": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FeeQuoteRequest": {
+            "type": "object",
+            "required": [
+                "operation"
+            ],
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "operation": {
+                    "type": "string",
+                    "enum": [
+                        "DEPOSIT",
+                        "WITHDRAWAL",
+                        "TRANSFER"
+                    ]
+                },
+                "payment_method": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FeeQuoteResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "fee": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "operation": {
+                    "type": "string"
+                },
+                "total": {
+                    "description": "Total is what the wallet is debited for a withdrawal or transfer, or\ncredited for a deposit, once the fee is taken",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.IssuedStatementResponse": {
+            "type": "object",
+            "properties": {
+                "closing_balance": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "content_hash": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "issued_at": {
+                    "type": "string"
+                },
+                "month": {
+                    "type": "string"
+                },
+                "opening_balance": {
+                    "$ref": "#/definitions/money.Money"
+                }
+            }
+        },
+        "models.JournalEntryResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "postings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PostingResponse"
+                    }
+                },
+                "transaction_id": {
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LedgerDiscrepancy": {
+            "type": "object",
+            "properties": {
+                "account_code": {
+                    "type": "string"
+                },
+                "actual": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "expected": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LedgerReconciliationResponse": {
+            "type": "object",
+            "properties": {
+                "balanced": {
+                    "type": "boolean"
+                },
+                "discrepancies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LedgerDiscrepancy"
+                    }
+                },
+                "trial_balances": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/money.Money"
+                    }
+                }
+            }
+        },
+        "models.LimitOverrideRequest": {
+            "type": "object",
+            "required": [
+                "currency",
+                "reason",
+                "type"
+            ],
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "daily": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "daily_count": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "monthly": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "monthly_count": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "per_transaction_max": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "per_transaction_min": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "DEPOSIT",
+                        "WITHDRAWAL",
+                        "TRANSFER_OUT"
+                    ]
+                }
+            }
+        },
+        "models.LimitResponse": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "daily": {
+                    "$ref": "#/definitions/models.AmountAllowance"
+                },
+                "daily_count": {
+                    "$ref": "#/definitions/models.CountAllowance"
+                },
+                "monthly": {
+                    "$ref": "#/definitions/models.AmountAllowance"
+                },
+                "monthly_count": {
+                    "$ref": "#/definitions/models.CountAllowance"
+                },
+                "per_transaction_max": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "per_transaction_min": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.LoginRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OpenWalletRequest": {
+            "type": "object",
+            "required": [
+                "currency"
+            ],
+            "properties": {
+                "currency": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PaymentRequestResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "closed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "memo": {
+                    "type": "string"
+                },
+                "payer_username": {
+                    "type": "string"
+                },
+                "requester_username": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "transaction_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PostingResponse": {
+            "type": "object",
+            "properties": {
+                "account_code": {
+                    "type": "string"
+                },
+                "amount": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "currency": {
+                    "type": "string"
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.BankAccountResponse": {
+        },
+        "models.ProblemResponse": {
             "type": "object",
             "properties": {
-                "account_number": {
+                "code": {
                     "type": "string"
                 },
-                "bank_code": {
+                "correlation_id": {
                     "type": "string"
                 },
-                "created_at": {
+                "detail": {
                     "type": "string"
                 },
-                "id": {
+                "instance": {
+                    "type": "string"
+                },
+                "status": {
                     "type": "integer"
                 },
-                "updated_at": {
+                "title": {
                     "type": "string"
                 },
-                "user_id": {
-                    "type": "integer"
+                "type": {
+                    "type": "string"
                 }
             }
         },
-        "models.CreateBankAccountRequest": {
+        "models.RefreshTokenRequest": {
             "type": "object",
-            "required": [
-                "account_number",
-                "bank_code"
-            ],
             "properties": {
-                "account_number": {
-                    "type": "string",
-                    "maxLength": 100
-                },
-                "bank_code": {
-                    "type": "string",
-                    "maxLength": 50
+                "refresh_token": {
+                    "description": "Optional when the refresh token is sent as a cookie",
+                    "type": "string"
                 }
             }
         },
-        "models.DepositRequest": {
+        "models.RefundRequest": {
             "type": "object",
             "required": [
-                "amount",
+                "transaction_id",
                 "uuid"
             ],
             "properties": {
                 "amount": {
-                    "type": "number"
+                    "$ref": "#/definitions/money.Money"
                 },
-                "payment_method": {
+                "transaction_id": {
                     "type": "string"
                 },
                 "userID": {
@@ -687,21 +3599,6 @@ const docTemplate = `{
                 }
             }
         },
-        "models.LoginRequest": {
-            "type": "object",
-            "required": [
-                "password",
-                "username"
-            ],
-            "properties": {
-                "password": {
-                    "type": "string"
-                },
-                "username": {
-                    "type": "string"
-                }
-            }
-        },
         "models.RegisterRequest": {
             "type": "object",
             "required": [
@@ -724,18 +3621,183 @@ const docTemplate = `{
                 }
             }
         },
+        "models.ReversalRequest": {
+            "type": "object",
+            "required": [
+                "reason",
+                "transaction_id",
+                "uuid"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "transaction_id": {
+                    "type": "string"
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ScheduledTransferOccurrenceResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "failure_code": {
+                    "type": "string"
+                },
+                "failure_reason": {
+                    "type": "string"
+                },
+                "run_on": {
+                    "type": "string"
+                },
+                "sequence": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "transaction_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ScheduledTransferResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "day_of_month": {
+                    "type": "integer"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "frequency": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "next_run_on": {
+                    "type": "string"
+                },
+                "occurrences": {
+                    "type": "integer"
+                },
+                "recipient_username": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SessionResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "current": {
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "ip_address": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TokenResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "type": "integer"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TransactionPageResponse": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "type": "string"
+                },
+                "transactions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TransactionResponse"
+                    }
+                }
+            }
+        },
         "models.TransactionResponse": {
             "type": "object",
             "properties": {
                 "amount": {
-                    "type": "number"
+                    "$ref": "#/definitions/money.Money"
                 },
                 "created_at": {
                     "type": "string"
                 },
+                "currency": {
+                    "type": "string"
+                },
+                "fee": {
+                    "description": "Fee is the fee charged for the transaction, collected by the FEE\ntransaction that relates to it",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/money.Money"
+                        }
+                    ]
+                },
                 "payment_method": {
                     "type": "string"
                 },
+                "payout_status": {
+                    "type": "string"
+                },
+                "related_transaction_id": {
+                    "description": "RelatedTransactionID links a transfer to its counterpart and a refund or\nreversal to the transaction it undoes",
+                    "type": "string"
+                },
                 "status": {
                     "type": "string"
                 },
@@ -750,12 +3812,14 @@ const docTemplate = `{
         "models.TransferRequest": {
             "type": "object",
             "required": [
-                "amount",
                 "recipient_username"
             ],
             "properties": {
                 "amount": {
-                    "type": "number"
+                    "$ref": "#/definitions/money.Money"
+                },
+                "currency": {
+                    "type": "string"
                 },
                 "recipient_username": {
                     "type": "string"
@@ -788,27 +3852,111 @@ const docTemplate = `{
         "models.UserInfoResponse": {
             "type": "object",
             "properties": {
-                "balance": {
-                    "type": "number"
+                "tier": {
+                    "type": "string"
                 },
                 "username": {
                     "type": "string"
+                },
+                "wallets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WalletResponse"
+                    }
+                }
+            }
+        },
+        "models.WalletResponse": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "$ref": "#/definitions/money.Money"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.WebhookDeliveryResponse": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "endpoint_id": {
+                    "type": "integer"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_attempt_at": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_status_code": {
+                    "type": "integer"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookEndpointResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
                 }
             }
         },
         "models.WithdrawRequest": {
             "type": "object",
             "required": [
-                "amount",
+                "bank_account_id",
                 "payment_method"
             ],
             "properties": {
                 "amount": {
-                    "type": "number"
+                    "$ref": "#/definitions/money.Money"
                 },
                 "bank_account_id": {
                     "type": "integer"
                 },
+                "currency": {
+                    "type": "string"
+                },
                 "payment_method": {
                     "type": "string"
                 },
@@ -820,6 +3968,9 @@ const docTemplate = `{
                 }
             }
         },
+        "money.Money": {
+            "type": "object"
+        },
         "psp.CancelRequest": {
             "type": "object",
             "required": [
@@ -831,17 +3982,46 @@ const docTemplate = `{
                 }
             }
         },
-        "psp.PayInResponse": {
+        "psp.ConfirmRequest": {
+            "type": "object",
+            "required": [
+                "transaction_id"
+            ],
+            "properties": {
+                "transaction_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "psp.PayOutStatusCallback": {
             "type": "object",
+            "required": [
+                "status",
+                "transaction_id"
+            ],
             "properties": {
-                "redirectUrl": {
+                "reason": {
                     "type": "string"
                 },
-                "transactionID": {
+                "reference": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "transaction_id": {
                     "type": "string"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "Access token from /user/login, sent as \"Bearer \u003ctoken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -0,0 +1,60 @@
+package entities
+
+import (
+	"banking-system/money"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type FXQuoteStatus string
+
+var FXQuoteStatuses = &struct {
+	Open     FXQuoteStatus
+	Executed FXQuoteStatus
+}{
+	Open:     "OPEN",
+	Executed: "EXECUTED",
+}
+
+// FXQuote locks a conversion rate for a user until ExpiresAt.
+type FXQuote struct {
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	UUID         uuid.UUID     `gorm:"type:uuid;primaryKey;not null"`
+	UserID       uint          `gorm:"index;not null"`
+	FromCurrency string        `gorm:"type:varchar(3);not null"`
+	ToCurrency   string        `gorm:"type:varchar(3);not null"`
+	FromAmount   money.Money   `gorm:"type:numeric(18,4);not null"`
+	ToAmount     money.Money   `gorm:"type:numeric(18,4);not null"`
+	Rate         string        `gorm:"type:numeric(20,10);not null"`
+	Status       FXQuoteStatus `gorm:"type:varchar(20);not null"`
+	ExpiresAt    time.Time     `gorm:"not null"`
+}
+
+func (q *FXQuote) AfterFind(*gorm.DB) error {
+	q.FromAmount = q.FromAmount.WithCurrency(q.FromCurrency)
+	q.ToAmount = q.ToAmount.WithCurrency(q.ToCurrency)
+	return nil
+}
+
+func (q *FXQuote) IsExpired(now time.Time) bool {
+	return !now.Before(q.ExpiresAt)
+}
+
+// NewConversionEntries books a conversion as one balanced entry per currency,
+// with the FX position accounts absorbing each side.
+func NewConversionEntries(conversionOutTx *Transaction, conversionInTx *Transaction) []*JournalEntry {
+	return []*JournalEntry{
+		NewJournalEntry(&conversionOutTx.UUID, "Currency conversion",
+			NewPosting(WalletAccount(conversionOutTx.Wallet), conversionOutTx.Amount.Neg()),
+			NewPosting(FXPositionAccount(conversionOutTx.Amount.Currency()), conversionOutTx.Amount),
+		),
+		NewJournalEntry(&conversionInTx.UUID, "Currency conversion",
+			NewPosting(FXPositionAccount(conversionInTx.Amount.Currency()), conversionInTx.Amount.Neg()),
+			NewPosting(WalletAccount(conversionInTx.Wallet), conversionInTx.Amount),
+		),
+	}
+}
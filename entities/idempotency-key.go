@@ -0,0 +1,34 @@
+package entities
+
+import "time"
+
+type IdempotencyKeyStatus string
+
+var IdempotencyKeyStatuses = &struct {
+	InProgress IdempotencyKeyStatus
+	Completed  IdempotencyKeyStatus
+}{
+	InProgress: "IN_PROGRESS",
+	Completed:  "COMPLETED",
+}
+
+// IdempotencyKey records the outcome of a mutating request so that retries
+// with the same Idempotency-Key header replay the original response. Keys are
+// scoped to the caller that sent them.
+type IdempotencyKey struct {
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Key                 string               `gorm:"type:varchar(255);primaryKey;not null"`
+	Owner               string               `gorm:"type:varchar(64);primaryKey;not null"`
+	Fingerprint         string               `gorm:"type:char(64);not null"`
+	Status              IdempotencyKeyStatus `gorm:"type:varchar(20);not null"`
+	ResponseStatus      int
+	ResponseContentType string `gorm:"type:varchar(255)"`
+	ResponseBody        []byte
+	ExpiresAt           time.Time `gorm:"index;not null"` // The end of the lease while IN_PROGRESS
+}
+
+func (k *IdempotencyKey) IsExpired(now time.Time) bool {
+	return !now.Before(k.ExpiresAt)
+}
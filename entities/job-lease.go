@@ -0,0 +1,12 @@
+package entities
+
+import "time"
+
+// JobLease marks the instance that currently runs a background job. An
+// instance may only run the job while it holds an unexpired lease, so that of
+// several replicas only one works on the same rows at a time.
+type JobLease struct {
+	Name      string    `gorm:"type:varchar(100);primaryKey;not null"`
+	Holder    string    `gorm:"type:varchar(255);not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,177 @@
+package entities
+
+import (
+	"banking-system/money"
+	"banking-system/psp"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type LedgerAccountType string
+
+var LedgerAccountTypes = &struct {
+	Wallet      LedgerAccountType
+	PSPClearing LedgerAccountType
+	Fees        LedgerAccountType
+	Suspense    LedgerAccountType
+	FXPosition  LedgerAccountType
+}{
+	Wallet:      "WALLET",
+	PSPClearing: "PSP_CLEARING",
+	Fees:        "FEES",
+	Suspense:    "SUSPENSE",
+	FXPosition:  "FX_POSITION",
+}
+
+// LedgerAccount is a general ledger account. Its balance is the running sum of
+// all postings against it and is only ever changed by posting a JournalEntry.
+type LedgerAccount struct {
+	gorm.Model
+	Code     string            `gorm:"type:varchar(100);uniqueIndex;not null"`
+	Type     LedgerAccountType `gorm:"type:varchar(20);not null"`
+	Currency string            `gorm:"type:varchar(3);not null"`
+	Balance  money.Money       `gorm:"type:numeric(18,4);not null"`
+
+	WalletID *uint `gorm:"uniqueIndex"`
+}
+
+func (account *LedgerAccount) AfterFind(*gorm.DB) error {
+	account.Balance = account.Balance.WithCurrency(account.Currency)
+	return nil
+}
+
+// JournalEntry is an immutable, balanced set of postings. Every change of a
+// wallet balance is recorded as exactly one journal entry.
+type JournalEntry struct {
+	CreatedAt time.Time
+
+	UUID          uuid.UUID  `gorm:"type:uuid;primaryKey;not null"`
+	TransactionID *uuid.UUID `gorm:"type:uuid;index"`
+	Description   string     `gorm:"type:varchar(255)"`
+
+	Postings []Posting `gorm:"foreignKey:JournalEntryID;references:UUID"`
+}
+
+// Posting moves Amount into (positive) or out of (negative) a ledger account.
+type Posting struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+
+	JournalEntryID  uuid.UUID `gorm:"type:uuid;index;not null"`
+	LedgerAccountID uint      `gorm:"index;not null"`
+	LedgerAccount   *LedgerAccount
+	Amount          money.Money `gorm:"type:numeric(18,4);not null"`
+	Currency        string      `gorm:"type:varchar(3);not null"`
+}
+
+func (p *Posting) BeforeCreate(*gorm.DB) error {
+	p.Currency = p.Amount.Currency()
+	return nil
+}
+
+func (p *Posting) AfterFind(*gorm.DB) error {
+	p.Amount = p.Amount.WithCurrency(p.Currency)
+	return nil
+}
+
+var ErrImmutableLedger = errors.New("journal entries and postings are immutable")
+
+func (*JournalEntry) BeforeUpdate(*gorm.DB) error { return ErrImmutableLedger }
+func (*JournalEntry) BeforeDelete(*gorm.DB) error { return ErrImmutableLedger }
+func (*Posting) BeforeUpdate(*gorm.DB) error      { return ErrImmutableLedger }
+func (*Posting) BeforeDelete(*gorm.DB) error      { return ErrImmutableLedger }
+
+func WalletAccountCode(walletID uint) string {
+	return fmt.Sprintf("wallet:%d", walletID)
+}
+
+func WalletAccount(wallet *Wallet) *LedgerAccount {
+	return &LedgerAccount{
+		Code:     WalletAccountCode(wallet.ID),
+		Type:     LedgerAccountTypes.Wallet,
+		Currency: wallet.Currency,
+		WalletID: &wallet.ID,
+	}
+}
+
+func PSPClearingAccount(paymentMethod psp.PaymentMethod, currency string) *LedgerAccount {
+	return &LedgerAccount{
+		Code:     fmt.Sprintf("psp_clearing:%s:%s", paymentMethod, currency),
+		Type:     LedgerAccountTypes.PSPClearing,
+		Currency: currency,
+	}
+}
+
+// FeesAccount collects fees in a currency. It is the ledger account of the
+// house fee wallet for that currency, which is bound when an entry is posted.
+func FeesAccount(currency string) *LedgerAccount {
+	return &LedgerAccount{
+		Code:     fmt.Sprintf("fees:%s", currency),
+		Type:     LedgerAccountTypes.Fees,
+		Currency: currency,
+	}
+}
+
+func SuspenseAccount(currency string) *LedgerAccount {
+	return &LedgerAccount{
+		Code:     fmt.Sprintf("suspense:%s", currency),
+		Type:     LedgerAccountTypes.Suspense,
+		Currency: currency,
+	}
+}
+
+// FXPositionAccount holds the house position in a currency built up by
+// currency conversions.
+func FXPositionAccount(currency string) *LedgerAccount {
+	return &LedgerAccount{
+		Code:     fmt.Sprintf("fx_position:%s", currency),
+		Type:     LedgerAccountTypes.FXPosition,
+		Currency: currency,
+	}
+}
+
+func NewPosting(account *LedgerAccount, amount money.Money) Posting {
+	return Posting{
+		LedgerAccount: account,
+		Amount:        amount,
+	}
+}
+
+func NewJournalEntry(transactionID *uuid.UUID, description string, postings ...Posting) *JournalEntry {
+	return &JournalEntry{
+		UUID:          uuid.New(),
+		TransactionID: transactionID,
+		Description:   description,
+		Postings:      postings,
+	}
+}
+
+// Validate checks the double-entry invariant: at least two postings in a
+// single currency whose amounts sum to zero.
+func (entry *JournalEntry) Validate() error {
+	if len(entry.Postings) < 2 {
+		return fmt.Errorf("journal entry '%s' must have at least two postings", entry.UUID)
+	}
+
+	sum := money.Zero(entry.Postings[0].LedgerAccount.Currency)
+	for _, p := range entry.Postings {
+		if p.Amount.Currency() != p.LedgerAccount.Currency {
+			return fmt.Errorf("journal entry '%s' posts %s to %s account '%s'", entry.UUID, p.Amount.Currency(), p.LedgerAccount.Currency, p.LedgerAccount.Code)
+		}
+
+		var err error
+		if sum, err = sum.Add(p.Amount); err != nil {
+			return fmt.Errorf("journal entry '%s': %w", entry.UUID, err)
+		}
+	}
+
+	if !sum.IsZero() {
+		return fmt.Errorf("journal entry '%s' is unbalanced by %s", entry.UUID, sum)
+	}
+
+	return nil
+}
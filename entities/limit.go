@@ -0,0 +1,166 @@
+package entities
+
+import (
+	"banking-system/money"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TransactionLimits bound the transactions of one type a user makes in one
+// currency. A nil limit does not apply.
+type TransactionLimits struct {
+	PerTransactionMin *money.Money
+	PerTransactionMax *money.Money
+	Daily             *money.Money
+	Monthly           *money.Money
+	DailyCount        *int64
+	MonthlyCount      *int64
+}
+
+// Override returns the limits with each limit that o sets replaced.
+func (l TransactionLimits) Override(o TransactionLimits) TransactionLimits {
+	if o.PerTransactionMin != nil {
+		l.PerTransactionMin = o.PerTransactionMin
+	}
+	if o.PerTransactionMax != nil {
+		l.PerTransactionMax = o.PerTransactionMax
+	}
+	if o.Daily != nil {
+		l.Daily = o.Daily
+	}
+	if o.Monthly != nil {
+		l.Monthly = o.Monthly
+	}
+	if o.DailyCount != nil {
+		l.DailyCount = o.DailyCount
+	}
+	if o.MonthlyCount != nil {
+		l.MonthlyCount = o.MonthlyCount
+	}
+	return l
+}
+
+// HasPeriodicLimits reports whether a daily or monthly limit applies.
+func (l TransactionLimits) HasPeriodicLimits() bool {
+	return l.Daily != nil || l.Monthly != nil || l.DailyCount != nil || l.MonthlyCount != nil
+}
+
+// LimitUsage is what a user already used of their limits: the amount moved
+// from one wallet and the number of transactions across all their wallets.
+type LimitUsage struct {
+	Daily        money.Money
+	Monthly      money.Money
+	DailyCount   int64
+	MonthlyCount int64
+}
+
+// LimitExceededError explains which limit a transaction would exceed. Code
+// is one of amount_below_minimum, amount_above_maximum, daily_limit_exceeded,
+// monthly_limit_exceeded, daily_count_exceeded or monthly_count_exceeded.
+type LimitExceededError struct {
+	Code    string
+	Message string
+}
+
+func (e *LimitExceededError) Error() string {
+	return e.Message
+}
+
+// CheckAmount checks amount against the per-transaction limits.
+func (l TransactionLimits) CheckAmount(amount money.Money) error {
+	if l.PerTransactionMin != nil && amount.LessThan(*l.PerTransactionMin) {
+		return &LimitExceededError{"amount_below_minimum", fmt.Sprintf("amount %s is below minimum allowed amount %s", amount, *l.PerTransactionMin)}
+	}
+	if l.PerTransactionMax != nil && amount.GreaterThan(*l.PerTransactionMax) {
+		return &LimitExceededError{"amount_above_maximum", fmt.Sprintf("amount %s exceeds maximum allowed amount %s", amount, *l.PerTransactionMax)}
+	}
+	return nil
+}
+
+// CheckUsage checks whether one more transaction of amount fits in the
+// periodic limits, given what was already used.
+func (l TransactionLimits) CheckUsage(amount money.Money, usage LimitUsage) error {
+	if l.Daily != nil {
+		if total, err := usage.Daily.Add(amount); err != nil || total.GreaterThan(*l.Daily) {
+			return &LimitExceededError{"daily_limit_exceeded", fmt.Sprintf("amount %s exceeds the remaining daily limit: %s of %s used", amount, usage.Daily, *l.Daily)}
+		}
+	}
+	if l.Monthly != nil {
+		if total, err := usage.Monthly.Add(amount); err != nil || total.GreaterThan(*l.Monthly) {
+			return &LimitExceededError{"monthly_limit_exceeded", fmt.Sprintf("amount %s exceeds the remaining monthly limit: %s of %s used", amount, usage.Monthly, *l.Monthly)}
+		}
+	}
+	if l.DailyCount != nil && usage.DailyCount >= *l.DailyCount {
+		return &LimitExceededError{"daily_count_exceeded", fmt.Sprintf("daily limit of %d transactions reached", *l.DailyCount)}
+	}
+	if l.MonthlyCount != nil && usage.MonthlyCount >= *l.MonthlyCount {
+		return &LimitExceededError{"monthly_count_exceeded", fmt.Sprintf("monthly limit of %d transactions reached", *l.MonthlyCount)}
+	}
+	return nil
+}
+
+// LimitPeriods are the starts of the day and month that periodic limits are
+// counted in. Limits reset at midnight UTC.
+type LimitPeriods struct {
+	DayStart   time.Time
+	MonthStart time.Time
+}
+
+func LimitPeriodsAt(now time.Time) LimitPeriods {
+	now = now.UTC()
+	return LimitPeriods{
+		DayStart:   time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		MonthStart: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// LimitCheck is checked when the transaction it belongs to is created, while
+// the user's other transactions of the same type are locked out, so that
+// concurrent transactions cannot together exceed the periodic limits.
+type LimitCheck struct {
+	UserID  uint
+	Limits  TransactionLimits
+	Periods LimitPeriods
+}
+
+// LimitOverride replaces limits of one user, set by an operator. Its nil
+// limits keep those of the user's tier.
+type LimitOverride struct {
+	gorm.Model
+	UserID   uint            `gorm:"not null;uniqueIndex:idx_limit_overrides_key"`
+	Type     TransactionType `gorm:"type:varchar(20);not null;uniqueIndex:idx_limit_overrides_key"`
+	Currency string          `gorm:"type:varchar(3);not null;uniqueIndex:idx_limit_overrides_key"`
+
+	PerTransactionMin *money.Money `gorm:"type:numeric(18,4)"`
+	PerTransactionMax *money.Money `gorm:"type:numeric(18,4)"`
+	Daily             *money.Money `gorm:"type:numeric(18,4)"`
+	Monthly           *money.Money `gorm:"type:numeric(18,4)"`
+	DailyCount        *int64
+	MonthlyCount      *int64
+
+	UpdatedBy string `gorm:"type:varchar(100);not null"`
+	Reason    string `gorm:"type:varchar(255)"`
+}
+
+func (o *LimitOverride) AfterFind(*gorm.DB) error {
+	for _, amount := range []*money.Money{o.PerTransactionMin, o.PerTransactionMax, o.Daily, o.Monthly} {
+		if amount != nil {
+			*amount = amount.WithCurrency(o.Currency)
+		}
+	}
+	return nil
+}
+
+// Limits returns the limits the override sets.
+func (o *LimitOverride) Limits() TransactionLimits {
+	return TransactionLimits{
+		PerTransactionMin: o.PerTransactionMin,
+		PerTransactionMax: o.PerTransactionMax,
+		Daily:             o.Daily,
+		Monthly:           o.Monthly,
+		DailyCount:        o.DailyCount,
+		MonthlyCount:      o.MonthlyCount,
+	}
+}
@@ -0,0 +1,189 @@
+package entities
+
+import (
+	"banking-system/money"
+	"banking-system/psp"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EventType string
+
+var EventTypes = &struct {
+	TransactionCompleted    EventType
+	TransactionCanceled     EventType
+	TransactionFailed       EventType
+	TransferReceived        EventType
+	WalletBalanceChanged    EventType
+	ScheduledTransferFailed EventType
+}{
+	TransactionCompleted:    "transaction.completed",
+	TransactionCanceled:     "transaction.canceled",
+	TransactionFailed:       "transaction.failed",
+	TransferReceived:        "transfer.received",
+	WalletBalanceChanged:    "wallet.balance_changed",
+	ScheduledTransferFailed: "scheduled_transfer.failed",
+}
+
+// AllEventTypes lists every event written to the outbox.
+var AllEventTypes = []EventType{
+	EventTypes.TransactionCompleted,
+	EventTypes.TransactionCanceled,
+	EventTypes.TransactionFailed,
+	EventTypes.TransferReceived,
+	EventTypes.WalletBalanceChanged,
+	EventTypes.ScheduledTransferFailed,
+}
+
+func (t EventType) IsValid() bool {
+	return slices.Contains(AllEventTypes, t)
+}
+
+// statusEventTypes are the events of transactions that reach a final status.
+var statusEventTypes = map[TransactionStatus]EventType{
+	TransactionStatuses.Completed: EventTypes.TransactionCompleted,
+	TransactionStatuses.Canceled:  EventTypes.TransactionCanceled,
+	TransactionStatuses.Failed:    EventTypes.TransactionFailed,
+}
+
+// OutboxEvent is a domain event written in the same database transaction as
+// the change it describes, so that it is stored if and only if the change
+// commits. The relay publishes the events of a wallet in the order of their
+// IDs, and at least once; UUID identifies an event to receivers that drop
+// duplicates.
+type OutboxEvent struct {
+	ID        uint `gorm:"primaryKey;index:idx_outbox_events_unpublished,where:published_at IS NULL"`
+	CreatedAt time.Time
+
+	UUID     uuid.UUID `gorm:"type:uuid;uniqueIndex;not null"`
+	Type     EventType `gorm:"type:varchar(50);not null"`
+	UserID   uint      `gorm:"not null"`
+	WalletID uint      `gorm:"not null;index"`
+
+	// Payload is the JSON of Data, encoded when the event is created
+	Payload string `gorm:"type:text;not null"`
+	Data    any    `gorm:"-"`
+
+	PublishedAt *time.Time
+	Attempts    int    `gorm:"not null;default:0"`
+	LastError   string `gorm:"type:varchar(500)"`
+}
+
+func (e *OutboxEvent) BeforeCreate(*gorm.DB) error {
+	if e.Payload != "" {
+		return nil
+	}
+
+	payload, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+	e.Payload = string(payload)
+	return nil
+}
+
+// TransactionEventData is the data of the events about a transaction.
+type TransactionEventData struct {
+	UUID                 uuid.UUID         `json:"uuid"`
+	Type                 TransactionType   `json:"type"`
+	Status               TransactionStatus `json:"status"`
+	Amount               money.Money       `json:"amount"`
+	Currency             string            `json:"currency"`
+	PaymentMethod        string            `json:"payment_method,omitempty"`
+	PayOutStatus         psp.PayOutStatus  `json:"payout_status,omitempty"`
+	CreatedAt            time.Time         `json:"created_at"`
+	Fee                  *money.Money      `json:"fee,omitempty"`
+	RelatedTransactionID *uuid.UUID        `json:"related_transaction_id,omitempty"`
+}
+
+// BalanceEventData is the data of a wallet.balance_changed event. Balance is
+// the balance of the wallet right after the change. TransactionID is the
+// transaction that changed it, unless it was a manual ledger posting.
+type BalanceEventData struct {
+	WalletID      uint        `json:"wallet_id"`
+	Currency      string      `json:"currency"`
+	Balance       money.Money `json:"balance"`
+	TransactionID *uuid.UUID  `json:"transaction_id,omitempty"`
+}
+
+// Events returns the events of a transaction that was just created, or that
+// just moved to its current status. Fees and pending transactions have none;
+// the wallet.balance_changed events of a change come from its journal entries.
+func (tx *Transaction) Events() []*OutboxEvent {
+	if tx.Type == TransactionTypes.Fee {
+		return nil
+	}
+
+	eventType, ok := statusEventTypes[tx.Status]
+	if !ok {
+		return nil
+	}
+
+	events := []*OutboxEvent{tx.newEvent(eventType)}
+	if tx.Type == TransactionTypes.TransferIn && tx.Status == TransactionStatuses.Completed {
+		events = append(events, tx.newEvent(EventTypes.TransferReceived))
+	}
+	return events
+}
+
+func (tx *Transaction) newEvent(eventType EventType) *OutboxEvent {
+	data := &TransactionEventData{
+		UUID:                 tx.UUID,
+		Type:                 tx.Type,
+		Status:               tx.Status,
+		Amount:               tx.Amount,
+		Currency:             tx.Amount.Currency(),
+		PaymentMethod:        string(tx.PaymentMethod),
+		PayOutStatus:         tx.PayOutStatus,
+		CreatedAt:            tx.CreatedAt,
+		RelatedTransactionID: tx.RelatedTransactionID,
+	}
+	if data.Currency == "" {
+		data.Currency = tx.Currency
+	}
+	if tx.HasFee() {
+		fee := tx.FeeAmount
+		data.Fee = &fee
+	}
+
+	return &OutboxEvent{
+		UUID:     uuid.New(),
+		Type:     eventType,
+		WalletID: tx.WalletID,
+		Data:     data,
+	}
+}
+
+// NewBalanceChangedEvent records the balance of a wallet after transactionID
+// posted to it. The wallet must be read in the same database transaction.
+func NewBalanceChangedEvent(wallet *Wallet, transactionID *uuid.UUID) *OutboxEvent {
+	return &OutboxEvent{
+		UUID:     uuid.New(),
+		Type:     EventTypes.WalletBalanceChanged,
+		UserID:   wallet.UserID,
+		WalletID: wallet.ID,
+		Data: &BalanceEventData{
+			WalletID:      wallet.ID,
+			Currency:      wallet.Currency,
+			Balance:       wallet.Balance,
+			TransactionID: transactionID,
+		},
+	}
+}
+
+// Entries returns the journal entries a status change posts, including those
+// of the changes linked to it.
+func (t *StatusTransition) Entries() []*JournalEntry {
+	var entries []*JournalEntry
+	if t.Entry != nil {
+		entries = append(entries, t.Entry)
+	}
+	for _, linked := range t.Linked {
+		entries = append(entries, linked.Entries()...)
+	}
+	return entries
+}
@@ -0,0 +1,76 @@
+package entities
+
+import (
+	"banking-system/money"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PaymentRequestStatus string
+
+var PaymentRequestStatuses = &struct {
+	Pending  PaymentRequestStatus
+	Accepted PaymentRequestStatus
+	Declined PaymentRequestStatus
+	Expired  PaymentRequestStatus
+}{
+	Pending:  "PENDING",
+	Accepted: "ACCEPTED",
+	Declined: "DECLINED",
+	Expired:  "EXPIRED",
+}
+
+func (s PaymentRequestStatus) IsValid() bool {
+	switch s {
+	case PaymentRequestStatuses.Pending, PaymentRequestStatuses.Accepted,
+		PaymentRequestStatuses.Declined, PaymentRequestStatuses.Expired:
+		return true
+	}
+	return false
+}
+
+// PaymentRequest is a request from one user to another to be paid an amount.
+// The payer accepts it by transferring the amount, or declines it; a request
+// neither accepted nor declined by ExpiresAt expires. Usernames are kept so
+// that both sides can be listed without loading the other user.
+type PaymentRequest struct {
+	CreatedAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+
+	UUID              uuid.UUID   `gorm:"type:uuid;primaryKey;not null"`
+	RequesterID       uint        `gorm:"index;not null"`
+	RequesterUsername string      `gorm:"type:varchar(20);not null"`
+	PayerID           uint        `gorm:"index;not null"`
+	PayerUsername     string      `gorm:"type:varchar(20);not null"`
+	Amount            money.Money `gorm:"type:numeric(18,4);not null"`
+	Currency          string      `gorm:"type:varchar(3);not null"`
+	Memo              string      `gorm:"type:varchar(140)"`
+
+	Status    PaymentRequestStatus `gorm:"type:varchar(20);not null;index"`
+	ExpiresAt time.Time            `gorm:"not null"`
+	ClosedAt  *time.Time
+
+	// TransactionID is the payer's TRANSFER_OUT that paid an accepted request
+	TransactionID *uuid.UUID `gorm:"type:uuid"`
+}
+
+func (r *PaymentRequest) BeforeSave(*gorm.DB) error {
+	if r.Amount.Currency() != "" {
+		r.Currency = r.Amount.Currency()
+	}
+	return nil
+}
+
+func (r *PaymentRequest) AfterFind(*gorm.DB) error {
+	r.Amount = r.Amount.WithCurrency(r.Currency)
+	return nil
+}
+
+// IsOpen reports whether the request can still be accepted or declined at
+// now. A pending request past its expiry is only closed by the expiry job, so
+// it is not open even though it is still PENDING.
+func (r *PaymentRequest) IsOpen(now time.Time) bool {
+	return r.Status == PaymentRequestStatuses.Pending && now.Before(r.ExpiresAt)
+}
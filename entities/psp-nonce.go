@@ -0,0 +1,13 @@
+package entities
+
+import "time"
+
+// PSPNonce records a nonce a payment service provider signed a callback with,
+// so that the callback is accepted only once across all instances. A nonce
+// only needs to be kept until the callback's timestamp would be rejected as
+// stale anyway.
+type PSPNonce struct {
+	Provider  string    `gorm:"type:varchar(64);primaryKey;not null"`
+	Nonce     string    `gorm:"type:varchar(128);primaryKey;not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
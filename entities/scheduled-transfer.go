@@ -0,0 +1,310 @@
+package entities
+
+import (
+	"banking-system/money"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ScheduleFrequency string
+
+var ScheduleFrequencies = &struct {
+	Once            ScheduleFrequency
+	Daily           ScheduleFrequency
+	Weekly          ScheduleFrequency
+	Monthly         ScheduleFrequency
+	LastBusinessDay ScheduleFrequency
+}{
+	Once:            "ONCE",
+	Daily:           "DAILY",
+	Weekly:          "WEEKLY",
+	Monthly:         "MONTHLY",
+	LastBusinessDay: "LAST_BUSINESS_DAY",
+}
+
+func (f ScheduleFrequency) IsValid() bool {
+	switch f {
+	case ScheduleFrequencies.Once, ScheduleFrequencies.Daily, ScheduleFrequencies.Weekly,
+		ScheduleFrequencies.Monthly, ScheduleFrequencies.LastBusinessDay:
+		return true
+	}
+	return false
+}
+
+type ScheduledTransferStatus string
+
+var ScheduledTransferStatuses = &struct {
+	Active    ScheduledTransferStatus
+	Paused    ScheduledTransferStatus
+	Canceled  ScheduledTransferStatus
+	Completed ScheduledTransferStatus
+}{
+	Active:    "ACTIVE",
+	Paused:    "PAUSED",
+	Canceled:  "CANCELED",
+	Completed: "COMPLETED",
+}
+
+var ErrScheduleNotActive = errors.New("scheduled transfer is not active")
+
+// ScheduledTransfer is a transfer a user set up to be made on a date, or
+// repeatedly. Dates are days in UTC; the occurrence of a day is made once the
+// day has started. A schedule ends after EndDate or after Count occurrences,
+// whichever comes first, and never if neither is set. Occurrences missed while
+// the schedule was not executed, for example while the service was down, are
+// skipped: an overdue schedule only makes its latest due occurrence.
+type ScheduledTransfer struct {
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	UUID              uuid.UUID   `gorm:"type:uuid;primaryKey;not null"`
+	UserID            uint        `gorm:"index;not null"`
+	WalletID          uint        `gorm:"not null"` // The sender's wallet in Currency
+	RecipientUsername string      `gorm:"type:varchar(255);not null"`
+	Amount            money.Money `gorm:"type:numeric(18,4);not null"`
+	Currency          string      `gorm:"type:varchar(3);not null"`
+
+	Frequency  ScheduleFrequency `gorm:"type:varchar(20);not null"`
+	StartDate  time.Time         `gorm:"type:date;not null"`
+	DayOfMonth int               `gorm:"not null;default:0"` // MONTHLY only; clamped to the last day of shorter months
+	EndDate    *time.Time        `gorm:"type:date"`
+	Count      *int
+
+	Status      ScheduledTransferStatus `gorm:"type:varchar(20);not null"`
+	NextRunOn   *time.Time              `gorm:"type:date;index"` // Nil once the schedule has ended
+	Occurrences int                     `gorm:"not null;default:0"`
+}
+
+func (s *ScheduledTransfer) BeforeSave(*gorm.DB) error {
+	if s.Amount.Currency() != "" {
+		s.Currency = s.Amount.Currency()
+	}
+	return nil
+}
+
+func (s *ScheduledTransfer) AfterFind(*gorm.DB) error {
+	s.Amount = s.Amount.WithCurrency(s.Currency)
+	return nil
+}
+
+// Start schedules the first occurrence, on or after from.
+func (s *ScheduledTransfer) Start(from time.Time) {
+	s.Status = ScheduledTransferStatuses.Active
+	s.schedule(from)
+}
+
+// Advance moves the schedule past the occurrence of runOn, whatever its
+// outcome, and completes it after its last occurrence.
+func (s *ScheduledTransfer) Advance(runOn time.Time) {
+	s.Occurrences++
+	s.schedule(runOn.AddDate(0, 0, 1))
+}
+
+// LatestDue returns the latest occurrence on or before today, from NextRunOn
+// on. The occurrences before it are skipped rather than made back-to-back, and
+// do not count toward Count. It returns false if no occurrence is due.
+func (s *ScheduledTransfer) LatestDue(today time.Time) (time.Time, bool) {
+	if s.NextRunOn == nil {
+		return time.Time{}, false
+	}
+
+	due := Day(*s.NextRunOn)
+	if due.After(today) {
+		return time.Time{}, false
+	}
+	for {
+		next, ok := s.OccurrenceFrom(due.AddDate(0, 0, 1))
+		if !ok || next.After(today) {
+			return due, true
+		}
+		due = next
+	}
+}
+
+// Pause stops the schedule until it is resumed. The occurrences that fall in
+// between are skipped.
+func (s *ScheduledTransfer) Pause() error {
+	if s.Status != ScheduledTransferStatuses.Active {
+		return ErrScheduleNotActive
+	}
+	s.Status = ScheduledTransferStatuses.Paused
+	return nil
+}
+
+// Resume continues a paused schedule with its first occurrence on or after
+// today.
+func (s *ScheduledTransfer) Resume(today time.Time) error {
+	if s.Status != ScheduledTransferStatuses.Paused {
+		return errors.New("scheduled transfer is not paused")
+	}
+	s.Start(today)
+	return nil
+}
+
+// Cancel ends the schedule for good.
+func (s *ScheduledTransfer) Cancel() error {
+	if s.Status != ScheduledTransferStatuses.Active && s.Status != ScheduledTransferStatuses.Paused {
+		return ErrScheduleNotActive
+	}
+	s.Status = ScheduledTransferStatuses.Canceled
+	s.NextRunOn = nil
+	return nil
+}
+
+func (s *ScheduledTransfer) schedule(from time.Time) {
+	next, ok := s.OccurrenceFrom(from)
+	if !ok {
+		s.NextRunOn = nil
+		if s.Status == ScheduledTransferStatuses.Active {
+			s.Status = ScheduledTransferStatuses.Completed
+		}
+		return
+	}
+	s.NextRunOn = &next
+}
+
+// OccurrenceFrom returns the first occurrence on or after the day of from. It
+// returns false if the schedule has no more occurrences.
+func (s *ScheduledTransfer) OccurrenceFrom(from time.Time) (time.Time, bool) {
+	if s.Count != nil && s.Occurrences >= *s.Count {
+		return time.Time{}, false
+	}
+
+	day := Day(from)
+	start := Day(s.StartDate)
+	if day.Before(start) {
+		day = start
+	}
+
+	var next time.Time
+	switch s.Frequency {
+	case ScheduleFrequencies.Once:
+		if s.Occurrences > 0 || day.After(start) {
+			return time.Time{}, false
+		}
+		next = start
+	case ScheduleFrequencies.Daily:
+		next = day
+	case ScheduleFrequencies.Weekly:
+		shift := (int(start.Weekday()) - int(day.Weekday()) + 7) % 7
+		next = day.AddDate(0, 0, shift)
+	case ScheduleFrequencies.Monthly:
+		next = dayOfMonth(day.Year(), day.Month(), s.DayOfMonth)
+		if next.Before(day) {
+			next = dayOfMonth(day.Year(), day.Month()+1, s.DayOfMonth)
+		}
+	case ScheduleFrequencies.LastBusinessDay:
+		next = lastBusinessDay(day.Year(), day.Month())
+		if next.Before(day) {
+			next = lastBusinessDay(day.Year(), day.Month()+1)
+		}
+	default:
+		return time.Time{}, false
+	}
+
+	if s.EndDate != nil && next.After(Day(*s.EndDate)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// Day returns the start of the UTC day of t.
+func Day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// dayOfMonth returns day n of a month, or its last day if it is shorter.
+func dayOfMonth(year int, month time.Month, n int) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+	return time.Date(year, month, min(n, last.Day()), 0, 0, 0, 0, time.UTC)
+}
+
+// lastBusinessDay returns the last weekday of a month. Public holidays are
+// not taken into account.
+func lastBusinessDay(year int, month time.Month) time.Time {
+	day := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+type OccurrenceStatus string
+
+var OccurrenceStatuses = &struct {
+	Pending   OccurrenceStatus
+	Succeeded OccurrenceStatus
+	Failed    OccurrenceStatus
+}{
+	Pending:   "PENDING",
+	Succeeded: "SUCCEEDED",
+	Failed:    "FAILED",
+}
+
+// ScheduledTransferOccurrence is the outcome of one occurrence of a scheduled
+// transfer. It is PENDING from the moment the occurrence is claimed until the
+// outcome of its transfer is recorded. TransactionID is derived from the
+// schedule and the day, so that an occurrence made again is rejected as a
+// duplicate transfer.
+type ScheduledTransferOccurrence struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+
+	ScheduledTransferID uuid.UUID        `gorm:"type:uuid;not null;uniqueIndex:idx_occurrences_schedule_day"`
+	RunOn               time.Time        `gorm:"type:date;not null;uniqueIndex:idx_occurrences_schedule_day"`
+	Sequence            int              `gorm:"not null"` // 1 for the first occurrence
+	Status              OccurrenceStatus `gorm:"type:varchar(20);not null;index"`
+	TransactionID       uuid.UUID        `gorm:"type:uuid;not null"`
+	FailureCode         string           `gorm:"type:varchar(50)"`
+	FailureReason       string           `gorm:"type:varchar(500)"`
+}
+
+// occurrenceNamespace derives the transaction IDs of occurrences.
+var occurrenceNamespace = uuid.MustParse("6f1d3c2a-8e4b-4f7a-9c5d-2b8a7e6f1c30")
+
+// OccurrenceTransactionID is the UUID of the transfer made for the occurrence
+// of a schedule on runOn.
+func OccurrenceTransactionID(scheduleID uuid.UUID, runOn time.Time) uuid.UUID {
+	return uuid.NewSHA1(occurrenceNamespace, []byte(scheduleID.String()+"/"+Day(runOn).Format(time.DateOnly)))
+}
+
+// ScheduledTransferEventData is the data of a scheduled_transfer.failed event.
+type ScheduledTransferEventData struct {
+	ScheduledTransferID uuid.UUID   `json:"scheduled_transfer_id"`
+	RunOn               string      `json:"run_on"`
+	Sequence            int         `json:"sequence"`
+	RecipientUsername   string      `json:"recipient_username"`
+	Amount              money.Money `json:"amount"`
+	Currency            string      `json:"currency"`
+	FailureCode         string      `json:"failure_code"`
+	FailureReason       string      `json:"failure_reason"`
+}
+
+// Events returns the events of an occurrence of s: users are notified of the
+// occurrences that failed.
+func (o *ScheduledTransferOccurrence) Events(s *ScheduledTransfer) []*OutboxEvent {
+	if o.Status != OccurrenceStatuses.Failed {
+		return nil
+	}
+
+	return []*OutboxEvent{{
+		UUID:     uuid.New(),
+		Type:     EventTypes.ScheduledTransferFailed,
+		UserID:   s.UserID,
+		WalletID: s.WalletID,
+		Data: &ScheduledTransferEventData{
+			ScheduledTransferID: s.UUID,
+			RunOn:               Day(o.RunOn).Format(time.DateOnly),
+			Sequence:            o.Sequence,
+			RecipientUsername:   s.RecipientUsername,
+			Amount:              s.Amount,
+			Currency:            s.Currency,
+			FailureCode:         o.FailureCode,
+			FailureReason:       o.FailureReason,
+		},
+	}}
+}
@@ -0,0 +1,31 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is one signed-in device. It holds the hash of the only refresh token
+// that is currently valid for the device; every refresh rotates it. A request
+// with any older token of the session means the token leaked, and the whole
+// session is revoked.
+type Session struct {
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	UUID             uuid.UUID `gorm:"type:uuid;primaryKey;not null"`
+	UserID           uint      `gorm:"index;not null"`
+	RefreshTokenHash string    `gorm:"type:char(64);not null"`
+	Generation       int       `gorm:"not null;default:0"`
+	UserAgent        string    `gorm:"type:varchar(255)"`
+	IPAddress        string    `gorm:"type:varchar(45)"`
+	LastUsedAt       time.Time `gorm:"not null"`
+	ExpiresAt        time.Time `gorm:"not null"`
+	RevokedAt        *time.Time
+	RevokedReason    string `gorm:"type:varchar(50)"`
+}
+
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,54 @@
+package entities
+
+import (
+	"banking-system/money"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StatementLine is one posting to a wallet, with the transaction it belongs
+// to from the wallet's side. Entries without a transaction, such as opening
+// balances, have no TransactionID and no Type.
+type StatementLine struct {
+	PostingID      uint
+	CreatedAt      time.Time
+	JournalEntryID uuid.UUID
+	Description    string
+	TransactionID  *uuid.UUID
+	Type           TransactionType
+	Amount         money.Money
+}
+
+// Statement is a monthly statement of a wallet as it was issued. The rendered
+// document is kept in the blob store under BlobKey and must still hash to
+// ContentHash when it is read. Statements are never changed once issued.
+type Statement struct {
+	CreatedAt time.Time
+
+	UUID           uuid.UUID   `gorm:"type:uuid;primaryKey;not null"`
+	UserID         uint        `gorm:"index;not null"`
+	WalletID       uint        `gorm:"uniqueIndex:idx_statements_wallet_period;not null"`
+	Currency       string      `gorm:"type:varchar(3);not null"`
+	PeriodStart    time.Time   `gorm:"uniqueIndex:idx_statements_wallet_period;not null"`
+	PeriodEnd      time.Time   `gorm:"not null"` // Exclusive
+	OpeningBalance money.Money `gorm:"type:numeric(18,4);not null"`
+	ClosingBalance money.Money `gorm:"type:numeric(18,4);not null"`
+	Format         string      `gorm:"type:varchar(10);not null"`
+	BlobKey        string      `gorm:"type:varchar(255);not null"`
+	ContentHash    string      `gorm:"type:varchar(64);not null"` // Hex SHA-256 of the document
+	Size           int64       `gorm:"not null"`
+}
+
+var ErrImmutableStatement = errors.New("issued statements are immutable")
+
+func (s *Statement) AfterFind(*gorm.DB) error {
+	s.OpeningBalance = s.OpeningBalance.WithCurrency(s.Currency)
+	s.ClosingBalance = s.ClosingBalance.WithCurrency(s.Currency)
+	return nil
+}
+
+func (*Statement) BeforeUpdate(*gorm.DB) error { return ErrImmutableStatement }
+func (*Statement) BeforeDelete(*gorm.DB) error { return ErrImmutableStatement }
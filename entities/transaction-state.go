@@ -0,0 +1,317 @@
+package entities
+
+import (
+	"banking-system/psp"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrIllegalTransition is returned for a status change that the state
+	// machine does not allow for the transaction's type, or whose guard fails.
+	ErrIllegalTransition = errors.New("illegal transaction status transition")
+
+	// ErrStatusUnchanged is returned when a transaction is already in the
+	// requested status, e.g. for a repeated provider callback.
+	ErrStatusUnchanged = errors.New("transaction is already in that status")
+)
+
+// TransitionError explains why a transaction cannot change status.
+type TransitionError struct {
+	TransactionID uuid.UUID
+	Type          TransactionType
+	From          TransactionStatus
+	To            TransactionStatus
+	Reason        string
+	Err           error
+}
+
+func (e *TransitionError) Error() string {
+	msg := fmt.Sprintf("%s: %s transaction '%s' from %s to %s", e.Err, e.Type, e.TransactionID, e.From, e.To)
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+func (e *TransitionError) Unwrap() error {
+	return e.Err
+}
+
+type TransitionActor string
+
+var TransitionActors = &struct {
+	User     TransitionActor
+	PSP      TransitionActor
+	System   TransitionActor
+	Operator TransitionActor
+}{
+	User:     "USER",
+	PSP:      "PSP",
+	System:   "SYSTEM",
+	Operator: "OPERATOR",
+}
+
+// Trigger is who or what caused a status change, and why.
+type Trigger struct {
+	Actor   TransitionActor
+	ActorID string
+	Reason  string
+}
+
+func UserTrigger(userID uint, reason string) Trigger {
+	return Trigger{Actor: TransitionActors.User, ActorID: strconv.FormatUint(uint64(userID), 10), Reason: reason}
+}
+
+func PSPTrigger(provider psp.PaymentMethod, reason string) Trigger {
+	return Trigger{Actor: TransitionActors.PSP, ActorID: string(provider), Reason: reason}
+}
+
+func SystemTrigger(component string, reason string) Trigger {
+	return Trigger{Actor: TransitionActors.System, ActorID: component, Reason: reason}
+}
+
+func OperatorTrigger(operatorID string, reason string) Trigger {
+	return Trigger{Actor: TransitionActors.Operator, ActorID: operatorID, Reason: reason}
+}
+
+// TransactionStatusChange is the audit record of one status change.
+type TransactionStatusChange struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+
+	TransactionID uuid.UUID         `gorm:"type:uuid;index;not null"`
+	FromStatus    TransactionStatus `gorm:"type:varchar(20);not null"`
+	ToStatus      TransactionStatus `gorm:"type:varchar(20);not null"`
+	Actor         TransitionActor   `gorm:"type:varchar(20);not null"`
+	ActorID       string            `gorm:"type:varchar(100)"`
+	Reason        string            `gorm:"type:varchar(255)"`
+}
+
+func (TransactionStatusChange) TableName() string {
+	return "transaction_status_history"
+}
+
+// CreationRecord is the audit record of a transaction that is created
+// directly in its status, which has no status to change from.
+func (tx *Transaction) CreationRecord(trigger Trigger) *TransactionStatusChange {
+	return &TransactionStatusChange{
+		TransactionID: tx.UUID,
+		ToStatus:      tx.Status,
+		Actor:         trigger.Actor,
+		ActorID:       trigger.ActorID,
+		Reason:        trigger.Reason,
+	}
+}
+
+// StatusTransition is a status change that passed the state machine, with the
+// journal entry it has to post, if any, and its audit record. The change only
+// takes effect once it is saved together with both.
+type StatusTransition struct {
+	From   TransactionStatus
+	To     TransactionStatus
+	Entry  *JournalEntry
+	Record *TransactionStatusChange
+
+	// Linked are the status changes of the transaction's fee that follow
+	// from this one and must be saved with it.
+	Linked []*StatusTransition
+}
+
+// transition is one allowed status change of a transaction type. Guard, if
+// set, must return nil for the change to be allowed; Effect updates the
+// transaction and returns the journal entry the change posts, if any.
+type transition struct {
+	from   TransactionStatus
+	to     TransactionStatus
+	guard  func(tx *Transaction) error
+	effect func(tx *Transaction) *JournalEntry
+}
+
+// transactionTransitions lists every status change the system allows, per
+// transaction type. Transfers, conversions and reversals are created COMPLETED
+// and never change status. A fee is COMPLETED once it is collected.
+var transactionTransitions = map[TransactionType][]transition{
+	TransactionTypes.Deposit: {
+		{from: TransactionStatuses.Pending, to: TransactionStatuses.Completed, guard: requireWallet, effect: creditDeposit},
+		{from: TransactionStatuses.Pending, to: TransactionStatuses.Canceled},
+		{from: TransactionStatuses.Pending, to: TransactionStatuses.Failed},
+	},
+	TransactionTypes.Withdrawal: {
+		// The amount already moved to PSP clearing when the withdrawal was made
+		{from: TransactionStatuses.Pending, to: TransactionStatuses.Completed, effect: markPayOut(psp.PayOutStatuses.Paid)},
+		{from: TransactionStatuses.Pending, to: TransactionStatuses.Canceled, guard: requireWallet, effect: refundWithdrawal("Withdrawal canceled", psp.PayOutStatuses.Rejected)},
+		{from: TransactionStatuses.Pending, to: TransactionStatuses.Failed, guard: requireWallet, effect: refundWithdrawal("Payout rejected", psp.PayOutStatuses.Rejected)},
+		{from: TransactionStatuses.Completed, to: TransactionStatuses.Failed, guard: requirePayOutReturned, effect: refundWithdrawal("Payout returned", psp.PayOutStatuses.Returned)},
+	},
+	TransactionTypes.Refund: {
+		// The amount already moved to PSP clearing when the refund was made
+		{from: TransactionStatuses.Pending, to: TransactionStatuses.Completed},
+		{from: TransactionStatuses.Pending, to: TransactionStatuses.Failed, guard: requireWallet, effect: returnRefund},
+	},
+	TransactionTypes.Fee: {
+		{from: TransactionStatuses.Pending, to: TransactionStatuses.Completed, guard: requireWallet, effect: NewFeeEntry},
+		{from: TransactionStatuses.Pending, to: TransactionStatuses.Canceled},
+		{from: TransactionStatuses.Pending, to: TransactionStatuses.Failed},
+		{from: TransactionStatuses.Completed, to: TransactionStatuses.Failed, guard: requireWallet, effect: returnFee},
+	},
+}
+
+// feeStatusFollowing returns the status a fee moves to when the transaction
+// it is charged for moves to status to: a fee is collected when the
+// transaction completes, and dropped or returned when it does not go through.
+func feeStatusFollowing(fee TransactionStatus, to TransactionStatus) (TransactionStatus, bool) {
+	switch {
+	case to == TransactionStatuses.Completed && fee == TransactionStatuses.Pending:
+		return TransactionStatuses.Completed, true
+	case to == TransactionStatuses.Canceled && fee == TransactionStatuses.Pending:
+		return TransactionStatuses.Canceled, true
+	case to == TransactionStatuses.Canceled || to == TransactionStatuses.Failed:
+		if fee == TransactionStatuses.Pending || fee == TransactionStatuses.Completed {
+			return TransactionStatuses.Failed, true
+		}
+	}
+	return "", false
+}
+
+// CanTransition reports whether the state machine has an edge from the
+// transaction's status to status, ignoring guards.
+func (tx *Transaction) CanTransition(to TransactionStatus) bool {
+	_, ok := tx.findTransition(to)
+	return ok
+}
+
+// Transition moves the transaction to status to, running the guard and effect
+// of the transition. The returned StatusTransition must be saved for the
+// change to take effect.
+func (tx *Transaction) Transition(to TransactionStatus, trigger Trigger) (*StatusTransition, error) {
+	from := tx.Status
+	if from == to {
+		return nil, tx.transitionError(to, ErrStatusUnchanged, "")
+	}
+
+	t, ok := tx.findTransition(to)
+	if !ok {
+		return nil, tx.transitionError(to, ErrIllegalTransition, "")
+	}
+
+	if t.guard != nil {
+		if err := t.guard(tx); err != nil {
+			return nil, tx.transitionError(to, ErrIllegalTransition, err.Error())
+		}
+	}
+
+	var linked []*StatusTransition
+	if fee := tx.FeeTransaction; fee != nil {
+		if feeTo, ok := feeStatusFollowing(fee.Status, to); ok {
+			feeTransition, err := fee.Transition(feeTo, trigger)
+			if err != nil {
+				return nil, err
+			}
+			linked = append(linked, feeTransition)
+		}
+	}
+
+	tx.Status = to
+
+	var entry *JournalEntry
+	if t.effect != nil {
+		entry = t.effect(tx)
+	}
+
+	return &StatusTransition{
+		From:  from,
+		To:    to,
+		Entry: entry,
+		Record: &TransactionStatusChange{
+			TransactionID: tx.UUID,
+			FromStatus:    from,
+			ToStatus:      to,
+			Actor:         trigger.Actor,
+			ActorID:       trigger.ActorID,
+			Reason:        trigger.Reason,
+		},
+		Linked: linked,
+	}, nil
+}
+
+func (tx *Transaction) findTransition(to TransactionStatus) (transition, bool) {
+	for _, t := range transactionTransitions[tx.Type] {
+		if t.from == tx.Status && t.to == to {
+			return t, true
+		}
+	}
+	return transition{}, false
+}
+
+func (tx *Transaction) transitionError(to TransactionStatus, err error, reason string) error {
+	return &TransitionError{
+		TransactionID: tx.UUID,
+		Type:          tx.Type,
+		From:          tx.Status,
+		To:            to,
+		Reason:        reason,
+		Err:           err,
+	}
+}
+
+func requireWallet(tx *Transaction) error {
+	if tx.Wallet == nil {
+		return errors.New("wallet not loaded")
+	}
+	return nil
+}
+
+func requirePayOutReturned(tx *Transaction) error {
+	if tx.PayOutStatus != psp.PayOutStatuses.Returned {
+		return fmt.Errorf("payout is %s, not %s", tx.PayOutStatus, psp.PayOutStatuses.Returned)
+	}
+	return requireWallet(tx)
+}
+
+func creditDeposit(tx *Transaction) *JournalEntry {
+	return NewJournalEntry(&tx.UUID, "Deposit completed",
+		NewPosting(WalletAccount(tx.Wallet), tx.Amount),
+		NewPosting(PSPClearingAccount(tx.PaymentMethod, tx.Wallet.Currency), tx.Amount.Neg()),
+	)
+}
+
+func markPayOut(status psp.PayOutStatus) func(tx *Transaction) *JournalEntry {
+	return func(tx *Transaction) *JournalEntry {
+		tx.PayOutStatus = status
+		return nil
+	}
+}
+
+// refundWithdrawal returns the withdrawn amount from PSP clearing to the
+// wallet.
+func refundWithdrawal(description string, status psp.PayOutStatus) func(tx *Transaction) *JournalEntry {
+	return func(tx *Transaction) *JournalEntry {
+		tx.PayOutStatus = status
+		return NewJournalEntry(&tx.UUID, description,
+			NewPosting(WalletAccount(tx.Wallet), tx.Amount),
+			NewPosting(PSPClearingAccount(tx.PaymentMethod, tx.Wallet.Currency), tx.Amount.Neg()),
+		)
+	}
+}
+
+// returnFee puts a collected fee back in the wallet.
+func returnFee(tx *Transaction) *JournalEntry {
+	return NewJournalEntry(&tx.UUID, "Fee returned",
+		NewPosting(WalletAccount(tx.Wallet), tx.Amount),
+		NewPosting(FeesAccount(tx.Wallet.Currency), tx.Amount.Neg()),
+	)
+}
+
+// returnRefund puts the amount of a refund the provider did not make back in
+// the wallet.
+func returnRefund(tx *Transaction) *JournalEntry {
+	return NewJournalEntry(&tx.UUID, "Refund failed",
+		NewPosting(WalletAccount(tx.Wallet), tx.Amount),
+		NewPosting(PSPClearingAccount(tx.PaymentMethod, tx.Wallet.Currency), tx.Amount.Neg()),
+	)
+}
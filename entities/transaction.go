@@ -1,25 +1,40 @@
 package entities
 
 import (
+	"banking-system/money"
 	"banking-system/psp"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
-	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 type TransactionType string
 
 var TransactionTypes = &struct {
-	Deposit     TransactionType
-	Withdrawal  TransactionType
-	TransferIn  TransactionType
-	TransferOut TransactionType
+	Deposit       TransactionType
+	Withdrawal    TransactionType
+	TransferIn    TransactionType
+	TransferOut   TransactionType
+	ConversionIn  TransactionType
+	ConversionOut TransactionType
+	Refund        TransactionType
+	ReversalIn    TransactionType
+	ReversalOut   TransactionType
+	Fee           TransactionType
 }{
-	Deposit:     "DEPOSIT",
-	Withdrawal:  "WITHDRAWAL",
-	TransferIn:  "TRANSFER_IN",
-	TransferOut: "TRANSFER_OUT",
+	Deposit:       "DEPOSIT",
+	Withdrawal:    "WITHDRAWAL",
+	TransferIn:    "TRANSFER_IN",
+	TransferOut:   "TRANSFER_OUT",
+	ConversionIn:  "CONVERSION_IN",
+	ConversionOut: "CONVERSION_OUT",
+	Refund:        "REFUND",
+	ReversalIn:    "REVERSAL_IN",
+	ReversalOut:   "REVERSAL_OUT",
+	Fee:           "FEE",
 }
 
 type TransactionStatus string
@@ -36,58 +51,180 @@ var TransactionStatuses = &struct {
 	Canceled:  "CANCELED",
 }
 
+var ErrInvalidPayOutTransition = errors.New("invalid payout status transition")
+
 type Transaction struct {
-	CreatedAt time.Time
+	// The history of a user's wallets is paged on (created_at, uuid)
+	CreatedAt time.Time `gorm:"index:idx_transactions_history,priority:2"`
 	UpdatedAt time.Time
 
-	UUID          uuid.UUID         `gorm:"type:uuid;primaryKey;not null"`
+	UUID          uuid.UUID         `gorm:"type:uuid;primaryKey;not null;index:idx_transactions_history,priority:3"`
 	Type          TransactionType   `gorm:"type:varchar(20);not null"`
 	Status        TransactionStatus `gorm:"type:varchar(20);not null"`
-	Amount        float64           `gorm:"type:numeric(18,4);not null"`
+	Amount        money.Money       `gorm:"type:numeric(18,4);not null"`
+	Currency      string            `gorm:"type:varchar(3);not null;default:'TWD'"` // default backfills rows created before multi-currency support
 	PaymentMethod psp.PaymentMethod `gorm:"type:varchar(50)"`
 
-	WalletID uint `gorm:"not null"`
+	// FeeAmount is the fee charged for the transaction. The fee is collected
+	// by its own FEE transaction, which relates to this one.
+	FeeAmount      money.Money  `gorm:"type:numeric(18,4);not null;default:0"`
+	FeeTransaction *Transaction `gorm:"-"`
+
+	// LimitCheck, if set, is checked when the transaction is created
+	LimitCheck *LimitCheck `gorm:"-"`
+
+	// PaymentRequestID, if set, is the payment request a TRANSFER_OUT pays. The
+	// request is accepted when the transfer is created.
+	PaymentRequestID *uuid.UUID `gorm:"-"`
+
+	// Withdrawals only: the provider's reference and status for the payout
+	PSPReference string           `gorm:"type:varchar(100)"`
+	PayOutStatus psp.PayOutStatus `gorm:"type:varchar(20);index"`
+
+	// NeedsReview marks a pending withdrawal that expired without its
+	// provider being asked about the payout. It is neither failed nor refunded
+	// automatically, since the provider may still pay it out, and is left for
+	// an operator to settle.
+	NeedsReview bool `gorm:"not null;default:false"`
+
+	WalletID uint `gorm:"not null;index:idx_transactions_history,priority:1"`
 	Wallet   *Wallet
 
 	RelatedTransactionID *uuid.UUID   `gorm:"index"`
 	RelatedTransaction   *Transaction `gorm:"foreignKey:RelatedTransactionID;references:UUID"`
 }
 
-func (tx *Transaction) Complete() error {
-	if tx.Status != TransactionStatuses.Pending {
-		log.Infof("Transaction '%s' already processed with status: %s", tx.UUID, tx.Status)
-		return nil
+func (tx *Transaction) BeforeSave(*gorm.DB) error {
+	if tx.Amount.Currency() != "" {
+		tx.Currency = tx.Amount.Currency()
 	}
+	return nil
+}
+
+func (tx *Transaction) AfterFind(*gorm.DB) error {
+	tx.Amount = tx.Amount.WithCurrency(tx.Currency)
+	tx.FeeAmount = tx.FeeAmount.WithCurrency(tx.Currency)
+	return nil
+}
 
-	tx.Status = TransactionStatuses.Completed
+// HasFee reports whether a fee is charged for the transaction.
+func (tx *Transaction) HasFee() bool {
+	return tx.FeeAmount.IsPositive()
+}
 
-	switch tx.Type {
-	case TransactionTypes.Deposit:
-		tx.Wallet.Balance += tx.Amount
-	case TransactionTypes.Withdrawal:
-		// No action needed, amount already deducted during withdrawal initiation
-	default:
-		log.Panicf("Unknown transaction type: %s", tx.Type)
+// AttachFee charges fee for the transaction and creates the FEE transaction
+// that collects it into the house fees account. Deposit fees are collected
+// once the deposit completes, all other fees together with the transaction.
+// A zero fee is not charged.
+func (tx *Transaction) AttachFee(fee money.Money) {
+	if !fee.IsPositive() {
+		return
 	}
 
-	return nil
+	status := TransactionStatuses.Completed
+	if tx.Type == TransactionTypes.Deposit {
+		status = TransactionStatuses.Pending
+	}
+
+	tx.FeeAmount = fee
+	tx.FeeTransaction = &Transaction{
+		UUID:                 uuid.New(),
+		WalletID:             tx.WalletID,
+		Wallet:               tx.Wallet,
+		Amount:               fee,
+		Status:               status,
+		Type:                 TransactionTypes.Fee,
+		PaymentMethod:        tx.PaymentMethod,
+		RelatedTransactionID: &tx.UUID,
+	}
 }
 
-func (tx *Transaction) Cancel() error {
-	if tx.Status != TransactionStatuses.Pending {
-		log.Infof("Transaction '%s' already processed with status: %s", tx.UUID, tx.Status)
-		return nil
+// ApplyPayOutStatus moves a withdrawal to the payout status reported by its
+// provider, and the transaction to the status that follows from it. The
+// returned transition is nil if the transaction status does not change. It
+// returns changed false for a payout status that is already applied or older
+// than the current one, since providers may repeat callbacks or deliver them
+// out of order.
+func (tx *Transaction) ApplyPayOutStatus(status psp.PayOutStatus, trigger Trigger) (t *StatusTransition, changed bool, err error) {
+	current := tx.PayOutStatus
+	if current == "" {
+		// Withdrawals created before payouts were tracked
+		current = psp.PayOutStatuses.Submitted
 	}
 
-	tx.Status = TransactionStatuses.Canceled
+	if status == current || status.Precedes(current) {
+		return nil, false, nil
+	}
 
-	switch tx.Type {
-	case TransactionTypes.Withdrawal:
-		tx.Wallet.Balance += tx.Amount
-	case TransactionTypes.Deposit:
+	if !current.CanTransitionTo(status) {
+		return nil, false, fmt.Errorf("%w: %s to %s", ErrInvalidPayOutTransition, current, status)
+	}
+
+	var to TransactionStatus
+	switch status {
+	case psp.PayOutStatuses.Paid:
+		to = TransactionStatuses.Completed
+	case psp.PayOutStatuses.Rejected, psp.PayOutStatuses.Returned:
+		to = TransactionStatuses.Failed
 	default:
-		log.Panicf("Unknown transaction type: %s", tx.Type)
+		tx.PayOutStatus = status
+		return nil, true, nil
 	}
 
-	return nil
+	previous := tx.PayOutStatus
+	tx.PayOutStatus = status
+
+	t, err = tx.Transition(to, trigger)
+	if errors.Is(err, ErrStatusUnchanged) {
+		// e.g. already completed through the confirm callback
+		return nil, true, nil
+	}
+	if err != nil {
+		tx.PayOutStatus = previous
+		return nil, false, err
+	}
+
+	return t, true, nil
+}
+
+// NewWithdrawalEntry moves the withdrawn amount from the wallet into PSP
+// clearing until the provider settles or rejects the payout.
+func NewWithdrawalEntry(tx *Transaction) *JournalEntry {
+	return NewJournalEntry(&tx.UUID, "Withdrawal initiated",
+		NewPosting(WalletAccount(tx.Wallet), tx.Amount.Neg()),
+		NewPosting(PSPClearingAccount(tx.PaymentMethod, tx.Wallet.Currency), tx.Amount),
+	)
+}
+
+func NewTransferEntry(transferOutTx *Transaction, transferInTx *Transaction) *JournalEntry {
+	return NewJournalEntry(&transferOutTx.UUID, "Transfer",
+		NewPosting(WalletAccount(transferOutTx.Wallet), transferOutTx.Amount.Neg()),
+		NewPosting(WalletAccount(transferInTx.Wallet), transferInTx.Amount),
+	)
+}
+
+// NewFeeEntry moves a fee from the wallet into the house fees account.
+func NewFeeEntry(fee *Transaction) *JournalEntry {
+	return NewJournalEntry(&fee.UUID, "Fee charged",
+		NewPosting(WalletAccount(fee.Wallet), fee.Amount.Neg()),
+		NewPosting(FeesAccount(fee.Wallet.Currency), fee.Amount),
+	)
+}
+
+// NewRefundEntry moves the refunded amount from the wallet into PSP clearing,
+// the reverse of the deposit that is refunded.
+func NewRefundEntry(tx *Transaction) *JournalEntry {
+	return NewJournalEntry(&tx.UUID, "Refund initiated",
+		NewPosting(WalletAccount(tx.Wallet), tx.Amount.Neg()),
+		NewPosting(PSPClearingAccount(tx.PaymentMethod, tx.Wallet.Currency), tx.Amount),
+	)
+}
+
+// NewReversalEntry takes the amount of a transfer back from its recipient and
+// returns it to its sender.
+func NewReversalEntry(reversalOutTx *Transaction, reversalInTx *Transaction) *JournalEntry {
+	return NewJournalEntry(&reversalInTx.UUID, "Transfer reversed",
+		NewPosting(WalletAccount(reversalOutTx.Wallet), reversalOutTx.Amount.Neg()),
+		NewPosting(WalletAccount(reversalInTx.Wallet), reversalInTx.Amount),
+	)
 }
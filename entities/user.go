@@ -2,12 +2,41 @@ package entities
 
 import "gorm.io/gorm"
 
+// UserTier is the KYC tier of a user, which selects the fee schedules and
+// limits that apply to them.
+type UserTier string
+
+var UserTiers = &struct {
+	Standard UserTier
+	Premium  UserTier
+}{
+	Standard: "STANDARD",
+	Premium:  "PREMIUM",
+}
+
+// HouseUsername is the username of the house user, which owns the house fee
+// wallets. The house user is found by IsHouse rather than by its username; it
+// cannot log in, and the username cannot be registered.
+const HouseUsername = "house"
+
 type User struct {
 	gorm.Model
-	Username     string `gorm:"type:varchar(20);unique;not null"`
-	PasswordHash string `gorm:"type:varchar(255);not null"`
-	Name         string `gorm:"type:varchar(100)"`
+	Username     string   `gorm:"type:varchar(20);unique;not null"`
+	PasswordHash string   `gorm:"type:varchar(255);not null"`
+	Name         string   `gorm:"type:varchar(100)"`
+	Tier         UserTier `gorm:"type:varchar(20);not null;default:'STANDARD'"`
+	IsHouse      bool     `gorm:"not null;default:false;uniqueIndex:idx_users_house,where:is_house"` // Only the house user
 
-	Wallet       Wallet
+	Wallets      []Wallet
 	BankAccounts []BankAccount
 }
+
+// WalletFor returns the user's wallet in the given currency.
+func (u *User) WalletFor(currency string) (*Wallet, bool) {
+	for i := range u.Wallets {
+		if u.Wallets[i].Currency == currency {
+			return &u.Wallets[i], true
+		}
+	}
+	return nil, false
+}
@@ -1,10 +1,26 @@
 package entities
 
-import "gorm.io/gorm"
+import (
+	"banking-system/money"
+
+	"gorm.io/gorm"
+)
 
 type Wallet struct {
 	gorm.Model
-	UserID   uint    `gorm:"unique;not null"`
-	Currency string  `gorm:"type:varchar(3);not null"`
-	Balance  float64 `gorm:"type:numeric(18,4);not null"`
+	UserID   uint        `gorm:"uniqueIndex:idx_wallets_user_currency;not null"`
+	Currency string      `gorm:"type:varchar(3);uniqueIndex:idx_wallets_user_currency;not null"`
+	Balance  money.Money `gorm:"type:numeric(18,4);not null"`
+}
+
+func (w *Wallet) BeforeSave(*gorm.DB) error {
+	if w.Balance.Currency() != "" {
+		w.Currency = w.Balance.Currency()
+	}
+	return nil
+}
+
+func (w *Wallet) AfterFind(*gorm.DB) error {
+	w.Balance = w.Balance.WithCurrency(w.Currency)
+	return nil
 }
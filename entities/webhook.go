@@ -0,0 +1,92 @@
+package entities
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookEndpoint is a URL a user registered to receive events about their
+// account. Deliveries to it are signed with Secret.
+type WebhookEndpoint struct {
+	gorm.Model
+	UserID uint     `gorm:"index;not null"`
+	URL    string   `gorm:"type:varchar(2048);not null"`
+	Secret string   `gorm:"type:varchar(128);not null"`
+	Events []string `gorm:"serializer:json;type:text;not null"`
+}
+
+// Subscribes reports whether the endpoint receives events of eventType.
+func (e *WebhookEndpoint) Subscribes(eventType string) bool {
+	return slices.Contains(e.Events, eventType)
+}
+
+type WebhookDeliveryStatus string
+
+var WebhookDeliveryStatuses = &struct {
+	Pending   WebhookDeliveryStatus
+	Delivered WebhookDeliveryStatus
+	Dead      WebhookDeliveryStatus
+}{
+	Pending:   "PENDING",
+	Delivered: "DELIVERED",
+	Dead:      "DEAD",
+}
+
+// WebhookDelivery is one event to be sent to one endpoint. A delivery that
+// still fails after its last attempt is dead-lettered until it is redelivered
+// by hand.
+type WebhookDelivery struct {
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	UUID           uuid.UUID             `gorm:"type:uuid;primaryKey;not null"`
+	EndpointID     uint                  `gorm:"uniqueIndex:idx_webhook_deliveries_endpoint_event;not null"`
+	Endpoint       *WebhookEndpoint      `gorm:"foreignKey:EndpointID"`
+	EventID        uuid.UUID             `gorm:"type:uuid;uniqueIndex:idx_webhook_deliveries_endpoint_event;not null"`
+	EventType      string                `gorm:"type:varchar(50);not null"`
+	Payload        string                `gorm:"type:text;not null"`
+	Status         WebhookDeliveryStatus `gorm:"type:varchar(20);index:idx_webhook_deliveries_due,priority:1;not null"`
+	Attempts       int                   `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time             `gorm:"index:idx_webhook_deliveries_due,priority:2;not null"`
+	LastAttemptAt  *time.Time
+	LastStatusCode int    `gorm:"not null;default:0"`
+	LastError      string `gorm:"type:varchar(500)"`
+	DeliveredAt    *time.Time
+}
+
+// Delivered records a successful attempt.
+func (d *WebhookDelivery) Delivered(at time.Time, statusCode int) {
+	d.Attempts++
+	d.LastAttemptAt = &at
+	d.LastStatusCode = statusCode
+	d.LastError = ""
+	d.DeliveredAt = &at
+	d.Status = WebhookDeliveryStatuses.Delivered
+}
+
+// Failed records a failed attempt and schedules the next one after
+// retryDelay, or dead-letters the delivery once it has been attempted
+// maxAttempts times.
+func (d *WebhookDelivery) Failed(at time.Time, statusCode int, reason string, maxAttempts int, retryDelay func(attempt int) time.Duration) {
+	d.Attempts++
+	d.LastAttemptAt = &at
+	d.LastStatusCode = statusCode
+	d.LastError = reason
+
+	if d.Attempts >= maxAttempts {
+		d.Status = WebhookDeliveryStatuses.Dead
+		return
+	}
+	d.NextAttemptAt = at.Add(retryDelay(d.Attempts))
+}
+
+// Redeliver takes a dead-lettered delivery back to be sent at once, with all
+// its attempts available again.
+func (d *WebhookDelivery) Redeliver(at time.Time) {
+	d.Status = WebhookDeliveryStatuses.Pending
+	d.Attempts = 0
+	d.NextAttemptAt = at
+}
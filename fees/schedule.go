@@ -0,0 +1,291 @@
+// Package fees calculates the fees charged for deposits, withdrawals and
+// transfers from configurable fee schedules.
+package fees
+
+import (
+	"banking-system/money"
+	"banking-system/psp"
+	"fmt"
+	"math/big"
+)
+
+type Operation string
+
+var Operations = &struct {
+	Deposit    Operation
+	Withdrawal Operation
+	Transfer   Operation
+}{
+	Deposit:    "DEPOSIT",
+	Withdrawal: "WITHDRAWAL",
+	Transfer:   "TRANSFER",
+}
+
+// Criteria describe the transaction a fee is looked up for.
+type Criteria struct {
+	Operation     Operation
+	PaymentMethod psp.PaymentMethod
+	Currency      string
+	Tier          string
+}
+
+// Schedule is how the fee of matching transactions is calculated, as read
+// from configuration. An empty PaymentMethod, Currency or Tier matches any.
+//
+// The fee is Fixed plus Percent of the amount, or, if Bands are given, the
+// fixed and percentage fee of the first band the amount falls in. It is then
+// raised to Min and capped at Max. Fixed amounts are in the schedule's
+// currency, so a schedule that uses them must name its currency.
+type Schedule struct {
+	ID            string            `json:"id"`
+	Operation     Operation         `json:"operation"`
+	PaymentMethod psp.PaymentMethod `json:"payment_method,omitempty"`
+	Currency      string            `json:"currency,omitempty"`
+	Tier          string            `json:"tier,omitempty"`
+	Fixed         string            `json:"fixed,omitempty"`
+	Percent       string            `json:"percent,omitempty"`
+	Bands         []Band            `json:"bands,omitempty"`
+	Min           string            `json:"min,omitempty"`
+	Max           string            `json:"max,omitempty"`
+}
+
+// Band is one tier of a tiered fee. It applies to amounts up to and including
+// UpTo, or to every amount if UpTo is empty, so the last band usually has none.
+type Band struct {
+	UpTo    string `json:"up_to,omitempty"`
+	Fixed   string `json:"fixed,omitempty"`
+	Percent string `json:"percent,omitempty"`
+}
+
+// Schedules are parsed fee schedules, in the order they were configured.
+// Without schedules nothing is charged.
+type Schedules []*schedule
+
+type schedule struct {
+	Schedule
+	rate  rate
+	bands []band
+	min   *money.Money
+	max   *money.Money
+}
+
+type rate struct {
+	fixed   *money.Money
+	percent *big.Rat
+}
+
+type band struct {
+	upTo *money.Money
+	rate
+}
+
+// Quote is the fee for an amount and the schedule it was calculated with.
+type Quote struct {
+	Fee        money.Money
+	ScheduleID string
+}
+
+// Parse validates schedules and prepares them for calculating fees.
+func Parse(raw []Schedule) (Schedules, error) {
+	schedules := make(Schedules, 0, len(raw))
+	for _, s := range raw {
+		parsed, err := parseSchedule(s)
+		if err != nil {
+			return nil, fmt.Errorf("fee schedule '%s': %w", s.ID, err)
+		}
+		schedules = append(schedules, parsed)
+	}
+	return schedules, nil
+}
+
+// MustParse is like Parse but panics on invalid schedules.
+func MustParse(raw []Schedule) Schedules {
+	schedules, err := Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+	return schedules
+}
+
+func parseSchedule(s Schedule) (*schedule, error) {
+	switch s.Operation {
+	case Operations.Deposit, Operations.Withdrawal, Operations.Transfer:
+	default:
+		return nil, fmt.Errorf("unknown operation '%s'", s.Operation)
+	}
+
+	if s.Currency != "" && !money.IsSupported(s.Currency) {
+		return nil, fmt.Errorf("unsupported currency '%s'", s.Currency)
+	}
+
+	parsed := &schedule{Schedule: s}
+
+	var err error
+	if parsed.rate, err = parseRate(s.Currency, s.Fixed, s.Percent); err != nil {
+		return nil, err
+	}
+
+	for i, b := range s.Bands {
+		upTo, err := parseAmount(s.Currency, b.UpTo)
+		if err != nil {
+			return nil, fmt.Errorf("band %d: %w", i+1, err)
+		}
+
+		bandRate, err := parseRate(s.Currency, b.Fixed, b.Percent)
+		if err != nil {
+			return nil, fmt.Errorf("band %d: %w", i+1, err)
+		}
+
+		if upTo != nil && i > 0 && parsed.bands[i-1].upTo != nil && !upTo.GreaterThan(*parsed.bands[i-1].upTo) {
+			return nil, fmt.Errorf("band %d: bands must be in ascending order", i+1)
+		}
+
+		parsed.bands = append(parsed.bands, band{upTo: upTo, rate: bandRate})
+	}
+
+	if parsed.min, err = parseAmount(s.Currency, s.Min); err != nil {
+		return nil, fmt.Errorf("min: %w", err)
+	}
+	if parsed.max, err = parseAmount(s.Currency, s.Max); err != nil {
+		return nil, fmt.Errorf("max: %w", err)
+	}
+	if parsed.min != nil && parsed.max != nil && parsed.min.GreaterThan(*parsed.max) {
+		return nil, fmt.Errorf("min %s is greater than max %s", parsed.min, parsed.max)
+	}
+
+	return parsed, nil
+}
+
+func parseRate(currency string, fixed string, percent string) (rate, error) {
+	var r rate
+
+	amount, err := parseAmount(currency, fixed)
+	if err != nil {
+		return r, fmt.Errorf("fixed: %w", err)
+	}
+	r.fixed = amount
+
+	if percent != "" {
+		p, ok := new(big.Rat).SetString(percent)
+		if !ok || p.Sign() < 0 {
+			return r, fmt.Errorf("invalid percent '%s'", percent)
+		}
+		r.percent = p.Quo(p, big.NewRat(100, 1))
+	}
+
+	return r, nil
+}
+
+func parseAmount(currency string, value string) (*money.Money, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if currency == "" {
+		return nil, fmt.Errorf("amount '%s' needs the schedule's currency", value)
+	}
+
+	amount, err := money.Parse(value, currency)
+	if err != nil {
+		return nil, err
+	}
+	if amount.IsNegative() {
+		return nil, fmt.Errorf("amount '%s' is negative", value)
+	}
+
+	return &amount, nil
+}
+
+// find returns the most specific schedule matching c. Of equally specific
+// schedules, the first one wins.
+func (schedules Schedules) find(c Criteria) (*schedule, bool) {
+	var best *schedule
+	bestScore := -1
+
+	for _, s := range schedules {
+		if !s.matches(c) {
+			continue
+		}
+
+		if score := s.specificity(); score > bestScore {
+			best, bestScore = s, score
+		}
+	}
+
+	return best, best != nil
+}
+
+// Calculate returns the fee for amount under the schedule matching c, or a
+// zero fee if no schedule matches.
+func (schedules Schedules) Calculate(c Criteria, amount money.Money) (Quote, error) {
+	s, ok := schedules.find(c)
+	if !ok {
+		return Quote{Fee: money.Zero(amount.Currency())}, nil
+	}
+
+	fee, err := s.calculate(amount)
+	if err != nil {
+		return Quote{}, fmt.Errorf("fee schedule '%s': %w", s.ID, err)
+	}
+
+	return Quote{Fee: fee, ScheduleID: s.ID}, nil
+}
+
+func (s *schedule) matches(c Criteria) bool {
+	return s.Operation == c.Operation &&
+		(s.PaymentMethod == "" || s.PaymentMethod == c.PaymentMethod) &&
+		(s.Currency == "" || s.Currency == c.Currency) &&
+		(s.Tier == "" || s.Tier == c.Tier)
+}
+
+func (s *schedule) specificity() int {
+	score := 0
+	for _, set := range []bool{s.PaymentMethod != "", s.Currency != "", s.Tier != ""} {
+		if set {
+			score++
+		}
+	}
+	return score
+}
+
+// calculate never returns more than amount, so that a fee never takes more
+// than the transaction moves.
+func (s *schedule) calculate(amount money.Money) (money.Money, error) {
+	r := s.rate
+	for _, b := range s.bands {
+		if b.upTo == nil || !amount.GreaterThan(*b.upTo) {
+			r = b.rate
+			break
+		}
+	}
+
+	fee := money.Zero(amount.Currency())
+	if r.percent != nil {
+		variable, err := amount.Mul(r.percent)
+		if err != nil {
+			return money.Money{}, err
+		}
+		fee = variable
+	}
+
+	if r.fixed != nil {
+		var err error
+		if fee, err = fee.Add(*r.fixed); err != nil {
+			return money.Money{}, err
+		}
+	}
+
+	fee = fee.Round()
+
+	if s.min != nil && fee.LessThan(*s.min) {
+		fee = *s.min
+	}
+	if s.max != nil && fee.GreaterThan(*s.max) {
+		fee = *s.max
+	}
+	if fee.GreaterThan(amount) {
+		fee = amount
+	}
+
+	return fee, nil
+}
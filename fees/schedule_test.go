@@ -0,0 +1,147 @@
+package fees_test
+
+import (
+	"banking-system/fees"
+	"banking-system/money"
+	"banking-system/psp"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func twd(s string) money.Money {
+	return money.MustParse(s, "TWD")
+}
+
+func TestCalculate_NoScheduleIsFree(t *testing.T) {
+	var schedules fees.Schedules
+
+	quote, err := schedules.Calculate(fees.Criteria{Operation: fees.Operations.Deposit, Currency: "TWD"}, twd("100"))
+	assert.Nil(t, err)
+	assert.True(t, quote.Fee.IsZero())
+	assert.Equal(t, "TWD", quote.Fee.Currency())
+	assert.Equal(t, "", quote.ScheduleID)
+}
+
+func TestCalculate_FixedAndPercent(t *testing.T) {
+	schedules := fees.MustParse([]fees.Schedule{
+		{ID: "withdrawal", Operation: fees.Operations.Withdrawal, Currency: "TWD", Fixed: "15", Percent: "0.5"},
+	})
+
+	quote, err := schedules.Calculate(fees.Criteria{Operation: fees.Operations.Withdrawal, Currency: "TWD"}, twd("1000"))
+	assert.Nil(t, err)
+	assert.Equal(t, twd("20"), quote.Fee)
+	assert.Equal(t, "withdrawal", quote.ScheduleID)
+}
+
+func TestCalculate_RoundsToMinorUnits(t *testing.T) {
+	schedules := fees.MustParse([]fees.Schedule{
+		{ID: "transfer", Operation: fees.Operations.Transfer, Percent: "0.333"},
+	})
+
+	quote, err := schedules.Calculate(fees.Criteria{Operation: fees.Operations.Transfer, Currency: "TWD"}, twd("10.00"))
+	assert.Nil(t, err)
+	assert.Equal(t, twd("0.03"), quote.Fee)
+}
+
+func TestCalculate_MinAndMax(t *testing.T) {
+	schedules := fees.MustParse([]fees.Schedule{
+		{ID: "capped", Operation: fees.Operations.Transfer, Currency: "TWD", Percent: "1", Min: "5", Max: "50"},
+	})
+	criteria := fees.Criteria{Operation: fees.Operations.Transfer, Currency: "TWD"}
+
+	quote, _ := schedules.Calculate(criteria, twd("100"))
+	assert.Equal(t, twd("5"), quote.Fee)
+
+	quote, _ = schedules.Calculate(criteria, twd("2000"))
+	assert.Equal(t, twd("20"), quote.Fee)
+
+	quote, _ = schedules.Calculate(criteria, twd("100000"))
+	assert.Equal(t, twd("50"), quote.Fee)
+}
+
+func TestCalculate_NeverMoreThanAmount(t *testing.T) {
+	schedules := fees.MustParse([]fees.Schedule{
+		{ID: "flat", Operation: fees.Operations.Deposit, Currency: "TWD", Fixed: "30"},
+	})
+
+	quote, _ := schedules.Calculate(fees.Criteria{Operation: fees.Operations.Deposit, Currency: "TWD"}, twd("10"))
+	assert.Equal(t, twd("10"), quote.Fee)
+}
+
+func TestCalculate_Bands(t *testing.T) {
+	schedules := fees.MustParse([]fees.Schedule{
+		{
+			ID:        "tiered",
+			Operation: fees.Operations.Withdrawal,
+			Currency:  "TWD",
+			Bands: []fees.Band{
+				{UpTo: "1000", Fixed: "10"},
+				{UpTo: "10000", Percent: "1"},
+				{Percent: "0.5"},
+			},
+		},
+	})
+	criteria := fees.Criteria{Operation: fees.Operations.Withdrawal, Currency: "TWD"}
+
+	quote, _ := schedules.Calculate(criteria, twd("1000"))
+	assert.Equal(t, twd("10"), quote.Fee)
+
+	quote, _ = schedules.Calculate(criteria, twd("5000"))
+	assert.Equal(t, twd("50"), quote.Fee)
+
+	quote, _ = schedules.Calculate(criteria, twd("20000"))
+	assert.Equal(t, twd("100"), quote.Fee)
+}
+
+func TestCalculate_MostSpecificScheduleWins(t *testing.T) {
+	schedules := fees.MustParse([]fees.Schedule{
+		{ID: "default", Operation: fees.Operations.Deposit, Percent: "1"},
+		{ID: "fakepay", Operation: fees.Operations.Deposit, PaymentMethod: psp.PaymentMethods.FakePay, Percent: "2"},
+		{ID: "fakepay-premium", Operation: fees.Operations.Deposit, PaymentMethod: psp.PaymentMethods.FakePay, Tier: "PREMIUM"},
+		{ID: "fakepay-twd", Operation: fees.Operations.Deposit, PaymentMethod: psp.PaymentMethods.FakePay, Currency: "TWD", Percent: "3"},
+	})
+
+	quote, _ := schedules.Calculate(fees.Criteria{Operation: fees.Operations.Deposit, PaymentMethod: psp.PaymentMethods.BankTransfer, Currency: "TWD"}, twd("100"))
+	assert.Equal(t, "default", quote.ScheduleID)
+
+	quote, _ = schedules.Calculate(fees.Criteria{Operation: fees.Operations.Deposit, PaymentMethod: psp.PaymentMethods.FakePay, Currency: "USD"}, money.MustParse("100", "USD"))
+	assert.Equal(t, "fakepay", quote.ScheduleID)
+
+	// Equally specific: the first configured schedule wins.
+	quote, _ = schedules.Calculate(fees.Criteria{Operation: fees.Operations.Deposit, PaymentMethod: psp.PaymentMethods.FakePay, Currency: "TWD", Tier: "PREMIUM"}, twd("100"))
+	assert.Equal(t, "fakepay-premium", quote.ScheduleID)
+	assert.True(t, quote.Fee.IsZero())
+
+	quote, _ = schedules.Calculate(fees.Criteria{Operation: fees.Operations.Withdrawal, Currency: "TWD"}, twd("100"))
+	assert.Equal(t, "", quote.ScheduleID)
+}
+
+func TestParse_RejectsInvalidSchedules(t *testing.T) {
+	invalid := []fees.Schedule{
+		{ID: "operation", Operation: "REFUND"},
+		{ID: "currency", Operation: fees.Operations.Deposit, Currency: "XXX"},
+		{ID: "fixed-without-currency", Operation: fees.Operations.Deposit, Fixed: "10"},
+		{ID: "percent", Operation: fees.Operations.Deposit, Percent: "-1"},
+		{ID: "min-max", Operation: fees.Operations.Deposit, Currency: "TWD", Min: "10", Max: "5"},
+		{ID: "bands", Operation: fees.Operations.Deposit, Currency: "TWD", Bands: []fees.Band{{UpTo: "100"}, {UpTo: "50"}}},
+	}
+
+	for _, s := range invalid {
+		_, err := fees.Parse([]fees.Schedule{s})
+		assert.NotNil(t, err, s.ID)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fees.json")
+	os.WriteFile(path, []byte(`[{"id": "transfer", "operation": "TRANSFER", "currency": "TWD", "fixed": "5"}]`), 0o600)
+
+	schedules, err := fees.LoadFile(path)
+	assert.Nil(t, err)
+
+	quote, _ := schedules.Calculate(fees.Criteria{Operation: fees.Operations.Transfer, Currency: "TWD"}, twd("100"))
+	assert.Equal(t, twd("5"), quote.Fee)
+}
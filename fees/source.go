@@ -0,0 +1,38 @@
+package fees
+
+import (
+	"encoding/json"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// NewSchedules loads the fee schedules from the JSON file FEE_SCHEDULES_FILE.
+// Without the file nothing is charged.
+func NewSchedules() Schedules {
+	path := os.Getenv("FEE_SCHEDULES_FILE")
+	if path == "" {
+		return nil
+	}
+
+	schedules, err := LoadFile(path)
+	if err != nil {
+		log.Panicf("Failed to load fee schedules from '%s': %v", path, err)
+	}
+	return schedules
+}
+
+// LoadFile reads a JSON array of schedules.
+func LoadFile(path string) (Schedules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []Schedule
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	return Parse(raw)
+}
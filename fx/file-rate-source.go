@@ -0,0 +1,70 @@
+package fx
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// fileRateSource reads rates from a JSON object such as {"USD/TWD": "32.5"}
+// and reloads the file whenever it changes on disk.
+type fileRateSource struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	rates   map[string]*big.Rat
+}
+
+func NewFileRateSource(path string) (RateSource, error) {
+	src := &fileRateSource{path: path}
+	if err := src.reload(); err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
+func (src *fileRateSource) GetRate(base string, quote string) (*big.Rat, error) {
+	if err := src.reload(); err != nil {
+		return nil, err
+	}
+
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	return lookup(src.rates, base, quote)
+}
+
+func (src *fileRateSource) reload() error {
+	info, err := os.Stat(src.path)
+	if err != nil {
+		return err
+	}
+
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	if src.rates != nil && !info.ModTime().After(src.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(src.path)
+	if err != nil {
+		return err
+	}
+
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	rates, err := parseRates(raw)
+	if err != nil {
+		return err
+	}
+
+	src.rates = rates
+	src.modTime = info.ModTime()
+	return nil
+}
@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: rate-source.go
+
+// Package mock_fx is a generated GoMock package.
+package mock_fx
+
+import (
+	big "math/big"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockRateSource is a mock of RateSource interface.
+type MockRateSource struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateSourceMockRecorder
+}
+
+// MockRateSourceMockRecorder is the mock recorder for MockRateSource.
+type MockRateSourceMockRecorder struct {
+	mock *MockRateSource
+}
+
+// NewMockRateSource creates a new mock instance.
+func NewMockRateSource(ctrl *gomock.Controller) *MockRateSource {
+	mock := &MockRateSource{ctrl: ctrl}
+	mock.recorder = &MockRateSourceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateSource) EXPECT() *MockRateSourceMockRecorder {
+	return m.recorder
+}
+
+// GetRate mocks base method.
+func (m *MockRateSource) GetRate(base, quote string) (*big.Rat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRate", base, quote)
+	ret0, _ := ret[0].(*big.Rat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRate indicates an expected call of GetRate.
+func (mr *MockRateSourceMockRecorder) GetRate(base, quote interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRate", reflect.TypeOf((*MockRateSource)(nil).GetRate), base, quote)
+}
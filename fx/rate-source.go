@@ -0,0 +1,79 @@
+package fx
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//go:generate mockgen -source=rate-source.go -destination=mock/rate-source.go
+
+// RateSource provides the mid-market rate to convert one unit of base currency
+// into quote currency.
+type RateSource interface {
+	GetRate(base string, quote string) (*big.Rat, error)
+}
+
+// ErrNoRate is returned when no rate is known for a currency pair.
+var ErrNoRate = errors.New("no FX rate")
+
+// DefaultRates are used when no rates file is configured.
+var DefaultRates = map[string]string{
+	"USD/TWD": "32.5",
+	"EUR/TWD": "35.2",
+	"JPY/TWD": "0.215",
+	"EUR/USD": "1.083",
+	"USD/JPY": "151.2",
+	"EUR/JPY": "163.75",
+}
+
+// NewRateSource returns a file-backed rate source when FX_RATES_FILE is set,
+// otherwise a static source with DefaultRates.
+func NewRateSource() RateSource {
+	path := os.Getenv("FX_RATES_FILE")
+	if path == "" {
+		return NewStaticRateSource(DefaultRates)
+	}
+
+	source, err := NewFileRateSource(path)
+	if err != nil {
+		log.Panicf("Failed to load FX rates from '%s': %v", path, err)
+	}
+	return source
+}
+
+func pairKey(base string, quote string) string {
+	return fmt.Sprintf("%s/%s", base, quote)
+}
+
+// lookup finds a rate directly or by inverting the opposite pair.
+func lookup(rates map[string]*big.Rat, base string, quote string) (*big.Rat, error) {
+	if base == quote {
+		return big.NewRat(1, 1), nil
+	}
+
+	if rate, ok := rates[pairKey(base, quote)]; ok {
+		return new(big.Rat).Set(rate), nil
+	}
+
+	if rate, ok := rates[pairKey(quote, base)]; ok && rate.Sign() != 0 {
+		return new(big.Rat).Inv(rate), nil
+	}
+
+	return nil, fmt.Errorf("%w for %s/%s", ErrNoRate, base, quote)
+}
+
+func parseRates(raw map[string]string) (map[string]*big.Rat, error) {
+	rates := make(map[string]*big.Rat, len(raw))
+	for pair, value := range raw {
+		rate, ok := new(big.Rat).SetString(value)
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid FX rate '%s' for %s", value, pair)
+		}
+		rates[pair] = rate
+	}
+	return rates, nil
+}
@@ -0,0 +1,44 @@
+package fx_test
+
+import (
+	"banking-system/fx"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticRateSource_DirectAndInverse(t *testing.T) {
+	src := fx.NewStaticRateSource(map[string]string{"USD/TWD": "32"})
+
+	rate, err := src.GetRate("USD", "TWD")
+	assert.Nil(t, err)
+	assert.Equal(t, "32", rate.RatString())
+
+	rate, err = src.GetRate("TWD", "USD")
+	assert.Nil(t, err)
+	assert.Equal(t, "1/32", rate.RatString())
+
+	_, err = src.GetRate("TWD", "JPY")
+	assert.NotNil(t, err)
+}
+
+func TestFileRateSource_ReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	os.WriteFile(path, []byte(`{"USD/TWD": "32.5"}`), 0o600)
+
+	src, err := fx.NewFileRateSource(path)
+	assert.Nil(t, err)
+
+	rate, _ := src.GetRate("USD", "TWD")
+	assert.Equal(t, "65/2", rate.RatString())
+
+	os.WriteFile(path, []byte(`{"USD/TWD": "33"}`), 0o600)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+
+	rate, _ = src.GetRate("USD", "TWD")
+	assert.Equal(t, "33", rate.RatString())
+}
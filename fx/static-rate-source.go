@@ -0,0 +1,26 @@
+package fx
+
+import (
+	"math/big"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type staticRateSource struct {
+	rates map[string]*big.Rat
+}
+
+// NewStaticRateSource serves fixed rates keyed by "BASE/QUOTE", e.g.
+// {"USD/TWD": "32.5"}.
+func NewStaticRateSource(rates map[string]string) RateSource {
+	parsed, err := parseRates(rates)
+	if err != nil {
+		log.Panicf("Invalid static FX rates: %v", err)
+	}
+
+	return &staticRateSource{rates: parsed}
+}
+
+func (src *staticRateSource) GetRate(base string, quote string) (*big.Rat, error) {
+	return lookup(src.rates, base, quote)
+}
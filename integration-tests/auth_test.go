@@ -0,0 +1,105 @@
+package integration_test
+
+import (
+	"banking-system/auth"
+	"banking-system/middleware"
+	"banking-system/models"
+	"banking-system/repos"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuth_MissingToken(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100.00")
+	res := postRequest("/api/v1/payments/transfer", transferBody(user.Username))
+
+	expectProblem(t, res, http.StatusUnauthorized, "missing_token")
+}
+
+func TestAuth_InvalidToken(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100.00")
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/payments/transfer", bytes.NewReader(transferBody(user.Username)))
+	req.Header.Set(middleware.AUTHORIZATION_HEADER, "Bearer not-a-jwt")
+	r.ServeHTTP(res, req)
+
+	expectProblem(t, res, http.StatusUnauthorized, "invalid_token")
+}
+
+func TestAuth_RawUserIDHeaderIsNotTrusted(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100.00")
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/payments/transfer", bytes.NewReader(transferBody(user.Username)))
+	req.Header.Set(middleware.USER_ID_HEADER, "1")
+	r.ServeHTTP(res, req)
+
+	expectProblem(t, res, http.StatusUnauthorized, "missing_token")
+}
+
+func TestAuth_LoginCookieAuthenticates(t *testing.T) {
+	truncateTables()
+
+	registerBody, _ := json.Marshal(&models.RegisterRequest{Username: "johndoe", Password: "password123", Name: "John Doe"})
+	assert.Equal(t, http.StatusCreated, postRequest("/api/v1/user", registerBody).Code)
+
+	loginBody, _ := json.Marshal(&models.LoginRequest{Username: "johndoe", Password: "password123"})
+	login := postRequest("/api/v1/user/login", loginBody)
+	assert.Equal(t, http.StatusOK, login.Code)
+
+	var cookie *http.Cookie
+	for _, c := range login.Result().Cookies() {
+		if c.Name == auth.AUTH_COOKIE_NAME {
+			cookie = c
+		}
+	}
+	assert.NotNil(t, cookie)
+
+	walletBody, _ := json.Marshal(&models.OpenWalletRequest{Currency: "USD"})
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/user/wallets", bytes.NewReader(walletBody))
+	req.AddCookie(cookie)
+	r.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusCreated, res.Code)
+}
+
+func TestAuth_TrustedGatewayMode(t *testing.T) {
+	t.Setenv("AUTH_TRUSTED_GATEWAY", "y")
+
+	engine := gin.New()
+	engine.Use(middleware.CorrelationID(), middleware.ErrorHandler(), middleware.Authenticate(repos.NewSessionRepo()))
+	engine.GET("/whoami", func(c *gin.Context) {
+		userID, _ := auth.UserID(c)
+		c.JSON(http.StatusOK, gin.H{"user_id": userID})
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/whoami", nil)
+	req.Header.Set(middleware.USER_ID_HEADER, "42")
+	engine.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"user_id": 42}`, res.Body.String())
+}
+
+func transferBody(recipientUsername string) []byte {
+	body, _ := json.Marshal(&models.TransferRequest{
+		UUID:              uuid.New(),
+		RecipientUsername: recipientUsername,
+		Amount:            twd("10.00"),
+	})
+	return body
+}
@@ -0,0 +1,91 @@
+package integration_test
+
+import (
+	"banking-system/controllers"
+	"banking-system/database"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/psp"
+	"banking-system/repos"
+	"banking-system/services"
+	"encoding/json"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	pspMock "banking-system/psp/mock"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWithdraw_ConcurrentDistinctRequestsNeverOverdraw(t *testing.T) {
+	truncateTables()
+
+	mockPSPFactory := new(pspMock.MockPSPFactoryTestify)
+	mockPaymentProvider := new(pspMock.MockPaymentServiceProviderTestify)
+	mockPSPFactory.On("NewPaymentServiceProvider", psp.PaymentMethod("AnyPay")).Return(mockPaymentProvider, nil)
+	mockPaymentProvider.On("PayOut", mock.Anything).Return(&psp.PayOutResponse{}, nil)
+
+	user := givenUserHasBalance("100.00")
+	bankAccount := givenBankAccount(user.ID)
+	sut := controllers.NewPaymentController(services.NewPaymentService(repos.NewUserRepo(), repos.NewTransactionRepo(), repos.NewBankAccountRepo(), mockPSPFactory, nil, newLimitService()))
+
+	// Every request reads a balance of 100.00 before any of them commits
+	concurrentCount := 10
+	successCount, _ := concurrentExec(func() *httptest.ResponseRecorder {
+		req, _ := json.Marshal(&models.WithdrawRequest{
+			UUID:          uuid.New(),
+			PaymentMethod: "AnyPay",
+			BankAccountID: bankAccount.ID,
+			Amount:        twd("30.00"),
+		})
+		return postRequestWithHandler("/api/v1/payments/withdraw", sut.Withdraw, req, user.ID)
+	}, concurrentCount)
+
+	assert.Equal(t, 3, len(successCount), "Only as many withdrawals as the balance covers should succeed")
+	expectBalance(t, user.Wallets[0].ID, "10.00")
+	expectLedgerReconciled(t)
+}
+
+func TestTransfer_ConcurrentOppositeDirections(t *testing.T) {
+	truncateTables()
+
+	alice := givenUserHasBalance("500.00")
+	bob := givenUserHasBalance("500.00")
+
+	// Transfers in both directions lock the same two wallets; they must not
+	// deadlock and must never leave either wallet negative.
+	concurrentCount := 20
+	var wg sync.WaitGroup
+	wg.Add(concurrentCount)
+	for i := range concurrentCount {
+		go func(index int) {
+			defer wg.Done()
+			sender, recipient := alice, bob
+			if index%2 == 1 {
+				sender, recipient = bob, alice
+			}
+
+			body, _ := json.Marshal(&models.TransferRequest{
+				UUID:              uuid.New(),
+				RecipientUsername: recipient.Username,
+				Amount:            twd("60.00"),
+			})
+			postRequest("/api/v1/payments/transfer", body, sender.ID)
+		}(i)
+	}
+	wg.Wait()
+
+	var wallets []entities.Wallet
+	database.DB.Where("id IN ?", []uint{alice.Wallets[0].ID, bob.Wallets[0].ID}).Find(&wallets)
+
+	total := twd("0")
+	for _, wallet := range wallets {
+		assert.False(t, wallet.Balance.IsNegative(), "wallet %d went negative", wallet.ID)
+		total, _ = total.Add(wallet.Balance)
+	}
+	assert.Equal(t, twd("1000.00"), total, "Transfers must not create or destroy money")
+	expectLedgerReconciled(t)
+}
@@ -0,0 +1,86 @@
+package integration_test
+
+import (
+	"banking-system/middleware"
+	"banking-system/models"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrors_DomainErrorIsProblemDocument(t *testing.T) {
+	truncateTables()
+
+	sender := givenUserHasBalance("10.00")
+	recipient := givenUserHasBalance("0")
+
+	body, _ := json.Marshal(&models.TransferRequest{
+		UUID:              uuid.New(),
+		RecipientUsername: recipient.Username,
+		Amount:            twd("50.00"),
+	})
+	res := postRequest("/api/v1/payments/transfer", body, sender.ID)
+
+	problem := expectProblem(t, res, http.StatusUnprocessableEntity, "insufficient_funds")
+	assert.Equal(t, "/api/v1/payments/transfer", problem.Instance)
+	assert.Equal(t, res.Header().Get(middleware.CORRELATION_ID_HEADER), problem.CorrelationID)
+}
+
+func TestErrors_NotFound(t *testing.T) {
+	truncateTables()
+
+	res := getRequest("/api/v1/user/12345")
+
+	expectProblem(t, res, http.StatusNotFound, "user_not_found")
+}
+
+func TestErrors_CorrelationIDIsEchoed(t *testing.T) {
+	truncateTables()
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user/12345", nil)
+	req.Header.Set(middleware.CORRELATION_ID_HEADER, "trace-abc-123")
+	r.ServeHTTP(res, req)
+
+	problem := expectProblem(t, res, http.StatusNotFound, "user_not_found")
+	assert.Equal(t, "trace-abc-123", res.Header().Get(middleware.CORRELATION_ID_HEADER))
+	assert.Equal(t, "trace-abc-123", problem.CorrelationID)
+}
+
+func TestErrors_InvalidBodyIsValidationProblem(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("10.00")
+	res := postRequest("/api/v1/payments/transfer", []byte(`{"amount": "abc"}`), user.ID)
+
+	expectProblem(t, res, http.StatusBadRequest, "invalid_request")
+}
+
+func getRequest(path string, userID ...uint) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, bytes.NewReader(nil))
+	if len(userID) > 0 {
+		authorize(req, userID[0])
+	}
+
+	r.ServeHTTP(res, req)
+	return res
+}
+
+func expectProblem(t *testing.T, res *httptest.ResponseRecorder, status int, code string) models.ProblemResponse {
+	var problem models.ProblemResponse
+	err := json.Unmarshal(res.Body.Bytes(), &problem)
+
+	assert.Nil(t, err)
+	assert.Equal(t, status, res.Code)
+	assert.Equal(t, middleware.PROBLEM_CONTENT_TYPE, res.Header().Get("Content-Type"))
+	assert.Equal(t, status, problem.Status)
+	assert.Equal(t, code, problem.Code)
+	assert.NotEmpty(t, problem.CorrelationID)
+	return problem
+}
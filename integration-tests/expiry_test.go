@@ -0,0 +1,175 @@
+package integration_test
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"banking-system/psp"
+	"banking-system/repos"
+	"banking-system/services"
+	"testing"
+	"time"
+
+	pspMock "banking-system/psp/mock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var expiryConfig = services.ExpiryConfig{
+	DefaultTTL: 24 * time.Hour,
+	TTLs:       map[psp.PaymentMethod]time.Duration{psp.PaymentMethods.FakePay: time.Hour},
+	QueryPSP:   true,
+}
+
+func TestExpiry_CancelsAbandonedDeposit(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100")
+	expired := givenPendingDeposit(user, psp.PaymentMethods.FakePay, 2*time.Hour)
+	recent := givenPendingDeposit(user, psp.PaymentMethods.FakePay, 10*time.Minute)
+	withinDefaultTTL := givenPendingDeposit(user, psp.PaymentMethods.BankTransfer, 2*time.Hour)
+
+	sut := services.NewExpiryService(repos.NewTransactionRepo(), psp.NewPSPFactory(), expiryConfig)
+	assert.Nil(t, sut.SweepExpired())
+
+	expectTransactionStatus(t, expired.String(), entities.TransactionStatuses.Canceled)
+	expectTransactionStatus(t, recent.String(), entities.TransactionStatuses.Pending)
+	expectTransactionStatus(t, withinDefaultTTL.String(), entities.TransactionStatuses.Pending)
+	expectBalance(t, user.Wallets[0].ID, "100.00")
+
+	var history []entities.TransactionStatusChange
+	database.DB.Where("transaction_id = ?", expired).Find(&history)
+	assert.Len(t, history, 1)
+	assert.Equal(t, entities.TransitionActors.System, history[0].Actor)
+	assert.Equal(t, entities.TransactionStatuses.Canceled, history[0].ToStatus)
+}
+
+func TestExpiry_CompletesDepositPaidAtProvider(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100")
+	txUUID := givenPendingDeposit(user, psp.PaymentMethods.FakePay, 2*time.Hour)
+
+	// The confirm callback was lost, but the payer did pay
+	ctrl := gomock.NewController(t)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
+	pspFactoryMock.EXPECT().NewPaymentServiceProvider(psp.PaymentMethods.FakePay).Return(paymentProviderMock, nil)
+	paymentProviderMock.EXPECT().GetPayInStatus(gomock.Any()).Return(&psp.PayInStatusResponse{Status: psp.PayInStatuses.Paid}, nil)
+
+	sut := services.NewExpiryService(repos.NewTransactionRepo(), pspFactoryMock, expiryConfig)
+	assert.Nil(t, sut.SweepExpired())
+
+	expectTransactionStatus(t, txUUID.String(), entities.TransactionStatuses.Completed)
+	expectBalance(t, user.Wallets[0].ID, "150.00")
+	expectLedgerReconciled(t)
+}
+
+func TestExpiry_FailsWithdrawalUnknownToProvider(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("200.00")
+	txUUID := givenFakePayWithdrawal(t, user, "50.00")
+	ageTransaction(txUUID, 2*time.Hour)
+
+	ctrl := gomock.NewController(t)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
+	pspFactoryMock.EXPECT().NewPaymentServiceProvider(psp.PaymentMethods.FakePay).Return(paymentProviderMock, nil)
+	paymentProviderMock.EXPECT().GetPayOutStatus(gomock.Any()).Return(nil, psp.ErrPayOutNotFound)
+
+	sut := services.NewExpiryService(repos.NewTransactionRepo(), pspFactoryMock, expiryConfig)
+	assert.Nil(t, sut.SweepExpired())
+
+	expectTransactionStatus(t, txUUID.String(), entities.TransactionStatuses.Failed)
+	expectBalance(t, user.Wallets[0].ID, "200.00")
+	expectLedgerReconciled(t)
+}
+
+func TestExpiry_KeepsWithdrawalInProgressAtProvider(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("200.00")
+	txUUID := givenFakePayWithdrawal(t, user, "50.00")
+	ageTransaction(txUUID, 2*time.Hour)
+
+	// FakePay reports every payout as PROCESSING
+	sut := services.NewExpiryService(repos.NewTransactionRepo(), psp.NewPSPFactory(), expiryConfig)
+	assert.Nil(t, sut.SweepExpired())
+
+	expectTransactionStatus(t, txUUID.String(), entities.TransactionStatuses.Pending)
+	expectBalance(t, user.Wallets[0].ID, "150.00")
+}
+
+func TestExpiry_FlagsWithdrawalForReviewWithoutAskingProvider(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("200.00")
+	txUUID := givenFakePayWithdrawal(t, user, "50.00")
+	ageTransaction(txUUID, 2*time.Hour)
+
+	cfg := expiryConfig
+	cfg.QueryPSP = false
+	sut := services.NewExpiryService(repos.NewTransactionRepo(), nil, cfg)
+	assert.Nil(t, sut.SweepExpired())
+
+	tx := getTransactionByUUID(t, txUUID)
+	assert.Equal(t, entities.TransactionStatuses.Pending, tx.Status)
+	assert.True(t, tx.NeedsReview)
+	expectBalance(t, user.Wallets[0].ID, "150.00")
+
+	// A flagged withdrawal is not swept again
+	expired, err := repos.NewTransactionRepo().GetExpiredPending(nil, time.Now(), 10)
+	assert.Nil(t, err)
+	assert.Empty(t, expired)
+}
+
+func TestJobLease_OnlyOneHolder(t *testing.T) {
+	truncateTables()
+	sut := repos.NewJobLeaseRepo()
+
+	acquired, err := sut.Acquire("sweep", "replica-a", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, acquired)
+
+	acquired, _ = sut.Acquire("sweep", "replica-b", time.Minute)
+	assert.False(t, acquired, "another replica must not take an unexpired lease")
+
+	acquired, _ = sut.Acquire("sweep", "replica-a", time.Minute)
+	assert.True(t, acquired, "the holder must be able to renew its lease")
+}
+
+func TestJobLease_TakenOverAfterExpiry(t *testing.T) {
+	truncateTables()
+	sut := repos.NewJobLeaseRepo()
+
+	acquired, _ := sut.Acquire("sweep", "replica-a", time.Millisecond)
+	assert.True(t, acquired)
+
+	time.Sleep(10 * time.Millisecond)
+
+	acquired, err := sut.Acquire("sweep", "replica-b", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, acquired)
+}
+
+func givenPendingDeposit(user *entities.User, method psp.PaymentMethod, age time.Duration) uuid.UUID {
+	txUUID := uuid.New()
+	givenTransaction(&entities.Transaction{
+		UUID:          txUUID,
+		Type:          entities.TransactionTypes.Deposit,
+		Status:        entities.TransactionStatuses.Pending,
+		Amount:        twd("50.00"),
+		PaymentMethod: method,
+		WalletID:      user.Wallets[0].ID,
+	})
+	ageTransaction(txUUID, age)
+	return txUUID
+}
+
+func ageTransaction(txUUID uuid.UUID, age time.Duration) {
+	database.DB.Model(&entities.Transaction{}).
+		Where("uuid = ?", txUUID).
+		Update("created_at", time.Now().Add(-age))
+}
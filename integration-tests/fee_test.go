@@ -0,0 +1,254 @@
+package integration_test
+
+import (
+	"banking-system/controllers"
+	"banking-system/database"
+	"banking-system/entities"
+	"banking-system/fees"
+	"banking-system/models"
+	"banking-system/psp"
+	"banking-system/repos"
+	"banking-system/services"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	pspMock "banking-system/psp/mock"
+)
+
+var integrationFeeSchedules = fees.MustParse([]fees.Schedule{
+	{ID: "deposit", Operation: fees.Operations.Deposit, Currency: "TWD", Percent: "2"},
+	{ID: "withdrawal", Operation: fees.Operations.Withdrawal, Currency: "TWD", Fixed: "15"},
+	{ID: "transfer", Operation: fees.Operations.Transfer, Currency: "TWD", Percent: "1", Min: "5"},
+})
+
+func TestFee_TransferChargesSender(t *testing.T) {
+	truncateTables()
+	sut := newFeePaymentController(nil)
+
+	sender := givenUserHasBalance("200.00")
+	recipient := givenUserHasBalance("50.00")
+
+	transferOutUUID := uuid.New()
+	body, _ := json.Marshal(&models.TransferRequest{
+		UUID:              transferOutUUID,
+		RecipientUsername: recipient.Username,
+		Amount:            twd("100.00"),
+	})
+	res := postRequestWithHandler("/api/v1/payments/transfer", sut.Transfer, body, sender.ID)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	expectBalance(t, sender.Wallets[0].ID, "95.00")
+	expectBalance(t, recipient.Wallets[0].ID, "150.00")
+	expectLedgerAccountBalance(t, "fees:TWD", "5.00")
+	expectHouseFeeWalletBalance(t, "5.00")
+	expectFeeTransaction(t, transferOutUUID, "5.00", entities.TransactionStatuses.Completed)
+	expectLedgerReconciled(t)
+}
+
+func TestFee_TransferBalanceMustCoverFee(t *testing.T) {
+	truncateTables()
+	sut := newFeePaymentController(nil)
+
+	sender := givenUserHasBalance("100.00")
+	recipient := givenUserHasBalance("50.00")
+
+	body, _ := json.Marshal(&models.TransferRequest{
+		UUID:              uuid.New(),
+		RecipientUsername: recipient.Username,
+		Amount:            twd("100.00"),
+	})
+	res := postRequestWithHandler("/api/v1/payments/transfer", sut.Transfer, body, sender.ID)
+
+	expectProblem(t, res, http.StatusUnprocessableEntity, "insufficient_funds")
+	expectBalance(t, sender.Wallets[0].ID, "100.00")
+}
+
+func TestFee_DepositFeeCollectedOnConfirm(t *testing.T) {
+	truncateTables()
+
+	mockPSPFactory := new(pspMock.MockPSPFactoryTestify)
+	mockPaymentProvider := new(pspMock.MockPaymentServiceProviderTestify)
+	mockPSPFactory.On("NewPaymentServiceProvider", psp.PaymentMethods.FakePay).Return(mockPaymentProvider, nil)
+	mockPaymentProvider.On("PayIn", mock.Anything).Return(&psp.PayInResponse{}, nil)
+	sut := newFeePaymentController(mockPSPFactory)
+
+	user := givenUserHasBalance("0")
+	depositUUID := uuid.New()
+	body, _ := json.Marshal(&models.DepositRequest{
+		UUID:          depositUUID,
+		Amount:        twd("100.00"),
+		PaymentMethod: psp.PaymentMethods.FakePay,
+	})
+	assert.Equal(t, http.StatusOK, postRequestWithHandler("/api/v1/payments/deposit", sut.Deposit, body, user.ID).Code)
+	expectFeeTransaction(t, depositUUID, "2.00", entities.TransactionStatuses.Pending)
+
+	body, _ = json.Marshal(&psp.ConfirmRequest{TransactionID: depositUUID.String()})
+	assert.Equal(t, http.StatusOK, postRequestWithPSPAuth("/api/v1/payments/confirm", body).Code)
+
+	expectBalance(t, user.Wallets[0].ID, "98.00")
+	expectLedgerAccountBalance(t, "fees:TWD", "2.00")
+	expectHouseFeeWalletBalance(t, "2.00")
+	expectFeeTransaction(t, depositUUID, "2.00", entities.TransactionStatuses.Completed)
+	expectLedgerReconciled(t)
+}
+
+func TestFee_RejectedWithdrawalReturnsFee(t *testing.T) {
+	truncateTables()
+
+	mockPSPFactory := new(pspMock.MockPSPFactoryTestify)
+	mockPaymentProvider := new(pspMock.MockPaymentServiceProviderTestify)
+	mockPSPFactory.On("NewPaymentServiceProvider", psp.PaymentMethods.FakePay).Return(mockPaymentProvider, nil)
+	mockPaymentProvider.On("PayOut", mock.Anything).Return(&psp.PayOutResponse{}, nil)
+	sut := newFeePaymentController(mockPSPFactory)
+
+	user := givenUserHasBalance("100.00")
+	bankAccount := givenBankAccount(user.ID)
+	withdrawalUUID := uuid.New()
+	body, _ := json.Marshal(&models.WithdrawRequest{
+		UUID:          withdrawalUUID,
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		BankAccountID: bankAccount.ID,
+		Amount:        twd("50.00"),
+	})
+	assert.Equal(t, http.StatusOK, postRequestWithHandler("/api/v1/payments/withdraw", sut.Withdraw, body, user.ID).Code)
+	expectBalance(t, user.Wallets[0].ID, "35.00")
+	expectFeeTransaction(t, withdrawalUUID, "15.00", entities.TransactionStatuses.Completed)
+	expectHouseFeeWalletBalance(t, "15.00")
+
+	assert.Equal(t, http.StatusOK, postPayOutStatus(psp.PaymentMethods.FakePay, withdrawalUUID, psp.PayOutStatuses.Rejected).Code)
+
+	expectBalance(t, user.Wallets[0].ID, "100.00")
+	expectLedgerAccountBalance(t, "fees:TWD", "0.00")
+	expectHouseFeeWalletBalance(t, "0.00")
+	expectFeeTransaction(t, withdrawalUUID, "15.00", entities.TransactionStatuses.Failed)
+	expectLedgerReconciled(t)
+}
+
+func TestFee_ShownInTransactionHistory(t *testing.T) {
+	truncateTables()
+	sut := newFeePaymentController(nil)
+
+	sender := givenUserHasBalance("200.00")
+	recipient := givenUserHasBalance("50.00")
+
+	transferOutUUID := uuid.New()
+	body, _ := json.Marshal(&models.TransferRequest{
+		UUID:              transferOutUUID,
+		RecipientUsername: recipient.Username,
+		Amount:            twd("100.00"),
+	})
+	assert.Equal(t, http.StatusOK, postRequestWithHandler("/api/v1/payments/transfer", sut.Transfer, body, sender.ID).Code)
+
+	res := getRequest("/api/v1/transactions", sender.ID)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var page models.TransactionPageResponse
+	json.Unmarshal(res.Body.Bytes(), &page)
+	assert.Len(t, page.Transactions, 2)
+
+	for _, tx := range page.Transactions {
+		switch tx.Type {
+		case entities.TransactionTypes.TransferOut:
+			assert.Equal(t, twd("5.00"), *tx.Fee)
+		case entities.TransactionTypes.Fee:
+			assert.Equal(t, twd("5.00"), tx.Amount)
+			assert.Equal(t, transferOutUUID, *tx.RelatedTransactionID)
+		default:
+			t.Errorf("unexpected %s transaction", tx.Type)
+		}
+	}
+
+	res = getRequest(fmt.Sprintf("/api/v1/transactions/user/%d", sender.ID), sender.ID)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var transactions []models.TransactionResponse
+	json.Unmarshal(res.Body.Bytes(), &transactions)
+	for _, tx := range transactions {
+		if tx.Type == entities.TransactionTypes.TransferOut {
+			assert.Equal(t, twd("5.00"), *tx.Fee)
+		}
+	}
+}
+
+func TestFee_Quote(t *testing.T) {
+	truncateTables()
+	sut := controllers.NewFeeController(services.NewFeeService(repos.NewUserRepo(), integrationFeeSchedules))
+
+	user := givenUserHasBalance("0")
+	body, _ := json.Marshal(&models.FeeQuoteRequest{
+		Operation: fees.Operations.Transfer,
+		Amount:    twd("1000.00"),
+	})
+	res := postRequestWithHandler("/api/v1/fees/quotes", sut.Quote, body, user.ID)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var quote models.FeeQuoteResponse
+	json.Unmarshal(res.Body.Bytes(), &quote)
+	assert.Equal(t, twd("10.00"), quote.Fee)
+	assert.Equal(t, twd("1010.00"), quote.Total)
+}
+
+func newFeePaymentController(pspFactory psp.PSPFactory) controllers.PaymentController {
+	return controllers.NewPaymentController(services.NewPaymentService(repos.NewUserRepo(), repos.NewTransactionRepo(), repos.NewBankAccountRepo(), pspFactory, integrationFeeSchedules, newLimitService()))
+}
+
+func TestFee_HouseUsernameCannotBeRegistered(t *testing.T) {
+	truncateTables()
+
+	body, _ := json.Marshal(&models.RegisterRequest{Username: entities.HouseUsername, Password: "password123", Name: "House"})
+	res := postRequest("/api/v1/user", body)
+
+	expectProblem(t, res, http.StatusConflict, "username_taken")
+}
+
+func TestFee_OrdinaryUserWithHouseUsernameDoesNotCollectFees(t *testing.T) {
+	truncateTables()
+	sut := newFeePaymentController(nil)
+
+	squatter := &entities.User{Username: entities.HouseUsername, PasswordHash: "hash"}
+	assert.Nil(t, database.DB.Create(squatter).Error)
+	sender := givenUserHasBalance("200.00")
+	recipient := givenUserHasBalance("50.00")
+
+	body, _ := json.Marshal(&models.TransferRequest{
+		UUID:              uuid.New(),
+		RecipientUsername: recipient.Username,
+		Amount:            twd("100.00"),
+	})
+	res := postRequestWithHandler("/api/v1/payments/transfer", sut.Transfer, body, sender.ID)
+
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	expectBalance(t, sender.Wallets[0].ID, "200.00")
+
+	var wallets int64
+	database.DB.Model(&entities.Wallet{}).Where("user_id = ?", squatter.ID).Count(&wallets)
+	assert.Zero(t, wallets)
+}
+
+func expectHouseFeeWalletBalance(t *testing.T, amount string) {
+	var house entities.User
+	result := database.DB.Preload("Wallets").Where("is_house = ?", true).First(&house)
+	assert.Nil(t, result.Error)
+
+	wallet, ok := house.WalletFor("TWD")
+	if assert.True(t, ok, "house fee wallet for TWD") {
+		expectBalance(t, wallet.ID, amount)
+	}
+}
+
+func expectFeeTransaction(t *testing.T, txUUID uuid.UUID, amount string, status entities.TransactionStatus) {
+	var fee entities.Transaction
+	result := database.DB.Where("related_transaction_id = ? AND type = ?", txUUID, entities.TransactionTypes.Fee).First(&fee)
+
+	assert.Nil(t, result.Error)
+	assert.Equal(t, twd(amount), fee.Amount)
+	assert.Equal(t, status, fee.Status)
+	assert.Equal(t, twd(amount), getTransactionByUUID(t, txUUID).FeeAmount)
+}
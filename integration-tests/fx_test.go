@@ -0,0 +1,86 @@
+package integration_test
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/money"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvert_Success(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalances(twd("0"), money.MustParse("100.00", "USD"))
+	quote := requestQuote(t, user.ID, "USD", "TWD", "10.00")
+
+	convertUUID := uuid.New()
+	body, _ := json.Marshal(&models.ConvertRequest{UUID: convertUUID, QuoteID: quote.QuoteID})
+	res := postRequest("/api/v1/payments/convert", body, user.ID)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	expectWalletBalance(t, user.Wallets[1].ID, money.MustParse("90.00", "USD"))
+	expectWalletBalance(t, user.Wallets[0].ID, quote.ToAmount.WithCurrency("TWD"))
+	expectConversionTransactionLinked(t, convertUUID)
+	expectLedgerReconciled(t)
+}
+
+func TestConvert_QuoteUsedTwice(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalances(twd("0"), money.MustParse("100.00", "USD"))
+	quote := requestQuote(t, user.ID, "USD", "TWD", "10.00")
+
+	first, _ := json.Marshal(&models.ConvertRequest{UUID: uuid.New(), QuoteID: quote.QuoteID})
+	assert.Equal(t, http.StatusOK, postRequest("/api/v1/payments/convert", first, user.ID).Code)
+
+	second, _ := json.Marshal(&models.ConvertRequest{UUID: uuid.New(), QuoteID: quote.QuoteID})
+	assert.Equal(t, http.StatusConflict, postRequest("/api/v1/payments/convert", second, user.ID).Code)
+
+	expectWalletBalance(t, user.Wallets[1].ID, money.MustParse("90.00", "USD"))
+}
+
+func TestConvert_ExpiredQuote(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalances(twd("0"), money.MustParse("100.00", "USD"))
+	quote := requestQuote(t, user.ID, "USD", "TWD", "10.00")
+	database.DB.Model(&entities.FXQuote{}).Where("uuid = ?", quote.QuoteID).Update("expires_at", time.Now().Add(-time.Minute))
+
+	body, _ := json.Marshal(&models.ConvertRequest{UUID: uuid.New(), QuoteID: quote.QuoteID})
+	res := postRequest("/api/v1/payments/convert", body, user.ID)
+
+	assert.Equal(t, http.StatusConflict, res.Code)
+	expectWalletBalance(t, user.Wallets[1].ID, money.MustParse("100.00", "USD"))
+}
+
+func requestQuote(t *testing.T, userID uint, from string, to string, amount string) *models.FXQuoteResponse {
+	body, _ := json.Marshal(&models.FXQuoteRequest{
+		FromCurrency: from,
+		ToCurrency:   to,
+		Amount:       money.MustParse(amount, ""),
+	})
+	res := postRequest("/api/v1/fx/quotes", body, userID)
+	assert.Equal(t, http.StatusCreated, res.Code)
+
+	var quote models.FXQuoteResponse
+	json.Unmarshal(res.Body.Bytes(), &quote)
+	return &quote
+}
+
+func expectConversionTransactionLinked(t *testing.T, conversionOutUUID uuid.UUID) {
+	var actual entities.Transaction
+	result := database.DB.Preload("RelatedTransaction").First(&actual, conversionOutUUID)
+
+	assert.Nil(t, result.Error)
+	assert.NotNil(t, actual.RelatedTransaction)
+	assert.Equal(t, entities.TransactionTypes.ConversionOut, actual.Type)
+	assert.Equal(t, entities.TransactionTypes.ConversionIn, actual.RelatedTransaction.Type)
+	assert.Equal(t, actual.UUID.String(), actual.RelatedTransaction.RelatedTransactionID.String())
+}
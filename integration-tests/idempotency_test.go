@@ -0,0 +1,254 @@
+package integration_test
+
+import (
+	"banking-system/apperrors"
+	"banking-system/database"
+	"banking-system/entities"
+	"banking-system/middleware"
+	"banking-system/models"
+	"banking-system/psp"
+	"banking-system/repos"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	pspMock "banking-system/psp/mock"
+)
+
+func TestIdempotency_ReplaysOriginalResponse(t *testing.T) {
+	truncateTables()
+
+	sender := givenUserHasBalance("200.00")
+	recipient := givenUserHasBalance("0")
+
+	body, _ := json.Marshal(&models.TransferRequest{
+		UUID:              uuid.New(),
+		RecipientUsername: recipient.Username,
+		Amount:            twd("50.00"),
+	})
+
+	first := postIdempotentRequest("/api/v1/payments/transfer", body, "transfer-1", sender.ID)
+	second := postIdempotentRequest("/api/v1/payments/transfer", body, "transfer-1", sender.ID)
+
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, first.Code, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(middleware.IDEMPOTENCY_REPLAYED_HEADER))
+	expectBalance(t, sender.Wallets[0].ID, "150.00", "Transfer should only be applied once")
+}
+
+func TestIdempotency_ReplaysErrorResponse(t *testing.T) {
+	truncateTables()
+
+	sender := givenUserHasBalance("10.00")
+	recipient := givenUserHasBalance("0")
+
+	body, _ := json.Marshal(&models.TransferRequest{
+		UUID:              uuid.New(),
+		RecipientUsername: recipient.Username,
+		Amount:            twd("50.00"),
+	})
+
+	first := postIdempotentRequest("/api/v1/payments/transfer", body, "transfer-1", sender.ID)
+	second := postIdempotentRequest("/api/v1/payments/transfer", body, "transfer-1", sender.ID)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, first.Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+}
+
+func TestIdempotency_DifferentBodyRejected(t *testing.T) {
+	truncateTables()
+
+	sender := givenUserHasBalance("200.00")
+	recipient := givenUserHasBalance("0")
+
+	firstBody, _ := json.Marshal(&models.TransferRequest{
+		UUID:              uuid.New(),
+		RecipientUsername: recipient.Username,
+		Amount:            twd("50.00"),
+	})
+	secondBody, _ := json.Marshal(&models.TransferRequest{
+		UUID:              uuid.New(),
+		RecipientUsername: recipient.Username,
+		Amount:            twd("60.00"),
+	})
+
+	first := postIdempotentRequest("/api/v1/payments/transfer", firstBody, "transfer-1", sender.ID)
+	second := postIdempotentRequest("/api/v1/payments/transfer", secondBody, "transfer-1", sender.ID)
+
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, second.Code)
+	expectBalance(t, sender.Wallets[0].ID, "150.00")
+}
+
+func TestIdempotency_KeysAreScopedToUser(t *testing.T) {
+	truncateTables()
+
+	alice := givenUserHasBalance("200.00")
+	bob := givenUserHasBalance("200.00")
+	recipient := givenUserHasBalance("0")
+
+	aliceBody, _ := json.Marshal(&models.TransferRequest{
+		UUID:              uuid.New(),
+		RecipientUsername: recipient.Username,
+		Amount:            twd("50.00"),
+	})
+	bobBody, _ := json.Marshal(&models.TransferRequest{
+		UUID:              uuid.New(),
+		RecipientUsername: recipient.Username,
+		Amount:            twd("50.00"),
+	})
+
+	assert.Equal(t, http.StatusOK, postIdempotentRequest("/api/v1/payments/transfer", aliceBody, "same-key", alice.ID).Code)
+	assert.Equal(t, http.StatusOK, postIdempotentRequest("/api/v1/payments/transfer", bobBody, "same-key", bob.ID).Code)
+	expectBalance(t, recipient.Wallets[0].ID, "100.00")
+}
+
+func TestIdempotency_ExpiredKeyCanBeReused(t *testing.T) {
+	truncateTables()
+
+	sender := givenUserHasBalance("200.00")
+	recipient := givenUserHasBalance("0")
+
+	firstBody, _ := json.Marshal(&models.TransferRequest{
+		UUID:              uuid.New(),
+		RecipientUsername: recipient.Username,
+		Amount:            twd("50.00"),
+	})
+	assert.Equal(t, http.StatusOK, postIdempotentRequest("/api/v1/payments/transfer", firstBody, "transfer-1", sender.ID).Code)
+
+	database.DB.Model(&entities.IdempotencyKey{}).
+		Where("key = ?", "transfer-1").
+		Update("expires_at", time.Now().Add(-time.Minute))
+
+	secondBody, _ := json.Marshal(&models.TransferRequest{
+		UUID:              uuid.New(),
+		RecipientUsername: recipient.Username,
+		Amount:            twd("60.00"),
+	})
+	res := postIdempotentRequest("/api/v1/payments/transfer", secondBody, "transfer-1", sender.ID)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	expectBalance(t, sender.Wallets[0].ID, "90.00")
+}
+
+func TestIdempotency_ConcurrentRequestsApplyOnce(t *testing.T) {
+	truncateTables()
+
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("0")
+
+	// Different transaction UUIDs, so only the key can deduplicate these
+	concurrentCount := 10
+	successCount, _ := concurrentExec(func() *httptest.ResponseRecorder {
+		body, _ := json.Marshal(&models.TransferRequest{
+			UUID:              uuid.New(),
+			RecipientUsername: recipient.Username,
+			Amount:            twd("100.00"),
+		})
+		return postIdempotentRequest("/api/v1/payments/transfer", body, "transfer-1", sender.ID)
+	}, concurrentCount)
+
+	assert.Equal(t, 1, len(successCount), "Exactly one request should succeed.")
+	expectBalance(t, sender.Wallets[0].ID, "900.00")
+}
+
+func TestIdempotency_ReplaysProviderFailureAfterTransactionCreated(t *testing.T) {
+	truncateTables()
+
+	mockPSPFactory := new(pspMock.MockPSPFactoryTestify)
+	mockPaymentProvider := new(pspMock.MockPaymentServiceProviderTestify)
+	mockPSPFactory.On("NewPaymentServiceProvider", psp.PaymentMethods.FakePay).Return(mockPaymentProvider, nil)
+	mockPaymentProvider.On("PayIn", mock.Anything).Return(nil, errors.New("connection reset")).Once()
+	sut := newFeePaymentController(mockPSPFactory)
+
+	user := givenUserHasBalance("0")
+	body, _ := json.Marshal(&models.DepositRequest{
+		UUID:          uuid.New(),
+		Amount:        twd("100.00"),
+		PaymentMethod: psp.PaymentMethods.FakePay,
+	})
+
+	first := postIdempotentRequestWithHandler("/api/v1/payments/deposit", sut.Deposit, body, "deposit-1", user.ID)
+	second := postIdempotentRequestWithHandler("/api/v1/payments/deposit", sut.Deposit, body, "deposit-1", user.ID)
+
+	assert.Equal(t, http.StatusBadGateway, first.Code)
+	assert.Equal(t, http.StatusBadGateway, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(middleware.IDEMPOTENCY_REPLAYED_HEADER))
+	mockPaymentProvider.AssertNumberOfCalls(t, "PayIn", 1)
+}
+
+func TestIdempotency_ReleasesKeyOfProviderFailureBeforeAnyChange(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("0")
+	calls := 0
+	failing := func(c *gin.Context) {
+		calls++
+		c.Error(apperrors.ProviderUnavailable("FX rate source", errors.New("connection reset")))
+	}
+
+	assert.Equal(t, http.StatusBadGateway, postIdempotentRequestWithHandler("/api/v1/fx/quotes", failing, nil, "quote-1", user.ID).Code)
+	assert.Equal(t, http.StatusBadGateway, postIdempotentRequestWithHandler("/api/v1/fx/quotes", failing, nil, "quote-1", user.ID).Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_InProgressKeyIsLeasedBriefly(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("0")
+	var leased entities.IdempotencyKey
+	handler := func(c *gin.Context) {
+		database.DB.Where("key = ?", "lease-1").First(&leased)
+		c.Status(http.StatusNoContent)
+	}
+
+	res := postIdempotentRequestWithHandler("/api/v1/user/wallets", handler, nil, "lease-1", user.ID)
+
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, entities.IdempotencyKeyStatuses.InProgress, leased.Status)
+	assert.True(t, leased.ExpiresAt.Before(time.Now().Add(5*time.Minute)), "an unfinished request holds its key briefly")
+
+	var completed entities.IdempotencyKey
+	database.DB.Where("key = ?", "lease-1").First(&completed)
+	assert.Equal(t, entities.IdempotencyKeyStatuses.Completed, completed.Status)
+	assert.True(t, completed.ExpiresAt.After(time.Now().Add(time.Hour)), "a finished request keeps its key for the TTL")
+}
+
+func postIdempotentRequest(path string, body []byte, key string, userID uint) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", path, bytes.NewReader(body))
+	req.Header.Add("Content-Type", "application/json")
+	authorize(req, userID)
+	req.Header.Set(middleware.IDEMPOTENCY_KEY_HEADER, key)
+
+	r.ServeHTTP(res, req)
+	return res
+}
+
+func postIdempotentRequestWithHandler(path string, handler func(c *gin.Context), body []byte, key string, userID uint) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(res)
+	r.Use(middleware.CorrelationID(), middleware.ErrorHandler(), middleware.Authenticate(repos.NewSessionRepo()),
+		middleware.Idempotency(repos.NewIdempotencyRepo()))
+	r.POST(path, handler)
+
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.IDEMPOTENCY_KEY_HEADER, key)
+	authorize(req, userID)
+
+	r.ServeHTTP(res, req)
+	return res
+}
@@ -0,0 +1,107 @@
+package integration_test
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"banking-system/middleware"
+	"banking-system/models"
+	"banking-system/psp"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLedger_DepositConfirmPostsBalancedEntry(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100")
+	txUUID := uuid.New()
+	givenTransaction(&entities.Transaction{
+		UUID:          txUUID,
+		Type:          entities.TransactionTypes.Deposit,
+		Status:        entities.TransactionStatuses.Pending,
+		Amount:        twd("50.00"),
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		WalletID:      user.Wallets[0].ID,
+	})
+
+	body, _ := json.Marshal(&psp.ConfirmRequest{TransactionID: txUUID.String()})
+	res := postRequestWithPSPAuth("/api/v1/payments/confirm", body)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	expectJournalEntryBalanced(t, txUUID)
+	expectLedgerAccountBalance(t, entities.WalletAccountCode(user.Wallets[0].ID), "150.00")
+	expectLedgerAccountBalance(t, entities.PSPClearingAccount(psp.PaymentMethods.FakePay, "TWD").Code, "-50.00")
+	expectLedgerReconciled(t)
+}
+
+func TestLedger_TransferPostsBalancedEntry(t *testing.T) {
+	truncateTables()
+
+	sender := givenUserHasBalance("200.00")
+	recipient := givenUserHasBalance("50.00")
+
+	txUUID := uuid.New()
+	body, _ := json.Marshal(&models.TransferRequest{
+		UUID:              txUUID,
+		RecipientUsername: recipient.Username,
+		Amount:            twd("10.00"),
+	})
+	res := postRequest("/api/v1/payments/transfer", body, sender.ID)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	expectJournalEntryBalanced(t, txUUID)
+	expectLedgerAccountBalance(t, entities.WalletAccountCode(sender.Wallets[0].ID), "190.00")
+	expectLedgerAccountBalance(t, entities.WalletAccountCode(recipient.Wallets[0].ID), "60.00")
+	expectLedgerReconciled(t)
+}
+
+func expectJournalEntryBalanced(t *testing.T, transactionID uuid.UUID) {
+	var entry entities.JournalEntry
+	result := database.DB.Preload("Postings").Where("transaction_id = ?", transactionID).First(&entry)
+
+	assert.Nil(t, result.Error)
+	assert.GreaterOrEqual(t, len(entry.Postings), 2)
+
+	sum := twd("0")
+	for _, p := range entry.Postings {
+		sum, _ = sum.Add(p.Amount)
+	}
+	assert.True(t, sum.IsZero(), "Postings should sum to zero")
+}
+
+func expectLedgerAccountBalance(t *testing.T, code string, amount string) {
+	var account entities.LedgerAccount
+	result := database.DB.Where("code = ?", code).First(&account)
+
+	assert.Nil(t, result.Error)
+	assert.Equal(t, twd(amount), account.Balance)
+}
+
+func expectLedgerReconciled(t *testing.T) {
+	operator := givenUserHasBalances()
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/ledger/reconciliation", nil)
+	authorize(req, operator.ID)
+	req.Header.Set(middleware.OPERATOR_KEY_HEADER, integrationOperatorKey)
+	r.ServeHTTP(res, req)
+
+	var report models.LedgerReconciliationResponse
+	json.Unmarshal(res.Body.Bytes(), &report)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.True(t, report.Balanced, "Ledger should reconcile: %+v", report.Discrepancies)
+}
+
+func TestLedger_ReconciliationRequiresOperator(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100")
+
+	expectProblem(t, getRequest("/api/v1/ledger/reconciliation"), http.StatusUnauthorized, "missing_token")
+	expectProblem(t, getRequest("/api/v1/ledger/reconciliation", user.ID), http.StatusUnauthorized, "missing_operator_key")
+}
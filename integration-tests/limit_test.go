@@ -0,0 +1,121 @@
+package integration_test
+
+import (
+	"banking-system/entities"
+	"banking-system/limits"
+	"banking-system/middleware"
+	"banking-system/models"
+	"banking-system/psp"
+	"banking-system/repos"
+	"banking-system/services"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimits_ShowsRemainingAllowance(t *testing.T) {
+	truncateTables()
+
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("50.00")
+	givenTransfer(t, sender, recipient, "300.00")
+
+	res := getRequest("/api/v1/limits", sender.ID)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var limitsRes []models.LimitResponse
+	json.Unmarshal(res.Body.Bytes(), &limitsRes)
+	assert.Len(t, limitsRes, len(limits.Types))
+
+	for _, l := range limitsRes {
+		assert.Equal(t, "TWD", l.Currency)
+		if l.Type != entities.TransactionTypes.TransferOut {
+			continue
+		}
+		assert.Equal(t, twd("100000.00"), *l.PerTransactionMax)
+		assert.Equal(t, twd("300.00"), l.Daily.Used)
+		assert.Equal(t, twd("199700.00"), l.Daily.Remaining)
+		assert.Equal(t, twd("999700.00"), l.Monthly.Remaining)
+	}
+}
+
+func TestLimits_OverrideDailyLimit(t *testing.T) {
+	truncateTables()
+
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("50.00")
+	daily := twd("500.00")
+
+	res := putLimitOverride(integrationOperatorKey, sender.ID, &models.LimitOverrideRequest{
+		Type:     entities.TransactionTypes.TransferOut,
+		Currency: "TWD",
+		Daily:    &daily,
+		Reason:   "new account",
+	})
+	assert.Equal(t, http.StatusNoContent, res.Code)
+
+	givenTransfer(t, sender, recipient, "300.00")
+	body, _ := json.Marshal(&models.TransferRequest{
+		UUID:              uuid.New(),
+		RecipientUsername: recipient.Username,
+		Amount:            twd("250.00"),
+	})
+	expectProblem(t, postRequest("/api/v1/payments/transfer", body, sender.ID), http.StatusUnprocessableEntity, "daily_limit_exceeded")
+
+	expectBalance(t, sender.Wallets[0].ID, "700.00")
+	expectBalance(t, recipient.Wallets[0].ID, "350.00")
+}
+
+func TestLimits_OverrideRequiresOperator(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100.00")
+	req := &models.LimitOverrideRequest{
+		Type:     entities.TransactionTypes.Deposit,
+		Currency: "TWD",
+		Reason:   "no reason",
+	}
+
+	expectProblem(t, putLimitOverride("", user.ID, req), http.StatusUnauthorized, "missing_operator_key")
+	expectProblem(t, putLimitOverride(integrationOperatorKey, 999, req), http.StatusNotFound, "user_not_found")
+}
+
+func TestWithdraw_AboveMaximum(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("200000.00")
+	bankAccount := givenBankAccount(user.ID)
+
+	body, _ := json.Marshal(&models.WithdrawRequest{
+		UUID:          uuid.New(),
+		Amount:        twd("150000.00"),
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		BankAccountID: bankAccount.ID,
+	})
+
+	expectProblem(t, postRequest("/api/v1/payments/withdraw", body, user.ID), http.StatusUnprocessableEntity, "amount_above_maximum")
+	expectBalance(t, user.Wallets[0].ID, "200000.00")
+}
+
+func newLimitService() services.LimitService {
+	return services.NewLimitService(repos.NewUserRepo(), repos.NewLimitRepo(), limits.MustParse(limits.DefaultRules()))
+}
+
+func putLimitOverride(operatorKey string, userID uint, override *models.LimitOverrideRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(override)
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/api/v1/limits/users/%d", userID), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if operatorKey != "" {
+		req.Header.Set(middleware.OPERATOR_KEY_HEADER, operatorKey)
+	}
+	r.ServeHTTP(res, req)
+	return res
+}
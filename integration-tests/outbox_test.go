@@ -0,0 +1,138 @@
+package integration_test
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/outbox"
+	"banking-system/repos"
+	"banking-system/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutbox_TransferWritesEventsWithItsBalanceChange(t *testing.T) {
+	truncateTables()
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("0.00")
+	clearOutbox()
+
+	transferID := givenTransfer(t, sender, recipient, "300.00")
+
+	assert.Equal(t, []entities.EventType{
+		entities.EventTypes.TransactionCompleted,
+		entities.EventTypes.WalletBalanceChanged,
+	}, outboxEventTypes(sender.ID))
+	assert.Equal(t, []entities.EventType{
+		entities.EventTypes.TransactionCompleted,
+		entities.EventTypes.TransferReceived,
+		entities.EventTypes.WalletBalanceChanged,
+	}, outboxEventTypes(recipient.ID))
+
+	var event entities.OutboxEvent
+	database.DB.Where("user_id = ? AND type = ?", sender.ID, entities.EventTypes.WalletBalanceChanged).First(&event)
+	var data entities.BalanceEventData
+	json.Unmarshal([]byte(event.Payload), &data)
+	assert.Equal(t, twd("700.00"), data.Balance.WithCurrency("TWD"))
+	assert.Equal(t, transferID, *data.TransactionID)
+}
+
+func TestOutbox_RejectedTransferWritesNoEvents(t *testing.T) {
+	truncateTables()
+	sender := givenUserHasBalance("10.00")
+	recipient := givenUserHasBalance("0.00")
+	clearOutbox()
+
+	body, _ := json.Marshal(&models.TransferRequest{
+		UUID:              uuid.New(),
+		RecipientUsername: recipient.Username,
+		Amount:            twd("50.00"),
+	})
+	expectProblem(t, postRequest("/api/v1/payments/transfer", body, sender.ID), http.StatusUnprocessableEntity, "insufficient_funds")
+
+	var count int64
+	database.DB.Model(&entities.OutboxEvent{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestOutbox_RelayHoldsBackWalletUntilItsEventIsPublished(t *testing.T) {
+	truncateTables()
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("0.00")
+	clearOutbox()
+	givenTransfer(t, sender, recipient, "100.00")
+	givenTransfer(t, sender, recipient, "200.00")
+
+	senderWallet := sender.Wallets[0].ID
+	failing := true
+	var published []*outbox.Message
+	bus := outbox.NewBus()
+	bus.Subscribe(func(msg *outbox.Message) error {
+		if failing && msg.WalletID == senderWallet {
+			return errors.New("subscriber is down")
+		}
+		published = append(published, msg)
+		return nil
+	})
+	relay := services.NewOutboxRelay(repos.NewOutboxRepo(), bus)
+
+	assert.Nil(t, relay.Relay())
+	for _, msg := range published {
+		assert.NotEqual(t, senderWallet, msg.WalletID)
+	}
+	assert.Len(t, published, 6)
+
+	failing = false
+	assert.Nil(t, relay.Relay())
+	assert.Len(t, published, 10)
+
+	var balances []string
+	for _, msg := range published[6:] {
+		assert.Equal(t, senderWallet, msg.WalletID)
+		if msg.Type == entities.EventTypes.WalletBalanceChanged {
+			var data entities.BalanceEventData
+			json.Unmarshal(msg.Data, &data)
+			balances = append(balances, data.Balance.WithCurrency("TWD").String())
+		}
+	}
+	assert.Equal(t, []string{twd("900.00").String(), twd("700.00").String()}, balances)
+
+	var unpublished int64
+	database.DB.Model(&entities.OutboxEvent{}).Where("published_at IS NULL").Count(&unpublished)
+	assert.Equal(t, int64(0), unpublished)
+}
+
+func TestOutbox_NotifySinkPublishes(t *testing.T) {
+	truncateTables()
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("0.00")
+	givenTransfer(t, sender, recipient, "100.00")
+
+	relay := services.NewOutboxRelay(repos.NewOutboxRepo(), outbox.NewNotifySink(database.DB, "account_events"))
+	assert.Nil(t, relay.Relay())
+
+	var unpublished int64
+	database.DB.Model(&entities.OutboxEvent{}).Where("published_at IS NULL").Count(&unpublished)
+	assert.Equal(t, int64(0), unpublished)
+}
+
+// clearOutbox drops the events of the opening balances of given users.
+func clearOutbox() {
+	database.DB.Exec("TRUNCATE TABLE outbox_events RESTART IDENTITY")
+}
+
+func outboxEventTypes(userID uint) []entities.EventType {
+	var events []entities.OutboxEvent
+	database.DB.Where("user_id = ?", userID).Order("id").Find(&events)
+
+	types := make([]entities.EventType, len(events))
+	for i := range events {
+		types[i] = events[i].Type
+	}
+	return types
+}
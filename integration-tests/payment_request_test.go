@@ -0,0 +1,115 @@
+package integration_test
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/psp"
+	"banking-system/repos"
+	"banking-system/services"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaymentRequest_AcceptPaysRequesterOnce(t *testing.T) {
+	truncateTables()
+	requester := givenUserHasBalance("0.00")
+	payer := givenUserHasBalance("1000.00")
+	request := givenPaymentRequest(t, requester, payer, "250.00")
+
+	res := postRequest("/api/v1/payment-requests/"+request.ID.String()+"/accept", nil, payer.ID)
+	assert.Equal(t, http.StatusOK, res.Code)
+	var accepted models.PaymentRequestResponse
+	json.Unmarshal(res.Body.Bytes(), &accepted)
+	assert.Equal(t, "ACCEPTED", accepted.Status)
+
+	expectBalance(t, payer.Wallets[0].ID, "750.00")
+	expectBalance(t, requester.Wallets[0].ID, "250.00")
+
+	var transfer entities.Transaction
+	database.DB.First(&transfer, *accepted.TransactionID)
+	assert.Equal(t, entities.TransactionTypes.TransferOut, transfer.Type)
+	assert.Equal(t, payer.Wallets[0].ID, transfer.WalletID)
+
+	expectProblem(t, postRequest("/api/v1/payment-requests/"+request.ID.String()+"/accept", nil, payer.ID),
+		http.StatusConflict, "payment_request_not_pending")
+	expectBalance(t, payer.Wallets[0].ID, "750.00")
+}
+
+func TestPaymentRequest_FailedTransferLeavesRequestPending(t *testing.T) {
+	truncateTables()
+	requester := givenUserHasBalance("0.00")
+	payer := givenUserHasBalance("100.00")
+	request := givenPaymentRequest(t, requester, payer, "250.00")
+
+	expectProblem(t, postRequest("/api/v1/payment-requests/"+request.ID.String()+"/accept", nil, payer.ID),
+		http.StatusUnprocessableEntity, "insufficient_funds")
+
+	incoming := getPaymentRequests(t, "/api/v1/payment-requests/incoming?status=PENDING", payer.ID)
+	assert.Len(t, incoming, 1)
+	assert.Equal(t, request.ID, incoming[0].ID)
+}
+
+func TestPaymentRequest_DeclineClosesRequestForBothSides(t *testing.T) {
+	truncateTables()
+	requester := givenUserHasBalance("0.00")
+	payer := givenUserHasBalance("1000.00")
+	request := givenPaymentRequest(t, requester, payer, "250.00")
+
+	expectProblem(t, postRequest("/api/v1/payment-requests/"+request.ID.String()+"/decline", nil, requester.ID),
+		http.StatusForbidden, "payment_request_forbidden")
+	assert.Equal(t, http.StatusOK, postRequest("/api/v1/payment-requests/"+request.ID.String()+"/decline", nil, payer.ID).Code)
+
+	outgoing := getPaymentRequests(t, "/api/v1/payment-requests/outgoing?status=DECLINED", requester.ID)
+	assert.Len(t, outgoing, 1)
+	assert.Empty(t, getPaymentRequests(t, "/api/v1/payment-requests/incoming?status=PENDING", payer.ID))
+	expectBalance(t, payer.Wallets[0].ID, "1000.00")
+}
+
+func TestPaymentRequest_ExpiredRequestsAreClosed(t *testing.T) {
+	truncateTables()
+	requester := givenUserHasBalance("0.00")
+	payer := givenUserHasBalance("1000.00")
+	request := givenPaymentRequest(t, requester, payer, "250.00")
+	database.DB.Model(&entities.PaymentRequest{}).Where("uuid = ?", request.ID).Update("expires_at", time.Now().Add(-time.Minute))
+
+	expectProblem(t, postRequest("/api/v1/payment-requests/"+request.ID.String()+"/accept", nil, payer.ID),
+		http.StatusConflict, "payment_request_not_pending")
+
+	paymentSrv := services.NewPaymentService(repos.NewUserRepo(), repos.NewTransactionRepo(), repos.NewBankAccountRepo(), psp.NewPSPFactory(), nil, newLimitService())
+	sut := services.NewPaymentRequestService(repos.NewPaymentRequestRepo(), repos.NewUserRepo(), paymentSrv)
+	assert.Nil(t, sut.ExpireDue())
+
+	outgoing := getPaymentRequests(t, "/api/v1/payment-requests/outgoing?status=EXPIRED", requester.ID)
+	assert.Len(t, outgoing, 1)
+	assert.NotNil(t, outgoing[0].ClosedAt)
+}
+
+func givenPaymentRequest(t *testing.T, requester *entities.User, payer *entities.User, amount string) *models.PaymentRequestResponse {
+	body, _ := json.Marshal(&models.CreatePaymentRequestRequest{
+		PayerUsername: payer.Username,
+		Amount:        twd(amount),
+		Memo:          "Concert tickets",
+	})
+	res := postRequest("/api/v1/payment-requests", body, requester.ID)
+	assert.Equal(t, http.StatusCreated, res.Code)
+
+	var request models.PaymentRequestResponse
+	json.Unmarshal(res.Body.Bytes(), &request)
+	assert.NotEqual(t, uuid.Nil, request.ID)
+	return &request
+}
+
+func getPaymentRequests(t *testing.T, path string, userID uint) []models.PaymentRequestResponse {
+	res := getRequest(path, userID)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var requests []models.PaymentRequestResponse
+	json.Unmarshal(res.Body.Bytes(), &requests)
+	return requests
+}
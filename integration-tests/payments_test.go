@@ -1,23 +1,27 @@
 package integration_test
 
 import (
+	"banking-system/auth"
 	"banking-system/controllers"
 	"banking-system/database"
 	"banking-system/entities"
+	"banking-system/middleware"
 	"banking-system/models"
+	"banking-system/money"
 	"banking-system/psp"
 	"banking-system/repos"
 	"banking-system/router"
 	"banking-system/services"
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
@@ -31,6 +35,20 @@ import (
 var r *gin.Engine
 
 func TestMain(m *testing.M) {
+	if os.Getenv("USER_TOKEN_SECRET_KEY") == "" {
+		os.Setenv("USER_TOKEN_SECRET_KEY", "integration_test_secret")
+	}
+	if os.Getenv("PSP_CALLBACK_SECRETS") == "" {
+		os.Setenv("PSP_CALLBACK_SECRETS", "FakePay=integration_test_psp_secret,BankTransfer=integration_test_bank_secret")
+	}
+	if os.Getenv("OPERATOR_API_KEYS") == "" {
+		os.Setenv("OPERATOR_API_KEYS", "ops="+integrationOperatorKey)
+	}
+	if os.Getenv("BLOB_STORE_DIR") == "" {
+		blobDir, _ := os.MkdirTemp("", "integration-blobs-")
+		os.Setenv("BLOB_STORE_DIR", blobDir)
+	}
+
 	r = router.Setup()
 	database.ConnectTestDB()
 	exitCode := m.Run()
@@ -47,16 +65,16 @@ func TestDeposit(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
 	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
-	sut := controllers.NewPaymentController(services.NewPaymentService(repos.NewUserRepo(), repos.NewTransactionRepo(), pspFactoryMock))
+	sut := controllers.NewPaymentController(services.NewPaymentService(repos.NewUserRepo(), repos.NewTransactionRepo(), repos.NewBankAccountRepo(), pspFactoryMock, nil, newLimitService()))
 
 	txUUID := uuid.New()
 	const redirectUrl = "https://external.payment.page/payin"
 	givenPayInResponse(txUUID.String(), redirectUrl)
-	user := givenUserHasBalance(0)
+	user := givenUserHasBalance("0")
 
 	req, _ := json.Marshal(&models.DepositRequest{
 		UUID:          txUUID,
-		Amount:        100.00,
+		Amount:        twd("100.00"),
 		PaymentMethod: "AnyPay",
 	})
 	res := postRequestWithHandler("/api/v1/payments/deposit", sut.Deposit, req, user.ID)
@@ -65,10 +83,10 @@ func TestDeposit(t *testing.T) {
 	assert.Equal(t, redirectUrl, getResponseField(res, "redirect_url"))
 	expectTransactionEqual(t, &entities.Transaction{
 		UUID:          txUUID,
-		WalletID:      user.Wallet.ID,
+		WalletID:      user.Wallets[0].ID,
 		Type:          entities.TransactionTypes.Deposit,
 		Status:        entities.TransactionStatuses.Pending,
-		Amount:        100.00,
+		Amount:        twd("100.00"),
 		PaymentMethod: "AnyPay",
 	})
 }
@@ -78,16 +96,16 @@ func TestDeposit_DuplicateRequests(t *testing.T) {
 
 	mockPSPFactory := new(pspMock.MockPSPFactoryTestify)
 	mockPaymentProvider := new(pspMock.MockPaymentServiceProviderTestify)
-	mockPSPFactory.On("NewPaymentServiceProvider", psp.PaymentMethod("AnyPay")).Return(mockPaymentProvider)
+	mockPSPFactory.On("NewPaymentServiceProvider", psp.PaymentMethod("AnyPay")).Return(mockPaymentProvider, nil)
 	mockPaymentProvider.On("PayIn", mock.Anything).Return(&psp.PayInResponse{}, nil)
 
-	user := givenUserHasBalance(0)
-	sut := controllers.NewPaymentController(services.NewPaymentService(repos.NewUserRepo(), repos.NewTransactionRepo(), mockPSPFactory))
+	user := givenUserHasBalance("0")
+	sut := controllers.NewPaymentController(services.NewPaymentService(repos.NewUserRepo(), repos.NewTransactionRepo(), repos.NewBankAccountRepo(), mockPSPFactory, nil, newLimitService()))
 
 	txUUID := uuid.New()
 	req, _ := json.Marshal(&models.DepositRequest{
 		UUID:          txUUID,
-		Amount:        100.00,
+		Amount:        twd("100.00"),
 		PaymentMethod: "AnyPay",
 	})
 
@@ -107,13 +125,14 @@ func TestDepositConfirm(t *testing.T) {
 		TransactionID: uuid.NewString(),
 	}
 
-	user := givenUserHasBalance(100)
+	user := givenUserHasBalance("100")
 	givenTransaction(&entities.Transaction{
-		UUID:     uuid.MustParse(req.TransactionID),
-		Type:     entities.TransactionTypes.Deposit,
-		Status:   entities.TransactionStatuses.Pending,
-		Amount:   50.00,
-		WalletID: user.Wallet.ID,
+		UUID:          uuid.MustParse(req.TransactionID),
+		Type:          entities.TransactionTypes.Deposit,
+		Status:        entities.TransactionStatuses.Pending,
+		Amount:        twd("50.00"),
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		WalletID:      user.Wallets[0].ID,
 	})
 
 	body, _ := json.Marshal(req)
@@ -121,7 +140,7 @@ func TestDepositConfirm(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, res.Code)
 	expectTransactionStatus(t, req.TransactionID, entities.TransactionStatuses.Completed)
-	expectBalance(t, user.Wallet.ID, 150.00)
+	expectBalance(t, user.Wallets[0].ID, "150.00")
 }
 
 func TestDepositConfirm_DuplicateRequest(t *testing.T) {
@@ -131,25 +150,26 @@ func TestDepositConfirm_DuplicateRequest(t *testing.T) {
 		TransactionID: uuid.NewString(),
 	}
 
-	user := givenUserHasBalance(100)
+	user := givenUserHasBalance("100")
 	givenTransaction(&entities.Transaction{
-		UUID:     uuid.MustParse(req.TransactionID),
-		Type:     entities.TransactionTypes.Deposit,
-		Status:   entities.TransactionStatuses.Pending,
-		Amount:   50.00,
-		WalletID: user.Wallet.ID,
+		UUID:          uuid.MustParse(req.TransactionID),
+		Type:          entities.TransactionTypes.Deposit,
+		Status:        entities.TransactionStatuses.Pending,
+		Amount:        twd("50.00"),
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		WalletID:      user.Wallets[0].ID,
 	})
 
 	body, _ := json.Marshal(req)
 	firstResp := postRequestWithPSPAuth("/api/v1/payments/confirm", body)
 	assert.Equal(t, http.StatusOK, firstResp.Code)
 	expectTransactionStatus(t, req.TransactionID, entities.TransactionStatuses.Completed)
-	expectBalance(t, user.Wallet.ID, 150.00)
+	expectBalance(t, user.Wallets[0].ID, "150.00")
 
 	secondResp := postRequestWithPSPAuth("/api/v1/payments/confirm", body)
 	assert.Equal(t, http.StatusOK, secondResp.Code)
 	expectTransactionStatus(t, req.TransactionID, entities.TransactionStatuses.Completed)
-	expectBalance(t, user.Wallet.ID, 150.00)
+	expectBalance(t, user.Wallets[0].ID, "150.00")
 }
 
 func TestDepositConfirm_ConcurrentRequests(t *testing.T) {
@@ -159,13 +179,14 @@ func TestDepositConfirm_ConcurrentRequests(t *testing.T) {
 		TransactionID: uuid.NewString(),
 	}
 
-	user := givenUserHasBalance(100)
+	user := givenUserHasBalance("100")
 	givenTransaction(&entities.Transaction{
-		UUID:     uuid.MustParse(req.TransactionID),
-		Type:     entities.TransactionTypes.Deposit,
-		Status:   entities.TransactionStatuses.Pending,
-		Amount:   50.00,
-		WalletID: user.Wallet.ID,
+		UUID:          uuid.MustParse(req.TransactionID),
+		Type:          entities.TransactionTypes.Deposit,
+		Status:        entities.TransactionStatuses.Pending,
+		Amount:        twd("50.00"),
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		WalletID:      user.Wallets[0].ID,
 	})
 
 	// Simulate 10 concurrent requests
@@ -176,7 +197,7 @@ func TestDepositConfirm_ConcurrentRequests(t *testing.T) {
 	}, concurrentCount)
 
 	expectTransactionStatus(t, req.TransactionID, entities.TransactionStatuses.Completed)
-	expectBalance(t, user.Wallet.ID, 150.00)
+	expectBalance(t, user.Wallets[0].ID, "150.00")
 }
 
 func TestDepositCancel(t *testing.T) {
@@ -186,13 +207,14 @@ func TestDepositCancel(t *testing.T) {
 		TransactionID: "a05aa863-d9ab-42e6-8122-f76e43edaa22",
 	}
 
-	user := givenUserHasBalance(100)
+	user := givenUserHasBalance("100")
 	givenTransaction(&entities.Transaction{
-		UUID:     uuid.MustParse(req.TransactionID),
-		Type:     entities.TransactionTypes.Deposit,
-		Status:   entities.TransactionStatuses.Pending,
-		Amount:   50.00,
-		WalletID: user.Wallet.ID,
+		UUID:          uuid.MustParse(req.TransactionID),
+		Type:          entities.TransactionTypes.Deposit,
+		Status:        entities.TransactionStatuses.Pending,
+		Amount:        twd("50.00"),
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		WalletID:      user.Wallets[0].ID,
 	})
 
 	body, _ := json.Marshal(req)
@@ -200,7 +222,7 @@ func TestDepositCancel(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, res.Code)
 	expectTransactionStatus(t, req.TransactionID, entities.TransactionStatuses.Canceled)
-	expectBalance(t, user.Wallet.ID, 100.00) // Balance should not change
+	expectBalance(t, user.Wallets[0].ID, "100.00") // Balance should not change
 }
 
 func TestDepositCancel_DuplicateRequest(t *testing.T) {
@@ -210,25 +232,80 @@ func TestDepositCancel_DuplicateRequest(t *testing.T) {
 		TransactionID: "b05aa863-d9ab-42e6-8122-f76e43edaa23",
 	}
 
-	user := givenUserHasBalance(100)
+	user := givenUserHasBalance("100")
 	givenTransaction(&entities.Transaction{
-		UUID:     uuid.MustParse(req.TransactionID),
-		Type:     entities.TransactionTypes.Deposit,
-		Status:   entities.TransactionStatuses.Pending,
-		Amount:   50.00,
-		WalletID: user.Wallet.ID,
+		UUID:          uuid.MustParse(req.TransactionID),
+		Type:          entities.TransactionTypes.Deposit,
+		Status:        entities.TransactionStatuses.Pending,
+		Amount:        twd("50.00"),
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		WalletID:      user.Wallets[0].ID,
 	})
 
 	body, _ := json.Marshal(req)
 	firstResp := postRequestWithPSPAuth("/api/v1/payments/cancel", body)
 	assert.Equal(t, http.StatusOK, firstResp.Code)
 	expectTransactionStatus(t, req.TransactionID, entities.TransactionStatuses.Canceled)
-	expectBalance(t, user.Wallet.ID, 100.00)
+	expectBalance(t, user.Wallets[0].ID, "100.00")
 
 	secondResp := postRequestWithPSPAuth("/api/v1/payments/cancel", body)
 	assert.Equal(t, http.StatusOK, secondResp.Code)
 	expectTransactionStatus(t, req.TransactionID, entities.TransactionStatuses.Canceled)
-	expectBalance(t, user.Wallet.ID, 100.00) // Balance should still be unchanged
+	expectBalance(t, user.Wallets[0].ID, "100.00") // Balance should still be unchanged
+}
+
+func TestDepositConfirm_AfterCancel(t *testing.T) {
+	truncateTables()
+
+	transactionID := uuid.NewString()
+	user := givenUserHasBalance("100")
+	givenTransaction(&entities.Transaction{
+		UUID:          uuid.MustParse(transactionID),
+		Type:          entities.TransactionTypes.Deposit,
+		Status:        entities.TransactionStatuses.Pending,
+		Amount:        twd("50.00"),
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		WalletID:      user.Wallets[0].ID,
+	})
+
+	cancelBody, _ := json.Marshal(&psp.CancelRequest{TransactionID: transactionID})
+	assert.Equal(t, http.StatusOK, postRequestWithPSPAuth("/api/v1/payments/cancel", cancelBody).Code)
+
+	confirmBody, _ := json.Marshal(&psp.ConfirmRequest{TransactionID: transactionID})
+	res := postRequestWithPSPAuth("/api/v1/payments/confirm", confirmBody)
+
+	expectProblem(t, res, http.StatusConflict, "illegal_transition")
+	expectTransactionStatus(t, transactionID, entities.TransactionStatuses.Canceled)
+	expectBalance(t, user.Wallets[0].ID, "100.00")
+}
+
+func TestDepositConfirm_RecordsStatusHistory(t *testing.T) {
+	truncateTables()
+
+	transactionID := uuid.NewString()
+	user := givenUserHasBalance("100")
+	givenTransaction(&entities.Transaction{
+		UUID:          uuid.MustParse(transactionID),
+		Type:          entities.TransactionTypes.Deposit,
+		Status:        entities.TransactionStatuses.Pending,
+		Amount:        twd("50.00"),
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		WalletID:      user.Wallets[0].ID,
+	})
+
+	body, _ := json.Marshal(&psp.ConfirmRequest{TransactionID: transactionID})
+	postRequestWithPSPAuth("/api/v1/payments/confirm", body)
+	postRequestWithPSPAuth("/api/v1/payments/confirm", body)
+
+	var history []entities.TransactionStatusChange
+	database.DB.Where("transaction_id = ?", transactionID).Find(&history)
+
+	// the duplicate callback must not be recorded
+	assert.Len(t, history, 1)
+	assert.Equal(t, entities.TransactionStatuses.Pending, history[0].FromStatus)
+	assert.Equal(t, entities.TransactionStatuses.Completed, history[0].ToStatus)
+	assert.Equal(t, entities.TransitionActors.PSP, history[0].Actor)
+	assert.Equal(t, string(psp.PaymentMethods.FakePay), history[0].ActorID)
 }
 
 func TestWithdraw_Success(t *testing.T) {
@@ -236,29 +313,31 @@ func TestWithdraw_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
 	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
-	sut := controllers.NewPaymentController(services.NewPaymentService(repos.NewUserRepo(), repos.NewTransactionRepo(), pspFactoryMock))
+	sut := controllers.NewPaymentController(services.NewPaymentService(repos.NewUserRepo(), repos.NewTransactionRepo(), repos.NewBankAccountRepo(), pspFactoryMock, nil, newLimitService()))
 
 	txUUID := uuid.New()
 	givenPayOutResponse(txUUID.String())
-	user := givenUserHasBalance(200.00)
+	user := givenUserHasBalance("200.00")
+	bankAccount := givenBankAccount(user.ID)
 
 	req, _ := json.Marshal(&models.WithdrawRequest{
 		UUID:          txUUID,
 		PaymentMethod: "AnyPay",
-		Amount:        50.00,
+		BankAccountID: bankAccount.ID,
+		Amount:        twd("50.00"),
 	})
 	res := postRequestWithHandler("/api/v1/payments/withdraw", sut.Withdraw, req, user.ID)
 
 	assert.Equal(t, http.StatusOK, res.Code)
 	expectTransactionEqual(t, &entities.Transaction{
 		UUID:          txUUID,
-		WalletID:      user.Wallet.ID,
+		WalletID:      user.Wallets[0].ID,
 		Type:          entities.TransactionTypes.Withdrawal,
 		Status:        entities.TransactionStatuses.Pending,
 		PaymentMethod: "AnyPay",
-		Amount:        50.00,
+		Amount:        twd("50.00"),
 	})
-	expectBalance(t, user.Wallet.ID, 150.00) // Balance should be deducted
+	expectBalance(t, user.Wallets[0].ID, "150.00") // Balance should be deducted
 }
 
 func TestWithdraw_InsufficientBalance(t *testing.T) {
@@ -266,20 +345,23 @@ func TestWithdraw_InsufficientBalance(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
 	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
-	sut := controllers.NewPaymentController(services.NewPaymentService(repos.NewUserRepo(), repos.NewTransactionRepo(), pspFactoryMock))
+	sut := controllers.NewPaymentController(services.NewPaymentService(repos.NewUserRepo(), repos.NewTransactionRepo(), repos.NewBankAccountRepo(), pspFactoryMock, nil, newLimitService()))
 
 	txUUID := uuid.New()
-	user := givenUserHasBalance(30.00)
+	pspFactoryMock.EXPECT().NewPaymentServiceProvider(gomock.Any()).Return(paymentProviderMock, nil)
+	user := givenUserHasBalance("30.00")
+	bankAccount := givenBankAccount(user.ID)
 
 	req, _ := json.Marshal(&models.WithdrawRequest{
 		UUID:          txUUID,
 		PaymentMethod: "AnyPay",
-		Amount:        50.00, // More than available balance
+		BankAccountID: bankAccount.ID,
+		Amount:        twd("50.00"), // More than available balance
 	})
 	res := postRequestWithHandler("/api/v1/payments/withdraw", sut.Withdraw, req, user.ID)
 
-	assert.Equal(t, http.StatusBadRequest, res.Code)
-	expectBalance(t, user.Wallet.ID, 30.00) // Balance should remain unchanged
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	expectBalance(t, user.Wallets[0].ID, "30.00") // Balance should remain unchanged
 }
 
 func TestWithdraw_DuplicateRequests(t *testing.T) {
@@ -287,17 +369,19 @@ func TestWithdraw_DuplicateRequests(t *testing.T) {
 
 	mockPSPFactory := new(pspMock.MockPSPFactoryTestify)
 	mockPaymentProvider := new(pspMock.MockPaymentServiceProviderTestify)
-	mockPSPFactory.On("NewPaymentServiceProvider", psp.PaymentMethod("AnyPay")).Return(mockPaymentProvider)
-	mockPaymentProvider.On("PayOut").Return(&psp.PayOutResponse{}, nil)
+	mockPSPFactory.On("NewPaymentServiceProvider", psp.PaymentMethod("AnyPay")).Return(mockPaymentProvider, nil)
+	mockPaymentProvider.On("PayOut", mock.Anything).Return(&psp.PayOutResponse{}, nil)
 
-	user := givenUserHasBalance(200.00)
-	sut := controllers.NewPaymentController(services.NewPaymentService(repos.NewUserRepo(), repos.NewTransactionRepo(), mockPSPFactory))
+	user := givenUserHasBalance("200.00")
+	bankAccount := givenBankAccount(user.ID)
+	sut := controllers.NewPaymentController(services.NewPaymentService(repos.NewUserRepo(), repos.NewTransactionRepo(), repos.NewBankAccountRepo(), mockPSPFactory, nil, newLimitService()))
 
 	txUUID := uuid.New()
 	req, _ := json.Marshal(&models.WithdrawRequest{
 		UUID:          txUUID,
 		PaymentMethod: "AnyPay",
-		Amount:        50.00,
+		BankAccountID: bankAccount.ID,
+		Amount:        twd("50.00"),
 	})
 
 	concurrentCount := 10
@@ -307,178 +391,234 @@ func TestWithdraw_DuplicateRequests(t *testing.T) {
 
 	assert.Equal(t, 1, len(successCount), "Exactly one request should succeed.")
 	assert.Equal(t, concurrentCount-1, len(failureCount), "The remaining requests should have failed.")
-	expectBalance(t, user.Wallet.ID, 150.00) // Balance should be deducted only once
+	expectBalance(t, user.Wallets[0].ID, "150.00") // Balance should be deducted only once
 	mockPaymentProvider.AssertNumberOfCalls(t, "PayOut", 1)
 }
 
-func TestWithdrawCancel(t *testing.T) {
+func TestWithdraw_PaysOutToBankAccount(t *testing.T) {
+	truncateTables()
+
+	mockPSPFactory := new(pspMock.MockPSPFactoryTestify)
+	mockPaymentProvider := new(pspMock.MockPaymentServiceProviderTestify)
+	mockPSPFactory.On("NewPaymentServiceProvider", psp.PaymentMethod("AnyPay")).Return(mockPaymentProvider, nil)
+
+	user := givenUserHasBalance("200.00")
+	bankAccount := givenBankAccount(user.ID)
+	txUUID := uuid.New()
+	mockPaymentProvider.On("PayOut", &psp.PayOutRequest{
+		TransactionID: txUUID.String(),
+		Amount:        twd("50.00"),
+		Beneficiary: psp.BankAccount{
+			BankCode:      bankAccount.BankCode,
+			AccountNumber: bankAccount.AccountNumber,
+		},
+	}).Return(&psp.PayOutResponse{}, nil)
+	sut := controllers.NewPaymentController(services.NewPaymentService(repos.NewUserRepo(), repos.NewTransactionRepo(), repos.NewBankAccountRepo(), mockPSPFactory, nil, newLimitService()))
+
+	req, _ := json.Marshal(&models.WithdrawRequest{
+		UUID:          txUUID,
+		PaymentMethod: "AnyPay",
+		BankAccountID: bankAccount.ID,
+		Amount:        twd("50.00"),
+	})
+	res := postRequestWithHandler("/api/v1/payments/withdraw", sut.Withdraw, req, user.ID)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	mockPaymentProvider.AssertExpectations(t)
+}
+
+func TestWithdraw_AnotherUsersBankAccount(t *testing.T) {
+	truncateTables()
+
+	mockPSPFactory := new(pspMock.MockPSPFactoryTestify)
+	mockPaymentProvider := new(pspMock.MockPaymentServiceProviderTestify)
+	mockPSPFactory.On("NewPaymentServiceProvider", psp.PaymentMethod("AnyPay")).Return(mockPaymentProvider, nil)
+	sut := controllers.NewPaymentController(services.NewPaymentService(repos.NewUserRepo(), repos.NewTransactionRepo(), repos.NewBankAccountRepo(), mockPSPFactory, nil, newLimitService()))
+
+	user := givenUserHasBalance("200.00")
+	otherUser := givenUserHasBalance("0")
+	bankAccount := givenBankAccount(otherUser.ID)
+
+	req, _ := json.Marshal(&models.WithdrawRequest{
+		UUID:          uuid.New(),
+		PaymentMethod: "AnyPay",
+		BankAccountID: bankAccount.ID,
+		Amount:        twd("50.00"),
+	})
+	res := postRequestWithHandler("/api/v1/payments/withdraw", sut.Withdraw, req, user.ID)
+
+	expectProblem(t, res, http.StatusForbidden, "bank_account_forbidden")
+	expectBalance(t, user.Wallets[0].ID, "200.00")
+	mockPaymentProvider.AssertNotCalled(t, "PayOut", mock.Anything)
+}
+
+func TestWithdrawCancel_IsNotFound(t *testing.T) {
 	truncateTables()
 
 	req := &psp.CancelRequest{
 		TransactionID: uuid.NewString(),
 	}
 
-	user := givenUserHasBalance(100.00)
+	user := givenUserHasBalance("100.00")
 	givenTransaction(&entities.Transaction{
-		UUID:     uuid.MustParse(req.TransactionID),
-		Type:     entities.TransactionTypes.Withdrawal,
-		Status:   entities.TransactionStatuses.Pending,
-		Amount:   10.00,
-		WalletID: user.Wallet.ID,
+		UUID:          uuid.MustParse(req.TransactionID),
+		Type:          entities.TransactionTypes.Withdrawal,
+		Status:        entities.TransactionStatuses.Pending,
+		Amount:        twd("10.00"),
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		WalletID:      user.Wallets[0].ID,
 	})
 
+	// Withdrawals are settled by payout status callbacks only
 	body, _ := json.Marshal(req)
 	res := postRequestWithPSPAuth("/api/v1/payments/cancel", body)
 
-	assert.Equal(t, http.StatusOK, res.Code)
-	expectTransactionStatus(t, req.TransactionID, entities.TransactionStatuses.Canceled)
-	expectBalance(t, user.Wallet.ID, 110.00, "Balance should be refunded back")
+	expectProblem(t, res, http.StatusNotFound, "transaction_not_found")
+	expectTransactionStatus(t, req.TransactionID, entities.TransactionStatuses.Pending)
+	expectBalance(t, user.Wallets[0].ID, "100.00")
 }
 
-func TestWithdrawCancel_ConcurrentRequests(t *testing.T) {
+func TestDepositConfirm_AnotherProviderIsNotFound(t *testing.T) {
 	truncateTables()
 
-	req := &psp.CancelRequest{
+	req := &psp.ConfirmRequest{
 		TransactionID: uuid.NewString(),
 	}
-	body, _ := json.Marshal(req)
 
-	user := givenUserHasBalance(100.00)
+	user := givenUserHasBalance("100")
 	givenTransaction(&entities.Transaction{
-		UUID:     uuid.MustParse(req.TransactionID),
-		Type:     entities.TransactionTypes.Withdrawal,
-		Status:   entities.TransactionStatuses.Pending,
-		Amount:   10.00,
-		WalletID: user.Wallet.ID,
+		UUID:          uuid.MustParse(req.TransactionID),
+		Type:          entities.TransactionTypes.Deposit,
+		Status:        entities.TransactionStatuses.Pending,
+		Amount:        twd("50.00"),
+		PaymentMethod: psp.PaymentMethods.BankTransfer,
+		WalletID:      user.Wallets[0].ID,
 	})
 
-	// Simulate 10 concurrent requests
-	concurrentCount := 10
-	successCount, _ := concurrentExec(func() *httptest.ResponseRecorder {
-		return postRequestWithPSPAuth("/api/v1/payments/cancel", body)
-	}, concurrentCount)
+	body, _ := json.Marshal(req)
+	res := postRequestWithPSPAuth("/api/v1/payments/confirm", body)
 
-	assert.Equal(t, concurrentCount, len(successCount), "All requests should succeed.")
-	expectTransactionStatus(t, req.TransactionID, entities.TransactionStatuses.Canceled)
-	expectBalance(t, user.Wallet.ID, 110.00, "Balance should be refunded only once")
+	expectProblem(t, res, http.StatusNotFound, "transaction_not_found")
+	expectTransactionStatus(t, req.TransactionID, entities.TransactionStatuses.Pending)
+	expectBalance(t, user.Wallets[0].ID, "100.00")
 }
 
 func TestTransfer_Success(t *testing.T) {
 	truncateTables()
 
-	sender := givenUserHasBalance(200.00)
-	recipient := givenUserHasBalance(50.00)
+	sender := givenUserHasBalance("200.00")
+	recipient := givenUserHasBalance("50.00")
 
 	transferOutUUID := uuid.New()
 	req, _ := json.Marshal(&models.TransferRequest{
 		UUID:              transferOutUUID,
 		SenderUserID:      sender.ID,
 		RecipientUsername: recipient.Username,
-		Amount:            10.00,
+		Amount:            twd("10.00"),
 	})
 
 	res := postRequest("/api/v1/payments/transfer", req, sender.ID)
 
 	assert.Equal(t, http.StatusOK, res.Code)
-	expectBalance(t, sender.Wallet.ID, 190.00)
-	expectBalance(t, recipient.Wallet.ID, 60.00)
+	expectBalance(t, sender.Wallets[0].ID, "190.00")
+	expectBalance(t, recipient.Wallets[0].ID, "60.00")
 	expectTransferTransactionLinked(t, transferOutUUID)
 }
 
 func TestTransfer_InsufficientBalance(t *testing.T) {
 	truncateTables()
 
-	sender := givenUserHasBalance(30.00)
-	recipient := givenUserHasBalance(50.00)
+	sender := givenUserHasBalance("30.00")
+	recipient := givenUserHasBalance("50.00")
 
 	txUUID := uuid.New()
 	req, _ := json.Marshal(&models.TransferRequest{
 		UUID:              txUUID,
 		SenderUserID:      sender.ID,
 		RecipientUsername: recipient.Username,
-		Amount:            100.00, // More than sender's balance
+		Amount:            twd("100.00"), // More than sender's balance
 	})
 
 	res := postRequest("/api/v1/payments/transfer", req, sender.ID)
 
-	assert.Equal(t, http.StatusBadRequest, res.Code)
-	expectBalance(t, sender.Wallet.ID, 30.00, "Balance should remain unchanged")
-	expectBalance(t, recipient.Wallet.ID, 50.00, "Balance should remain unchanged")
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	expectBalance(t, sender.Wallets[0].ID, "30.00", "Balance should remain unchanged")
+	expectBalance(t, recipient.Wallets[0].ID, "50.00", "Balance should remain unchanged")
 }
 
 func TestTransfer_SameUser(t *testing.T) {
 	truncateTables()
 
-	user := givenUserHasBalance(100.00)
+	user := givenUserHasBalance("100.00")
 
 	txUUID := uuid.New()
 	req, _ := json.Marshal(&models.TransferRequest{
 		UUID:              txUUID,
 		SenderUserID:      user.ID,
 		RecipientUsername: user.Username, // Same user
-		Amount:            50.00,
+		Amount:            twd("50.00"),
 	})
 
 	res := postRequest("/api/v1/payments/transfer", req, user.ID)
 
 	assert.Equal(t, http.StatusBadRequest, res.Code)
-	expectBalance(t, user.Wallet.ID, 100.00, "Balance should remain unchanged")
+	expectBalance(t, user.Wallets[0].ID, "100.00", "Balance should remain unchanged")
 }
 
 func TestTransfer_BelowMinimum(t *testing.T) {
 	truncateTables()
 
-	sender := givenUserHasBalance(100.00)
-	recipient := givenUserHasBalance(50.00)
+	sender := givenUserHasBalance("100.00")
+	recipient := givenUserHasBalance("50.00")
 
 	txUUID := uuid.New()
 	req, _ := json.Marshal(&models.TransferRequest{
 		UUID:              txUUID,
 		SenderUserID:      sender.ID,
 		RecipientUsername: recipient.Username,
-		Amount:            0.50, // Below minimum
+		Amount:            twd("0.50"), // Below minimum
 	})
 
 	res := postRequest("/api/v1/payments/transfer", req, sender.ID)
 
-	assert.Equal(t, http.StatusBadRequest, res.Code)
-	expectBalance(t, sender.Wallet.ID, 100.00, "Balance should remain unchanged")
-	expectBalance(t, recipient.Wallet.ID, 50.00, "Balance should remain unchanged")
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	expectBalance(t, sender.Wallets[0].ID, "100.00", "Balance should remain unchanged")
+	expectBalance(t, recipient.Wallets[0].ID, "50.00", "Balance should remain unchanged")
 }
 
 func TestTransfer_AboveMaximum(t *testing.T) {
 	truncateTables()
 
-	sender := givenUserHasBalance(200000.00)
-	recipient := givenUserHasBalance(50.00)
+	sender := givenUserHasBalance("200000.00")
+	recipient := givenUserHasBalance("50.00")
 
 	txUUID := uuid.New()
 	req, _ := json.Marshal(&models.TransferRequest{
 		UUID:              txUUID,
 		SenderUserID:      sender.ID,
 		RecipientUsername: recipient.Username,
-		Amount:            150000.00, // Above maximum
+		Amount:            twd("150000.00"), // Above maximum
 	})
 
 	res := postRequest("/api/v1/payments/transfer", req, sender.ID)
 
-	assert.Equal(t, http.StatusBadRequest, res.Code)
-	expectBalance(t, sender.Wallet.ID, 200000.00, "Balance should remain unchanged")
-	expectBalance(t, recipient.Wallet.ID, 50.00, "Balance should remain unchanged")
+	assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	expectBalance(t, sender.Wallets[0].ID, "200000.00", "Balance should remain unchanged")
+	expectBalance(t, recipient.Wallets[0].ID, "50.00", "Balance should remain unchanged")
 }
 
 func TestTransfer_ConcurrentRequests(t *testing.T) {
 	truncateTables()
 
-	sender := givenUserHasBalance(1000.00)
-	recipient := givenUserHasBalance(50.00)
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("50.00")
 
 	transferOutUUID := uuid.New()
 	transferRequest := &models.TransferRequest{
 		UUID:              transferOutUUID,
 		SenderUserID:      sender.ID,
 		RecipientUsername: recipient.Username,
-		Amount:            100.00,
+		Amount:            twd("100.00"),
 	}
 
 	// Simulate 10 concurrent transfer requests with the same UUID
@@ -488,8 +628,8 @@ func TestTransfer_ConcurrentRequests(t *testing.T) {
 		return postRequest("/api/v1/payments/transfer", body, sender.ID)
 	}, concurrentCount)
 
-	expectBalance(t, sender.Wallet.ID, 900.00, "Sender balance should be deducted only once")
-	expectBalance(t, recipient.Wallet.ID, 150.00, "Recipient balance should be credited only once")
+	expectBalance(t, sender.Wallets[0].ID, "900.00", "Sender balance should be deducted only once")
+	expectBalance(t, recipient.Wallets[0].ID, "150.00", "Recipient balance should be credited only once")
 }
 
 func truncateTables() {
@@ -501,6 +641,23 @@ func truncateTables() {
 		"wallets",
 		"bank_accounts",
 		"transactions",
+		"ledger_accounts",
+		"journal_entries",
+		"postings",
+		"fx_quotes",
+		"idempotency_keys",
+		"sessions",
+		"transaction_status_history",
+		"job_leases",
+		"limit_overrides",
+		"statements",
+		"webhook_endpoints",
+		"webhook_deliveries",
+		"outbox_events",
+		"scheduled_transfers",
+		"scheduled_transfer_occurrences",
+		"payment_requests",
+		"psp_nonces",
 	}
 
 	for _, tableName := range tables {
@@ -518,9 +675,8 @@ func postRequest(path string, body []byte, userID ...uint) *httptest.ResponseRec
 	req, _ := http.NewRequest("POST", path, bytes.NewReader(body))
 	req.Header.Add("Content-Type", "application/json")
 
-	// Set X-USER-ID header if provided
 	if len(userID) > 0 {
-		req.Header.Set("X-User-ID", fmt.Sprintf("%d", userID[0]))
+		authorize(req, userID[0])
 	}
 
 	r.ServeHTTP(res, req)
@@ -531,44 +687,83 @@ func postRequestWithPSPAuth(path string, body []byte) *httptest.ResponseRecorder
 	res := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", path, bytes.NewReader(body))
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("X-PSP-API-Key", "psp_secret_key_12345")
+	signPSPCallback(req, psp.PaymentMethods.FakePay, body)
 	r.ServeHTTP(res, req)
 	return res
 }
 
+// signPSPCallback signs the request the way the provider would.
+func signPSPCallback(req *http.Request, provider psp.PaymentMethod, body []byte) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := uuid.NewString()
+	req.Header.Set(middleware.PSP_PROVIDER_HEADER, string(provider))
+	req.Header.Set(middleware.PSP_TIMESTAMP_HEADER, timestamp)
+	req.Header.Set(middleware.PSP_NONCE_HEADER, nonce)
+	req.Header.Set(middleware.PSP_SIGNATURE_HEADER, psp.SignCallback(psp.LoadCallbackSecrets()[provider], timestamp, nonce, body))
+}
+
 func postRequestWithHandler(path string, handler func(c *gin.Context), body []byte, userID ...uint) *httptest.ResponseRecorder {
 	res := httptest.NewRecorder()
 	ctx, r := gin.CreateTestContext(res)
+	r.Use(middleware.CorrelationID(), middleware.ErrorHandler(), middleware.Authenticate(repos.NewSessionRepo()))
 	r.POST(path, handler)
 	ctx.Request = httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
 	ctx.Request.Header.Set("Content-Type", "application/json")
 
-	// Set X-USER-ID header if provided
 	if len(userID) > 0 {
-		ctx.Request.Header.Set("X-User-ID", fmt.Sprintf("%d", userID[0]))
+		authorize(ctx.Request, userID[0])
 	}
 
 	r.ServeHTTP(res, ctx.Request)
 	return res
 }
 
-func givenUserHasBalance(amount float64) *entities.User {
+// authorize starts a session for userID the same way login does and signs the
+// request with its access token.
+func authorize(req *http.Request, userID uint) {
+	tokens, err := services.NewSessionService(repos.NewSessionRepo(), auth.LoadConfig()).Start(userID, "integration-test", "")
+	if err != nil {
+		log.Fatalf("Failed to start session: %v", err)
+	}
+	req.Header.Set(middleware.AUTHORIZATION_HEADER, "Bearer "+tokens.AccessToken)
+}
+
+func givenUserHasBalance(amount string) *entities.User {
+	return givenUserHasBalances(twd(amount))
+}
+
+// givenUserHasBalances creates a user with one wallet per balance, in order.
+func givenUserHasBalances(balances ...money.Money) *entities.User {
 	user := &entities.User{
 		Username:     "usr_" + uuid.NewString()[:8],
 		PasswordHash: "any",
-		Wallet: entities.Wallet{
-			Balance:  amount,
-			Currency: "TWD",
-		},
+	}
+	for _, balance := range balances {
+		user.Wallets = append(user.Wallets, entities.Wallet{Currency: balance.Currency()})
 	}
 	database.DB.Create(user)
+
+	for i, balance := range balances {
+		if !balance.IsZero() {
+			// Opening balances are funded from suspense so the ledger stays balanced
+			err := repos.NewLedgerRepo().Post(entities.NewJournalEntry(nil, "Opening balance",
+				entities.NewPosting(entities.WalletAccount(&user.Wallets[i]), balance),
+				entities.NewPosting(entities.SuspenseAccount(balance.Currency()), balance.Neg()),
+			))
+			if err != nil {
+				log.Fatalf("Failed to seed opening balance: %v", err)
+			}
+		}
+		user.Wallets[i].Balance = balance
+	}
+
 	return user
 }
 
 func givenPayInResponse(txUUID string, redirectUrl string) {
 	pspFactoryMock.EXPECT().
 		NewPaymentServiceProvider(gomock.Any()).
-		Return(paymentProviderMock)
+		Return(paymentProviderMock, nil)
 
 	paymentProviderMock.EXPECT().PayIn(gomock.Any()).
 		Return(&psp.PayInResponse{
@@ -580,7 +775,7 @@ func givenPayInResponse(txUUID string, redirectUrl string) {
 func expectPayInCalledOnce() {
 	pspFactoryMock.EXPECT().
 		NewPaymentServiceProvider(gomock.Any()).
-		Return(paymentProviderMock).
+		Return(paymentProviderMock, nil).
 		Times(1)
 
 	paymentProviderMock.EXPECT().PayIn(gomock.Any()).
@@ -591,12 +786,12 @@ func expectPayInCalledOnce() {
 func givenPayOutResponse(txID string) {
 	pspFactoryMock.EXPECT().
 		NewPaymentServiceProvider(gomock.Any()).
-		Return(paymentProviderMock).
+		Return(paymentProviderMock, nil).
 		Times(1)
 
-	paymentProviderMock.EXPECT().PayOut().
+	paymentProviderMock.EXPECT().PayOut(gomock.Any()).
 		Return(&psp.PayOutResponse{
-			TransactionID: txID,
+			Reference: "PSP-" + txID,
 		}, nil).
 		Times(1)
 }
@@ -604,14 +799,24 @@ func givenPayOutResponse(txID string) {
 func expectPayOutCalledOnce() {
 	pspFactoryMock.EXPECT().
 		NewPaymentServiceProvider(gomock.Any()).
-		Return(paymentProviderMock).
+		Return(paymentProviderMock, nil).
 		Times(1)
 
-	paymentProviderMock.EXPECT().PayOut().
+	paymentProviderMock.EXPECT().PayOut(gomock.Any()).
 		Return(&psp.PayOutResponse{}, nil).
 		Times(1)
 }
 
+func givenBankAccount(userID uint) *entities.BankAccount {
+	bankAccount := &entities.BankAccount{
+		UserID:        userID,
+		BankCode:      "822",
+		AccountNumber: "123456789012",
+	}
+	database.DB.Create(bankAccount)
+	return bankAccount
+}
+
 func givenTransaction(transaction *entities.Transaction) {
 	database.DB.Create(transaction)
 }
@@ -649,12 +854,16 @@ func expectTransactionStatus(t *testing.T, transactionId string, transactionStat
 	assert.Equal(t, transactionStatus, tx.Status)
 }
 
-func expectBalance(t *testing.T, walletId uint, amount float64, msgAndArgs ...interface{}) {
+func expectBalance(t *testing.T, walletId uint, amount string, msgAndArgs ...interface{}) {
 	var wallet entities.Wallet
 	result := database.DB.Where("id = ?", walletId).First(&wallet)
 
 	assert.Nil(t, result.Error)
-	assert.Equal(t, amount, wallet.Balance, msgAndArgs...)
+	assert.Equal(t, twd(amount), wallet.Balance, msgAndArgs...)
+}
+
+func twd(amount string) money.Money {
+	return money.MustParse(amount, "TWD")
 }
 
 func getResponseField(resp *httptest.ResponseRecorder, field string) string {
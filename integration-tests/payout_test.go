@@ -0,0 +1,134 @@
+package integration_test
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/psp"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayOut_WithdrawalIsSubmittedWithReference(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("200.00")
+	txUUID := givenFakePayWithdrawal(t, user, "50.00")
+
+	tx := getTransactionByUUID(t, txUUID)
+	assert.Equal(t, psp.PayOutStatuses.Submitted, tx.PayOutStatus)
+	assert.Equal(t, "FP-"+txUUID.String(), tx.PSPReference)
+}
+
+func TestPayOut_PaidCompletesWithdrawal(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("200.00")
+	txUUID := givenFakePayWithdrawal(t, user, "50.00")
+
+	assert.Equal(t, http.StatusOK, postPayOutStatus(psp.PaymentMethods.FakePay, txUUID, psp.PayOutStatuses.Processing).Code)
+	assert.Equal(t, http.StatusOK, postPayOutStatus(psp.PaymentMethods.FakePay, txUUID, psp.PayOutStatuses.Paid).Code)
+
+	expectTransactionStatus(t, txUUID.String(), entities.TransactionStatuses.Completed)
+	expectBalance(t, user.Wallets[0].ID, "150.00")
+}
+
+func TestPayOut_RejectedRefundsWallet(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("200.00")
+	txUUID := givenFakePayWithdrawal(t, user, "50.00")
+
+	res := postPayOutStatus(psp.PaymentMethods.FakePay, txUUID, psp.PayOutStatuses.Rejected)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	// a repeated callback must not refund twice
+	res = postPayOutStatus(psp.PaymentMethods.FakePay, txUUID, psp.PayOutStatuses.Rejected)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	expectTransactionStatus(t, txUUID.String(), entities.TransactionStatuses.Failed)
+	expectBalance(t, user.Wallets[0].ID, "200.00")
+	expectLedgerReconciled(t)
+}
+
+func TestPayOut_ReturnedAfterPaidRefundsWallet(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("200.00")
+	txUUID := givenFakePayWithdrawal(t, user, "50.00")
+
+	assert.Equal(t, http.StatusOK, postPayOutStatus(psp.PaymentMethods.FakePay, txUUID, psp.PayOutStatuses.Paid).Code)
+	expectBalance(t, user.Wallets[0].ID, "150.00")
+
+	assert.Equal(t, http.StatusOK, postPayOutStatus(psp.PaymentMethods.FakePay, txUUID, psp.PayOutStatuses.Returned).Code)
+
+	expectTransactionStatus(t, txUUID.String(), entities.TransactionStatuses.Failed)
+	expectBalance(t, user.Wallets[0].ID, "200.00")
+	expectLedgerReconciled(t)
+}
+
+func TestPayOut_RejectedAfterPaidIsConflict(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("200.00")
+	txUUID := givenFakePayWithdrawal(t, user, "50.00")
+
+	assert.Equal(t, http.StatusOK, postPayOutStatus(psp.PaymentMethods.FakePay, txUUID, psp.PayOutStatuses.Paid).Code)
+	res := postPayOutStatus(psp.PaymentMethods.FakePay, txUUID, psp.PayOutStatuses.Rejected)
+
+	expectProblem(t, res, http.StatusConflict, "invalid_payout_transition")
+	expectBalance(t, user.Wallets[0].ID, "150.00")
+}
+
+func TestPayOut_OtherProviderCannotUpdate(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("200.00")
+	txUUID := givenFakePayWithdrawal(t, user, "50.00")
+
+	res := postPayOutStatus(psp.PaymentMethods.BankTransfer, txUUID, psp.PayOutStatuses.Rejected)
+
+	expectProblem(t, res, http.StatusNotFound, "transaction_not_found")
+	expectBalance(t, user.Wallets[0].ID, "150.00")
+}
+
+func givenFakePayWithdrawal(t *testing.T, user *entities.User, amount string) uuid.UUID {
+	bankAccount := givenBankAccount(user.ID)
+	txUUID := uuid.New()
+	body, _ := json.Marshal(&models.WithdrawRequest{
+		UUID:          txUUID,
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		BankAccountID: bankAccount.ID,
+		Amount:        twd(amount),
+	})
+
+	res := postRequest("/api/v1/payments/withdraw", body, user.ID)
+	assert.Equal(t, http.StatusOK, res.Code)
+	return txUUID
+}
+
+func postPayOutStatus(provider psp.PaymentMethod, txUUID uuid.UUID, status psp.PayOutStatus) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(&psp.PayOutStatusCallback{
+		TransactionID: txUUID.String(),
+		Status:        status,
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/payments/payout-status", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	signPSPCallback(req, provider, body)
+	r.ServeHTTP(res, req)
+	return res
+}
+
+func getTransactionByUUID(t *testing.T, txUUID uuid.UUID) *entities.Transaction {
+	var tx entities.Transaction
+	assert.Nil(t, database.DB.First(&tx, txUUID).Error)
+	return &tx
+}
@@ -0,0 +1,123 @@
+package integration_test
+
+import (
+	"banking-system/entities"
+	"banking-system/middleware"
+	"banking-system/psp"
+	"banking-system/repos"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestPSPCallback_Unsigned(t *testing.T) {
+	truncateTables()
+
+	body, txUUID := givenPendingDepositCallback()
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/payments/confirm", bytes.NewReader(body))
+	r.ServeHTTP(res, req)
+
+	expectProblem(t, res, http.StatusUnauthorized, "unknown_psp")
+	expectTransactionStatus(t, txUUID.String(), entities.TransactionStatuses.Pending)
+}
+
+func TestPSPCallback_InvalidSignature(t *testing.T) {
+	truncateTables()
+
+	body, txUUID := givenPendingDepositCallback()
+	req, _ := http.NewRequest("POST", "/api/v1/payments/confirm", bytes.NewReader(body))
+	signPSPCallback(req, psp.PaymentMethods.FakePay, []byte(`{"transaction_id":"tampered"}`))
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+
+	expectProblem(t, res, http.StatusUnauthorized, "invalid_signature")
+	expectTransactionStatus(t, txUUID.String(), entities.TransactionStatuses.Pending)
+}
+
+func TestPSPCallback_StaleTimestamp(t *testing.T) {
+	truncateTables()
+
+	body, txUUID := givenPendingDepositCallback()
+	req, _ := http.NewRequest("POST", "/api/v1/payments/confirm", bytes.NewReader(body))
+	timestamp := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	nonce := uuid.NewString()
+	req.Header.Set(middleware.PSP_PROVIDER_HEADER, string(psp.PaymentMethods.FakePay))
+	req.Header.Set(middleware.PSP_TIMESTAMP_HEADER, timestamp)
+	req.Header.Set(middleware.PSP_NONCE_HEADER, nonce)
+	req.Header.Set(middleware.PSP_SIGNATURE_HEADER, psp.SignCallback(psp.LoadCallbackSecrets()[psp.PaymentMethods.FakePay], timestamp, nonce, body))
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+
+	expectProblem(t, res, http.StatusUnauthorized, "stale_signature")
+	expectTransactionStatus(t, txUUID.String(), entities.TransactionStatuses.Pending)
+}
+
+func TestPSPCallback_ReplayedNonce(t *testing.T) {
+	truncateTables()
+
+	body, _ := givenPendingDepositCallback()
+	first, _ := http.NewRequest("POST", "/api/v1/payments/cancel", bytes.NewReader(body))
+	signPSPCallback(first, psp.PaymentMethods.FakePay, body)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, first)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected first callback to succeed, got %d", res.Code)
+	}
+
+	replay, _ := http.NewRequest("POST", "/api/v1/payments/cancel", bytes.NewReader(body))
+	replay.Header = first.Header.Clone()
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, replay)
+
+	expectProblem(t, res, http.StatusUnauthorized, "replayed_callback")
+}
+
+func TestPSPCallback_ReplayedNonceOnAnotherInstance(t *testing.T) {
+	truncateTables()
+
+	body, _ := givenPendingDepositCallback()
+	first, _ := http.NewRequest("POST", "/api/v1/payments/cancel", bytes.NewReader(body))
+	signPSPCallback(first, psp.PaymentMethods.FakePay, body)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, first)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected first callback to succeed, got %d", res.Code)
+	}
+
+	// Another instance has not seen the nonce itself
+	other := gin.New()
+	other.Use(middleware.ErrorHandler())
+	other.POST("/api/v1/payments/cancel", middleware.VerifyPSPSignature(psp.LoadCallbackSecrets(), repos.NewPSPNonceRepo()), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	replay, _ := http.NewRequest("POST", "/api/v1/payments/cancel", bytes.NewReader(body))
+	replay.Header = first.Header.Clone()
+	res = httptest.NewRecorder()
+	other.ServeHTTP(res, replay)
+
+	expectProblem(t, res, http.StatusUnauthorized, "replayed_callback")
+}
+
+func givenPendingDepositCallback() ([]byte, uuid.UUID) {
+	user := givenUserHasBalance("100")
+	txUUID := uuid.New()
+	givenTransaction(&entities.Transaction{
+		UUID:          txUUID,
+		Type:          entities.TransactionTypes.Deposit,
+		Status:        entities.TransactionStatuses.Pending,
+		Amount:        twd("50.00"),
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		WalletID:      user.Wallets[0].ID,
+	})
+
+	body, _ := json.Marshal(&psp.ConfirmRequest{TransactionID: txUUID.String()})
+	return body, txUUID
+}
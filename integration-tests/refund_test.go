@@ -0,0 +1,203 @@
+package integration_test
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"banking-system/middleware"
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/psp"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const integrationOperatorKey = "integration_test_operator_key"
+
+func TestRefund_PartialRefundsUpToDepositAmount(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100.00")
+	depositUUID := givenCompletedDeposit(t, user, "50.00")
+
+	res := postRefund(user.ID, depositUUID, "20.00")
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var refund models.TransactionResponse
+	json.Unmarshal(res.Body.Bytes(), &refund)
+	assert.Equal(t, entities.TransactionTypes.Refund, refund.Type)
+	assert.Equal(t, entities.TransactionStatuses.Completed, refund.Status)
+	assert.Equal(t, depositUUID, *refund.RelatedTransactionID)
+
+	expectProblem(t, postRefund(user.ID, depositUUID, "40.00"), http.StatusUnprocessableEntity, "refund_exceeds_amount")
+
+	assert.Equal(t, http.StatusOK, postRefund(user.ID, depositUUID, "30.00").Code)
+	expectProblem(t, postRefund(user.ID, depositUUID, "0.01"), http.StatusUnprocessableEntity, "refund_exceeds_amount")
+
+	expectBalance(t, user.Wallets[0].ID, "100.00")
+	expectTransactionStatus(t, depositUUID.String(), entities.TransactionStatuses.Completed)
+	assert.Equal(t, twd("50.00"), getTransactionByUUID(t, depositUUID).Amount, "the deposit must never change")
+	expectLedgerReconciled(t)
+}
+
+func TestRefund_FullRefundWithoutAmount(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100.00")
+	depositUUID := givenCompletedDeposit(t, user, "50.00")
+
+	assert.Equal(t, http.StatusOK, postRefund(user.ID, depositUUID, "").Code)
+	expectProblem(t, postRefund(user.ID, depositUUID, ""), http.StatusUnprocessableEntity, "refund_exceeds_amount")
+
+	expectBalance(t, user.Wallets[0].ID, "100.00")
+	expectLedgerReconciled(t)
+}
+
+func TestRefund_AnotherUsersDeposit(t *testing.T) {
+	truncateTables()
+
+	owner := givenUserHasBalance("100.00")
+	other := givenUserHasBalance("100.00")
+	depositUUID := givenCompletedDeposit(t, owner, "50.00")
+
+	expectProblem(t, postRefund(other.ID, depositUUID, "10.00"), http.StatusForbidden, "transaction_forbidden")
+	expectBalance(t, owner.Wallets[0].ID, "150.00")
+}
+
+func TestRefund_PendingDeposit(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100.00")
+	depositUUID := uuid.New()
+	givenTransaction(&entities.Transaction{
+		UUID:          depositUUID,
+		Type:          entities.TransactionTypes.Deposit,
+		Status:        entities.TransactionStatuses.Pending,
+		Amount:        twd("50.00"),
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		WalletID:      user.Wallets[0].ID,
+	})
+
+	expectProblem(t, postRefund(user.ID, depositUUID, "10.00"), http.StatusUnprocessableEntity, "transaction_not_refundable")
+}
+
+func TestReverse_Transfer(t *testing.T) {
+	truncateTables()
+
+	sender := givenUserHasBalance("200.00")
+	recipient := givenUserHasBalance("50.00")
+	transferOutUUID := givenTransfer(t, sender, recipient, "10.00")
+
+	res := postReversal(integrationOperatorKey, transferOutUUID)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var reversal models.TransactionResponse
+	json.Unmarshal(res.Body.Bytes(), &reversal)
+	assert.Equal(t, entities.TransactionTypes.ReversalIn, reversal.Type)
+	assert.Equal(t, transferOutUUID, *reversal.RelatedTransactionID)
+
+	expectBalance(t, sender.Wallets[0].ID, "200.00")
+	expectBalance(t, recipient.Wallets[0].ID, "50.00")
+	expectTransactionStatus(t, transferOutUUID.String(), entities.TransactionStatuses.Completed)
+	expectLedgerReconciled(t)
+
+	var history []entities.TransactionStatusChange
+	database.DB.Where("transaction_id = ?", reversal.UUID).Find(&history)
+	assert.Len(t, history, 1)
+	assert.Equal(t, entities.TransitionActors.Operator, history[0].Actor)
+	assert.Equal(t, "ops", history[0].ActorID)
+	assert.Equal(t, "sent to the wrong recipient", history[0].Reason)
+}
+
+func TestReverse_TwiceIsConflict(t *testing.T) {
+	truncateTables()
+
+	sender := givenUserHasBalance("200.00")
+	recipient := givenUserHasBalance("50.00")
+	transferOutUUID := givenTransfer(t, sender, recipient, "10.00")
+	transferInUUID := *getTransactionByUUID(t, transferOutUUID).RelatedTransactionID
+
+	assert.Equal(t, http.StatusOK, postReversal(integrationOperatorKey, transferOutUUID).Code)
+	expectProblem(t, postReversal(integrationOperatorKey, transferInUUID), http.StatusConflict, "already_reversed")
+
+	expectBalance(t, sender.Wallets[0].ID, "200.00")
+	expectBalance(t, recipient.Wallets[0].ID, "50.00")
+}
+
+func TestReverse_RequiresOperator(t *testing.T) {
+	truncateTables()
+
+	sender := givenUserHasBalance("200.00")
+	recipient := givenUserHasBalance("50.00")
+	transferOutUUID := givenTransfer(t, sender, recipient, "10.00")
+
+	expectProblem(t, postReversal("", transferOutUUID), http.StatusUnauthorized, "missing_operator_key")
+	expectProblem(t, postReversal("wrong_key", transferOutUUID), http.StatusUnauthorized, "invalid_operator_key")
+	expectBalance(t, sender.Wallets[0].ID, "190.00")
+}
+
+// givenCompletedDeposit makes a FakePay deposit and confirms it through the
+// provider callback, so that the ledger records it.
+func givenCompletedDeposit(t *testing.T, user *entities.User, amount string) uuid.UUID {
+	depositUUID := uuid.New()
+	givenTransaction(&entities.Transaction{
+		UUID:          depositUUID,
+		Type:          entities.TransactionTypes.Deposit,
+		Status:        entities.TransactionStatuses.Pending,
+		Amount:        twd(amount),
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		WalletID:      user.Wallets[0].ID,
+	})
+
+	body, _ := json.Marshal(&psp.ConfirmRequest{TransactionID: depositUUID.String()})
+	assert.Equal(t, http.StatusOK, postRequestWithPSPAuth("/api/v1/payments/confirm", body).Code)
+	return depositUUID
+}
+
+func givenTransfer(t *testing.T, sender *entities.User, recipient *entities.User, amount string) uuid.UUID {
+	transferOutUUID := uuid.New()
+	body, _ := json.Marshal(&models.TransferRequest{
+		UUID:              transferOutUUID,
+		RecipientUsername: recipient.Username,
+		Amount:            twd(amount),
+	})
+
+	assert.Equal(t, http.StatusOK, postRequest("/api/v1/payments/transfer", body, sender.ID).Code)
+	return transferOutUUID
+}
+
+func postRefund(userID uint, depositUUID uuid.UUID, amount string) *httptest.ResponseRecorder {
+	req := &models.RefundRequest{
+		UUID:          uuid.New(),
+		TransactionID: depositUUID.String(),
+	}
+	if amount != "" {
+		refundAmount := money.MustParse(amount, "TWD")
+		req.Amount = &refundAmount
+	}
+
+	body, _ := json.Marshal(req)
+	return postRequest("/api/v1/payments/refund", body, userID)
+}
+
+func postReversal(operatorKey string, transactionUUID uuid.UUID) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(&models.ReversalRequest{
+		UUID:          uuid.New(),
+		TransactionID: transactionUUID.String(),
+		Reason:        "sent to the wrong recipient",
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/payments/reverse", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if operatorKey != "" {
+		req.Header.Set(middleware.OPERATOR_KEY_HEADER, operatorKey)
+	}
+	r.ServeHTTP(res, req)
+	return res
+}
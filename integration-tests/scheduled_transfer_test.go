@@ -0,0 +1,206 @@
+package integration_test
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/psp"
+	"banking-system/repos"
+	"banking-system/services"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduledTransfer_ExecutesDueOccurrenceOnce(t *testing.T) {
+	truncateTables()
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("0.00")
+	schedule := givenScheduledTransfer(t, sender, recipient, "300.00", "DAILY")
+	sut := newScheduledTransferService()
+
+	assert.Nil(t, sut.ExecuteDue())
+	assert.Nil(t, sut.ExecuteDue())
+
+	expectBalance(t, sender.Wallets[0].ID, "700.00")
+	expectBalance(t, recipient.Wallets[0].ID, "300.00")
+
+	occurrences := getOccurrences(t, sender.ID, schedule.ID)
+	assert.Len(t, occurrences, 1)
+	assert.Equal(t, "SUCCEEDED", occurrences[0].Status)
+	assert.Equal(t, entities.OccurrenceTransactionID(schedule.ID, time.Now()), occurrences[0].TransactionID)
+
+	var stored entities.ScheduledTransfer
+	database.DB.First(&stored, schedule.ID)
+	assert.Equal(t, 1, stored.Occurrences)
+	assert.Equal(t, entities.Day(time.Now()).AddDate(0, 0, 1), entities.Day(*stored.NextRunOn))
+}
+
+func TestScheduledTransfer_InsufficientFundsIsFailedOccurrence(t *testing.T) {
+	truncateTables()
+	sender := givenUserHasBalance("100.00")
+	recipient := givenUserHasBalance("0.00")
+	schedule := givenScheduledTransfer(t, sender, recipient, "300.00", "ONCE")
+	clearOutbox()
+
+	assert.Nil(t, newScheduledTransferService().ExecuteDue())
+
+	expectBalance(t, sender.Wallets[0].ID, "100.00")
+	occurrences := getOccurrences(t, sender.ID, schedule.ID)
+	assert.Len(t, occurrences, 1)
+	assert.Equal(t, "FAILED", occurrences[0].Status)
+	assert.Equal(t, "insufficient_funds", occurrences[0].FailureCode)
+	assert.Equal(t, []entities.EventType{entities.EventTypes.ScheduledTransferFailed}, outboxEventTypes(sender.ID))
+
+	var stored entities.ScheduledTransfer
+	database.DB.First(&stored, schedule.ID)
+	assert.Equal(t, entities.ScheduledTransferStatuses.Completed, stored.Status)
+	assert.Nil(t, stored.NextRunOn)
+}
+
+func TestScheduledTransfer_OccurrenceMadeBeforeCrashIsNotPaidTwice(t *testing.T) {
+	truncateTables()
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("0.00")
+	schedule := givenScheduledTransfer(t, sender, recipient, "300.00", "DAILY")
+
+	// The transfer of today's occurrence went through, but the job stopped
+	// before recording it
+	body, _ := json.Marshal(&models.TransferRequest{
+		UUID:              entities.OccurrenceTransactionID(schedule.ID, time.Now()),
+		RecipientUsername: recipient.Username,
+		Amount:            twd("300.00"),
+	})
+	assert.Equal(t, http.StatusOK, postRequest("/api/v1/payments/transfer", body, sender.ID).Code)
+
+	assert.Nil(t, newScheduledTransferService().ExecuteDue())
+
+	expectBalance(t, sender.Wallets[0].ID, "700.00")
+	occurrences := getOccurrences(t, sender.ID, schedule.ID)
+	assert.Len(t, occurrences, 1)
+	assert.Equal(t, "SUCCEEDED", occurrences[0].Status)
+}
+
+func TestScheduledTransfer_PausedScheduleIsNotExecuted(t *testing.T) {
+	truncateTables()
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("0.00")
+	schedule := givenScheduledTransfer(t, sender, recipient, "300.00", "DAILY")
+
+	res := postRequest("/api/v1/scheduled-transfers/"+schedule.ID.String()+"/pause", nil, sender.ID)
+	assert.Equal(t, http.StatusOK, res.Code)
+	expectProblem(t, postRequest("/api/v1/scheduled-transfers/"+schedule.ID.String()+"/pause", nil, sender.ID),
+		http.StatusConflict, "scheduled_transfer_not_active")
+
+	assert.Nil(t, newScheduledTransferService().ExecuteDue())
+	expectBalance(t, sender.Wallets[0].ID, "1000.00")
+
+	res = postRequest("/api/v1/scheduled-transfers/"+schedule.ID.String()+"/resume", nil, sender.ID)
+	var resumed models.ScheduledTransferResponse
+	json.Unmarshal(res.Body.Bytes(), &resumed)
+	assert.Equal(t, "ACTIVE", resumed.Status)
+	assert.Equal(t, time.Now().UTC().Format(time.DateOnly), resumed.NextRunOn)
+}
+
+func TestScheduledTransfer_ScheduleDueBeforePauseIsNotClaimed(t *testing.T) {
+	truncateTables()
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("0.00")
+	schedule := givenScheduledTransfer(t, sender, recipient, "300.00", "DAILY")
+	repo := repos.NewScheduledTransferRepo()
+
+	// The schedule was due when the job read it, then paused before the
+	// occurrence was claimed
+	due, err := repo.GetDue(entities.Day(time.Now()), 10)
+	assert.Nil(t, err)
+	assert.Len(t, due, 1)
+	res := postRequest("/api/v1/scheduled-transfers/"+schedule.ID.String()+"/pause", nil, sender.ID)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	runOn := entities.Day(*due[0].NextRunOn)
+	due[0].Advance(runOn)
+	claimed, err := repo.ClaimOccurrence(&due[0], runOn, &entities.ScheduledTransferOccurrence{
+		ScheduledTransferID: schedule.ID,
+		RunOn:               runOn,
+		Sequence:            1,
+		Status:              entities.OccurrenceStatuses.Pending,
+		TransactionID:       entities.OccurrenceTransactionID(schedule.ID, runOn),
+	})
+
+	assert.Nil(t, err)
+	assert.False(t, claimed)
+	assert.Empty(t, getOccurrences(t, sender.ID, schedule.ID))
+
+	var stored entities.ScheduledTransfer
+	database.DB.First(&stored, schedule.ID)
+	assert.Equal(t, entities.ScheduledTransferStatuses.Paused, stored.Status)
+	assert.Equal(t, 0, stored.Occurrences)
+}
+
+func TestScheduledTransfer_OverdueScheduleSkipsMissedOccurrences(t *testing.T) {
+	truncateTables()
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("0.00")
+	schedule := givenScheduledTransfer(t, sender, recipient, "300.00", "DAILY")
+
+	// The job did not run for the last three days
+	missedFrom := entities.Day(time.Now()).AddDate(0, 0, -3)
+	database.DB.Model(&entities.ScheduledTransfer{}).Where("uuid = ?", schedule.ID).
+		Updates(map[string]interface{}{"start_date": missedFrom, "next_run_on": missedFrom})
+
+	assert.Nil(t, newScheduledTransferService().ExecuteDue())
+
+	expectBalance(t, sender.Wallets[0].ID, "700.00")
+	occurrences := getOccurrences(t, sender.ID, schedule.ID)
+	assert.Len(t, occurrences, 1)
+	assert.Equal(t, time.Now().UTC().Format(time.DateOnly), occurrences[0].RunOn)
+
+	var stored entities.ScheduledTransfer
+	database.DB.First(&stored, schedule.ID)
+	assert.Equal(t, 1, stored.Occurrences)
+	assert.Equal(t, entities.Day(time.Now()).AddDate(0, 0, 1), entities.Day(*stored.NextRunOn))
+}
+
+func TestScheduledTransfer_AnotherUsersScheduleIsForbidden(t *testing.T) {
+	truncateTables()
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("0.00")
+	schedule := givenScheduledTransfer(t, sender, recipient, "300.00", "WEEKLY")
+
+	expectProblem(t, postRequest("/api/v1/scheduled-transfers/"+schedule.ID.String()+"/cancel", nil, recipient.ID),
+		http.StatusForbidden, "scheduled_transfer_forbidden")
+}
+
+// givenScheduledTransfer schedules a transfer that starts today.
+func givenScheduledTransfer(t *testing.T, sender *entities.User, recipient *entities.User, amount string, frequency string) *models.ScheduledTransferResponse {
+	body, _ := json.Marshal(&models.CreateScheduledTransferRequest{
+		RecipientUsername: recipient.Username,
+		Amount:            twd(amount),
+		Frequency:         frequency,
+		StartDate:         time.Now().UTC().Format(time.DateOnly),
+	})
+	res := postRequest("/api/v1/scheduled-transfers", body, sender.ID)
+	assert.Equal(t, http.StatusCreated, res.Code)
+
+	var schedule models.ScheduledTransferResponse
+	json.Unmarshal(res.Body.Bytes(), &schedule)
+	return &schedule
+}
+
+func getOccurrences(t *testing.T, userID uint, scheduleID uuid.UUID) []models.ScheduledTransferOccurrenceResponse {
+	res := getRequest("/api/v1/scheduled-transfers/"+scheduleID.String()+"/occurrences", userID)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var occurrences []models.ScheduledTransferOccurrenceResponse
+	json.Unmarshal(res.Body.Bytes(), &occurrences)
+	return occurrences
+}
+
+func newScheduledTransferService() services.ScheduledTransferService {
+	paymentSrv := services.NewPaymentService(repos.NewUserRepo(), repos.NewTransactionRepo(), repos.NewBankAccountRepo(), psp.NewPSPFactory(), nil, newLimitService())
+	return services.NewScheduledTransferService(repos.NewScheduledTransferRepo(), repos.NewUserRepo(), paymentSrv)
+}
@@ -0,0 +1,124 @@
+package integration_test
+
+import (
+	"banking-system/middleware"
+	"banking-system/models"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession_RefreshRotatesToken(t *testing.T) {
+	truncateTables()
+
+	login := givenLoggedInUser(t, "johndoe")
+	refreshed := refreshTokens(t, login.RefreshToken)
+
+	assert.Equal(t, http.StatusOK, refreshed.Code)
+	tokens := decodeTokens(t, refreshed)
+	assert.NotEqual(t, login.RefreshToken, tokens.RefreshToken)
+	assert.Equal(t, http.StatusOK, sessionsRequest("GET", "/api/v1/user/sessions", tokens.AccessToken).Code)
+}
+
+func TestSession_ReusedRefreshTokenRevokesSession(t *testing.T) {
+	truncateTables()
+
+	login := givenLoggedInUser(t, "johndoe")
+	rotated := decodeTokens(t, refreshTokens(t, login.RefreshToken))
+
+	// the old refresh token is presented again, e.g. by an attacker
+	expectProblem(t, refreshTokens(t, login.RefreshToken), http.StatusUnauthorized, "refresh_token_reused")
+
+	// every token of the session is now rejected
+	expectProblem(t, refreshTokens(t, rotated.RefreshToken), http.StatusUnauthorized, "session_revoked")
+	expectProblem(t, sessionsRequest("GET", "/api/v1/user/sessions", rotated.AccessToken), http.StatusUnauthorized, "session_revoked")
+}
+
+func TestSession_LogoutRevokesAccessToken(t *testing.T) {
+	truncateTables()
+
+	login := givenLoggedInUser(t, "johndoe")
+	assert.Equal(t, http.StatusNoContent, sessionsRequest("POST", "/api/v1/user/logout", login.AccessToken).Code)
+
+	expectProblem(t, sessionsRequest("GET", "/api/v1/user/sessions", login.AccessToken), http.StatusUnauthorized, "session_revoked")
+	expectProblem(t, refreshTokens(t, login.RefreshToken), http.StatusUnauthorized, "session_revoked")
+}
+
+func TestSession_ListAndRevokeDevices(t *testing.T) {
+	truncateTables()
+
+	laptop := givenLoggedInUser(t, "johndoe")
+	phone := loginAs(t, "johndoe")
+
+	res := sessionsRequest("GET", "/api/v1/user/sessions", laptop.AccessToken)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var sessions []models.SessionResponse
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &sessions))
+	assert.Len(t, sessions, 2)
+
+	var phoneSession models.SessionResponse
+	for _, session := range sessions {
+		if !session.Current {
+			phoneSession = session
+		}
+	}
+
+	res = sessionsRequest("DELETE", "/api/v1/user/sessions/"+phoneSession.ID.String(), laptop.AccessToken)
+	assert.Equal(t, http.StatusNoContent, res.Code)
+
+	expectProblem(t, sessionsRequest("GET", "/api/v1/user/sessions", phone.AccessToken), http.StatusUnauthorized, "session_revoked")
+	assert.Equal(t, http.StatusOK, sessionsRequest("GET", "/api/v1/user/sessions", laptop.AccessToken).Code)
+}
+
+func TestSession_CannotRevokeAnotherUsersSession(t *testing.T) {
+	truncateTables()
+
+	john := givenLoggedInUser(t, "johndoe")
+	jane := givenLoggedInUser(t, "janedoe")
+
+	res := sessionsRequest("GET", "/api/v1/user/sessions", jane.AccessToken)
+	var sessions []models.SessionResponse
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &sessions))
+
+	res = sessionsRequest("DELETE", "/api/v1/user/sessions/"+sessions[0].ID.String(), john.AccessToken)
+	expectProblem(t, res, http.StatusNotFound, "session_not_found")
+}
+
+func givenLoggedInUser(t *testing.T, username string) *models.TokenResponse {
+	registerBody, _ := json.Marshal(&models.RegisterRequest{Username: username, Password: "password123", Name: "John Doe"})
+	assert.Equal(t, http.StatusCreated, postRequest("/api/v1/user", registerBody).Code)
+
+	return loginAs(t, username)
+}
+
+func loginAs(t *testing.T, username string) *models.TokenResponse {
+	loginBody, _ := json.Marshal(&models.LoginRequest{Username: username, Password: "password123"})
+	res := postRequest("/api/v1/user/login", loginBody)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	return decodeTokens(t, res)
+}
+
+func refreshTokens(t *testing.T, refreshToken string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(&models.RefreshTokenRequest{RefreshToken: refreshToken})
+	return postRequest("/api/v1/user/token/refresh", body)
+}
+
+func sessionsRequest(method string, path string, accessToken string) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, bytes.NewReader(nil))
+	req.Header.Set(middleware.AUTHORIZATION_HEADER, "Bearer "+accessToken)
+	r.ServeHTTP(res, req)
+	return res
+}
+
+func decodeTokens(t *testing.T, res *httptest.ResponseRecorder) *models.TokenResponse {
+	var tokens models.TokenResponse
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &tokens))
+	return &tokens
+}
@@ -0,0 +1,132 @@
+package integration_test
+
+import (
+	"banking-system/blobs"
+	"banking-system/database"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/repos"
+	"banking-system/services"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatement_CSVShowsEachSideOfATransfer(t *testing.T) {
+	truncateTables()
+
+	sender := givenUserHasBalance("200.00")
+	recipient := givenUserHasBalance("50.00")
+	givenTransfer(t, sender, recipient, "10.00")
+
+	rows := getCSVStatement(t, sender.ID)
+	assert.Equal(t, []string{"Opening balance", "0.00"}, rows[5])
+	assert.Equal(t, "ADJUSTMENT", rows[7][2])
+	assert.Equal(t, []string{"TRANSFER_OUT", "Transfer", "-10.00", "190.00"}, rows[8][2:])
+	assert.Contains(t, rows, []string{"Closing balance", "190.00"})
+
+	rows = getCSVStatement(t, recipient.ID)
+	assert.Equal(t, []string{"TRANSFER_IN", "Transfer", "10.00", "60.00"}, rows[8][2:])
+	assert.Contains(t, rows, []string{"TRANSFER_IN", "1", "10.00"})
+}
+
+func TestStatement_Formats(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100.00")
+	today := time.Now().UTC().Format("2006-01-02")
+
+	res := getRequest("/api/v1/statements?"+url.Values{"from": {today}, "to": {today}, "format": {"pdf"}}.Encode(), user.ID)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "application/pdf", res.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(res.Body.String(), "%PDF-"))
+
+	res = getRequest("/api/v1/statements?"+url.Values{"from": {today}, "to": {today}, "format": {"ofx"}}.Encode(), user.ID)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Header().Get("Content-Disposition"), ".ofx")
+	assert.Contains(t, res.Body.String(), "<BALAMT>100.00</BALAMT>")
+
+	expectProblem(t, getRequest("/api/v1/statements?"+url.Values{"from": {today}, "to": {today}, "format": {"xlsx"}}.Encode(), user.ID),
+		http.StatusBadRequest, "invalid_query")
+}
+
+func getCSVStatement(t *testing.T, userID uint) [][]string {
+	today := time.Now().UTC().Format("2006-01-02")
+	res := getRequest("/api/v1/statements?"+url.Values{"from": {today}, "to": {today}, "format": {"csv"}}.Encode(), userID)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	r := csv.NewReader(res.Body)
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	assert.Nil(t, err)
+	return rows
+}
+
+func TestMonthlyStatement_IssuedOnceAndNeverChanges(t *testing.T) {
+	truncateTables()
+
+	sender := givenUserHasBalance("200.00")
+	recipient := givenUserHasBalance("50.00")
+	givenTransfer(t, sender, recipient, "10.00")
+	lastMonth := time.Now().UTC().AddDate(0, 0, -time.Now().UTC().Day())
+	backdateWallet(sender.Wallets[0].ID, lastMonth)
+
+	sut := services.NewStatementService(repos.NewUserRepo(), repos.NewStatementRepo(), blobs.NewStore())
+	assert.Nil(t, sut.IssueMonthly())
+
+	issued := getIssuedStatements(t, sender.ID)
+	assert.Len(t, issued, 1)
+	assert.Equal(t, lastMonth.Format("2006-01"), issued[0].Month)
+	assert.Equal(t, twd("0.00"), issued[0].OpeningBalance)
+	assert.Equal(t, twd("190.00"), issued[0].ClosingBalance)
+	document := downloadStatement(t, sender.ID, issued[0])
+
+	// Neither a later posting into the month nor another run changes it
+	givenTransfer(t, sender, recipient, "5.00")
+	backdateWallet(sender.Wallets[0].ID, lastMonth)
+	assert.Nil(t, sut.IssueMonthly())
+
+	assert.Equal(t, issued, getIssuedStatements(t, sender.ID))
+	assert.Equal(t, document, downloadStatement(t, sender.ID, issued[0]))
+
+	var statement entities.Statement
+	database.DB.First(&statement, issued[0].ID)
+	assert.ErrorIs(t, database.DB.Model(&statement).Update("closing_balance", "0").Error, entities.ErrImmutableStatement)
+
+	expectProblem(t, getRequest("/api/v1/statements/"+issued[0].ID.String()+"/download", recipient.ID), http.StatusForbidden, "statement_forbidden")
+}
+
+// backdateWallet moves the wallet and all its postings to t. Postings are
+// immutable, so this bypasses the model hooks.
+func backdateWallet(walletID uint, t time.Time) {
+	database.DB.Exec("UPDATE wallets SET created_at = ? WHERE id = ?", t, walletID)
+	database.DB.Exec(`UPDATE postings SET created_at = ? WHERE ledger_account_id IN
+		(SELECT id FROM ledger_accounts WHERE wallet_id = ?)`, t, walletID)
+}
+
+func getIssuedStatements(t *testing.T, userID uint) []models.IssuedStatementResponse {
+	res := getRequest("/api/v1/statements/monthly", userID)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var issued []models.IssuedStatementResponse
+	json.Unmarshal(res.Body.Bytes(), &issued)
+	return issued
+}
+
+func downloadStatement(t *testing.T, userID uint, issued models.IssuedStatementResponse) []byte {
+	res := getRequest("/api/v1/statements/"+issued.ID.String()+"/download", userID)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "application/pdf", res.Header().Get("Content-Type"))
+
+	hash := sha256.Sum256(res.Body.Bytes())
+	assert.Equal(t, issued.ContentHash, hex.EncodeToString(hash[:]))
+	return res.Body.Bytes()
+}
@@ -0,0 +1,75 @@
+package integration_test
+
+import (
+	"banking-system/controllers"
+	"banking-system/database"
+	"banking-system/entities"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream_ReplaysEventsAfterLastEventID(t *testing.T) {
+	truncateTables()
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("0.00")
+	givenTransfer(t, sender, recipient, "100.00")
+
+	var first entities.OutboxEvent
+	database.DB.Where("user_id = ?", recipient.ID).Order("id").First(&first)
+	givenTransfer(t, sender, recipient, "200.00")
+
+	lastEventID := fmt.Sprintf("%d:%d", first.WalletID, first.ID)
+	res := openStream(recipient.ID, lastEventID)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "text/event-stream", res.Header().Get("Content-Type"))
+
+	body := res.Body.String()
+	assert.Equal(t, 1, strings.Count(body, "event: transaction.completed"))
+	assert.Equal(t, 2, strings.Count(body, "event: transfer.received"))
+	assert.Equal(t, 2, strings.Count(body, "event: wallet.balance_changed"))
+	assert.Contains(t, body, `"balance":300`)
+}
+
+func TestStream_WithoutLastEventIDReplaysNothing(t *testing.T) {
+	truncateTables()
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("0.00")
+	givenTransfer(t, sender, recipient, "100.00")
+
+	res := openStream(recipient.ID, "")
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.NotContains(t, res.Body.String(), "event:")
+}
+
+func TestStream_RejectsInvalidLastEventID(t *testing.T) {
+	truncateTables()
+	user := givenUserHasBalance("0.00")
+
+	expectProblem(t, openStream(user.ID, "garbage"), http.StatusBadRequest, "invalid_last_event_id")
+}
+
+// openStream reads the event stream of the user for a moment, then
+// disconnects.
+func openStream(userID uint, lastEventID string) *httptest.ResponseRecorder {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/api/v1/stream", nil)
+	authorize(req, userID)
+	if lastEventID != "" {
+		req.Header.Set(controllers.LAST_EVENT_ID_HEADER, lastEventID)
+	}
+
+	r.ServeHTTP(res, req)
+	return res
+}
@@ -0,0 +1,130 @@
+package integration_test
+
+import (
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/psp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory_PagesThroughAllTransactions(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100.00")
+	created := givenHistory(user, 5)
+
+	var seen []uuid.UUID
+	query := url.Values{"limit": {"2"}}
+	for pages := 0; pages < 5; pages++ {
+		page := getHistory(t, user.ID, query)
+		for _, tx := range page.Transactions {
+			seen = append(seen, tx.UUID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+
+	// Newest first
+	assert.Equal(t, []uuid.UUID{created[4], created[3], created[2], created[1], created[0]}, seen)
+}
+
+func TestHistory_Filters(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100.00")
+	other := givenUserHasBalance("100.00")
+	created := givenHistory(user, 4)
+	givenHistory(other, 2)
+
+	page := getHistory(t, user.ID, url.Values{"status": {"COMPLETED"}, "type": {"DEPOSIT,WITHDRAWAL"}, "order": {"asc"}})
+	assert.Len(t, page.Transactions, 2)
+	assert.Equal(t, created[0], page.Transactions[0].UUID)
+	assert.Equal(t, created[2], page.Transactions[1].UUID)
+
+	page = getHistory(t, user.ID, url.Values{"min_amount": {"20"}, "max_amount": {"30"}})
+	assert.Len(t, page.Transactions, 2)
+
+	from := time.Now().Add(-150 * time.Minute).UTC().Format(time.RFC3339)
+	page = getHistory(t, user.ID, url.Values{"from": {from}, "payment_method": {string(psp.PaymentMethods.FakePay)}})
+	assert.Len(t, page.Transactions, 2)
+}
+
+func TestHistory_InvalidQuery(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100.00")
+
+	expectProblem(t, getRequest("/api/v1/transactions?cursor=bogus", user.ID), http.StatusBadRequest, "invalid_cursor")
+	expectProblem(t, getRequest("/api/v1/transactions?order=sideways", user.ID), http.StatusBadRequest, "invalid_query")
+	expectProblem(t, getRequest("/api/v1/transactions"), http.StatusUnauthorized, "missing_token")
+}
+
+func TestHistory_ByUserIDListsCallersRecentTransactions(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100.00")
+	other := givenUserHasBalance("100.00")
+	created := givenHistory(user, 3)
+	givenTransaction(&entities.Transaction{
+		UUID:      uuid.New(),
+		CreatedAt: time.Now().AddDate(0, -7, 0),
+		Type:      entities.TransactionTypes.Deposit,
+		Status:    entities.TransactionStatuses.Completed,
+		Amount:    twd("10.00"),
+		WalletID:  user.Wallets[0].ID,
+	})
+
+	res := getRequest(fmt.Sprintf("/api/v1/transactions/user/%d", user.ID), user.ID)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var transactions []models.TransactionResponse
+	json.Unmarshal(res.Body.Bytes(), &transactions)
+	assert.Len(t, transactions, 3)
+	assert.Equal(t, created[2], transactions[0].UUID)
+
+	expectProblem(t, getRequest(fmt.Sprintf("/api/v1/transactions/user/%d", user.ID), other.ID), http.StatusForbidden, "transactions_forbidden")
+	expectProblem(t, getRequest("/api/v1/transactions/user/abc", user.ID), http.StatusBadRequest, "invalid_user_id")
+}
+
+// givenHistory creates n deposits of 10.00, 20.00, ... an hour apart, the
+// oldest first. Even ones are completed, odd ones pending.
+func givenHistory(user *entities.User, n int) []uuid.UUID {
+	ids := make([]uuid.UUID, n)
+	for i := range ids {
+		status := entities.TransactionStatuses.Completed
+		if i%2 == 1 {
+			status = entities.TransactionStatuses.Pending
+		}
+
+		ids[i] = uuid.New()
+		givenTransaction(&entities.Transaction{
+			UUID:          ids[i],
+			CreatedAt:     time.Now().Add(-time.Duration(n-i) * time.Hour),
+			Type:          entities.TransactionTypes.Deposit,
+			Status:        status,
+			Amount:        twd(fmt.Sprintf("%d.00", 10*(i+1))),
+			PaymentMethod: psp.PaymentMethods.FakePay,
+			WalletID:      user.Wallets[0].ID,
+		})
+	}
+	return ids
+}
+
+func getHistory(t *testing.T, userID uint, query url.Values) models.TransactionPageResponse {
+	res := getRequest("/api/v1/transactions?"+query.Encode(), userID)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var page models.TransactionPageResponse
+	json.Unmarshal(res.Body.Bytes(), &page)
+	return page
+}
@@ -26,7 +26,7 @@ func TestRegister_Success(t *testing.T) {
 
 	assert.Equal(t, http.StatusCreated, res.Code)
 	user := expectUserCreated(t, req.Username, req.Password, req.Name)
-	expectWalletCreated(t, user.ID, 0.00)
+	expectWalletCreated(t, user.ID, "0.00")
 }
 
 func TestRegister_DuplicateUsername(t *testing.T) {
@@ -47,7 +47,7 @@ func TestRegister_DuplicateUsername(t *testing.T) {
 	})
 	secondRes := postRequest("/api/v1/user", secondReq)
 
-	assert.Equal(t, http.StatusBadRequest, secondRes.Code)
+	assert.Equal(t, http.StatusConflict, secondRes.Code)
 	expectUniqueUsername(t, "johndoe")
 }
 
@@ -92,7 +92,7 @@ func TestRegister_MissingFields(t *testing.T) {
 
 func expectUserCreated(t *testing.T, username, password, name string) entities.User {
 	var user entities.User
-	result := database.DB.Preload("Wallet").Where("username = ?", username).First(&user)
+	result := database.DB.Preload("Wallets").Where("username = ?", username).First(&user)
 
 	assert.Nil(t, result.Error)
 	assert.Equal(t, username, user.Username)
@@ -104,13 +104,13 @@ func expectUserCreated(t *testing.T, username, password, name string) entities.U
 	return user
 }
 
-func expectWalletCreated(t *testing.T, userID uint, amount float64) {
+func expectWalletCreated(t *testing.T, userID uint, amount string) {
 	var user entities.User
-	result := database.DB.Preload("Wallet").First(&user, userID)
+	result := database.DB.Preload("Wallets").First(&user, userID)
 
 	assert.Nil(t, result.Error)
-	assert.NotNil(t, user.Wallet)
-	assert.Equal(t, 0.00, amount)
+	assert.Len(t, user.Wallets, 1)
+	assert.Equal(t, twd(amount), user.Wallets[0].Balance)
 }
 
 func expectUniqueUsername(t *testing.T, username string) {
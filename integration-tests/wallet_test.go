@@ -0,0 +1,115 @@
+package integration_test
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/money"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenWallet_Success(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("0")
+
+	body, _ := json.Marshal(&models.OpenWalletRequest{Currency: "USD"})
+	res := postRequest("/api/v1/user/wallets", body, user.ID)
+
+	assert.Equal(t, http.StatusCreated, res.Code)
+	expectWalletCount(t, user.ID, 2)
+}
+
+func TestOpenWallet_DuplicateCurrency(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("0")
+
+	body, _ := json.Marshal(&models.OpenWalletRequest{Currency: "TWD"})
+	res := postRequest("/api/v1/user/wallets", body, user.ID)
+
+	assert.Equal(t, http.StatusConflict, res.Code)
+	expectWalletCount(t, user.ID, 1)
+}
+
+func TestGetUser_ReturnsEveryBalance(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalances(twd("100.00"), money.MustParse("25.50", "USD"))
+
+	res := getRequest(fmt.Sprintf("/api/v1/user/%d", user.ID), user.ID)
+
+	var info models.UserInfoResponse
+	json.Unmarshal(res.Body.Bytes(), &info)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Len(t, info.Wallets, 2)
+}
+
+func TestGetUser_AnotherUserIsForbidden(t *testing.T) {
+	truncateTables()
+
+	user := givenUserHasBalance("100.00")
+	otherUser := givenUserHasBalance("0")
+
+	expectProblem(t, getRequest(fmt.Sprintf("/api/v1/user/%d", otherUser.ID), user.ID), http.StatusForbidden, "user_forbidden")
+	expectProblem(t, getRequest(fmt.Sprintf("/api/v1/user/%d", otherUser.ID)), http.StatusUnauthorized, "missing_token")
+}
+
+func TestTransfer_InWalletCurrency(t *testing.T) {
+	truncateTables()
+
+	sender := givenUserHasBalances(twd("100.00"), money.MustParse("50.00", "USD"))
+	recipient := givenUserHasBalances(twd("0"), money.MustParse("0", "USD"))
+
+	body, _ := json.Marshal(&models.TransferRequest{
+		UUID:              uuid.New(),
+		RecipientUsername: recipient.Username,
+		Amount:            money.MustParse("20.00", ""),
+		Currency:          "USD",
+	})
+	res := postRequest("/api/v1/payments/transfer", body, sender.ID)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	expectWalletBalance(t, sender.Wallets[1].ID, money.MustParse("30.00", "USD"))
+	expectWalletBalance(t, recipient.Wallets[1].ID, money.MustParse("20.00", "USD"))
+	expectBalance(t, sender.Wallets[0].ID, "100.00", "TWD wallet should be untouched")
+}
+
+func TestTransfer_RecipientLacksCurrency(t *testing.T) {
+	truncateTables()
+
+	sender := givenUserHasBalances(twd("100.00"), money.MustParse("50.00", "USD"))
+	recipient := givenUserHasBalance("0")
+
+	body, _ := json.Marshal(&models.TransferRequest{
+		UUID:              uuid.New(),
+		RecipientUsername: recipient.Username,
+		Amount:            money.MustParse("20.00", ""),
+		Currency:          "USD",
+	})
+	res := postRequest("/api/v1/payments/transfer", body, sender.ID)
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	expectWalletBalance(t, sender.Wallets[1].ID, money.MustParse("50.00", "USD"))
+}
+
+func expectWalletCount(t *testing.T, userID uint, count int64) {
+	var actual int64
+	database.DB.Model(&entities.Wallet{}).Where("user_id = ?", userID).Count(&actual)
+	assert.Equal(t, count, actual)
+}
+
+func expectWalletBalance(t *testing.T, walletID uint, amount money.Money) {
+	var wallet entities.Wallet
+	result := database.DB.First(&wallet, walletID)
+
+	assert.Nil(t, result.Error)
+	assert.Equal(t, amount, wallet.Balance)
+}
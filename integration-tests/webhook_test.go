@@ -0,0 +1,180 @@
+package integration_test
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/outbox"
+	"banking-system/repos"
+	"banking-system/services"
+	"banking-system/webhooks"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhooks_TransferIsDeliveredSigned(t *testing.T) {
+	truncateTables()
+	receiver := newWebhookReceiver(http.StatusOK)
+	defer receiver.Close()
+
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("0.00")
+	endpoint := givenWebhookEndpoint(t, recipient.ID, receiver.URL, "transfer.received", "wallet.balance_changed")
+
+	givenTransfer(t, sender, recipient, "300.00")
+	relayToWebhooks(t)
+	assert.Nil(t, newWebhookService().DeliverDue())
+
+	deliveries := receiver.Deliveries()
+	assert.Len(t, deliveries, 2)
+	for _, d := range deliveries {
+		assert.True(t, webhooks.Verify(endpoint.Secret, d.Header.Get(webhooks.TIMESTAMP_HEADER), d.Header.Get(webhooks.ID_HEADER), d.Body, d.Header.Get(webhooks.SIGNATURE_HEADER)))
+	}
+
+	var event struct {
+		Type string                    `json:"type"`
+		Data entities.BalanceEventData `json:"data"`
+	}
+	for _, d := range deliveries {
+		json.Unmarshal(d.Body, &event)
+		if event.Type == "wallet.balance_changed" {
+			assert.Equal(t, twd("300.00"), event.Data.Balance.WithCurrency("TWD"))
+		}
+	}
+}
+
+func TestWebhooks_OtherUsersEventsAreNotDelivered(t *testing.T) {
+	truncateTables()
+	receiver := newWebhookReceiver(http.StatusOK)
+	defer receiver.Close()
+
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("0.00")
+	bystander := givenUserHasBalance("0.00")
+	givenWebhookEndpoint(t, bystander.ID, receiver.URL, "transfer.received")
+
+	givenTransfer(t, sender, recipient, "300.00")
+	relayToWebhooks(t)
+	assert.Nil(t, newWebhookService().DeliverDue())
+
+	assert.Empty(t, receiver.Deliveries())
+}
+
+func TestWebhooks_DeadLetterCanBeRedelivered(t *testing.T) {
+	truncateTables()
+	receiver := newWebhookReceiver(http.StatusInternalServerError)
+	defer receiver.Close()
+
+	sender := givenUserHasBalance("1000.00")
+	recipient := givenUserHasBalance("0.00")
+	givenWebhookEndpoint(t, recipient.ID, receiver.URL, "transfer.received")
+	givenTransfer(t, sender, recipient, "300.00")
+	relayToWebhooks(t)
+
+	// Make the next failure the last attempt
+	database.DB.Model(&entities.WebhookDelivery{}).Where("1 = 1").Update("attempts", webhooks.MAX_ATTEMPTS-1)
+	assert.Nil(t, newWebhookService().DeliverDue())
+
+	res := getRequest("/api/v1/webhooks/dead-letters", recipient.ID)
+	assert.Equal(t, http.StatusOK, res.Code)
+	var deadLetters []models.WebhookDeliveryResponse
+	json.Unmarshal(res.Body.Bytes(), &deadLetters)
+	assert.Len(t, deadLetters, 1)
+	assert.Equal(t, 500, deadLetters[0].LastStatusCode)
+
+	redeliverPath := "/api/v1/webhooks/dead-letters/" + deadLetters[0].ID.String() + "/redeliver"
+	expectProblem(t, postRequest(redeliverPath, nil, sender.ID), http.StatusForbidden, "webhook_delivery_forbidden")
+	assert.Equal(t, http.StatusAccepted, postRequest(redeliverPath, nil, recipient.ID).Code)
+	expectProblem(t, postRequest(redeliverPath, nil, recipient.ID), http.StatusConflict, "delivery_not_dead_lettered")
+
+	receiver.status = http.StatusOK
+	assert.Nil(t, newWebhookService().DeliverDue())
+
+	var delivery entities.WebhookDelivery
+	database.DB.First(&delivery, deadLetters[0].ID)
+	assert.Equal(t, entities.WebhookDeliveryStatuses.Delivered, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+
+	// Every attempt carries the same event ID
+	deliveries := receiver.Deliveries()
+	assert.Len(t, deliveries, 2)
+	assert.Equal(t, deliveries[0].Header.Get(webhooks.ID_HEADER), deliveries[1].Header.Get(webhooks.ID_HEADER))
+}
+
+func TestWebhooks_RegisterRejectsUnknownEvent(t *testing.T) {
+	truncateTables()
+	user := givenUserHasBalance("0.00")
+
+	body, _ := json.Marshal(&models.CreateWebhookEndpointRequest{URL: "https://merchant.example.com/hooks", Events: []string{"user.deleted"}})
+	expectProblem(t, postRequest("/api/v1/webhooks", body, user.ID), http.StatusBadRequest, "invalid_event_type")
+}
+
+func TestWebhooks_RegisterRejectsInternalAddress(t *testing.T) {
+	truncateTables()
+	user := givenUserHasBalance("0.00")
+
+	body, _ := json.Marshal(&models.CreateWebhookEndpointRequest{URL: "https://169.254.169.254/latest/meta-data", Events: []string{"transfer.received"}})
+	expectProblem(t, postRequest("/api/v1/webhooks", body, user.ID), http.StatusBadRequest, "invalid_webhook_url")
+}
+
+// newWebhookService delivers to the loopback receivers of these tests.
+func newWebhookService() services.WebhookService {
+	return services.NewWebhookService(repos.NewWebhookRepo(), webhooks.NewSenderAllowing(webhooks.AnyAddr))
+}
+
+// relayToWebhooks publishes the outbox to the webhook service, as the relay
+// job does.
+func relayToWebhooks(t *testing.T) {
+	bus := outbox.NewBus()
+	bus.Subscribe(newWebhookService().Handle)
+	assert.Nil(t, services.NewOutboxRelay(repos.NewOutboxRepo(), bus).Relay())
+}
+
+// givenWebhookEndpoint stores an endpoint directly, since registration only
+// accepts public https URLs and the receiver listens on loopback.
+func givenWebhookEndpoint(t *testing.T, userID uint, url string, events ...string) *entities.WebhookEndpoint {
+	secret, err := webhooks.NewSecret()
+	assert.Nil(t, err)
+
+	endpoint := &entities.WebhookEndpoint{UserID: userID, URL: url, Secret: secret, Events: events}
+	assert.Nil(t, repos.NewWebhookRepo().CreateEndpoint(endpoint))
+	return endpoint
+}
+
+type receivedDelivery struct {
+	Header http.Header
+	Body   []byte
+}
+
+// webhookReceiver is an endpoint that records the deliveries it receives and
+// answers them with status.
+type webhookReceiver struct {
+	*httptest.Server
+	status     int
+	mu         sync.Mutex
+	deliveries []receivedDelivery
+}
+
+func newWebhookReceiver(status int) *webhookReceiver {
+	receiver := &webhookReceiver{status: status}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		receiver.deliveries = append(receiver.deliveries, receivedDelivery{Header: r.Header.Clone(), Body: body})
+		receiver.mu.Unlock()
+		w.WriteHeader(receiver.status)
+	}))
+	return receiver
+}
+
+func (r *webhookReceiver) Deliveries() []receivedDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedDelivery(nil), r.deliveries...)
+}
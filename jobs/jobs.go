@@ -0,0 +1,85 @@
+// Package jobs runs background work, such as polling payment providers, on a
+// fixed interval next to the HTTP server.
+package jobs
+
+import (
+	"banking-system/repos"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// InstanceID identifies this process as the holder of job leases.
+var InstanceID = instanceID()
+
+// Every runs fn every interval until the process exits. Errors and panics are
+// logged so that one failed run does not stop the job or the server.
+func Every(name string, interval time.Duration, fn func() error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			run(name, fn)
+		}
+	}()
+}
+
+func run(name string, fn func() error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Job '%s' panicked: %v", name, r)
+		}
+	}()
+
+	if err := fn(); err != nil {
+		log.Errorf("Job '%s' failed: %v", name, err)
+	}
+}
+
+// Exclusive wraps fn so that it only runs on the instance that holds the
+// lease on the job. The lease is renewed on every run and lapses after ttl,
+// so another replica takes over when the holder stops. ttl should be longer
+// than the job's interval.
+func Exclusive(leases repos.JobLeaseRepo, name string, ttl time.Duration, fn func() error) func() error {
+	return func() error {
+		acquired, err := leases.Acquire(name, InstanceID, ttl)
+		if err != nil {
+			return fmt.Errorf("failed to acquire lease: %w", err)
+		}
+
+		if !acquired {
+			log.Debugf("Skipping job '%s': another instance holds its lease", name)
+			return nil
+		}
+
+		return fn()
+	}
+}
+
+func instanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8])
+}
+
+// Interval reads a job interval such as "1m" from the environment variable
+// name, falling back to defaultInterval when it is not set.
+func Interval(name string, defaultInterval time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultInterval
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		log.Panicf("Invalid %s '%s': expected a positive duration such as 1m", name, value)
+	}
+
+	return interval
+}
@@ -0,0 +1,159 @@
+// Package limits resolves the transaction limits of a user from configurable
+// rules per transaction type, currency and tier.
+package limits
+
+import (
+	"banking-system/entities"
+	"banking-system/money"
+	"fmt"
+)
+
+// Rule sets limits for matching transactions, as read from configuration. An
+// empty Currency or Tier matches any. Amounts are in the rule's currency, so
+// a rule that sets them must name its currency.
+//
+// Each limit is taken from the most specific rule that sets it; of equally
+// specific rules, the first one wins. A limit no rule sets does not apply.
+type Rule struct {
+	Type              entities.TransactionType `json:"type"`
+	Currency          string                   `json:"currency,omitempty"`
+	Tier              entities.UserTier        `json:"tier,omitempty"`
+	PerTransactionMin string                   `json:"per_transaction_min,omitempty"`
+	PerTransactionMax string                   `json:"per_transaction_max,omitempty"`
+	Daily             string                   `json:"daily,omitempty"`
+	Monthly           string                   `json:"monthly,omitempty"`
+	DailyCount        *int64                   `json:"daily_count,omitempty"`
+	MonthlyCount      *int64                   `json:"monthly_count,omitempty"`
+}
+
+// Types are the transaction types limits apply to.
+var Types = []entities.TransactionType{
+	entities.TransactionTypes.Deposit,
+	entities.TransactionTypes.Withdrawal,
+	entities.TransactionTypes.TransferOut,
+}
+
+// Rules are parsed limit rules, in the order they were configured.
+type Rules []*rule
+
+type rule struct {
+	Rule
+	limits entities.TransactionLimits
+}
+
+// Parse validates rules and prepares them for resolving limits.
+func Parse(raw []Rule) (Rules, error) {
+	rules := make(Rules, 0, len(raw))
+	for i, r := range raw {
+		parsed, err := parseRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("limit rule %d (%s %s %s): %w", i+1, r.Type, r.Currency, r.Tier, err)
+		}
+		rules = append(rules, parsed)
+	}
+	return rules, nil
+}
+
+// MustParse is like Parse but panics on invalid rules.
+func MustParse(raw []Rule) Rules {
+	rules, err := Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+func parseRule(r Rule) (*rule, error) {
+	if !isLimited(r.Type) {
+		return nil, fmt.Errorf("transaction type '%s' has no limits", r.Type)
+	}
+
+	if r.Currency != "" && !money.IsSupported(r.Currency) {
+		return nil, fmt.Errorf("unsupported currency '%s'", r.Currency)
+	}
+
+	parsed := &rule{Rule: r}
+
+	amounts := []struct {
+		name   string
+		value  string
+		target **money.Money
+	}{
+		{"per_transaction_min", r.PerTransactionMin, &parsed.limits.PerTransactionMin},
+		{"per_transaction_max", r.PerTransactionMax, &parsed.limits.PerTransactionMax},
+		{"daily", r.Daily, &parsed.limits.Daily},
+		{"monthly", r.Monthly, &parsed.limits.Monthly},
+	}
+	for _, a := range amounts {
+		if a.value == "" {
+			continue
+		}
+		if r.Currency == "" {
+			return nil, fmt.Errorf("%s needs the rule's currency", a.name)
+		}
+
+		amount, err := money.Parse(a.value, r.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.name, err)
+		}
+		if amount.IsNegative() {
+			return nil, fmt.Errorf("%s is negative", a.name)
+		}
+		*a.target = &amount
+	}
+
+	for _, count := range []*int64{r.DailyCount, r.MonthlyCount} {
+		if count != nil && *count < 0 {
+			return nil, fmt.Errorf("count limit is negative")
+		}
+	}
+	parsed.limits.DailyCount = r.DailyCount
+	parsed.limits.MonthlyCount = r.MonthlyCount
+
+	return parsed, nil
+}
+
+func isLimited(txType entities.TransactionType) bool {
+	for _, t := range Types {
+		if t == txType {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the limits of transactions of txType in currency for users
+// of tier.
+func (rules Rules) Resolve(txType entities.TransactionType, currency string, tier entities.UserTier) entities.TransactionLimits {
+	var resolved entities.TransactionLimits
+
+	// Rules are applied from least to most specific, so that more specific
+	// rules replace the limits they set
+	for score := 0; score <= 2; score++ {
+		for i := len(rules) - 1; i >= 0; i-- {
+			r := rules[i]
+			if r.specificity() == score && r.matches(txType, currency, tier) {
+				resolved = resolved.Override(r.limits)
+			}
+		}
+	}
+
+	return resolved
+}
+
+func (r *rule) matches(txType entities.TransactionType, currency string, tier entities.UserTier) bool {
+	return r.Type == txType &&
+		(r.Currency == "" || r.Currency == currency) &&
+		(r.Tier == "" || r.Tier == tier)
+}
+
+func (r *rule) specificity() int {
+	score := 0
+	if r.Currency != "" {
+		score++
+	}
+	if r.Tier != "" {
+		score++
+	}
+	return score
+}
@@ -0,0 +1,83 @@
+package limits_test
+
+import (
+	"banking-system/entities"
+	"banking-system/limits"
+	"banking-system/money"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func count(n int64) *int64 {
+	return &n
+}
+
+func TestResolve_MostSpecificRuleWinsPerLimit(t *testing.T) {
+	rules := limits.MustParse([]limits.Rule{
+		{Type: entities.TransactionTypes.Withdrawal, DailyCount: count(10)},
+		{Type: entities.TransactionTypes.Withdrawal, Currency: "TWD", PerTransactionMax: "50000", Daily: "100000"},
+		{Type: entities.TransactionTypes.Withdrawal, Currency: "TWD", Tier: entities.UserTiers.Premium, Daily: "300000"},
+		{Type: entities.TransactionTypes.Withdrawal, Tier: entities.UserTiers.Premium, DailyCount: count(50)},
+	})
+
+	standard := rules.Resolve(entities.TransactionTypes.Withdrawal, "TWD", entities.UserTiers.Standard)
+	assert.Equal(t, money.MustParse("50000", "TWD"), *standard.PerTransactionMax)
+	assert.Equal(t, money.MustParse("100000", "TWD"), *standard.Daily)
+	assert.Equal(t, int64(10), *standard.DailyCount)
+	assert.Nil(t, standard.Monthly)
+
+	premium := rules.Resolve(entities.TransactionTypes.Withdrawal, "TWD", entities.UserTiers.Premium)
+	assert.Equal(t, money.MustParse("50000", "TWD"), *premium.PerTransactionMax)
+	assert.Equal(t, money.MustParse("300000", "TWD"), *premium.Daily)
+	assert.Equal(t, int64(50), *premium.DailyCount)
+}
+
+func TestResolve_FirstOfEquallySpecificRulesWins(t *testing.T) {
+	rules := limits.MustParse([]limits.Rule{
+		{Type: entities.TransactionTypes.Deposit, Currency: "TWD", Daily: "100"},
+		{Type: entities.TransactionTypes.Deposit, Currency: "TWD", Daily: "200"},
+	})
+
+	resolved := rules.Resolve(entities.TransactionTypes.Deposit, "TWD", entities.UserTiers.Standard)
+	assert.Equal(t, money.MustParse("100", "TWD"), *resolved.Daily)
+}
+
+func TestResolve_DefaultRules(t *testing.T) {
+	rules := limits.MustParse(limits.DefaultRules())
+
+	resolved := rules.Resolve(entities.TransactionTypes.TransferOut, "JPY", entities.UserTiers.Standard)
+	assert.Equal(t, money.MustParse("100", "JPY"), *resolved.PerTransactionMin)
+	assert.Equal(t, money.MustParse("450000", "JPY"), *resolved.PerTransactionMax)
+
+	assert.Equal(t, entities.TransactionLimits{}, rules.Resolve(entities.TransactionTypes.Refund, "TWD", entities.UserTiers.Standard))
+}
+
+func TestParse_RejectsInvalidRules(t *testing.T) {
+	invalid := []limits.Rule{
+		{Type: entities.TransactionTypes.Refund},
+		{Type: entities.TransactionTypes.Deposit, Currency: "XXX"},
+		{Type: entities.TransactionTypes.Deposit, Daily: "100"},
+		{Type: entities.TransactionTypes.Deposit, Currency: "TWD", Monthly: "-1"},
+		{Type: entities.TransactionTypes.Deposit, MonthlyCount: count(-1)},
+	}
+
+	for _, r := range invalid {
+		_, err := limits.Parse([]limits.Rule{r})
+		assert.NotNil(t, err, "%+v", r)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	os.WriteFile(path, []byte(`[{"type": "DEPOSIT", "currency": "TWD", "daily": "500", "daily_count": 3}]`), 0o600)
+
+	rules, err := limits.LoadFile(path)
+	assert.Nil(t, err)
+
+	resolved := rules.Resolve(entities.TransactionTypes.Deposit, "TWD", entities.UserTiers.Standard)
+	assert.Equal(t, money.MustParse("500", "TWD"), *resolved.Daily)
+	assert.Equal(t, int64(3), *resolved.DailyCount)
+}
@@ -0,0 +1,66 @@
+package limits
+
+import (
+	"encoding/json"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultAmounts are the per-transaction minimum and maximum and the daily
+// and monthly limits per currency, for every transaction type, when no rules
+// file is configured.
+var defaultAmounts = map[string][4]string{
+	"TWD": {"1.00", "100000.00", "200000.00", "1000000.00"},
+	"USD": {"1.00", "3000.00", "6000.00", "30000.00"},
+	"EUR": {"1.00", "3000.00", "6000.00", "30000.00"},
+	"JPY": {"100", "450000", "900000", "4500000"},
+}
+
+// DefaultRules are used when no rules file is configured.
+func DefaultRules() []Rule {
+	var rules []Rule
+	for _, txType := range Types {
+		for currency, amounts := range defaultAmounts {
+			rules = append(rules, Rule{
+				Type:              txType,
+				Currency:          currency,
+				PerTransactionMin: amounts[0],
+				PerTransactionMax: amounts[1],
+				Daily:             amounts[2],
+				Monthly:           amounts[3],
+			})
+		}
+	}
+	return rules
+}
+
+// NewRules loads the limit rules from the JSON file LIMIT_RULES_FILE, or
+// returns DefaultRules if it is not set.
+func NewRules() Rules {
+	path := os.Getenv("LIMIT_RULES_FILE")
+	if path == "" {
+		return MustParse(DefaultRules())
+	}
+
+	rules, err := LoadFile(path)
+	if err != nil {
+		log.Panicf("Failed to load limit rules from '%s': %v", path, err)
+	}
+	return rules
+}
+
+// LoadFile reads a JSON array of rules.
+func LoadFile(path string) (Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []Rule
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	return Parse(raw)
+}
@@ -1,9 +1,19 @@
 package main
 
 import (
+	"banking-system/blobs"
 	"banking-system/database"
+	"banking-system/fees"
+	"banking-system/jobs"
+	"banking-system/limits"
+	"banking-system/outbox"
+	"banking-system/psp"
+	"banking-system/repos"
 	"banking-system/router"
+	"banking-system/services"
+	"banking-system/webhooks"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -29,8 +39,13 @@ func loadEnv() {
 	godotenv.Load() // The Original .env
 }
 
+// @securityDefinitions.apikey BearerAuth
+// @in                         header
+// @name                       Authorization
+// @description                Access token from /user/login, sent as "Bearer <token>"
 func main() {
 	r := router.Setup()
+	startJobs()
 
 	if env == "development" && os.Getenv("RUN_HTTPS") == "y" {
 		r.RunTLS(":8444", os.Getenv("CERT_FILE"), os.Getenv("CERT_KEY"))
@@ -38,3 +53,46 @@ func main() {
 		r.Run(os.Getenv("APP_PORT"))
 	}
 }
+
+func startJobs() {
+	webhookSrv := services.NewWebhookService(repos.NewWebhookRepo(), webhooks.NewSender())
+	deliveryInterval := jobs.Interval("WEBHOOK_DELIVERY_INTERVAL", 10*time.Second)
+	jobs.Every("deliver webhooks", deliveryInterval,
+		jobs.Exclusive(repos.NewJobLeaseRepo(), "deliver webhooks", 2*deliveryInterval, webhookSrv.DeliverDue))
+
+	bus := outbox.NewBus()
+	bus.Subscribe(webhookSrv.Handle)
+	relay := services.NewOutboxRelay(repos.NewOutboxRepo(), outbox.NewSinks(bus)...)
+	// The lease outlasts the longest run of the relay, so that the events of
+	// a wallet are never published by two instances at once
+	jobs.Every("relay outbox", jobs.Interval("OUTBOX_RELAY_INTERVAL", time.Second),
+		jobs.Exclusive(repos.NewJobLeaseRepo(), "relay outbox", time.Minute, relay.Relay))
+
+	payOutSrv := services.NewPayOutService(repos.NewTransactionRepo(), psp.NewPSPFactory())
+	pollInterval := jobs.Interval("PAYOUT_POLL_INTERVAL", time.Minute)
+	jobs.Every("poll payouts", pollInterval,
+		jobs.Exclusive(repos.NewJobLeaseRepo(), "poll payouts", 2*pollInterval, payOutSrv.PollUnsettled))
+
+	expirySrv := services.NewExpiryService(repos.NewTransactionRepo(), psp.NewPSPFactory(), services.LoadExpiryConfig())
+	sweepInterval := jobs.Interval("EXPIRY_SWEEP_INTERVAL", 5*time.Minute)
+	jobs.Every("expire pending transactions", sweepInterval,
+		jobs.Exclusive(repos.NewJobLeaseRepo(), "expire pending transactions", 2*sweepInterval, expirySrv.SweepExpired))
+
+	userRepo := repos.NewUserRepo()
+	limitSrv := services.NewLimitService(userRepo, repos.NewLimitRepo(), limits.NewRules())
+	paymentSrv := services.NewPaymentService(userRepo, repos.NewTransactionRepo(), repos.NewBankAccountRepo(), psp.NewPSPFactory(), fees.NewSchedules(), limitSrv)
+	scheduledTransferSrv := services.NewScheduledTransferService(repos.NewScheduledTransferRepo(), userRepo, paymentSrv)
+	scheduleInterval := jobs.Interval("SCHEDULED_TRANSFER_INTERVAL", time.Minute)
+	jobs.Every("execute scheduled transfers", scheduleInterval,
+		jobs.Exclusive(repos.NewJobLeaseRepo(), "execute scheduled transfers", 2*scheduleInterval, scheduledTransferSrv.ExecuteDue))
+
+	paymentRequestSrv := services.NewPaymentRequestService(repos.NewPaymentRequestRepo(), userRepo, paymentSrv)
+	requestExpiryInterval := jobs.Interval("PAYMENT_REQUEST_EXPIRY_INTERVAL", time.Minute)
+	jobs.Every("expire payment requests", requestExpiryInterval,
+		jobs.Exclusive(repos.NewJobLeaseRepo(), "expire payment requests", 2*requestExpiryInterval, paymentRequestSrv.ExpireDue))
+
+	statementSrv := services.NewStatementService(userRepo, repos.NewStatementRepo(), blobs.NewStore())
+	statementInterval := jobs.Interval("STATEMENT_ISSUE_INTERVAL", time.Hour)
+	jobs.Every("issue monthly statements", statementInterval,
+		jobs.Exclusive(repos.NewJobLeaseRepo(), "issue monthly statements", 2*statementInterval, statementSrv.IssueMonthly))
+}
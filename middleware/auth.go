@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"banking-system/apperrors"
+	"banking-system/auth"
+	"banking-system/repos"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	USER_ID_HEADER       = "X-User-ID"
+	AUTHORIZATION_HEADER = "Authorization"
+	bearerPrefix         = "Bearer "
+)
+
+// Authenticate requires a valid access token, sent either as a Bearer token or
+// as the authorization cookie set by login, whose session has not been revoked.
+// It stores the user and session IDs in the request context. Behind a trusted
+// gateway the X-User-ID header set by the gateway is used instead.
+func Authenticate(sessionRepo repos.SessionRepo) gin.HandlerFunc {
+	cfg := auth.LoadConfig()
+
+	return func(c *gin.Context) {
+		if cfg.TrustedGateway {
+			authenticateFromGateway(c)
+			return
+		}
+
+		token := bearerToken(c)
+		if token == "" {
+			token, _ = c.Cookie(auth.AUTH_COOKIE_NAME)
+		}
+
+		if token == "" {
+			c.Error(apperrors.Unauthorized("missing_token", "authentication required"))
+			c.Abort()
+			return
+		}
+
+		userID, sessionID, err := cfg.ParseAccessToken(token)
+		if errors.Is(err, auth.ErrInvalidToken) {
+			c.Error(apperrors.Unauthorized("invalid_token", "invalid or expired token"))
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		session, err := sessionRepo.GetByUUID(sessionID)
+		if err != nil && !repos.IsNotFound(err) {
+			c.Error(fmt.Errorf("failed to get session: %w", err))
+			c.Abort()
+			return
+		}
+		if err != nil || session.UserID != userID || !session.IsActive(time.Now()) {
+			c.Error(apperrors.Unauthorized("session_revoked", "session has been revoked or has expired"))
+			c.Abort()
+			return
+		}
+
+		auth.SetUserID(c, userID)
+		auth.SetSessionID(c, sessionID)
+		c.Next()
+	}
+}
+
+func authenticateFromGateway(c *gin.Context) {
+	userIDStr := c.GetHeader(USER_ID_HEADER)
+	if userIDStr == "" {
+		c.Error(apperrors.Unauthorized("missing_user_context", "authentication context missing (No X-User-ID header)"))
+		c.Abort()
+		return
+	}
+
+	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+	if err != nil || userID == 0 {
+		c.Error(apperrors.Unauthorized("invalid_user_context", "invalid user ID format from gateway"))
+		c.Abort()
+		return
+	}
+
+	auth.SetUserID(c, uint(userID))
+	c.Next()
+}
+
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader(AUTHORIZATION_HEADER)
+	if len(header) < len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return ""
+	}
+	return strings.TrimSpace(header[len(bearerPrefix):])
+}
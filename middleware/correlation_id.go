@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	CORRELATION_ID_HEADER = "X-Correlation-ID"
+	correlationIDKey      = "correlation_id"
+	maxCorrelationIDLen   = 128
+)
+
+// CorrelationID tags every request with an ID that is echoed in the response
+// and attached to error logs. A caller-supplied ID is reused so that a request
+// can be followed across services.
+func CorrelationID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(CORRELATION_ID_HEADER)
+		if id == "" || len(id) > maxCorrelationIDLen {
+			id = uuid.NewString()
+		}
+
+		c.Set(correlationIDKey, id)
+		c.Header(CORRELATION_ID_HEADER, id)
+		c.Next()
+	}
+}
+
+// GetCorrelationID returns the correlation ID of the request, if any.
+func GetCorrelationID(c *gin.Context) string {
+	return c.GetString(correlationIDKey)
+}
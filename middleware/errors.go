@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"banking-system/apperrors"
+	"banking-system/models"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	PROBLEM_CONTENT_TYPE = "application/problem+json"
+	problemTypeBase      = "/problems/"
+)
+
+// ErrorHandler turns errors attached with c.Error into problem+json responses.
+// Domain errors keep their status and code; anything else, including panics,
+// is logged with the correlation ID and reported as a 500 without details.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.WithField(correlationIDKey, GetCorrelationID(c)).
+					Errorf("Panic while handling %s %s: %v", c.Request.Method, c.Request.URL.Path, r)
+				if !c.Writer.Written() {
+					writeProblem(c, nil)
+				}
+				c.Abort()
+			}
+		}()
+
+		c.Next()
+		renderErrors(c)
+	}
+}
+
+// renderErrors writes the last error attached to the request, unless a
+// response has already been written.
+func renderErrors(c *gin.Context) {
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+
+	err := c.Errors.Last().Err
+	if apperrors.As(err) == nil {
+		log.WithField(correlationIDKey, GetCorrelationID(c)).
+			Errorf("Unexpected error while handling %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+	}
+
+	writeProblem(c, err)
+}
+
+func writeProblem(c *gin.Context, err error) {
+	problem := models.ProblemResponse{
+		Type:          "about:blank",
+		Title:         http.StatusText(http.StatusInternalServerError),
+		Status:        http.StatusInternalServerError,
+		Detail:        "An unexpected error occurred",
+		Instance:      c.Request.URL.Path,
+		Code:          apperrors.CodeOf(nil),
+		CorrelationID: GetCorrelationID(c),
+	}
+
+	if appErr := apperrors.As(err); appErr != nil {
+		problem.Status = appErr.Kind.Status()
+		problem.Title = http.StatusText(problem.Status)
+		problem.Detail = appErr.Message
+		problem.Code = appErr.Code
+		problem.Type = problemTypeBase + strings.ReplaceAll(appErr.Code, "_", "-")
+	}
+
+	body, _ := json.Marshal(problem)
+	c.Data(problem.Status, PROBLEM_CONTENT_TYPE, body)
+}
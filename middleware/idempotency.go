@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"banking-system/apperrors"
+	"banking-system/auth"
+	"banking-system/entities"
+	"banking-system/repos"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	IDEMPOTENCY_KEY_HEADER      = "Idempotency-Key"
+	IDEMPOTENCY_REPLAYED_HEADER = "Idempotent-Replayed"
+	DEFAULT_IDEMPOTENCY_KEY_TTL = 24 * time.Hour
+	maxIdempotencyKeyLength     = 255
+
+	// inProgressLease is how long a key is held by a request that has not
+	// finished, so that a key of a request that crashed is soon free again.
+	inProgressLease = time.Minute
+)
+
+// Idempotency makes mutating endpoints safe to retry. It must run after
+// Authenticate. A request carrying an
+// Idempotency-Key header is executed once; later requests with the same key
+// and body get the recorded response back, and the same key with a different
+// body is rejected with 422. Requests without the header are passed through.
+// A request that failed on our side before changing anything frees its key for
+// a retry; one that failed after committing a change, such as a deposit whose
+// provider was unavailable, is recorded like any other response.
+func Idempotency(repo repos.IdempotencyRepo) gin.HandlerFunc {
+	ttl := idempotencyKeyTTL()
+
+	return func(c *gin.Context) {
+		keyValue := c.GetHeader(IDEMPOTENCY_KEY_HEADER)
+		if keyValue == "" {
+			c.Next()
+			return
+		}
+
+		if len(keyValue) > maxIdempotencyKeyLength {
+			c.Error(apperrors.Validation("invalid_idempotency_key", "Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(apperrors.Validation("invalid_request", "Failed to read request body"))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := &entities.IdempotencyKey{
+			Key:         keyValue,
+			Owner:       idempotencyKeyOwner(c),
+			Fingerprint: fingerprint(c.Request, body),
+			Status:      entities.IdempotencyKeyStatuses.InProgress,
+			ExpiresAt:   time.Now().Add(inProgressLease),
+		}
+
+		existing, err := repo.Reserve(key)
+		if err != nil {
+			c.Error(fmt.Errorf("failed to reserve idempotency key: %w", err))
+			c.Abort()
+			return
+		}
+
+		if existing != nil {
+			replay(c, existing, key.Fingerprint)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		completed := false
+		defer func() {
+			// A request that panicked or failed on our side before changing
+			// anything did not happen as far as the client is concerned, so the
+			// key is freed for a retry.
+			if !completed {
+				if err := repo.Release(key); err != nil {
+					log.Errorf("Failed to release idempotency key '%s': %v", key.Key, err)
+				}
+			}
+		}()
+
+		c.Next()
+		// Errors must be rendered now so that the response can be recorded
+		renderErrors(c)
+
+		if recorder.Status() >= http.StatusInternalServerError && !failedAfterCommit(c) {
+			return
+		}
+
+		key.ResponseStatus = recorder.Status()
+		key.ResponseContentType = recorder.Header().Get("Content-Type")
+		key.ResponseBody = recorder.body.Bytes()
+		key.ExpiresAt = time.Now().Add(ttl)
+		if err := repo.Complete(key); err != nil {
+			log.Errorf("Failed to record response for idempotency key '%s': %v", key.Key, err)
+			return
+		}
+		completed = true
+	}
+}
+
+// failedAfterCommit reports whether the request failed after committing a
+// change.
+func failedAfterCommit(c *gin.Context) bool {
+	for _, err := range c.Errors {
+		if apperrors.IsAfterCommit(err.Err) {
+			return true
+		}
+	}
+	return false
+}
+
+func replay(c *gin.Context, existing *entities.IdempotencyKey, fingerprint string) {
+	if existing.Fingerprint != fingerprint {
+		c.Error(apperrors.Unprocessable("idempotency_key_reused", "Idempotency-Key has already been used with a different request"))
+		c.Abort()
+		return
+	}
+
+	if existing.Status == entities.IdempotencyKeyStatuses.InProgress {
+		c.Error(apperrors.Conflict("idempotency_key_in_progress", "A request with this Idempotency-Key is still being processed"))
+		c.Abort()
+		return
+	}
+
+	c.Header(IDEMPOTENCY_REPLAYED_HEADER, "true")
+	if len(existing.ResponseBody) == 0 {
+		c.AbortWithStatus(existing.ResponseStatus)
+		return
+	}
+	c.Data(existing.ResponseStatus, existing.ResponseContentType, existing.ResponseBody)
+	c.Abort()
+}
+
+// idempotencyKeyOwner scopes keys to the authenticated user, so one user can
+// never replay another user's response.
+func idempotencyKeyOwner(c *gin.Context) string {
+	if userID, ok := auth.UserID(c); ok {
+		return strconv.FormatUint(uint64(userID), 10)
+	}
+	return ""
+}
+
+// fingerprint identifies a request by its method, path and exact body.
+func fingerprint(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(req.URL.Path))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func idempotencyKeyTTL() time.Duration {
+	value := os.Getenv("IDEMPOTENCY_KEY_TTL")
+	if value == "" {
+		return DEFAULT_IDEMPOTENCY_KEY_TTL
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		log.Panicf("Invalid IDEMPOTENCY_KEY_TTL '%s': expected a positive duration such as 24h", value)
+	}
+
+	return ttl
+}
+
+// responseRecorder keeps a copy of everything written to the client.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"banking-system/apperrors"
+	"banking-system/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+const OPERATOR_KEY_HEADER = "X-Operator-Key"
+
+// RequireOperator restricts back-office endpoints to operators that send one
+// of the configured keys in X-Operator-Key.
+func RequireOperator(keys auth.OperatorKeys) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(OPERATOR_KEY_HEADER)
+		if key == "" {
+			c.Error(apperrors.Unauthorized("missing_operator_key", "operator key missing"))
+			c.Abort()
+			return
+		}
+
+		operatorID, ok := keys.Authenticate(key)
+		if !ok {
+			c.Error(apperrors.Unauthorized("invalid_operator_key", "invalid operator key"))
+			c.Abort()
+			return
+		}
+
+		auth.SetOperatorID(c, operatorID)
+		c.Next()
+	}
+}
@@ -1,28 +1,119 @@
 package middleware
 
 import (
-	"net/http"
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/psp"
+	"banking-system/repos"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
-	PSP_API_KEY_HEADER = "X-PSP-API-Key"
-	PSP_API_KEY        = "psp_secret_key_12345" // TODO: Move to secret
+	PSP_PROVIDER_HEADER            = "X-PSP-ID"
+	PSP_TIMESTAMP_HEADER           = "X-PSP-Timestamp"
+	PSP_NONCE_HEADER               = "X-PSP-Nonce"
+	PSP_SIGNATURE_HEADER           = "X-PSP-Signature"
+	DEFAULT_PSP_CALLBACK_TOLERANCE = 5 * time.Minute
+	maxPSPNonceLength              = 128
+	pspProviderKey                 = "psp_provider"
 )
 
-func VerifyPSPApiKey() gin.HandlerFunc {
+// VerifyPSPSignature authenticates callbacks from payment service providers.
+// The provider names itself in X-PSP-ID and signs the timestamp, a nonce and
+// the raw body with its own secret (see psp.SignCallback). Callbacks older
+// than the tolerance are rejected, and a nonce is accepted only once within
+// it by any instance, so a captured callback cannot be replayed.
+func VerifyPSPSignature(secrets psp.CallbackSecrets, nonces repos.PSPNonceRepo) gin.HandlerFunc {
+	tolerance := pspCallbackTolerance()
+
 	return func(c *gin.Context) {
-		apiKey := c.GetHeader(PSP_API_KEY_HEADER)
+		provider := psp.PaymentMethod(c.GetHeader(PSP_PROVIDER_HEADER))
+		secret, ok := secrets[provider]
+		if !ok {
+			abortUnauthorizedCallback(c, "unknown_psp", "unknown payment service provider")
+			return
+		}
+
+		timestamp := c.GetHeader(PSP_TIMESTAMP_HEADER)
+		nonce := c.GetHeader(PSP_NONCE_HEADER)
+		signature := c.GetHeader(PSP_SIGNATURE_HEADER)
+		if timestamp == "" || nonce == "" || signature == "" || len(nonce) > maxPSPNonceLength {
+			abortUnauthorizedCallback(c, "missing_signature", "callback signature headers missing")
+			return
+		}
+
+		signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || math.Abs(time.Since(time.Unix(signedAt, 0)).Seconds()) > tolerance.Seconds() {
+			abortUnauthorizedCallback(c, "stale_signature", "callback timestamp is outside the allowed tolerance")
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(apperrors.Validation("invalid_request", "Failed to read request body"))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		if !psp.VerifyCallbackSignature(secret, timestamp, nonce, body, signature) {
+			abortUnauthorizedCallback(c, "invalid_signature", "invalid callback signature")
+			return
+		}
 
-		if apiKey != PSP_API_KEY {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"message": "Unauthorized - Invalid API key",
-			})
+		// Only checked once the signature is valid, so that forged requests
+		// cannot burn a provider's nonces
+		fresh, err := nonces.Use(&entities.PSPNonce{
+			Provider:  string(provider),
+			Nonce:     nonce,
+			ExpiresAt: time.Now().Add(2 * tolerance),
+		})
+		if err != nil {
+			c.Error(fmt.Errorf("failed to record callback nonce: %w", err))
 			c.Abort()
 			return
 		}
+		if !fresh {
+			abortUnauthorizedCallback(c, "replayed_callback", "callback nonce has already been used")
+			return
+		}
 
+		c.Set(pspProviderKey, provider)
 		c.Next()
 	}
 }
+
+// GetPSPProvider returns the provider that signed the callback.
+func GetPSPProvider(c *gin.Context) psp.PaymentMethod {
+	value, _ := c.Get(pspProviderKey)
+	provider, _ := value.(psp.PaymentMethod)
+	return provider
+}
+
+func abortUnauthorizedCallback(c *gin.Context, code string, message string) {
+	c.Error(apperrors.Unauthorized(code, "%s", message))
+	c.Abort()
+}
+
+func pspCallbackTolerance() time.Duration {
+	value := os.Getenv("PSP_CALLBACK_TOLERANCE")
+	if value == "" {
+		return DEFAULT_PSP_CALLBACK_TOLERANCE
+	}
+
+	tolerance, err := time.ParseDuration(value)
+	if err != nil || tolerance <= 0 {
+		log.Panicf("Invalid PSP_CALLBACK_TOLERANCE '%s': expected a positive duration such as 5m", value)
+	}
+
+	return tolerance
+}
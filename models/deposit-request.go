@@ -1,6 +1,7 @@
 package models
 
 import (
+	"banking-system/money"
 	"banking-system/psp"
 
 	"github.com/google/uuid"
@@ -9,6 +10,7 @@ import (
 type DepositRequest struct {
 	UUID          uuid.UUID `json:"uuid" binding:"required"`
 	UserID        uint
-	Amount        float64           `json:"amount" binding:"required,gt=0"`
+	Amount        money.Money       `json:"amount"`
+	Currency      string            `json:"currency" binding:"omitempty,len=3"`
 	PaymentMethod psp.PaymentMethod `json:"payment_method"`
 }
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"banking-system/fees"
+	"banking-system/money"
+)
+
+type FeeQuoteResponse struct {
+	Operation fees.Operation `json:"operation"`
+	Amount    money.Money    `json:"amount"`
+	Fee       money.Money    `json:"fee"`
+	Currency  string         `json:"currency"`
+
+	// Total is what the wallet is debited for a withdrawal or transfer, or
+	// credited for a deposit, once the fee is taken
+	Total money.Money `json:"total"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"banking-system/fees"
+	"banking-system/money"
+	"banking-system/psp"
+)
+
+type FeeQuoteRequest struct {
+	UserID        uint              `json:"-"` // Read from header, not JSON
+	Operation     fees.Operation    `json:"operation" binding:"required,oneof=DEPOSIT WITHDRAWAL TRANSFER"`
+	Amount        money.Money       `json:"amount"`
+	Currency      string            `json:"currency" binding:"omitempty,len=3"`
+	PaymentMethod psp.PaymentMethod `json:"payment_method"`
+}
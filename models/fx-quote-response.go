@@ -0,0 +1,18 @@
+package models
+
+import (
+	"banking-system/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type FXQuoteResponse struct {
+	QuoteID      uuid.UUID   `json:"quote_id"`
+	FromCurrency string      `json:"from_currency"`
+	ToCurrency   string      `json:"to_currency"`
+	FromAmount   money.Money `json:"from_amount"`
+	ToAmount     money.Money `json:"to_amount"`
+	Rate         string      `json:"rate"`
+	ExpiresAt    time.Time   `json:"expires_at"`
+}
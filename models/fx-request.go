@@ -0,0 +1,20 @@
+package models
+
+import (
+	"banking-system/money"
+
+	"github.com/google/uuid"
+)
+
+type FXQuoteRequest struct {
+	UserID       uint        `json:"-"` // Read from header, not JSON
+	FromCurrency string      `json:"from_currency" binding:"required,len=3"`
+	ToCurrency   string      `json:"to_currency" binding:"required,len=3"`
+	Amount       money.Money `json:"amount"`
+}
+
+type ConvertRequest struct {
+	UUID    uuid.UUID `json:"uuid" binding:"required"`
+	UserID  uint      `json:"-"` // Read from header, not JSON
+	QuoteID uuid.UUID `json:"quote_id" binding:"required"`
+}
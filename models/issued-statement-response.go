@@ -0,0 +1,20 @@
+package models
+
+import (
+	"banking-system/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IssuedStatementResponse describes a monthly statement as it was issued.
+// ContentHash is the hex SHA-256 of the downloaded document.
+type IssuedStatementResponse struct {
+	ID             uuid.UUID   `json:"id"`
+	Currency       string      `json:"currency"`
+	Month          string      `json:"month"`
+	OpeningBalance money.Money `json:"opening_balance"`
+	ClosingBalance money.Money `json:"closing_balance"`
+	ContentHash    string      `json:"content_hash"`
+	IssuedAt       time.Time   `json:"issued_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"banking-system/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type LedgerDiscrepancy struct {
+	AccountCode string      `json:"account_code"`
+	Expected    money.Money `json:"expected"`
+	Actual      money.Money `json:"actual"`
+	Currency    string      `json:"currency"`
+	Reason      string      `json:"reason"`
+}
+
+type LedgerReconciliationResponse struct {
+	Balanced      bool                   `json:"balanced"`
+	TrialBalances map[string]money.Money `json:"trial_balances"`
+	Discrepancies []LedgerDiscrepancy    `json:"discrepancies"`
+}
+
+type PostingResponse struct {
+	AccountCode string      `json:"account_code"`
+	Amount      money.Money `json:"amount"`
+	Currency    string      `json:"currency"`
+}
+
+type JournalEntryResponse struct {
+	UUID          uuid.UUID         `json:"uuid"`
+	TransactionID *uuid.UUID        `json:"transaction_id,omitempty"`
+	Description   string            `json:"description"`
+	Postings      []PostingResponse `json:"postings"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
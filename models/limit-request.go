@@ -0,0 +1,23 @@
+package models
+
+import (
+	"banking-system/entities"
+	"banking-system/money"
+)
+
+// LimitOverrideRequest replaces limits of one user for one transaction type
+// and currency. Limits left out keep those of the user's tier; leaving out
+// all of them removes the override.
+type LimitOverrideRequest struct {
+	UserID            uint                     `json:"-"` // Read from the path, not JSON
+	OperatorID        string                   `json:"-"`
+	Type              entities.TransactionType `json:"type" binding:"required,oneof=DEPOSIT WITHDRAWAL TRANSFER_OUT"`
+	Currency          string                   `json:"currency" binding:"required,len=3"`
+	PerTransactionMin *money.Money             `json:"per_transaction_min,omitempty"`
+	PerTransactionMax *money.Money             `json:"per_transaction_max,omitempty"`
+	Daily             *money.Money             `json:"daily,omitempty"`
+	Monthly           *money.Money             `json:"monthly,omitempty"`
+	DailyCount        *int64                   `json:"daily_count,omitempty" binding:"omitempty,min=0"`
+	MonthlyCount      *int64                   `json:"monthly_count,omitempty" binding:"omitempty,min=0"`
+	Reason            string                   `json:"reason" binding:"required,max=255"`
+}
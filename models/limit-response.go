@@ -0,0 +1,31 @@
+package models
+
+import (
+	"banking-system/entities"
+	"banking-system/money"
+)
+
+// LimitResponse shows the limits of one transaction type in one currency and
+// what is left of them. Limits that do not apply are left out.
+type LimitResponse struct {
+	Type              entities.TransactionType `json:"type"`
+	Currency          string                   `json:"currency"`
+	PerTransactionMin *money.Money             `json:"per_transaction_min,omitempty"`
+	PerTransactionMax *money.Money             `json:"per_transaction_max,omitempty"`
+	Daily             *AmountAllowance         `json:"daily,omitempty"`
+	Monthly           *AmountAllowance         `json:"monthly,omitempty"`
+	DailyCount        *CountAllowance          `json:"daily_count,omitempty"`
+	MonthlyCount      *CountAllowance          `json:"monthly_count,omitempty"`
+}
+
+type AmountAllowance struct {
+	Limit     money.Money `json:"limit"`
+	Used      money.Money `json:"used"`
+	Remaining money.Money `json:"remaining"`
+}
+
+type CountAllowance struct {
+	Limit     int64 `json:"limit"`
+	Used      int64 `json:"used"`
+	Remaining int64 `json:"remaining"`
+}
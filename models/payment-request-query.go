@@ -0,0 +1,10 @@
+package models
+
+import "banking-system/entities"
+
+// PaymentRequestQuery filters a user's payment requests. An empty status
+// matches every request.
+type PaymentRequestQuery struct {
+	UserID uint                          `form:"-"` // Read from header, not the query
+	Status entities.PaymentRequestStatus `form:"status"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"banking-system/money"
+	"time"
+)
+
+// CreatePaymentRequestRequest asks PayerUsername to pay Amount. The request
+// expires at ExpiresAt, or after 7 days if it is left out.
+type CreatePaymentRequestRequest struct {
+	UserID        uint        `json:"-"` // Read from header, not JSON
+	PayerUsername string      `json:"payer_username" binding:"required"`
+	Amount        money.Money `json:"amount"`
+	Currency      string      `json:"currency" binding:"omitempty,len=3"`
+	Memo          string      `json:"memo" binding:"max=140"`
+	ExpiresAt     *time.Time  `json:"expires_at"`
+}
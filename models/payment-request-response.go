@@ -0,0 +1,24 @@
+package models
+
+import (
+	"banking-system/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentRequestResponse describes a payment request to either side.
+// TransactionID is the payer's transfer that paid an accepted request.
+type PaymentRequestResponse struct {
+	ID                uuid.UUID   `json:"id"`
+	RequesterUsername string      `json:"requester_username"`
+	PayerUsername     string      `json:"payer_username"`
+	Amount            money.Money `json:"amount"`
+	Currency          string      `json:"currency"`
+	Memo              string      `json:"memo,omitempty"`
+	Status            string      `json:"status"`
+	ExpiresAt         time.Time   `json:"expires_at"`
+	ClosedAt          *time.Time  `json:"closed_at,omitempty"`
+	TransactionID     *uuid.UUID  `json:"transaction_id,omitempty"`
+	CreatedAt         time.Time   `json:"created_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// ProblemResponse is an RFC 7807 problem details document, served as
+// application/problem+json for every failed request.
+type ProblemResponse struct {
+	Type          string `json:"type"`
+	Title         string `json:"title"`
+	Status        int    `json:"status"`
+	Detail        string `json:"detail,omitempty"`
+	Instance      string `json:"instance,omitempty"`
+	Code          string `json:"code"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"banking-system/money"
+
+	"github.com/google/uuid"
+)
+
+// RefundRequest refunds a completed deposit through the provider it was paid
+// in with. Without an amount, the deposit is refunded in full.
+type RefundRequest struct {
+	UUID          uuid.UUID `json:"uuid" binding:"required"`
+	UserID        uint
+	TransactionID string       `json:"transaction_id" binding:"required"`
+	Amount        *money.Money `json:"amount,omitempty"`
+}
+
+// ReversalRequest reverses a completed transfer in full. TransactionID may be
+// either side of the transfer.
+type ReversalRequest struct {
+	UUID          uuid.UUID `json:"uuid" binding:"required"`
+	OperatorID    string    `json:"-"`
+	TransactionID string    `json:"transaction_id" binding:"required"`
+	Reason        string    `json:"reason" binding:"required,max=255"`
+}
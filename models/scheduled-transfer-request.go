@@ -0,0 +1,19 @@
+package models
+
+import "banking-system/money"
+
+// CreateScheduledTransferRequest sets up a transfer on StartDate, repeated at
+// Frequency until EndDate or for Count occurrences. Dates are YYYY-MM-DD in
+// UTC. DayOfMonth is only used by MONTHLY schedules and defaults to the day of
+// StartDate.
+type CreateScheduledTransferRequest struct {
+	UserID            uint        `json:"-"` // Set from the authenticated user, not JSON
+	RecipientUsername string      `json:"recipient_username" binding:"required"`
+	Amount            money.Money `json:"amount"`
+	Currency          string      `json:"currency" binding:"omitempty,len=3"`
+	Frequency         string      `json:"frequency" binding:"required"`
+	StartDate         string      `json:"start_date" binding:"required"`
+	DayOfMonth        int         `json:"day_of_month" binding:"omitempty,min=1,max=31"`
+	EndDate           string      `json:"end_date"`
+	Count             *int        `json:"count" binding:"omitempty,min=1"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"banking-system/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScheduledTransferResponse describes a schedule. NextRunOn is omitted once
+// the schedule has ended or while it is paused.
+type ScheduledTransferResponse struct {
+	ID                uuid.UUID   `json:"id"`
+	RecipientUsername string      `json:"recipient_username"`
+	Amount            money.Money `json:"amount"`
+	Currency          string      `json:"currency"`
+	Frequency         string      `json:"frequency"`
+	StartDate         string      `json:"start_date"`
+	DayOfMonth        int         `json:"day_of_month,omitempty"`
+	EndDate           string      `json:"end_date,omitempty"`
+	Count             *int        `json:"count,omitempty"`
+	Status            string      `json:"status"`
+	NextRunOn         string      `json:"next_run_on,omitempty"`
+	Occurrences       int         `json:"occurrences"`
+	CreatedAt         time.Time   `json:"created_at"`
+}
+
+// ScheduledTransferOccurrenceResponse is the outcome of one occurrence of a
+// schedule. TransactionID is the transfer made, or attempted, for it.
+type ScheduledTransferOccurrenceResponse struct {
+	RunOn         string    `json:"run_on"`
+	Sequence      int       `json:"sequence"`
+	Status        string    `json:"status"`
+	TransactionID uuid.UUID `json:"transaction_id"`
+	FailureCode   string    `json:"failure_code,omitempty"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
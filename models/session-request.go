@@ -0,0 +1,6 @@
+package models
+
+type RefreshTokenRequest struct {
+	// Optional when the refresh token is sent as a cookie
+	RefreshToken string `json:"refresh_token"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"banking-system/statements"
+	"time"
+)
+
+// StatementRequest selects the wallet and days of a statement. Both days are
+// included and count in UTC.
+type StatementRequest struct {
+	UserID   uint              `form:"-"` // Read from header, not the query
+	From     time.Time         `form:"from" binding:"required" time_format:"2006-01-02" time_utc:"1"`
+	To       time.Time         `form:"to" binding:"required" time_format:"2006-01-02" time_utc:"1"`
+	Format   statements.Format `form:"format" binding:"required,oneof=csv pdf ofx"`
+	Currency string            `form:"currency" binding:"omitempty,len=3"`
+}
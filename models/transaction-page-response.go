@@ -0,0 +1,8 @@
+package models
+
+// TransactionPageResponse is one page of the transaction history. NextCursor
+// fetches the following page and is left out on the last one.
+type TransactionPageResponse struct {
+	Transactions []TransactionResponse `json:"transactions"`
+	NextCursor   string                `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"banking-system/entities"
+	"banking-system/psp"
+	"time"
+)
+
+// TransactionQuery filters and pages the transaction history. Lists accept
+// repeated or comma-separated values; filters left out match every
+// transaction.
+type TransactionQuery struct {
+	UserID         uint                         `form:"-"` // Set from the authenticated user, not the query
+	From           time.Time                    `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To             time.Time                    `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Types          []entities.TransactionType   `form:"type" collection_format:"csv"`
+	Statuses       []entities.TransactionStatus `form:"status" collection_format:"csv"`
+	PaymentMethods []psp.PaymentMethod          `form:"payment_method" collection_format:"csv"`
+	Currency       string                       `form:"currency" binding:"omitempty,len=3"`
+	MinAmount      string                       `form:"min_amount"`
+	MaxAmount      string                       `form:"max_amount"`
+	Order          string                       `form:"order" binding:"omitempty,oneof=asc desc"`
+	Limit          int                          `form:"limit" binding:"omitempty,min=1"`
+	Cursor         string                       `form:"cursor"`
+}
@@ -2,16 +2,28 @@ package models
 
 import (
 	"banking-system/entities"
+	"banking-system/money"
+	"banking-system/psp"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type TransactionResponse struct {
-	UUID          uuid.UUID                `json:"uuid"`
-	Type          entities.TransactionType `json:"type"`
+	UUID          uuid.UUID                  `json:"uuid"`
+	Type          entities.TransactionType   `json:"type"`
 	Status        entities.TransactionStatus `json:"status"`
-	Amount        float64                  `json:"amount"`
-	PaymentMethod string                   `json:"payment_method,omitempty"`
-	CreatedAt     time.Time                `json:"created_at"`
+	Amount        money.Money                `json:"amount"`
+	Currency      string                     `json:"currency"`
+	PaymentMethod string                     `json:"payment_method,omitempty"`
+	PayOutStatus  psp.PayOutStatus           `json:"payout_status,omitempty"`
+	CreatedAt     time.Time                  `json:"created_at"`
+
+	// Fee is the fee charged for the transaction, collected by the FEE
+	// transaction that relates to it
+	Fee *money.Money `json:"fee,omitempty"`
+
+	// RelatedTransactionID links a transfer to its counterpart and a refund or
+	// reversal to the transaction it undoes
+	RelatedTransactionID *uuid.UUID `json:"related_transaction_id,omitempty"`
 }
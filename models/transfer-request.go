@@ -1,10 +1,18 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"banking-system/money"
+
+	"github.com/google/uuid"
+)
 
 type TransferRequest struct {
 	UUID              uuid.UUID `json:"uuid"`
 	SenderUserID      uint
-	RecipientUsername string  `json:"recipient_username" binding:"required"`
-	Amount            float64 `json:"amount" binding:"required,gt=0"`
+	RecipientUsername string      `json:"recipient_username" binding:"required"`
+	Amount            money.Money `json:"amount"`
+	Currency          string      `json:"currency" binding:"omitempty,len=3"`
+
+	// PaymentRequestID is the payment request the transfer pays, if any
+	PaymentRequestID *uuid.UUID `json:"-"`
 }
@@ -1,6 +1,18 @@
 package models
 
+import (
+	"banking-system/entities"
+	"banking-system/money"
+)
+
+type WalletResponse struct {
+	ID       uint        `json:"id"`
+	Currency string      `json:"currency"`
+	Balance  money.Money `json:"balance"`
+}
+
 type UserInfoResponse struct {
-	Username string  `json:"username"`
-	Balance  float64 `json:"balance"`
+	Username string            `json:"username"`
+	Tier     entities.UserTier `json:"tier"`
+	Wallets  []WalletResponse  `json:"wallets"`
 }
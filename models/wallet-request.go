@@ -0,0 +1,6 @@
+package models
+
+type OpenWalletRequest struct {
+	UserID   uint   `json:"-"` // Read from header, not JSON
+	Currency string `json:"currency" binding:"required,len=3"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// CreateWebhookEndpointRequest registers a URL to receive the given events
+// about the user's account.
+type CreateWebhookEndpointRequest struct {
+	UserID uint     `json:"-"` // Read from header, not JSON
+	URL    string   `json:"url" binding:"required,url,max=2048"`
+	Events []string `json:"events" binding:"required,min=1,dive,required"`
+}
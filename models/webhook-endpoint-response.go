@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// WebhookEndpointResponse describes a registered endpoint. The secret that
+// deliveries are signed with is only returned when the endpoint is created.
+type WebhookEndpointResponse struct {
+	ID        uint      `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
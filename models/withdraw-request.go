@@ -1,6 +1,7 @@
 package models
 
 import (
+	"banking-system/money"
 	"banking-system/psp"
 
 	"github.com/google/uuid"
@@ -9,7 +10,8 @@ import (
 type WithdrawRequest struct {
 	UUID          uuid.UUID `json:"uuid"`
 	UserID        uint
-	Amount        float64           `json:"amount" binding:"required,gt=0"`
+	Amount        money.Money       `json:"amount"`
+	Currency      string            `json:"currency" binding:"omitempty,len=3"`
 	PaymentMethod psp.PaymentMethod `json:"payment_method" binding:"required"`
-	BankAccountID uint              `json:"bank_account_id"`
+	BankAccountID uint              `json:"bank_account_id" binding:"required"`
 }
@@ -0,0 +1,350 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of decimal places Money is stored with. It matches the
+// numeric(18,4) columns amounts are persisted in.
+const Scale = 4
+
+const (
+	unitsPerWhole = 10000
+	maxIntDigits  = 18 - Scale
+	maxUnits      = 999999999999999999
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrOverflow         = errors.New("amount out of range")
+	ErrInvalidAmount    = errors.New("invalid amount")
+)
+
+// minorUnits holds the ISO 4217 number of minor units for the currencies we
+// support. Unknown currencies round to two decimal places.
+var minorUnits = map[string]int{
+	"TWD": 2,
+	"USD": 2,
+	"EUR": 2,
+	"JPY": 0,
+}
+
+// Money is an exact decimal amount in a currency. The zero value is zero in an
+// unspecified currency; use WithCurrency to attach one.
+type Money struct {
+	units    int64
+	currency string
+}
+
+func Zero(currency string) Money {
+	return Money{currency: currency}
+}
+
+// FromMinorUnits builds an amount from the smallest unit of the currency,
+// e.g. cents for USD.
+func FromMinorUnits(minor int64, currency string) Money {
+	return Money{units: minor * pow10(Scale-MinorUnits(currency)), currency: currency}
+}
+
+func Parse(s string, currency string) (Money, error) {
+	units, err := parseUnits(s)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{units: units, currency: currency}, nil
+}
+
+func MustParse(s string, currency string) Money {
+	m, err := Parse(s, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// IsSupported reports whether wallets can be held in the currency.
+func IsSupported(currency string) bool {
+	_, ok := minorUnits[currency]
+	return ok
+}
+
+func MinorUnits(currency string) int {
+	if digits, ok := minorUnits[currency]; ok {
+		return digits
+	}
+	return 2
+}
+
+func (m Money) Currency() string {
+	return m.currency
+}
+
+func (m Money) WithCurrency(currency string) Money {
+	return Money{units: m.units, currency: currency}
+}
+
+func (m Money) IsZero() bool     { return m.units == 0 }
+func (m Money) IsPositive() bool { return m.units > 0 }
+func (m Money) IsNegative() bool { return m.units < 0 }
+
+func (m Money) Neg() Money {
+	return Money{units: -m.units, currency: m.currency}
+}
+
+func (m Money) Abs() Money {
+	if m.units < 0 {
+		return m.Neg()
+	}
+	return m
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if m.currency != other.currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.currency, other.currency)
+	}
+
+	sum := m.units + other.units
+	if sum > maxUnits || sum < -maxUnits {
+		return Money{}, ErrOverflow
+	}
+
+	return Money{units: sum, currency: m.currency}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	return m.Add(other.Neg())
+}
+
+// Cmp returns -1, 0 or +1 depending on whether m is less than, equal to or
+// greater than other. Comparing different currencies is a programming error
+// and panics.
+func (m Money) Cmp(other Money) int {
+	if m.currency != other.currency {
+		panic(fmt.Sprintf("%v: %s and %s", ErrCurrencyMismatch, m.currency, other.currency))
+	}
+
+	switch {
+	case m.units < other.units:
+		return -1
+	case m.units > other.units:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (m Money) LessThan(other Money) bool    { return m.Cmp(other) < 0 }
+func (m Money) GreaterThan(other Money) bool { return m.Cmp(other) > 0 }
+
+// Mul multiplies by an exact factor, rounding half away from zero to the
+// storage scale.
+func (m Money) Mul(factor *big.Rat) (Money, error) {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(m.units), factor)
+	units, err := roundRat(product)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{units: units, currency: m.currency}, nil
+}
+
+// Round rounds half away from zero to the minor units of the currency.
+func (m Money) Round() Money {
+	step := pow10(Scale - MinorUnits(m.currency))
+	if step == 1 {
+		return m
+	}
+
+	rem := m.units % step
+	units := m.units - rem
+	if 2*abs(rem) >= step {
+		if m.units < 0 {
+			units -= step
+		} else {
+			units += step
+		}
+	}
+
+	return Money{units: units, currency: m.currency}
+}
+
+// IsRounded reports whether m has no more decimal places than the minor units
+// of its currency, so that it can actually be paid, unlike 0.5 JPY.
+func (m Money) IsRounded() bool {
+	return m.units%pow10(Scale-MinorUnits(m.currency)) == 0
+}
+
+// String formats the amount with at least the currency's minor units, e.g.
+// "100.00" for TWD and "1000" for JPY. Extra storage digits are kept only when
+// they are significant.
+func (m Money) String() string {
+	s := m.fixed()
+	minDigits := MinorUnits(m.currency)
+	intPart, frac, _ := strings.Cut(s, ".")
+	for len(frac) > minDigits && frac[len(frac)-1] == '0' {
+		frac = frac[:len(frac)-1]
+	}
+	if frac == "" {
+		return intPart
+	}
+	return intPart + "." + frac
+}
+
+func (m Money) fixed() string {
+	sign := ""
+	units := m.units
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, units/unitsPerWhole, Scale, units%unitsPerWhole)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a numeric string. The literal is
+// parsed exactly, never through float64. The currency is left unspecified.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	units, err := parseUnits(s)
+	if err != nil {
+		return err
+	}
+
+	m.units = units
+	return nil
+}
+
+// Value stores the amount in a numeric column. The currency is persisted in
+// its own column by the owning entity.
+func (m Money) Value() (driver.Value, error) {
+	return m.fixed(), nil
+}
+
+func (m *Money) Scan(value any) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		m.units = 0
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', Scale, 64)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", value)
+	}
+
+	units, err := parseUnits(s)
+	if err != nil {
+		return err
+	}
+
+	m.units = units
+	return nil
+}
+
+func parseUnits(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	neg := false
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+
+	intPart, frac, _ := strings.Cut(s, ".")
+	if intPart == "" && frac == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+	if !isDigits(intPart) || !isDigits(frac) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	intPart = strings.TrimLeft(intPart, "0")
+	if len(intPart) > maxIntDigits {
+		return 0, ErrOverflow
+	}
+
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > Scale {
+		return 0, fmt.Errorf("%w: more than %d decimal places", ErrInvalidAmount, Scale)
+	}
+
+	digits := intPart + frac + strings.Repeat("0", Scale-len(frac))
+	units, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	if neg {
+		units = -units
+	}
+	return units, nil
+}
+
+func roundRat(r *big.Rat) (int64, error) {
+	num := new(big.Int).Set(r.Num())
+	den := r.Denom()
+
+	neg := num.Sign() < 0
+	num.Abs(num)
+
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Lsh(rem, 1).Cmp(den) >= 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+
+	if !quo.IsInt64() || quo.Int64() > maxUnits {
+		return 0, ErrOverflow
+	}
+
+	if neg {
+		return -quo.Int64(), nil
+	}
+	return quo.Int64(), nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func pow10(n int) int64 {
+	result := int64(1)
+	for range n {
+		result *= 10
+	}
+	return result
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
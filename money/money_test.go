@@ -0,0 +1,97 @@
+package money_test
+
+import (
+	"banking-system/money"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		currency string
+		expected string
+	}{
+		{"100", "TWD", "100.00"},
+		{"0.1", "USD", "0.10"},
+		{"-12.3456", "USD", "-12.3456"},
+		{"1000", "JPY", "1000"},
+		{".5", "EUR", "0.50"},
+	}
+
+	for _, tt := range tests {
+		m, err := money.Parse(tt.input, tt.currency)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.expected, m.String())
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, input := range []string{"", "-", "abc", "1.23456", "1e5", "1000000000000000"} {
+		_, err := money.Parse(input, "TWD")
+		assert.NotNil(t, err, "expected error for %q", input)
+	}
+}
+
+func TestAdd_NoDrift(t *testing.T) {
+	sum := money.Zero("TWD")
+	tenCents := money.MustParse("0.10", "TWD")
+	for range 1000 {
+		sum, _ = sum.Add(tenCents)
+	}
+
+	assert.Equal(t, money.MustParse("100", "TWD"), sum)
+}
+
+func TestAdd_CurrencyMismatch(t *testing.T) {
+	_, err := money.MustParse("1", "TWD").Add(money.MustParse("1", "USD"))
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+}
+
+func TestRound(t *testing.T) {
+	assert.Equal(t, "1.01", money.MustParse("1.005", "USD").Round().String())
+	assert.Equal(t, "-1.01", money.MustParse("-1.005", "USD").Round().String())
+	assert.Equal(t, "1.00", money.MustParse("1.0049", "USD").Round().String())
+	assert.Equal(t, "101", money.MustParse("100.5", "JPY").Round().String())
+}
+
+func TestIsRounded(t *testing.T) {
+	assert.True(t, money.MustParse("1.01", "TWD").IsRounded())
+	assert.True(t, money.MustParse("-1.01", "TWD").IsRounded())
+	assert.False(t, money.MustParse("1.005", "TWD").IsRounded())
+	assert.True(t, money.MustParse("100", "JPY").IsRounded())
+	assert.False(t, money.MustParse("0.5", "JPY").IsRounded())
+}
+
+func TestMul(t *testing.T) {
+	fee, err := money.MustParse("333.33", "TWD").Mul(big.NewRat(15, 1000))
+	assert.Nil(t, err)
+	assert.Equal(t, "5.00", fee.Round().String())
+}
+
+func TestJSON(t *testing.T) {
+	var req struct {
+		Amount money.Money `json:"amount"`
+	}
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"amount": 0.30}`), &req))
+	assert.Equal(t, money.MustParse("0.3", ""), req.Amount)
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"amount": "12.34"}`), &req))
+	assert.Equal(t, money.MustParse("12.34", ""), req.Amount)
+
+	out, _ := json.Marshal(map[string]money.Money{"amount": money.MustParse("5", "USD")})
+	assert.JSONEq(t, `{"amount": 5.00}`, string(out))
+}
+
+func TestScanValue(t *testing.T) {
+	var m money.Money
+	assert.Nil(t, m.Scan([]byte("150.2500")))
+	assert.Equal(t, money.MustParse("150.25", ""), m)
+
+	value, _ := money.MustParse("-7.5", "TWD").Value()
+	assert.Equal(t, "-7.5000", value)
+}
@@ -0,0 +1,48 @@
+package outbox
+
+import (
+	"errors"
+	"sync"
+)
+
+// Handler processes a message published on the bus. It must be safe to call
+// again with a message it already processed.
+type Handler func(msg *Message) error
+
+// Bus hands messages to the handlers subscribed in this process, one after
+// the other. A message is only published once every handler took it.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+func (b *Bus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+func (*Bus) Name() string {
+	return "bus"
+}
+
+// Publish calls every handler, even after one fails, and returns their
+// errors. Since the message is then published again, handlers that succeeded
+// see it twice.
+func (b *Bus) Publish(msg *Message) error {
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
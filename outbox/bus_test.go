@@ -0,0 +1,32 @@
+package outbox_test
+
+import (
+	"banking-system/outbox"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBus_HandsMessageToEveryHandler(t *testing.T) {
+	bus := outbox.NewBus()
+	var handled []string
+	bus.Subscribe(func(*outbox.Message) error {
+		handled = append(handled, "first")
+		return errors.New("database is down")
+	})
+	bus.Subscribe(func(*outbox.Message) error {
+		handled = append(handled, "second")
+		return nil
+	})
+
+	err := bus.Publish(&outbox.Message{ID: uuid.New()})
+
+	assert.ErrorContains(t, err, "database is down")
+	assert.Equal(t, []string{"first", "second"}, handled)
+}
+
+func TestBus_WithoutHandlersPublishes(t *testing.T) {
+	assert.Nil(t, outbox.NewBus().Publish(&outbox.Message{ID: uuid.New()}))
+}
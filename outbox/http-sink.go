@@ -0,0 +1,43 @@
+package outbox
+
+import (
+	"banking-system/webhooks"
+	"encoding/json"
+)
+
+// HTTPSink posts every message as JSON to a URL, signed like a webhook
+// delivery with secret. Any status other than 2xx is a failure.
+type HTTPSink struct {
+	url    string
+	secret string
+	sender webhooks.Sender
+}
+
+func NewHTTPSink(url string, secret string) *HTTPSink {
+	return &HTTPSink{
+		url:    url,
+		secret: secret,
+		// The sink is configured by operators and may be on the internal network
+		sender: webhooks.NewSenderAllowing(webhooks.AnyAddr),
+	}
+}
+
+func (*HTTPSink) Name() string {
+	return "http"
+}
+
+func (s *HTTPSink) Publish(msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.sender.Send(&webhooks.Message{
+		URL:       s.url,
+		Secret:    s.secret,
+		EventID:   msg.ID.String(),
+		EventType: msg.Type,
+		Body:      body,
+	})
+	return err
+}
@@ -0,0 +1,54 @@
+package outbox_test
+
+import (
+	"banking-system/entities"
+	"banking-system/outbox"
+	"banking-system/webhooks"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPSink_PostsSignedMessage(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	msg := &outbox.Message{
+		ID:       uuid.New(),
+		Sequence: 3,
+		Type:     entities.EventTypes.TransferReceived,
+		WalletID: 7,
+		Data:     json.RawMessage(`{"uuid":"1"}`),
+	}
+	err := outbox.NewHTTPSink(server.URL, "secret").Publish(msg)
+
+	assert.Nil(t, err)
+	assert.Equal(t, msg.ID.String(), received.Header.Get(webhooks.ID_HEADER))
+	assert.True(t, webhooks.Verify("secret", received.Header.Get(webhooks.TIMESTAMP_HEADER), msg.ID.String(), body, received.Header.Get(webhooks.SIGNATURE_HEADER)))
+
+	var sent outbox.Message
+	json.Unmarshal(body, &sent)
+	assert.Equal(t, uint(3), sent.Sequence)
+	assert.JSONEq(t, `{"uuid":"1"}`, string(sent.Data))
+}
+
+func TestHTTPSink_FailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := outbox.NewHTTPSink(server.URL, "secret").Publish(&outbox.Message{ID: uuid.New()})
+
+	assert.ErrorIs(t, err, webhooks.ErrRejected)
+}
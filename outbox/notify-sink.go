@@ -0,0 +1,52 @@
+package outbox
+
+import (
+	"encoding/json"
+
+	"gorm.io/gorm"
+)
+
+// maxNotifyPayload stays below the 8000 byte limit Postgres puts on the
+// payload of a notification.
+const maxNotifyPayload = 7900
+
+// NotifySink sends every message as JSON with pg_notify on a channel, for
+// listeners connected to the database. Notifications only reach the
+// listeners connected when they are sent, and a message whose data is too
+// large for a notification is sent without it.
+type NotifySink struct {
+	db      *gorm.DB
+	channel string
+}
+
+func NewNotifySink(db *gorm.DB, channel string) *NotifySink {
+	return &NotifySink{
+		db:      db,
+		channel: channel,
+	}
+}
+
+func (*NotifySink) Name() string {
+	return "notify"
+}
+
+func (s *NotifySink) Publish(msg *Message) error {
+	payload, err := NotifyPayload(msg)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Exec("SELECT pg_notify(?, ?)", s.channel, string(payload)).Error
+}
+
+// NotifyPayload is the JSON of a message as it is sent in a notification.
+func NotifyPayload(msg *Message) ([]byte, error) {
+	payload, err := json.Marshal(msg)
+	if err != nil || len(payload) <= maxNotifyPayload {
+		return payload, err
+	}
+
+	withoutData := *msg
+	withoutData.Data = nil
+	return json.Marshal(&withoutData)
+}
@@ -0,0 +1,27 @@
+package outbox_test
+
+import (
+	"banking-system/outbox"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifyPayload_KeepsSmallData(t *testing.T) {
+	payload, err := outbox.NotifyPayload(&outbox.Message{ID: uuid.New(), Data: json.RawMessage(`{"uuid":"1"}`)})
+
+	assert.Nil(t, err)
+	assert.Contains(t, string(payload), `"data":{"uuid":"1"}`)
+}
+
+func TestNotifyPayload_DropsDataTooLargeForNotification(t *testing.T) {
+	large := json.RawMessage(`"` + strings.Repeat("x", 8000) + `"`)
+	payload, err := outbox.NotifyPayload(&outbox.Message{ID: uuid.New(), Data: large})
+
+	assert.Nil(t, err)
+	assert.Less(t, len(payload), 8000)
+	assert.Contains(t, string(payload), `"data":null`)
+}
@@ -0,0 +1,64 @@
+// Package outbox publishes the domain events that repositories write to the
+// outbox table together with the changes they describe.
+package outbox
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//go:generate mockgen -source=sink.go -destination=mock/sink.go
+
+// Message is an outbox event as it is handed to sinks. ID stays the same when
+// an event is published again, so consumers can use it to drop duplicates.
+// Sequence orders the events of a wallet.
+type Message struct {
+	ID        uuid.UUID          `json:"id"`
+	Sequence  uint               `json:"sequence"`
+	Type      entities.EventType `json:"type"`
+	UserID    uint               `json:"user_id"`
+	WalletID  uint               `json:"wallet_id"`
+	CreatedAt time.Time          `json:"created_at"`
+	Data      json.RawMessage    `json:"data"`
+}
+
+func NewMessage(event *entities.OutboxEvent) *Message {
+	return &Message{
+		ID:        event.UUID,
+		Sequence:  event.ID,
+		Type:      event.Type,
+		UserID:    event.UserID,
+		WalletID:  event.WalletID,
+		CreatedAt: event.CreatedAt,
+		Data:      json.RawMessage(event.Payload),
+	}
+}
+
+// Sink is somewhere events are published to. Publish returns an error if the
+// message may not have arrived, and the message is then published again.
+type Sink interface {
+	Name() string
+	Publish(msg *Message) error
+}
+
+// NewSinks returns the in-process bus, followed by an HTTP sink posting to
+// OUTBOX_HTTP_SINK_URL and a LISTEN/NOTIFY sink on the channel
+// OUTBOX_NOTIFY_CHANNEL, each if its variable is set.
+func NewSinks(bus *Bus) []Sink {
+	sinks := []Sink{bus}
+
+	if url := os.Getenv("OUTBOX_HTTP_SINK_URL"); url != "" {
+		sinks = append(sinks, NewHTTPSink(url, os.Getenv("OUTBOX_HTTP_SINK_SECRET")))
+	}
+
+	if channel := os.Getenv("OUTBOX_NOTIFY_CHANNEL"); channel != "" {
+		sinks = append(sinks, NewNotifySink(database.DB, channel))
+	}
+
+	return sinks
+}
@@ -19,8 +19,35 @@ func (*bankTransfer) PayIn(req *PayInRequest) (*PayInResponse, error) {
 	}, nil
 }
 
-func (*bankTransfer) PayOut() (*PayOutResponse, error) {
-	log.Debug("Simulate bank transfer withdrawal process...")
+func (*bankTransfer) GetPayInStatus(req *PayInStatusRequest) (*PayInStatusResponse, error) {
+	log.Debug("Simulate bank transfer deposit lookup...")
 
-	return &PayOutResponse{}, nil
+	return &PayInStatusResponse{
+		Status: PayInStatuses.Paid,
+	}, nil
+}
+
+func (*bankTransfer) PayOut(req *PayOutRequest) (*PayOutResponse, error) {
+	log.Debugf("Simulate bank transfer of %s %s to account %s at bank %s...", req.Amount, req.Amount.Currency(), req.Beneficiary.AccountNumber, req.Beneficiary.BankCode)
+
+	return &PayOutResponse{
+		Reference: "BT-" + req.TransactionID,
+	}, nil
+}
+
+func (*bankTransfer) GetPayOutStatus(req *PayOutStatusRequest) (*PayOutStatusResponse, error) {
+	log.Debug("Simulate bank transfer settlement...")
+
+	return &PayOutStatusResponse{
+		Reference: req.Reference,
+		Status:    PayOutStatuses.Paid,
+	}, nil
+}
+
+func (*bankTransfer) Refund(req *RefundRequest) (*RefundResponse, error) {
+	log.Debugf("Simulate bank transfer refund of %s %s for pay-in %s...", req.Amount, req.Amount.Currency(), req.PayInTransactionID)
+
+	return &RefundResponse{
+		Reference: "BTR-" + req.TransactionID,
+	}, nil
 }
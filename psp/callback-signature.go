@@ -0,0 +1,56 @@
+package psp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CallbackSecrets holds the shared secret each provider signs its callbacks
+// with.
+type CallbackSecrets map[PaymentMethod][]byte
+
+// LoadCallbackSecrets reads PSP_CALLBACK_SECRETS, a comma-separated list of
+// provider=secret pairs such as "FakePay=s3cr3t,BankTransfer=0th3r".
+func LoadCallbackSecrets() CallbackSecrets {
+	secrets := CallbackSecrets{}
+
+	value := os.Getenv("PSP_CALLBACK_SECRETS")
+	if value == "" {
+		return secrets
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		provider, secret, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || provider == "" || secret == "" {
+			log.Panicf("Invalid PSP_CALLBACK_SECRETS entry '%s': expected provider=secret", pair)
+		}
+		secrets[PaymentMethod(provider)] = []byte(secret)
+	}
+
+	return secrets
+}
+
+// SignCallback computes the hex HMAC-SHA256 a provider sends with a callback.
+// The timestamp and nonce are signed together with the raw body so that
+// neither can be replaced on a replayed request.
+func SignCallback(secret []byte, timestamp string, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write([]byte(nonce))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyCallbackSignature reports whether signature is the provider's
+// signature of the callback, comparing in constant time.
+func VerifyCallbackSignature(secret []byte, timestamp string, nonce string, body []byte, signature string) bool {
+	expected := SignCallback(secret, timestamp, nonce, body)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
@@ -19,14 +19,37 @@ func (*fakePay) PayIn(req *PayInRequest) (*PayInResponse, error) {
 
 	return &PayInResponse{
 		TransactionID: req.TransactionID,
-		RedirectUrl:   fmt.Sprintf("%s/payin/%s?merchant=MH&amount=%.2f&confirm_callback=%s&cancel_callback=%s", os.Getenv("FAKE_PAYMENT_PROVIDER_URL"), req.TransactionID, req.Amount, req.ConfirmCallbackURL, req.CancelCallbackURL),
+		RedirectUrl:   fmt.Sprintf("%s/payin/%s?merchant=MH&amount=%s&currency=%s&confirm_callback=%s&cancel_callback=%s", os.Getenv("FAKE_PAYMENT_PROVIDER_URL"), req.TransactionID, req.Amount, req.Amount.Currency(), req.ConfirmCallbackURL, req.CancelCallbackURL),
 	}, nil
 }
 
-func (*fakePay) PayOut() (*PayOutResponse, error) {
-	log.Debug("Simulate third party withdrawal process...")
+// GetPayInStatus reports pay-ins as unknown; the simulator keeps no record of
+// them and sends a confirm callback once the payer pays.
+func (*fakePay) GetPayInStatus(req *PayInStatusRequest) (*PayInStatusResponse, error) {
+	return nil, ErrPayInNotFound
+}
+
+func (*fakePay) PayOut(req *PayOutRequest) (*PayOutResponse, error) {
+	log.Debugf("Simulate third party withdrawal of %s %s to account %s at bank %s...", req.Amount, req.Amount.Currency(), req.Beneficiary.AccountNumber, req.Beneficiary.BankCode)
 
 	return &PayOutResponse{
-		TransactionID: "<transaction_id>",
+		Reference: "FP-" + req.TransactionID,
+	}, nil
+}
+
+// GetPayOutStatus reports payouts as processing; the simulator sends a status
+// callback once it settles them.
+func (*fakePay) GetPayOutStatus(req *PayOutStatusRequest) (*PayOutStatusResponse, error) {
+	return &PayOutStatusResponse{
+		Reference: req.Reference,
+		Status:    PayOutStatuses.Processing,
+	}, nil
+}
+
+func (*fakePay) Refund(req *RefundRequest) (*RefundResponse, error) {
+	log.Debugf("Simulate third party refund of %s %s for pay-in %s...", req.Amount, req.Amount.Currency(), req.PayInTransactionID)
+
+	return &RefundResponse{
+		Reference: "FPR-" + req.TransactionID,
 	}, nil
 }
@@ -18,10 +18,34 @@ func (m *MockPaymentServiceProviderTestify) PayIn(req *psp.PayInRequest) (*psp.P
 	return args.Get(0).(*psp.PayInResponse), args.Error(1)
 }
 
-func (m *MockPaymentServiceProviderTestify) PayOut() (*psp.PayOutResponse, error) {
-	args := m.Called()
+func (m *MockPaymentServiceProviderTestify) GetPayInStatus(req *psp.PayInStatusRequest) (*psp.PayInStatusResponse, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*psp.PayInStatusResponse), args.Error(1)
+}
+
+func (m *MockPaymentServiceProviderTestify) PayOut(req *psp.PayOutRequest) (*psp.PayOutResponse, error) {
+	args := m.Called(req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*psp.PayOutResponse), args.Error(1)
 }
+
+func (m *MockPaymentServiceProviderTestify) GetPayOutStatus(req *psp.PayOutStatusRequest) (*psp.PayOutStatusResponse, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*psp.PayOutStatusResponse), args.Error(1)
+}
+
+func (m *MockPaymentServiceProviderTestify) Refund(req *psp.RefundRequest) (*psp.RefundResponse, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*psp.RefundResponse), args.Error(1)
+}
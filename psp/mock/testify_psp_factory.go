@@ -10,10 +10,10 @@ type MockPSPFactoryTestify struct {
 	mock.Mock
 }
 
-func (m *MockPSPFactoryTestify) NewPaymentServiceProvider(paymentMethod psp.PaymentMethod) psp.PaymentServiceProvider {
+func (m *MockPSPFactoryTestify) NewPaymentServiceProvider(paymentMethod psp.PaymentMethod) (psp.PaymentServiceProvider, error) {
 	args := m.Called(paymentMethod)
 	if args.Get(0) == nil {
-		return nil
+		return nil, args.Error(1)
 	}
-	return args.Get(0).(psp.PaymentServiceProvider)
+	return args.Get(0).(psp.PaymentServiceProvider), args.Error(1)
 }
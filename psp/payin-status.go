@@ -0,0 +1,22 @@
+package psp
+
+import "errors"
+
+// PayInStatus is where a pay-in is at the provider. A pay-in is PENDING until
+// the payer pays it, and ends PAID or FAILED when the payer abandons or the
+// provider declines it.
+type PayInStatus string
+
+var PayInStatuses = &struct {
+	Pending PayInStatus
+	Paid    PayInStatus
+	Failed  PayInStatus
+}{
+	Pending: "PENDING",
+	Paid:    "PAID",
+	Failed:  "FAILED",
+}
+
+// ErrPayInNotFound is returned by GetPayInStatus when the provider has no
+// record of the pay-in, i.e. the payer never started paying it.
+var ErrPayInNotFound = errors.New("pay-in not found")
@@ -4,5 +4,8 @@ package psp
 
 type PaymentServiceProvider interface {
 	PayIn(req *PayInRequest) (*PayInResponse, error)
-	PayOut() (*PayOutResponse, error)
+	GetPayInStatus(req *PayInStatusRequest) (*PayInStatusResponse, error)
+	PayOut(req *PayOutRequest) (*PayOutResponse, error)
+	GetPayOutStatus(req *PayOutStatusRequest) (*PayOutStatusResponse, error)
+	Refund(req *RefundRequest) (*RefundResponse, error)
 }
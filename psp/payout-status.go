@@ -0,0 +1,71 @@
+package psp
+
+import "errors"
+
+// PayOutStatus is where a payout is in the provider's lifecycle. A payout is
+// SUBMITTED when the provider accepts it, PROCESSING while the bank handles it,
+// and ends PAID or REJECTED. A PAID payout can still be RETURNED later by the
+// beneficiary's bank.
+type PayOutStatus string
+
+var PayOutStatuses = &struct {
+	Submitted  PayOutStatus
+	Processing PayOutStatus
+	Paid       PayOutStatus
+	Rejected   PayOutStatus
+	Returned   PayOutStatus
+}{
+	Submitted:  "SUBMITTED",
+	Processing: "PROCESSING",
+	Paid:       "PAID",
+	Rejected:   "REJECTED",
+	Returned:   "RETURNED",
+}
+
+// ErrPayOutNotFound is returned by GetPayOutStatus when the provider has no
+// record of the payout, i.e. it was never accepted.
+var ErrPayOutNotFound = errors.New("payout not found")
+
+var payOutTransitions = map[PayOutStatus][]PayOutStatus{
+	PayOutStatuses.Submitted:  {PayOutStatuses.Processing, PayOutStatuses.Paid, PayOutStatuses.Rejected},
+	PayOutStatuses.Processing: {PayOutStatuses.Paid, PayOutStatuses.Rejected},
+	PayOutStatuses.Paid:       {PayOutStatuses.Returned},
+}
+
+var payOutStages = map[PayOutStatus]int{
+	PayOutStatuses.Submitted:  0,
+	PayOutStatuses.Processing: 1,
+	PayOutStatuses.Paid:       2,
+	PayOutStatuses.Rejected:   2,
+	PayOutStatuses.Returned:   3,
+}
+
+func (s PayOutStatus) IsValid() bool {
+	switch s {
+	case PayOutStatuses.Submitted, PayOutStatuses.Processing, PayOutStatuses.Paid, PayOutStatuses.Rejected, PayOutStatuses.Returned:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether a payout in status s may move to next.
+func (s PayOutStatus) CanTransitionTo(next PayOutStatus) bool {
+	for _, allowed := range payOutTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Precedes reports whether s comes earlier in the lifecycle than other, so
+// that a report of s arriving after other is stale.
+func (s PayOutStatus) Precedes(other PayOutStatus) bool {
+	return payOutStages[s] < payOutStages[other]
+}
+
+// IsFinal reports whether the provider will not report the payout again
+// unless it is returned.
+func (s PayOutStatus) IsFinal() bool {
+	return s != PayOutStatuses.Submitted && s != PayOutStatuses.Processing
+}
@@ -1,11 +1,14 @@
 package psp
 
-import "log"
+import (
+	"errors"
+	"fmt"
+)
 
 //go:generate mockgen -source=psp-factory.go -destination=mock/psp-factory.go
 
 type PSPFactory interface {
-	NewPaymentServiceProvider(paymentMethod PaymentMethod) PaymentServiceProvider
+	NewPaymentServiceProvider(paymentMethod PaymentMethod) (PaymentServiceProvider, error)
 }
 
 type pspFactory struct {
@@ -17,6 +20,10 @@ func NewPSPFactory() PSPFactory {
 
 type PaymentMethod string
 
+// ErrUnsupportedPaymentMethod is returned by NewPaymentServiceProvider for a
+// payment method no provider handles.
+var ErrUnsupportedPaymentMethod = errors.New("payment method is not supported")
+
 var PaymentMethods = struct {
 	FakePay      PaymentMethod
 	BankTransfer PaymentMethod
@@ -33,15 +40,14 @@ var PaymentMethods = struct {
 	// PayPal:     "paypal",
 }
 
-func (f *pspFactory) NewPaymentServiceProvider(paymentMethod PaymentMethod) PaymentServiceProvider {
+func (f *pspFactory) NewPaymentServiceProvider(paymentMethod PaymentMethod) (PaymentServiceProvider, error) {
 	switch paymentMethod {
 	case PaymentMethods.FakePay:
-		return NewFakePay()
+		return NewFakePay(), nil
 	case PaymentMethods.BankTransfer:
-		return NewBankTransferPSP()
+		return NewBankTransferPSP(), nil
 
 	default:
-		log.Panicf("payment method '%s' is not supported", paymentMethod)
-		return nil
+		return nil, fmt.Errorf("%w: '%s'", ErrUnsupportedPaymentMethod, paymentMethod)
 	}
 }
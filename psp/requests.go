@@ -1,21 +1,70 @@
 package psp
 
+import "banking-system/money"
+
 type PayInRequest struct {
 	TransactionID      string
-	Amount             float64
+	Amount             money.Money
 	ConfirmCallbackURL string
 	CancelCallbackURL  string
 }
 
+// PayInStatusRequest looks a pay-in up by our transaction ID.
+type PayInStatusRequest struct {
+	TransactionID string
+}
+
+// PayOutRequest sends Amount, in its own currency, to the beneficiary bank
+// account.
 type PayOutRequest struct {
+	TransactionID     string
+	Amount            money.Money
+	Beneficiary       BankAccount
+	StatusCallbackURL string
+}
+
+// PayOutStatusRequest looks a payout up by the provider's reference or, if
+// the provider never returned one, by our transaction ID.
+type PayOutStatusRequest struct {
 	TransactionID string
-	Amount        float64
+	Reference     string
+}
+
+// RefundRequest returns Amount of the pay-in PayInTransactionID to the payer,
+// who may be refunded in several parts.
+type RefundRequest struct {
+	TransactionID      string
+	PayInTransactionID string
+	Amount             money.Money
+}
+
+type BankAccount struct {
+	BankCode      string
+	AccountNumber string
 }
 
 type ConfirmRequest struct {
 	TransactionID string `json:"transaction_id" binding:"required"`
+
+	// Provider is the provider that signed the callback
+	Provider PaymentMethod `json:"-"`
 }
 
 type CancelRequest struct {
 	TransactionID string `json:"transaction_id" binding:"required"`
+
+	// Provider is the provider that signed the callback
+	Provider PaymentMethod `json:"-"`
+}
+
+// PayOutStatusCallback is sent by the provider whenever a payout changes
+// status.
+type PayOutStatusCallback struct {
+	TransactionID string       `json:"transaction_id" binding:"required"`
+	Reference     string       `json:"reference"`
+	Status        PayOutStatus `json:"status" binding:"required"`
+	Reason        string       `json:"reason"`
+
+	// Provider is the provider that signed the callback
+	Provider PaymentMethod `json:"-"`
 }
@@ -5,6 +5,21 @@ type PayInResponse struct {
 	RedirectUrl   string
 }
 
+type PayInStatusResponse struct {
+	Status PayInStatus
+}
+
 type PayOutResponse struct {
-	TransactionID string
+	// Reference is the provider's own ID for the payout
+	Reference string
+}
+
+type RefundResponse struct {
+	// Reference is the provider's own ID for the refund
+	Reference string
+}
+
+type PayOutStatusResponse struct {
+	Reference string
+	Status    PayOutStatus
 }
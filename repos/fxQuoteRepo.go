@@ -0,0 +1,79 @@
+package repos
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+//go:generate mockgen -source=fxQuoteRepo.go -destination=mock/fxQuoteRepo.go
+
+type FXQuoteRepo interface {
+	Create(quote *entities.FXQuote) error
+	GetByUUID(uuid.UUID) (*entities.FXQuote, error)
+	Execute(quote *entities.FXQuote, conversionOutTx *entities.Transaction, conversionInTx *entities.Transaction, entries []*entities.JournalEntry) (bool, error)
+}
+
+type fxQuoteRepo struct {
+}
+
+func NewFXQuoteRepo() FXQuoteRepo {
+	return &fxQuoteRepo{}
+}
+
+func (*fxQuoteRepo) Create(quote *entities.FXQuote) error {
+	return database.DB.Create(quote).Error
+}
+
+func (*fxQuoteRepo) GetByUUID(uuid uuid.UUID) (*entities.FXQuote, error) {
+	var quote entities.FXQuote
+	result := database.DB.First(&quote, uuid)
+	return &quote, result.Error
+}
+
+// Execute consumes an open, unexpired quote and books both legs of the
+// conversion in one database transaction. It returns false if the quote was
+// already used or has expired.
+func (*fxQuoteRepo) Execute(quote *entities.FXQuote, conversionOutTx *entities.Transaction, conversionInTx *entities.Transaction, entries []*entities.JournalEntry) (bool, error) {
+	var executed bool
+	err := inTransaction(func(db *gorm.DB) error {
+		executed = false
+		result := db.Model(&entities.FXQuote{}).
+			Where("uuid = ? AND status = ? AND expires_at > ?", quote.UUID, entities.FXQuoteStatuses.Open, time.Now()).
+			Updates(map[string]interface{}{
+				"status":     entities.FXQuoteStatuses.Executed,
+				"updated_at": db.NowFunc(),
+			})
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := createLinkedTransactions(db, conversionOutTx, conversionInTx); err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if err := postJournalEntry(db, entry); err != nil {
+				return err
+			}
+		}
+
+		if err := writeOutbox(db, entries, conversionOutTx, conversionInTx); err != nil {
+			return err
+		}
+
+		quote.Status = entities.FXQuoteStatuses.Executed
+		executed = true
+		return nil
+	})
+
+	return executed, err
+}
@@ -0,0 +1,82 @@
+package repos
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:generate mockgen -source=idempotencyRepo.go -destination=mock/idempotencyRepo.go
+
+type IdempotencyRepo interface {
+	Reserve(key *entities.IdempotencyKey) (existing *entities.IdempotencyKey, err error)
+	Complete(key *entities.IdempotencyKey) error
+	Release(key *entities.IdempotencyKey) error
+}
+
+type idempotencyRepo struct {
+}
+
+func NewIdempotencyRepo() IdempotencyRepo {
+	return &idempotencyRepo{}
+}
+
+// Reserve claims the key for a new request until key.ExpiresAt. If the key is
+// already held by an unexpired record, that record is returned instead and
+// nothing is written.
+func (*idempotencyRepo) Reserve(key *entities.IdempotencyKey) (*entities.IdempotencyKey, error) {
+	for {
+		// An expired key is free to be reused
+		if err := database.DB.
+			Where("key = ? AND owner = ? AND expires_at <= ?", key.Key, key.Owner, time.Now()).
+			Delete(&entities.IdempotencyKey{}).Error; err != nil {
+			return nil, err
+		}
+
+		result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+
+		if result.RowsAffected == 1 {
+			return nil, nil
+		}
+
+		var existing entities.IdempotencyKey
+		err := database.DB.Where("key = ? AND owner = ?", key.Key, key.Owner).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Released by its request in the meantime; try to claim it again
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return &existing, nil
+	}
+}
+
+// Complete records the response of the request holding the key, to be
+// replayed until key.ExpiresAt.
+func (*idempotencyRepo) Complete(key *entities.IdempotencyKey) error {
+	return database.DB.Model(&entities.IdempotencyKey{}).
+		Where("key = ? AND owner = ?", key.Key, key.Owner).
+		Updates(map[string]interface{}{
+			"status":                entities.IdempotencyKeyStatuses.Completed,
+			"response_status":       key.ResponseStatus,
+			"response_content_type": key.ResponseContentType,
+			"response_body":         key.ResponseBody,
+			"expires_at":            key.ExpiresAt,
+		}).Error
+}
+
+// Release forgets a key whose request did not finish, so the client can retry.
+func (*idempotencyRepo) Release(key *entities.IdempotencyKey) error {
+	return database.DB.
+		Where("key = ? AND owner = ? AND status = ?", key.Key, key.Owner, entities.IdempotencyKeyStatuses.InProgress).
+		Delete(&entities.IdempotencyKey{}).Error
+}
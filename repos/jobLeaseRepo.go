@@ -0,0 +1,34 @@
+package repos
+
+import (
+	"banking-system/database"
+	"time"
+)
+
+//go:generate mockgen -source=jobLeaseRepo.go -destination=mock/jobLeaseRepo.go
+
+type JobLeaseRepo interface {
+	Acquire(name string, holder string, ttl time.Duration) (bool, error)
+}
+
+type jobLeaseRepo struct {
+}
+
+func NewJobLeaseRepo() JobLeaseRepo {
+	return &jobLeaseRepo{}
+}
+
+// Acquire takes or renews the lease on a job for ttl. It returns false if
+// another holder has an unexpired lease. Expiry is measured with the
+// database clock so that the clocks of the replicas do not have to agree.
+func (*jobLeaseRepo) Acquire(name string, holder string, ttl time.Duration) (bool, error) {
+	result := database.DB.Exec(`
+		INSERT INTO job_leases (name, holder, expires_at)
+		VALUES (?, ?, NOW() + ? * INTERVAL '1 millisecond')
+		ON CONFLICT (name) DO UPDATE
+		SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		WHERE job_leases.holder = EXCLUDED.holder OR job_leases.expires_at < NOW()`,
+		name, holder, ttl.Milliseconds())
+
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,285 @@
+package repos
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"banking-system/money"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:generate mockgen -source=ledgerRepo.go -destination=mock/ledgerRepo.go
+
+type LedgerRepo interface {
+	Post(entry *entities.JournalEntry) error
+	GetAccounts() ([]entities.LedgerAccount, error)
+	GetPostingSums() (map[uint]money.Money, error)
+	GetWallets() ([]entities.Wallet, error)
+	GetEntriesByAccountCode(code string) ([]entities.JournalEntry, error)
+}
+
+// ErrInsufficientFunds is returned when posting an entry would overdraw a wallet.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+type ledgerRepo struct {
+}
+
+func NewLedgerRepo() LedgerRepo {
+	return &ledgerRepo{}
+}
+
+func (*ledgerRepo) Post(entry *entities.JournalEntry) error {
+	return inTransaction(func(db *gorm.DB) error {
+		if err := postJournalEntry(db, entry); err != nil {
+			return err
+		}
+
+		return writeOutbox(db, []*entities.JournalEntry{entry})
+	})
+}
+
+func (*ledgerRepo) GetAccounts() ([]entities.LedgerAccount, error) {
+	var accounts []entities.LedgerAccount
+	result := database.DB.Order("id").Find(&accounts)
+	return accounts, result.Error
+}
+
+func (*ledgerRepo) GetPostingSums() (map[uint]money.Money, error) {
+	var rows []struct {
+		LedgerAccountID uint
+		Currency        string
+		Sum             money.Money
+	}
+
+	result := database.DB.Model(&entities.Posting{}).
+		Select("ledger_account_id, currency, SUM(amount) AS sum").
+		Group("ledger_account_id, currency").
+		Scan(&rows)
+
+	sums := make(map[uint]money.Money, len(rows))
+	for _, row := range rows {
+		sums[row.LedgerAccountID] = row.Sum.WithCurrency(row.Currency)
+	}
+
+	return sums, result.Error
+}
+
+func (*ledgerRepo) GetWallets() ([]entities.Wallet, error) {
+	var wallets []entities.Wallet
+	result := database.DB.Order("id").Find(&wallets)
+	return wallets, result.Error
+}
+
+func (*ledgerRepo) GetEntriesByAccountCode(code string) ([]entities.JournalEntry, error) {
+	var entries []entities.JournalEntry
+
+	result := database.DB.
+		Preload("Postings.LedgerAccount").
+		Where("uuid IN (?)", database.DB.Model(&entities.Posting{}).
+			Select("postings.journal_entry_id").
+			Joins("JOIN ledger_accounts ON ledger_accounts.id = postings.ledger_account_id").
+			Where("ledger_accounts.code = ?", code)).
+		Order("created_at").
+		Find(&entries)
+
+	return entries, result.Error
+}
+
+// postJournalEntry writes a balanced journal entry inside the caller's database
+// transaction. Ledger accounts are created on first use, and wallet balances
+// are kept as a projection of their ledger account. Affected wallets are
+// locked first so that no wallet can be overdrawn by concurrent entries.
+func postJournalEntry(db *gorm.DB, entry *entities.JournalEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	if err := bindHouseFeeWallets(db, entry); err != nil {
+		return err
+	}
+
+	if err := lockWalletsForEntry(db, entry); err != nil {
+		return err
+	}
+
+	if err := db.Omit("Postings").Create(entry).Error; err != nil {
+		return err
+	}
+
+	for i := range entry.Postings {
+		posting := &entry.Postings[i]
+
+		account, err := resolveLedgerAccount(db, posting.LedgerAccount)
+		if err != nil {
+			return err
+		}
+
+		posting.ID = 0 // the transaction may be retried
+		posting.JournalEntryID = entry.UUID
+		posting.LedgerAccountID = account.ID
+		posting.LedgerAccount = account
+		if err := db.Omit("LedgerAccount").Create(posting).Error; err != nil {
+			return err
+		}
+
+		if err := db.Model(account).
+			Update("balance", gorm.Expr("balance + ?", posting.Amount)).Error; err != nil {
+			return err
+		}
+
+		if account.WalletID != nil {
+			if err := db.Model(&entities.Wallet{}).
+				Where("id = ?", *account.WalletID).
+				Update("balance", gorm.Expr("balance + ?", posting.Amount)).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// bindHouseFeeWallets points every fees posting of the entry at the house fee
+// wallet for its currency, so that collected fees are held in a real wallet.
+func bindHouseFeeWallets(db *gorm.DB, entry *entities.JournalEntry) error {
+	for i := range entry.Postings {
+		posting := &entry.Postings[i]
+		if posting.LedgerAccount.Type != entities.LedgerAccountTypes.Fees || posting.LedgerAccount.WalletID != nil {
+			continue
+		}
+
+		account, err := resolveHouseFeeAccount(db, posting.LedgerAccount.Currency)
+		if err != nil {
+			return err
+		}
+		posting.LedgerAccount = account
+	}
+
+	return nil
+}
+
+// resolveHouseFeeAccount returns the fees account for a currency bound to the
+// house fee wallet, creating the house user, the wallet and the account on
+// first use. A fees account that collected fees before it had a wallet is
+// bound to a new wallet holding its balance. It fails rather than collect fees
+// into the wallet of an ordinary user who holds the house username.
+func resolveHouseFeeAccount(db *gorm.DB, currency string) (*entities.LedgerAccount, error) {
+	house := entities.User{Username: entities.HouseUsername, IsHouse: true}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Omit("Wallets", "BankAccounts").Create(&house).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("is_house = ?", true).First(&house).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("no house user to collect fees: username '%s' belongs to an ordinary user", entities.HouseUsername)
+		}
+		return nil, err
+	}
+
+	wallet := entities.Wallet{UserID: house.ID, Currency: currency, Balance: money.Zero(currency)}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "currency"}},
+		DoNothing: true,
+	}).Create(&wallet).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ? AND currency = ?", house.ID, currency).First(&wallet).Error; err != nil {
+		return nil, err
+	}
+
+	template := entities.FeesAccount(currency)
+	template.WalletID = &wallet.ID
+	account, err := resolveLedgerAccount(db, template)
+	if err != nil {
+		return nil, err
+	}
+
+	if account.WalletID == nil {
+		bound := db.Model(account).Where("wallet_id IS NULL").Update("wallet_id", wallet.ID)
+		if bound.Error != nil {
+			return nil, bound.Error
+		}
+		if bound.RowsAffected == 1 {
+			if err := db.Model(&wallet).Update("balance", account.Balance).Error; err != nil {
+				return nil, err
+			}
+		}
+		account.WalletID = &wallet.ID
+	}
+
+	return account, nil
+}
+
+// lockWalletsForEntry takes row locks on every wallet the entry touches, in
+// ascending ID order to avoid deadlocks, and rejects the entry if it would
+// leave any wallet with a negative balance. House fee wallets are not locked,
+// so that fee postings are not serialized on one wallet per currency: they are
+// only credited with fees, or debited by the refund of a fee they collected.
+func lockWalletsForEntry(db *gorm.DB, entry *entities.JournalEntry) error {
+	changes := make(map[uint]money.Money)
+	for _, posting := range entry.Postings {
+		if posting.LedgerAccount.WalletID == nil || posting.LedgerAccount.Type == entities.LedgerAccountTypes.Fees {
+			continue
+		}
+
+		walletID := *posting.LedgerAccount.WalletID
+		change, ok := changes[walletID]
+		if !ok {
+			change = money.Zero(posting.Amount.Currency())
+		}
+
+		var err error
+		if changes[walletID], err = change.Add(posting.Amount); err != nil {
+			return err
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	walletIDs := make([]uint, 0, len(changes))
+	for walletID := range changes {
+		walletIDs = append(walletIDs, walletID)
+	}
+
+	var wallets []entities.Wallet
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", walletIDs).
+		Order("id").
+		Find(&wallets).Error; err != nil {
+		return err
+	}
+
+	for _, wallet := range wallets {
+		after, err := wallet.Balance.Add(changes[wallet.ID])
+		if err != nil {
+			return err
+		}
+
+		if after.IsNegative() {
+			return fmt.Errorf("%w: wallet %d has %s, needs %s", ErrInsufficientFunds, wallet.ID, wallet.Balance, changes[wallet.ID].Neg())
+		}
+	}
+
+	return nil
+}
+
+func resolveLedgerAccount(db *gorm.DB, template *entities.LedgerAccount) (*entities.LedgerAccount, error) {
+	account := *template
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoNothing: true,
+	}).Create(&account).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Where("code = ?", template.Code).First(&account).Error; err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
@@ -0,0 +1,108 @@
+package repos
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:generate mockgen -source=limitRepo.go -destination=mock/limitRepo.go
+
+type LimitRepo interface {
+	GetOverride(userID uint, txType entities.TransactionType, currency string) (*entities.LimitOverride, error)
+	SaveOverride(override *entities.LimitOverride) error
+	DeleteOverride(userID uint, txType entities.TransactionType, currency string) error
+	GetUsage(userID uint, wallet *entities.Wallet, txType entities.TransactionType, periods entities.LimitPeriods) (entities.LimitUsage, error)
+}
+
+type limitRepo struct {
+}
+
+func NewLimitRepo() LimitRepo {
+	return &limitRepo{}
+}
+
+// GetOverride returns the user's override of the limits of txType in
+// currency, or nil if there is none.
+func (*limitRepo) GetOverride(userID uint, txType entities.TransactionType, currency string) (*entities.LimitOverride, error) {
+	var override entities.LimitOverride
+	err := database.DB.Where("user_id = ? AND type = ? AND currency = ?", userID, txType, currency).First(&override).Error
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	return &override, err
+}
+
+// SaveOverride creates the override or replaces the existing one for the same
+// user, type and currency.
+func (*limitRepo) SaveOverride(override *entities.LimitOverride) error {
+	return database.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "type"}, {Name: "currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"per_transaction_min", "per_transaction_max", "daily", "monthly",
+			"daily_count", "monthly_count", "updated_by", "reason", "updated_at",
+		}),
+	}).Create(override).Error
+}
+
+func (*limitRepo) DeleteOverride(userID uint, txType entities.TransactionType, currency string) error {
+	return database.DB.Unscoped().
+		Where("user_id = ? AND type = ? AND currency = ?", userID, txType, currency).
+		Delete(&entities.LimitOverride{}).Error
+}
+
+func (*limitRepo) GetUsage(userID uint, wallet *entities.Wallet, txType entities.TransactionType, periods entities.LimitPeriods) (entities.LimitUsage, error) {
+	return getLimitUsage(database.DB, userID, wallet.ID, wallet.Currency, txType, periods)
+}
+
+// getLimitUsage sums the pending and completed transactions of txType that
+// count against the user's limits: their amounts on the wallet and their
+// number on all of the user's wallets.
+func getLimitUsage(db *gorm.DB, userID uint, walletID uint, currency string, txType entities.TransactionType, periods entities.LimitPeriods) (entities.LimitUsage, error) {
+	var usage entities.LimitUsage
+
+	err := db.Model(&entities.Transaction{}).
+		Select(`COALESCE(SUM(CASE WHEN transactions.wallet_id = ? AND transactions.created_at >= ? THEN transactions.amount END), 0) AS daily,
+			COALESCE(SUM(CASE WHEN transactions.wallet_id = ? THEN transactions.amount END), 0) AS monthly,
+			COUNT(CASE WHEN transactions.created_at >= ? THEN 1 END) AS daily_count,
+			COUNT(*) AS monthly_count`,
+			walletID, periods.DayStart, walletID, periods.DayStart).
+		Joins("JOIN wallets ON wallets.id = transactions.wallet_id").
+		Where("wallets.user_id = ? AND transactions.type = ? AND transactions.status IN ? AND transactions.created_at >= ?",
+			userID, txType,
+			[]entities.TransactionStatus{entities.TransactionStatuses.Pending, entities.TransactionStatuses.Completed},
+			periods.MonthStart).
+		Scan(&usage).Error
+	if err != nil {
+		return entities.LimitUsage{}, err
+	}
+
+	usage.Daily = usage.Daily.WithCurrency(currency)
+	usage.Monthly = usage.Monthly.WithCurrency(currency)
+	return usage, nil
+}
+
+// checkLimits checks a transaction against its periodic limits. The user's
+// row is locked first, so that the user's transactions are checked one at a
+// time and each sees those created before it.
+func checkLimits(db *gorm.DB, tx *entities.Transaction) error {
+	check := tx.LimitCheck
+	if check == nil || !check.Limits.HasPeriodicLimits() {
+		return nil
+	}
+
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		First(&entities.User{}, check.UserID).Error; err != nil {
+		return err
+	}
+
+	usage, err := getLimitUsage(db, check.UserID, tx.WalletID, tx.Amount.Currency(), tx.Type, check.Periods)
+	if err != nil {
+		return err
+	}
+
+	return check.Limits.CheckUsage(tx.Amount, usage)
+}
@@ -0,0 +1,202 @@
+package repos
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:generate mockgen -source=outboxRepo.go -destination=mock/outboxRepo.go
+
+type OutboxRepo interface {
+	GetUnpublished(limit int) ([]entities.OutboxEvent, error)
+	GetByID(id uint) (*entities.OutboxEvent, error)
+	GetByUserAfter(userID uint, after map[uint]uint, limit int) ([]entities.OutboxEvent, error)
+	MarkPublished(ids []uint, at time.Time) error
+	RecordFailure(id uint, reason string) error
+}
+
+type outboxRepo struct {
+}
+
+func NewOutboxRepo() OutboxRepo {
+	return &outboxRepo{}
+}
+
+// GetUnpublished returns the events that have not been published yet, in the
+// order they were written.
+func (*outboxRepo) GetUnpublished(limit int) ([]entities.OutboxEvent, error) {
+	var events []entities.OutboxEvent
+	err := database.DB.
+		Where("published_at IS NULL").
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (*outboxRepo) GetByID(id uint) (*entities.OutboxEvent, error) {
+	var event entities.OutboxEvent
+	err := database.DB.First(&event, id).Error
+	return &event, err
+}
+
+// GetByUserAfter returns the events of a user's wallets that come after the
+// sequence of their wallet in after, in the order they were written. The
+// wallets missing from after start from the lowest sequence in it.
+func (*outboxRepo) GetByUserAfter(userID uint, after map[uint]uint, limit int) ([]entities.OutboxEvent, error) {
+	var lowest uint
+	walletIDs := make([]uint, 0, len(after))
+	positions := database.DB.Where("1 = 0")
+	for walletID, sequence := range after {
+		if len(walletIDs) == 0 || sequence < lowest {
+			lowest = sequence
+		}
+		walletIDs = append(walletIDs, walletID)
+		positions = positions.Or("wallet_id = ? AND id > ?", walletID, sequence)
+	}
+
+	if len(walletIDs) == 0 {
+		positions = positions.Or("id > ?", lowest)
+	} else {
+		positions = positions.Or("wallet_id NOT IN ? AND id > ?", walletIDs, lowest)
+	}
+
+	var events []entities.OutboxEvent
+	err := database.DB.
+		Where("user_id = ?", userID).
+		Where(positions).
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (*outboxRepo) MarkPublished(ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return database.DB.Model(&entities.OutboxEvent{}).
+		Where("id IN ? AND published_at IS NULL", ids).
+		Update("published_at", at).Error
+}
+
+// RecordFailure counts a failed attempt to publish an event, which stays
+// unpublished.
+func (*outboxRepo) RecordFailure(id uint, reason string) error {
+	return database.DB.Model(&entities.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":   gorm.Expr("attempts + 1"),
+			"last_error": reason,
+		}).Error
+}
+
+// writeOutbox writes the events of transactions that were just created or
+// changed, and a wallet.balance_changed event for every wallet the entries
+// posted to, as part of the database transaction that made the change.
+func writeOutbox(db *gorm.DB, entries []*entities.JournalEntry, transactions ...*entities.Transaction) error {
+	var events []*entities.OutboxEvent
+	for _, tx := range transactions {
+		events = append(events, tx.Events()...)
+	}
+
+	posted := make(map[uint]*uuid.UUID)
+	for _, entry := range entries {
+		for _, posting := range entry.Postings {
+			if posting.LedgerAccount == nil || posting.LedgerAccount.WalletID == nil {
+				continue
+			}
+			walletID := *posting.LedgerAccount.WalletID
+			if _, ok := posted[walletID]; !ok {
+				posted[walletID] = postedBy(walletID, entry, transactions)
+			}
+		}
+	}
+
+	walletIDs := eventWalletIDs(events)
+	for walletID := range posted {
+		if !slices.Contains(walletIDs, walletID) {
+			walletIDs = append(walletIDs, walletID)
+		}
+	}
+
+	wallets, err := lockEventWallets(db, walletIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range wallets {
+		wallet := &wallets[i]
+		for _, event := range events {
+			if event.WalletID == wallet.ID {
+				event.UserID = wallet.UserID
+			}
+		}
+		if transactionID, ok := posted[wallet.ID]; ok {
+			events = append(events, entities.NewBalanceChangedEvent(wallet, transactionID))
+		}
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+	return db.Create(events).Error
+}
+
+// writeEvents writes events whose user and wallet are already set, as part of
+// the database transaction that made the change they describe.
+func writeEvents(db *gorm.DB, events []*entities.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	if _, err := lockEventWallets(db, eventWalletIDs(events)); err != nil {
+		return err
+	}
+	return db.Create(events).Error
+}
+
+func eventWalletIDs(events []*entities.OutboxEvent) []uint {
+	var walletIDs []uint
+	for _, event := range events {
+		if !slices.Contains(walletIDs, event.WalletID) {
+			walletIDs = append(walletIDs, event.WalletID)
+		}
+	}
+	return walletIDs
+}
+
+// lockEventWallets locks the wallets events are written for, in ascending ID
+// order like lockWalletsForEntry, so that the events of a wallet get IDs in
+// the order in which its changes commit.
+func lockEventWallets(db *gorm.DB, walletIDs []uint) ([]entities.Wallet, error) {
+	if len(walletIDs) == 0 {
+		return nil, nil
+	}
+
+	var wallets []entities.Wallet
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", walletIDs).
+		Order("id").
+		Find(&wallets).Error
+	return wallets, err
+}
+
+// postedBy returns the transaction that moved the balance of a wallet through
+// entry: the transaction on that wallet if one is given, else the one the
+// entry belongs to, if any.
+func postedBy(walletID uint, entry *entities.JournalEntry, transactions []*entities.Transaction) *uuid.UUID {
+	for _, tx := range transactions {
+		if tx.WalletID == walletID {
+			return &tx.UUID
+		}
+	}
+	return entry.TransactionID
+}
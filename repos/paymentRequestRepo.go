@@ -0,0 +1,117 @@
+package repos
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+//go:generate mockgen -source=paymentRequestRepo.go -destination=mock/paymentRequestRepo.go
+
+// ErrPaymentRequestClosed is returned when a transfer pays a payment request
+// that was accepted, declined or expired first.
+var ErrPaymentRequestClosed = errors.New("payment request is no longer pending")
+
+type PaymentRequestRepo interface {
+	Create(request *entities.PaymentRequest) error
+	GetByUUID(id uuid.UUID) (*entities.PaymentRequest, error)
+	GetByRequesterID(userID uint, status entities.PaymentRequestStatus, limit int) ([]entities.PaymentRequest, error)
+	GetByPayerID(userID uint, status entities.PaymentRequestStatus, limit int) ([]entities.PaymentRequest, error)
+	Decline(request *entities.PaymentRequest, at time.Time) (bool, error)
+	ExpireDue(now time.Time) (int64, error)
+}
+
+type paymentRequestRepo struct {
+}
+
+func NewPaymentRequestRepo() PaymentRequestRepo {
+	return &paymentRequestRepo{}
+}
+
+func (*paymentRequestRepo) Create(request *entities.PaymentRequest) error {
+	return database.DB.Create(request).Error
+}
+
+func (*paymentRequestRepo) GetByUUID(id uuid.UUID) (*entities.PaymentRequest, error) {
+	var request entities.PaymentRequest
+	err := database.DB.First(&request, id).Error
+	return &request, err
+}
+
+// GetByRequesterID returns the latest requests a user sent, the most recent
+// first. An empty status matches every request.
+func (*paymentRequestRepo) GetByRequesterID(userID uint, status entities.PaymentRequestStatus, limit int) ([]entities.PaymentRequest, error) {
+	return getPaymentRequests(database.DB.Where("requester_id = ?", userID), status, limit)
+}
+
+// GetByPayerID returns the latest requests a user received, the most recent
+// first. An empty status matches every request.
+func (*paymentRequestRepo) GetByPayerID(userID uint, status entities.PaymentRequestStatus, limit int) ([]entities.PaymentRequest, error) {
+	return getPaymentRequests(database.DB.Where("payer_id = ?", userID), status, limit)
+}
+
+func getPaymentRequests(db *gorm.DB, status entities.PaymentRequestStatus, limit int) ([]entities.PaymentRequest, error) {
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+
+	var requests []entities.PaymentRequest
+	err := db.Order("created_at DESC").Limit(limit).Find(&requests).Error
+	return requests, err
+}
+
+// Decline closes a request that is still open at at. It returns false if the
+// request was accepted, declined or expired first.
+func (*paymentRequestRepo) Decline(request *entities.PaymentRequest, at time.Time) (bool, error) {
+	result := database.DB.Model(&entities.PaymentRequest{}).
+		Where("uuid = ? AND status = ? AND expires_at > ?", request.UUID, entities.PaymentRequestStatuses.Pending, at).
+		Updates(map[string]interface{}{
+			"status":     entities.PaymentRequestStatuses.Declined,
+			"closed_at":  at,
+			"updated_at": at,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ExpireDue closes the pending requests whose expiry has passed, and returns
+// how many it closed.
+func (*paymentRequestRepo) ExpireDue(now time.Time) (int64, error) {
+	result := database.DB.Model(&entities.PaymentRequest{}).
+		Where("status = ? AND expires_at <= ?", entities.PaymentRequestStatuses.Pending, now).
+		Updates(map[string]interface{}{
+			"status":     entities.PaymentRequestStatuses.Expired,
+			"closed_at":  now,
+			"updated_at": now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// payPaymentRequest accepts the payment request a transfer pays, if any, as
+// part of the database transaction that creates the transfer, so that the
+// request is accepted if and only if the transfer commits.
+func payPaymentRequest(db *gorm.DB, transferOutTx *entities.Transaction) error {
+	if transferOutTx.PaymentRequestID == nil {
+		return nil
+	}
+
+	now := db.NowFunc()
+	result := db.Model(&entities.PaymentRequest{}).
+		Where("uuid = ? AND status = ? AND expires_at > ?", *transferOutTx.PaymentRequestID, entities.PaymentRequestStatuses.Pending, now).
+		Updates(map[string]interface{}{
+			"status":         entities.PaymentRequestStatuses.Accepted,
+			"transaction_id": transferOutTx.UUID,
+			"closed_at":      now,
+			"updated_at":     now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPaymentRequestClosed
+	}
+	return nil
+}
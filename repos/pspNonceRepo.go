@@ -0,0 +1,35 @@
+package repos
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+//go:generate mockgen -source=pspNonceRepo.go -destination=mock/pspNonceRepo.go
+
+type PSPNonceRepo interface {
+	Use(nonce *entities.PSPNonce) (bool, error)
+}
+
+type pspNonceRepo struct {
+}
+
+func NewPSPNonceRepo() PSPNonceRepo {
+	return &pspNonceRepo{}
+}
+
+// Use records a nonce and reports whether it was not already recorded. Expired
+// nonces are forgotten first, so they can be used again.
+func (*pspNonceRepo) Use(nonce *entities.PSPNonce) (bool, error) {
+	if err := database.DB.
+		Where("expires_at <= ?", time.Now()).
+		Delete(&entities.PSPNonce{}).Error; err != nil {
+		return false, err
+	}
+
+	result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(nonce)
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,45 @@
+package repos
+
+import (
+	"banking-system/database"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	maxTransactionAttempts = 5
+	serializationFailure   = "40001"
+	deadlockDetected       = "40P01"
+)
+
+// inTransaction runs fn in a database transaction and retries the whole
+// transaction when Postgres aborts it with a serialization failure or a
+// deadlock. fn must therefore be safe to run more than once.
+func inTransaction(fn func(db *gorm.DB) error) error {
+	var err error
+	for attempt := 1; attempt <= maxTransactionAttempts; attempt++ {
+		err = database.DB.Transaction(fn)
+		if !isRetryable(err) {
+			return err
+		}
+
+		backoff := time.Duration(attempt*attempt)*10*time.Millisecond + rand.N(10*time.Millisecond)
+		log.Warnf("Retrying database transaction after attempt %d failed: %v", attempt, err)
+		time.Sleep(backoff)
+	}
+
+	return err
+}
+
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
+}
@@ -0,0 +1,163 @@
+package repos
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:generate mockgen -source=scheduledTransferRepo.go -destination=mock/scheduledTransferRepo.go
+
+type ScheduledTransferRepo interface {
+	Create(schedule *entities.ScheduledTransfer) error
+	GetByUUID(id uuid.UUID) (*entities.ScheduledTransfer, error)
+	GetByUserID(userID uint) ([]entities.ScheduledTransfer, error)
+	GetOccurrences(scheduleID uuid.UUID, limit int) ([]entities.ScheduledTransferOccurrence, error)
+	UpdateStatus(schedule *entities.ScheduledTransfer, expectedStatus entities.ScheduledTransferStatus) (bool, error)
+	GetDue(today time.Time, limit int) ([]entities.ScheduledTransfer, error)
+	ClaimOccurrence(schedule *entities.ScheduledTransfer, expectedRunOn time.Time, occurrence *entities.ScheduledTransferOccurrence) (bool, error)
+	GetPendingOccurrences(limit int) ([]entities.ScheduledTransferOccurrence, error)
+	RecordOccurrence(schedule *entities.ScheduledTransfer, occurrence *entities.ScheduledTransferOccurrence) (bool, error)
+}
+
+type scheduledTransferRepo struct {
+}
+
+func NewScheduledTransferRepo() ScheduledTransferRepo {
+	return &scheduledTransferRepo{}
+}
+
+func (*scheduledTransferRepo) Create(schedule *entities.ScheduledTransfer) error {
+	return database.DB.Create(schedule).Error
+}
+
+func (*scheduledTransferRepo) GetByUUID(id uuid.UUID) (*entities.ScheduledTransfer, error) {
+	var schedule entities.ScheduledTransfer
+	err := database.DB.First(&schedule, id).Error
+	return &schedule, err
+}
+
+// GetByUserID returns the schedules of a user, the most recently created
+// first.
+func (*scheduledTransferRepo) GetByUserID(userID uint) ([]entities.ScheduledTransfer, error) {
+	var schedules []entities.ScheduledTransfer
+	err := database.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&schedules).Error
+	return schedules, err
+}
+
+// GetOccurrences returns the latest occurrences of a schedule, the most
+// recent first.
+func (*scheduledTransferRepo) GetOccurrences(scheduleID uuid.UUID, limit int) ([]entities.ScheduledTransferOccurrence, error) {
+	var occurrences []entities.ScheduledTransferOccurrence
+	err := database.DB.
+		Where("scheduled_transfer_id = ?", scheduleID).
+		Order("run_on DESC").
+		Limit(limit).
+		Find(&occurrences).Error
+	return occurrences, err
+}
+
+// UpdateStatus saves a pause, resume or cancel of a schedule, only if it is
+// still in expectedStatus. It returns false if the schedule changed first.
+func (*scheduledTransferRepo) UpdateStatus(schedule *entities.ScheduledTransfer, expectedStatus entities.ScheduledTransferStatus) (bool, error) {
+	result := database.DB.Model(&entities.ScheduledTransfer{}).
+		Where("uuid = ? AND status = ?", schedule.UUID, expectedStatus).
+		Updates(map[string]interface{}{
+			"status":      schedule.Status,
+			"next_run_on": schedule.NextRunOn,
+			"updated_at":  time.Now(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetDue returns the active schedules whose next occurrence is on or before
+// today, the longest overdue first.
+func (*scheduledTransferRepo) GetDue(today time.Time, limit int) ([]entities.ScheduledTransfer, error) {
+	var schedules []entities.ScheduledTransfer
+	err := database.DB.
+		Where("status = ? AND next_run_on <= ?", entities.ScheduledTransferStatuses.Active, today).
+		Order("next_run_on").
+		Limit(limit).
+		Find(&schedules).Error
+	return schedules, err
+}
+
+// ClaimOccurrence moves an active schedule whose next occurrence is still on
+// expectedRunOn on to its next occurrence, and saves occurrence as pending,
+// before the transfer of the occurrence is made. It returns false if the
+// schedule was paused, canceled or moved on meanwhile, in which case nothing
+// is saved, or if an occurrence of the same day was made before, in which case
+// the schedule is only moved on.
+func (*scheduledTransferRepo) ClaimOccurrence(schedule *entities.ScheduledTransfer, expectedRunOn time.Time, occurrence *entities.ScheduledTransferOccurrence) (bool, error) {
+	var claimed bool
+	err := inTransaction(func(db *gorm.DB) error {
+		claimed = false
+		occurrence.ID = 0 // the transaction may be retried
+
+		result := db.Model(&entities.ScheduledTransfer{}).
+			Where("uuid = ? AND status = ? AND next_run_on = ?", schedule.UUID, entities.ScheduledTransferStatuses.Active, expectedRunOn).
+			Updates(map[string]interface{}{
+				"status":      schedule.Status,
+				"next_run_on": schedule.NextRunOn,
+				"updated_at":  db.NowFunc(),
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		result = db.Clauses(clause.OnConflict{DoNothing: true}).Create(occurrence)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		claimed = true
+
+		return db.Model(&entities.ScheduledTransfer{}).
+			Where("uuid = ?", schedule.UUID).
+			Update("occurrences", gorm.Expr("occurrences + 1")).Error
+	})
+
+	return claimed, err
+}
+
+// GetPendingOccurrences returns the claimed occurrences whose outcome was not
+// recorded, the oldest first.
+func (*scheduledTransferRepo) GetPendingOccurrences(limit int) ([]entities.ScheduledTransferOccurrence, error) {
+	var occurrences []entities.ScheduledTransferOccurrence
+	err := database.DB.
+		Where("status = ?", entities.OccurrenceStatuses.Pending).
+		Order("id").
+		Limit(limit).
+		Find(&occurrences).Error
+	return occurrences, err
+}
+
+// RecordOccurrence saves the outcome of a pending occurrence of a schedule,
+// with the events it notifies. It returns false if the outcome was already
+// recorded.
+func (*scheduledTransferRepo) RecordOccurrence(schedule *entities.ScheduledTransfer, occurrence *entities.ScheduledTransferOccurrence) (bool, error) {
+	var recorded bool
+	err := inTransaction(func(db *gorm.DB) error {
+		result := db.Model(&entities.ScheduledTransferOccurrence{}).
+			Where("id = ? AND status = ?", occurrence.ID, entities.OccurrenceStatuses.Pending).
+			Updates(map[string]interface{}{
+				"status":         occurrence.Status,
+				"failure_code":   occurrence.FailureCode,
+				"failure_reason": occurrence.FailureReason,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		recorded = result.RowsAffected > 0
+		if !recorded {
+			return nil
+		}
+		return writeEvents(db, occurrence.Events(schedule))
+	})
+
+	return recorded, err
+}
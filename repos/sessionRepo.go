@@ -0,0 +1,86 @@
+package repos
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//go:generate mockgen -source=sessionRepo.go -destination=mock/sessionRepo.go
+
+type SessionRepo interface {
+	Create(session *entities.Session) error
+	GetByUUID(sessionID uuid.UUID) (*entities.Session, error)
+	GetActiveByUserID(userID uint) ([]entities.Session, error)
+	Rotate(session *entities.Session, previousHash string) (bool, error)
+	Revoke(sessionID uuid.UUID, reason string) error
+	RevokeForUser(userID uint, sessionID uuid.UUID, reason string) (bool, error)
+}
+
+type sessionRepo struct {
+}
+
+func NewSessionRepo() SessionRepo {
+	return &sessionRepo{}
+}
+
+func (*sessionRepo) Create(session *entities.Session) error {
+	return database.DB.Create(session).Error
+}
+
+func (*sessionRepo) GetByUUID(sessionID uuid.UUID) (*entities.Session, error) {
+	var session entities.Session
+	result := database.DB.First(&session, sessionID)
+	return &session, result.Error
+}
+
+func (*sessionRepo) GetActiveByUserID(userID uint) ([]entities.Session, error) {
+	var sessions []entities.Session
+	result := database.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions)
+	return sessions, result.Error
+}
+
+// Rotate replaces the refresh token of an active session, but only if the
+// session still holds previousHash. It returns false if another request
+// rotated or revoked the session first.
+func (*sessionRepo) Rotate(session *entities.Session, previousHash string) (bool, error) {
+	result := database.DB.Model(&entities.Session{}).
+		Where("uuid = ? AND refresh_token_hash = ? AND revoked_at IS NULL", session.UUID, previousHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash": session.RefreshTokenHash,
+			"generation":         session.Generation,
+			"last_used_at":       session.LastUsedAt,
+			"ip_address":         session.IPAddress,
+			"user_agent":         session.UserAgent,
+			"updated_at":         time.Now(),
+		})
+
+	return result.RowsAffected == 1, result.Error
+}
+
+func (*sessionRepo) Revoke(sessionID uuid.UUID, reason string) error {
+	return database.DB.Model(&entities.Session{}).
+		Where("uuid = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now(),
+			"revoked_reason": reason,
+		}).Error
+}
+
+// RevokeForUser revokes a session only if it belongs to the user. It returns
+// false if the user has no such active session.
+func (*sessionRepo) RevokeForUser(userID uint, sessionID uuid.UUID, reason string) (bool, error) {
+	result := database.DB.Model(&entities.Session{}).
+		Where("uuid = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now(),
+			"revoked_reason": reason,
+		})
+
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,110 @@
+package repos
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"banking-system/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//go:generate mockgen -source=statementRepo.go -destination=mock/statementRepo.go
+
+type StatementRepo interface {
+	GetBalanceAt(wallet *entities.Wallet, at time.Time) (money.Money, error)
+	GetLines(wallet *entities.Wallet, from time.Time, to time.Time, afterPostingID uint, limit int) ([]entities.StatementLine, error)
+	GetWalletsWithoutStatement(periodStart time.Time, periodEnd time.Time, afterWalletID uint, limit int) ([]entities.Wallet, error)
+	Create(statement *entities.Statement) error
+	GetByUUID(id uuid.UUID) (*entities.Statement, error)
+	GetByUserID(userID uint) ([]entities.Statement, error)
+}
+
+type statementRepo struct {
+}
+
+func NewStatementRepo() StatementRepo {
+	return &statementRepo{}
+}
+
+// GetBalanceAt returns the balance of the wallet right before at, the sum of
+// the postings to its ledger account until then.
+func (*statementRepo) GetBalanceAt(wallet *entities.Wallet, at time.Time) (money.Money, error) {
+	var balance money.Money
+	err := database.DB.Model(&entities.Posting{}).
+		Select("COALESCE(SUM(postings.amount), 0)").
+		Joins("JOIN ledger_accounts ON ledger_accounts.id = postings.ledger_account_id").
+		Where("ledger_accounts.wallet_id = ? AND postings.created_at < ?", wallet.ID, at).
+		Scan(&balance).Error
+
+	return balance.WithCurrency(wallet.Currency), err
+}
+
+// GetLines returns up to limit postings to the wallet created in [from, to),
+// in the order they were booked, starting after afterPostingID.
+//
+// A journal entry belongs to one transaction, but moves money between two
+// wallets for transfers and reversals. The wallet's own side of the entry is
+// then the counterpart that the transaction relates to.
+func (*statementRepo) GetLines(wallet *entities.Wallet, from time.Time, to time.Time, afterPostingID uint, limit int) ([]entities.StatementLine, error) {
+	var lines []entities.StatementLine
+	err := database.DB.Model(&entities.Posting{}).
+		Select(`postings.id AS posting_id, postings.created_at, postings.journal_entry_id, postings.amount,
+			journal_entries.description,
+			COALESCE(counterparts.uuid, transactions.uuid) AS transaction_id,
+			COALESCE(counterparts.type, transactions.type, '') AS type`).
+		Joins("JOIN ledger_accounts ON ledger_accounts.id = postings.ledger_account_id").
+		Joins("JOIN journal_entries ON journal_entries.uuid = postings.journal_entry_id").
+		Joins("LEFT JOIN transactions ON transactions.uuid = journal_entries.transaction_id").
+		Joins(`LEFT JOIN transactions counterparts ON counterparts.uuid = transactions.related_transaction_id
+			AND transactions.wallet_id <> ledger_accounts.wallet_id AND counterparts.wallet_id = ledger_accounts.wallet_id`).
+		Where("ledger_accounts.wallet_id = ? AND postings.created_at >= ? AND postings.created_at < ?", wallet.ID, from, to).
+		Where("postings.id > ?", afterPostingID).
+		Order("postings.id").
+		Limit(limit).
+		Scan(&lines).Error
+
+	for i := range lines {
+		lines[i].Amount = lines[i].Amount.WithCurrency(wallet.Currency)
+	}
+
+	return lines, err
+}
+
+// GetWalletsWithoutStatement returns up to limit wallets after afterWalletID
+// that were opened before periodEnd and have no statement for the period
+// starting at periodStart yet.
+func (*statementRepo) GetWalletsWithoutStatement(periodStart time.Time, periodEnd time.Time, afterWalletID uint, limit int) ([]entities.Wallet, error) {
+	var wallets []entities.Wallet
+	result := database.DB.
+		Where("id > ? AND created_at < ?", afterWalletID, periodEnd).
+		Where("NOT EXISTS (?)", database.DB.Model(&entities.Statement{}).
+			Select("1").
+			Where("statements.wallet_id = wallets.id AND statements.period_start = ?", periodStart)).
+		Order("id").
+		Limit(limit).
+		Find(&wallets)
+
+	return wallets, result.Error
+}
+
+func (*statementRepo) Create(statement *entities.Statement) error {
+	return database.DB.Create(statement).Error
+}
+
+func (*statementRepo) GetByUUID(id uuid.UUID) (*entities.Statement, error) {
+	var statement entities.Statement
+	err := database.DB.First(&statement, id).Error
+	return &statement, err
+}
+
+// GetByUserID returns the statements issued to the user, the latest first.
+func (*statementRepo) GetByUserID(userID uint) ([]entities.Statement, error) {
+	var statements []entities.Statement
+	result := database.DB.
+		Where("user_id = ?", userID).
+		Order("period_start DESC, currency").
+		Find(&statements)
+
+	return statements, result.Error
+}
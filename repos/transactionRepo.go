@@ -3,23 +3,46 @@ package repos
 import (
 	"banking-system/database"
 	"banking-system/entities"
+	"banking-system/money"
+	"banking-system/psp"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 //go:generate mockgen -source=transactionRepo.go -destination=mock/transactionRepo.go
 
 type TransactionRepo interface {
 	Create(tx *entities.Transaction) error
+	CreateWithJournalEntry(tx *entities.Transaction, entries ...*entities.JournalEntry) error
 	GetByUUID(uuid.UUID) (*entities.Transaction, error)
 	Update(tx *entities.Transaction) error
-	UpdateConditional(tx *entities.Transaction, expectedStatus entities.TransactionStatus) (bool, error)
-	CreateTransferTransactions(transferOutTx *entities.Transaction, transferInTx *entities.Transaction) error
-	GetByUserID(userID uint, cutoffDate time.Time) ([]entities.Transaction, error)
+	ApplyTransition(tx *entities.Transaction, t *entities.StatusTransition) (bool, error)
+	CreateTransferTransactions(transferOutTx *entities.Transaction, transferInTx *entities.Transaction, entries ...*entities.JournalEntry) error
+	GetHistory(filter *TransactionFilter) ([]entities.Transaction, error)
+	SetPSPReference(transactionID uuid.UUID, reference string) error
+	UpdatePayOut(tx *entities.Transaction, expectedStatus psp.PayOutStatus, t *entities.StatusTransition) (bool, error)
+	TouchPayOut(tx *entities.Transaction) error
+	GetUnsettledPayOuts(updatedBefore time.Time, limit int) ([]entities.Transaction, error)
+	GetExpiredPending(cutoffs map[psp.PaymentMethod]time.Time, defaultCutoff time.Time, limit int) ([]entities.Transaction, error)
+	FlagForReview(transactionID uuid.UUID) error
+	CreateRefund(refund *entities.Transaction, entry *entities.JournalEntry) error
+	CreateReversal(reversalOutTx *entities.Transaction, reversalInTx *entities.Transaction, entry *entities.JournalEntry, audit ...*entities.TransactionStatusChange) error
 }
 
+var (
+	// ErrRefundExceedsAmount is returned when a refund would take the refunded
+	// total of a transaction above its amount.
+	ErrRefundExceedsAmount = errors.New("refunds exceed the transaction amount")
+
+	// ErrAlreadyReversed is returned when reversing a transfer twice.
+	ErrAlreadyReversed = errors.New("transaction is already reversed")
+)
+
 type transactionRepo struct {
 }
 
@@ -28,53 +51,359 @@ func NewTransactionRepo() TransactionRepo {
 }
 
 func (*transactionRepo) Create(transaction *entities.Transaction) error {
-	if err := database.DB.Create(&transaction).Error; err != nil {
+	return inTransaction(func(db *gorm.DB) error {
+		if err := createTransaction(db, transaction); err != nil {
+			return err
+		}
+
+		return writeOutbox(db, nil, transaction)
+	})
+}
+
+// CreateWithJournalEntry creates a transaction, and its fee transaction if it
+// has one, together with the journal entries that move their amounts.
+func (*transactionRepo) CreateWithJournalEntry(transaction *entities.Transaction, entries ...*entities.JournalEntry) error {
+	return inTransaction(func(db *gorm.DB) error {
+		if err := createTransaction(db, transaction); err != nil {
+			return err
+		}
+
+		if err := postJournalEntries(db, entries); err != nil {
+			return err
+		}
+
+		return writeOutbox(db, entries, transaction)
+	})
+}
+
+// createTransaction creates a transaction and its fee transaction, if any,
+// unless the transaction exceeds the limits of its LimitCheck.
+func createTransaction(db *gorm.DB, transaction *entities.Transaction) error {
+	if err := checkLimits(db, transaction); err != nil {
+		return err
+	}
+
+	if err := db.Omit("Wallet").Create(transaction).Error; err != nil {
 		return err
 	}
+
+	if transaction.FeeTransaction == nil {
+		return nil
+	}
+	return db.Omit("Wallet", "RelatedTransaction").Create(transaction.FeeTransaction).Error
+}
+
+func postJournalEntries(db *gorm.DB, entries []*entities.JournalEntry) error {
+	for _, entry := range entries {
+		if err := postJournalEntry(db, entry); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (*transactionRepo) GetByUUID(uuid uuid.UUID) (*entities.Transaction, error) {
 	var transaction entities.Transaction
-	result := database.DB.Preload("Wallet").First(&transaction, uuid)
-	return &transaction, result.Error
+	if err := database.DB.Preload("Wallet").First(&transaction, uuid).Error; err != nil {
+		return &transaction, err
+	}
+
+	err := loadFeeTransactions(database.DB, &transaction)
+	return &transaction, err
+}
+
+// loadFeeTransactions sets the FeeTransaction of the transactions that were
+// charged a fee, so that their status changes carry over to their fee.
+func loadFeeTransactions(db *gorm.DB, transactions ...*entities.Transaction) error {
+	charged := make(map[uuid.UUID]*entities.Transaction)
+	for _, tx := range transactions {
+		if tx.HasFee() {
+			charged[tx.UUID] = tx
+		}
+	}
+
+	if len(charged) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(charged))
+	for id := range charged {
+		ids = append(ids, id)
+	}
+
+	var fees []*entities.Transaction
+	if err := db.Where("related_transaction_id IN ? AND type = ?", ids, entities.TransactionTypes.Fee).
+		Find(&fees).Error; err != nil {
+		return err
+	}
+
+	for _, fee := range fees {
+		tx := charged[*fee.RelatedTransactionID]
+		fee.Wallet = tx.Wallet
+		tx.FeeTransaction = fee
+	}
+
+	return nil
+}
+
+func pointersTo(transactions []entities.Transaction) []*entities.Transaction {
+	pointers := make([]*entities.Transaction, len(transactions))
+	for i := range transactions {
+		pointers[i] = &transactions[i]
+	}
+	return pointers
 }
 
 func (*transactionRepo) Update(transaction *entities.Transaction) error {
-	return database.DB.Transaction(func(db *gorm.DB) error {
-		if err := db.Save(transaction).Error; err != nil {
+	return database.DB.Omit("Wallet").Save(transaction).Error
+}
+
+// ApplyTransition saves a status change, its journal entry and its audit
+// record, but only if the transaction is still in the status the change was
+// made from. It returns false if another request changed the status first.
+func (*transactionRepo) ApplyTransition(transaction *entities.Transaction, t *entities.StatusTransition) (bool, error) {
+	var updated bool
+	err := inTransaction(func(db *gorm.DB) error {
+		updated = false
+		result := db.Model(&entities.Transaction{}).
+			Where("uuid = ? AND status = ?", transaction.UUID, t.From).
+			Updates(map[string]interface{}{
+				"status":        transaction.Status,
+				"payout_status": transaction.PayOutStatus,
+				"updated_at":    db.NowFunc(),
+			})
+
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := saveTransition(db, t); err != nil {
 			return err
 		}
 
-		if err := db.Save(&transaction.Wallet).Error; err != nil {
+		if err := writeOutbox(db, t.Entries(), transaction); err != nil {
 			return err
 		}
 
+		updated = true
 		return nil
 	})
+
+	return updated, err
 }
 
-func (*transactionRepo) UpdateConditional(transaction *entities.Transaction, expectedStatus entities.TransactionStatus) (bool, error) {
-	var updated bool
-	err := database.DB.Transaction(func(db *gorm.DB) error {
+// saveTransition posts the journal entry of a status change and records it in
+// the status history, then saves the status changes of its fee.
+func saveTransition(db *gorm.DB, t *entities.StatusTransition) error {
+	if t.Entry != nil {
+		if err := postJournalEntry(db, t.Entry); err != nil {
+			return err
+		}
+	}
+
+	t.Record.ID = 0
+	if err := db.Create(t.Record).Error; err != nil {
+		return err
+	}
+
+	for _, linked := range t.Linked {
+		// The fee only changes together with its transaction, whose row
+		// was just updated conditionally, so it cannot have moved on
 		result := db.Model(&entities.Transaction{}).
-			Where("uuid = ? AND status = ?", transaction.UUID, expectedStatus).
+			Where("uuid = ? AND status = ?", linked.Record.TransactionID, linked.From).
 			Updates(map[string]interface{}{
-				"status":     transaction.Status,
+				"status":     linked.To,
 				"updated_at": db.NowFunc(),
 			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("fee transaction '%s' is no longer %s", linked.Record.TransactionID, linked.From)
+		}
+
+		if err := saveTransition(db, linked); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (*transactionRepo) CreateTransferTransactions(transferOutTx *entities.Transaction, transferInTx *entities.Transaction, entries ...*entities.JournalEntry) error {
+	return inTransaction(func(tx *gorm.DB) error {
+		if err := payPaymentRequest(tx, transferOutTx); err != nil {
+			return err
+		}
+
+		if err := createLinkedTransactions(tx, transferOutTx, transferInTx); err != nil {
+			return err
+		}
+
+		if err := postJournalEntries(tx, entries); err != nil {
+			return err
+		}
 
+		return writeOutbox(tx, entries, transferOutTx, transferInTx)
+	})
+}
+
+// createLinkedTransactions creates an outgoing and incoming transaction that
+// reference each other through RelatedTransactionID, and the fee transaction
+// of the outgoing one if it has one.
+func createLinkedTransactions(db *gorm.DB, outTx *entities.Transaction, inTx *entities.Transaction) error {
+	if err := createTransaction(db, outTx); err != nil {
+		return err
+	}
+
+	inTx.RelatedTransactionID = &outTx.UUID
+	if err := db.Omit("Wallet").Create(inTx).Error; err != nil {
+		return err
+	}
+
+	outTx.RelatedTransactionID = &inTx.UUID
+	return db.Model(outTx).Update("RelatedTransactionID", inTx.UUID).Error
+}
+
+// TransactionFilter selects one page of a user's transactions, ordered by
+// creation time and UUID. Filters left empty match every transaction.
+type TransactionFilter struct {
+	UserID uint
+
+	// From is inclusive, To exclusive
+	From time.Time
+	To   time.Time
+
+	Types          []entities.TransactionType
+	Statuses       []entities.TransactionStatus
+	PaymentMethods []psp.PaymentMethod
+	Currency       string
+
+	// MinAmount and MaxAmount are inclusive and compared with the amount in
+	// the transaction's own currency
+	MinAmount *money.Money
+	MaxAmount *money.Money
+
+	// Ascending lists the oldest transactions first
+	Ascending bool
+
+	// After is the position of the last transaction of the previous page
+	After *TransactionCursor
+
+	// Limit caps the number of transactions; a negative limit returns all
+	Limit int
+}
+
+// TransactionCursor is the position of a transaction in the history.
+type TransactionCursor struct {
+	CreatedAt time.Time
+	UUID      uuid.UUID
+}
+
+// GetHistory returns the transactions of the user that match filter. Pages
+// are keyed on (created_at, uuid), so that transactions created while paging
+// neither shift nor repeat later pages.
+func (*transactionRepo) GetHistory(filter *TransactionFilter) ([]entities.Transaction, error) {
+	query := database.DB.
+		Joins("JOIN wallets ON wallets.id = transactions.wallet_id").
+		Where("wallets.user_id = ?", filter.UserID)
+
+	if !filter.From.IsZero() {
+		query = query.Where("transactions.created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("transactions.created_at < ?", filter.To)
+	}
+	if len(filter.Types) > 0 {
+		query = query.Where("transactions.type IN ?", filter.Types)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("transactions.status IN ?", filter.Statuses)
+	}
+	if len(filter.PaymentMethods) > 0 {
+		query = query.Where("transactions.payment_method IN ?", filter.PaymentMethods)
+	}
+	if filter.Currency != "" {
+		query = query.Where("transactions.currency = ?", filter.Currency)
+	}
+	if filter.MinAmount != nil {
+		query = query.Where("transactions.amount >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where("transactions.amount <= ?", *filter.MaxAmount)
+	}
+
+	direction, comparison := "DESC", "<"
+	if filter.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+
+	if filter.After != nil {
+		query = query.Where("(transactions.created_at, transactions.uuid) "+comparison+" (?, ?)", filter.After.CreatedAt, filter.After.UUID)
+	}
+
+	var transactions []entities.Transaction
+	err := query.
+		Order("transactions.created_at " + direction).
+		Order("transactions.uuid " + direction).
+		Limit(filter.Limit).
+		Find(&transactions).Error
+
+	return transactions, err
+}
+
+// SetPSPReference records the provider's reference for a payout unless a
+// status callback already did.
+func (*transactionRepo) SetPSPReference(transactionID uuid.UUID, reference string) error {
+	return database.DB.Model(&entities.Transaction{}).
+		Where("uuid = ? AND psp_reference = ''", transactionID).
+		Update("psp_reference", reference).Error
+}
+
+// UpdatePayOut saves the payout status of a withdrawal, together with the
+// status change it caused if t is not nil, only if the payout is still in
+// expectedStatus. It returns false if another request changed the payout
+// first.
+func (*transactionRepo) UpdatePayOut(transaction *entities.Transaction, expectedStatus psp.PayOutStatus, t *entities.StatusTransition) (bool, error) {
+	var updated bool
+	err := inTransaction(func(db *gorm.DB) error {
+		updated = false
+		changes := map[string]interface{}{
+			"status":        transaction.Status,
+			"payout_status": transaction.PayOutStatus,
+			"updated_at":    db.NowFunc(),
+		}
+		if transaction.PSPReference != "" {
+			changes["psp_reference"] = transaction.PSPReference
+		}
+
+		query := db.Model(&entities.Transaction{}).
+			Where("uuid = ? AND payout_status = ?", transaction.UUID, expectedStatus)
+		if t != nil {
+			query = query.Where("status = ?", t.From)
+		}
+
+		result := query.Updates(changes)
 		if result.Error != nil {
 			return result.Error
 		}
 
 		if result.RowsAffected == 0 {
-			updated = false
 			return nil
 		}
 
-		if err := db.Save(&transaction.Wallet).Error; err != nil {
-			return err
+		if t != nil {
+			if err := saveTransition(db, t); err != nil {
+				return err
+			}
+
+			if err := writeOutbox(db, t.Entries(), transaction); err != nil {
+				return err
+			}
 		}
 
 		updated = true
@@ -84,41 +413,172 @@ func (*transactionRepo) UpdateConditional(transaction *entities.Transaction, exp
 	return updated, err
 }
 
-func (*transactionRepo) CreateTransferTransactions(transferOutTx *entities.Transaction, transferInTx *entities.Transaction) error {
-	return database.DB.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(transferOutTx).Error; err != nil {
+// TouchPayOut moves a payout whose status did not change to the back of the
+// polling queue.
+func (*transactionRepo) TouchPayOut(transaction *entities.Transaction) error {
+	return database.DB.Model(&entities.Transaction{}).
+		Where("uuid = ? AND payout_status = ?", transaction.UUID, transaction.PayOutStatus).
+		Update("updated_at", time.Now()).Error
+}
+
+// GetUnsettledPayOuts returns the withdrawals whose payout has not been
+// settled and has not changed since updatedBefore, least recently updated
+// first.
+func (*transactionRepo) GetUnsettledPayOuts(updatedBefore time.Time, limit int) ([]entities.Transaction, error) {
+	var transactions []entities.Transaction
+
+	result := database.DB.Preload("Wallet").
+		Where("type = ? AND payout_status IN ? AND updated_at < ?",
+			entities.TransactionTypes.Withdrawal,
+			[]psp.PayOutStatus{psp.PayOutStatuses.Submitted, psp.PayOutStatuses.Processing},
+			updatedBefore).
+		Order("updated_at").
+		Limit(limit).
+		Find(&transactions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return transactions, loadFeeTransactions(database.DB, pointersTo(transactions)...)
+}
+
+// GetExpiredPending returns the pending deposits and withdrawals created
+// before the cutoff of their payment method, or before defaultCutoff if their
+// payment method has none, oldest first. Transactions flagged for review are
+// left out.
+func (*transactionRepo) GetExpiredPending(cutoffs map[psp.PaymentMethod]time.Time, defaultCutoff time.Time, limit int) ([]entities.Transaction, error) {
+	var transactions []entities.Transaction
+
+	methods := make([]psp.PaymentMethod, 0, len(cutoffs))
+	for method := range cutoffs {
+		methods = append(methods, method)
+	}
+
+	var expired *gorm.DB
+	if len(methods) == 0 {
+		expired = database.DB.Where("created_at < ?", defaultCutoff)
+	} else {
+		expired = database.DB.Where("((payment_method IS NULL OR payment_method NOT IN ?) AND created_at < ?)", methods, defaultCutoff)
+	}
+	for method, cutoff := range cutoffs {
+		expired = expired.Or("(payment_method = ? AND created_at < ?)", method, cutoff)
+	}
+
+	result := database.DB.Preload("Wallet").
+		Where("type IN ? AND status = ? AND NOT needs_review",
+			[]entities.TransactionType{entities.TransactionTypes.Deposit, entities.TransactionTypes.Withdrawal},
+			entities.TransactionStatuses.Pending).
+		Where(expired).
+		Order("created_at").
+		Limit(limit).
+		Find(&transactions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return transactions, loadFeeTransactions(database.DB, pointersTo(transactions)...)
+}
+
+// FlagForReview marks a pending transaction for an operator to settle.
+func (*transactionRepo) FlagForReview(transactionID uuid.UUID) error {
+	return database.DB.Model(&entities.Transaction{}).
+		Where("uuid = ? AND status = ?", transactionID, entities.TransactionStatuses.Pending).
+		Update("needs_review", true).Error
+}
+
+// CreateRefund creates a pending refund of the transaction it relates to,
+// together with its journal entry, unless the refunds of that transaction
+// would then add up to more than its amount. The refunded transaction itself
+// is only locked, never changed.
+func (*transactionRepo) CreateRefund(refund *entities.Transaction, entry *entities.JournalEntry) error {
+	return inTransaction(func(db *gorm.DB) error {
+		// Refunds of the same transaction queue up on its row, so that each
+		// sees the ones before it
+		var original entities.Transaction
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&original, *refund.RelatedTransactionID).Error; err != nil {
 			return err
 		}
 
-		transferInTx.RelatedTransactionID = &transferOutTx.UUID
-		if err := tx.Create(transferInTx).Error; err != nil {
+		var refunded money.Money
+		err := db.Model(&entities.Transaction{}).
+			Select("COALESCE(SUM(amount), 0)").
+			Where("related_transaction_id = ? AND type = ? AND status IN ?",
+				original.UUID,
+				entities.TransactionTypes.Refund,
+				[]entities.TransactionStatus{entities.TransactionStatuses.Pending, entities.TransactionStatuses.Completed}).
+			Scan(&refunded).Error
+		if err != nil {
 			return err
 		}
 
-		if err := tx.Model(transferOutTx).Update("RelatedTransactionID", transferInTx.UUID).Error; err != nil {
+		total, err := refunded.WithCurrency(original.Currency).Add(refund.Amount)
+		if err != nil {
 			return err
 		}
+		if total.GreaterThan(original.Amount) {
+			return ErrRefundExceedsAmount
+		}
 
-		if err := tx.Save(&transferOutTx.Wallet).Error; err != nil {
+		if err := db.Omit("Wallet", "RelatedTransaction").Create(refund).Error; err != nil {
 			return err
 		}
 
-		if err := tx.Save(&transferInTx.Wallet).Error; err != nil {
+		if err := postJournalEntry(db, entry); err != nil {
 			return err
 		}
 
-		return nil
+		return writeOutbox(db, []*entities.JournalEntry{entry}, refund)
 	})
 }
 
-func (*transactionRepo) GetByUserID(userID uint, cutoffDate time.Time) ([]entities.Transaction, error) {
-	var transactions []entities.Transaction
+// CreateReversal creates the two transactions that reverse a transfer, each
+// relating to the transfer transaction on the same wallet, together with the
+// journal entry and audit records, unless the transfer is already reversed.
+func (*transactionRepo) CreateReversal(reversalOutTx *entities.Transaction, reversalInTx *entities.Transaction, entry *entities.JournalEntry, audit ...*entities.TransactionStatusChange) error {
+	return inTransaction(func(db *gorm.DB) error {
+		originals := []uuid.UUID{*reversalOutTx.RelatedTransactionID, *reversalInTx.RelatedTransactionID}
 
-	result := database.DB.
-		Joins("JOIN wallets ON wallets.id = transactions.wallet_id").
-		Where("wallets.user_id = ? AND transactions.created_at >= ?", userID, cutoffDate).
-		Order("transactions.created_at DESC").
-		Find(&transactions)
+		// Locked in a fixed order so that concurrent reversals cannot deadlock
+		var locked []entities.Transaction
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uuid IN ?", originals).
+			Order("uuid").
+			Find(&locked).Error; err != nil {
+			return err
+		}
+
+		var reversals int64
+		if err := db.Model(&entities.Transaction{}).
+			Where("related_transaction_id IN ? AND type IN ?", originals,
+				[]entities.TransactionType{entities.TransactionTypes.ReversalOut, entities.TransactionTypes.ReversalIn}).
+			Count(&reversals).Error; err != nil {
+			return err
+		}
+		if reversals > 0 {
+			return ErrAlreadyReversed
+		}
 
-	return transactions, result.Error
+		for _, tx := range []*entities.Transaction{reversalOutTx, reversalInTx} {
+			if err := db.Omit("Wallet", "RelatedTransaction").Create(tx).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := postJournalEntry(db, entry); err != nil {
+			return err
+		}
+
+		if err := writeOutbox(db, []*entities.JournalEntry{entry}, reversalOutTx, reversalInTx); err != nil {
+			return err
+		}
+
+		for _, record := range audit {
+			record.ID = 0 // the transaction may be retried
+			if err := db.Create(record).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 }
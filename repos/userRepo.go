@@ -11,7 +11,7 @@ type UserRepo interface {
 	Create(user *entities.User) error
 	Get(id uint) (*entities.User, error)
 	GetByUsername(username string) (*entities.User, error)
-	UpdateWallet(user *entities.User) error
+	CreateWallet(wallet *entities.Wallet) error
 }
 
 type userRepo struct {
@@ -28,17 +28,17 @@ func (*userRepo) Create(user *entities.User) error {
 
 func (*userRepo) Get(id uint) (*entities.User, error) {
 	var user entities.User
-	result := database.DB.Preload("Wallet").First(&user, id)
+	result := database.DB.Preload("Wallets").First(&user, id)
 	return &user, result.Error
 }
 
-func (*userRepo) UpdateWallet(user *entities.User) error {
-	result := database.DB.Save(&user.Wallet)
-	return result.Error
-}
-
 func (*userRepo) GetByUsername(username string) (*entities.User, error) {
 	var user entities.User
-	result := database.DB.Preload("Wallet").Where("username = ?", username).First(&user)
+	result := database.DB.Preload("Wallets").Where("username = ?", username).First(&user)
 	return &user, result.Error
 }
+
+func (*userRepo) CreateWallet(wallet *entities.Wallet) error {
+	result := database.DB.Create(wallet)
+	return result.Error
+}
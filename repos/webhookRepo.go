@@ -0,0 +1,126 @@
+package repos
+
+import (
+	"banking-system/database"
+	"banking-system/entities"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:generate mockgen -source=webhookRepo.go -destination=mock/webhookRepo.go
+
+type WebhookRepo interface {
+	CreateEndpoint(endpoint *entities.WebhookEndpoint) error
+	GetEndpoint(id uint) (*entities.WebhookEndpoint, error)
+	GetEndpointsByUserID(userID uint) ([]entities.WebhookEndpoint, error)
+	DeleteEndpoint(id uint) error
+	CreateDeliveries(deliveries []entities.WebhookDelivery) error
+	GetDelivery(id uuid.UUID) (*entities.WebhookDelivery, error)
+	GetDueDeliveries(now time.Time, limit int) ([]entities.WebhookDelivery, error)
+	GetDeliveriesByUserID(userID uint, status entities.WebhookDeliveryStatus, limit int) ([]entities.WebhookDelivery, error)
+	UpdateDelivery(delivery *entities.WebhookDelivery, expectedStatus entities.WebhookDeliveryStatus) (bool, error)
+}
+
+type webhookRepo struct {
+}
+
+func NewWebhookRepo() WebhookRepo {
+	return &webhookRepo{}
+}
+
+func (*webhookRepo) CreateEndpoint(endpoint *entities.WebhookEndpoint) error {
+	return database.DB.Create(endpoint).Error
+}
+
+func (*webhookRepo) GetEndpoint(id uint) (*entities.WebhookEndpoint, error) {
+	var endpoint entities.WebhookEndpoint
+	err := database.DB.First(&endpoint, id).Error
+	return &endpoint, err
+}
+
+func (*webhookRepo) GetEndpointsByUserID(userID uint) ([]entities.WebhookEndpoint, error) {
+	var endpoints []entities.WebhookEndpoint
+	err := database.DB.Where("user_id = ?", userID).Order("id").Find(&endpoints).Error
+	return endpoints, err
+}
+
+// DeleteEndpoint removes an endpoint and dead-letters the deliveries still
+// waiting to be sent to it.
+func (*webhookRepo) DeleteEndpoint(id uint) error {
+	return inTransaction(func(db *gorm.DB) error {
+		if err := db.Delete(&entities.WebhookEndpoint{}, id).Error; err != nil {
+			return err
+		}
+
+		return db.Model(&entities.WebhookDelivery{}).
+			Where("endpoint_id = ? AND status = ?", id, entities.WebhookDeliveryStatuses.Pending).
+			Updates(map[string]interface{}{
+				"status":     entities.WebhookDeliveryStatuses.Dead,
+				"last_error": "endpoint deleted",
+				"updated_at": db.NowFunc(),
+			}).Error
+	})
+}
+
+// CreateDeliveries creates deliveries, skipping those of an event that were
+// already created for the same endpoint.
+func (*webhookRepo) CreateDeliveries(deliveries []entities.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return database.DB.Clauses(clause.OnConflict{DoNothing: true}).Omit("Endpoint").Create(&deliveries).Error
+}
+
+func (*webhookRepo) GetDelivery(id uuid.UUID) (*entities.WebhookDelivery, error) {
+	var delivery entities.WebhookDelivery
+	err := database.DB.Preload("Endpoint").First(&delivery, id).Error
+	return &delivery, err
+}
+
+// GetDueDeliveries returns up to limit pending deliveries whose next attempt
+// is due, the longest overdue first, with their endpoints.
+func (*webhookRepo) GetDueDeliveries(now time.Time, limit int) ([]entities.WebhookDelivery, error) {
+	var deliveries []entities.WebhookDelivery
+	err := database.DB.Preload("Endpoint").
+		Where("status = ? AND next_attempt_at <= ?", entities.WebhookDeliveryStatuses.Pending, now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// GetDeliveriesByUserID returns up to limit deliveries in status to the
+// endpoints of the user, the most recent first.
+func (*webhookRepo) GetDeliveriesByUserID(userID uint, status entities.WebhookDeliveryStatus, limit int) ([]entities.WebhookDelivery, error) {
+	var deliveries []entities.WebhookDelivery
+	err := database.DB.Preload("Endpoint").
+		Joins("JOIN webhook_endpoints ON webhook_endpoints.id = webhook_deliveries.endpoint_id").
+		Where("webhook_endpoints.user_id = ? AND webhook_endpoints.deleted_at IS NULL", userID).
+		Where("webhook_deliveries.status = ?", status).
+		Order("webhook_deliveries.updated_at DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// UpdateDelivery saves the outcome of an attempt or a redelivery, but only if
+// the delivery is still in expectedStatus. It returns false if it was changed
+// in the meantime, e.g. because its endpoint was deleted.
+func (*webhookRepo) UpdateDelivery(delivery *entities.WebhookDelivery, expectedStatus entities.WebhookDeliveryStatus) (bool, error) {
+	result := database.DB.Model(&entities.WebhookDelivery{}).
+		Where("uuid = ? AND status = ?", delivery.UUID, expectedStatus).
+		Updates(map[string]interface{}{
+			"status":           delivery.Status,
+			"attempts":         delivery.Attempts,
+			"next_attempt_at":  delivery.NextAttemptAt,
+			"last_attempt_at":  delivery.LastAttemptAt,
+			"last_status_code": delivery.LastStatusCode,
+			"last_error":       delivery.LastError,
+			"delivered_at":     delivery.DeliveredAt,
+			"updated_at":       database.DB.NowFunc(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
@@ -1,11 +1,20 @@
 package router
 
 import (
+	"banking-system/auth"
+	"banking-system/blobs"
 	"banking-system/controllers"
+	"banking-system/database"
 	"banking-system/docs"
+	"banking-system/fees"
+	"banking-system/fx"
+	"banking-system/limits"
+	"banking-system/middleware"
 	"banking-system/psp"
 	"banking-system/repos"
 	"banking-system/services"
+	"banking-system/stream"
+	"banking-system/webhooks"
 
 	"github.com/gin-gonic/gin"
 	swaggerfiles "github.com/swaggo/files"
@@ -14,15 +23,38 @@ import (
 
 func Setup() *gin.Engine {
 	r := gin.Default()
+	r.Use(middleware.CorrelationID(), middleware.ErrorHandler())
 	docs.SwaggerInfo.BasePath = "/api/v1"
 	r.StaticFile("/version", "./version.txt")
 
 	userRepo := repos.NewUserRepo()
 	transactionRepo := repos.NewTransactionRepo()
-	paymentCtrl := controllers.NewPaymentController(services.NewPaymentService(userRepo, transactionRepo, psp.NewPSPFactory()))
-	userCtrl := controllers.NewUserController(services.NewUserService(userRepo))
-	bankAccountCtrl := controllers.NewBankAccountController(services.NewBankAccountService(repos.NewBankAccountRepo()))
+	bankAccountRepo := repos.NewBankAccountRepo()
+	feeSchedules := fees.NewSchedules()
+	limitSrv := services.NewLimitService(userRepo, repos.NewLimitRepo(), limits.NewRules())
+	paymentSrv := services.NewPaymentService(userRepo, transactionRepo, bankAccountRepo, psp.NewPSPFactory(), feeSchedules, limitSrv)
+	paymentCtrl := controllers.NewPaymentController(paymentSrv)
+	payOutCtrl := controllers.NewPayOutController(services.NewPayOutService(transactionRepo, psp.NewPSPFactory()))
+	sessionRepo := repos.NewSessionRepo()
+	userCtrl := controllers.NewUserController(services.NewUserService(userRepo), services.NewSessionService(sessionRepo, auth.LoadConfig()))
+	bankAccountCtrl := controllers.NewBankAccountController(services.NewBankAccountService(bankAccountRepo))
 	transactionCtrl := controllers.NewTransactionController(services.NewTransactionService(transactionRepo))
+	ledgerCtrl := controllers.NewLedgerController(services.NewLedgerService(repos.NewLedgerRepo()))
+	fxCtrl := controllers.NewFXController(services.NewFXService(userRepo, repos.NewFXQuoteRepo(), fx.NewRateSource()))
+	feeCtrl := controllers.NewFeeController(services.NewFeeService(userRepo, feeSchedules))
+	limitCtrl := controllers.NewLimitController(limitSrv)
+	statementCtrl := controllers.NewStatementController(services.NewStatementService(userRepo, repos.NewStatementRepo(), blobs.NewStore()))
+	webhookCtrl := controllers.NewWebhookController(services.NewWebhookService(repos.NewWebhookRepo(), webhooks.NewSender()))
+	scheduledTransferCtrl := controllers.NewScheduledTransferController(services.NewScheduledTransferService(repos.NewScheduledTransferRepo(), userRepo, paymentSrv))
+	paymentRequestCtrl := controllers.NewPaymentRequestController(services.NewPaymentRequestService(repos.NewPaymentRequestRepo(), userRepo, paymentSrv))
+	hub := stream.NewHub()
+	streamSrv := services.NewStreamService(repos.NewOutboxRepo(), hub)
+	stream.StartListener(database.DB, streamSrv.Receive, hub.Reset)
+	streamCtrl := controllers.NewStreamController(streamSrv)
+	authenticated := middleware.Authenticate(sessionRepo)
+	idempotent := middleware.Idempotency(repos.NewIdempotencyRepo())
+	pspSigned := middleware.VerifyPSPSignature(psp.LoadCallbackSecrets(), repos.NewPSPNonceRepo())
+	operator := middleware.RequireOperator(auth.LoadOperatorKeys())
 
 	api := r.Group("/api/v1")
 	{
@@ -30,20 +62,29 @@ func Setup() *gin.Engine {
 			userApi := api.Group("/user")
 			userApi.POST("", userCtrl.Register)
 			userApi.POST("/login", userCtrl.Login)
-			userApi.GET("/:user_id", userCtrl.GetByID)
+			userApi.POST("/token/refresh", userCtrl.RefreshToken)
+			userApi.POST("/logout", authenticated, userCtrl.Logout)
+			userApi.GET("/sessions", authenticated, userCtrl.GetSessions)
+			userApi.DELETE("/sessions/:session_id", authenticated, userCtrl.RevokeSession)
+			userApi.POST("/wallets", authenticated, userCtrl.OpenWallet)
+			userApi.GET("/:user_id", authenticated, userCtrl.GetByID)
 		}
 
 		{
 			paymentApi := api.Group("/payments")
-			paymentApi.POST("/deposit", paymentCtrl.Deposit)
-			paymentApi.POST("/withdraw", paymentCtrl.Withdraw)
-			paymentApi.POST("/transfer", paymentCtrl.Transfer)
-			paymentApi.POST("/confirm", paymentCtrl.Confirm)
-			paymentApi.POST("/cancel", paymentCtrl.Cancel)
+			paymentApi.POST("/deposit", authenticated, idempotent, paymentCtrl.Deposit)
+			paymentApi.POST("/withdraw", authenticated, idempotent, paymentCtrl.Withdraw)
+			paymentApi.POST("/transfer", authenticated, idempotent, paymentCtrl.Transfer)
+			paymentApi.POST("/convert", authenticated, idempotent, fxCtrl.Convert)
+			paymentApi.POST("/refund", authenticated, idempotent, paymentCtrl.Refund)
+			paymentApi.POST("/reverse", operator, paymentCtrl.Reverse)
+			paymentApi.POST("/confirm", pspSigned, paymentCtrl.Confirm)
+			paymentApi.POST("/cancel", pspSigned, paymentCtrl.Cancel)
+			paymentApi.POST("/payout-status", pspSigned, payOutCtrl.UpdateStatus)
 		}
 
 		{
-			bankAccountApi := api.Group("/bank-accounts")
+			bankAccountApi := api.Group("/bank-accounts", authenticated)
 			bankAccountApi.GET("/user/:userId", bankAccountCtrl.GetByUserID)
 			bankAccountApi.POST("", bankAccountCtrl.Create)
 			bankAccountApi.GET("", bankAccountCtrl.GetAll)
@@ -53,9 +94,69 @@ func Setup() *gin.Engine {
 		}
 
 		{
-			transactionApi := api.Group("/transactions")
+			paymentRequestApi := api.Group("/payment-requests", authenticated)
+			paymentRequestApi.POST("", paymentRequestCtrl.Create)
+			paymentRequestApi.GET("/incoming", paymentRequestCtrl.GetIncoming)
+			paymentRequestApi.GET("/outgoing", paymentRequestCtrl.GetOutgoing)
+			paymentRequestApi.POST("/:id/accept", paymentRequestCtrl.Accept)
+			paymentRequestApi.POST("/:id/decline", paymentRequestCtrl.Decline)
+		}
+
+		{
+			scheduledTransferApi := api.Group("/scheduled-transfers", authenticated)
+			scheduledTransferApi.POST("", scheduledTransferCtrl.Create)
+			scheduledTransferApi.GET("", scheduledTransferCtrl.GetAll)
+			scheduledTransferApi.GET("/:id/occurrences", scheduledTransferCtrl.GetOccurrences)
+			scheduledTransferApi.POST("/:id/pause", scheduledTransferCtrl.Pause)
+			scheduledTransferApi.POST("/:id/resume", scheduledTransferCtrl.Resume)
+			scheduledTransferApi.POST("/:id/cancel", scheduledTransferCtrl.Cancel)
+		}
+
+		{
+			transactionApi := api.Group("/transactions", authenticated)
+			transactionApi.GET("", transactionCtrl.GetHistory)
 			transactionApi.GET("/user/:user_id", transactionCtrl.GetByUserID)
 		}
+
+		{
+			statementApi := api.Group("/statements", authenticated)
+			statementApi.GET("", statementCtrl.Export)
+			statementApi.GET("/monthly", statementCtrl.GetIssued)
+			statementApi.GET("/:id/download", statementCtrl.Download)
+		}
+
+		{
+			webhookApi := api.Group("/webhooks", authenticated)
+			webhookApi.POST("", webhookCtrl.Register)
+			webhookApi.GET("", webhookCtrl.GetEndpoints)
+			webhookApi.DELETE("/:id", webhookCtrl.DeleteEndpoint)
+			webhookApi.GET("/dead-letters", webhookCtrl.GetDeadLetters)
+			webhookApi.POST("/dead-letters/:id/redeliver", webhookCtrl.Redeliver)
+		}
+
+		api.GET("/stream", authenticated, streamCtrl.Stream)
+
+		{
+			fxApi := api.Group("/fx", authenticated)
+			fxApi.POST("/quotes", fxCtrl.Quote)
+		}
+
+		{
+			feeApi := api.Group("/fees", authenticated)
+			feeApi.POST("/quotes", feeCtrl.Quote)
+		}
+
+		{
+			limitApi := api.Group("/limits")
+			limitApi.GET("", authenticated, limitCtrl.GetLimits)
+			limitApi.PUT("/users/:user_id", operator, limitCtrl.SetOverride)
+		}
+
+		{
+			ledgerApi := api.Group("/ledger", authenticated, operator)
+			ledgerApi.GET("/reconciliation", ledgerCtrl.Reconcile)
+			ledgerApi.GET("/wallets/:wallet_id/entries", ledgerCtrl.GetEntriesByWalletID)
+		}
 	}
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
@@ -1,12 +1,11 @@
 package services
 
 import (
+	"banking-system/apperrors"
 	"banking-system/entities"
 	"banking-system/models"
 	"banking-system/repos"
 	"fmt"
-
-	log "github.com/sirupsen/logrus"
 )
 
 //go:generate mockgen -source=bankAccount.go -destination=mock/bankAccount.go
@@ -37,7 +36,7 @@ func (srv *bankAccountService) Create(req *models.CreateBankAccountRequest) (*mo
 	}
 
 	if err := srv.bankAccountRepo.Create(bankAccount); err != nil {
-		log.Panicf("Failed to create bank account: %v", err)
+		return nil, fmt.Errorf("failed to create bank account: %w", err)
 	}
 
 	return &models.BankAccountResponse{
@@ -51,16 +50,9 @@ func (srv *bankAccountService) Create(req *models.CreateBankAccountRequest) (*mo
 }
 
 func (srv *bankAccountService) GetByID(id uint, userID uint) (*models.BankAccountResponse, error) {
-	bankAccount, err := srv.bankAccountRepo.GetByID(id)
+	bankAccount, err := getOwnedBankAccount(srv.bankAccountRepo, id, userID)
 	if err != nil {
-		if err.Error() == "record not found" {
-			return nil, fmt.Errorf("bank account not found")
-		}
-		log.Panicf("Failed to get bank account: %v", err)
-	}
-
-	if bankAccount.UserID != userID {
-		return nil, fmt.Errorf("unauthorized access to bank account")
+		return nil, err
 	}
 
 	return &models.BankAccountResponse{
@@ -76,7 +68,7 @@ func (srv *bankAccountService) GetByID(id uint, userID uint) (*models.BankAccoun
 func (srv *bankAccountService) GetByUserID(userID uint) ([]models.BankAccountResponse, error) {
 	bankAccounts, err := srv.bankAccountRepo.GetByUserID(userID)
 	if err != nil {
-		log.Panicf("Failed to get bank accounts: %v", err)
+		return nil, fmt.Errorf("failed to get bank accounts: %w", err)
 	}
 
 	responses := make([]models.BankAccountResponse, len(bankAccounts))
@@ -95,23 +87,16 @@ func (srv *bankAccountService) GetByUserID(userID uint) ([]models.BankAccountRes
 }
 
 func (srv *bankAccountService) Update(id uint, userID uint, req *models.UpdateBankAccountRequest) (*models.BankAccountResponse, error) {
-	bankAccount, err := srv.bankAccountRepo.GetByID(id)
+	bankAccount, err := getOwnedBankAccount(srv.bankAccountRepo, id, userID)
 	if err != nil {
-		if err.Error() == "record not found" {
-			return nil, fmt.Errorf("bank account not found")
-		}
-		log.Panicf("Failed to get bank account: %v", err)
-	}
-
-	if bankAccount.UserID != userID {
-		return nil, fmt.Errorf("unauthorized access to bank account")
+		return nil, err
 	}
 
 	bankAccount.BankCode = req.BankCode
 	bankAccount.AccountNumber = req.AccountNumber
 
 	if err := srv.bankAccountRepo.Update(bankAccount); err != nil {
-		log.Panicf("Failed to update bank account: %v", err)
+		return nil, fmt.Errorf("failed to update bank account: %w", err)
 	}
 
 	return &models.BankAccountResponse{
@@ -125,21 +110,30 @@ func (srv *bankAccountService) Update(id uint, userID uint, req *models.UpdateBa
 }
 
 func (srv *bankAccountService) Delete(id uint, userID uint) error {
-	bankAccount, err := srv.bankAccountRepo.GetByID(id)
-	if err != nil {
-		if err.Error() == "record not found" {
-			return fmt.Errorf("bank account not found")
-		}
-		log.Panicf("Failed to get bank account: %v", err)
-	}
-
-	if bankAccount.UserID != userID {
-		return fmt.Errorf("unauthorized access to bank account")
+	if _, err := getOwnedBankAccount(srv.bankAccountRepo, id, userID); err != nil {
+		return err
 	}
 
 	if err := srv.bankAccountRepo.Delete(id); err != nil {
-		log.Panicf("Failed to delete bank account: %v", err)
+		return fmt.Errorf("failed to delete bank account: %w", err)
 	}
 
 	return nil
 }
+
+// getOwnedBankAccount loads a bank account that must belong to userID.
+func getOwnedBankAccount(bankAccountRepo repos.BankAccountRepo, id uint, userID uint) (*entities.BankAccount, error) {
+	bankAccount, err := bankAccountRepo.GetByID(id)
+	if repos.IsNotFound(err) {
+		return nil, apperrors.NotFound("bank_account_not_found", "bank account not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bank account: %w", err)
+	}
+
+	if bankAccount.UserID != userID {
+		return nil, apperrors.Forbidden("bank_account_forbidden", "unauthorized access to bank account")
+	}
+
+	return bankAccount, nil
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/psp"
+	"banking-system/repos"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//go:generate mockgen -source=expiry.go -destination=mock/expiry.go
+
+const (
+	// DEFAULT_PENDING_TTL is how long a deposit or withdrawal may stay pending
+	// when neither PENDING_TTL nor PENDING_TTLS sets it.
+	DEFAULT_PENDING_TTL  = 24 * time.Hour
+	expirySweepBatchSize = 100
+	expirySweeper        = "expiry sweeper"
+)
+
+// ExpiryConfig sets how long deposits and withdrawals may stay pending before
+// the sweeper gives up on them.
+type ExpiryConfig struct {
+	DefaultTTL time.Duration
+
+	// TTLs overrides DefaultTTL for the transactions of a provider
+	TTLs map[psp.PaymentMethod]time.Duration
+
+	// QueryPSP asks the provider for the status of an expired deposit or
+	// payout before ending it, so that a deposit the payer did pay is credited
+	// and a payout the provider did make is not refunded. Without it, expired
+	// deposits are canceled and expired withdrawals are flagged for review
+	QueryPSP bool
+}
+
+// LoadExpiryConfig reads PENDING_TTL, the default time to live such as "24h",
+// PENDING_TTLS, a comma-separated list of provider=ttl overrides such as
+// "FakePay=30m,BankTransfer=72h", and PENDING_EXPIRY_QUERY_PSP (y/n, default
+// y).
+func LoadExpiryConfig() ExpiryConfig {
+	cfg := ExpiryConfig{
+		DefaultTTL: DEFAULT_PENDING_TTL,
+		TTLs:       map[psp.PaymentMethod]time.Duration{},
+		QueryPSP:   os.Getenv("PENDING_EXPIRY_QUERY_PSP") != "n",
+	}
+
+	if value := os.Getenv("PENDING_TTL"); value != "" {
+		cfg.DefaultTTL = parsePendingTTL("PENDING_TTL", value)
+	}
+
+	if value := os.Getenv("PENDING_TTLS"); value != "" {
+		for _, pair := range strings.Split(value, ",") {
+			provider, ttl, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || provider == "" {
+				log.Panicf("Invalid PENDING_TTLS entry '%s': expected provider=ttl", pair)
+			}
+			cfg.TTLs[psp.PaymentMethod(provider)] = parsePendingTTL("PENDING_TTLS", ttl)
+		}
+	}
+
+	return cfg
+}
+
+func parsePendingTTL(name string, value string) time.Duration {
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		log.Panicf("Invalid %s '%s': expected a positive duration such as 24h", name, value)
+	}
+	return ttl
+}
+
+// TTL returns how long transactions of the provider may stay pending.
+func (cfg ExpiryConfig) TTL(provider psp.PaymentMethod) time.Duration {
+	if ttl, ok := cfg.TTLs[provider]; ok {
+		return ttl
+	}
+	return cfg.DefaultTTL
+}
+
+type ExpiryService interface {
+	SweepExpired() error
+}
+
+type expiryService struct {
+	transactionRepo repos.TransactionRepo
+	pspFactory      psp.PSPFactory
+	payOuts         *payOutService
+	cfg             ExpiryConfig
+}
+
+func NewExpiryService(transactionRepo repos.TransactionRepo, pspFactory psp.PSPFactory, cfg ExpiryConfig) ExpiryService {
+	return &expiryService{
+		transactionRepo: transactionRepo,
+		pspFactory:      pspFactory,
+		payOuts:         &payOutService{transactionRepo: transactionRepo, pspFactory: pspFactory},
+		cfg:             cfg,
+	}
+}
+
+// SweepExpired ends the deposits and withdrawals that have been pending for
+// longer than their provider's TTL. Deposits are canceled and withdrawals are
+// failed and refunded, unless their provider reports them as still in
+// progress or already settled. A withdrawal is only failed on the provider's
+// word; if the provider is not asked, it stays pending and is flagged for
+// review.
+func (srv *expiryService) SweepExpired() error {
+	now := time.Now()
+	cutoffs := make(map[psp.PaymentMethod]time.Time, len(srv.cfg.TTLs))
+	for provider, ttl := range srv.cfg.TTLs {
+		cutoffs[provider] = now.Add(-ttl)
+	}
+
+	expired, err := srv.transactionRepo.GetExpiredPending(cutoffs, now.Add(-srv.cfg.DefaultTTL), expirySweepBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to get expired transactions: %w", err)
+	}
+
+	for i := range expired {
+		if err := srv.expire(&expired[i]); err != nil {
+			log.Warnf("Failed to expire transaction '%s': %v", expired[i].UUID, err)
+		}
+	}
+
+	return nil
+}
+
+func (srv *expiryService) expire(tx *entities.Transaction) error {
+	reason := fmt.Sprintf("pending for longer than %s", srv.cfg.TTL(tx.PaymentMethod))
+	trigger := entities.SystemTrigger(expirySweeper, reason)
+
+	if tx.Type == entities.TransactionTypes.Deposit {
+		return srv.expireDeposit(tx, trigger)
+	}
+	return srv.expireWithdrawal(tx, trigger)
+}
+
+func (srv *expiryService) expireDeposit(tx *entities.Transaction, trigger entities.Trigger) error {
+	if srv.cfg.QueryPSP {
+		provider, err := getProvider(srv.pspFactory, tx.PaymentMethod)
+		if err != nil {
+			return err
+		}
+
+		res, err := provider.GetPayInStatus(&psp.PayInStatusRequest{
+			TransactionID: tx.UUID.String(),
+		})
+
+		switch {
+		case errors.Is(err, psp.ErrPayInNotFound):
+			// The payer never started paying; cancel it below
+		case err != nil:
+			return apperrors.ProviderUnavailable(fmt.Sprintf("payment provider '%s'", tx.PaymentMethod), err)
+		case res.Status == psp.PayInStatuses.Paid:
+			// The confirm callback was lost
+			trigger.Reason = fmt.Sprintf("%s; paid according to provider", trigger.Reason)
+			return applyTransition(srv.transactionRepo, tx, entities.TransactionStatuses.Completed, trigger)
+		case res.Status == psp.PayInStatuses.Pending:
+			log.Infof("Not expiring transaction '%s': its pay-in is still pending at the provider", tx.UUID)
+			return nil
+		}
+	}
+
+	return applyTransition(srv.transactionRepo, tx, entities.TransactionStatuses.Canceled, trigger)
+}
+
+func (srv *expiryService) expireWithdrawal(tx *entities.Transaction, trigger entities.Trigger) error {
+	if srv.cfg.QueryPSP {
+		provider, err := getProvider(srv.pspFactory, tx.PaymentMethod)
+		if err != nil {
+			return err
+		}
+
+		res, err := provider.GetPayOutStatus(&psp.PayOutStatusRequest{
+			TransactionID: tx.UUID.String(),
+			Reference:     tx.PSPReference,
+		})
+
+		switch {
+		case errors.Is(err, psp.ErrPayOutNotFound):
+			// The provider never accepted the payout; fail it below
+		case err != nil:
+			return apperrors.ProviderUnavailable(fmt.Sprintf("payment provider '%s'", tx.PaymentMethod), err)
+		case res.Status.IsFinal():
+			trigger.Reason = fmt.Sprintf("%s; payout %s according to provider", trigger.Reason, res.Status)
+			return srv.payOuts.applyStatus(tx.UUID.String(), res.Status, res.Reference, trigger, nil)
+		default:
+			log.Infof("Not expiring transaction '%s': its payout is still %s at the provider", tx.UUID, res.Status)
+			return nil
+		}
+
+		return applyTransition(srv.transactionRepo, tx, entities.TransactionStatuses.Failed, trigger)
+	}
+
+	// The provider may still pay the payout out, so refunding it could pay the
+	// amount twice
+	if err := srv.transactionRepo.FlagForReview(tx.UUID); err != nil {
+		return fmt.Errorf("failed to flag transaction for review: %w", err)
+	}
+	log.Warnf("Withdrawal '%s' is %s; flagged for review", tx.UUID, trigger.Reason)
+	return nil
+}
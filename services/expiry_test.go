@@ -0,0 +1,195 @@
+package services_test
+
+import (
+	"banking-system/entities"
+	"banking-system/psp"
+	"banking-system/services"
+	"testing"
+	"time"
+
+	pspMock "banking-system/psp/mock"
+	repoMock "banking-system/repos/mock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var expiryConfig = services.ExpiryConfig{
+	DefaultTTL: 24 * time.Hour,
+	TTLs:       map[psp.PaymentMethod]time.Duration{psp.PaymentMethods.FakePay: 30 * time.Minute},
+	QueryPSP:   true,
+}
+
+func TestExpiry_UsesProviderTTL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	transactionRepoMock.EXPECT().
+		GetExpiredPending(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(cutoffs map[psp.PaymentMethod]time.Time, defaultCutoff time.Time, limit int) ([]entities.Transaction, error) {
+			assert.WithinDuration(t, time.Now().Add(-30*time.Minute), cutoffs[psp.PaymentMethods.FakePay], time.Second)
+			assert.WithinDuration(t, time.Now().Add(-24*time.Hour), defaultCutoff, time.Second)
+			return nil, nil
+		})
+
+	sut := services.NewExpiryService(transactionRepoMock, pspFactoryMock, expiryConfig)
+	assert.Nil(t, sut.SweepExpired())
+}
+
+func TestExpiry_CancelsDepositUnknownToProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
+
+	tx := newExpiredTransaction(entities.TransactionTypes.Deposit)
+	givenExpiredPending(tx)
+	givenPayInStatus(nil, psp.ErrPayInNotFound)
+	transactionRepoMock.EXPECT().
+		ApplyTransition(gomock.Any(), transitionTo(entities.TransactionStatuses.Canceled, entities.TransitionActors.System)).
+		Return(true, nil)
+
+	sut := services.NewExpiryService(transactionRepoMock, pspFactoryMock, expiryConfig)
+	assert.Nil(t, sut.SweepExpired())
+}
+
+func TestExpiry_CompletesDepositPaidAtProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
+
+	tx := newExpiredTransaction(entities.TransactionTypes.Deposit)
+	givenExpiredPending(tx)
+	givenPayInStatus(&psp.PayInStatusResponse{Status: psp.PayInStatuses.Paid}, nil)
+	transactionRepoMock.EXPECT().
+		ApplyTransition(gomock.Any(), transitionTo(entities.TransactionStatuses.Completed, entities.TransitionActors.System)).
+		Return(true, nil)
+
+	sut := services.NewExpiryService(transactionRepoMock, pspFactoryMock, expiryConfig)
+	assert.Nil(t, sut.SweepExpired())
+}
+
+func TestExpiry_KeepsDepositPendingAtProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
+
+	// ApplyTransition must not be called
+	tx := newExpiredTransaction(entities.TransactionTypes.Deposit)
+	givenExpiredPending(tx)
+	givenPayInStatus(&psp.PayInStatusResponse{Status: psp.PayInStatuses.Pending}, nil)
+
+	sut := services.NewExpiryService(transactionRepoMock, pspFactoryMock, expiryConfig)
+	assert.Nil(t, sut.SweepExpired())
+}
+
+func TestExpiry_CancelsDepositWithoutAskingProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	tx := newExpiredTransaction(entities.TransactionTypes.Deposit)
+	givenExpiredPending(tx)
+	transactionRepoMock.EXPECT().
+		ApplyTransition(gomock.Any(), transitionTo(entities.TransactionStatuses.Canceled, entities.TransitionActors.System)).
+		Return(true, nil)
+
+	cfg := expiryConfig
+	cfg.QueryPSP = false
+	sut := services.NewExpiryService(transactionRepoMock, pspFactoryMock, cfg)
+	assert.Nil(t, sut.SweepExpired())
+}
+
+func TestExpiry_FailsWithdrawalUnknownToProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
+
+	tx := newExpiredTransaction(entities.TransactionTypes.Withdrawal)
+	givenExpiredPending(tx)
+	givenPayOutStatus(nil, psp.ErrPayOutNotFound)
+	transactionRepoMock.EXPECT().
+		ApplyTransition(gomock.Any(), refundOf(tx)).
+		Return(true, nil)
+
+	sut := services.NewExpiryService(transactionRepoMock, pspFactoryMock, expiryConfig)
+	assert.Nil(t, sut.SweepExpired())
+}
+
+func TestExpiry_KeepsWithdrawalInProgress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
+
+	// ApplyTransition and UpdatePayOut must not be called
+	tx := newExpiredTransaction(entities.TransactionTypes.Withdrawal)
+	givenExpiredPending(tx)
+	givenPayOutStatus(&psp.PayOutStatusResponse{Status: psp.PayOutStatuses.Processing}, nil)
+
+	sut := services.NewExpiryService(transactionRepoMock, pspFactoryMock, expiryConfig)
+	assert.Nil(t, sut.SweepExpired())
+}
+
+func TestExpiry_CompletesWithdrawalPaidByProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
+
+	tx := newExpiredTransaction(entities.TransactionTypes.Withdrawal)
+	givenExpiredPending(tx)
+	givenPayOutStatus(&psp.PayOutStatusResponse{Status: psp.PayOutStatuses.Paid}, nil)
+	transactionRepoMock.EXPECT().GetByUUID(tx.UUID).Return(tx, nil)
+	transactionRepoMock.EXPECT().
+		UpdatePayOut(gomock.Any(), psp.PayOutStatuses.Submitted, transitionTo(entities.TransactionStatuses.Completed, entities.TransitionActors.System)).
+		Return(true, nil)
+
+	sut := services.NewExpiryService(transactionRepoMock, pspFactoryMock, expiryConfig)
+	assert.Nil(t, sut.SweepExpired())
+}
+
+func TestExpiry_FlagsWithdrawalForReviewWithoutAskingProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	// ApplyTransition must not be called: the payout may still be made
+	tx := newExpiredTransaction(entities.TransactionTypes.Withdrawal)
+	givenExpiredPending(tx)
+	transactionRepoMock.EXPECT().FlagForReview(tx.UUID).Return(nil)
+
+	cfg := expiryConfig
+	cfg.QueryPSP = false
+	sut := services.NewExpiryService(transactionRepoMock, pspFactoryMock, cfg)
+	assert.Nil(t, sut.SweepExpired())
+}
+
+func newExpiredTransaction(txType entities.TransactionType) *entities.Transaction {
+	tx := newPayOut(psp.PayOutStatuses.Submitted)
+	tx.Type = txType
+	if txType != entities.TransactionTypes.Withdrawal {
+		tx.PayOutStatus = ""
+	}
+	return tx
+}
+
+func givenExpiredPending(tx *entities.Transaction) {
+	transactionRepoMock.EXPECT().
+		GetExpiredPending(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]entities.Transaction{*tx}, nil)
+}
+
+func givenPayInStatus(res *psp.PayInStatusResponse, err error) {
+	pspFactoryMock.EXPECT().NewPaymentServiceProvider(psp.PaymentMethods.FakePay).Return(paymentProviderMock, nil)
+	paymentProviderMock.EXPECT().GetPayInStatus(gomock.Any()).Return(res, err)
+}
+
+func givenPayOutStatus(res *psp.PayOutStatusResponse, err error) {
+	pspFactoryMock.EXPECT().NewPaymentServiceProvider(psp.PaymentMethods.FakePay).Return(paymentProviderMock, nil)
+	paymentProviderMock.EXPECT().GetPayOutStatus(gomock.Any()).Return(res, err)
+}
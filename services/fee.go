@@ -0,0 +1,116 @@
+package services
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/fees"
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/psp"
+	"banking-system/repos"
+	"fmt"
+)
+
+//go:generate mockgen -source=fee.go -destination=mock/fee.go
+
+type FeeService interface {
+	Quote(req *models.FeeQuoteRequest) (*models.FeeQuoteResponse, error)
+}
+
+type feeService struct {
+	userRepo  repos.UserRepo
+	schedules fees.Schedules
+}
+
+func NewFeeService(userRepo repos.UserRepo, schedules fees.Schedules) FeeService {
+	return &feeService{
+		userRepo:  userRepo,
+		schedules: schedules,
+	}
+}
+
+// Quote previews the fee the user would be charged, without reserving it;
+// the fee is calculated again when the transaction is made.
+func (srv *feeService) Quote(req *models.FeeQuoteRequest) (*models.FeeQuoteResponse, error) {
+	currency := currencyOrDefault(req.Currency)
+	if !money.IsSupported(currency) {
+		return nil, apperrors.Validation("unsupported_currency", "currency '%s' is not supported", currency)
+	}
+
+	amount := req.Amount.WithCurrency(currency)
+	if !amount.IsPositive() {
+		return nil, apperrors.Validation("invalid_amount", "amount must be greater than zero")
+	}
+	if err := checkPrecision(amount); err != nil {
+		return nil, err
+	}
+
+	user, err := getUser(srv.userRepo, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := calculateFee(srv.schedules, req.Operation, req.PaymentMethod, user, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	var total money.Money
+	if req.Operation == fees.Operations.Deposit {
+		total, err = amount.Sub(fee)
+	} else {
+		total, err = amount.Add(fee)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate total: %w", err)
+	}
+
+	return &models.FeeQuoteResponse{
+		Operation: req.Operation,
+		Amount:    amount,
+		Fee:       fee,
+		Currency:  currency,
+		Total:     total,
+	}, nil
+}
+
+// calculateFee returns the fee the schedules charge user for amount.
+func calculateFee(schedules fees.Schedules, operation fees.Operation, paymentMethod psp.PaymentMethod, user *entities.User, amount money.Money) (money.Money, error) {
+	quote, err := schedules.Calculate(fees.Criteria{
+		Operation:     operation,
+		PaymentMethod: paymentMethod,
+		Currency:      amount.Currency(),
+		Tier:          string(user.Tier),
+	}, amount)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("failed to calculate fee: %w", err)
+	}
+	return quote.Fee, nil
+}
+
+// withFeeEntry adds the journal entry that collects the fee of tx, if it is
+// charged one, to the entry that moves its amount.
+func withFeeEntry(tx *entities.Transaction, entry *entities.JournalEntry) []*entities.JournalEntry {
+	entries := []*entities.JournalEntry{entry}
+	if tx.FeeTransaction != nil {
+		entries = append(entries, entities.NewFeeEntry(tx.FeeTransaction))
+	}
+	return entries
+}
+
+// checkBalance reports whether the wallet can pay amount and its fee.
+func checkBalance(wallet *entities.Wallet, amount money.Money, fee money.Money) error {
+	total, err := amount.Add(fee)
+	if err != nil {
+		return fmt.Errorf("failed to add fee: %w", err)
+	}
+
+	if !wallet.Balance.LessThan(total) {
+		return nil
+	}
+
+	if fee.IsPositive() {
+		return apperrors.InsufficientFunds("insufficient balance: current balance %s, requested amount %s plus fee %s", wallet.Balance, amount, fee)
+	}
+	return apperrors.InsufficientFunds("insufficient balance: current balance %s, requested amount %s", wallet.Balance, amount)
+}
@@ -0,0 +1,222 @@
+package services_test
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/fees"
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/psp"
+	"banking-system/services"
+	"fmt"
+	"testing"
+
+	pspMock "banking-system/psp/mock"
+	repoMock "banking-system/repos/mock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var testFeeSchedules = fees.MustParse([]fees.Schedule{
+	{ID: "deposit", Operation: fees.Operations.Deposit, Currency: "TWD", Percent: "1"},
+	{ID: "withdrawal", Operation: fees.Operations.Withdrawal, Currency: "TWD", Fixed: "15"},
+	{ID: "transfer", Operation: fees.Operations.Transfer, Currency: "TWD", Percent: "0.5", Min: "5"},
+})
+
+func TestFeeQuote_Withdrawal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	givenUserHasBalance(1, "0")
+
+	sut := services.NewFeeService(userRepoMock, testFeeSchedules)
+	quote, err := sut.Quote(&models.FeeQuoteRequest{
+		UserID:    1,
+		Operation: fees.Operations.Withdrawal,
+		Amount:    money.MustParse("100", "TWD"),
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, money.MustParse("15", "TWD"), quote.Fee)
+	assert.Equal(t, money.MustParse("115", "TWD"), quote.Total)
+	assert.Equal(t, "TWD", quote.Currency)
+}
+
+func TestFeeQuote_DepositIsCreditedNetOfFee(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	givenUserHasBalance(1, "0")
+
+	sut := services.NewFeeService(userRepoMock, testFeeSchedules)
+	quote, err := sut.Quote(&models.FeeQuoteRequest{
+		UserID:    1,
+		Operation: fees.Operations.Deposit,
+		Amount:    money.MustParse("200", "TWD"),
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, money.MustParse("2", "TWD"), quote.Fee)
+	assert.Equal(t, money.MustParse("198", "TWD"), quote.Total)
+}
+
+func TestFeeQuote_InvalidAmount(t *testing.T) {
+	sut := services.NewFeeService(nil, testFeeSchedules)
+	_, err := sut.Quote(&models.FeeQuoteRequest{UserID: 1, Operation: fees.Operations.Transfer})
+
+	assert.Equal(t, "invalid_amount", apperrors.CodeOf(err))
+}
+
+func TestWithdraw_ChargesFee(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
+
+	req := &models.WithdrawRequest{
+		UUID:          uuid.New(),
+		UserID:        1,
+		Amount:        money.MustParse("50.00", "TWD"),
+		BankAccountID: 7,
+	}
+
+	givenUserHasBalance(req.UserID, "65.00")
+	givenBankAccount(req.BankAccountID, req.UserID)
+	transactionRepoMock.EXPECT().
+		CreateWithJournalEntry(withFee(money.MustParse("15", "TWD"), entities.TransactionStatuses.Completed), gomock.Any(), gomock.Any()).
+		Return(nil).
+		Times(1)
+	expectPayOutCalled()
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, testFeeSchedules, unlimited(t))
+	err := sut.Withdraw(req)
+
+	assert.Nil(t, err)
+}
+
+func TestWithdraw_BalanceDoesNotCoverFee(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	req := &models.WithdrawRequest{
+		UUID:          uuid.New(),
+		UserID:        1,
+		Amount:        money.MustParse("100.00", "TWD"),
+		BankAccountID: 7,
+	}
+
+	givenSupportedPaymentMethod()
+	givenUserHasBalance(req.UserID, "100.00")
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, testFeeSchedules, unlimited(t))
+	err := sut.Withdraw(req)
+
+	assert.Equal(t, apperrors.Kinds.InsufficientFunds, apperrors.KindOf(err))
+	assert.Contains(t, err.Error(), "plus fee 15.00")
+}
+
+func TestDeposit_FeeIsPendingUntilConfirmed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
+
+	givenUserHasBalance(1, "0")
+	givenPayInResponse("https://doesnt.matter", nil)
+	transactionRepoMock.EXPECT().
+		Create(withFee(money.MustParse("1", "TWD"), entities.TransactionStatuses.Pending)).
+		Return(nil).
+		Times(1)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, testFeeSchedules, unlimited(t))
+	_, err := sut.Deposit(&models.DepositRequest{
+		UUID:          uuid.New(),
+		UserID:        1,
+		Amount:        money.MustParse("100.00", "TWD"),
+		PaymentMethod: psp.PaymentMethods.FakePay,
+	})
+
+	assert.Nil(t, err)
+}
+
+func TestConfirm_CollectsDepositFee(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+
+	tx := givenStoredTransaction(entities.TransactionTypes.Deposit, entities.TransactionStatuses.Pending)
+	tx.AttachFee(money.MustParse("0.50", "TWD"))
+	transactionRepoMock.EXPECT().
+		ApplyTransition(tx, withLinkedFee(entities.TransactionStatuses.Completed, entities.LedgerAccountTypes.Fees)).
+		Return(true, nil)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	err := sut.Confirm(&psp.ConfirmRequest{TransactionID: tx.UUID.String(), Provider: psp.PaymentMethods.FakePay})
+
+	assert.Nil(t, err)
+	assert.Equal(t, entities.TransactionStatuses.Completed, tx.FeeTransaction.Status)
+}
+
+func TestPayOutRejected_ReturnsWithdrawalFee(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	tx := givenPayOut(psp.PayOutStatuses.Submitted)
+	tx.AttachFee(money.MustParse("15", "TWD"))
+	transactionRepoMock.EXPECT().
+		UpdatePayOut(tx, psp.PayOutStatuses.Submitted, withLinkedFee(entities.TransactionStatuses.Failed, entities.LedgerAccountTypes.Wallet)).
+		Return(true, nil)
+
+	sut := services.NewPayOutService(transactionRepoMock, pspFactoryMock)
+	err := sut.UpdateStatus(payOutCallback(tx, psp.PayOutStatuses.Rejected))
+
+	assert.Nil(t, err)
+	assert.Equal(t, entities.TransactionStatuses.Failed, tx.FeeTransaction.Status)
+}
+
+// withFee matches a transaction that is charged fee by a fee transaction in
+// status.
+func withFee(fee money.Money, status entities.TransactionStatus) gomock.Matcher {
+	return matcher{
+		description: fmt.Sprintf("transaction with %s fee %s", status, fee),
+		matches: func(x any) bool {
+			tx, ok := x.(*entities.Transaction)
+			return ok && tx.FeeAmount == fee && tx.FeeTransaction != nil &&
+				tx.FeeTransaction.Amount == fee &&
+				tx.FeeTransaction.Status == status &&
+				*tx.FeeTransaction.RelatedTransactionID == tx.UUID
+		},
+	}
+}
+
+// withLinkedFee matches a transition that moves the transaction's fee to
+// status, crediting the fee to an account of the given type.
+func withLinkedFee(status entities.TransactionStatus, creditedTo entities.LedgerAccountType) gomock.Matcher {
+	return matcher{
+		description: fmt.Sprintf("transition with fee moving to %s and credited to %s", status, creditedTo),
+		matches: func(x any) bool {
+			t, ok := x.(*entities.StatusTransition)
+			if !ok || t == nil || len(t.Linked) != 1 {
+				return false
+			}
+
+			fee := t.Linked[0]
+			if fee.To != status || fee.Entry == nil {
+				return false
+			}
+			for _, posting := range fee.Entry.Postings {
+				if posting.LedgerAccount.Type == creditedTo && posting.Amount.IsPositive() {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/fx"
+	"banking-system/models"
+	"banking-system/repos"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//go:generate mockgen -source=fx.go -destination=mock/fx.go
+
+const FX_QUOTE_TTL = 30 * time.Second
+
+type FXService interface {
+	Quote(req *models.FXQuoteRequest) (*models.FXQuoteResponse, error)
+	Convert(req *models.ConvertRequest) error
+}
+
+type fxService struct {
+	userRepo    repos.UserRepo
+	fxQuoteRepo repos.FXQuoteRepo
+	rateSource  fx.RateSource
+}
+
+func NewFXService(userRepo repos.UserRepo, fxQuoteRepo repos.FXQuoteRepo, rateSource fx.RateSource) FXService {
+	return &fxService{
+		userRepo:    userRepo,
+		fxQuoteRepo: fxQuoteRepo,
+		rateSource:  rateSource,
+	}
+}
+
+func (srv *fxService) Quote(req *models.FXQuoteRequest) (*models.FXQuoteResponse, error) {
+	if req.FromCurrency == req.ToCurrency {
+		return nil, apperrors.Validation("same_currency_conversion", "cannot convert %s to itself", req.FromCurrency)
+	}
+
+	fromAmount := req.Amount.WithCurrency(req.FromCurrency)
+	if !fromAmount.IsPositive() {
+		return nil, apperrors.Validation("invalid_amount", "conversion amount must be greater than zero")
+	}
+	if err := checkPrecision(fromAmount); err != nil {
+		return nil, err
+	}
+
+	user, err := getUser(srv.userRepo, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := user.WalletFor(req.FromCurrency); !ok {
+		return nil, apperrors.NotFound("wallet_not_found", "user has no %s wallet", req.FromCurrency)
+	}
+
+	if _, ok := user.WalletFor(req.ToCurrency); !ok {
+		return nil, apperrors.NotFound("wallet_not_found", "user has no %s wallet", req.ToCurrency)
+	}
+
+	rate, err := srv.rateSource.GetRate(req.FromCurrency, req.ToCurrency)
+	if errors.Is(err, fx.ErrNoRate) {
+		return nil, apperrors.Validation("unsupported_currency_pair", "%v", err)
+	}
+	if err != nil {
+		return nil, apperrors.ProviderUnavailable("FX rate source", err)
+	}
+
+	converted, err := fromAmount.WithCurrency(req.ToCurrency).Mul(rate)
+	if err != nil {
+		return nil, apperrors.Validation("invalid_amount", "conversion amount %s is out of range", fromAmount)
+	}
+
+	toAmount := converted.Round()
+	if !toAmount.IsPositive() {
+		return nil, apperrors.Validation("invalid_amount", "conversion amount %s is too small", fromAmount)
+	}
+
+	quote := &entities.FXQuote{
+		UUID:         uuid.New(),
+		UserID:       req.UserID,
+		FromCurrency: req.FromCurrency,
+		ToCurrency:   req.ToCurrency,
+		FromAmount:   fromAmount,
+		ToAmount:     toAmount,
+		Rate:         rate.FloatString(10),
+		Status:       entities.FXQuoteStatuses.Open,
+		ExpiresAt:    time.Now().Add(FX_QUOTE_TTL),
+	}
+
+	if err := srv.fxQuoteRepo.Create(quote); err != nil {
+		return nil, fmt.Errorf("failed to create FX quote: %w", err)
+	}
+
+	return &models.FXQuoteResponse{
+		QuoteID:      quote.UUID,
+		FromCurrency: quote.FromCurrency,
+		ToCurrency:   quote.ToCurrency,
+		FromAmount:   quote.FromAmount,
+		ToAmount:     quote.ToAmount,
+		Rate:         quote.Rate,
+		ExpiresAt:    quote.ExpiresAt,
+	}, nil
+}
+
+func (srv *fxService) Convert(req *models.ConvertRequest) error {
+	quote, err := srv.fxQuoteRepo.GetByUUID(req.QuoteID)
+	if repos.IsNotFound(err) {
+		return apperrors.NotFound("quote_not_found", "quote not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get FX quote: %w", err)
+	}
+
+	// Another user's quote is reported as missing rather than forbidden
+	if quote.UserID != req.UserID {
+		return apperrors.NotFound("quote_not_found", "quote not found")
+	}
+
+	if quote.Status != entities.FXQuoteStatuses.Open {
+		return apperrors.Conflict("quote_used", "quote has already been used")
+	}
+
+	if quote.IsExpired(time.Now()) {
+		return apperrors.Conflict("quote_expired", "quote has expired")
+	}
+
+	user, err := getUser(srv.userRepo, req.UserID)
+	if err != nil {
+		return err
+	}
+
+	fromWallet, ok := user.WalletFor(quote.FromCurrency)
+	if !ok {
+		return apperrors.NotFound("wallet_not_found", "user has no %s wallet", quote.FromCurrency)
+	}
+
+	toWallet, ok := user.WalletFor(quote.ToCurrency)
+	if !ok {
+		return apperrors.NotFound("wallet_not_found", "user has no %s wallet", quote.ToCurrency)
+	}
+
+	if fromWallet.Balance.LessThan(quote.FromAmount) {
+		return apperrors.InsufficientFunds("insufficient balance: current balance %s, requested amount %s", fromWallet.Balance, quote.FromAmount)
+	}
+
+	conversionOutTx := &entities.Transaction{
+		UUID:     req.UUID,
+		WalletID: fromWallet.ID,
+		Amount:   quote.FromAmount,
+		Status:   entities.TransactionStatuses.Completed,
+		Type:     entities.TransactionTypes.ConversionOut,
+		Wallet:   fromWallet,
+	}
+
+	conversionInTx := &entities.Transaction{
+		UUID:     uuid.New(),
+		WalletID: toWallet.ID,
+		Amount:   quote.ToAmount,
+		Status:   entities.TransactionStatuses.Completed,
+		Type:     entities.TransactionTypes.ConversionIn,
+		Wallet:   toWallet,
+	}
+
+	entries := entities.NewConversionEntries(conversionOutTx, conversionInTx)
+	executed, err := srv.fxQuoteRepo.Execute(quote, conversionOutTx, conversionInTx, entries)
+	if errors.Is(err, repos.ErrInsufficientFunds) {
+		return apperrors.InsufficientFunds("insufficient balance: %s wallet cannot cover %s", quote.FromCurrency, quote.FromAmount)
+	}
+	if repos.IsDuplicateKey(err) {
+		return apperrors.Conflict("duplicate_transaction", "a transaction with this UUID already exists")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to execute conversion: %w", err)
+	}
+
+	if !executed {
+		return apperrors.Conflict("quote_unavailable", "quote has already been used or has expired")
+	}
+
+	return nil
+}
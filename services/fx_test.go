@@ -0,0 +1,96 @@
+package services_test
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/fx"
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/services"
+	"testing"
+	"time"
+
+	repoMock "banking-system/repos/mock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFXQuote_LocksConvertedAmount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	fxQuoteRepoMock := repoMock.NewMockFXQuoteRepo(ctrl)
+
+	givenUserHasWallets(money.MustParse("100", "USD"), money.MustParse("0", "TWD"))
+	fxQuoteRepoMock.EXPECT().Create(gomock.Any()).Times(1)
+
+	sut := services.NewFXService(userRepoMock, fxQuoteRepoMock, fx.NewStaticRateSource(map[string]string{"USD/TWD": "32.123"}))
+	quote, err := sut.Quote(&models.FXQuoteRequest{
+		UserID:       1,
+		FromCurrency: "USD",
+		ToCurrency:   "TWD",
+		Amount:       money.MustParse("10", ""),
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, money.MustParse("321.23", "TWD"), quote.ToAmount)
+	assert.WithinDuration(t, time.Now().Add(services.FX_QUOTE_TTL), quote.ExpiresAt, time.Second)
+}
+
+func TestFXQuote_MissingTargetWallet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	fxQuoteRepoMock := repoMock.NewMockFXQuoteRepo(ctrl)
+
+	givenUserHasWallets(money.MustParse("100", "USD"))
+
+	sut := services.NewFXService(userRepoMock, fxQuoteRepoMock, fx.NewStaticRateSource(fx.DefaultRates))
+	_, err := sut.Quote(&models.FXQuoteRequest{
+		UserID:       1,
+		FromCurrency: "USD",
+		ToCurrency:   "TWD",
+		Amount:       money.MustParse("10", ""),
+	})
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "no TWD wallet")
+}
+
+func TestFXConvert_ExpiredQuote(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	fxQuoteRepoMock := repoMock.NewMockFXQuoteRepo(ctrl)
+
+	quoteID := uuid.New()
+	fxQuoteRepoMock.EXPECT().GetByUUID(quoteID).Return(&entities.FXQuote{
+		UUID:      quoteID,
+		UserID:    1,
+		Status:    entities.FXQuoteStatuses.Open,
+		ExpiresAt: time.Now().Add(-time.Second),
+	}, nil)
+
+	sut := services.NewFXService(userRepoMock, fxQuoteRepoMock, fx.NewStaticRateSource(fx.DefaultRates))
+	err := sut.Convert(&models.ConvertRequest{UUID: uuid.New(), UserID: 1, QuoteID: quoteID})
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "expired")
+	assert.Equal(t, apperrors.Kinds.Conflict, apperrors.KindOf(err))
+}
+
+func givenUserHasWallets(balances ...money.Money) {
+	user := &entities.User{}
+	for i, balance := range balances {
+		user.Wallets = append(user.Wallets, entities.Wallet{
+			UserID:   1,
+			Currency: balance.Currency(),
+			Balance:  balance,
+		})
+		user.Wallets[i].ID = uint(i + 1)
+	}
+
+	userRepoMock.EXPECT().
+		Get(gomock.Any()).
+		Return(user, nil).
+		AnyTimes()
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repos"
+	"fmt"
+)
+
+//go:generate mockgen -source=ledger.go -destination=mock/ledger.go
+
+type LedgerService interface {
+	Reconcile() (*models.LedgerReconciliationResponse, error)
+	GetEntriesByWalletID(walletID uint) ([]models.JournalEntryResponse, error)
+}
+
+type ledgerService struct {
+	ledgerRepo repos.LedgerRepo
+}
+
+func NewLedgerService(ledgerRepo repos.LedgerRepo) LedgerService {
+	return &ledgerService{
+		ledgerRepo: ledgerRepo,
+	}
+}
+
+// Reconcile proves that every ledger account balance equals the sum of its
+// postings, that every wallet balance equals its ledger account, and that the
+// ledger as a whole sums to zero in every currency.
+func (srv *ledgerService) Reconcile() (*models.LedgerReconciliationResponse, error) {
+	accounts, err := srv.ledgerRepo.GetAccounts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger accounts: %w", err)
+	}
+
+	sums, err := srv.ledgerRepo.GetPostingSums()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum ledger postings: %w", err)
+	}
+
+	wallets, err := srv.ledgerRepo.GetWallets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallets: %w", err)
+	}
+
+	res := &models.LedgerReconciliationResponse{
+		TrialBalances: map[string]money.Money{},
+		Discrepancies: []models.LedgerDiscrepancy{},
+	}
+
+	walletAccounts := make(map[uint]entities.LedgerAccount)
+	for _, account := range accounts {
+		sum, ok := sums[account.ID]
+		if !ok {
+			sum = money.Zero(account.Currency)
+		}
+
+		total, ok := res.TrialBalances[account.Currency]
+		if !ok {
+			total = money.Zero(account.Currency)
+		}
+		if res.TrialBalances[account.Currency], err = total.Add(sum); err != nil {
+			return nil, fmt.Errorf("failed to total ledger account '%s': %w", account.Code, err)
+		}
+
+		if sum != account.Balance {
+			res.Discrepancies = append(res.Discrepancies, models.LedgerDiscrepancy{
+				AccountCode: account.Code,
+				Expected:    sum,
+				Actual:      account.Balance,
+				Currency:    account.Currency,
+				Reason:      "account balance does not match sum of postings",
+			})
+		}
+
+		if account.WalletID != nil {
+			walletAccounts[*account.WalletID] = account
+		}
+	}
+
+	for _, wallet := range wallets {
+		expected := money.Zero(wallet.Currency)
+		if account, ok := walletAccounts[wallet.ID]; ok {
+			expected = account.Balance
+		}
+
+		if wallet.Balance != expected {
+			res.Discrepancies = append(res.Discrepancies, models.LedgerDiscrepancy{
+				AccountCode: entities.WalletAccountCode(wallet.ID),
+				Expected:    expected,
+				Actual:      wallet.Balance,
+				Currency:    wallet.Currency,
+				Reason:      "wallet balance does not match ledger account",
+			})
+		}
+	}
+
+	res.Balanced = len(res.Discrepancies) == 0
+	for _, total := range res.TrialBalances {
+		res.Balanced = res.Balanced && total.IsZero()
+	}
+
+	return res, nil
+}
+
+func (srv *ledgerService) GetEntriesByWalletID(walletID uint) ([]models.JournalEntryResponse, error) {
+	entries, err := srv.ledgerRepo.GetEntriesByAccountCode(entities.WalletAccountCode(walletID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get journal entries for wallet %d: %w", walletID, err)
+	}
+
+	responses := make([]models.JournalEntryResponse, len(entries))
+	for i, entry := range entries {
+		postings := make([]models.PostingResponse, len(entry.Postings))
+		for j, p := range entry.Postings {
+			postings[j] = models.PostingResponse{
+				AccountCode: p.LedgerAccount.Code,
+				Amount:      p.Amount,
+				Currency:    p.Currency,
+			}
+		}
+
+		responses[i] = models.JournalEntryResponse{
+			UUID:          entry.UUID,
+			TransactionID: entry.TransactionID,
+			Description:   entry.Description,
+			Postings:      postings,
+			CreatedAt:     entry.CreatedAt,
+		}
+	}
+
+	return responses, nil
+}
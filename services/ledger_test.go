@@ -0,0 +1,63 @@
+package services_test
+
+import (
+	"banking-system/entities"
+	"banking-system/money"
+	"banking-system/services"
+	"testing"
+
+	repoMock "banking-system/repos/mock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestReconcile_Balanced(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ledgerRepoMock := repoMock.NewMockLedgerRepo(ctrl)
+
+	walletID := uint(7)
+	ledgerRepoMock.EXPECT().GetAccounts().Return([]entities.LedgerAccount{
+		{Model: gorm.Model{ID: 1}, Code: entities.WalletAccountCode(walletID), Currency: "TWD", Balance: twd("150"), WalletID: &walletID},
+		{Model: gorm.Model{ID: 2}, Code: "psp_clearing:FakePay:TWD", Currency: "TWD", Balance: twd("-150")},
+	}, nil)
+	ledgerRepoMock.EXPECT().GetPostingSums().Return(map[uint]money.Money{1: twd("150"), 2: twd("-150")}, nil)
+	ledgerRepoMock.EXPECT().GetWallets().Return([]entities.Wallet{
+		{Model: gorm.Model{ID: walletID}, Currency: "TWD", Balance: twd("150")},
+	}, nil)
+
+	sut := services.NewLedgerService(ledgerRepoMock)
+	report, err := sut.Reconcile()
+
+	assert.Nil(t, err)
+	assert.True(t, report.Balanced)
+	assert.Empty(t, report.Discrepancies)
+}
+
+func TestReconcile_WalletDriftedFromLedger(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ledgerRepoMock := repoMock.NewMockLedgerRepo(ctrl)
+
+	walletID := uint(7)
+	ledgerRepoMock.EXPECT().GetAccounts().Return([]entities.LedgerAccount{
+		{Model: gorm.Model{ID: 1}, Code: entities.WalletAccountCode(walletID), Currency: "TWD", Balance: twd("150"), WalletID: &walletID},
+		{Model: gorm.Model{ID: 2}, Code: "suspense:TWD", Currency: "TWD", Balance: twd("-150")},
+	}, nil)
+	ledgerRepoMock.EXPECT().GetPostingSums().Return(map[uint]money.Money{1: twd("150"), 2: twd("-150")}, nil)
+	ledgerRepoMock.EXPECT().GetWallets().Return([]entities.Wallet{
+		{Model: gorm.Model{ID: walletID}, Currency: "TWD", Balance: twd("200")},
+	}, nil)
+
+	sut := services.NewLedgerService(ledgerRepoMock)
+	report, err := sut.Reconcile()
+
+	assert.Nil(t, err)
+	assert.False(t, report.Balanced)
+	assert.Len(t, report.Discrepancies, 1)
+	assert.Equal(t, entities.WalletAccountCode(walletID), report.Discrepancies[0].AccountCode)
+}
+
+func twd(amount string) money.Money {
+	return money.MustParse(amount, "TWD")
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/limits"
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repos"
+	"errors"
+	"fmt"
+	"time"
+)
+
+//go:generate mockgen -source=limit.go -destination=mock/limit.go
+
+type LimitService interface {
+	Resolve(user *entities.User, txType entities.TransactionType, currency string) (entities.TransactionLimits, error)
+	GetByUserID(userID uint) ([]models.LimitResponse, error)
+	SetOverride(req *models.LimitOverrideRequest) error
+}
+
+type limitService struct {
+	userRepo  repos.UserRepo
+	limitRepo repos.LimitRepo
+	rules     limits.Rules
+}
+
+func NewLimitService(userRepo repos.UserRepo, limitRepo repos.LimitRepo, rules limits.Rules) LimitService {
+	return &limitService{
+		userRepo:  userRepo,
+		limitRepo: limitRepo,
+		rules:     rules,
+	}
+}
+
+// Resolve returns the limits of the user's tier for txType in currency, with
+// the user's overrides applied.
+func (srv *limitService) Resolve(user *entities.User, txType entities.TransactionType, currency string) (entities.TransactionLimits, error) {
+	resolved := srv.rules.Resolve(txType, currency, user.Tier)
+
+	override, err := srv.limitRepo.GetOverride(user.ID, txType, currency)
+	if err != nil {
+		return entities.TransactionLimits{}, fmt.Errorf("failed to get limit override: %w", err)
+	}
+	if override != nil {
+		resolved = resolved.Override(override.Limits())
+	}
+
+	return resolved, nil
+}
+
+// GetByUserID returns the limits of every limited transaction type in the
+// currency of each of the user's wallets, and the allowance left of them.
+func (srv *limitService) GetByUserID(userID uint) ([]models.LimitResponse, error) {
+	user, err := getUser(srv.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	periods := entities.LimitPeriodsAt(time.Now())
+
+	responses := []models.LimitResponse{}
+	for i := range user.Wallets {
+		wallet := &user.Wallets[i]
+		for _, txType := range limits.Types {
+			resolved, err := srv.Resolve(user, txType, wallet.Currency)
+			if err != nil {
+				return nil, err
+			}
+
+			var usage entities.LimitUsage
+			if resolved.HasPeriodicLimits() {
+				if usage, err = srv.limitRepo.GetUsage(user.ID, wallet, txType, periods); err != nil {
+					return nil, fmt.Errorf("failed to get limit usage: %w", err)
+				}
+			}
+
+			responses = append(responses, newLimitResponse(txType, wallet.Currency, resolved, usage))
+		}
+	}
+
+	return responses, nil
+}
+
+// SetOverride saves the override of an operator, or removes the user's
+// override if the request sets no limits.
+func (srv *limitService) SetOverride(req *models.LimitOverrideRequest) error {
+	if !money.IsSupported(req.Currency) {
+		return apperrors.Validation("unsupported_currency", "currency '%s' is not supported", req.Currency)
+	}
+
+	if _, err := getUser(srv.userRepo, req.UserID); err != nil {
+		return err
+	}
+
+	override := &entities.LimitOverride{
+		UserID:            req.UserID,
+		Type:              req.Type,
+		Currency:          req.Currency,
+		PerTransactionMin: inCurrency(req.PerTransactionMin, req.Currency),
+		PerTransactionMax: inCurrency(req.PerTransactionMax, req.Currency),
+		Daily:             inCurrency(req.Daily, req.Currency),
+		Monthly:           inCurrency(req.Monthly, req.Currency),
+		DailyCount:        req.DailyCount,
+		MonthlyCount:      req.MonthlyCount,
+		UpdatedBy:         req.OperatorID,
+		Reason:            req.Reason,
+	}
+
+	for _, amount := range []*money.Money{override.PerTransactionMin, override.PerTransactionMax, override.Daily, override.Monthly} {
+		if amount != nil && amount.IsNegative() {
+			return apperrors.Validation("invalid_amount", "limits cannot be negative")
+		}
+	}
+
+	if override.Limits() == (entities.TransactionLimits{}) {
+		if err := srv.limitRepo.DeleteOverride(req.UserID, req.Type, req.Currency); err != nil {
+			return fmt.Errorf("failed to delete limit override: %w", err)
+		}
+		return nil
+	}
+
+	if err := srv.limitRepo.SaveOverride(override); err != nil {
+		return fmt.Errorf("failed to save limit override: %w", err)
+	}
+	return nil
+}
+
+// newLimitCheck checks amount against the per-transaction limits of the user
+// and returns the check of the periodic limits, which is only made when the
+// transaction is created.
+func newLimitCheck(limitSrv LimitService, user *entities.User, txType entities.TransactionType, amount money.Money) (*entities.LimitCheck, error) {
+	resolved, err := limitSrv.Resolve(user, txType, amount.Currency())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolved.CheckAmount(amount); err != nil {
+		return nil, limitExceeded(err)
+	}
+
+	return &entities.LimitCheck{
+		UserID:  user.ID,
+		Limits:  resolved,
+		Periods: entities.LimitPeriodsAt(time.Now()),
+	}, nil
+}
+
+// limitExceeded reports a transaction over one of its limits. Other errors
+// are returned as they are.
+func limitExceeded(err error) error {
+	var limitErr *entities.LimitExceededError
+	if errors.As(err, &limitErr) {
+		return apperrors.LimitExceeded(limitErr.Code, "%s", limitErr.Message)
+	}
+	return err
+}
+
+func newLimitResponse(txType entities.TransactionType, currency string, resolved entities.TransactionLimits, usage entities.LimitUsage) models.LimitResponse {
+	res := models.LimitResponse{
+		Type:              txType,
+		Currency:          currency,
+		PerTransactionMin: resolved.PerTransactionMin,
+		PerTransactionMax: resolved.PerTransactionMax,
+	}
+
+	if resolved.Daily != nil {
+		res.Daily = newAmountAllowance(*resolved.Daily, usage.Daily.WithCurrency(currency))
+	}
+	if resolved.Monthly != nil {
+		res.Monthly = newAmountAllowance(*resolved.Monthly, usage.Monthly.WithCurrency(currency))
+	}
+	if resolved.DailyCount != nil {
+		res.DailyCount = newCountAllowance(*resolved.DailyCount, usage.DailyCount)
+	}
+	if resolved.MonthlyCount != nil {
+		res.MonthlyCount = newCountAllowance(*resolved.MonthlyCount, usage.MonthlyCount)
+	}
+
+	return res
+}
+
+func newAmountAllowance(limit money.Money, used money.Money) *models.AmountAllowance {
+	remaining, err := limit.Sub(used)
+	if err != nil || remaining.IsNegative() {
+		// Used can exceed a limit that was lowered after it was used
+		remaining = money.Zero(limit.Currency())
+	}
+	return &models.AmountAllowance{Limit: limit, Used: used, Remaining: remaining}
+}
+
+func newCountAllowance(limit int64, used int64) *models.CountAllowance {
+	return &models.CountAllowance{Limit: limit, Used: used, Remaining: max(limit-used, 0)}
+}
+
+func inCurrency(amount *money.Money, currency string) *money.Money {
+	if amount == nil {
+		return nil
+	}
+	converted := amount.WithCurrency(currency)
+	return &converted
+}
@@ -0,0 +1,177 @@
+package services_test
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/limits"
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/services"
+	"testing"
+
+	pspMock "banking-system/psp/mock"
+	repoMock "banking-system/repos/mock"
+	serviceMock "banking-system/services/mock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var depositLimits = entities.TransactionLimits{
+	PerTransactionMin: twdPtr("1.00"),
+	PerTransactionMax: twdPtr("100000.00"),
+}
+
+var testLimitRules = limits.MustParse([]limits.Rule{
+	{Type: entities.TransactionTypes.Withdrawal, Currency: "TWD", PerTransactionMax: "50000", Daily: "100000"},
+	{Type: entities.TransactionTypes.Withdrawal, Tier: entities.UserTiers.Premium, DailyCount: count(20)},
+})
+
+func TestLimits_OverrideReplacesTierLimits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	limitRepoMock := repoMock.NewMockLimitRepo(ctrl)
+	limitRepoMock.EXPECT().
+		GetOverride(uint(1), entities.TransactionTypes.Withdrawal, "TWD").
+		Return(&entities.LimitOverride{Daily: twdPtr("250000"), DailyCount: count(3)}, nil)
+
+	sut := services.NewLimitService(nil, limitRepoMock, testLimitRules)
+	resolved, err := sut.Resolve(&entities.User{Model: gorm.Model{ID: 1}, Tier: entities.UserTiers.Premium}, entities.TransactionTypes.Withdrawal, "TWD")
+
+	assert.Nil(t, err)
+	assert.Equal(t, twd("50000"), *resolved.PerTransactionMax)
+	assert.Equal(t, twd("250000"), *resolved.Daily)
+	assert.Equal(t, int64(3), *resolved.DailyCount)
+}
+
+func TestLimits_GetByUserIDShowsRemainingAllowance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	limitRepoMock := repoMock.NewMockLimitRepo(ctrl)
+	givenUserHasBalance(1, "0")
+	limitRepoMock.EXPECT().GetOverride(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	limitRepoMock.EXPECT().
+		GetUsage(gomock.Any(), gomock.Any(), entities.TransactionTypes.Withdrawal, gomock.Any()).
+		Return(entities.LimitUsage{Daily: twd("30000"), Monthly: twd("30000"), DailyCount: 2, MonthlyCount: 2}, nil)
+
+	sut := services.NewLimitService(userRepoMock, limitRepoMock, testLimitRules)
+	res, err := sut.GetByUserID(1)
+
+	assert.Nil(t, err)
+	assert.Len(t, res, len(limits.Types))
+
+	for _, l := range res {
+		if l.Type != entities.TransactionTypes.Withdrawal {
+			assert.Nil(t, l.Daily)
+			continue
+		}
+		assert.Equal(t, twd("50000"), *l.PerTransactionMax)
+		assert.Equal(t, twd("70000"), l.Daily.Remaining)
+		assert.Nil(t, l.Monthly)
+	}
+}
+
+func TestLimits_OverrideWithoutLimitsIsRemoved(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	limitRepoMock := repoMock.NewMockLimitRepo(ctrl)
+	givenUserHasBalance(1, "0")
+	limitRepoMock.EXPECT().DeleteOverride(uint(1), entities.TransactionTypes.Deposit, "TWD").Return(nil)
+
+	sut := services.NewLimitService(userRepoMock, limitRepoMock, testLimitRules)
+	err := sut.SetOverride(&models.LimitOverrideRequest{
+		UserID:     1,
+		OperatorID: "ops",
+		Type:       entities.TransactionTypes.Deposit,
+		Currency:   "TWD",
+		Reason:     "back to tier limits",
+	})
+
+	assert.Nil(t, err)
+}
+
+func TestWithdraw_AboveMaximum(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	givenSupportedPaymentMethod()
+	givenUserHasBalance(1, "100000")
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil,
+		limitedTo(t, entities.TransactionLimits{PerTransactionMax: twdPtr("50000")}))
+	err := sut.Withdraw(&models.WithdrawRequest{
+		UUID:          uuid.New(),
+		UserID:        1,
+		Amount:        twd("50000.01"),
+		BankAccountID: 7,
+	})
+
+	assert.Equal(t, "amount_above_maximum", apperrors.CodeOf(err))
+}
+
+func TestWithdraw_DailyLimitCheckedWhenCreated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	daily := entities.TransactionLimits{Daily: twdPtr("1000")}
+	givenSupportedPaymentMethod()
+	givenUserHasBalance(1, "100")
+	givenBankAccount(7, 1)
+	transactionRepoMock.EXPECT().
+		CreateWithJournalEntry(withLimitCheck(daily), gomock.Any()).
+		Return(&entities.LimitExceededError{Code: "daily_limit_exceeded", Message: "daily limit reached"})
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, limitedTo(t, daily))
+	err := sut.Withdraw(&models.WithdrawRequest{
+		UUID:          uuid.New(),
+		UserID:        1,
+		Amount:        twd("50"),
+		BankAccountID: 7,
+	})
+
+	assert.Equal(t, apperrors.Kinds.LimitExceeded, apperrors.KindOf(err))
+	assert.Equal(t, "daily_limit_exceeded", apperrors.CodeOf(err))
+}
+
+// unlimited returns a limit service that applies no limits.
+func unlimited(t *testing.T) services.LimitService {
+	return limitedTo(t, entities.TransactionLimits{})
+}
+
+// limitedTo returns a limit service that applies l to every transaction.
+func limitedTo(t *testing.T, l entities.TransactionLimits) services.LimitService {
+	limitServiceMock := serviceMock.NewMockLimitService(gomock.NewController(t))
+	limitServiceMock.EXPECT().
+		Resolve(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(l, nil).
+		AnyTimes()
+	return limitServiceMock
+}
+
+// withLimitCheck matches a transaction that is created subject to l.
+func withLimitCheck(l entities.TransactionLimits) gomock.Matcher {
+	return matcher{
+		description: "transaction with limit check",
+		matches: func(x any) bool {
+			tx, ok := x.(*entities.Transaction)
+			return ok && tx.LimitCheck != nil && tx.LimitCheck.Limits == l &&
+				!tx.LimitCheck.Periods.DayStart.Before(tx.LimitCheck.Periods.MonthStart)
+		},
+	}
+}
+
+func twdPtr(amount string) *money.Money {
+	m := twd(amount)
+	return &m
+}
+
+func count(n int64) *int64 {
+	return &n
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"banking-system/outbox"
+	"banking-system/repos"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//go:generate mockgen -source=outbox.go -destination=mock/outbox.go
+
+const (
+	relayBatchSize = 200
+
+	// relayRunBudget bounds a run of the relay, so that it ends well within
+	// the lease of its job and no other instance publishes at the same time
+	relayRunBudget = 20 * time.Second
+
+	maxRelayError = 500
+)
+
+type OutboxRelay interface {
+	Relay() error
+}
+
+type outboxRelay struct {
+	outboxRepo repos.OutboxRepo
+	sinks      []outbox.Sink
+}
+
+func NewOutboxRelay(outboxRepo repos.OutboxRepo, sinks ...outbox.Sink) OutboxRelay {
+	return &outboxRelay{
+		outboxRepo: outboxRepo,
+		sinks:      sinks,
+	}
+}
+
+// Relay publishes the unpublished outbox events to every sink, in the order
+// they were written. An event is published again until all sinks took it, so
+// sinks may see it more than once. When an event fails, the later events of
+// its wallet wait for the next run, so that each wallet's events arrive in
+// order; the events of other wallets go on.
+func (r *outboxRelay) Relay() error {
+	events, err := r.outboxRepo.GetUnpublished(relayBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to get unpublished outbox events: %w", err)
+	}
+
+	started := time.Now()
+	held := make(map[uint]bool)
+	var published []uint
+
+	for i := range events {
+		event := &events[i]
+		if held[event.WalletID] {
+			continue
+		}
+		if time.Since(started) > relayRunBudget {
+			break
+		}
+
+		if err := r.publish(outbox.NewMessage(event)); err != nil {
+			held[event.WalletID] = true
+			log.Warnf("Failed to publish outbox event %d '%s', holding back wallet %d: %v", event.ID, event.UUID, event.WalletID, err)
+			if err := r.outboxRepo.RecordFailure(event.ID, truncate(err.Error(), maxRelayError)); err != nil {
+				log.Warnf("Failed to record failure of outbox event %d: %v", event.ID, err)
+			}
+			continue
+		}
+
+		published = append(published, event.ID)
+	}
+
+	if err := r.outboxRepo.MarkPublished(published, time.Now()); err != nil {
+		return fmt.Errorf("failed to mark %d outbox events published: %w", len(published), err)
+	}
+	return nil
+}
+
+func (r *outboxRelay) publish(msg *outbox.Message) error {
+	var errs []error
+	for _, sink := range r.sinks {
+		if err := sink.Publish(msg); err != nil {
+			errs = append(errs, fmt.Errorf("sink '%s': %w", sink.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,94 @@
+package services_test
+
+import (
+	"banking-system/entities"
+	"banking-system/outbox"
+	"banking-system/services"
+	"errors"
+	"testing"
+
+	outboxMock "banking-system/outbox/mock"
+	repoMock "banking-system/repos/mock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboxRelay_PublishesInOrderToEverySink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	outboxRepoMock := repoMock.NewMockOutboxRepo(ctrl)
+	first := outboxMock.NewMockSink(ctrl)
+	second := outboxMock.NewMockSink(ctrl)
+
+	events := []entities.OutboxEvent{givenOutboxEvent(1, 7), givenOutboxEvent(2, 7)}
+	outboxRepoMock.EXPECT().GetUnpublished(gomock.Any()).Return(events, nil)
+
+	var published []uint
+	first.EXPECT().Publish(gomock.Any()).DoAndReturn(func(msg *outbox.Message) error {
+		published = append(published, msg.Sequence)
+		return nil
+	}).Times(2)
+	second.EXPECT().Publish(gomock.Any()).Return(nil).Times(2)
+	outboxRepoMock.EXPECT().MarkPublished([]uint{1, 2}, gomock.Any()).Return(nil)
+
+	sut := services.NewOutboxRelay(outboxRepoMock, first, second)
+
+	assert.Nil(t, sut.Relay())
+	assert.Equal(t, []uint{1, 2}, published)
+}
+
+func TestOutboxRelay_FailureHoldsBackLaterEventsOfWallet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	outboxRepoMock := repoMock.NewMockOutboxRepo(ctrl)
+	sink := outboxMock.NewMockSink(ctrl)
+
+	events := []entities.OutboxEvent{givenOutboxEvent(1, 7), givenOutboxEvent(2, 8), givenOutboxEvent(3, 7)}
+	outboxRepoMock.EXPECT().GetUnpublished(gomock.Any()).Return(events, nil)
+
+	sink.EXPECT().Name().Return("http").AnyTimes()
+	sink.EXPECT().Publish(gomock.Any()).DoAndReturn(func(msg *outbox.Message) error {
+		if msg.Sequence == 1 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}).Times(2)
+	outboxRepoMock.EXPECT().RecordFailure(uint(1), "sink 'http': connection refused").Return(nil)
+	outboxRepoMock.EXPECT().MarkPublished([]uint{2}, gomock.Any()).Return(nil)
+
+	sut := services.NewOutboxRelay(outboxRepoMock, sink)
+
+	assert.Nil(t, sut.Relay())
+}
+
+func TestOutboxRelay_MessageCarriesEventID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	outboxRepoMock := repoMock.NewMockOutboxRepo(ctrl)
+	sink := outboxMock.NewMockSink(ctrl)
+
+	event := givenOutboxEvent(5, 7)
+	outboxRepoMock.EXPECT().GetUnpublished(gomock.Any()).Return([]entities.OutboxEvent{event}, nil)
+	sink.EXPECT().Publish(gomock.Any()).DoAndReturn(func(msg *outbox.Message) error {
+		assert.Equal(t, event.UUID, msg.ID)
+		assert.Equal(t, event.Type, msg.Type)
+		assert.Equal(t, uint(42), msg.UserID)
+		assert.JSONEq(t, event.Payload, string(msg.Data))
+		return nil
+	})
+	outboxRepoMock.EXPECT().MarkPublished([]uint{5}, gomock.Any()).Return(nil)
+
+	sut := services.NewOutboxRelay(outboxRepoMock, sink)
+
+	assert.Nil(t, sut.Relay())
+}
+
+func givenOutboxEvent(id uint, walletID uint) entities.OutboxEvent {
+	return entities.OutboxEvent{
+		ID:       id,
+		UUID:     uuid.New(),
+		Type:     entities.EventTypes.WalletBalanceChanged,
+		UserID:   42,
+		WalletID: walletID,
+		Payload:  `{"wallet_id":7,"currency":"TWD","balance":100}`,
+	}
+}
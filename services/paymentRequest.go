@@ -0,0 +1,248 @@
+package services
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repos"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+//go:generate mockgen -source=paymentRequest.go -destination=mock/paymentRequest.go
+
+const (
+	defaultPaymentRequestExpiry = 7 * 24 * time.Hour
+	maxPaymentRequestExpiry     = 30 * 24 * time.Hour
+
+	paymentRequestLimit = 100
+)
+
+type PaymentRequestService interface {
+	Create(req *models.CreatePaymentRequestRequest) (*models.PaymentRequestResponse, error)
+	GetIncoming(query *models.PaymentRequestQuery) ([]models.PaymentRequestResponse, error)
+	GetOutgoing(query *models.PaymentRequestQuery) ([]models.PaymentRequestResponse, error)
+	Accept(userID uint, id string) (*models.PaymentRequestResponse, error)
+	Decline(userID uint, id string) (*models.PaymentRequestResponse, error)
+	ExpireDue() error
+}
+
+type paymentRequestService struct {
+	paymentRequestRepo repos.PaymentRequestRepo
+	userRepo           repos.UserRepo
+	paymentSrv         PaymentService
+}
+
+func NewPaymentRequestService(paymentRequestRepo repos.PaymentRequestRepo, userRepo repos.UserRepo, paymentSrv PaymentService) PaymentRequestService {
+	return &paymentRequestService{
+		paymentRequestRepo: paymentRequestRepo,
+		userRepo:           userRepo,
+		paymentSrv:         paymentSrv,
+	}
+}
+
+func (srv *paymentRequestService) Create(req *models.CreatePaymentRequestRequest) (*models.PaymentRequestResponse, error) {
+	currency := currencyOrDefault(req.Currency)
+	amount := req.Amount.WithCurrency(currency)
+	if !money.IsSupported(currency) {
+		return nil, apperrors.Validation("unsupported_currency", "currency '%s' is not supported", currency)
+	}
+	if !amount.IsPositive() {
+		return nil, apperrors.Validation("invalid_amount", "requested amount must be greater than zero")
+	}
+	if err := checkPrecision(amount); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(defaultPaymentRequestExpiry)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+	if !expiresAt.After(now) || expiresAt.After(now.Add(maxPaymentRequestExpiry)) {
+		return nil, apperrors.Validation("invalid_expires_at", "expires_at must be in the future and within %d days", int(maxPaymentRequestExpiry.Hours()/24))
+	}
+
+	requester, err := getUser(srv.userRepo, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := requester.WalletFor(currency); !ok {
+		return nil, apperrors.NotFound("wallet_not_found", "requester has no %s wallet", currency)
+	}
+
+	payer, err := srv.userRepo.GetByUsername(req.PayerUsername)
+	if repos.IsNotFound(err) {
+		return nil, apperrors.NotFound("payer_not_found", "payer user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payer user: %w", err)
+	}
+
+	if payer.ID == requester.ID {
+		return nil, apperrors.Validation("same_user_request", "cannot request a payment from the same user")
+	}
+
+	request := &entities.PaymentRequest{
+		UUID:              uuid.New(),
+		RequesterID:       requester.ID,
+		RequesterUsername: requester.Username,
+		PayerID:           payer.ID,
+		PayerUsername:     payer.Username,
+		Amount:            amount,
+		Memo:              req.Memo,
+		Status:            entities.PaymentRequestStatuses.Pending,
+		ExpiresAt:         expiresAt.UTC(),
+	}
+	if err := srv.paymentRequestRepo.Create(request); err != nil {
+		return nil, fmt.Errorf("failed to create payment request: %w", err)
+	}
+
+	res := newPaymentRequestResponse(request)
+	return &res, nil
+}
+
+// GetIncoming returns the latest payment requests the user was sent, the most
+// recent first.
+func (srv *paymentRequestService) GetIncoming(query *models.PaymentRequestQuery) ([]models.PaymentRequestResponse, error) {
+	return srv.getPaymentRequests(query, srv.paymentRequestRepo.GetByPayerID)
+}
+
+// GetOutgoing returns the latest payment requests the user sent, the most
+// recent first.
+func (srv *paymentRequestService) GetOutgoing(query *models.PaymentRequestQuery) ([]models.PaymentRequestResponse, error) {
+	return srv.getPaymentRequests(query, srv.paymentRequestRepo.GetByRequesterID)
+}
+
+func (srv *paymentRequestService) getPaymentRequests(query *models.PaymentRequestQuery, get func(uint, entities.PaymentRequestStatus, int) ([]entities.PaymentRequest, error)) ([]models.PaymentRequestResponse, error) {
+	if query.Status != "" && !query.Status.IsValid() {
+		return nil, apperrors.Validation("invalid_status", "unknown payment request status '%s'", query.Status)
+	}
+
+	requests, err := get(query.UserID, query.Status, paymentRequestLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment requests: %w", err)
+	}
+
+	res := make([]models.PaymentRequestResponse, len(requests))
+	for i := range requests {
+		res[i] = newPaymentRequestResponse(&requests[i])
+	}
+	return res, nil
+}
+
+// Accept pays a payment request the user was sent with a transfer to the
+// requester. The request is accepted in the same database transaction as the
+// transfer, so a request is never paid twice and a failed transfer leaves it
+// pending.
+func (srv *paymentRequestService) Accept(userID uint, id string) (*models.PaymentRequestResponse, error) {
+	request, err := srv.getOpenPaymentRequest(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = srv.paymentSrv.Transfer(&models.TransferRequest{
+		UUID:              uuid.New(),
+		SenderUserID:      userID,
+		RecipientUsername: request.RequesterUsername,
+		Amount:            request.Amount,
+		Currency:          request.Currency,
+		PaymentRequestID:  &request.UUID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	accepted, err := srv.paymentRequestRepo.GetByUUID(request.UUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accepted payment request '%s': %w", request.UUID, err)
+	}
+
+	res := newPaymentRequestResponse(accepted)
+	return &res, nil
+}
+
+// Decline closes a payment request the user was sent without paying it.
+func (srv *paymentRequestService) Decline(userID uint, id string) (*models.PaymentRequestResponse, error) {
+	request, err := srv.getOpenPaymentRequest(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	declined, err := srv.paymentRequestRepo.Decline(request, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decline payment request '%s': %w", request.UUID, err)
+	}
+	if !declined {
+		return nil, apperrors.Conflict("payment_request_not_pending", "payment request was accepted, declined or expired meanwhile")
+	}
+
+	request.Status = entities.PaymentRequestStatuses.Declined
+	request.ClosedAt = &now
+	res := newPaymentRequestResponse(request)
+	return &res, nil
+}
+
+// ExpireDue closes the pending payment requests whose expiry has passed.
+func (srv *paymentRequestService) ExpireDue() error {
+	expired, err := srv.paymentRequestRepo.ExpireDue(time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to expire payment requests: %w", err)
+	}
+
+	if expired > 0 {
+		log.Infof("Expired %d payment requests", expired)
+	}
+	return nil
+}
+
+// getOpenPaymentRequest returns a payment request the user was sent that can
+// still be accepted or declined.
+func (srv *paymentRequestService) getOpenPaymentRequest(userID uint, id string) (*entities.PaymentRequest, error) {
+	requestID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, apperrors.Validation("invalid_payment_request_id", "invalid payment request ID '%s'", id)
+	}
+
+	request, err := srv.paymentRequestRepo.GetByUUID(requestID)
+	if repos.IsNotFound(err) {
+		return nil, apperrors.NotFound("payment_request_not_found", "payment request not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment request '%s': %w", requestID, err)
+	}
+
+	if request.PayerID != userID {
+		return nil, apperrors.Forbidden("payment_request_forbidden", "only the payer can accept or decline a payment request")
+	}
+
+	if !request.IsOpen(time.Now()) {
+		if request.Status == entities.PaymentRequestStatuses.Pending {
+			return nil, apperrors.Conflict("payment_request_not_pending", "payment request has expired")
+		}
+		return nil, apperrors.Conflict("payment_request_not_pending", "payment request is %s", request.Status)
+	}
+	return request, nil
+}
+
+func newPaymentRequestResponse(request *entities.PaymentRequest) models.PaymentRequestResponse {
+	return models.PaymentRequestResponse{
+		ID:                request.UUID,
+		RequesterUsername: request.RequesterUsername,
+		PayerUsername:     request.PayerUsername,
+		Amount:            request.Amount,
+		Currency:          request.Currency,
+		Memo:              request.Memo,
+		Status:            string(request.Status),
+		ExpiresAt:         request.ExpiresAt,
+		ClosedAt:          request.ClosedAt,
+		TransactionID:     request.TransactionID,
+		CreatedAt:         request.CreatedAt,
+	}
+}
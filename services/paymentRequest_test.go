@@ -0,0 +1,171 @@
+package services_test
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/services"
+	"testing"
+	"time"
+
+	repoMock "banking-system/repos/mock"
+	serviceMock "banking-system/services/mock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var paymentRequestRepoMock *repoMock.MockPaymentRequestRepo
+
+func TestPaymentRequest_CreateRejectsExpiryTooFarAhead(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	paymentRequestRepoMock = repoMock.NewMockPaymentRequestRepo(ctrl)
+
+	expiresAt := time.Now().Add(31 * 24 * time.Hour)
+	sut := services.NewPaymentRequestService(paymentRequestRepoMock, nil, nil)
+	_, err := sut.Create(&models.CreatePaymentRequestRequest{
+		UserID:        2,
+		PayerUsername: "tenant",
+		Amount:        twd("15000"),
+		ExpiresAt:     &expiresAt,
+	})
+
+	assert.Equal(t, "invalid_expires_at", apperrors.CodeOf(err))
+}
+
+func TestPaymentRequest_CreateRejectsRequestingFromSelf(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	paymentRequestRepoMock = repoMock.NewMockPaymentRequestRepo(ctrl)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+
+	tenant := &entities.User{
+		Model:    gorm.Model{ID: 1},
+		Username: "tenant",
+		Wallets:  []entities.Wallet{{Model: gorm.Model{ID: 10}, UserID: 1, Currency: "TWD"}},
+	}
+	userRepoMock.EXPECT().Get(uint(1)).Return(tenant, nil)
+	userRepoMock.EXPECT().GetByUsername("tenant").Return(tenant, nil)
+
+	sut := services.NewPaymentRequestService(paymentRequestRepoMock, userRepoMock, nil)
+	_, err := sut.Create(&models.CreatePaymentRequestRequest{
+		UserID:        1,
+		PayerUsername: "tenant",
+		Amount:        twd("15000"),
+	})
+
+	assert.Equal(t, "same_user_request", apperrors.CodeOf(err))
+}
+
+func TestPaymentRequest_CreateDefaultsExpiry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	paymentRequestRepoMock = repoMock.NewMockPaymentRequestRepo(ctrl)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+
+	givenTenantAndLandlord()
+	paymentRequestRepoMock.EXPECT().Create(gomock.Any()).Return(nil)
+
+	sut := services.NewPaymentRequestService(paymentRequestRepoMock, userRepoMock, nil)
+	res, err := sut.Create(&models.CreatePaymentRequestRequest{
+		UserID:        1,
+		PayerUsername: "landlord",
+		Amount:        twd("200"),
+		Memo:          "Dinner",
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "PENDING", res.Status)
+	assert.Equal(t, "tenant", res.RequesterUsername)
+	assert.Equal(t, "landlord", res.PayerUsername)
+	assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), res.ExpiresAt, time.Minute)
+}
+
+func TestPaymentRequest_AcceptTransfersToRequester(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	paymentRequestRepoMock = repoMock.NewMockPaymentRequestRepo(ctrl)
+	paymentServiceMock = serviceMock.NewMockPaymentService(ctrl)
+
+	request := givenPaymentRequest(time.Hour)
+	paymentRequestRepoMock.EXPECT().GetByUUID(request.UUID).Return(request, nil).Times(2)
+	paymentServiceMock.EXPECT().Transfer(gomock.Any()).DoAndReturn(func(req *models.TransferRequest) error {
+		assert.Equal(t, uint(2), req.SenderUserID)
+		assert.Equal(t, "tenant", req.RecipientUsername)
+		assert.Equal(t, twd("200"), req.Amount)
+		assert.Equal(t, request.UUID, *req.PaymentRequestID)
+		return nil
+	})
+
+	sut := services.NewPaymentRequestService(paymentRequestRepoMock, nil, paymentServiceMock)
+	_, err := sut.Accept(2, request.UUID.String())
+
+	assert.Nil(t, err)
+}
+
+func TestPaymentRequest_AcceptByRequesterIsForbidden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	paymentRequestRepoMock = repoMock.NewMockPaymentRequestRepo(ctrl)
+
+	request := givenPaymentRequest(time.Hour)
+	paymentRequestRepoMock.EXPECT().GetByUUID(request.UUID).Return(request, nil)
+
+	sut := services.NewPaymentRequestService(paymentRequestRepoMock, nil, nil)
+	_, err := sut.Accept(1, request.UUID.String())
+
+	assert.Equal(t, "payment_request_forbidden", apperrors.CodeOf(err))
+}
+
+func TestPaymentRequest_AcceptExpiredIsConflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	paymentRequestRepoMock = repoMock.NewMockPaymentRequestRepo(ctrl)
+	paymentServiceMock = serviceMock.NewMockPaymentService(ctrl)
+
+	request := givenPaymentRequest(-time.Minute)
+	paymentRequestRepoMock.EXPECT().GetByUUID(request.UUID).Return(request, nil)
+	paymentServiceMock.EXPECT().Transfer(gomock.Any()).Times(0)
+
+	sut := services.NewPaymentRequestService(paymentRequestRepoMock, nil, paymentServiceMock)
+	_, err := sut.Accept(2, request.UUID.String())
+
+	assert.Equal(t, "payment_request_not_pending", apperrors.CodeOf(err))
+}
+
+func TestPaymentRequest_DeclineClosedMeanwhileIsConflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	paymentRequestRepoMock = repoMock.NewMockPaymentRequestRepo(ctrl)
+
+	request := givenPaymentRequest(time.Hour)
+	paymentRequestRepoMock.EXPECT().GetByUUID(request.UUID).Return(request, nil)
+	paymentRequestRepoMock.EXPECT().Decline(request, gomock.Any()).Return(false, nil)
+
+	sut := services.NewPaymentRequestService(paymentRequestRepoMock, nil, nil)
+	_, err := sut.Decline(2, request.UUID.String())
+
+	assert.Equal(t, "payment_request_not_pending", apperrors.CodeOf(err))
+}
+
+func TestPaymentRequest_ListRejectsUnknownStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	paymentRequestRepoMock = repoMock.NewMockPaymentRequestRepo(ctrl)
+
+	sut := services.NewPaymentRequestService(paymentRequestRepoMock, nil, nil)
+	_, err := sut.GetIncoming(&models.PaymentRequestQuery{UserID: 2, Status: "PAID"})
+
+	assert.Equal(t, "invalid_status", apperrors.CodeOf(err))
+}
+
+// givenPaymentRequest returns a pending request from user 1, "tenant", to
+// user 2, "landlord", that expires after expiresIn.
+func givenPaymentRequest(expiresIn time.Duration) *entities.PaymentRequest {
+	return &entities.PaymentRequest{
+		UUID:              uuid.New(),
+		RequesterID:       1,
+		RequesterUsername: "tenant",
+		PayerID:           2,
+		PayerUsername:     "landlord",
+		Amount:            twd("200"),
+		Currency:          "TWD",
+		Status:            entities.PaymentRequestStatuses.Pending,
+		ExpiresAt:         time.Now().Add(expiresIn),
+	}
+}
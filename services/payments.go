@@ -1,10 +1,14 @@
 package services
 
 import (
+	"banking-system/apperrors"
 	"banking-system/entities"
+	"banking-system/fees"
 	"banking-system/models"
+	"banking-system/money"
 	"banking-system/psp"
 	"banking-system/repos"
+	"errors"
 	"fmt"
 	"os"
 
@@ -15,12 +19,10 @@ import (
 //go:generate mockgen -source=payments.go -destination=mock/payments.go
 
 const (
-	MIN_DEPOSIT_AMOUNT  = 1.00
-	MAX_DEPOSIT_AMOUNT  = 100000.00
-	MIN_TRANSFER_AMOUNT = 1.00
-	MAX_TRANSFER_AMOUNT = 100000.00
+	defaultCurrency     = "TWD"
 	confirmCallbackPath = "/payments/confirm"
 	cancelCallbackPath  = "/payments/cancel"
+	payOutCallbackPath  = "/payments/payout-status"
 )
 
 type PaymentService interface {
@@ -29,47 +31,86 @@ type PaymentService interface {
 	Transfer(req *models.TransferRequest) error
 	Confirm(req *psp.ConfirmRequest) error
 	Cancel(req *psp.CancelRequest) error
+	Refund(req *models.RefundRequest) (*models.TransactionResponse, error)
+	Reverse(req *models.ReversalRequest) (*models.TransactionResponse, error)
 }
 
 type paymentService struct {
 	userRepo        repos.UserRepo
 	transactionRepo repos.TransactionRepo
+	bankAccountRepo repos.BankAccountRepo
 	pspFactory      psp.PSPFactory
+	feeSchedules    fees.Schedules
+	limitSrv        LimitService
 }
 
-func NewPaymentService(userRepo repos.UserRepo, transactionRepo repos.TransactionRepo, pspFactory psp.PSPFactory) PaymentService {
+func NewPaymentService(userRepo repos.UserRepo, transactionRepo repos.TransactionRepo, bankAccountRepo repos.BankAccountRepo, pspFactory psp.PSPFactory, feeSchedules fees.Schedules, limitSrv LimitService) PaymentService {
 	return &paymentService{
 		userRepo:        userRepo,
 		transactionRepo: transactionRepo,
+		bankAccountRepo: bankAccountRepo,
 		pspFactory:      pspFactory,
+		feeSchedules:    feeSchedules,
+		limitSrv:        limitSrv,
 	}
 }
 
 func (srv *paymentService) Deposit(req *models.DepositRequest) (redirectUrl string, err error) {
-	if req.Amount < MIN_DEPOSIT_AMOUNT {
-		return "", fmt.Errorf("deposit amount %.2f is below minimum allowed amount %.2f", req.Amount, MIN_DEPOSIT_AMOUNT)
+	currency := currencyOrDefault(req.Currency)
+	amount := req.Amount.WithCurrency(currency)
+
+	if !money.IsSupported(currency) {
+		return "", apperrors.Validation("unsupported_currency", "currency '%s' is not supported", currency)
 	}
 
-	if req.Amount > MAX_DEPOSIT_AMOUNT {
-		return "", fmt.Errorf("deposit amount %.2f exceeds maximum allowed amount %.2f", req.Amount, MAX_DEPOSIT_AMOUNT)
+	if !amount.IsPositive() {
+		return "", apperrors.Validation("invalid_amount", "deposit amount must be greater than zero")
+	}
+	if err := checkPrecision(amount); err != nil {
+		return "", err
 	}
 
-	user, _ := srv.userRepo.Get(req.UserID)
+	provider, err := getProvider(srv.pspFactory, req.PaymentMethod)
+	if err != nil {
+		return "", err
+	}
+
+	user, err := getUser(srv.userRepo, req.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	wallet, ok := user.WalletFor(currency)
+	if !ok {
+		return "", apperrors.NotFound("wallet_not_found", "user has no %s wallet", currency)
+	}
+
+	limitCheck, err := newLimitCheck(srv.limitSrv, user, entities.TransactionTypes.Deposit, amount)
+	if err != nil {
+		return "", err
+	}
+
+	// Deposit fees are taken from the deposited amount once it arrives
+	fee, err := calculateFee(srv.feeSchedules, fees.Operations.Deposit, req.PaymentMethod, user, amount)
+	if err != nil {
+		return "", err
+	}
 
 	tx := &entities.Transaction{
 		UUID:          req.UUID,
-		WalletID:      user.Wallet.ID,
-		Amount:        req.Amount,
+		WalletID:      wallet.ID,
+		Amount:        amount,
 		Status:        entities.TransactionStatuses.Pending,
 		Type:          entities.TransactionTypes.Deposit,
 		PaymentMethod: req.PaymentMethod,
+		LimitCheck:    limitCheck,
 	}
+	tx.AttachFee(fee)
 
 	if err := srv.transactionRepo.Create(tx); err != nil {
-		log.Panicf("Failed to create transaction: %v", err)
+		return "", transactionCreateError(err)
 	}
 
-	provider := srv.pspFactory.NewPaymentServiceProvider(req.PaymentMethod)
 	baseUrl := os.Getenv("API_GATEWAY_URL")
 	res, err := provider.PayIn(&psp.PayInRequest{
 		TransactionID:      tx.UUID.String(),
@@ -78,148 +119,335 @@ func (srv *paymentService) Deposit(req *models.DepositRequest) (redirectUrl stri
 		CancelCallbackURL:  fmt.Sprintf("%s%s", baseUrl, cancelCallbackPath),
 	})
 	if err != nil {
-		log.Panicf("Payment service provider '%s' error: %v", req.PaymentMethod, err)
+		return "", apperrors.AfterCommit(apperrors.ProviderUnavailable(fmt.Sprintf("payment provider '%s'", req.PaymentMethod), err))
 	}
 
 	return res.RedirectUrl, nil
 }
 
 func (srv *paymentService) Withdraw(req *models.WithdrawRequest) error {
-	user, err := srv.userRepo.Get(req.UserID)
+	provider, err := getProvider(srv.pspFactory, req.PaymentMethod)
+	if err != nil {
+		return err
+	}
+
+	user, err := getUser(srv.userRepo, req.UserID)
 	if err != nil {
-		log.Panicf("Failed to get user with ID %d: %v", req.UserID, err)
+		return err
+	}
+
+	currency := currencyOrDefault(req.Currency)
+	wallet, ok := user.WalletFor(currency)
+	if !ok {
+		return apperrors.NotFound("wallet_not_found", "user has no %s wallet", currency)
+	}
+
+	amount := req.Amount.WithCurrency(currency)
+	if !amount.IsPositive() {
+		return apperrors.Validation("invalid_amount", "withdrawal amount must be greater than zero")
+	}
+	if err := checkPrecision(amount); err != nil {
+		return err
 	}
 
-	if user.Wallet.Balance < req.Amount {
-		return fmt.Errorf("insufficient balance: current balance %.2f, requested amount %.2f", user.Wallet.Balance, req.Amount)
+	limitCheck, err := newLimitCheck(srv.limitSrv, user, entities.TransactionTypes.Withdrawal, amount)
+	if err != nil {
+		return err
+	}
+
+	fee, err := calculateFee(srv.feeSchedules, fees.Operations.Withdrawal, req.PaymentMethod, user, amount)
+	if err != nil {
+		return err
+	}
+
+	if err := checkBalance(wallet, amount, fee); err != nil {
+		return err
+	}
+
+	bankAccount, err := getOwnedBankAccount(srv.bankAccountRepo, req.BankAccountID, req.UserID)
+	if err != nil {
+		return err
 	}
 
 	tx := &entities.Transaction{
 		UUID:          req.UUID,
-		WalletID:      user.Wallet.ID,
-		Amount:        req.Amount,
+		WalletID:      wallet.ID,
+		Amount:        amount,
 		Status:        entities.TransactionStatuses.Pending,
 		Type:          entities.TransactionTypes.Withdrawal,
 		PaymentMethod: req.PaymentMethod,
-		Wallet:        &user.Wallet,
+		PayOutStatus:  psp.PayOutStatuses.Submitted,
+		Wallet:        wallet,
+		LimitCheck:    limitCheck,
 	}
+	tx.AttachFee(fee)
 
-	if err := srv.transactionRepo.Create(tx); err != nil {
-		log.Panicf("Failed to create transaction: %v", err)
+	// The balance may have changed since it was read; the ledger re-checks it
+	// under a row lock.
+	err = srv.transactionRepo.CreateWithJournalEntry(tx, withFeeEntry(tx, entities.NewWithdrawalEntry(tx))...)
+	if errors.Is(err, repos.ErrInsufficientFunds) {
+		return apperrors.InsufficientFunds("insufficient balance: requested amount %s", amount)
 	}
-
-	user.Wallet.Balance -= req.Amount
-	if err := srv.userRepo.UpdateWallet(user); err != nil {
-		log.Panicf("Failed to update wallet for user ID %d: %v", req.UserID, err)
+	if err != nil {
+		return transactionCreateError(err)
 	}
 
-	provider := srv.pspFactory.NewPaymentServiceProvider(req.PaymentMethod)
-	_, err = provider.PayOut()
+	res, err := provider.PayOut(&psp.PayOutRequest{
+		TransactionID: tx.UUID.String(),
+		Amount:        amount,
+		Beneficiary: psp.BankAccount{
+			BankCode:      bankAccount.BankCode,
+			AccountNumber: bankAccount.AccountNumber,
+		},
+		StatusCallbackURL: fmt.Sprintf("%s%s", os.Getenv("API_GATEWAY_URL"), payOutCallbackPath),
+	})
 	if err != nil {
-		log.Panicf("Payment service provider error: %v", err)
+		// The payout stays SUBMITTED; polling finds out whether the provider
+		// accepted it and refunds the wallet if it did not
+		return apperrors.AfterCommit(apperrors.ProviderUnavailable(fmt.Sprintf("payment provider '%s'", req.PaymentMethod), err))
+	}
+
+	if res.Reference != "" {
+		if err := srv.transactionRepo.SetPSPReference(tx.UUID, res.Reference); err != nil {
+			log.Errorf("Failed to record PSP reference '%s' for transaction '%s': %v", res.Reference, tx.UUID, err)
+		}
 	}
 
 	return nil
 }
 
 func (srv *paymentService) Transfer(req *models.TransferRequest) error {
-	if req.Amount < MIN_TRANSFER_AMOUNT {
-		return fmt.Errorf("transfer amount %.2f is below minimum allowed amount %.2f", req.Amount, MIN_TRANSFER_AMOUNT)
+	currency := currencyOrDefault(req.Currency)
+	amount := req.Amount.WithCurrency(currency)
+
+	if !money.IsSupported(currency) {
+		return apperrors.Validation("unsupported_currency", "currency '%s' is not supported", currency)
 	}
 
-	if req.Amount > MAX_TRANSFER_AMOUNT {
-		return fmt.Errorf("transfer amount %.2f exceeds maximum allowed amount %.2f", req.Amount, MAX_TRANSFER_AMOUNT)
+	if !amount.IsPositive() {
+		return apperrors.Validation("invalid_amount", "transfer amount must be greater than zero")
+	}
+	if err := checkPrecision(amount); err != nil {
+		return err
 	}
 
-	sender, err := srv.userRepo.Get(req.SenderUserID)
+	sender, err := getUser(srv.userRepo, req.SenderUserID)
 	if err != nil {
-		log.Panicf("Failed to get sender user: %v", err)
+		return err
 	}
 
 	recipient, err := srv.userRepo.GetByUsername(req.RecipientUsername)
+	if repos.IsNotFound(err) {
+		return apperrors.NotFound("recipient_not_found", "recipient user not found")
+	}
 	if err != nil {
-		if err.Error() == "record not found" {
-			return fmt.Errorf("recipient user not found")
-		}
-		log.Panicf("Failed to get recipient user: %v", err)
+		return fmt.Errorf("failed to get recipient user: %w", err)
 	}
 
 	if sender.ID == recipient.ID {
-		return fmt.Errorf("cannot transfer to the same user")
+		return apperrors.Validation("same_user_transfer", "cannot transfer to the same user")
 	}
 
-	if sender.Wallet.Balance < req.Amount {
-		return fmt.Errorf("insufficient balance: current balance %.2f, requested amount %.2f", sender.Wallet.Balance, req.Amount)
+	senderWallet, ok := sender.WalletFor(currency)
+	if !ok {
+		return apperrors.NotFound("wallet_not_found", "sender has no %s wallet", currency)
+	}
+
+	// Transfers never convert currencies; the recipient must hold the same currency
+	recipientWallet, ok := recipient.WalletFor(currency)
+	if !ok {
+		return apperrors.Validation("recipient_currency_unsupported", "recipient cannot receive %s: no %s wallet", currency, currency)
+	}
+
+	limitCheck, err := newLimitCheck(srv.limitSrv, sender, entities.TransactionTypes.TransferOut, amount)
+	if err != nil {
+		return err
+	}
+
+	// The sender pays the fee on top of the amount the recipient receives
+	fee, err := calculateFee(srv.feeSchedules, fees.Operations.Transfer, "", sender, amount)
+	if err != nil {
+		return err
+	}
+
+	if err := checkBalance(senderWallet, amount, fee); err != nil {
+		return err
 	}
 
 	transferOutTx := &entities.Transaction{
-		UUID:     req.UUID,
-		WalletID: sender.Wallet.ID,
-		Amount:   req.Amount,
-		Status:   entities.TransactionStatuses.Completed,
-		Type:     entities.TransactionTypes.TransferOut,
-		Wallet:   &sender.Wallet,
+		UUID:             req.UUID,
+		WalletID:         senderWallet.ID,
+		Amount:           amount,
+		Status:           entities.TransactionStatuses.Completed,
+		Type:             entities.TransactionTypes.TransferOut,
+		Wallet:           senderWallet,
+		LimitCheck:       limitCheck,
+		PaymentRequestID: req.PaymentRequestID,
 	}
+	transferOutTx.AttachFee(fee)
 
 	transferInTx := &entities.Transaction{
 		UUID:     uuid.New(),
-		WalletID: recipient.Wallet.ID,
-		Amount:   req.Amount,
+		WalletID: recipientWallet.ID,
+		Amount:   amount,
 		Status:   entities.TransactionStatuses.Completed,
 		Type:     entities.TransactionTypes.TransferIn,
-		Wallet:   &recipient.Wallet,
+		Wallet:   recipientWallet,
 	}
 
-	sender.Wallet.Balance -= req.Amount
-	recipient.Wallet.Balance += req.Amount
-
-	if err := srv.transactionRepo.CreateTransferTransactions(transferOutTx, transferInTx); err != nil {
-		log.Panicf("Failed to create transfer: %v", err)
+	entry := entities.NewTransferEntry(transferOutTx, transferInTx)
+	err = srv.transactionRepo.CreateTransferTransactions(transferOutTx, transferInTx, withFeeEntry(transferOutTx, entry)...)
+	if errors.Is(err, repos.ErrInsufficientFunds) {
+		return apperrors.InsufficientFunds("insufficient balance: requested amount %s", amount)
+	}
+	if errors.Is(err, repos.ErrPaymentRequestClosed) {
+		return apperrors.Conflict("payment_request_not_pending", "payment request was accepted, declined or expired meanwhile")
+	}
+	if err != nil {
+		return transactionCreateError(err)
 	}
 
 	return nil
 }
 
 func (srv *paymentService) Confirm(req *psp.ConfirmRequest) error {
-	tx, err := srv.transactionRepo.GetByUUID(uuid.MustParse(req.TransactionID))
+	tx, err := srv.getPayIn(req.TransactionID, req.Provider)
+	if err != nil {
+		return err
+	}
+
+	return applyTransition(srv.transactionRepo, tx, entities.TransactionStatuses.Completed,
+		entities.PSPTrigger(req.Provider, "confirmed by provider"))
+}
+
+func (srv *paymentService) Cancel(req *psp.CancelRequest) error {
+	tx, err := srv.getPayIn(req.TransactionID, req.Provider)
+	if err != nil {
+		return err
+	}
+
+	return applyTransition(srv.transactionRepo, tx, entities.TransactionStatuses.Canceled,
+		entities.PSPTrigger(req.Provider, "canceled by provider"))
+}
+
+// getPayIn returns the deposit a pay-in callback is about. A provider can only
+// report on its own deposits; withdrawals are settled by payout callbacks.
+func (srv *paymentService) getPayIn(transactionID string, provider psp.PaymentMethod) (*entities.Transaction, error) {
+	tx, err := getTransaction(srv.transactionRepo, transactionID)
 	if err != nil {
-		log.Panicf("Failed to get transaction: %v", err)
+		return nil, err
+	}
+
+	if tx.Type != entities.TransactionTypes.Deposit || tx.PaymentMethod != provider {
+		return nil, apperrors.NotFound("transaction_not_found", "transaction '%s' not found", transactionID)
 	}
 
-	if err = tx.Complete(); err != nil {
-		log.Panicf("Failed to complete transaction: %v", err)
+	return tx, nil
+}
+
+// applyTransition moves a transaction to status and saves the change. A
+// transaction already in that status is left as it is, so that callbacks can
+// safely be repeated.
+func applyTransition(transactionRepo repos.TransactionRepo, tx *entities.Transaction, to entities.TransactionStatus, trigger entities.Trigger) error {
+	t, err := tx.Transition(to, trigger)
+	if errors.Is(err, entities.ErrStatusUnchanged) {
+		log.Infof("Transaction '%s' already processed with status: %s", tx.UUID, to)
+		return nil
+	}
+	if err != nil {
+		return transitionError(err)
 	}
 
-	updated, err := srv.transactionRepo.UpdateConditional(tx, entities.TransactionStatuses.Pending)
+	updated, err := transactionRepo.ApplyTransition(tx, t)
 	if err != nil {
-		log.Panicf("Failed to update transaction: %v", err)
+		return fmt.Errorf("failed to update transaction: %w", err)
 	}
 
 	if !updated {
-		log.Infof("Transaction '%s' was already processed by another request", req.TransactionID)
+		log.Infof("Transaction '%s' was already processed by another request", tx.UUID)
 	}
 
 	return nil
 }
 
-func (srv *paymentService) Cancel(req *psp.CancelRequest) error {
-	tx, err := srv.transactionRepo.GetByUUID(uuid.MustParse(req.TransactionID))
+// transitionError reports a status change the state machine rejects as a
+// conflict with the transaction's current status.
+func transitionError(err error) error {
+	var transitionErr *entities.TransitionError
+	if errors.As(err, &transitionErr) && errors.Is(err, entities.ErrIllegalTransition) {
+		return apperrors.Conflict("illegal_transition", "%s transaction cannot move from %s to %s", transitionErr.Type, transitionErr.From, transitionErr.To)
+	}
+	return err
+}
+
+func getUser(userRepo repos.UserRepo, userID uint) (*entities.User, error) {
+	user, err := userRepo.Get(userID)
+	if repos.IsNotFound(err) {
+		return nil, apperrors.NotFound("user_not_found", "user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user with ID %d: %w", userID, err)
+	}
+	return user, nil
+}
+
+// getProvider returns the provider that handles paymentMethod, or a
+// validation error if there is none.
+func getProvider(pspFactory psp.PSPFactory, paymentMethod psp.PaymentMethod) (psp.PaymentServiceProvider, error) {
+	provider, err := pspFactory.NewPaymentServiceProvider(paymentMethod)
+	if errors.Is(err, psp.ErrUnsupportedPaymentMethod) {
+		return nil, apperrors.Validation("unsupported_payment_method", "payment method '%s' is not supported", paymentMethod)
+	}
 	if err != nil {
-		log.Panicf("Failed to get transaction: %v", err)
+		return nil, fmt.Errorf("failed to get payment provider: %w", err)
 	}
 
-	if err = tx.Cancel(); err != nil {
-		log.Panicf("Failed to cancel transaction: %v", err)
+	return provider, nil
+}
+
+func getTransaction(transactionRepo repos.TransactionRepo, transactionID string) (*entities.Transaction, error) {
+	txUUID, err := uuid.Parse(transactionID)
+	if err != nil {
+		return nil, apperrors.Validation("invalid_transaction_id", "invalid transaction ID '%s'", transactionID)
 	}
 
-	updated, err := srv.transactionRepo.UpdateConditional(tx, entities.TransactionStatuses.Pending)
+	tx, err := transactionRepo.GetByUUID(txUUID)
+	if repos.IsNotFound(err) {
+		return nil, apperrors.NotFound("transaction_not_found", "transaction '%s' not found", transactionID)
+	}
 	if err != nil {
-		log.Panicf("Failed to update transaction: %v", err)
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
 	}
+	return tx, nil
+}
 
-	if !updated {
-		log.Infof("Transaction '%s' was already processed by another request", req.TransactionID)
+// transactionCreateError reports a reused transaction UUID as a conflict and
+// a transaction over its periodic limits as such.
+func transactionCreateError(err error) error {
+	if repos.IsDuplicateKey(err) {
+		return apperrors.Conflict("duplicate_transaction", "a transaction with this UUID already exists")
 	}
+	var limitErr *entities.LimitExceededError
+	if errors.As(err, &limitErr) {
+		return limitExceeded(err)
+	}
+	return fmt.Errorf("failed to create transaction: %w", err)
+}
 
+// checkPrecision rejects an amount finer than the minor units of its currency,
+// such as 0.5 JPY or 1.005 TWD. Such amounts are rejected rather than rounded,
+// so that no user pays or receives an amount they did not ask for.
+func checkPrecision(amount money.Money) error {
+	if !amount.IsRounded() {
+		return apperrors.Validation("invalid_amount", "amount %s has more than %d decimal places for %s",
+			amount, money.MinorUnits(amount.Currency()), amount.Currency())
+	}
 	return nil
 }
+
+func currencyOrDefault(currency string) string {
+	if currency == "" {
+		return defaultCurrency
+	}
+	return currency
+}
@@ -1,9 +1,12 @@
 package services_test
 
 import (
+	"banking-system/apperrors"
 	"banking-system/entities"
 	"banking-system/models"
+	"banking-system/money"
 	"banking-system/psp"
+	"banking-system/repos"
 	"banking-system/services"
 	"errors"
 	"testing"
@@ -14,11 +17,13 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
 var (
 	userRepoMock        *repoMock.MockUserRepo
 	transactionRepoMock *repoMock.MockTransactionRepo
+	bankAccountRepoMock *repoMock.MockBankAccountRepo
 	pspFactoryMock      *pspMock.MockPSPFactory
 	paymentProviderMock *pspMock.MockPaymentServiceProvider
 )
@@ -27,23 +32,24 @@ func TestValidDeposit(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	userRepoMock = repoMock.NewMockUserRepo(ctrl)
 	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
 	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
 	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
 
 	req := &models.DepositRequest{
 		UUID:          uuid.New(),
 		UserID:        1,
-		Amount:        100.00,
+		Amount:        money.MustParse("100.00", "TWD"),
 		PaymentMethod: "AnyPay",
 	}
 
-	givenUserHasBalance(req.UserID, 0)
+	givenUserHasBalance(req.UserID, "0")
 	givenPayInResponse("https://doesnt.matter", nil)
 
 	// assert transaction is created
 	expectTransactionCreated()
 
-	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, pspFactoryMock)
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
 	_, err := sut.Deposit(req)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
@@ -54,43 +60,46 @@ func TestDeposit_PspRespondsError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	userRepoMock = repoMock.NewMockUserRepo(ctrl)
 	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
 	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
 
 	req := &models.DepositRequest{
 		UUID:          uuid.New(),
 		UserID:        1,
-		Amount:        100.00,
+		Amount:        money.MustParse("100.00", "TWD"),
 		PaymentMethod: "FragilePay",
 	}
 
-	givenUserHasBalance(req.UserID, 0)
+	givenUserHasBalance(req.UserID, "0")
 	givenPayInResponse("", errors.New("Something went wrong QQ"))
 
 	expectTransactionCreated()
 
-	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, pspFactoryMock)
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	_, err := sut.Deposit(req)
 
-	expectPanic(t, func() { sut.Deposit(req) })
+	assert.Equal(t, apperrors.Kinds.ProviderUnavailable, apperrors.KindOf(err))
 }
 
 func TestDeposit_MinimumAmount(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	userRepoMock = repoMock.NewMockUserRepo(ctrl)
 	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
 	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
 
 	req := &models.DepositRequest{
 		UUID:          uuid.New(),
 		UserID:        1,
-		Amount:        services.MIN_DEPOSIT_AMOUNT,
+		Amount:        money.MustParse("1.00", "TWD"),
 		PaymentMethod: "AnyPay",
 	}
 
-	givenUserHasBalance(req.UserID, 0)
+	givenUserHasBalance(req.UserID, "0")
 	givenPayInResponse("https://doesnt.matter", nil)
 	expectTransactionCreated()
 
-	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, pspFactoryMock)
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, limitedTo(t, depositLimits))
 	_, err := sut.Deposit(req)
 
 	assert.Nil(t, err, "Expected no error, got: %v", err)
@@ -100,20 +109,21 @@ func TestDeposit_MaximumAmount(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	userRepoMock = repoMock.NewMockUserRepo(ctrl)
 	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
 	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
 
 	req := &models.DepositRequest{
 		UUID:          uuid.New(),
 		UserID:        1,
-		Amount:        services.MAX_DEPOSIT_AMOUNT,
+		Amount:        money.MustParse("100000.00", "TWD"),
 		PaymentMethod: "AnyPay",
 	}
 
-	givenUserHasBalance(req.UserID, 0)
+	givenUserHasBalance(req.UserID, "0")
 	givenPayInResponse("https://doesnt.matter", nil)
 	expectTransactionCreated()
 
-	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, pspFactoryMock)
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, limitedTo(t, depositLimits))
 	_, err := sut.Deposit(req)
 
 	assert.Nil(t, err, "Expected no error, got: %v", err)
@@ -123,62 +133,74 @@ func TestDeposit_BelowMinimum(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	userRepoMock = repoMock.NewMockUserRepo(ctrl)
 	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
 	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
 
 	req := &models.DepositRequest{
 		UUID:          uuid.New(),
 		UserID:        1,
-		Amount:        services.MIN_DEPOSIT_AMOUNT - 1.00,
+		Amount:        money.MustParse("0.99", "TWD"),
 		PaymentMethod: "AnyPay",
 	}
 
-	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, pspFactoryMock)
+	givenSupportedPaymentMethod()
+	givenUserHasBalance(req.UserID, "0")
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, limitedTo(t, depositLimits))
 
 	_, err := sut.Deposit(req)
 
 	assert.NotNil(t, err, "Expected error for amount below minimum, got nil")
+	assert.Equal(t, "amount_below_minimum", apperrors.CodeOf(err))
 }
 
 func TestDeposit_AboveMaximum(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	userRepoMock = repoMock.NewMockUserRepo(ctrl)
 	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
 	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
 
 	req := &models.DepositRequest{
 		UUID:          uuid.New(),
 		UserID:        1,
-		Amount:        services.MAX_DEPOSIT_AMOUNT + 1.00,
+		Amount:        money.MustParse("100000.01", "TWD"),
 		PaymentMethod: "AnyPay",
 	}
 
-	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, pspFactoryMock)
+	givenSupportedPaymentMethod()
+	givenUserHasBalance(req.UserID, "0")
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, limitedTo(t, depositLimits))
 
 	_, err := sut.Deposit(req)
 	assert.NotNil(t, err, "Expected error for amount above maximum, got nil")
+	assert.Equal(t, "amount_above_maximum", apperrors.CodeOf(err))
 }
 
 func TestWithdraw_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	userRepoMock = repoMock.NewMockUserRepo(ctrl)
 	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
 	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
 
 	req := &models.WithdrawRequest{
-		UUID:   uuid.New(),
-		UserID: 1,
-		Amount: 50.00,
+		UUID:          uuid.New(),
+		UserID:        1,
+		Amount:        money.MustParse("50.00", "TWD"),
+		BankAccountID: 7,
 	}
 
 	// given user has sufficient balance
-	givenUserHasBalance(req.UserID, 100.00)
+	givenUserHasBalance(req.UserID, "100.00")
+	givenBankAccount(req.BankAccountID, req.UserID)
 
 	// assertions
-	expectTransactionCreated()
-	expectWalletUpdated()
+	expectTransactionCreatedWithJournalEntry()
 	expectPayOutCalled()
 
-	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, pspFactoryMock)
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
 	err := sut.Withdraw(req)
 
 	assert.Nil(t, err)
@@ -188,39 +210,419 @@ func TestWithdraw_InsufficientBalance(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	userRepoMock = repoMock.NewMockUserRepo(ctrl)
 	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
 	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
 
 	req := &models.WithdrawRequest{
-		UUID:   uuid.New(),
-		UserID: 1,
-		Amount: 150.00, // More than balance
+		UUID:          uuid.New(),
+		UserID:        1,
+		Amount:        money.MustParse("150.00", "TWD"), // More than balance
+		BankAccountID: 7,
 	}
 
-	givenUserHasBalance(req.UserID, 100)
+	givenSupportedPaymentMethod()
+	givenUserHasBalance(req.UserID, "100")
 
-	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, pspFactoryMock)
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
 	err := sut.Withdraw(req)
 
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "insufficient balance")
+	assert.Equal(t, apperrors.Kinds.InsufficientFunds, apperrors.KindOf(err))
+}
+
+func TestWithdraw_BalanceSpentConcurrently(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	req := &models.WithdrawRequest{
+		UUID:          uuid.New(),
+		UserID:        1,
+		Amount:        money.MustParse("50.00", "TWD"),
+		BankAccountID: 7,
+	}
+
+	// the balance read looks sufficient, but another request spends it first
+	givenSupportedPaymentMethod()
+	givenUserHasBalance(req.UserID, "100.00")
+	givenBankAccount(req.BankAccountID, req.UserID)
+	transactionRepoMock.EXPECT().
+		CreateWithJournalEntry(gomock.Any(), gomock.Any()).
+		Return(repos.ErrInsufficientFunds).
+		Times(1)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	err := sut.Withdraw(req)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "insufficient balance")
+	assert.Equal(t, apperrors.Kinds.InsufficientFunds, apperrors.KindOf(err))
+}
+
+func TestWithdraw_AnotherUsersBankAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	req := &models.WithdrawRequest{
+		UUID:          uuid.New(),
+		UserID:        1,
+		Amount:        money.MustParse("50.00", "TWD"),
+		BankAccountID: 7,
+	}
+
+	givenSupportedPaymentMethod()
+	givenUserHasBalance(req.UserID, "100.00")
+	givenBankAccount(req.BankAccountID, 2)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	err := sut.Withdraw(req)
+
+	assert.Equal(t, apperrors.Kinds.Forbidden, apperrors.KindOf(err))
+}
+
+func TestDeposit_NoWalletInCurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	req := &models.DepositRequest{
+		UUID:          uuid.New(),
+		UserID:        1,
+		Amount:        money.MustParse("100.00", ""),
+		Currency:      "USD",
+		PaymentMethod: "AnyPay",
+	}
+
+	givenSupportedPaymentMethod()
+	givenUserHasBalance(req.UserID, "0")
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	_, err := sut.Deposit(req)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "no USD wallet")
+	assert.Equal(t, apperrors.Kinds.NotFound, apperrors.KindOf(err))
+}
+
+func TestDeposit_UnsupportedCurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	req := &models.DepositRequest{
+		UUID:          uuid.New(),
+		UserID:        1,
+		Amount:        money.MustParse("100.00", ""),
+		Currency:      "XYZ",
+		PaymentMethod: "AnyPay",
+	}
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	_, err := sut.Deposit(req)
+
+	assert.NotNil(t, err)
+}
+
+func TestDeposit_ZeroAmount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	req := &models.DepositRequest{
+		UUID:          uuid.New(),
+		UserID:        1,
+		Amount:        money.MustParse("0", "TWD"),
+		PaymentMethod: "AnyPay",
+	}
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	_, err := sut.Deposit(req)
+
+	assert.Equal(t, apperrors.Kinds.Validation, apperrors.KindOf(err))
+	assert.Equal(t, "invalid_amount", apperrors.CodeOf(err))
+}
+
+func TestTransfer_NegativeAmount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	req := &models.TransferRequest{
+		UUID:              uuid.New(),
+		SenderUserID:      1,
+		RecipientUsername: "recipient",
+		Amount:            money.MustParse("-100.00", "TWD"),
+	}
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	err := sut.Transfer(req)
+
+	assert.Equal(t, apperrors.Kinds.Validation, apperrors.KindOf(err))
+	assert.Equal(t, "invalid_amount", apperrors.CodeOf(err))
+}
+
+func TestDeposit_AmountFinerThanMinorUnits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	req := &models.DepositRequest{
+		UUID:          uuid.New(),
+		UserID:        1,
+		Amount:        money.MustParse("1000.5", "JPY"),
+		Currency:      "JPY",
+		PaymentMethod: "AnyPay",
+	}
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	_, err := sut.Deposit(req)
+
+	assert.Equal(t, apperrors.Kinds.Validation, apperrors.KindOf(err))
+	assert.Equal(t, "invalid_amount", apperrors.CodeOf(err))
 }
 
-func givenUserHasBalance(userID uint, amount float64) {
+func TestTransfer_AmountFinerThanMinorUnits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	req := &models.TransferRequest{
+		UUID:              uuid.New(),
+		SenderUserID:      1,
+		RecipientUsername: "recipient",
+		Amount:            money.MustParse("1.005", "TWD"),
+	}
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	err := sut.Transfer(req)
+
+	assert.Equal(t, apperrors.Kinds.Validation, apperrors.KindOf(err))
+	assert.Equal(t, "invalid_amount", apperrors.CodeOf(err))
+}
+
+func TestDeposit_UnsupportedPaymentMethod(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	req := &models.DepositRequest{
+		UUID:          uuid.New(),
+		UserID:        1,
+		Amount:        money.MustParse("100.00", "TWD"),
+		PaymentMethod: "NoSuchPay",
+	}
+
+	// no transaction may be created for a payment method nobody can pay in
+	givenUnsupportedPaymentMethod(req.PaymentMethod)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	_, err := sut.Deposit(req)
+
+	assert.Equal(t, "unsupported_payment_method", apperrors.CodeOf(err))
+}
+
+func TestWithdraw_UnsupportedPaymentMethod(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	bankAccountRepoMock = repoMock.NewMockBankAccountRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	req := &models.WithdrawRequest{
+		UUID:          uuid.New(),
+		UserID:        1,
+		Amount:        money.MustParse("50.00", "TWD"),
+		BankAccountID: 7,
+		PaymentMethod: "NoSuchPay",
+	}
+
+	// the wallet must not be debited for a payout nobody can make
+	givenUnsupportedPaymentMethod(req.PaymentMethod)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	err := sut.Withdraw(req)
+
+	assert.Equal(t, "unsupported_payment_method", apperrors.CodeOf(err))
+}
+
+func TestConfirm_CreditsPendingDeposit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+
+	tx := givenStoredTransaction(entities.TransactionTypes.Deposit, entities.TransactionStatuses.Pending)
+	transactionRepoMock.EXPECT().
+		ApplyTransition(tx, transitionTo(entities.TransactionStatuses.Completed, entities.TransitionActors.PSP)).
+		Return(true, nil)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	err := sut.Confirm(&psp.ConfirmRequest{TransactionID: tx.UUID.String(), Provider: psp.PaymentMethods.FakePay})
+
+	assert.Nil(t, err)
+	assert.Equal(t, entities.TransactionStatuses.Completed, tx.Status)
+}
+
+func TestConfirm_AlreadyCompletedIsIgnored(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+
+	// ApplyTransition must not be called
+	tx := givenStoredTransaction(entities.TransactionTypes.Deposit, entities.TransactionStatuses.Completed)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	err := sut.Confirm(&psp.ConfirmRequest{TransactionID: tx.UUID.String(), Provider: psp.PaymentMethods.FakePay})
+
+	assert.Nil(t, err)
+}
+
+func TestConfirm_CanceledDepositIsConflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+
+	tx := givenStoredTransaction(entities.TransactionTypes.Deposit, entities.TransactionStatuses.Canceled)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	err := sut.Confirm(&psp.ConfirmRequest{TransactionID: tx.UUID.String(), Provider: psp.PaymentMethods.FakePay})
+
+	assert.Equal(t, "illegal_transition", apperrors.CodeOf(err))
+	assert.Equal(t, entities.TransactionStatuses.Canceled, tx.Status)
+}
+
+func TestConfirm_AnotherProvidersDepositIsNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+
+	// ApplyTransition must not be called
+	tx := givenStoredTransaction(entities.TransactionTypes.Deposit, entities.TransactionStatuses.Pending)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	err := sut.Confirm(&psp.ConfirmRequest{TransactionID: tx.UUID.String(), Provider: psp.PaymentMethods.BankTransfer})
+
+	assert.Equal(t, "transaction_not_found", apperrors.CodeOf(err))
+	assert.Equal(t, entities.TransactionStatuses.Pending, tx.Status)
+}
+
+func TestCancel_PendingDepositIsCanceled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+
+	tx := givenStoredTransaction(entities.TransactionTypes.Deposit, entities.TransactionStatuses.Pending)
+	transactionRepoMock.EXPECT().
+		ApplyTransition(tx, transitionTo(entities.TransactionStatuses.Canceled, entities.TransitionActors.PSP)).
+		Return(true, nil)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	err := sut.Cancel(&psp.CancelRequest{TransactionID: tx.UUID.String(), Provider: psp.PaymentMethods.FakePay})
+
+	assert.Nil(t, err)
+	assert.Equal(t, entities.TransactionStatuses.Canceled, tx.Status)
+}
+
+func TestCancel_TransferIsNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+
+	tx := givenStoredTransaction(entities.TransactionTypes.TransferOut, entities.TransactionStatuses.Completed)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	err := sut.Cancel(&psp.CancelRequest{TransactionID: tx.UUID.String(), Provider: psp.PaymentMethods.FakePay})
+
+	assert.Equal(t, "transaction_not_found", apperrors.CodeOf(err))
+}
+
+func TestCancel_WithdrawalIsNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+
+	// withdrawals are settled by payout callbacks; ApplyTransition must not be called
+	tx := givenStoredTransaction(entities.TransactionTypes.Withdrawal, entities.TransactionStatuses.Pending)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	err := sut.Cancel(&psp.CancelRequest{TransactionID: tx.UUID.String(), Provider: psp.PaymentMethods.FakePay})
+
+	assert.Equal(t, "transaction_not_found", apperrors.CodeOf(err))
+	assert.Equal(t, entities.TransactionStatuses.Pending, tx.Status)
+}
+
+func givenUserHasBalance(userID uint, amount string) {
 	userRepoMock.EXPECT().
 		Get(gomock.Any()).
 		Return(&entities.User{
-			Wallet: entities.Wallet{
-				UserID:  userID,
-				Balance: amount,
+			Wallets: []entities.Wallet{
+				{
+					UserID:   userID,
+					Currency: "TWD",
+					Balance:  money.MustParse(amount, "TWD"),
+				},
 			},
 		}, nil).
 		AnyTimes()
 }
 
+func givenBankAccount(bankAccountID uint, ownerID uint) {
+	bankAccountRepoMock.EXPECT().
+		GetByID(bankAccountID).
+		Return(&entities.BankAccount{
+			Model:         gorm.Model{ID: bankAccountID},
+			UserID:        ownerID,
+			BankCode:      "822",
+			AccountNumber: "123456789012",
+		}, nil).
+		Times(1)
+}
+
+func givenStoredTransaction(txType entities.TransactionType, status entities.TransactionStatus) *entities.Transaction {
+	tx := &entities.Transaction{
+		UUID:          uuid.New(),
+		Type:          txType,
+		Status:        status,
+		Amount:        money.MustParse("50.00", "TWD"),
+		Currency:      "TWD",
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		WalletID:      1,
+		Wallet:        &entities.Wallet{UserID: 1, Currency: "TWD"},
+	}
+	transactionRepoMock.EXPECT().GetByUUID(tx.UUID).Return(tx, nil).Times(1)
+	return tx
+}
+
+func givenSupportedPaymentMethod() {
+	pspFactoryMock.EXPECT().
+		NewPaymentServiceProvider(gomock.Any()).
+		Return(paymentProviderMock, nil).
+		Times(1)
+}
+
+func givenUnsupportedPaymentMethod(paymentMethod psp.PaymentMethod) {
+	pspFactoryMock.EXPECT().
+		NewPaymentServiceProvider(paymentMethod).
+		Return(nil, psp.ErrUnsupportedPaymentMethod).
+		Times(1)
+}
+
 func givenPayInResponse(redirectUrl string, err error) {
 	pspFactoryMock.EXPECT().
 		NewPaymentServiceProvider(gomock.Any()).
-		Return(paymentProviderMock).
+		Return(paymentProviderMock, nil).
 		Times(1)
 
 	paymentProviderMock.EXPECT().
@@ -237,29 +639,20 @@ func expectTransactionCreated() {
 		Times(1)
 }
 
-func expectWalletUpdated() {
-	userRepoMock.EXPECT().
-		UpdateWallet(gomock.Any()).
+func expectTransactionCreatedWithJournalEntry() {
+	transactionRepoMock.EXPECT().
+		CreateWithJournalEntry(gomock.Any(), gomock.Any()).
 		Times(1)
 }
 
 func expectPayOutCalled() {
 	pspFactoryMock.EXPECT().
 		NewPaymentServiceProvider(gomock.Any()).
-		Return(paymentProviderMock).
+		Return(paymentProviderMock, nil).
 		Times(1)
 
 	paymentProviderMock.EXPECT().
-		PayOut().
+		PayOut(gomock.Any()).
 		Return(&psp.PayOutResponse{}, nil).
 		Times(1)
 }
-
-func expectPanic(t *testing.T, f func()) {
-	defer func() {
-		if r := recover(); r == nil {
-			t.Errorf("The code did not panic")
-		}
-	}()
-	f()
-}
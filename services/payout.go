@@ -0,0 +1,149 @@
+package services
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/psp"
+	"banking-system/repos"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//go:generate mockgen -source=payout.go -destination=mock/payout.go
+
+const (
+	// PAYOUT_POLL_AFTER is how long a payout may go without a status callback
+	// before its provider is asked for the status.
+	PAYOUT_POLL_AFTER       = 5 * time.Minute
+	payOutPollBatchSize     = 100
+	maxPayOutUpdateAttempts = 3
+)
+
+type PayOutService interface {
+	UpdateStatus(req *psp.PayOutStatusCallback) error
+	PollUnsettled() error
+}
+
+type payOutService struct {
+	transactionRepo repos.TransactionRepo
+	pspFactory      psp.PSPFactory
+}
+
+func NewPayOutService(transactionRepo repos.TransactionRepo, pspFactory psp.PSPFactory) PayOutService {
+	return &payOutService{
+		transactionRepo: transactionRepo,
+		pspFactory:      pspFactory,
+	}
+}
+
+// UpdateStatus applies a status callback from the provider that made the
+// payout.
+func (srv *payOutService) UpdateStatus(req *psp.PayOutStatusCallback) error {
+	if !req.Status.IsValid() {
+		return apperrors.Validation("invalid_payout_status", "unknown payout status '%s'", req.Status)
+	}
+
+	trigger := entities.PSPTrigger(req.Provider, strings.TrimSpace(fmt.Sprintf("payout %s %s", req.Status, req.Reason)))
+	return srv.applyStatus(req.TransactionID, req.Status, req.Reference, trigger, func(tx *entities.Transaction) error {
+		// A provider can only report on its own payouts
+		if tx.Type != entities.TransactionTypes.Withdrawal || tx.PaymentMethod != req.Provider {
+			return apperrors.NotFound("transaction_not_found", "transaction '%s' not found", req.TransactionID)
+		}
+		return nil
+	})
+}
+
+// PollUnsettled asks providers for the status of payouts that have not had a
+// callback for PAYOUT_POLL_AFTER, in case a callback was lost.
+func (srv *payOutService) PollUnsettled() error {
+	payOuts, err := srv.transactionRepo.GetUnsettledPayOuts(time.Now().Add(-PAYOUT_POLL_AFTER), payOutPollBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to get unsettled payouts: %w", err)
+	}
+
+	for i := range payOuts {
+		if err := srv.poll(&payOuts[i]); err != nil {
+			log.Warnf("Failed to poll payout status of transaction '%s': %v", payOuts[i].UUID, err)
+		}
+	}
+
+	return nil
+}
+
+func (srv *payOutService) poll(tx *entities.Transaction) error {
+	provider, err := getProvider(srv.pspFactory, tx.PaymentMethod)
+	if err != nil {
+		return err
+	}
+
+	res, err := provider.GetPayOutStatus(&psp.PayOutStatusRequest{
+		TransactionID: tx.UUID.String(),
+		Reference:     tx.PSPReference,
+	})
+
+	switch {
+	case errors.Is(err, psp.ErrPayOutNotFound):
+		// The provider never accepted the payout, e.g. the request to it failed
+		res = &psp.PayOutStatusResponse{Status: psp.PayOutStatuses.Rejected}
+	case err != nil:
+		return apperrors.ProviderUnavailable(fmt.Sprintf("payment provider '%s'", tx.PaymentMethod), err)
+	}
+
+	if res.Status == tx.PayOutStatus {
+		return srv.transactionRepo.TouchPayOut(tx)
+	}
+
+	trigger := entities.SystemTrigger("payout poller", fmt.Sprintf("payout %s according to provider", res.Status))
+	return srv.applyStatus(tx.UUID.String(), res.Status, res.Reference, trigger, nil)
+}
+
+// applyStatus moves a payout to status, refunding the wallet if it failed. If
+// another callback changes the payout at the same time, the status is applied
+// again on top of the other change.
+func (srv *payOutService) applyStatus(transactionID string, status psp.PayOutStatus, reference string, trigger entities.Trigger, check func(tx *entities.Transaction) error) error {
+	for range maxPayOutUpdateAttempts {
+		tx, err := getTransaction(srv.transactionRepo, transactionID)
+		if err != nil {
+			return err
+		}
+
+		if check != nil {
+			if err := check(tx); err != nil {
+				return err
+			}
+		}
+
+		previous := tx.PayOutStatus
+		t, changed, err := tx.ApplyPayOutStatus(status, trigger)
+		if errors.Is(err, entities.ErrInvalidPayOutTransition) {
+			return apperrors.Conflict("invalid_payout_transition", "payout of transaction '%s' cannot move from %s to %s", transactionID, previous, status)
+		}
+		if err != nil {
+			return transitionError(err)
+		}
+
+		if !changed {
+			log.Infof("Ignoring %s status for payout of transaction '%s' in status %s", status, transactionID, previous)
+			return nil
+		}
+
+		if tx.PSPReference == "" {
+			tx.PSPReference = reference
+		}
+
+		updated, err := srv.transactionRepo.UpdatePayOut(tx, previous, t)
+		if err != nil {
+			return fmt.Errorf("failed to update payout: %w", err)
+		}
+
+		if updated {
+			return nil
+		}
+	}
+
+	return apperrors.Conflict("payout_update_conflict", "payout of transaction '%s' is being updated concurrently", transactionID)
+}
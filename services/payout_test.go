@@ -0,0 +1,274 @@
+package services_test
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/money"
+	"banking-system/psp"
+	"banking-system/services"
+	"fmt"
+	"testing"
+
+	pspMock "banking-system/psp/mock"
+	repoMock "banking-system/repos/mock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayOutStatus_PaidCompletesWithdrawal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	tx := givenPayOut(psp.PayOutStatuses.Processing)
+	transactionRepoMock.EXPECT().
+		UpdatePayOut(payOutWithStatus(psp.PayOutStatuses.Paid, entities.TransactionStatuses.Completed), psp.PayOutStatuses.Processing, transitionTo(entities.TransactionStatuses.Completed, entities.TransitionActors.PSP)).
+		Return(true, nil)
+
+	sut := services.NewPayOutService(transactionRepoMock, pspFactoryMock)
+	err := sut.UpdateStatus(payOutCallback(tx, psp.PayOutStatuses.Paid))
+
+	assert.Nil(t, err)
+}
+
+func TestPayOutStatus_RejectedRefundsWallet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	tx := givenPayOut(psp.PayOutStatuses.Submitted)
+	transactionRepoMock.EXPECT().
+		UpdatePayOut(payOutWithStatus(psp.PayOutStatuses.Rejected, entities.TransactionStatuses.Failed), psp.PayOutStatuses.Submitted, refundOf(tx)).
+		Return(true, nil)
+
+	sut := services.NewPayOutService(transactionRepoMock, pspFactoryMock)
+	err := sut.UpdateStatus(payOutCallback(tx, psp.PayOutStatuses.Rejected))
+
+	assert.Nil(t, err)
+}
+
+func TestPayOutStatus_ReturnedAfterPaidRefundsWallet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	tx := givenPayOut(psp.PayOutStatuses.Paid)
+	transactionRepoMock.EXPECT().
+		UpdatePayOut(payOutWithStatus(psp.PayOutStatuses.Returned, entities.TransactionStatuses.Failed), psp.PayOutStatuses.Paid, refundOf(tx)).
+		Return(true, nil)
+
+	sut := services.NewPayOutService(transactionRepoMock, pspFactoryMock)
+	err := sut.UpdateStatus(payOutCallback(tx, psp.PayOutStatuses.Returned))
+
+	assert.Nil(t, err)
+}
+
+func TestPayOutStatus_StaleCallbackIgnored(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	// PROCESSING arrives after PAID; UpdatePayOut must not be called
+	tx := givenPayOut(psp.PayOutStatuses.Paid)
+
+	sut := services.NewPayOutService(transactionRepoMock, pspFactoryMock)
+	err := sut.UpdateStatus(payOutCallback(tx, psp.PayOutStatuses.Processing))
+
+	assert.Nil(t, err)
+}
+
+func TestPayOutStatus_InvalidTransition(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	tx := givenPayOut(psp.PayOutStatuses.Paid)
+
+	sut := services.NewPayOutService(transactionRepoMock, pspFactoryMock)
+	err := sut.UpdateStatus(payOutCallback(tx, psp.PayOutStatuses.Rejected))
+
+	assert.Equal(t, "invalid_payout_transition", apperrors.CodeOf(err))
+}
+
+func TestPayOutStatus_OtherProvidersPayOut(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	tx := givenPayOut(psp.PayOutStatuses.Submitted)
+	req := payOutCallback(tx, psp.PayOutStatuses.Paid)
+	req.Provider = psp.PaymentMethods.BankTransfer
+
+	sut := services.NewPayOutService(transactionRepoMock, pspFactoryMock)
+	err := sut.UpdateStatus(req)
+
+	assert.Equal(t, apperrors.Kinds.NotFound, apperrors.KindOf(err))
+}
+
+func TestPayOutStatus_ConcurrentUpdateIsReapplied(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+
+	// the PROCESSING callback commits between reading and updating the payout
+	submitted := newPayOut(psp.PayOutStatuses.Submitted)
+	processing := *submitted
+	processing.PayOutStatus = psp.PayOutStatuses.Processing
+	gomock.InOrder(
+		transactionRepoMock.EXPECT().GetByUUID(submitted.UUID).Return(submitted, nil),
+		transactionRepoMock.EXPECT().UpdatePayOut(gomock.Any(), psp.PayOutStatuses.Submitted, gomock.Any()).Return(false, nil),
+		transactionRepoMock.EXPECT().GetByUUID(submitted.UUID).Return(&processing, nil),
+		transactionRepoMock.EXPECT().UpdatePayOut(gomock.Any(), psp.PayOutStatuses.Processing, gomock.Any()).Return(true, nil),
+	)
+
+	sut := services.NewPayOutService(transactionRepoMock, pspFactoryMock)
+	err := sut.UpdateStatus(payOutCallback(submitted, psp.PayOutStatuses.Paid))
+
+	assert.Nil(t, err)
+}
+
+func TestPayOutPoll_UnknownPayOutIsRejected(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
+
+	tx := newPayOut(psp.PayOutStatuses.Submitted)
+	transactionRepoMock.EXPECT().GetUnsettledPayOuts(gomock.Any(), gomock.Any()).Return([]entities.Transaction{*tx}, nil)
+	pspFactoryMock.EXPECT().NewPaymentServiceProvider(tx.PaymentMethod).Return(paymentProviderMock, nil)
+	paymentProviderMock.EXPECT().GetPayOutStatus(gomock.Any()).Return(nil, psp.ErrPayOutNotFound)
+	transactionRepoMock.EXPECT().GetByUUID(tx.UUID).Return(tx, nil)
+	transactionRepoMock.EXPECT().
+		UpdatePayOut(payOutWithStatus(psp.PayOutStatuses.Rejected, entities.TransactionStatuses.Failed), psp.PayOutStatuses.Submitted, refundOf(tx)).
+		Return(true, nil)
+
+	sut := services.NewPayOutService(transactionRepoMock, pspFactoryMock)
+	err := sut.PollUnsettled()
+
+	assert.Nil(t, err)
+}
+
+func TestPayOutPoll_UnchangedPayOutIsRequeued(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
+
+	tx := newPayOut(psp.PayOutStatuses.Processing)
+	transactionRepoMock.EXPECT().GetUnsettledPayOuts(gomock.Any(), gomock.Any()).Return([]entities.Transaction{*tx}, nil)
+	pspFactoryMock.EXPECT().NewPaymentServiceProvider(tx.PaymentMethod).Return(paymentProviderMock, nil)
+	paymentProviderMock.EXPECT().GetPayOutStatus(gomock.Any()).Return(&psp.PayOutStatusResponse{Status: psp.PayOutStatuses.Processing}, nil)
+	transactionRepoMock.EXPECT().TouchPayOut(gomock.Any()).Return(nil)
+
+	sut := services.NewPayOutService(transactionRepoMock, pspFactoryMock)
+	err := sut.PollUnsettled()
+
+	assert.Nil(t, err)
+}
+
+func TestPayOutPoll_UnsupportedPaymentMethodDoesNotStopBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
+
+	unsupported := newPayOut(psp.PayOutStatuses.Submitted)
+	unsupported.PaymentMethod = "RetiredPay"
+	tx := newPayOut(psp.PayOutStatuses.Processing)
+	transactionRepoMock.EXPECT().GetUnsettledPayOuts(gomock.Any(), gomock.Any()).Return([]entities.Transaction{*unsupported, *tx}, nil)
+	pspFactoryMock.EXPECT().NewPaymentServiceProvider(unsupported.PaymentMethod).Return(nil, psp.ErrUnsupportedPaymentMethod)
+	pspFactoryMock.EXPECT().NewPaymentServiceProvider(tx.PaymentMethod).Return(paymentProviderMock, nil)
+	paymentProviderMock.EXPECT().GetPayOutStatus(gomock.Any()).Return(&psp.PayOutStatusResponse{Status: psp.PayOutStatuses.Processing}, nil)
+	transactionRepoMock.EXPECT().TouchPayOut(gomock.Any()).Return(nil)
+
+	sut := services.NewPayOutService(transactionRepoMock, pspFactoryMock)
+	err := sut.PollUnsettled()
+
+	assert.Nil(t, err)
+}
+
+func newPayOut(status psp.PayOutStatus) *entities.Transaction {
+	txStatus := entities.TransactionStatuses.Pending
+	if status == psp.PayOutStatuses.Paid {
+		txStatus = entities.TransactionStatuses.Completed
+	}
+
+	return &entities.Transaction{
+		UUID:          uuid.New(),
+		Type:          entities.TransactionTypes.Withdrawal,
+		Status:        txStatus,
+		Amount:        money.MustParse("50.00", "TWD"),
+		Currency:      "TWD",
+		PaymentMethod: psp.PaymentMethods.FakePay,
+		PayOutStatus:  status,
+		WalletID:      1,
+		Wallet:        &entities.Wallet{UserID: 1, Currency: "TWD"},
+	}
+}
+
+func givenPayOut(status psp.PayOutStatus) *entities.Transaction {
+	tx := newPayOut(status)
+	transactionRepoMock.EXPECT().GetByUUID(tx.UUID).Return(tx, nil).Times(1)
+	return tx
+}
+
+func payOutCallback(tx *entities.Transaction, status psp.PayOutStatus) *psp.PayOutStatusCallback {
+	return &psp.PayOutStatusCallback{
+		TransactionID: tx.UUID.String(),
+		Status:        status,
+		Provider:      tx.PaymentMethod,
+	}
+}
+
+// matcher adapts a predicate to gomock.Matcher.
+type matcher struct {
+	description string
+	matches     func(x any) bool
+}
+
+func (m matcher) Matches(x any) bool { return m.matches(x) }
+func (m matcher) String() string     { return m.description }
+
+func payOutWithStatus(payOutStatus psp.PayOutStatus, status entities.TransactionStatus) gomock.Matcher {
+	return matcher{
+		description: fmt.Sprintf("payout %s with transaction status %s", payOutStatus, status),
+		matches: func(x any) bool {
+			tx, ok := x.(*entities.Transaction)
+			return ok && tx.PayOutStatus == payOutStatus && tx.Status == status
+		},
+	}
+}
+
+// transitionTo matches a status transition to status that is recorded on
+// behalf of actor.
+func transitionTo(status entities.TransactionStatus, actor entities.TransitionActor) gomock.Matcher {
+	return matcher{
+		description: fmt.Sprintf("transition to %s by %s", status, actor),
+		matches: func(x any) bool {
+			t, ok := x.(*entities.StatusTransition)
+			return ok && t != nil && t.To == status && t.Record != nil && t.Record.Actor == actor
+		},
+	}
+}
+
+// refundOf matches the transition whose journal entry returns the payout
+// amount to the wallet.
+func refundOf(tx *entities.Transaction) gomock.Matcher {
+	return matcher{
+		description: fmt.Sprintf("refund of %s to the wallet", tx.Amount),
+		matches: func(x any) bool {
+			t, ok := x.(*entities.StatusTransition)
+			if !ok || t == nil || t.Entry == nil {
+				return false
+			}
+			for _, posting := range t.Entry.Postings {
+				if posting.LedgerAccount.Type == entities.LedgerAccountTypes.Wallet && posting.Amount.Cmp(tx.Amount) == 0 {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/psp"
+	"banking-system/repos"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const refunds = "refunds"
+
+// Refund returns all or part of a completed deposit to the payer through the
+// deposit's provider. The refund is a new transaction related to the deposit;
+// the deposit itself is never changed.
+func (srv *paymentService) Refund(req *models.RefundRequest) (*models.TransactionResponse, error) {
+	deposit, err := getTransaction(srv.transactionRepo, req.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if deposit.Wallet == nil || deposit.Wallet.UserID != req.UserID {
+		return nil, apperrors.Forbidden("transaction_forbidden", "transaction '%s' belongs to another user", req.TransactionID)
+	}
+
+	if deposit.Type != entities.TransactionTypes.Deposit || deposit.Status != entities.TransactionStatuses.Completed {
+		return nil, apperrors.Unprocessable("transaction_not_refundable", "only completed deposits can be refunded; transaction '%s' is a %s %s", req.TransactionID, deposit.Status, deposit.Type)
+	}
+
+	amount := deposit.Amount
+	if req.Amount != nil {
+		amount = req.Amount.WithCurrency(deposit.Currency)
+	}
+
+	if !amount.IsPositive() {
+		return nil, apperrors.Validation("invalid_amount", "refund amount must be greater than zero")
+	}
+	if err := checkPrecision(amount); err != nil {
+		return nil, err
+	}
+
+	provider, err := getProvider(srv.pspFactory, deposit.PaymentMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	refund := &entities.Transaction{
+		UUID:                 req.UUID,
+		WalletID:             deposit.WalletID,
+		Amount:               amount,
+		Status:               entities.TransactionStatuses.Pending,
+		Type:                 entities.TransactionTypes.Refund,
+		PaymentMethod:        deposit.PaymentMethod,
+		Wallet:               deposit.Wallet,
+		RelatedTransactionID: &deposit.UUID,
+	}
+
+	err = srv.transactionRepo.CreateRefund(refund, entities.NewRefundEntry(refund))
+	if errors.Is(err, repos.ErrRefundExceedsAmount) {
+		return nil, apperrors.Unprocessable("refund_exceeds_amount", "refunding %s would exceed the deposit amount %s", amount, deposit.Amount)
+	}
+	if errors.Is(err, repos.ErrInsufficientFunds) {
+		return nil, apperrors.InsufficientFunds("insufficient balance: requested refund %s", amount)
+	}
+	if err != nil {
+		return nil, transactionCreateError(err)
+	}
+
+	res, err := provider.Refund(&psp.RefundRequest{
+		TransactionID:      refund.UUID.String(),
+		PayInTransactionID: deposit.UUID.String(),
+		Amount:             amount,
+	})
+	if err != nil {
+		trigger := entities.SystemTrigger(refunds, fmt.Sprintf("provider failed to refund: %v", err))
+		if failErr := applyTransition(srv.transactionRepo, refund, entities.TransactionStatuses.Failed, trigger); failErr != nil {
+			log.Errorf("Failed to return the amount of refund '%s': %v", refund.UUID, failErr)
+		}
+		return nil, apperrors.AfterCommit(apperrors.ProviderUnavailable(fmt.Sprintf("payment provider '%s'", deposit.PaymentMethod), err))
+	}
+
+	if res.Reference != "" {
+		if err := srv.transactionRepo.SetPSPReference(refund.UUID, res.Reference); err != nil {
+			log.Errorf("Failed to record PSP reference '%s' for transaction '%s': %v", res.Reference, refund.UUID, err)
+		}
+	}
+
+	trigger := entities.PSPTrigger(deposit.PaymentMethod, "refund accepted by provider")
+	if err := applyTransition(srv.transactionRepo, refund, entities.TransactionStatuses.Completed, trigger); err != nil {
+		return nil, err
+	}
+
+	return newTransactionResponse(refund), nil
+}
+
+// Reverse undoes a completed transfer on behalf of an operator by moving its
+// amount from the recipient back to the sender. The reversal is a new pair of
+// transactions related to the transfer; the transfer itself is never changed.
+func (srv *paymentService) Reverse(req *models.ReversalRequest) (*models.TransactionResponse, error) {
+	tx, err := getTransaction(srv.transactionRepo, req.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if tx.Type != entities.TransactionTypes.TransferOut && tx.Type != entities.TransactionTypes.TransferIn {
+		return nil, apperrors.Unprocessable("transaction_not_reversible", "only transfers can be reversed; transaction '%s' is a %s", req.TransactionID, tx.Type)
+	}
+
+	if tx.RelatedTransactionID == nil {
+		return nil, fmt.Errorf("transfer '%s' has no counterpart", tx.UUID)
+	}
+
+	counterpart, err := getTransaction(srv.transactionRepo, tx.RelatedTransactionID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	transferOutTx, transferInTx := tx, counterpart
+	if tx.Type == entities.TransactionTypes.TransferIn {
+		transferOutTx, transferInTx = counterpart, tx
+	}
+
+	if transferOutTx.Status != entities.TransactionStatuses.Completed || transferInTx.Status != entities.TransactionStatuses.Completed {
+		return nil, apperrors.Unprocessable("transaction_not_reversible", "only completed transfers can be reversed")
+	}
+
+	reversalOutTx := &entities.Transaction{
+		UUID:                 uuid.New(),
+		WalletID:             transferInTx.WalletID,
+		Amount:               transferInTx.Amount,
+		Status:               entities.TransactionStatuses.Completed,
+		Type:                 entities.TransactionTypes.ReversalOut,
+		Wallet:               transferInTx.Wallet,
+		RelatedTransactionID: &transferInTx.UUID,
+	}
+
+	reversalInTx := &entities.Transaction{
+		UUID:                 req.UUID,
+		WalletID:             transferOutTx.WalletID,
+		Amount:               transferOutTx.Amount,
+		Status:               entities.TransactionStatuses.Completed,
+		Type:                 entities.TransactionTypes.ReversalIn,
+		Wallet:               transferOutTx.Wallet,
+		RelatedTransactionID: &transferOutTx.UUID,
+	}
+
+	// Reversals are created COMPLETED, so their creation is the only change
+	// that names the operator
+	trigger := entities.OperatorTrigger(req.OperatorID, req.Reason)
+	entry := entities.NewReversalEntry(reversalOutTx, reversalInTx)
+	err = srv.transactionRepo.CreateReversal(reversalOutTx, reversalInTx, entry,
+		reversalOutTx.CreationRecord(trigger), reversalInTx.CreationRecord(trigger))
+	if errors.Is(err, repos.ErrAlreadyReversed) {
+		return nil, apperrors.Conflict("already_reversed", "transfer '%s' is already reversed", transferOutTx.UUID)
+	}
+	if errors.Is(err, repos.ErrInsufficientFunds) {
+		return nil, apperrors.InsufficientFunds("recipient balance is too low to reverse %s", transferInTx.Amount)
+	}
+	if err != nil {
+		return nil, transactionCreateError(err)
+	}
+
+	return newTransactionResponse(reversalInTx), nil
+}
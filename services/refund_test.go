@@ -0,0 +1,191 @@
+package services_test
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/psp"
+	"banking-system/repos"
+	"banking-system/services"
+	"errors"
+	"testing"
+
+	pspMock "banking-system/psp/mock"
+	repoMock "banking-system/repos/mock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefund_FullDeposit(t *testing.T) {
+	setUpRefundMocks(t)
+
+	deposit := givenStoredTransaction(entities.TransactionTypes.Deposit, entities.TransactionStatuses.Completed)
+	transactionRepoMock.EXPECT().
+		CreateRefund(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(refund *entities.Transaction, entry *entities.JournalEntry) error {
+			assert.Equal(t, deposit.Amount, refund.Amount)
+			assert.Equal(t, deposit.UUID, *refund.RelatedTransactionID)
+			assert.Equal(t, entities.TransactionStatuses.Pending, refund.Status)
+			return nil
+		})
+	givenRefundResponse(&psp.RefundResponse{Reference: "FPR-1"}, nil)
+	transactionRepoMock.EXPECT().SetPSPReference(gomock.Any(), "FPR-1").Return(nil)
+	transactionRepoMock.EXPECT().
+		ApplyTransition(gomock.Any(), transitionTo(entities.TransactionStatuses.Completed, entities.TransitionActors.PSP)).
+		Return(true, nil)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	res, err := sut.Refund(&models.RefundRequest{UUID: uuid.New(), UserID: 1, TransactionID: deposit.UUID.String()})
+
+	assert.Nil(t, err)
+	assert.Equal(t, entities.TransactionTypes.Refund, res.Type)
+	assert.Equal(t, entities.TransactionStatuses.Completed, res.Status)
+	assert.Equal(t, entities.TransactionStatuses.Completed, deposit.Status, "the deposit must not change")
+}
+
+func TestRefund_ExceedsDepositAmount(t *testing.T) {
+	setUpRefundMocks(t)
+
+	deposit := givenStoredTransaction(entities.TransactionTypes.Deposit, entities.TransactionStatuses.Completed)
+	givenSupportedPaymentMethod()
+	transactionRepoMock.EXPECT().CreateRefund(gomock.Any(), gomock.Any()).Return(repos.ErrRefundExceedsAmount)
+
+	amount := money.MustParse("10.00", "TWD")
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	_, err := sut.Refund(&models.RefundRequest{UUID: uuid.New(), UserID: 1, TransactionID: deposit.UUID.String(), Amount: &amount})
+
+	assert.Equal(t, "refund_exceeds_amount", apperrors.CodeOf(err))
+}
+
+func TestRefund_AnotherUsersDeposit(t *testing.T) {
+	setUpRefundMocks(t)
+
+	deposit := givenStoredTransaction(entities.TransactionTypes.Deposit, entities.TransactionStatuses.Completed)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	_, err := sut.Refund(&models.RefundRequest{UUID: uuid.New(), UserID: 2, TransactionID: deposit.UUID.String()})
+
+	assert.Equal(t, "transaction_forbidden", apperrors.CodeOf(err))
+}
+
+func TestRefund_OnlyCompletedDeposits(t *testing.T) {
+	setUpRefundMocks(t)
+
+	withdrawal := givenStoredTransaction(entities.TransactionTypes.Withdrawal, entities.TransactionStatuses.Completed)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	_, err := sut.Refund(&models.RefundRequest{UUID: uuid.New(), UserID: 1, TransactionID: withdrawal.UUID.String()})
+
+	assert.Equal(t, "transaction_not_refundable", apperrors.CodeOf(err))
+}
+
+func TestRefund_ProviderFailureReturnsAmount(t *testing.T) {
+	setUpRefundMocks(t)
+
+	deposit := givenStoredTransaction(entities.TransactionTypes.Deposit, entities.TransactionStatuses.Completed)
+	transactionRepoMock.EXPECT().CreateRefund(gomock.Any(), gomock.Any()).Return(nil)
+	givenRefundResponse(nil, errors.New("connection reset"))
+	transactionRepoMock.EXPECT().
+		ApplyTransition(gomock.Any(), transitionTo(entities.TransactionStatuses.Failed, entities.TransitionActors.System)).
+		DoAndReturn(func(refund *entities.Transaction, transition *entities.StatusTransition) (bool, error) {
+			assert.NotNil(t, transition.Entry, "the refunded amount must be returned to the wallet")
+			return true, nil
+		})
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	_, err := sut.Refund(&models.RefundRequest{UUID: uuid.New(), UserID: 1, TransactionID: deposit.UUID.String()})
+
+	assert.Equal(t, apperrors.Kinds.ProviderUnavailable, apperrors.KindOf(err))
+}
+
+func TestReverse_Transfer(t *testing.T) {
+	setUpRefundMocks(t)
+
+	transferOut, transferIn := givenStoredTransfer()
+	transactionRepoMock.EXPECT().
+		CreateReversal(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(reversalOut *entities.Transaction, reversalIn *entities.Transaction, entry *entities.JournalEntry, audit ...*entities.TransactionStatusChange) error {
+			assert.Equal(t, transferIn.WalletID, reversalOut.WalletID)
+			assert.Equal(t, transferIn.UUID, *reversalOut.RelatedTransactionID)
+			assert.Equal(t, transferOut.WalletID, reversalIn.WalletID)
+			assert.Equal(t, transferOut.UUID, *reversalIn.RelatedTransactionID)
+			for _, record := range audit {
+				assert.Equal(t, entities.TransitionActors.Operator, record.Actor)
+				assert.Equal(t, "ops", record.ActorID)
+			}
+			return nil
+		})
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	res, err := sut.Reverse(&models.ReversalRequest{UUID: uuid.New(), OperatorID: "ops", TransactionID: transferIn.UUID.String(), Reason: "fraud"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, entities.TransactionTypes.ReversalIn, res.Type)
+}
+
+func TestReverse_AlreadyReversed(t *testing.T) {
+	setUpRefundMocks(t)
+
+	transferOut, _ := givenStoredTransfer()
+	transactionRepoMock.EXPECT().
+		CreateReversal(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(repos.ErrAlreadyReversed)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	_, err := sut.Reverse(&models.ReversalRequest{UUID: uuid.New(), OperatorID: "ops", TransactionID: transferOut.UUID.String(), Reason: "fraud"})
+
+	assert.Equal(t, "already_reversed", apperrors.CodeOf(err))
+}
+
+func TestReverse_OnlyTransfers(t *testing.T) {
+	setUpRefundMocks(t)
+
+	deposit := givenStoredTransaction(entities.TransactionTypes.Deposit, entities.TransactionStatuses.Completed)
+
+	sut := services.NewPaymentService(userRepoMock, transactionRepoMock, bankAccountRepoMock, pspFactoryMock, nil, unlimited(t))
+	_, err := sut.Reverse(&models.ReversalRequest{UUID: uuid.New(), OperatorID: "ops", TransactionID: deposit.UUID.String(), Reason: "fraud"})
+
+	assert.Equal(t, "transaction_not_reversible", apperrors.CodeOf(err))
+}
+
+func setUpRefundMocks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	pspFactoryMock = pspMock.NewMockPSPFactory(ctrl)
+	paymentProviderMock = pspMock.NewMockPaymentServiceProvider(ctrl)
+}
+
+func givenStoredTransfer() (transferOut *entities.Transaction, transferIn *entities.Transaction) {
+	transferOut = &entities.Transaction{
+		UUID:     uuid.New(),
+		Type:     entities.TransactionTypes.TransferOut,
+		Status:   entities.TransactionStatuses.Completed,
+		Amount:   money.MustParse("10.00", "TWD"),
+		Currency: "TWD",
+		WalletID: 1,
+		Wallet:   &entities.Wallet{UserID: 1, Currency: "TWD"},
+	}
+	transferIn = &entities.Transaction{
+		UUID:                 uuid.New(),
+		Type:                 entities.TransactionTypes.TransferIn,
+		Status:               entities.TransactionStatuses.Completed,
+		Amount:               money.MustParse("10.00", "TWD"),
+		Currency:             "TWD",
+		WalletID:             2,
+		Wallet:               &entities.Wallet{UserID: 2, Currency: "TWD"},
+		RelatedTransactionID: &transferOut.UUID,
+	}
+	transferOut.RelatedTransactionID = &transferIn.UUID
+
+	transactionRepoMock.EXPECT().GetByUUID(transferOut.UUID).Return(transferOut, nil).AnyTimes()
+	transactionRepoMock.EXPECT().GetByUUID(transferIn.UUID).Return(transferIn, nil).AnyTimes()
+	return transferOut, transferIn
+}
+
+func givenRefundResponse(res *psp.RefundResponse, err error) {
+	pspFactoryMock.EXPECT().NewPaymentServiceProvider(psp.PaymentMethods.FakePay).Return(paymentProviderMock, nil)
+	paymentProviderMock.EXPECT().Refund(gomock.Any()).Return(res, err)
+}
@@ -0,0 +1,401 @@
+package services
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repos"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+//go:generate mockgen -source=scheduledTransfer.go -destination=mock/scheduledTransfer.go
+
+const (
+	// scheduleBatchSize is the number of due schedules executed per run, so
+	// that a run ends well within the lease of its job. Schedules that are
+	// still due are executed on the next run.
+	scheduleBatchSize = 100
+
+	occurrenceLimit    = 100
+	maxOccurrenceError = 500
+)
+
+type ScheduledTransferService interface {
+	Create(req *models.CreateScheduledTransferRequest) (*models.ScheduledTransferResponse, error)
+	GetAll(userID uint) ([]models.ScheduledTransferResponse, error)
+	GetOccurrences(userID uint, id string) ([]models.ScheduledTransferOccurrenceResponse, error)
+	Pause(userID uint, id string) (*models.ScheduledTransferResponse, error)
+	Resume(userID uint, id string) (*models.ScheduledTransferResponse, error)
+	Cancel(userID uint, id string) (*models.ScheduledTransferResponse, error)
+	ExecuteDue() error
+}
+
+type scheduledTransferService struct {
+	scheduledTransferRepo repos.ScheduledTransferRepo
+	userRepo              repos.UserRepo
+	paymentSrv            PaymentService
+}
+
+func NewScheduledTransferService(scheduledTransferRepo repos.ScheduledTransferRepo, userRepo repos.UserRepo, paymentSrv PaymentService) ScheduledTransferService {
+	return &scheduledTransferService{
+		scheduledTransferRepo: scheduledTransferRepo,
+		userRepo:              userRepo,
+		paymentSrv:            paymentSrv,
+	}
+}
+
+func (srv *scheduledTransferService) Create(req *models.CreateScheduledTransferRequest) (*models.ScheduledTransferResponse, error) {
+	frequency := entities.ScheduleFrequency(req.Frequency)
+	if !frequency.IsValid() {
+		return nil, apperrors.Validation("invalid_frequency", "unknown frequency '%s'", req.Frequency)
+	}
+
+	today := entities.Day(time.Now())
+	startDate, err := time.Parse(time.DateOnly, req.StartDate)
+	if err != nil {
+		return nil, apperrors.Validation("invalid_start_date", "start_date '%s' is not a YYYY-MM-DD date", req.StartDate)
+	}
+	if startDate.Before(today) {
+		return nil, apperrors.Validation("invalid_start_date", "start_date must not be in the past")
+	}
+
+	var endDate *time.Time
+	if req.EndDate != "" {
+		date, err := time.Parse(time.DateOnly, req.EndDate)
+		if err != nil {
+			return nil, apperrors.Validation("invalid_end_date", "end_date '%s' is not a YYYY-MM-DD date", req.EndDate)
+		}
+		if date.Before(startDate) {
+			return nil, apperrors.Validation("invalid_end_date", "end_date must not be before start_date")
+		}
+		endDate = &date
+	}
+
+	dayOfMonth := req.DayOfMonth
+	if frequency == entities.ScheduleFrequencies.Monthly {
+		if dayOfMonth == 0 {
+			dayOfMonth = startDate.Day()
+		}
+	} else if dayOfMonth != 0 {
+		return nil, apperrors.Validation("invalid_day_of_month", "day_of_month is only used by %s schedules", entities.ScheduleFrequencies.Monthly)
+	}
+
+	currency := currencyOrDefault(req.Currency)
+	amount := req.Amount.WithCurrency(currency)
+	if !money.IsSupported(currency) {
+		return nil, apperrors.Validation("unsupported_currency", "currency '%s' is not supported", currency)
+	}
+	if !amount.IsPositive() {
+		return nil, apperrors.Validation("invalid_amount", "transfer amount must be greater than zero")
+	}
+	if err := checkPrecision(amount); err != nil {
+		return nil, err
+	}
+
+	sender, err := getUser(srv.userRepo, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	senderWallet, ok := sender.WalletFor(currency)
+	if !ok {
+		return nil, apperrors.NotFound("wallet_not_found", "sender has no %s wallet", currency)
+	}
+
+	recipient, err := srv.userRepo.GetByUsername(req.RecipientUsername)
+	if repos.IsNotFound(err) {
+		return nil, apperrors.NotFound("recipient_not_found", "recipient user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recipient user: %w", err)
+	}
+
+	if sender.ID == recipient.ID {
+		return nil, apperrors.Validation("same_user_transfer", "cannot transfer to the same user")
+	}
+	if _, ok := recipient.WalletFor(currency); !ok {
+		return nil, apperrors.Validation("recipient_currency_unsupported", "recipient cannot receive %s: no %s wallet", currency, currency)
+	}
+
+	schedule := &entities.ScheduledTransfer{
+		UUID:              uuid.New(),
+		UserID:            sender.ID,
+		WalletID:          senderWallet.ID,
+		RecipientUsername: recipient.Username,
+		Amount:            amount,
+		Frequency:         frequency,
+		StartDate:         startDate,
+		DayOfMonth:        dayOfMonth,
+		EndDate:           endDate,
+		Count:             req.Count,
+	}
+	schedule.Start(today)
+	if schedule.NextRunOn == nil {
+		return nil, apperrors.Validation("no_occurrences", "the schedule has no occurrence on or before end_date")
+	}
+
+	if err := srv.scheduledTransferRepo.Create(schedule); err != nil {
+		return nil, fmt.Errorf("failed to create scheduled transfer: %w", err)
+	}
+
+	res := newScheduledTransferResponse(schedule)
+	return &res, nil
+}
+
+func (srv *scheduledTransferService) GetAll(userID uint) ([]models.ScheduledTransferResponse, error) {
+	schedules, err := srv.scheduledTransferRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled transfers: %w", err)
+	}
+
+	res := make([]models.ScheduledTransferResponse, len(schedules))
+	for i := range schedules {
+		res[i] = newScheduledTransferResponse(&schedules[i])
+	}
+	return res, nil
+}
+
+// GetOccurrences returns the latest occurrences of a schedule of the user,
+// the most recent first.
+func (srv *scheduledTransferService) GetOccurrences(userID uint, id string) ([]models.ScheduledTransferOccurrenceResponse, error) {
+	schedule, err := srv.getSchedule(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences, err := srv.scheduledTransferRepo.GetOccurrences(schedule.UUID, occurrenceLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get occurrences of scheduled transfer '%s': %w", schedule.UUID, err)
+	}
+
+	res := make([]models.ScheduledTransferOccurrenceResponse, len(occurrences))
+	for i := range occurrences {
+		res[i] = newScheduledTransferOccurrenceResponse(&occurrences[i])
+	}
+	return res, nil
+}
+
+// Pause stops an active schedule. The occurrences that fall before it is
+// resumed are skipped.
+func (srv *scheduledTransferService) Pause(userID uint, id string) (*models.ScheduledTransferResponse, error) {
+	return srv.updateStatus(userID, id, "scheduled_transfer_not_active", func(schedule *entities.ScheduledTransfer) error {
+		return schedule.Pause()
+	})
+}
+
+// Resume continues a paused schedule with its first occurrence from today on.
+func (srv *scheduledTransferService) Resume(userID uint, id string) (*models.ScheduledTransferResponse, error) {
+	today := entities.Day(time.Now())
+	return srv.updateStatus(userID, id, "scheduled_transfer_not_paused", func(schedule *entities.ScheduledTransfer) error {
+		return schedule.Resume(today)
+	})
+}
+
+// Cancel ends an active or paused schedule for good.
+func (srv *scheduledTransferService) Cancel(userID uint, id string) (*models.ScheduledTransferResponse, error) {
+	return srv.updateStatus(userID, id, "scheduled_transfer_not_active", func(schedule *entities.ScheduledTransfer) error {
+		return schedule.Cancel()
+	})
+}
+
+func (srv *scheduledTransferService) updateStatus(userID uint, id string, code string, change func(*entities.ScheduledTransfer) error) (*models.ScheduledTransferResponse, error) {
+	schedule, err := srv.getSchedule(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedStatus := schedule.Status
+	if err := change(schedule); err != nil {
+		return nil, apperrors.Conflict(code, "%s: it is %s", err.Error(), expectedStatus)
+	}
+
+	updated, err := srv.scheduledTransferRepo.UpdateStatus(schedule, expectedStatus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update scheduled transfer '%s': %w", schedule.UUID, err)
+	}
+	if !updated {
+		return nil, apperrors.Conflict("scheduled_transfer_changed", "scheduled transfer changed meanwhile, try again")
+	}
+
+	res := newScheduledTransferResponse(schedule)
+	return &res, nil
+}
+
+// ExecuteDue makes the due occurrences of the active schedules, the longest
+// overdue first. An overdue schedule only makes its latest due occurrence; the
+// ones missed before it, for example while the service was down, are skipped
+// rather than paid back-to-back.
+//
+// An occurrence is claimed before its transfer is made: the schedule is moved
+// on only if it is still active and due on the same day, so that a schedule
+// paused or canceled meanwhile makes no transfer. Each occurrence is a
+// transfer whose UUID is derived from the schedule and the day, so that an
+// occurrence made again after a crash is rejected as a duplicate rather than
+// paid twice. An occurrence the transfer fails for, such as for insufficient
+// funds, is recorded as failed and notified to the user. An occurrence that
+// fails on an unexpected error stays pending and is retried on the next run.
+func (srv *scheduledTransferService) ExecuteDue() error {
+	pending, err := srv.scheduledTransferRepo.GetPendingOccurrences(scheduleBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to get pending occurrences: %w", err)
+	}
+
+	for i := range pending {
+		if err := srv.retry(&pending[i]); err != nil {
+			log.Warnf("Failed to make occurrence of scheduled transfer '%s': %v", pending[i].ScheduledTransferID, err)
+		}
+	}
+
+	today := entities.Day(time.Now())
+	schedules, err := srv.scheduledTransferRepo.GetDue(today, scheduleBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to get due scheduled transfers: %w", err)
+	}
+
+	for i := range schedules {
+		if err := srv.execute(&schedules[i], today); err != nil {
+			log.Warnf("Failed to execute scheduled transfer '%s': %v", schedules[i].UUID, err)
+		}
+	}
+
+	return nil
+}
+
+func (srv *scheduledTransferService) execute(schedule *entities.ScheduledTransfer, today time.Time) error {
+	expectedRunOn := entities.Day(*schedule.NextRunOn)
+	runOn, ok := schedule.LatestDue(today)
+	if !ok {
+		return nil
+	}
+
+	occurrence := &entities.ScheduledTransferOccurrence{
+		ScheduledTransferID: schedule.UUID,
+		RunOn:               runOn,
+		Sequence:            schedule.Occurrences + 1,
+		Status:              entities.OccurrenceStatuses.Pending,
+		TransactionID:       entities.OccurrenceTransactionID(schedule.UUID, runOn),
+	}
+
+	schedule.Advance(runOn)
+	claimed, err := srv.scheduledTransferRepo.ClaimOccurrence(schedule, expectedRunOn, occurrence)
+	if err != nil {
+		return fmt.Errorf("failed to claim occurrence on %s: %w", runOn.Format(time.DateOnly), err)
+	}
+	if !claimed {
+		log.Infof("Occurrence of scheduled transfer '%s' on %s was made or the schedule changed meanwhile", schedule.UUID, runOn.Format(time.DateOnly))
+		return nil
+	}
+	if runOn.After(expectedRunOn) {
+		log.Infof("Skipped the occurrences of scheduled transfer '%s' from %s to %s", schedule.UUID,
+			expectedRunOn.Format(time.DateOnly), runOn.AddDate(0, 0, -1).Format(time.DateOnly))
+	}
+
+	return srv.makeOccurrence(schedule, occurrence)
+}
+
+// retry makes a pending occurrence whose outcome a previous run did not
+// record.
+func (srv *scheduledTransferService) retry(occurrence *entities.ScheduledTransferOccurrence) error {
+	schedule, err := srv.scheduledTransferRepo.GetByUUID(occurrence.ScheduledTransferID)
+	if err != nil {
+		return fmt.Errorf("failed to get scheduled transfer: %w", err)
+	}
+
+	return srv.makeOccurrence(schedule, occurrence)
+}
+
+// makeOccurrence makes the transfer of a claimed occurrence and records its
+// outcome.
+func (srv *scheduledTransferService) makeOccurrence(schedule *entities.ScheduledTransfer, occurrence *entities.ScheduledTransferOccurrence) error {
+	runOn := entities.Day(occurrence.RunOn)
+	err := srv.paymentSrv.Transfer(&models.TransferRequest{
+		UUID:              occurrence.TransactionID,
+		SenderUserID:      schedule.UserID,
+		RecipientUsername: schedule.RecipientUsername,
+		Amount:            schedule.Amount,
+		Currency:          schedule.Currency,
+	})
+	// A duplicate is the transfer of an occurrence that was made before its
+	// outcome could be recorded
+	if err != nil && apperrors.CodeOf(err) != "duplicate_transaction" {
+		appErr := apperrors.As(err)
+		if appErr == nil || appErr.Kind == apperrors.Kinds.Internal {
+			return fmt.Errorf("transfer of occurrence on %s failed, retrying on the next run: %w", runOn.Format(time.DateOnly), err)
+		}
+		occurrence.Status = entities.OccurrenceStatuses.Failed
+		occurrence.FailureCode = appErr.Code
+		occurrence.FailureReason = truncate(appErr.Message, maxOccurrenceError)
+	} else {
+		occurrence.Status = entities.OccurrenceStatuses.Succeeded
+	}
+
+	recorded, err := srv.scheduledTransferRepo.RecordOccurrence(schedule, occurrence)
+	if err != nil {
+		return fmt.Errorf("failed to record occurrence on %s: %w", runOn.Format(time.DateOnly), err)
+	}
+	if !recorded {
+		log.Infof("Occurrence of scheduled transfer '%s' on %s was already recorded", schedule.UUID, runOn.Format(time.DateOnly))
+	}
+	return nil
+}
+
+func (srv *scheduledTransferService) getSchedule(userID uint, id string) (*entities.ScheduledTransfer, error) {
+	scheduleID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, apperrors.Validation("invalid_scheduled_transfer_id", "invalid scheduled transfer ID '%s'", id)
+	}
+
+	schedule, err := srv.scheduledTransferRepo.GetByUUID(scheduleID)
+	if repos.IsNotFound(err) {
+		return nil, apperrors.NotFound("scheduled_transfer_not_found", "scheduled transfer not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled transfer '%s': %w", scheduleID, err)
+	}
+
+	if schedule.UserID != userID {
+		return nil, apperrors.Forbidden("scheduled_transfer_forbidden", "scheduled transfer belongs to another user")
+	}
+	return schedule, nil
+}
+
+func newScheduledTransferResponse(schedule *entities.ScheduledTransfer) models.ScheduledTransferResponse {
+	res := models.ScheduledTransferResponse{
+		ID:                schedule.UUID,
+		RecipientUsername: schedule.RecipientUsername,
+		Amount:            schedule.Amount,
+		Currency:          schedule.Currency,
+		Frequency:         string(schedule.Frequency),
+		StartDate:         schedule.StartDate.Format(time.DateOnly),
+		DayOfMonth:        schedule.DayOfMonth,
+		Count:             schedule.Count,
+		Status:            string(schedule.Status),
+		Occurrences:       schedule.Occurrences,
+		CreatedAt:         schedule.CreatedAt,
+	}
+
+	if schedule.EndDate != nil {
+		res.EndDate = schedule.EndDate.Format(time.DateOnly)
+	}
+	if schedule.NextRunOn != nil && schedule.Status == entities.ScheduledTransferStatuses.Active {
+		res.NextRunOn = schedule.NextRunOn.Format(time.DateOnly)
+	}
+	return res
+}
+
+func newScheduledTransferOccurrenceResponse(occurrence *entities.ScheduledTransferOccurrence) models.ScheduledTransferOccurrenceResponse {
+	return models.ScheduledTransferOccurrenceResponse{
+		RunOn:         occurrence.RunOn.Format(time.DateOnly),
+		Sequence:      occurrence.Sequence,
+		Status:        string(occurrence.Status),
+		TransactionID: occurrence.TransactionID,
+		FailureCode:   occurrence.FailureCode,
+		FailureReason: occurrence.FailureReason,
+		CreatedAt:     occurrence.CreatedAt,
+	}
+}
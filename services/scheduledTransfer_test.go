@@ -0,0 +1,362 @@
+package services_test
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/services"
+	"errors"
+	"testing"
+	"time"
+
+	repoMock "banking-system/repos/mock"
+	serviceMock "banking-system/services/mock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var (
+	scheduledTransferRepoMock *repoMock.MockScheduledTransferRepo
+	paymentServiceMock        *serviceMock.MockPaymentService
+)
+
+func TestScheduledTransfer_CreateRejectsPastStartDate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	scheduledTransferRepoMock = repoMock.NewMockScheduledTransferRepo(ctrl)
+
+	sut := services.NewScheduledTransferService(scheduledTransferRepoMock, nil, nil)
+	_, err := sut.Create(&models.CreateScheduledTransferRequest{
+		UserID:            1,
+		RecipientUsername: "landlord",
+		Amount:            twd("15000"),
+		Frequency:         "MONTHLY",
+		StartDate:         time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly),
+	})
+
+	assert.Equal(t, "invalid_start_date", apperrors.CodeOf(err))
+}
+
+func TestScheduledTransfer_CreateRejectsDayOfMonthOfWeeklySchedule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	scheduledTransferRepoMock = repoMock.NewMockScheduledTransferRepo(ctrl)
+
+	sut := services.NewScheduledTransferService(scheduledTransferRepoMock, nil, nil)
+	_, err := sut.Create(&models.CreateScheduledTransferRequest{
+		UserID:            1,
+		RecipientUsername: "kid",
+		Amount:            twd("500"),
+		Frequency:         "WEEKLY",
+		StartDate:         time.Now().UTC().Format(time.DateOnly),
+		DayOfMonth:        5,
+	})
+
+	assert.Equal(t, "invalid_day_of_month", apperrors.CodeOf(err))
+}
+
+func TestScheduledTransfer_CreateSchedulesLastBusinessDay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	scheduledTransferRepoMock = repoMock.NewMockScheduledTransferRepo(ctrl)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+
+	givenTenantAndLandlord()
+	scheduledTransferRepoMock.EXPECT().Create(gomock.Any()).Return(nil)
+
+	// 2099-05-31 is a Sunday
+	sut := services.NewScheduledTransferService(scheduledTransferRepoMock, userRepoMock, nil)
+	res, err := sut.Create(&models.CreateScheduledTransferRequest{
+		UserID:            1,
+		RecipientUsername: "landlord",
+		Amount:            twd("15000"),
+		Frequency:         "LAST_BUSINESS_DAY",
+		StartDate:         "2099-05-02",
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "ACTIVE", res.Status)
+	assert.Equal(t, "2099-05-29", res.NextRunOn)
+}
+
+func TestScheduledTransfer_CreateRejectsEndBeforeFirstOccurrence(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	scheduledTransferRepoMock = repoMock.NewMockScheduledTransferRepo(ctrl)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+
+	givenTenantAndLandlord()
+
+	sut := services.NewScheduledTransferService(scheduledTransferRepoMock, userRepoMock, nil)
+	_, err := sut.Create(&models.CreateScheduledTransferRequest{
+		UserID:            1,
+		RecipientUsername: "landlord",
+		Amount:            twd("15000"),
+		Frequency:         "MONTHLY",
+		StartDate:         "2099-05-02",
+		DayOfMonth:        31,
+		EndDate:           "2099-05-30",
+	})
+
+	assert.Equal(t, "no_occurrences", apperrors.CodeOf(err))
+}
+
+func TestScheduledTransfer_PauseCanceledScheduleIsConflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	scheduledTransferRepoMock = repoMock.NewMockScheduledTransferRepo(ctrl)
+
+	schedule := givenSchedule(entities.ScheduleFrequencies.Monthly)
+	schedule.Status = entities.ScheduledTransferStatuses.Canceled
+	scheduledTransferRepoMock.EXPECT().GetByUUID(schedule.UUID).Return(schedule, nil)
+
+	sut := services.NewScheduledTransferService(scheduledTransferRepoMock, nil, nil)
+	_, err := sut.Pause(1, schedule.UUID.String())
+
+	assert.Equal(t, "scheduled_transfer_not_active", apperrors.CodeOf(err))
+}
+
+func TestScheduledTransfer_CancelAnotherUsersScheduleIsForbidden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	scheduledTransferRepoMock = repoMock.NewMockScheduledTransferRepo(ctrl)
+
+	schedule := givenSchedule(entities.ScheduleFrequencies.Monthly)
+	scheduledTransferRepoMock.EXPECT().GetByUUID(schedule.UUID).Return(schedule, nil)
+
+	sut := services.NewScheduledTransferService(scheduledTransferRepoMock, nil, nil)
+	_, err := sut.Cancel(2, schedule.UUID.String())
+
+	assert.Equal(t, "scheduled_transfer_forbidden", apperrors.CodeOf(err))
+}
+
+func TestScheduledTransfer_ExecuteDueTransfersWithOccurrenceID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	scheduledTransferRepoMock = repoMock.NewMockScheduledTransferRepo(ctrl)
+	paymentServiceMock = serviceMock.NewMockPaymentService(ctrl)
+
+	schedule := givenSchedule(entities.ScheduleFrequencies.Monthly)
+	runOn := *schedule.NextRunOn
+	givenDue(schedule)
+	scheduledTransferRepoMock.EXPECT().ClaimOccurrence(gomock.Any(), runOn, gomock.Any()).
+		DoAndReturn(func(s *entities.ScheduledTransfer, _ time.Time, o *entities.ScheduledTransferOccurrence) (bool, error) {
+			assert.Equal(t, entities.OccurrenceStatuses.Pending, o.Status)
+			assert.Equal(t, runOn, o.RunOn)
+			assert.Equal(t, 1, s.Occurrences)
+			assert.True(t, s.NextRunOn.After(runOn))
+			return true, nil
+		})
+
+	paymentServiceMock.EXPECT().Transfer(gomock.Any()).DoAndReturn(func(req *models.TransferRequest) error {
+		assert.Equal(t, entities.OccurrenceTransactionID(schedule.UUID, runOn), req.UUID)
+		assert.Equal(t, uint(1), req.SenderUserID)
+		assert.Equal(t, "landlord", req.RecipientUsername)
+		return nil
+	})
+	scheduledTransferRepoMock.EXPECT().RecordOccurrence(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(s *entities.ScheduledTransfer, o *entities.ScheduledTransferOccurrence) (bool, error) {
+			assert.Equal(t, entities.OccurrenceStatuses.Succeeded, o.Status)
+			assert.Equal(t, 1, o.Sequence)
+			return true, nil
+		})
+
+	sut := services.NewScheduledTransferService(scheduledTransferRepoMock, nil, paymentServiceMock)
+	err := sut.ExecuteDue()
+
+	assert.Nil(t, err)
+}
+
+func TestScheduledTransfer_ExecuteDueRecordsInsufficientFundsAsFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	scheduledTransferRepoMock = repoMock.NewMockScheduledTransferRepo(ctrl)
+	paymentServiceMock = serviceMock.NewMockPaymentService(ctrl)
+
+	schedule := givenSchedule(entities.ScheduleFrequencies.Once)
+	givenDue(schedule)
+	givenClaimed()
+
+	paymentServiceMock.EXPECT().Transfer(gomock.Any()).Return(apperrors.InsufficientFunds("insufficient balance"))
+	scheduledTransferRepoMock.EXPECT().RecordOccurrence(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(s *entities.ScheduledTransfer, o *entities.ScheduledTransferOccurrence) (bool, error) {
+			assert.Equal(t, entities.OccurrenceStatuses.Failed, o.Status)
+			assert.Equal(t, "insufficient_funds", o.FailureCode)
+			assert.Equal(t, entities.ScheduledTransferStatuses.Completed, s.Status)
+			assert.Nil(t, s.NextRunOn)
+
+			events := o.Events(s)
+			assert.Len(t, events, 1)
+			assert.Equal(t, entities.EventTypes.ScheduledTransferFailed, events[0].Type)
+			return true, nil
+		})
+
+	sut := services.NewScheduledTransferService(scheduledTransferRepoMock, nil, paymentServiceMock)
+	err := sut.ExecuteDue()
+
+	assert.Nil(t, err)
+}
+
+func TestScheduledTransfer_ExecuteDueTreatsDuplicateAsSucceeded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	scheduledTransferRepoMock = repoMock.NewMockScheduledTransferRepo(ctrl)
+	paymentServiceMock = serviceMock.NewMockPaymentService(ctrl)
+
+	schedule := givenSchedule(entities.ScheduleFrequencies.Daily)
+	givenDue(schedule)
+	givenClaimed()
+
+	paymentServiceMock.EXPECT().Transfer(gomock.Any()).
+		Return(apperrors.Conflict("duplicate_transaction", "a transaction with this UUID already exists"))
+	scheduledTransferRepoMock.EXPECT().RecordOccurrence(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(s *entities.ScheduledTransfer, o *entities.ScheduledTransferOccurrence) (bool, error) {
+			assert.Equal(t, entities.OccurrenceStatuses.Succeeded, o.Status)
+			return false, nil
+		})
+
+	sut := services.NewScheduledTransferService(scheduledTransferRepoMock, nil, paymentServiceMock)
+	err := sut.ExecuteDue()
+
+	assert.Nil(t, err)
+}
+
+func TestScheduledTransfer_ExecuteDueRetriesUnexpectedErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	scheduledTransferRepoMock = repoMock.NewMockScheduledTransferRepo(ctrl)
+	paymentServiceMock = serviceMock.NewMockPaymentService(ctrl)
+
+	schedule := givenSchedule(entities.ScheduleFrequencies.Daily)
+	givenDue(schedule)
+	givenClaimed()
+
+	paymentServiceMock.EXPECT().Transfer(gomock.Any()).Return(errors.New("connection reset"))
+	scheduledTransferRepoMock.EXPECT().RecordOccurrence(gomock.Any(), gomock.Any()).Times(0)
+
+	sut := services.NewScheduledTransferService(scheduledTransferRepoMock, nil, paymentServiceMock)
+	err := sut.ExecuteDue()
+
+	assert.Nil(t, err)
+}
+
+func TestScheduledTransfer_ExecuteDueSkipsScheduleChangedBeforeClaim(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	scheduledTransferRepoMock = repoMock.NewMockScheduledTransferRepo(ctrl)
+	paymentServiceMock = serviceMock.NewMockPaymentService(ctrl)
+
+	schedule := givenSchedule(entities.ScheduleFrequencies.Daily)
+	givenDue(schedule)
+	scheduledTransferRepoMock.EXPECT().ClaimOccurrence(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil)
+
+	paymentServiceMock.EXPECT().Transfer(gomock.Any()).Times(0)
+	scheduledTransferRepoMock.EXPECT().RecordOccurrence(gomock.Any(), gomock.Any()).Times(0)
+
+	sut := services.NewScheduledTransferService(scheduledTransferRepoMock, nil, paymentServiceMock)
+	err := sut.ExecuteDue()
+
+	assert.Nil(t, err)
+}
+
+func TestScheduledTransfer_ExecuteDueSkipsMissedOccurrences(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	scheduledTransferRepoMock = repoMock.NewMockScheduledTransferRepo(ctrl)
+	paymentServiceMock = serviceMock.NewMockPaymentService(ctrl)
+
+	today := entities.Day(time.Now())
+	missedFrom := today.AddDate(0, 0, -5)
+	schedule := givenSchedule(entities.ScheduleFrequencies.Daily)
+	schedule.StartDate = missedFrom
+	schedule.NextRunOn = &missedFrom
+	givenDue(schedule)
+
+	scheduledTransferRepoMock.EXPECT().ClaimOccurrence(gomock.Any(), missedFrom, gomock.Any()).
+		DoAndReturn(func(s *entities.ScheduledTransfer, _ time.Time, o *entities.ScheduledTransferOccurrence) (bool, error) {
+			assert.Equal(t, today, o.RunOn)
+			assert.Equal(t, 1, o.Sequence)
+			assert.Equal(t, today.AddDate(0, 0, 1), *s.NextRunOn)
+			return true, nil
+		})
+	paymentServiceMock.EXPECT().Transfer(gomock.Any()).DoAndReturn(func(req *models.TransferRequest) error {
+		assert.Equal(t, entities.OccurrenceTransactionID(schedule.UUID, today), req.UUID)
+		return nil
+	})
+	scheduledTransferRepoMock.EXPECT().RecordOccurrence(gomock.Any(), gomock.Any()).Return(true, nil)
+
+	sut := services.NewScheduledTransferService(scheduledTransferRepoMock, nil, paymentServiceMock)
+	err := sut.ExecuteDue()
+
+	assert.Nil(t, err)
+}
+
+func TestScheduledTransfer_ExecuteDueRetriesPendingOccurrences(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	scheduledTransferRepoMock = repoMock.NewMockScheduledTransferRepo(ctrl)
+	paymentServiceMock = serviceMock.NewMockPaymentService(ctrl)
+
+	schedule := givenSchedule(entities.ScheduleFrequencies.Daily)
+	runOn := *schedule.NextRunOn
+	pending := entities.ScheduledTransferOccurrence{
+		ID:                  7,
+		ScheduledTransferID: schedule.UUID,
+		RunOn:               runOn,
+		Sequence:            1,
+		Status:              entities.OccurrenceStatuses.Pending,
+		TransactionID:       entities.OccurrenceTransactionID(schedule.UUID, runOn),
+	}
+	scheduledTransferRepoMock.EXPECT().GetPendingOccurrences(gomock.Any()).Return([]entities.ScheduledTransferOccurrence{pending}, nil)
+	scheduledTransferRepoMock.EXPECT().GetByUUID(schedule.UUID).Return(schedule, nil)
+	scheduledTransferRepoMock.EXPECT().GetDue(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+	paymentServiceMock.EXPECT().Transfer(gomock.Any()).
+		Return(apperrors.Conflict("duplicate_transaction", "a transaction with this UUID already exists"))
+	scheduledTransferRepoMock.EXPECT().RecordOccurrence(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(s *entities.ScheduledTransfer, o *entities.ScheduledTransferOccurrence) (bool, error) {
+			assert.Equal(t, uint(7), o.ID)
+			assert.Equal(t, entities.OccurrenceStatuses.Succeeded, o.Status)
+			return true, nil
+		})
+
+	sut := services.NewScheduledTransferService(scheduledTransferRepoMock, nil, paymentServiceMock)
+	err := sut.ExecuteDue()
+
+	assert.Nil(t, err)
+}
+
+// givenDue stores schedule as the only due schedule, with no pending
+// occurrences.
+func givenDue(schedule *entities.ScheduledTransfer) {
+	scheduledTransferRepoMock.EXPECT().GetPendingOccurrences(gomock.Any()).Return(nil, nil)
+	scheduledTransferRepoMock.EXPECT().GetDue(gomock.Any(), gomock.Any()).Return([]entities.ScheduledTransfer{*schedule}, nil)
+}
+
+// givenClaimed claims every occurrence.
+func givenClaimed() {
+	scheduledTransferRepoMock.EXPECT().ClaimOccurrence(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+}
+
+// givenTenantAndLandlord stores user 1, "tenant", and user 2, "landlord",
+// both with a TWD wallet.
+func givenTenantAndLandlord() {
+	userRepoMock.EXPECT().Get(uint(1)).Return(&entities.User{
+		Model:    gorm.Model{ID: 1},
+		Username: "tenant",
+		Wallets:  []entities.Wallet{{Model: gorm.Model{ID: 10}, UserID: 1, Currency: "TWD"}},
+	}, nil)
+	userRepoMock.EXPECT().GetByUsername("landlord").Return(&entities.User{
+		Model:    gorm.Model{ID: 2},
+		Username: "landlord",
+		Wallets:  []entities.Wallet{{Model: gorm.Model{ID: 20}, UserID: 2, Currency: "TWD"}},
+	}, nil)
+}
+
+// givenSchedule returns an active schedule of user 1 that is due today.
+func givenSchedule(frequency entities.ScheduleFrequency) *entities.ScheduledTransfer {
+	today := entities.Day(time.Now())
+	schedule := &entities.ScheduledTransfer{
+		UUID:              uuid.New(),
+		UserID:            1,
+		WalletID:          10,
+		RecipientUsername: "landlord",
+		Amount:            twd("15000"),
+		Currency:          "TWD",
+		Frequency:         frequency,
+		StartDate:         today,
+		DayOfMonth:        today.Day(),
+	}
+	schedule.Start(today)
+	return schedule
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"banking-system/apperrors"
+	"banking-system/auth"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/repos"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//go:generate mockgen -source=session.go -destination=mock/session.go
+
+const (
+	SESSION_REVOKED_LOGOUT       = "LOGOUT"
+	SESSION_REVOKED_BY_USER      = "REVOKED_BY_USER"
+	SESSION_REVOKED_TOKEN_REUSED = "REFRESH_TOKEN_REUSED"
+	sessionUserAgentMaxLength    = 255
+	sessionIPAddressMaxLength    = 45
+)
+
+type SessionService interface {
+	Start(userID uint, userAgent string, ipAddress string) (*models.TokenResponse, error)
+	Refresh(refreshToken string, userAgent string, ipAddress string) (*models.TokenResponse, error)
+	Logout(userID uint, sessionID uuid.UUID) error
+	List(userID uint, currentSessionID uuid.UUID) ([]models.SessionResponse, error)
+	Revoke(userID uint, sessionID uuid.UUID) error
+}
+
+type sessionService struct {
+	sessionRepo repos.SessionRepo
+	authCfg     auth.Config
+}
+
+func NewSessionService(sessionRepo repos.SessionRepo, authCfg auth.Config) SessionService {
+	return &sessionService{
+		sessionRepo: sessionRepo,
+		authCfg:     authCfg,
+	}
+}
+
+// Start opens a new session for a user who has just proven their credentials.
+func (srv *sessionService) Start(userID uint, userAgent string, ipAddress string) (*models.TokenResponse, error) {
+	now := time.Now()
+	session := &entities.Session{
+		UUID:       uuid.New(),
+		UserID:     userID,
+		UserAgent:  truncate(userAgent, sessionUserAgentMaxLength),
+		IPAddress:  truncate(ipAddress, sessionIPAddressMaxLength),
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(auth.REFRESH_TOKEN_TTL),
+	}
+
+	refreshToken, hash, err := auth.NewRefreshToken(session.UUID)
+	if err != nil {
+		return nil, err
+	}
+	session.RefreshTokenHash = hash
+
+	if err := srv.sessionRepo.Create(session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return srv.tokenResponse(session, refreshToken)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token. Each refresh token can be used once: presenting one that has already
+// been rotated means it was stolen, so the session is revoked for both the
+// thief and the legitimate client.
+func (srv *sessionService) Refresh(refreshToken string, userAgent string, ipAddress string) (*models.TokenResponse, error) {
+	sessionID, hash, err := auth.ParseRefreshToken(refreshToken)
+	if errors.Is(err, auth.ErrInvalidRefreshToken) {
+		return nil, apperrors.Unauthorized("invalid_refresh_token", "invalid refresh token")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := srv.sessionRepo.GetByUUID(sessionID)
+	if repos.IsNotFound(err) {
+		return nil, apperrors.Unauthorized("invalid_refresh_token", "invalid refresh token")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if !session.IsActive(time.Now()) {
+		return nil, apperrors.Unauthorized("session_revoked", "session has been revoked or has expired")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(session.RefreshTokenHash)) != 1 {
+		return nil, srv.revokeReusedSession(session)
+	}
+
+	newToken, newHash, err := auth.NewRefreshToken(session.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	session.RefreshTokenHash = newHash
+	session.Generation++
+	session.LastUsedAt = time.Now()
+	session.UserAgent = truncate(userAgent, sessionUserAgentMaxLength)
+	session.IPAddress = truncate(ipAddress, sessionIPAddressMaxLength)
+
+	rotated, err := srv.sessionRepo.Rotate(session, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	// Another request rotated the same token first, so it was used twice
+	if !rotated {
+		return nil, srv.revokeReusedSession(session)
+	}
+
+	return srv.tokenResponse(session, newToken)
+}
+
+func (srv *sessionService) Logout(userID uint, sessionID uuid.UUID) error {
+	if _, err := srv.sessionRepo.RevokeForUser(userID, sessionID, SESSION_REVOKED_LOGOUT); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+func (srv *sessionService) List(userID uint, currentSessionID uuid.UUID) ([]models.SessionResponse, error) {
+	sessions, err := srv.sessionRepo.GetActiveByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	response := make([]models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, models.SessionResponse{
+			ID:         session.UUID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			Current:    session.UUID == currentSessionID,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+		})
+	}
+
+	return response, nil
+}
+
+func (srv *sessionService) Revoke(userID uint, sessionID uuid.UUID) error {
+	revoked, err := srv.sessionRepo.RevokeForUser(userID, sessionID, SESSION_REVOKED_BY_USER)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	// Another user's session is reported as missing rather than forbidden
+	if !revoked {
+		return apperrors.NotFound("session_not_found", "session not found")
+	}
+
+	return nil
+}
+
+func (srv *sessionService) revokeReusedSession(session *entities.Session) error {
+	if err := srv.sessionRepo.Revoke(session.UUID, SESSION_REVOKED_TOKEN_REUSED); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return apperrors.Unauthorized("refresh_token_reused", "refresh token has already been used; the session has been revoked")
+}
+
+func (srv *sessionService) tokenResponse(session *entities.Session, refreshToken string) (*models.TokenResponse, error) {
+	accessToken, err := srv.authCfg.IssueAccessToken(session.UserID, session.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(auth.ACCESS_TOKEN_TTL.Seconds()),
+	}, nil
+}
+
+func truncate(s string, maxLength int) string {
+	if len(s) <= maxLength {
+		return s
+	}
+	// Cut on a byte boundary, then drop a rune that was split in half
+	return strings.ToValidUTF8(s[:maxLength], "")
+}
@@ -0,0 +1,116 @@
+package services_test
+
+import (
+	"banking-system/apperrors"
+	"banking-system/auth"
+	"banking-system/entities"
+	"banking-system/services"
+	"testing"
+	"time"
+
+	repoMock "banking-system/repos/mock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var testAuthConfig = auth.Config{
+	Secret:   []byte("session_test_secret"),
+	Issuer:   auth.DEFAULT_ISSUER,
+	Audience: auth.DEFAULT_AUDIENCE,
+}
+
+func TestSessionRefresh_RotatesToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	sessionRepoMock := repoMock.NewMockSessionRepo(ctrl)
+
+	session, refreshToken := givenSession(t)
+	sessionRepoMock.EXPECT().GetByUUID(session.UUID).Return(session, nil)
+	sessionRepoMock.EXPECT().Rotate(gomock.Any(), session.RefreshTokenHash).Return(true, nil)
+
+	sut := services.NewSessionService(sessionRepoMock, testAuthConfig)
+	tokens, err := sut.Refresh(refreshToken, "test-agent", "127.0.0.1")
+
+	assert.Nil(t, err)
+	assert.NotEqual(t, refreshToken, tokens.RefreshToken)
+
+	userID, sessionID, err := testAuthConfig.ParseAccessToken(tokens.AccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, session.UserID, userID)
+	assert.Equal(t, session.UUID, sessionID)
+}
+
+func TestSessionRefresh_ReusedTokenRevokesSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	sessionRepoMock := repoMock.NewMockSessionRepo(ctrl)
+
+	session, staleToken := givenSession(t)
+	_, session.RefreshTokenHash, _ = auth.NewRefreshToken(session.UUID) // already rotated
+	sessionRepoMock.EXPECT().GetByUUID(session.UUID).Return(session, nil)
+	sessionRepoMock.EXPECT().Revoke(session.UUID, services.SESSION_REVOKED_TOKEN_REUSED).Times(1)
+
+	sut := services.NewSessionService(sessionRepoMock, testAuthConfig)
+	_, err := sut.Refresh(staleToken, "test-agent", "127.0.0.1")
+
+	assert.Equal(t, apperrors.Kinds.Unauthorized, apperrors.KindOf(err))
+	assert.Equal(t, "refresh_token_reused", apperrors.CodeOf(err))
+}
+
+func TestSessionRefresh_ConcurrentReuseRevokesSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	sessionRepoMock := repoMock.NewMockSessionRepo(ctrl)
+
+	// the token matches when read, but another request rotates it first
+	session, refreshToken := givenSession(t)
+	sessionRepoMock.EXPECT().GetByUUID(session.UUID).Return(session, nil)
+	sessionRepoMock.EXPECT().Rotate(gomock.Any(), gomock.Any()).Return(false, nil)
+	sessionRepoMock.EXPECT().Revoke(session.UUID, services.SESSION_REVOKED_TOKEN_REUSED).Times(1)
+
+	sut := services.NewSessionService(sessionRepoMock, testAuthConfig)
+	_, err := sut.Refresh(refreshToken, "test-agent", "127.0.0.1")
+
+	assert.Equal(t, "refresh_token_reused", apperrors.CodeOf(err))
+}
+
+func TestSessionRefresh_RevokedSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	sessionRepoMock := repoMock.NewMockSessionRepo(ctrl)
+
+	session, refreshToken := givenSession(t)
+	revokedAt := time.Now()
+	session.RevokedAt = &revokedAt
+	sessionRepoMock.EXPECT().GetByUUID(session.UUID).Return(session, nil)
+
+	sut := services.NewSessionService(sessionRepoMock, testAuthConfig)
+	_, err := sut.Refresh(refreshToken, "test-agent", "127.0.0.1")
+
+	assert.Equal(t, "session_revoked", apperrors.CodeOf(err))
+}
+
+func TestSessionRevoke_AnotherUsersSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	sessionRepoMock := repoMock.NewMockSessionRepo(ctrl)
+
+	sessionID := uuid.New()
+	sessionRepoMock.EXPECT().RevokeForUser(uint(2), sessionID, services.SESSION_REVOKED_BY_USER).Return(false, nil)
+
+	sut := services.NewSessionService(sessionRepoMock, testAuthConfig)
+	err := sut.Revoke(2, sessionID)
+
+	assert.Equal(t, apperrors.Kinds.NotFound, apperrors.KindOf(err))
+}
+
+func givenSession(t *testing.T) (*entities.Session, string) {
+	sessionID := uuid.New()
+	refreshToken, hash, err := auth.NewRefreshToken(sessionID)
+	assert.Nil(t, err)
+
+	return &entities.Session{
+		UUID:             sessionID,
+		UserID:           1,
+		RefreshTokenHash: hash,
+		LastUsedAt:       time.Now(),
+		ExpiresAt:        time.Now().Add(auth.REFRESH_TOKEN_TTL),
+	}, refreshToken
+}
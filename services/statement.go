@@ -0,0 +1,337 @@
+package services
+
+import (
+	"banking-system/apperrors"
+	"banking-system/blobs"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/money"
+	"banking-system/repos"
+	"banking-system/statements"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+//go:generate mockgen -source=statement.go -destination=mock/statement.go
+
+const (
+	// statementBatchSize is the number of lines read from the ledger at a
+	// time while a statement is written.
+	statementBatchSize = 500
+
+	// issueBatchSize is the number of wallets read at a time when issuing
+	// monthly statements.
+	issueBatchSize = 100
+
+	monthFormat = "2006-01"
+)
+
+type StatementService interface {
+	Open(req *models.StatementRequest) (*StatementExport, error)
+	IssueMonthly() error
+	GetIssued(userID uint) ([]models.IssuedStatementResponse, error)
+	Download(userID uint, statementID string) (*StatementDocument, error)
+}
+
+type statementService struct {
+	userRepo      repos.UserRepo
+	statementRepo repos.StatementRepo
+	store         blobs.Store
+}
+
+func NewStatementService(userRepo repos.UserRepo, statementRepo repos.StatementRepo, store blobs.Store) StatementService {
+	return &statementService{
+		userRepo:      userRepo,
+		statementRepo: statementRepo,
+		store:         store,
+	}
+}
+
+// StatementExport is a statement ready to be written. Its lines are read in
+// batches while it is written, so statements of any length can be streamed.
+type StatementExport struct {
+	Format   statements.Format
+	Filename string
+
+	header        *statements.Header
+	wallet        *entities.Wallet
+	statementRepo repos.StatementRepo
+}
+
+// StatementDocument is the document of an issued statement.
+type StatementDocument struct {
+	Filename    string
+	ContentType string
+	ContentHash string
+	Data        []byte
+}
+
+// Open checks the request and reads the opening balance of the statement,
+// so that nothing but write errors can happen once it is written.
+func (srv *statementService) Open(req *models.StatementRequest) (*StatementExport, error) {
+	if req.To.Before(req.From) {
+		return nil, apperrors.Validation("invalid_date_range", "from must not be after to")
+	}
+
+	user, err := getUser(srv.userRepo, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	currency := currencyOrDefault(req.Currency)
+	wallet, ok := user.WalletFor(currency)
+	if !ok {
+		return nil, apperrors.NotFound("wallet_not_found", "user has no %s wallet", currency)
+	}
+
+	// Statements end after the last day requested
+	export, err := srv.newExport(user, wallet, req.Format, req.From.UTC(), req.To.UTC().AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	export.Filename = fmt.Sprintf("statement-%s-%s-%s.%s", currency, req.From.Format("20060102"), req.To.Format("20060102"), req.Format)
+	return export, nil
+}
+
+func (srv *statementService) newExport(user *entities.User, wallet *entities.Wallet, format statements.Format, from time.Time, to time.Time) (*StatementExport, error) {
+	opening, err := srv.statementRepo.GetBalanceAt(wallet, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance of wallet %d: %w", wallet.ID, err)
+	}
+
+	return &StatementExport{
+		Format: format,
+		header: &statements.Header{
+			Holder:      user.Username,
+			AccountID:   entities.WalletAccountCode(wallet.ID),
+			Currency:    wallet.Currency,
+			From:        from,
+			To:          to,
+			Opening:     opening,
+			GeneratedAt: time.Now(),
+		},
+		wallet:        wallet,
+		statementRepo: srv.statementRepo,
+	}, nil
+}
+
+// Write renders the statement to w.
+func (e *StatementExport) Write(w io.Writer) error {
+	_, err := e.write(w)
+	return err
+}
+
+// write renders the statement to w and returns its closing balance.
+func (e *StatementExport) write(w io.Writer) (money.Money, error) {
+	writer, err := statements.NewWriter(e.Format, w)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	statement, err := statements.New(writer, e.header)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	var after uint
+	for {
+		lines, err := e.statementRepo.GetLines(e.wallet, e.header.From, e.header.To, after, statementBatchSize)
+		if err != nil {
+			return money.Money{}, fmt.Errorf("failed to get statement lines of wallet %d: %w", e.wallet.ID, err)
+		}
+
+		for i := range lines {
+			if err := statement.Add(newStatementEntry(&lines[i])); err != nil {
+				return money.Money{}, err
+			}
+		}
+
+		if len(lines) < statementBatchSize {
+			break
+		}
+		after = lines[len(lines)-1].PostingID
+	}
+
+	return statement.Balance(), statement.Close()
+}
+
+// IssueMonthly issues the statement of the last closed calendar month (UTC)
+// to every wallet that was open in it and has none yet. A wallet that fails
+// is retried on the next run without holding up the others.
+func (srv *statementService) IssueMonthly() error {
+	periodEnd := startOfMonth(time.Now().UTC())
+	periodStart := periodEnd.AddDate(0, -1, 0)
+
+	var after uint
+	var failed int
+	for {
+		wallets, err := srv.statementRepo.GetWalletsWithoutStatement(periodStart, periodEnd, after, issueBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to get wallets without a %s statement: %w", periodStart.Format(monthFormat), err)
+		}
+
+		for i := range wallets {
+			if err := srv.issue(&wallets[i], periodStart, periodEnd); err != nil {
+				failed++
+				log.Errorf("Failed to issue the %s statement of wallet %d: %v", periodStart.Format(monthFormat), wallets[i].ID, err)
+			}
+		}
+
+		if len(wallets) < issueBatchSize {
+			break
+		}
+		after = wallets[len(wallets)-1].ID
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to issue %d %s statements", failed, periodStart.Format(monthFormat))
+	}
+	return nil
+}
+
+// issue renders the statement of the wallet for the period, stores the
+// document and records it with its hash. The document is stored first, so
+// that a recorded statement always has its document.
+func (srv *statementService) issue(wallet *entities.Wallet, periodStart time.Time, periodEnd time.Time) error {
+	user, err := getUser(srv.userRepo, wallet.UserID)
+	if err != nil {
+		return err
+	}
+
+	export, err := srv.newExport(user, wallet, statements.Formats.PDF, periodStart, periodEnd)
+	if err != nil {
+		return err
+	}
+
+	var document bytes.Buffer
+	closing, err := export.write(&document)
+	if err != nil {
+		return fmt.Errorf("failed to render statement: %w", err)
+	}
+
+	hash := sha256.Sum256(document.Bytes())
+	statement := &entities.Statement{
+		UUID:           uuid.New(),
+		UserID:         wallet.UserID,
+		WalletID:       wallet.ID,
+		Currency:       wallet.Currency,
+		PeriodStart:    periodStart,
+		PeriodEnd:      periodEnd,
+		OpeningBalance: export.header.Opening,
+		ClosingBalance: closing,
+		Format:         string(export.Format),
+		ContentHash:    hex.EncodeToString(hash[:]),
+		Size:           int64(document.Len()),
+	}
+	statement.BlobKey = fmt.Sprintf("statements/%d/%s-%s.%s", wallet.ID, periodStart.Format(monthFormat), statement.UUID, statement.Format)
+
+	if err := srv.store.Put(statement.BlobKey, &document); err != nil {
+		return fmt.Errorf("failed to store statement: %w", err)
+	}
+
+	err = srv.statementRepo.Create(statement)
+	if repos.IsDuplicateKey(err) {
+		// Another run issued the statement first; its document is the one
+		// on record
+		return nil
+	}
+	return err
+}
+
+// GetIssued returns the monthly statements issued to the user, the latest
+// first.
+func (srv *statementService) GetIssued(userID uint) ([]models.IssuedStatementResponse, error) {
+	issued, err := srv.statementRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]models.IssuedStatementResponse, len(issued))
+	for i, statement := range issued {
+		res[i] = models.IssuedStatementResponse{
+			ID:             statement.UUID,
+			Currency:       statement.Currency,
+			Month:          statement.PeriodStart.UTC().Format(monthFormat),
+			OpeningBalance: statement.OpeningBalance,
+			ClosingBalance: statement.ClosingBalance,
+			ContentHash:    statement.ContentHash,
+			IssuedAt:       statement.CreatedAt,
+		}
+	}
+	return res, nil
+}
+
+// Download returns the document of an issued statement of the user, after
+// checking that it still matches the hash it was issued with.
+func (srv *statementService) Download(userID uint, statementID string) (*StatementDocument, error) {
+	id, err := uuid.Parse(statementID)
+	if err != nil {
+		return nil, apperrors.Validation("invalid_statement_id", "invalid statement ID '%s'", statementID)
+	}
+
+	statement, err := srv.statementRepo.GetByUUID(id)
+	if repos.IsNotFound(err) {
+		return nil, apperrors.NotFound("statement_not_found", "statement not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get statement %s: %w", id, err)
+	}
+
+	if statement.UserID != userID {
+		return nil, apperrors.Forbidden("statement_forbidden", "cannot download another user's statement")
+	}
+
+	blob, err := srv.store.Get(statement.BlobKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read statement %s: %w", id, err)
+	}
+	defer blob.Close()
+
+	data, err := io.ReadAll(blob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read statement %s: %w", id, err)
+	}
+
+	hash := sha256.Sum256(data)
+	if hex.EncodeToString(hash[:]) != statement.ContentHash {
+		return nil, fmt.Errorf("statement %s does not match its content hash", id)
+	}
+
+	format := statements.Format(statement.Format)
+	return &StatementDocument{
+		Filename:    fmt.Sprintf("statement-%s-%s.%s", statement.Currency, statement.PeriodStart.UTC().Format(monthFormat), format),
+		ContentType: format.ContentType(),
+		ContentHash: statement.ContentHash,
+		Data:        data,
+	}, nil
+}
+
+// newStatementEntry shows ledger entries without a transaction, such as
+// opening balances, as adjustments.
+func newStatementEntry(line *entities.StatementLine) statements.Entry {
+	entryType := string(line.Type)
+	if entryType == "" {
+		entryType = "ADJUSTMENT"
+	}
+
+	return statements.Entry{
+		ID:            line.JournalEntryID,
+		Date:          line.CreatedAt,
+		TransactionID: line.TransactionID,
+		Type:          entryType,
+		Description:   line.Description,
+		Amount:        line.Amount,
+	}
+}
+
+func startOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
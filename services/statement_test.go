@@ -0,0 +1,189 @@
+package services_test
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/services"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	blobMock "banking-system/blobs/mock"
+	repoMock "banking-system/repos/mock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var statementRepoMock *repoMock.MockStatementRepo
+
+func TestStatement_ReadsLinesInBatches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	statementRepoMock = repoMock.NewMockStatementRepo(ctrl)
+
+	givenUserHasBalance(1, "0")
+	statementRepoMock.EXPECT().GetBalanceAt(gomock.Any(), time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)).Return(twd("100.00"), nil)
+
+	firstBatch := givenStatementLines(500, 1)
+	gomock.InOrder(
+		statementRepoMock.EXPECT().
+			GetLines(gomock.Any(), gomock.Any(), time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), uint(0), 500).
+			Return(firstBatch, nil),
+		statementRepoMock.EXPECT().
+			GetLines(gomock.Any(), gomock.Any(), gomock.Any(), uint(500), 500).
+			Return(givenStatementLines(2, 501), nil),
+	)
+
+	sut := services.NewStatementService(userRepoMock, statementRepoMock, nil)
+	export, err := sut.Open(&models.StatementRequest{
+		UserID: 1,
+		From:   time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC),
+		Format: "csv",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "statement-TWD-20260901-20260930.csv", export.Filename)
+
+	var out bytes.Buffer
+	assert.Nil(t, export.Write(&out))
+
+	r := csv.NewReader(strings.NewReader(out.String()))
+	r.FieldsPerRecord = -1
+	rows, _ := r.ReadAll()
+	assert.Contains(t, rows, []string{"Closing balance", "602.00"})
+	assert.Contains(t, rows, []string{"DEPOSIT", "502", "502.00"})
+}
+
+func TestStatement_InvalidRequest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	sut := services.NewStatementService(userRepoMock, repoMock.NewMockStatementRepo(ctrl), nil)
+
+	_, err := sut.Open(&models.StatementRequest{
+		UserID: 1,
+		From:   time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		Format: "pdf",
+	})
+	assert.Equal(t, "invalid_date_range", apperrors.CodeOf(err))
+
+	givenUserHasBalance(1, "0")
+	_, err = sut.Open(&models.StatementRequest{
+		UserID:   1,
+		From:     time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC),
+		Format:   "pdf",
+		Currency: "USD",
+	})
+	assert.Equal(t, "wallet_not_found", apperrors.CodeOf(err))
+}
+
+func TestIssueMonthly_StoresDocumentWithItsHash(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userRepoMock = repoMock.NewMockUserRepo(ctrl)
+	statementRepoMock = repoMock.NewMockStatementRepo(ctrl)
+	store := blobMock.NewMockStore(ctrl)
+
+	now := time.Now().UTC()
+	periodEnd := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	periodStart := periodEnd.AddDate(0, -1, 0)
+	wallet := entities.Wallet{Model: gorm.Model{ID: 7}, UserID: 1, Currency: "TWD"}
+
+	givenUserHasBalance(1, "0")
+	statementRepoMock.EXPECT().
+		GetWalletsWithoutStatement(periodStart, periodEnd, uint(0), gomock.Any()).
+		Return([]entities.Wallet{wallet}, nil)
+	statementRepoMock.EXPECT().GetBalanceAt(&wallet, periodStart).Return(twd("100.00"), nil)
+	statementRepoMock.EXPECT().
+		GetLines(&wallet, periodStart, periodEnd, uint(0), gomock.Any()).
+		Return(givenStatementLines(3, 1), nil)
+
+	var stored []byte
+	store.EXPECT().
+		Put(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(key string, data io.Reader) error {
+			assert.True(t, strings.HasPrefix(key, "statements/7/"+periodStart.Format("2006-01")+"-"))
+			stored, _ = io.ReadAll(data)
+			return nil
+		})
+
+	var issued *entities.Statement
+	statementRepoMock.EXPECT().
+		Create(gomock.Any()).
+		DoAndReturn(func(statement *entities.Statement) error {
+			issued = statement
+			return nil
+		})
+
+	sut := services.NewStatementService(userRepoMock, statementRepoMock, store)
+	assert.Nil(t, sut.IssueMonthly())
+
+	hash := sha256.Sum256(stored)
+	assert.True(t, bytes.HasPrefix(stored, []byte("%PDF-")))
+	assert.Equal(t, hex.EncodeToString(hash[:]), issued.ContentHash)
+	assert.Equal(t, int64(len(stored)), issued.Size)
+	assert.Equal(t, twd("100.00"), issued.OpeningBalance)
+	assert.Equal(t, twd("103.00"), issued.ClosingBalance)
+	assert.Equal(t, periodStart, issued.PeriodStart)
+}
+
+func TestDownload_RejectsTamperedDocument(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	statementRepoMock = repoMock.NewMockStatementRepo(ctrl)
+	store := blobMock.NewMockStore(ctrl)
+
+	original := sha256.Sum256([]byte("issued"))
+	statement := &entities.Statement{
+		UUID:        uuid.New(),
+		UserID:      1,
+		Currency:    "TWD",
+		PeriodStart: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		Format:      "pdf",
+		BlobKey:     "statements/7/2026-09.pdf",
+		ContentHash: hex.EncodeToString(original[:]),
+	}
+	statementRepoMock.EXPECT().GetByUUID(statement.UUID).Return(statement, nil).Times(3)
+	store.EXPECT().Get(statement.BlobKey).Return(io.NopCloser(strings.NewReader("issued")), nil)
+	store.EXPECT().Get(statement.BlobKey).Return(io.NopCloser(strings.NewReader("edited")), nil)
+
+	sut := services.NewStatementService(nil, statementRepoMock, store)
+
+	document, err := sut.Download(1, statement.UUID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, "statement-TWD-2026-09.pdf", document.Filename)
+	assert.Equal(t, "issued", string(document.Data))
+
+	_, err = sut.Download(1, statement.UUID.String())
+	assert.ErrorContains(t, err, "does not match its content hash")
+
+	_, err = sut.Download(2, statement.UUID.String())
+	assert.Equal(t, "statement_forbidden", apperrors.CodeOf(err))
+}
+
+// givenStatementLines returns n deposits of 1.00 with posting IDs from
+// firstID on.
+func givenStatementLines(n int, firstID uint) []entities.StatementLine {
+	lines := make([]entities.StatementLine, n)
+	for i := range lines {
+		txID := uuid.New()
+		lines[i] = entities.StatementLine{
+			PostingID:      firstID + uint(i),
+			CreatedAt:      time.Date(2026, 9, 2, 0, 0, 0, 0, time.UTC),
+			JournalEntryID: uuid.New(),
+			Description:    "Deposit completed",
+			TransactionID:  &txID,
+			Type:           entities.TransactionTypes.Deposit,
+			Amount:         twd("1.00"),
+		}
+	}
+	return lines
+}
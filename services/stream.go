@@ -0,0 +1,93 @@
+package services
+
+import (
+	"banking-system/apperrors"
+	"banking-system/outbox"
+	"banking-system/repos"
+	"banking-system/stream"
+	"bytes"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//go:generate mockgen -source=stream.go -destination=mock/stream.go
+
+// replayLimit is the most events replayed to a reconnecting stream. A stream
+// further behind is told to reload its state instead.
+const replayLimit = 500
+
+// EventStream is a user's open stream: the events missed since the cursor it
+// reconnected with, followed by those published from now on.
+type EventStream struct {
+	*stream.Subscription
+	Cursor stream.Cursor
+	Replay []*outbox.Message
+
+	// Reset is set when the stream missed too many events to replay them
+	Reset bool
+}
+
+type StreamService interface {
+	Open(userID uint, lastEventID string) (*EventStream, error)
+	Receive(msg *outbox.Message)
+}
+
+type streamService struct {
+	outboxRepo repos.OutboxRepo
+	hub        *stream.Hub
+}
+
+func NewStreamService(outboxRepo repos.OutboxRepo, hub *stream.Hub) StreamService {
+	return &streamService{
+		outboxRepo: outboxRepo,
+		hub:        hub,
+	}
+}
+
+// Open subscribes to the events of the user. A stream that reconnects with the
+// ID of the last event it received first gets the events it missed.
+func (srv *streamService) Open(userID uint, lastEventID string) (*EventStream, error) {
+	cursor, err := stream.ParseCursor(lastEventID)
+	if err != nil {
+		return nil, apperrors.Validation("invalid_last_event_id", "invalid Last-Event-ID '%s'", lastEventID)
+	}
+
+	// Subscribed before reading the missed events, so that none fall in
+	// between; those seen twice are dropped by the cursor
+	s := &EventStream{Subscription: srv.hub.Subscribe(userID), Cursor: cursor}
+	if lastEventID == "" {
+		return s, nil
+	}
+
+	events, err := srv.outboxRepo.GetByUserAfter(userID, cursor, replayLimit+1)
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to get events to replay: %w", err)
+	}
+
+	if len(events) > replayLimit {
+		s.Reset = true
+		return s, nil
+	}
+
+	for i := range events {
+		s.Replay = append(s.Replay, outbox.NewMessage(&events[i]))
+	}
+	return s, nil
+}
+
+// Receive hands a message sent by the relay to the streams of its user. The
+// data of a message too large for a notification is read from the outbox.
+func (srv *streamService) Receive(msg *outbox.Message) {
+	if len(msg.Data) == 0 || bytes.Equal(msg.Data, []byte("null")) {
+		event, err := srv.outboxRepo.GetByID(msg.Sequence)
+		if err != nil {
+			log.Warnf("Failed to read outbox event %d for event streams: %v", msg.Sequence, err)
+			return
+		}
+		msg = outbox.NewMessage(event)
+	}
+
+	srv.hub.Publish(msg)
+}
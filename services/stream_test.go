@@ -0,0 +1,87 @@
+package services_test
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/outbox"
+	"banking-system/services"
+	"banking-system/stream"
+	"encoding/json"
+	"testing"
+
+	repoMock "banking-system/repos/mock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream_OpenWithoutLastEventIDReplaysNothing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	outboxRepoMock := repoMock.NewMockOutboxRepo(ctrl)
+
+	sut := services.NewStreamService(outboxRepoMock, stream.NewHub())
+	s, err := sut.Open(1, "")
+
+	assert.Nil(t, err)
+	assert.Empty(t, s.Replay)
+	s.Close()
+}
+
+func TestStream_OpenReplaysEventsAfterCursor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	outboxRepoMock := repoMock.NewMockOutboxRepo(ctrl)
+
+	outboxRepoMock.EXPECT().GetByUserAfter(uint(1), map[uint]uint{7: 4}, gomock.Any()).
+		Return([]entities.OutboxEvent{givenOutboxEvent(5, 7), givenOutboxEvent(6, 7)}, nil)
+
+	sut := services.NewStreamService(outboxRepoMock, stream.NewHub())
+	s, err := sut.Open(1, "7:4")
+
+	assert.Nil(t, err)
+	assert.Len(t, s.Replay, 2)
+	assert.Equal(t, uint(5), s.Replay[0].Sequence)
+	assert.False(t, s.Reset)
+	s.Close()
+}
+
+func TestStream_OpenTooFarBehindResets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	outboxRepoMock := repoMock.NewMockOutboxRepo(ctrl)
+
+	outboxRepoMock.EXPECT().GetByUserAfter(uint(1), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(userID uint, after map[uint]uint, limit int) ([]entities.OutboxEvent, error) {
+			return make([]entities.OutboxEvent, limit), nil
+		})
+
+	sut := services.NewStreamService(outboxRepoMock, stream.NewHub())
+	s, err := sut.Open(1, "7:4")
+
+	assert.Nil(t, err)
+	assert.True(t, s.Reset)
+	assert.Empty(t, s.Replay)
+	s.Close()
+}
+
+func TestStream_OpenRejectsInvalidLastEventID(t *testing.T) {
+	sut := services.NewStreamService(nil, stream.NewHub())
+	_, err := sut.Open(1, "not-a-cursor")
+
+	assert.Equal(t, "invalid_last_event_id", apperrors.CodeOf(err))
+}
+
+func TestStream_ReceiveLoadsDataDroppedFromNotification(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	outboxRepoMock := repoMock.NewMockOutboxRepo(ctrl)
+	hub := stream.NewHub()
+
+	event := givenOutboxEvent(5, 7)
+	outboxRepoMock.EXPECT().GetByID(uint(5)).Return(&event, nil)
+
+	sut := services.NewStreamService(outboxRepoMock, hub)
+	sub := hub.Subscribe(42)
+	defer sub.Close()
+	sut.Receive(&outbox.Message{ID: event.UUID, Sequence: 5, UserID: 42, WalletID: 7, Data: json.RawMessage("null")})
+
+	received := <-sub.C
+	assert.JSONEq(t, event.Payload, string(received.Data))
+}
@@ -1,15 +1,29 @@
 package services
 
 import (
+	"banking-system/apperrors"
+	"banking-system/entities"
 	"banking-system/models"
+	"banking-system/money"
 	"banking-system/repos"
+	"encoding/base64"
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 //go:generate mockgen -source=transaction.go -destination=mock/transaction.go
 
+const (
+	defaultHistoryPageSize = 20
+	maxHistoryPageSize     = 100
+)
+
 type TransactionService interface {
-	GetByUserID(userID uint, cutoffDate time.Time) ([]models.TransactionResponse, error)
+	GetHistory(query *models.TransactionQuery) (*models.TransactionPageResponse, error)
+	GetSince(userID uint, since time.Time) ([]models.TransactionResponse, error)
 }
 
 type transactionService struct {
@@ -22,23 +36,162 @@ func NewTransactionService(transactionRepo repos.TransactionRepo) TransactionSer
 	}
 }
 
-func (srv *transactionService) GetByUserID(userID uint, cutoffDate time.Time) ([]models.TransactionResponse, error) {
-	transactions, err := srv.transactionRepo.GetByUserID(userID, cutoffDate)
+// GetHistory returns one page of the user's transactions, newest first
+// unless the query asks for ascending order. Page sizes above the cap are
+// reduced to it.
+func (srv *transactionService) GetHistory(query *models.TransactionQuery) (*models.TransactionPageResponse, error) {
+	filter, err := newTransactionFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// One extra row tells whether there is a next page
+	pageSize := filter.Limit
+	filter.Limit++
+
+	transactions, err := srv.transactionRepo.GetHistory(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.TransactionPageResponse{
+		Transactions: make([]models.TransactionResponse, 0, len(transactions)),
+	}
+
+	if len(transactions) > pageSize {
+		transactions = transactions[:pageSize]
+		last := transactions[pageSize-1]
+		page.NextCursor = encodeCursor(&repos.TransactionCursor{CreatedAt: last.CreatedAt, UUID: last.UUID})
+	}
+
+	for i := range transactions {
+		page.Transactions = append(page.Transactions, *newTransactionResponse(&transactions[i]))
+	}
+
+	return page, nil
+}
+
+// GetSince returns all of the user's transactions created since since, newest
+// first, in one unpaged list.
+func (srv *transactionService) GetSince(userID uint, since time.Time) ([]models.TransactionResponse, error) {
+	transactions, err := srv.transactionRepo.GetHistory(&repos.TransactionFilter{
+		UserID: userID,
+		From:   since,
+		Limit:  -1, // no limit
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.TransactionResponse, 0, len(transactions))
+	for i := range transactions {
+		responses = append(responses, *newTransactionResponse(&transactions[i]))
+	}
+
+	return responses, nil
+}
+
+func newTransactionFilter(query *models.TransactionQuery) (*repos.TransactionFilter, error) {
+	filter := &repos.TransactionFilter{
+		UserID:         query.UserID,
+		From:           query.From,
+		To:             query.To,
+		Types:          query.Types,
+		Statuses:       query.Statuses,
+		PaymentMethods: query.PaymentMethods,
+		Currency:       query.Currency,
+		Ascending:      query.Order == "asc",
+		Limit:          query.Limit,
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, apperrors.Validation("invalid_date_range", "from must be before to")
+	}
+
+	var err error
+	if filter.MinAmount, err = parseAmountFilter(query.MinAmount, query.Currency); err != nil {
+		return nil, err
+	}
+	if filter.MaxAmount, err = parseAmountFilter(query.MaxAmount, query.Currency); err != nil {
+		return nil, err
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && filter.MinAmount.GreaterThan(*filter.MaxAmount) {
+		return nil, apperrors.Validation("invalid_amount_range", "min_amount must not exceed max_amount")
+	}
+
+	if query.Cursor != "" {
+		if filter.After, err = decodeCursor(query.Cursor); err != nil {
+			return nil, apperrors.Validation("invalid_cursor", "cursor is malformed")
+		}
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = defaultHistoryPageSize
+	}
+	if filter.Limit > maxHistoryPageSize {
+		filter.Limit = maxHistoryPageSize
+	}
+
+	return filter, nil
+}
+
+func parseAmountFilter(s string, currency string) (*money.Money, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	amount, err := money.Parse(s, currency)
+	if err != nil || amount.IsNegative() {
+		return nil, apperrors.Validation("invalid_amount", "amount filter '%s' is not a valid amount", s)
+	}
+	return &amount, nil
+}
+
+// encodeCursor encodes the position of a transaction as an opaque string
+// clients hand back to get the next page.
+func encodeCursor(cursor *repos.TransactionCursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.UUID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (*repos.TransactionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
 	if err != nil {
 		return nil, err
 	}
 
-	var transactionResponses []models.TransactionResponse
-	for _, tx := range transactions {
-		transactionResponses = append(transactionResponses, models.TransactionResponse{
-			UUID:          tx.UUID,
-			Type:          tx.Type,
-			Status:        tx.Status,
-			Amount:        tx.Amount,
-			PaymentMethod: string(tx.PaymentMethod),
-			CreatedAt:     tx.CreatedAt,
-		})
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, fmt.Errorf("cursor '%s' has no UUID", raw)
+	}
+
+	cursor := &repos.TransactionCursor{}
+	if cursor.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return nil, err
+	}
+	if cursor.UUID, err = uuid.Parse(id); err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
+
+func newTransactionResponse(tx *entities.Transaction) *models.TransactionResponse {
+	res := &models.TransactionResponse{
+		UUID:                 tx.UUID,
+		Type:                 tx.Type,
+		Status:               tx.Status,
+		Amount:               tx.Amount,
+		Currency:             tx.Currency,
+		PaymentMethod:        string(tx.PaymentMethod),
+		PayOutStatus:         tx.PayOutStatus,
+		CreatedAt:            tx.CreatedAt,
+		RelatedTransactionID: tx.RelatedTransactionID,
+	}
+
+	if tx.HasFee() {
+		fee := tx.FeeAmount
+		res.Fee = &fee
 	}
 
-	return transactionResponses, nil
+	return res
 }
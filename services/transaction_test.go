@@ -0,0 +1,133 @@
+package services_test
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/repos"
+	"banking-system/services"
+	"testing"
+	"time"
+
+	repoMock "banking-system/repos/mock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory_NextCursorPointsPastLastTransaction(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+
+	page := givenTransactions(3)
+	var filters []*repos.TransactionFilter
+	transactionRepoMock.EXPECT().
+		GetHistory(gomock.Any()).
+		DoAndReturn(func(f *repos.TransactionFilter) ([]entities.Transaction, error) {
+			filters = append(filters, f)
+			if f.After == nil {
+				return page, nil
+			}
+			return nil, nil
+		}).
+		Times(2)
+
+	sut := services.NewTransactionService(transactionRepoMock)
+	res, err := sut.GetHistory(&models.TransactionQuery{UserID: 1, Limit: 2})
+
+	assert.Nil(t, err)
+	assert.Len(t, res.Transactions, 2)
+	assert.Equal(t, 3, filters[0].Limit, "one extra transaction tells whether there is a next page")
+	assert.NotEmpty(t, res.NextCursor)
+
+	res, err = sut.GetHistory(&models.TransactionQuery{UserID: 1, Limit: 2, Cursor: res.NextCursor})
+
+	assert.Nil(t, err)
+	assert.Empty(t, res.Transactions)
+	assert.Empty(t, res.NextCursor)
+	assert.Equal(t, page[1].UUID, filters[1].After.UUID)
+	assert.True(t, page[1].CreatedAt.Equal(filters[1].After.CreatedAt))
+}
+
+func TestHistory_PageSizeIsCapped(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+	transactionRepoMock.EXPECT().
+		GetHistory(matcher{
+			description: "filter limited to 101 transactions",
+			matches: func(x any) bool {
+				return x.(*repos.TransactionFilter).Limit == 101
+			},
+		}).
+		Return(nil, nil)
+
+	sut := services.NewTransactionService(transactionRepoMock)
+	res, err := sut.GetHistory(&models.TransactionQuery{UserID: 1, Limit: 5000})
+
+	assert.Nil(t, err)
+	assert.NotNil(t, res.Transactions)
+}
+
+func TestHistory_InvalidQuery(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		query models.TransactionQuery
+		code  string
+	}{
+		{"date range", models.TransactionQuery{From: now, To: now.Add(-time.Hour)}, "invalid_date_range"},
+		{"amount", models.TransactionQuery{MinAmount: "ten"}, "invalid_amount"},
+		{"amount range", models.TransactionQuery{MinAmount: "20", MaxAmount: "10"}, "invalid_amount_range"},
+		{"cursor", models.TransactionQuery{Cursor: "not-a-cursor"}, "invalid_cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := services.NewTransactionService(repoMock.NewMockTransactionRepo(gomock.NewController(t)))
+			_, err := sut.GetHistory(&tt.query)
+
+			assert.Equal(t, apperrors.Kinds.Validation, apperrors.KindOf(err))
+			assert.Equal(t, tt.code, apperrors.CodeOf(err))
+		})
+	}
+}
+
+// givenTransactions returns n completed deposits, newest first.
+func givenTransactions(n int) []entities.Transaction {
+	now := time.Now()
+	transactions := make([]entities.Transaction, n)
+	for i := range transactions {
+		transactions[i] = entities.Transaction{
+			UUID:      uuid.New(),
+			Type:      entities.TransactionTypes.Deposit,
+			Status:    entities.TransactionStatuses.Completed,
+			Amount:    twd("10.00"),
+			Currency:  "TWD",
+			CreatedAt: now.Add(-time.Duration(i) * time.Minute),
+		}
+	}
+	return transactions
+}
+
+func TestHistory_GetSinceListsEveryTransactionSinceCutoff(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	transactionRepoMock = repoMock.NewMockTransactionRepo(ctrl)
+
+	since := time.Now().AddDate(0, -6, 0)
+	transactionRepoMock.EXPECT().
+		GetHistory(gomock.Any()).
+		DoAndReturn(func(f *repos.TransactionFilter) ([]entities.Transaction, error) {
+			assert.Equal(t, uint(1), f.UserID)
+			assert.True(t, since.Equal(f.From))
+			assert.Negative(t, f.Limit, "the list is not paged")
+			return givenTransactions(3), nil
+		})
+
+	sut := services.NewTransactionService(transactionRepoMock)
+	res, err := sut.GetSince(1, since)
+
+	assert.Nil(t, err)
+	assert.Len(t, res, 3)
+}
@@ -1,13 +1,13 @@
 package services
 
 import (
+	"banking-system/apperrors"
 	"banking-system/entities"
 	"banking-system/models"
+	"banking-system/money"
 	"banking-system/repos"
 	"fmt"
-	"strings"
 
-	log "github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -17,6 +17,7 @@ type UserService interface {
 	Register(req *models.RegisterRequest) error
 	GetByUsername(username string) (*entities.User, error)
 	GetByID(id uint) (*models.UserInfoResponse, error)
+	OpenWallet(req *models.OpenWalletRequest) (*models.WalletResponse, error)
 }
 
 type userService struct {
@@ -30,26 +31,33 @@ func NewUserService(userRepo repos.UserRepo) UserService {
 }
 
 func (srv *userService) Register(req *models.RegisterRequest) error {
+	if req.Username == entities.HouseUsername {
+		return apperrors.Conflict("username_taken", "username '%s' is already taken", req.Username)
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		log.Panicf("Failed to hash password: %v", err)
+		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
 	user := &entities.User{
 		Username:     req.Username,
 		PasswordHash: string(hashedPassword),
 		Name:         req.Name,
-		Wallet: entities.Wallet{
-			Currency: "TWD",
-			Balance:  0,
+		Tier:         entities.UserTiers.Standard,
+		Wallets: []entities.Wallet{
+			{
+				Currency: defaultCurrency,
+				Balance:  money.Zero(defaultCurrency),
+			},
 		},
 	}
 
 	if err := srv.userRepo.Create(user); err != nil {
-		if strings.Contains(err.Error(), "uni_users_username") || strings.Contains(err.Error(), "duplicate key") {
-			return fmt.Errorf("username '%s' is already taken", req.Username)
+		if repos.IsDuplicateKey(err) {
+			return apperrors.Conflict("username_taken", "username '%s' is already taken", req.Username)
 		}
-		log.Panicf("Failed to create user: %v", err)
+		return fmt.Errorf("failed to create user: %w", err)
 	}
 
 	return nil
@@ -57,28 +65,62 @@ func (srv *userService) Register(req *models.RegisterRequest) error {
 
 func (srv *userService) GetByUsername(username string) (*entities.User, error) {
 	userEntity, err := srv.userRepo.GetByUsername(username)
-
+	if repos.IsNotFound(err) {
+		return nil, apperrors.NotFound("user_not_found", "user not found")
+	}
 	if err != nil {
-		if err.Error() == "record not found" {
-			return nil, err
-		} else {
-			log.Panicf("Failed to get user by username: %v", err)
-		}
+		return nil, fmt.Errorf("failed to get user by username: %w", err)
 	}
+
 	return userEntity, nil
 }
 
 func (srv *userService) GetByID(id uint) (*models.UserInfoResponse, error) {
 	user, err := srv.userRepo.Get(id)
+	if repos.IsNotFound(err) {
+		return nil, apperrors.NotFound("user_not_found", "user not found")
+	}
 	if err != nil {
-		if err.Error() == "record not found" {
-			return nil, fmt.Errorf("user not found")
+		return nil, fmt.Errorf("failed to get user by ID: %w", err)
+	}
+
+	wallets := make([]models.WalletResponse, len(user.Wallets))
+	for i, wallet := range user.Wallets {
+		wallets[i] = models.WalletResponse{
+			ID:       wallet.ID,
+			Currency: wallet.Currency,
+			Balance:  wallet.Balance,
 		}
-		log.Panicf("Failed to get user by ID: %v", err)
 	}
 
 	return &models.UserInfoResponse{
 		Username: user.Username,
-		Balance:  user.Wallet.Balance,
+		Tier:     user.Tier,
+		Wallets:  wallets,
+	}, nil
+}
+
+func (srv *userService) OpenWallet(req *models.OpenWalletRequest) (*models.WalletResponse, error) {
+	if !money.IsSupported(req.Currency) {
+		return nil, apperrors.Validation("unsupported_currency", "currency '%s' is not supported", req.Currency)
+	}
+
+	wallet := &entities.Wallet{
+		UserID:   req.UserID,
+		Currency: req.Currency,
+		Balance:  money.Zero(req.Currency),
+	}
+
+	if err := srv.userRepo.CreateWallet(wallet); err != nil {
+		if repos.IsDuplicateKey(err) {
+			return nil, apperrors.Conflict("wallet_exists", "user already has a %s wallet", req.Currency)
+		}
+		return nil, fmt.Errorf("failed to create wallet: %w", err)
+	}
+
+	return &models.WalletResponse{
+		ID:       wallet.ID,
+		Currency: wallet.Currency,
+		Balance:  wallet.Balance,
 	}, nil
 }
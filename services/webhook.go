@@ -0,0 +1,312 @@
+package services
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/outbox"
+	"banking-system/repos"
+	"banking-system/webhooks"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+//go:generate mockgen -source=webhook.go -destination=mock/webhook.go
+
+const (
+	// deliveryBatchSize is the number of due deliveries sent per run, so that
+	// a run ends well within the lease of the delivery job.
+	deliveryBatchSize = 50
+
+	maxWebhookEndpoints = 10
+	deadLetterLimit     = 100
+	maxDeliveryError    = 500
+)
+
+type WebhookService interface {
+	Handle(msg *outbox.Message) error
+	Register(req *models.CreateWebhookEndpointRequest) (*models.WebhookEndpointResponse, error)
+	GetEndpoints(userID uint) ([]models.WebhookEndpointResponse, error)
+	DeleteEndpoint(userID uint, id uint) error
+	GetDeadLetters(userID uint) ([]models.WebhookDeliveryResponse, error)
+	Redeliver(userID uint, deliveryID string) (*models.WebhookDeliveryResponse, error)
+	DeliverDue() error
+}
+
+type webhookService struct {
+	webhookRepo repos.WebhookRepo
+	sender      webhooks.Sender
+}
+
+func NewWebhookService(webhookRepo repos.WebhookRepo, sender webhooks.Sender) WebhookService {
+	return &webhookService{
+		webhookRepo: webhookRepo,
+		sender:      sender,
+	}
+}
+
+func (srv *webhookService) Register(req *models.CreateWebhookEndpointRequest) (*models.WebhookEndpointResponse, error) {
+	if err := webhooks.CheckURL(req.URL); err != nil {
+		return nil, apperrors.Validation("invalid_webhook_url", "%s", err)
+	}
+
+	events := make([]string, 0, len(req.Events))
+	for _, event := range req.Events {
+		if !entities.EventType(event).IsValid() {
+			return nil, apperrors.Validation("invalid_event_type", "unknown event type '%s'", event)
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+
+	existing, err := srv.webhookRepo.GetEndpointsByUserID(req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoints: %w", err)
+	}
+	if len(existing) >= maxWebhookEndpoints {
+		return nil, apperrors.Unprocessable("too_many_webhook_endpoints", "a user can register at most %d webhook endpoints", maxWebhookEndpoints)
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	endpoint := &entities.WebhookEndpoint{
+		UserID: req.UserID,
+		URL:    req.URL,
+		Secret: secret,
+		Events: events,
+	}
+	if err := srv.webhookRepo.CreateEndpoint(endpoint); err != nil {
+		return nil, fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+
+	res := newWebhookEndpointResponse(endpoint)
+	res.Secret = endpoint.Secret
+	return &res, nil
+}
+
+func (srv *webhookService) GetEndpoints(userID uint) ([]models.WebhookEndpointResponse, error) {
+	endpoints, err := srv.webhookRepo.GetEndpointsByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoints: %w", err)
+	}
+
+	res := make([]models.WebhookEndpointResponse, len(endpoints))
+	for i := range endpoints {
+		res[i] = newWebhookEndpointResponse(&endpoints[i])
+	}
+	return res, nil
+}
+
+// DeleteEndpoint removes an endpoint of the user. Deliveries still waiting to
+// be sent to it are dead-lettered.
+func (srv *webhookService) DeleteEndpoint(userID uint, id uint) error {
+	endpoint, err := srv.webhookRepo.GetEndpoint(id)
+	if repos.IsNotFound(err) {
+		return apperrors.NotFound("webhook_endpoint_not_found", "webhook endpoint not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+
+	if endpoint.UserID != userID {
+		return apperrors.Forbidden("webhook_endpoint_forbidden", "webhook endpoint belongs to another user")
+	}
+
+	if err := srv.webhookRepo.DeleteEndpoint(id); err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+// GetDeadLetters returns the latest deliveries to the user's endpoints that
+// failed on every attempt.
+func (srv *webhookService) GetDeadLetters(userID uint) ([]models.WebhookDeliveryResponse, error) {
+	deliveries, err := srv.webhookRepo.GetDeliveriesByUserID(userID, entities.WebhookDeliveryStatuses.Dead, deadLetterLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead-lettered deliveries: %w", err)
+	}
+
+	res := make([]models.WebhookDeliveryResponse, len(deliveries))
+	for i := range deliveries {
+		res[i] = newWebhookDeliveryResponse(&deliveries[i])
+	}
+	return res, nil
+}
+
+// Redeliver takes a dead-lettered delivery back to be sent by the next run of
+// the delivery job, with all its attempts available again.
+func (srv *webhookService) Redeliver(userID uint, deliveryID string) (*models.WebhookDeliveryResponse, error) {
+	id, err := uuid.Parse(deliveryID)
+	if err != nil {
+		return nil, apperrors.Validation("invalid_delivery_id", "invalid delivery ID '%s'", deliveryID)
+	}
+
+	delivery, err := srv.webhookRepo.GetDelivery(id)
+	if repos.IsNotFound(err) || (err == nil && delivery.Endpoint == nil) {
+		return nil, apperrors.NotFound("webhook_delivery_not_found", "webhook delivery '%s' not found", deliveryID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	if delivery.Endpoint.UserID != userID {
+		return nil, apperrors.Forbidden("webhook_delivery_forbidden", "webhook delivery '%s' belongs to another user", deliveryID)
+	}
+
+	if delivery.Status != entities.WebhookDeliveryStatuses.Dead {
+		return nil, apperrors.Conflict("delivery_not_dead_lettered", "only dead-lettered deliveries can be redelivered; delivery '%s' is %s", deliveryID, delivery.Status)
+	}
+
+	delivery.Redeliver(time.Now())
+	updated, err := srv.webhookRepo.UpdateDelivery(delivery, entities.WebhookDeliveryStatuses.Dead)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	if !updated {
+		return nil, apperrors.Conflict("delivery_not_dead_lettered", "delivery '%s' is already being redelivered", deliveryID)
+	}
+
+	res := newWebhookDeliveryResponse(delivery)
+	return &res, nil
+}
+
+// Handle creates a delivery of an outbox event to each endpoint of its user
+// that subscribes to it. The deliveries are sent by DeliverDue. An event that
+// is handed over again creates no further deliveries.
+func (srv *webhookService) Handle(msg *outbox.Message) error {
+	endpoints, err := srv.webhookRepo.GetEndpointsByUserID(msg.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get webhook endpoints of user %d: %w", msg.UserID, err)
+	}
+
+	event := webhooks.Event{
+		ID:        msg.ID,
+		Type:      msg.Type,
+		CreatedAt: msg.CreatedAt.UTC(),
+		Data:      msg.Data,
+		UserID:    msg.UserID,
+	}
+
+	var deliveries []entities.WebhookDelivery
+	var body []byte
+	now := time.Now()
+
+	for i := range endpoints {
+		if !endpoints[i].Subscribes(string(event.Type)) {
+			continue
+		}
+
+		if body == nil {
+			if body, err = event.Body(); err != nil {
+				return fmt.Errorf("failed to encode event '%s': %w", event.ID, err)
+			}
+		}
+
+		deliveries = append(deliveries, entities.WebhookDelivery{
+			UUID:          uuid.New(),
+			EndpointID:    endpoints[i].ID,
+			EventID:       event.ID,
+			EventType:     string(event.Type),
+			Payload:       string(body),
+			Status:        entities.WebhookDeliveryStatuses.Pending,
+			NextAttemptAt: now,
+		})
+	}
+
+	if err := srv.webhookRepo.CreateDeliveries(deliveries); err != nil {
+		return fmt.Errorf("failed to create webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// DeliverDue sends the deliveries whose next attempt is due. A failed attempt
+// is retried with exponential backoff until webhooks.MAX_ATTEMPTS, after
+// which the delivery is dead-lettered. Deliveries are sent at least once, so
+// receivers should drop events whose ID they have already seen.
+func (srv *webhookService) DeliverDue() error {
+	deliveries, err := srv.webhookRepo.GetDueDeliveries(time.Now(), deliveryBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to get due webhook deliveries: %w", err)
+	}
+
+	for i := range deliveries {
+		if err := srv.deliver(&deliveries[i]); err != nil {
+			log.Warnf("Failed to record webhook delivery '%s': %v", deliveries[i].UUID, err)
+		}
+	}
+
+	return nil
+}
+
+func (srv *webhookService) deliver(delivery *entities.WebhookDelivery) error {
+	if delivery.Endpoint == nil {
+		// The endpoint was deleted after the delivery was read
+		delivery.Failed(time.Now(), 0, "endpoint deleted", 0, webhooks.RetryDelay)
+		_, err := srv.webhookRepo.UpdateDelivery(delivery, entities.WebhookDeliveryStatuses.Pending)
+		return err
+	}
+
+	status, err := srv.sender.Send(&webhooks.Message{
+		URL:       delivery.Endpoint.URL,
+		Secret:    delivery.Endpoint.Secret,
+		EventID:   delivery.EventID.String(),
+		EventType: entities.EventType(delivery.EventType),
+		Body:      []byte(delivery.Payload),
+	})
+
+	if err != nil {
+		delivery.Failed(time.Now(), status, truncate(err.Error(), maxDeliveryError), webhooks.MAX_ATTEMPTS, webhooks.RetryDelay)
+		if delivery.Status == entities.WebhookDeliveryStatuses.Dead {
+			log.Warnf("Dead-lettered webhook delivery '%s' to endpoint %d after %d attempts: %v", delivery.UUID, delivery.EndpointID, delivery.Attempts, err)
+		}
+	} else {
+		delivery.Delivered(time.Now(), status)
+	}
+
+	updated, err := srv.webhookRepo.UpdateDelivery(delivery, entities.WebhookDeliveryStatuses.Pending)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		log.Infof("Webhook delivery '%s' changed while it was sent", delivery.UUID)
+	}
+	return nil
+}
+
+func newWebhookEndpointResponse(endpoint *entities.WebhookEndpoint) models.WebhookEndpointResponse {
+	return models.WebhookEndpointResponse{
+		ID:        endpoint.ID,
+		URL:       endpoint.URL,
+		Events:    endpoint.Events,
+		CreatedAt: endpoint.CreatedAt,
+	}
+}
+
+func newWebhookDeliveryResponse(delivery *entities.WebhookDelivery) models.WebhookDeliveryResponse {
+	res := models.WebhookDeliveryResponse{
+		ID:             delivery.UUID,
+		EndpointID:     delivery.EndpointID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		LastAttemptAt:  delivery.LastAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+	}
+
+	if delivery.Status == entities.WebhookDeliveryStatuses.Pending {
+		next := delivery.NextAttemptAt
+		res.NextAttemptAt = &next
+	}
+	return res
+}
@@ -0,0 +1,294 @@
+package services_test
+
+import (
+	"banking-system/apperrors"
+	"banking-system/entities"
+	"banking-system/models"
+	"banking-system/outbox"
+	"banking-system/services"
+	"banking-system/webhooks"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	repoMock "banking-system/repos/mock"
+	webhookMock "banking-system/webhooks/mock"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var (
+	webhookRepoMock *repoMock.MockWebhookRepo
+	senderMock      *webhookMock.MockSender
+)
+
+func TestWebhook_RegisterRejectsUnknownEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepoMock = repoMock.NewMockWebhookRepo(ctrl)
+
+	sut := services.NewWebhookService(webhookRepoMock, nil)
+	_, err := sut.Register(&models.CreateWebhookEndpointRequest{
+		UserID: 1,
+		URL:    "https://merchant.example.com/hooks",
+		Events: []string{"transaction.completed", "transaction.exploded"},
+	})
+
+	assert.Equal(t, "invalid_event_type", apperrors.CodeOf(err))
+}
+
+func TestWebhook_RegisterRejectsOtherSchemes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepoMock = repoMock.NewMockWebhookRepo(ctrl)
+
+	sut := services.NewWebhookService(webhookRepoMock, nil)
+	_, err := sut.Register(&models.CreateWebhookEndpointRequest{
+		UserID: 1,
+		URL:    "ftp://merchant.example.com/hooks",
+		Events: []string{"transaction.completed"},
+	})
+
+	assert.Equal(t, "invalid_webhook_url", apperrors.CodeOf(err))
+}
+
+func TestWebhook_RegisterRejectsPlainHTTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepoMock = repoMock.NewMockWebhookRepo(ctrl)
+
+	sut := services.NewWebhookService(webhookRepoMock, nil)
+	_, err := sut.Register(&models.CreateWebhookEndpointRequest{
+		UserID: 1,
+		URL:    "http://merchant.example.com/hooks",
+		Events: []string{"transaction.completed"},
+	})
+
+	assert.Equal(t, "invalid_webhook_url", apperrors.CodeOf(err))
+}
+
+func TestWebhook_RegisterRejectsInternalAddresses(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepoMock = repoMock.NewMockWebhookRepo(ctrl)
+	sut := services.NewWebhookService(webhookRepoMock, nil)
+
+	for _, url := range []string{
+		"https://127.0.0.1/hooks",
+		"https://localhost:8443/hooks",
+		"https://10.0.0.5/hooks",
+		"https://192.168.1.10/hooks",
+		"https://169.254.169.254/latest/meta-data",
+		"https://[::1]/hooks",
+		"https://[fe80::1]/hooks",
+	} {
+		_, err := sut.Register(&models.CreateWebhookEndpointRequest{
+			UserID: 1,
+			URL:    url,
+			Events: []string{"transaction.completed"},
+		})
+
+		assert.Equal(t, "invalid_webhook_url", apperrors.CodeOf(err), url)
+	}
+}
+
+func TestWebhook_RegisterReturnsSecret(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepoMock = repoMock.NewMockWebhookRepo(ctrl)
+
+	webhookRepoMock.EXPECT().GetEndpointsByUserID(uint(1)).Return(nil, nil)
+	webhookRepoMock.EXPECT().CreateEndpoint(gomock.Any()).Return(nil)
+
+	sut := services.NewWebhookService(webhookRepoMock, nil)
+	res, err := sut.Register(&models.CreateWebhookEndpointRequest{
+		UserID: 1,
+		URL:    "https://merchant.example.com/hooks",
+		Events: []string{"transfer.received", "transfer.received"},
+	})
+
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(res.Secret, "whsec_"))
+	assert.Equal(t, []string{"transfer.received"}, res.Events)
+}
+
+func TestWebhook_HandleCreatesDeliveriesForSubscribedEndpoints(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepoMock = repoMock.NewMockWebhookRepo(ctrl)
+
+	webhookRepoMock.EXPECT().GetEndpointsByUserID(uint(1)).Return([]entities.WebhookEndpoint{
+		{Model: gorm.Model{ID: 10}, UserID: 1, Events: []string{"transaction.completed"}},
+		{Model: gorm.Model{ID: 11}, UserID: 1, Events: []string{"wallet.balance_changed"}},
+	}, nil)
+
+	var created []entities.WebhookDelivery
+	webhookRepoMock.EXPECT().CreateDeliveries(gomock.Any()).DoAndReturn(func(deliveries []entities.WebhookDelivery) error {
+		created = deliveries
+		return nil
+	})
+
+	msg := &outbox.Message{
+		ID:        uuid.New(),
+		Sequence:  7,
+		Type:      entities.EventTypes.TransactionCompleted,
+		UserID:    1,
+		WalletID:  3,
+		CreatedAt: time.Now(),
+		Data:      json.RawMessage(`{"uuid":"1"}`),
+	}
+
+	sut := services.NewWebhookService(webhookRepoMock, nil)
+	err := sut.Handle(msg)
+
+	assert.Nil(t, err)
+	assert.Len(t, created, 1)
+	assert.Equal(t, uint(10), created[0].EndpointID)
+	assert.Equal(t, msg.ID, created[0].EventID)
+	assert.Equal(t, entities.WebhookDeliveryStatuses.Pending, created[0].Status)
+	assert.Contains(t, created[0].Payload, `"type":"transaction.completed"`)
+	assert.Contains(t, created[0].Payload, `"data":{"uuid":"1"}`)
+}
+
+func TestWebhook_HandleWithoutSubscribersCreatesNothing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepoMock = repoMock.NewMockWebhookRepo(ctrl)
+
+	webhookRepoMock.EXPECT().GetEndpointsByUserID(uint(1)).Return([]entities.WebhookEndpoint{
+		{Model: gorm.Model{ID: 10}, UserID: 1, Events: []string{"transfer.received"}},
+	}, nil)
+	webhookRepoMock.EXPECT().CreateDeliveries(gomock.Len(0)).Return(nil)
+
+	sut := services.NewWebhookService(webhookRepoMock, nil)
+	err := sut.Handle(&outbox.Message{ID: uuid.New(), Type: entities.EventTypes.WalletBalanceChanged, UserID: 1})
+
+	assert.Nil(t, err)
+}
+
+func TestWebhook_FailedDeliveryIsRetriedWithBackoff(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepoMock = repoMock.NewMockWebhookRepo(ctrl)
+	senderMock = webhookMock.NewMockSender(ctrl)
+
+	delivery := givenDueDelivery(2)
+	senderMock.EXPECT().Send(gomock.Any()).Return(503, webhooks.ErrRejected)
+	webhookRepoMock.EXPECT().UpdateDelivery(delivery, entities.WebhookDeliveryStatuses.Pending).Return(true, nil)
+
+	sut := services.NewWebhookService(webhookRepoMock, senderMock)
+	before := time.Now()
+	err := sut.DeliverDue()
+
+	assert.Nil(t, err)
+	assert.Equal(t, entities.WebhookDeliveryStatuses.Pending, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Equal(t, 503, delivery.LastStatusCode)
+	assert.WithinDuration(t, before.Add(webhooks.RetryDelay(3)), delivery.NextAttemptAt, time.Second)
+}
+
+func TestWebhook_LastFailedAttemptIsDeadLettered(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepoMock = repoMock.NewMockWebhookRepo(ctrl)
+	senderMock = webhookMock.NewMockSender(ctrl)
+
+	delivery := givenDueDelivery(webhooks.MAX_ATTEMPTS - 1)
+	senderMock.EXPECT().Send(gomock.Any()).Return(0, errors.New("connection refused"))
+	webhookRepoMock.EXPECT().UpdateDelivery(delivery, entities.WebhookDeliveryStatuses.Pending).Return(true, nil)
+
+	sut := services.NewWebhookService(webhookRepoMock, senderMock)
+	err := sut.DeliverDue()
+
+	assert.Nil(t, err)
+	assert.Equal(t, entities.WebhookDeliveryStatuses.Dead, delivery.Status)
+	assert.Equal(t, "connection refused", delivery.LastError)
+}
+
+func TestWebhook_SuccessfulDelivery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepoMock = repoMock.NewMockWebhookRepo(ctrl)
+	senderMock = webhookMock.NewMockSender(ctrl)
+
+	delivery := givenDueDelivery(0)
+	senderMock.EXPECT().Send(gomock.Any()).DoAndReturn(func(msg *webhooks.Message) (int, error) {
+		assert.Equal(t, delivery.Endpoint.URL, msg.URL)
+		assert.Equal(t, delivery.EventID.String(), msg.EventID)
+		assert.Equal(t, []byte(delivery.Payload), msg.Body)
+		return 200, nil
+	})
+	webhookRepoMock.EXPECT().UpdateDelivery(delivery, entities.WebhookDeliveryStatuses.Pending).Return(true, nil)
+
+	sut := services.NewWebhookService(webhookRepoMock, senderMock)
+	err := sut.DeliverDue()
+
+	assert.Nil(t, err)
+	assert.Equal(t, entities.WebhookDeliveryStatuses.Delivered, delivery.Status)
+	assert.NotNil(t, delivery.DeliveredAt)
+}
+
+func TestWebhook_RedeliverDeadLetter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepoMock = repoMock.NewMockWebhookRepo(ctrl)
+
+	delivery := givenStoredDelivery(1, entities.WebhookDeliveryStatuses.Dead)
+	webhookRepoMock.EXPECT().UpdateDelivery(delivery, entities.WebhookDeliveryStatuses.Dead).Return(true, nil)
+
+	sut := services.NewWebhookService(webhookRepoMock, nil)
+	res, err := sut.Redeliver(1, delivery.UUID.String())
+
+	assert.Nil(t, err)
+	assert.Equal(t, "PENDING", res.Status)
+	assert.Equal(t, 0, delivery.Attempts)
+}
+
+func TestWebhook_RedeliverAnotherUsersDeliveryIsForbidden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepoMock = repoMock.NewMockWebhookRepo(ctrl)
+
+	delivery := givenStoredDelivery(2, entities.WebhookDeliveryStatuses.Dead)
+
+	sut := services.NewWebhookService(webhookRepoMock, nil)
+	_, err := sut.Redeliver(1, delivery.UUID.String())
+
+	assert.Equal(t, "webhook_delivery_forbidden", apperrors.CodeOf(err))
+}
+
+func TestWebhook_RedeliverPendingDeliveryIsConflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	webhookRepoMock = repoMock.NewMockWebhookRepo(ctrl)
+
+	delivery := givenStoredDelivery(1, entities.WebhookDeliveryStatuses.Pending)
+
+	sut := services.NewWebhookService(webhookRepoMock, nil)
+	_, err := sut.Redeliver(1, delivery.UUID.String())
+
+	assert.Equal(t, "delivery_not_dead_lettered", apperrors.CodeOf(err))
+}
+
+func givenDueDelivery(attempts int) *entities.WebhookDelivery {
+	delivery := entities.WebhookDelivery{
+		UUID:          uuid.New(),
+		EndpointID:    10,
+		Endpoint:      &entities.WebhookEndpoint{Model: gorm.Model{ID: 10}, UserID: 1, URL: "https://merchant.example.com/hooks", Secret: "whsec_test"},
+		EventID:       uuid.New(),
+		EventType:     string(entities.EventTypes.TransactionCompleted),
+		Payload:       `{"type":"transaction.completed"}`,
+		Status:        entities.WebhookDeliveryStatuses.Pending,
+		Attempts:      attempts,
+		NextAttemptAt: time.Now(),
+	}
+	deliveries := []entities.WebhookDelivery{delivery}
+	webhookRepoMock.EXPECT().GetDueDeliveries(gomock.Any(), gomock.Any()).Return(deliveries, nil)
+	return &deliveries[0]
+}
+
+func givenStoredDelivery(ownerID uint, status entities.WebhookDeliveryStatus) *entities.WebhookDelivery {
+	delivery := &entities.WebhookDelivery{
+		UUID:       uuid.New(),
+		EndpointID: 10,
+		Endpoint:   &entities.WebhookEndpoint{Model: gorm.Model{ID: 10}, UserID: ownerID},
+		EventID:    uuid.New(),
+		EventType:  string(entities.EventTypes.WalletBalanceChanged),
+		Status:     status,
+		Attempts:   webhooks.MAX_ATTEMPTS,
+	}
+	webhookRepoMock.EXPECT().GetDelivery(delivery.UUID).Return(delivery, nil)
+	return delivery
+}
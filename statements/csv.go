@@ -0,0 +1,66 @@
+package statements
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+const dateFormat = "2006-01-02"
+
+// csvWriter writes the lines as one table between the opening balance and
+// the summary, separated by empty rows.
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (cw *csvWriter) Begin(h *Header) error {
+	cw.w.WriteAll([][]string{
+		{"Statement", h.Holder},
+		{"Account", h.AccountID},
+		{"Currency", h.Currency},
+		{"From", h.From.UTC().Format(dateFormat)},
+		{"To", h.To.UTC().AddDate(0, 0, -1).Format(dateFormat)},
+		{"Opening balance", h.Opening.String()},
+		{},
+		{"Date", "Transaction", "Type", "Description", "Amount", "Balance"},
+	})
+	return cw.w.Error()
+}
+
+func (cw *csvWriter) Line(l *Line) error {
+	var transactionID string
+	if l.TransactionID != nil {
+		transactionID = l.TransactionID.String()
+	}
+
+	// The csv.Writer flushes by itself whenever its buffer fills up
+	return cw.w.Write([]string{
+		l.Date.UTC().Format("2006-01-02T15:04:05Z"),
+		transactionID,
+		l.Type,
+		l.Description,
+		l.Amount.String(),
+		l.Balance.String(),
+	})
+}
+
+func (cw *csvWriter) End(s *Summary) error {
+	rows := [][]string{
+		{},
+		{"Total credits", s.Credits.String()},
+		{"Total debits", s.Debits.String()},
+		{"Closing balance", s.Closing.String()},
+		{},
+		{"Type", "Count", "Amount"},
+	}
+	for _, total := range s.Totals {
+		rows = append(rows, []string{total.Type, strconv.Itoa(total.Count), total.Amount.String()})
+	}
+
+	return cw.w.WriteAll(rows)
+}
@@ -0,0 +1,130 @@
+package statements
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	ofxDateFormat = "20060102150405"
+
+	// ofxBankID identifies us as the bank of the account in OFX files
+	ofxBankID = "BANKSYS"
+)
+
+// ofxHeader is the header of OFX 1.0.2, the version personal finance tools
+// import most widely.
+const ofxHeader = "OFXHEADER:100\r\n" +
+	"DATA:OFXSGML\r\n" +
+	"VERSION:102\r\n" +
+	"SECURITY:NONE\r\n" +
+	"ENCODING:USASCII\r\n" +
+	"CHARSET:1252\r\n" +
+	"COMPRESSION:NONE\r\n" +
+	"OLDFILEUID:NONE\r\n" +
+	"NEWFILEUID:NONE\r\n" +
+	"\r\n"
+
+var ofxEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// ofxWriter writes a bank statement response. Elements are closed
+// explicitly, which both SGML and XML based importers accept.
+type ofxWriter struct {
+	w *bufio.Writer
+}
+
+func newOFXWriter(w io.Writer) *ofxWriter {
+	return &ofxWriter{w: bufio.NewWriter(w)}
+}
+
+func (ow *ofxWriter) Begin(h *Header) error {
+	ow.w.WriteString(ofxHeader)
+	ow.w.WriteString("<OFX>\r\n")
+	ow.w.WriteString("<SIGNONMSGSRSV1><SONRS>\r\n")
+	ow.writeStatus()
+	ow.element("DTSERVER", ofxDate(h.GeneratedAt))
+	ow.element("LANGUAGE", "ENG")
+	ow.w.WriteString("</SONRS></SIGNONMSGSRSV1>\r\n")
+
+	ow.w.WriteString("<BANKMSGSRSV1><STMTTRNRS>\r\n")
+	ow.element("TRNUID", "0")
+	ow.writeStatus()
+	ow.w.WriteString("<STMTRS>\r\n")
+	ow.element("CURDEF", h.Currency)
+	ow.w.WriteString("<BANKACCTFROM>\r\n")
+	ow.element("BANKID", ofxBankID)
+	ow.element("ACCTID", h.AccountID)
+	ow.element("ACCTTYPE", "CHECKING")
+	ow.w.WriteString("</BANKACCTFROM>\r\n")
+
+	ow.w.WriteString("<BANKTRANLIST>\r\n")
+	ow.element("DTSTART", ofxDate(h.From))
+	return ow.element("DTEND", ofxDate(h.To))
+}
+
+func (ow *ofxWriter) Line(l *Line) error {
+	ow.w.WriteString("<STMTTRN>\r\n")
+	ow.element("TRNTYPE", ofxTransactionType(l))
+	ow.element("DTPOSTED", ofxDate(l.Date))
+	ow.element("TRNAMT", l.Amount.String())
+	ow.element("FITID", l.ID.String())
+	ow.element("NAME", truncate(l.Type, 32))
+	ow.element("MEMO", truncate(l.Description, 255))
+	_, err := ow.w.WriteString("</STMTTRN>\r\n")
+	return err
+}
+
+func (ow *ofxWriter) End(s *Summary) error {
+	ow.w.WriteString("</BANKTRANLIST>\r\n")
+	ow.w.WriteString("<LEDGERBAL>\r\n")
+	ow.element("BALAMT", s.Closing.String())
+	ow.element("DTASOF", ofxDate(time.Now()))
+	ow.w.WriteString("</LEDGERBAL>\r\n")
+	ow.w.WriteString("</STMTRS></STMTTRNRS></BANKMSGSRSV1>\r\n")
+	ow.w.WriteString("</OFX>\r\n")
+	return ow.w.Flush()
+}
+
+func (ow *ofxWriter) writeStatus() {
+	ow.w.WriteString("<STATUS>\r\n")
+	ow.element("CODE", "0")
+	ow.element("SEVERITY", "INFO")
+	ow.w.WriteString("</STATUS>\r\n")
+}
+
+// element writes one element. Write errors are kept by the bufio.Writer and
+// returned again by every later write.
+func (ow *ofxWriter) element(name string, value string) error {
+	_, err := ow.w.WriteString("<" + name + ">" + ofxEscaper.Replace(value) + "</" + name + ">\r\n")
+	return err
+}
+
+func ofxDate(t time.Time) string {
+	return t.UTC().Format(ofxDateFormat) + "[0:GMT]"
+}
+
+func ofxTransactionType(l *Line) string {
+	switch l.Type {
+	case "FEE":
+		return "FEE"
+	case "TRANSFER_IN", "TRANSFER_OUT":
+		return "XFER"
+	case "DEPOSIT":
+		return "DEP"
+	}
+
+	if l.Amount.IsNegative() {
+		return "DEBIT"
+	}
+	return "CREDIT"
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
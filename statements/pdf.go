@@ -0,0 +1,267 @@
+package statements
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 in points, the unit of PDF page coordinates.
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 40
+	fontSize   = 9
+	lineHeight = 13
+)
+
+// Objects with a fixed number; pages and their content follow them.
+const (
+	catalogObject = iota + 1
+	pagesObject
+	regularFontObject
+	boldFontObject
+)
+
+// Column positions of the statement table. Amounts are right-aligned.
+const (
+	dateColumn        = margin
+	typeColumn        = 110
+	descriptionColumn = 215
+	amountColumn      = 470
+	balanceColumn     = pageWidth - margin
+)
+
+// pdfWriter writes a PDF with the standard Helvetica fonts, which every
+// reader provides, so no fonts have to be embedded. Each page is written out
+// as soon as it is full; only the object offsets and page numbers are kept
+// for the cross-reference table at the end.
+type pdfWriter struct {
+	w       *bufio.Writer
+	offset  int64
+	err     error
+	offsets []int64 // Byte offset of each object, by object number
+	pages   []int   // Object numbers of the pages written so far
+
+	content *bytes.Buffer // Content of the current page
+	y       float64       // Baseline of the next line on the current page
+}
+
+func newPDFWriter(w io.Writer) *pdfWriter {
+	return &pdfWriter{
+		w:       bufio.NewWriter(w),
+		offsets: make([]int64, boldFontObject+1),
+	}
+}
+
+func (pw *pdfWriter) Begin(h *Header) error {
+	pw.write("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	pw.object(regularFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	pw.object(boldFontObject, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	pw.startPage()
+	pw.text(margin, pw.y, "F2", 16, "Account statement")
+	pw.y -= 2 * lineHeight
+
+	for _, row := range [][2]string{
+		{"Account holder", h.Holder},
+		{"Account", h.AccountID},
+		{"Currency", h.Currency},
+		{"Period", h.From.UTC().Format(dateFormat) + " to " + h.To.UTC().AddDate(0, 0, -1).Format(dateFormat)},
+		{"Generated", h.GeneratedAt.UTC().Format("2006-01-02 15:04 UTC")},
+	} {
+		pw.text(margin, pw.y, "F2", fontSize, row[0])
+		pw.text(typeColumn, pw.y, "F1", fontSize, row[1])
+		pw.y -= lineHeight
+	}
+
+	pw.y -= lineHeight
+	pw.summaryRow("Opening balance", h.Opening.String())
+	pw.y -= lineHeight
+	pw.tableHeader()
+
+	return pw.err
+}
+
+func (pw *pdfWriter) Line(l *Line) error {
+	if pw.y < margin+2*lineHeight {
+		pw.endPage()
+		pw.startPage()
+		pw.tableHeader()
+	}
+
+	pw.text(dateColumn, pw.y, "F1", fontSize, l.Date.UTC().Format(dateFormat))
+	pw.text(typeColumn, pw.y, "F1", fontSize, l.Type)
+	pw.text(descriptionColumn, pw.y, "F1", fontSize, truncate(l.Description, 40))
+	pw.rightText(amountColumn, pw.y, "F1", l.Amount.String())
+	pw.rightText(balanceColumn, pw.y, "F1", l.Balance.String())
+	pw.y -= lineHeight
+
+	return pw.err
+}
+
+func (pw *pdfWriter) End(s *Summary) error {
+	rows := 4 + len(s.Totals) + 2
+	if pw.y-float64(rows*lineHeight) < margin+lineHeight {
+		pw.endPage()
+		pw.startPage()
+	}
+
+	pw.y -= lineHeight
+	pw.summaryRow("Total credits", s.Credits.String())
+	pw.summaryRow("Total debits", s.Debits.String())
+	pw.summaryRow("Closing balance", s.Closing.String())
+
+	pw.y -= lineHeight
+	pw.text(margin, pw.y, "F2", fontSize, "Type")
+	pw.rightText(amountColumn-80, pw.y, "F2", "Count")
+	pw.rightText(amountColumn, pw.y, "F2", "Amount")
+	pw.y -= lineHeight
+	for _, total := range s.Totals {
+		pw.text(margin, pw.y, "F1", fontSize, total.Type)
+		pw.rightText(amountColumn-80, pw.y, "F1", fmt.Sprint(total.Count))
+		pw.rightText(amountColumn, pw.y, "F1", total.Amount.String())
+		pw.y -= lineHeight
+	}
+	pw.endPage()
+
+	kids := make([]string, len(pw.pages))
+	for i, page := range pw.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+	pw.object(pagesObject, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pw.pages)))
+	pw.object(catalogObject, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObject))
+
+	xref := pw.offset
+	pw.write(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", len(pw.offsets)))
+	for _, offset := range pw.offsets[1:] {
+		pw.write(fmt.Sprintf("%010d 00000 n \n", offset))
+	}
+	pw.write(fmt.Sprintf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(pw.offsets), catalogObject, xref))
+
+	if pw.err != nil {
+		return pw.err
+	}
+	return pw.w.Flush()
+}
+
+func (pw *pdfWriter) startPage() {
+	pw.content = &bytes.Buffer{}
+	pw.y = pageHeight - margin - fontSize
+}
+
+// endPage writes the current page with its page number.
+func (pw *pdfWriter) endPage() {
+	pw.rightText(balanceColumn, margin/2, "F1", fmt.Sprintf("Page %d", len(pw.pages)+1))
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(pw.content.Bytes())
+	zw.Close()
+
+	contentObject := pw.newObject()
+	pw.stream(contentObject, compressed.Bytes())
+
+	pageObject := pw.newObject()
+	pw.object(pageObject, fmt.Sprintf(
+		"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+		pagesObject, pageWidth, pageHeight, regularFontObject, boldFontObject, contentObject))
+	pw.pages = append(pw.pages, pageObject)
+}
+
+func (pw *pdfWriter) tableHeader() {
+	pw.text(dateColumn, pw.y, "F2", fontSize, "Date")
+	pw.text(typeColumn, pw.y, "F2", fontSize, "Type")
+	pw.text(descriptionColumn, pw.y, "F2", fontSize, "Description")
+	pw.rightText(amountColumn, pw.y, "F2", "Amount")
+	pw.rightText(balanceColumn, pw.y, "F2", "Balance")
+	pw.y -= lineHeight
+}
+
+func (pw *pdfWriter) summaryRow(label string, amount string) {
+	pw.text(margin, pw.y, "F2", fontSize, label)
+	pw.rightText(amountColumn, pw.y, "F1", amount)
+	pw.y -= lineHeight
+}
+
+func (pw *pdfWriter) text(x float64, y float64, font string, size float64, s string) {
+	fmt.Fprintf(pw.content, "BT /%s %g Tf %g %g Td (%s) Tj ET\n", font, size, x, y, pdfString(s))
+}
+
+// rightText writes s ending at x. Only the widths of the characters that
+// amounts and page numbers are made of are known.
+func (pw *pdfWriter) rightText(x float64, y float64, font string, s string) {
+	pw.text(x-textWidth(s, fontSize), y, font, fontSize, s)
+}
+
+func (pw *pdfWriter) newObject() int {
+	pw.offsets = append(pw.offsets, 0)
+	return len(pw.offsets) - 1
+}
+
+func (pw *pdfWriter) object(number int, body string) {
+	pw.offsets[number] = pw.offset
+	pw.write(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", number, body))
+}
+
+func (pw *pdfWriter) stream(number int, data []byte) {
+	pw.offsets[number] = pw.offset
+	pw.write(fmt.Sprintf("%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", number, len(data)))
+	pw.write(string(data))
+	pw.write("\nendstream\nendobj\n")
+}
+
+func (pw *pdfWriter) write(s string) {
+	if pw.err != nil {
+		return
+	}
+
+	n, err := pw.w.WriteString(s)
+	pw.offset += int64(n)
+	pw.err = err
+}
+
+// helveticaWidths are the widths of Helvetica characters in thousandths of
+// the font size, for the characters amounts are made of. Helvetica digits all
+// have the same width.
+var helveticaWidths = map[rune]float64{
+	'.': 278,
+	',': 278,
+	'-': 333,
+	' ': 278,
+}
+
+func textWidth(s string, size float64) float64 {
+	var width float64
+	for _, r := range s {
+		w, ok := helveticaWidths[r]
+		if !ok {
+			w = 556
+		}
+		width += w
+	}
+	return width * size / 1000
+}
+
+// pdfString escapes s for a PDF string literal in WinAnsiEncoding. Characters
+// outside Latin-1 cannot be shown with the standard fonts and are replaced.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
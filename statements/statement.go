@@ -0,0 +1,176 @@
+// Package statements renders account statements. A statement is written line
+// by line as its entries are read, so that statements over long periods never
+// have to be held in memory.
+package statements
+
+import (
+	"banking-system/money"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Format string
+
+var Formats = &struct {
+	CSV Format
+	PDF Format
+	OFX Format
+}{
+	CSV: "csv",
+	PDF: "pdf",
+	OFX: "ofx",
+}
+
+// ContentType is the media type statements in the format are served as.
+func (f Format) ContentType() string {
+	switch f {
+	case Formats.CSV:
+		return "text/csv; charset=utf-8"
+	case Formats.PDF:
+		return "application/pdf"
+	case Formats.OFX:
+		return "application/x-ofx"
+	}
+	return "application/octet-stream"
+}
+
+// Header describes the account and period of a statement.
+type Header struct {
+	Holder      string
+	AccountID   string
+	Currency    string
+	From        time.Time // Inclusive
+	To          time.Time // Exclusive
+	Opening     money.Money
+	GeneratedAt time.Time
+}
+
+// Entry is one change of the account balance.
+type Entry struct {
+	ID            uuid.UUID // Unique per entry, also across statements
+	Date          time.Time
+	TransactionID *uuid.UUID
+	Type          string
+	Description   string
+	Amount        money.Money
+}
+
+// Line is an entry together with the balance after it.
+type Line struct {
+	Entry
+	Balance money.Money
+}
+
+// Total sums the entries of one type.
+type Total struct {
+	Type   string
+	Count  int
+	Amount money.Money
+}
+
+// Summary closes a statement.
+type Summary struct {
+	Credits money.Money
+	Debits  money.Money
+	Closing money.Money
+	Totals  []Total // Ordered by type
+}
+
+// Writer renders a statement in one format: the header first, then every
+// line in order, then the summary.
+type Writer interface {
+	Begin(h *Header) error
+	Line(l *Line) error
+	End(s *Summary) error
+}
+
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case Formats.CSV:
+		return newCSVWriter(w), nil
+	case Formats.PDF:
+		return newPDFWriter(w), nil
+	case Formats.OFX:
+		return newOFXWriter(w), nil
+	}
+	return nil, fmt.Errorf("unknown statement format '%s'", format)
+}
+
+// Statement keeps the running balance and the totals of a statement while
+// its entries are written.
+type Statement struct {
+	w       Writer
+	balance money.Money
+	summary Summary
+	totals  map[string]*Total
+}
+
+// New begins a statement with header h.
+func New(w Writer, h *Header) (*Statement, error) {
+	if err := w.Begin(h); err != nil {
+		return nil, err
+	}
+
+	return &Statement{
+		w:       w,
+		balance: h.Opening,
+		summary: Summary{
+			Credits: money.Zero(h.Currency),
+			Debits:  money.Zero(h.Currency),
+		},
+		totals: make(map[string]*Total),
+	}, nil
+}
+
+// Add writes the line of e. Entries must be added in the order they were
+// booked.
+func (s *Statement) Add(e Entry) error {
+	balance, err := s.balance.Add(e.Amount)
+	if err != nil {
+		return fmt.Errorf("failed to add entry %s: %w", e.ID, err)
+	}
+	s.balance = balance
+
+	if e.Amount.IsNegative() {
+		s.summary.Debits, err = s.summary.Debits.Add(e.Amount.Neg())
+	} else {
+		s.summary.Credits, err = s.summary.Credits.Add(e.Amount)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add entry %s: %w", e.ID, err)
+	}
+
+	total, ok := s.totals[e.Type]
+	if !ok {
+		total = &Total{Type: e.Type, Amount: money.Zero(e.Amount.Currency())}
+		s.totals[e.Type] = total
+	}
+	total.Count++
+	if total.Amount, err = total.Amount.Add(e.Amount); err != nil {
+		return fmt.Errorf("failed to add entry %s: %w", e.ID, err)
+	}
+
+	return s.w.Line(&Line{Entry: e, Balance: balance})
+}
+
+// Balance returns the balance after the entries added so far.
+func (s *Statement) Balance() money.Money {
+	return s.balance
+}
+
+// Close writes the closing balance and totals.
+func (s *Statement) Close() error {
+	s.summary.Closing = s.balance
+	for _, total := range s.totals {
+		s.summary.Totals = append(s.summary.Totals, *total)
+	}
+	sort.Slice(s.summary.Totals, func(i, j int) bool {
+		return s.summary.Totals[i].Type < s.summary.Totals[j].Type
+	})
+
+	return s.w.End(&s.summary)
+}
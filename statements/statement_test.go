@@ -0,0 +1,135 @@
+package statements_test
+
+import (
+	"banking-system/money"
+	"banking-system/statements"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func twd(s string) money.Money {
+	return money.MustParse(s, "TWD")
+}
+
+var header = &statements.Header{
+	Holder:      "alice",
+	AccountID:   "wallet:7",
+	Currency:    "TWD",
+	From:        time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+	To:          time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+	Opening:     twd("100.00"),
+	GeneratedAt: time.Date(2026, 10, 2, 8, 0, 0, 0, time.UTC),
+}
+
+func entry(txType string, amount string) statements.Entry {
+	txID := uuid.New()
+	return statements.Entry{
+		ID:            uuid.New(),
+		Date:          time.Date(2026, 9, 15, 12, 0, 0, 0, time.UTC),
+		TransactionID: &txID,
+		Type:          txType,
+		Description:   txType + " & more",
+		Amount:        twd(amount),
+	}
+}
+
+func render(t *testing.T, format statements.Format, entries ...statements.Entry) string {
+	var buf bytes.Buffer
+	w, err := statements.NewWriter(format, &buf)
+	assert.Nil(t, err)
+
+	statement, err := statements.New(w, header)
+	assert.Nil(t, err)
+	for _, e := range entries {
+		assert.Nil(t, statement.Add(e))
+	}
+	assert.Nil(t, statement.Close())
+
+	return buf.String()
+}
+
+func TestCSV_RunningBalanceAndTotals(t *testing.T) {
+	out := render(t, statements.Formats.CSV,
+		entry("DEPOSIT", "50.00"),
+		entry("TRANSFER_OUT", "-30.00"),
+		entry("FEE", "-5.00"),
+		entry("DEPOSIT", "10.00"),
+	)
+
+	r := csv.NewReader(strings.NewReader(out))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"Opening balance", "100.00"}, rows[5])
+	assert.Equal(t, []string{"Date", "Transaction", "Type", "Description", "Amount", "Balance"}, rows[6])
+
+	var balances []string
+	for _, row := range rows[7:11] {
+		balances = append(balances, row[5])
+	}
+	assert.Equal(t, []string{"150.00", "120.00", "115.00", "125.00"}, balances)
+
+	assert.Equal(t, []string{"Total credits", "60.00"}, rows[11])
+	assert.Equal(t, []string{"Total debits", "35.00"}, rows[12])
+	assert.Equal(t, []string{"Closing balance", "125.00"}, rows[13])
+	assert.Equal(t, [][]string{
+		{"DEPOSIT", "2", "60.00"},
+		{"FEE", "1", "-5.00"},
+		{"TRANSFER_OUT", "1", "-30.00"},
+	}, rows[15:])
+}
+
+func TestOFX_ImportableStatement(t *testing.T) {
+	deposit := entry("DEPOSIT", "50.00")
+	out := render(t, statements.Formats.OFX, deposit, entry("WITHDRAWAL", "-20.00"))
+
+	assert.True(t, strings.HasPrefix(out, "OFXHEADER:100\r\nDATA:OFXSGML\r\nVERSION:102\r\n"))
+	assert.Contains(t, out, "<CURDEF>TWD</CURDEF>")
+	assert.Contains(t, out, "<DTSTART>20260901000000[0:GMT]</DTSTART>")
+	assert.Equal(t, 2, strings.Count(out, "<STMTTRN>"))
+	assert.Contains(t, out, "<FITID>"+deposit.ID.String()+"</FITID>")
+	assert.Contains(t, out, "<TRNTYPE>DEBIT</TRNTYPE>\r\n<DTPOSTED>20260915120000[0:GMT]</DTPOSTED>\r\n<TRNAMT>-20.00</TRNAMT>")
+	assert.Contains(t, out, "<MEMO>DEPOSIT &amp; more</MEMO>")
+	assert.Contains(t, out, "<LEDGERBAL>\r\n<BALAMT>130.00</BALAMT>")
+	assert.True(t, strings.HasSuffix(out, "</OFX>\r\n"))
+}
+
+func TestPDF_CrossReferencesEveryObject(t *testing.T) {
+	var entries []statements.Entry
+	for i := 0; i < 150; i++ {
+		entries = append(entries, entry("DEPOSIT", "1.00"))
+	}
+	out := render(t, statements.Formats.PDF, entries...)
+
+	assert.True(t, strings.HasPrefix(out, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(out, "%%EOF\n"))
+
+	pages := regexp.MustCompile(`/Type /Page /Parent`).FindAllString(out, -1)
+	assert.Greater(t, len(pages), 2, "150 lines do not fit on two pages")
+	assert.Contains(t, out, fmt.Sprintf("/Count %d", len(pages)))
+
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(out)
+	xref, _ := strconv.Atoi(startxref[1])
+	assert.True(t, strings.HasPrefix(out[xref:], "xref\n"))
+
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(out[xref:], -1)
+	for i, match := range offsets {
+		offset, _ := strconv.Atoi(match[1])
+		assert.True(t, strings.HasPrefix(out[offset:], fmt.Sprintf("%d 0 obj\n", i+1)), "object %d", i+1)
+	}
+}
+
+func TestNewWriter_UnknownFormat(t *testing.T) {
+	_, err := statements.NewWriter("xlsx", &bytes.Buffer{})
+	assert.NotNil(t, err)
+}
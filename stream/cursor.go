@@ -0,0 +1,74 @@
+// Package stream pushes the outbox events of users to the event streams they
+// have open on this replica.
+package stream
+
+import (
+	"banking-system/outbox"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidCursor is returned when parsing a cursor this package did not
+// write.
+var ErrInvalidCursor = errors.New("invalid stream cursor")
+
+// Cursor is the position of a stream: the sequence of the last event sent for
+// each wallet. The events of different wallets are not published in the order
+// of their sequences, so a single sequence could skip events that were still
+// held back when the stream stopped.
+type Cursor map[uint]uint
+
+// ParseCursor reads a cursor written by String, such as "3:41,4:40". An empty
+// string is the start of a stream.
+func ParseCursor(s string) (Cursor, error) {
+	cursor := make(Cursor)
+	if s == "" {
+		return cursor, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		wallet, sequence, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: '%s'", ErrInvalidCursor, s)
+		}
+
+		walletID, err := strconv.ParseUint(wallet, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: '%s'", ErrInvalidCursor, s)
+		}
+		seq, err := strconv.ParseUint(sequence, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: '%s'", ErrInvalidCursor, s)
+		}
+		cursor[uint(walletID)] = uint(seq)
+	}
+
+	return cursor, nil
+}
+
+func (c Cursor) String() string {
+	walletIDs := make([]uint, 0, len(c))
+	for walletID := range c {
+		walletIDs = append(walletIDs, walletID)
+	}
+	slices.Sort(walletIDs)
+
+	parts := make([]string, len(walletIDs))
+	for i, walletID := range walletIDs {
+		parts[i] = fmt.Sprintf("%d:%d", walletID, c[walletID])
+	}
+	return strings.Join(parts, ",")
+}
+
+// Advance moves the cursor past msg. It returns false if msg was already
+// sent, since the relay publishes events at least once.
+func (c Cursor) Advance(msg *outbox.Message) bool {
+	if msg.Sequence <= c[msg.WalletID] {
+		return false
+	}
+	c[msg.WalletID] = msg.Sequence
+	return true
+}
@@ -0,0 +1,42 @@
+package stream_test
+
+import (
+	"banking-system/outbox"
+	"banking-system/stream"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor_RoundTrips(t *testing.T) {
+	cursor := stream.Cursor{4: 40, 3: 41}
+
+	parsed, err := stream.ParseCursor(cursor.String())
+
+	assert.Nil(t, err)
+	assert.Equal(t, "3:41,4:40", cursor.String())
+	assert.Equal(t, cursor, parsed)
+}
+
+func TestCursor_EmptyIsStart(t *testing.T) {
+	cursor, err := stream.ParseCursor("")
+
+	assert.Nil(t, err)
+	assert.Empty(t, cursor)
+}
+
+func TestCursor_RejectsForeignIDs(t *testing.T) {
+	for _, id := range []string{"41", "3:x", "3:41,", "a:1"} {
+		_, err := stream.ParseCursor(id)
+		assert.ErrorIs(t, err, stream.ErrInvalidCursor, id)
+	}
+}
+
+func TestCursor_AdvancesPerWallet(t *testing.T) {
+	cursor := stream.Cursor{3: 41}
+
+	assert.True(t, cursor.Advance(&outbox.Message{WalletID: 4, Sequence: 40}))
+	assert.False(t, cursor.Advance(&outbox.Message{WalletID: 3, Sequence: 41}))
+	assert.True(t, cursor.Advance(&outbox.Message{WalletID: 3, Sequence: 45}))
+	assert.Equal(t, "3:45,4:40", cursor.String())
+}
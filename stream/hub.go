@@ -0,0 +1,93 @@
+package stream
+
+import (
+	"banking-system/outbox"
+	"sync"
+)
+
+// subscriptionBuffer is how many messages a stream can fall behind before it
+// is closed. The client then reconnects and catches up from its cursor.
+const subscriptionBuffer = 64
+
+// Subscription receives the messages of one user. C is closed when the
+// subscription fell behind or the hub was reset.
+type Subscription struct {
+	C <-chan *outbox.Message
+
+	c      chan *outbox.Message
+	hub    *Hub
+	userID uint
+}
+
+// Hub fans the messages of each user out to their subscriptions.
+type Hub struct {
+	mu            sync.Mutex
+	subscriptions map[uint]map[*Subscription]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscriptions: make(map[uint]map[*Subscription]struct{}),
+	}
+}
+
+func (h *Hub) Subscribe(userID uint) *Subscription {
+	c := make(chan *outbox.Message, subscriptionBuffer)
+	sub := &Subscription{C: c, c: c, hub: h, userID: userID}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscriptions[userID] == nil {
+		h.subscriptions[userID] = make(map[*Subscription]struct{})
+	}
+	h.subscriptions[userID][sub] = struct{}{}
+	return sub
+}
+
+// Publish hands msg to every subscription of its user without waiting on
+// them. A subscription whose buffer is full is closed.
+func (h *Hub) Publish(msg *outbox.Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscriptions[msg.UserID] {
+		select {
+		case sub.c <- msg:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// Reset closes every subscription, for when messages may have been missed.
+func (h *Hub) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, subs := range h.subscriptions {
+		for sub := range subs {
+			h.remove(sub)
+		}
+	}
+}
+
+// Close stops the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// remove must be called with h.mu held.
+func (h *Hub) remove(sub *Subscription) {
+	subs := h.subscriptions[sub.userID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscriptions, sub.userID)
+	}
+	close(sub.c)
+}
@@ -0,0 +1,50 @@
+package stream_test
+
+import (
+	"banking-system/outbox"
+	"banking-system/stream"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub_PublishesToSubscriptionsOfUser(t *testing.T) {
+	hub := stream.NewHub()
+	mine := hub.Subscribe(1)
+	theirs := hub.Subscribe(2)
+	defer mine.Close()
+	defer theirs.Close()
+
+	msg := &outbox.Message{UserID: 1, Sequence: 5}
+	hub.Publish(msg)
+
+	assert.Equal(t, msg, <-mine.C)
+	assert.Empty(t, theirs.C)
+}
+
+func TestHub_ClosesSubscriptionThatFallsBehind(t *testing.T) {
+	hub := stream.NewHub()
+	sub := hub.Subscribe(1)
+
+	for i := 0; i < 100; i++ {
+		hub.Publish(&outbox.Message{UserID: 1, Sequence: uint(i)})
+	}
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	assert.Less(t, received, 100)
+	sub.Close()
+}
+
+func TestHub_ResetClosesSubscriptions(t *testing.T) {
+	hub := stream.NewHub()
+	sub := hub.Subscribe(1)
+
+	hub.Reset()
+
+	_, open := <-sub.C
+	assert.False(t, open)
+	sub.Close()
+}
@@ -0,0 +1,88 @@
+package stream
+
+import (
+	"banking-system/outbox"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const maxListenBackoff = 30 * time.Second
+
+// StartListener receives the messages the relay sends on the channel
+// OUTBOX_NOTIFY_CHANNEL, so that every replica sees the events of all users,
+// and hands them to handle. Notifications sent while the connection is down
+// are lost, so reset is called once it listens again. Without the channel no
+// events reach the streams.
+func StartListener(db *gorm.DB, handle func(msg *outbox.Message), reset func()) {
+	channel := os.Getenv("OUTBOX_NOTIFY_CHANNEL")
+	if channel == "" {
+		log.Warn("OUTBOX_NOTIFY_CHANNEL is not set; event streams will not receive events")
+		return
+	}
+
+	go func() {
+		backoff := time.Second
+		for attempt := 0; ; attempt++ {
+			err := listen(db, channel, handle, func() {
+				backoff = time.Second
+				if attempt > 0 {
+					reset()
+				}
+			})
+			log.Errorf("Stopped listening on '%s', retrying in %s: %v", channel, backoff, err)
+			time.Sleep(backoff)
+			backoff = min(2*backoff, maxListenBackoff)
+		}
+	}()
+}
+
+// listen holds a connection of the pool for as long as it listens, and only
+// returns when the connection fails.
+func listen(db *gorm.DB, channel string, handle func(msg *outbox.Message), listening func()) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		stdlibConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("database connection is not a pgx connection")
+		}
+		pgConn := stdlibConn.Conn()
+
+		if _, err := pgConn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+		listening()
+
+		for {
+			notification, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+
+			var msg outbox.Message
+			if err := json.Unmarshal([]byte(notification.Payload), &msg); err != nil {
+				log.Warnf("Dropping notification on '%s' that is not an outbox message: %v", channel, err)
+				continue
+			}
+			handle(&msg)
+		}
+	})
+}
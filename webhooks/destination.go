@@ -0,0 +1,130 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// lookupTimeout bounds the DNS lookup of a webhook host at registration.
+const lookupTimeout = 2 * time.Second
+
+// ErrForbiddenDestination is returned for a webhook destination that is not a
+// public https address, such as loopback, private or link-local addresses.
+var ErrForbiddenDestination = errors.New("webhook destination not allowed")
+
+// deniedPrefixes are the address ranges deliveries may never be sent to:
+// special-purpose, private, shared, loopback, link-local, documentation,
+// multicast and reserved ranges, and IPv6 ranges that embed IPv4 addresses.
+var deniedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("10.0.0.0/8"),      // private
+	netip.MustParsePrefix("100.64.0.0/10"),   // shared address space (CGNAT)
+	netip.MustParsePrefix("127.0.0.0/8"),     // loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // link-local
+	netip.MustParsePrefix("172.16.0.0/12"),   // private
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("192.88.99.0/24"),  // 6to4 relay anycast
+	netip.MustParsePrefix("192.168.0.0/16"),  // private
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("224.0.0.0/4"),     // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved and broadcast
+	netip.MustParsePrefix("::/96"),           // unspecified, loopback and IPv4-compatible
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use NAT64
+	netip.MustParsePrefix("100::/64"),        // discard-only
+	netip.MustParsePrefix("2001::/23"),       // IETF protocol assignments, including Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4
+	netip.MustParsePrefix("fc00::/7"),        // unique local
+	netip.MustParsePrefix("fe80::/10"),       // link-local
+	netip.MustParsePrefix("ff00::/8"),        // multicast
+}
+
+// IsPublicAddr reports whether addr is outside every denied range, so that
+// deliveries may be sent to it. IPv4-mapped IPv6 addresses are checked as the
+// IPv4 address they map.
+func IsPublicAddr(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+
+	addr = addr.Unmap()
+	for _, prefix := range deniedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// AnyAddr allows every address. It is for destinations configured by
+// operators rather than by users.
+func AnyAddr(netip.Addr) bool {
+	return true
+}
+
+// CheckURL validates the URL of a webhook endpoint. It must be an absolute
+// https URL whose host is, or resolves to, public addresses only. A host that
+// does not resolve yet is accepted; every delivery checks the address it
+// connects to again.
+func CheckURL(rawURL string) error {
+	endpointURL, err := url.Parse(rawURL)
+	if err != nil || endpointURL.Scheme != "https" || endpointURL.Hostname() == "" {
+		return fmt.Errorf("%w: URL must be an absolute https URL", ErrForbiddenDestination)
+	}
+
+	host := endpointURL.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return checkAddr(addr)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil
+	}
+
+	for _, addr := range addrs {
+		if err := checkAddr(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkAddr(addr netip.Addr) error {
+	if !IsPublicAddr(addr) {
+		return fmt.Errorf("%w: %s is not a public address", ErrForbiddenDestination, addr)
+	}
+	return nil
+}
+
+// guardedDialer returns a dialer that refuses to connect to addresses not
+// allowed by allow. The address is checked after DNS resolution, so a host
+// that is re-pointed at an internal address after registration is refused.
+func guardedDialer(allow func(netip.Addr) bool) *net.Dialer {
+	return &net.Dialer{
+		Timeout: sendTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrForbiddenDestination, err)
+			}
+
+			if !allow(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s is not a public address", ErrForbiddenDestination, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+}
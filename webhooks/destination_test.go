@@ -0,0 +1,87 @@
+package webhooks_test
+
+import (
+	"banking-system/webhooks"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		name   string
+		addr   string
+		public bool
+	}{
+		{"this network", "0.1.2.3", false},
+		{"unspecified", "0.0.0.0", false},
+		{"private 10/8", "10.1.2.3", false},
+		{"shared address space", "100.64.0.1", false},
+		{"shared address space upper end", "100.127.255.254", false},
+		{"loopback", "127.0.0.1", false},
+		{"loopback range", "127.8.9.10", false},
+		{"link-local", "169.254.169.254", false},
+		{"private 172.16/12", "172.31.255.1", false},
+		{"IETF protocol assignments", "192.0.0.170", false},
+		{"documentation 192.0.2/24", "192.0.2.1", false},
+		{"6to4 relay anycast", "192.88.99.1", false},
+		{"private 192.168/16", "192.168.0.1", false},
+		{"benchmarking", "198.19.0.1", false},
+		{"documentation 198.51.100/24", "198.51.100.7", false},
+		{"documentation 203.0.113/24", "203.0.113.10", false},
+		{"multicast", "224.0.0.1", false},
+		{"reserved", "240.0.0.1", false},
+		{"broadcast", "255.255.255.255", false},
+		{"IPv6 unspecified", "::", false},
+		{"IPv6 loopback", "::1", false},
+		{"IPv4-compatible", "::127.0.0.1", false},
+		{"IPv4-mapped loopback", "::ffff:127.0.0.1", false},
+		{"IPv4-mapped private", "::ffff:10.0.0.1", false},
+		{"IPv4-mapped shared address space", "::ffff:100.64.0.1", false},
+		{"NAT64", "64:ff9b::a9fe:a9fe", false},
+		{"local-use NAT64", "64:ff9b:1::1", false},
+		{"discard-only", "100::1", false},
+		{"Teredo", "2001::1", false},
+		{"IPv6 documentation", "2001:db8::1", false},
+		{"6to4", "2002:7f00:1::1", false},
+		{"unique local", "fd00::1", false},
+		{"IPv6 link-local", "fe80::1", false},
+		{"IPv6 multicast", "ff02::1", false},
+		{"public IPv4", "8.8.8.8", true},
+		{"public IPv4 next to shared address space", "100.128.0.1", true},
+		{"public IPv4 next to benchmarking", "198.20.0.1", true},
+		{"IPv4-mapped public", "::ffff:8.8.8.8", true},
+		{"public IPv6", "2001:4860:4860::8888", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.public, webhooks.IsPublicAddr(netip.MustParseAddr(tt.addr)), tt.addr)
+		})
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://8.8.8.8/hooks", true},
+		{"http://8.8.8.8/hooks", false},
+		{"https://100.64.0.1/hooks", false},
+		{"https://198.18.0.1/hooks", false},
+		{"https://[64:ff9b::7f00:1]/hooks", false},
+		{"https://[::ffff:169.254.169.254]/hooks", false},
+		{"https://localhost/hooks", false},
+	}
+
+	for _, tt := range tests {
+		err := webhooks.CheckURL(tt.url)
+		if tt.allowed {
+			assert.Nil(t, err, tt.url)
+		} else {
+			assert.ErrorIs(t, err, webhooks.ErrForbiddenDestination, tt.url)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+// Package webhooks describes the account events delivered to the endpoints
+// users register, and signs and sends their deliveries.
+package webhooks
+
+import (
+	"banking-system/entities"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event is something that happened to a user's account. Its ID stays the same
+// across retries and redeliveries, so receivers can use it to drop duplicates.
+type Event struct {
+	ID        uuid.UUID          `json:"id"`
+	Type      entities.EventType `json:"type"`
+	CreatedAt time.Time          `json:"created_at"`
+	Data      any                `json:"data"`
+
+	// UserID is the user whose account the event is about; only their
+	// endpoints receive it
+	UserID uint `json:"-"`
+}
+
+// Body is the JSON the event is delivered as.
+func (e Event) Body() ([]byte, error) {
+	return json.Marshal(e)
+}
@@ -0,0 +1,23 @@
+package webhooks
+
+import "time"
+
+const (
+	// MAX_ATTEMPTS is the number of times a delivery is sent before it is
+	// dead-lettered. With the delays below the last attempt is made about
+	// 8.5 hours after the first.
+	MAX_ATTEMPTS = 10
+
+	firstRetryDelay = 30 * time.Second
+	maxRetryDelay   = 4 * time.Hour
+)
+
+// RetryDelay is how long to wait after the given failed attempt, counting from
+// 1, before sending again. It doubles with every attempt up to maxRetryDelay.
+func RetryDelay(attempt int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
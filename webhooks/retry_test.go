@@ -0,0 +1,17 @@
+package webhooks_test
+
+import (
+	"banking-system/webhooks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryDelay_DoublesUpToMaximum(t *testing.T) {
+	assert.Equal(t, 30*time.Second, webhooks.RetryDelay(1))
+	assert.Equal(t, time.Minute, webhooks.RetryDelay(2))
+	assert.Equal(t, 2*time.Minute, webhooks.RetryDelay(3))
+	assert.Equal(t, 4*time.Hour, webhooks.RetryDelay(webhooks.MAX_ATTEMPTS))
+	assert.Equal(t, 4*time.Hour, webhooks.RetryDelay(100))
+}
@@ -0,0 +1,94 @@
+package webhooks
+
+import (
+	"banking-system/entities"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+)
+
+//go:generate mockgen -source=sender.go -destination=mock/sender.go
+
+// sendTimeout bounds a single delivery so that a slow endpoint cannot hold up
+// the others.
+const sendTimeout = 10 * time.Second
+
+// ErrRejected is returned when an endpoint answers a delivery with a status
+// other than 2xx.
+var ErrRejected = errors.New("delivery rejected by endpoint")
+
+// Message is one delivery of an event to an endpoint.
+type Message struct {
+	URL       string
+	Secret    string
+	EventID   string
+	EventType entities.EventType
+	Body      []byte
+}
+
+// Sender posts deliveries to endpoints. It returns the HTTP status the
+// endpoint answered with, or 0 if it did not answer.
+type Sender interface {
+	Send(msg *Message) (statusCode int, err error)
+}
+
+type httpSender struct {
+	client *http.Client
+}
+
+// NewSender returns a Sender that only connects to public addresses.
+func NewSender() Sender {
+	return NewSenderAllowing(IsPublicAddr)
+}
+
+// NewSenderAllowing returns a Sender that only connects to addresses allowed
+// by allow.
+func NewSenderAllowing(allow func(netip.Addr) bool) Sender {
+	return &httpSender{
+		client: &http.Client{
+			Timeout: sendTimeout,
+			Transport: &http.Transport{
+				// No proxy, so that the dialer sees the endpoint's own address
+				Proxy:               nil,
+				DialContext:         guardedDialer(allow).DialContext,
+				TLSHandshakeTimeout: sendTimeout,
+				MaxIdleConnsPerHost: 2,
+			},
+			// A redirect would resend the signed body to another URL
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (s *httpSender) Send(msg *Message) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, msg.URL, bytes.NewReader(msg.Body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(ID_HEADER, msg.EventID)
+	req.Header.Set(EVENT_HEADER, string(msg.EventType))
+	req.Header.Set(TIMESTAMP_HEADER, timestamp)
+	req.Header.Set(SIGNATURE_HEADER, Sign(msg.Secret, timestamp, msg.EventID, msg.Body))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("%w with status %d", ErrRejected, res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
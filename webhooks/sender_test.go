@@ -0,0 +1,87 @@
+package webhooks_test
+
+import (
+	"banking-system/entities"
+	"banking-system/webhooks"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSend_SignsDelivery(t *testing.T) {
+	body := []byte(`{"type":"transaction.completed"}`)
+	var received *http.Request
+	var receivedBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	status, err := localSender().Send(&webhooks.Message{
+		URL:       server.URL,
+		Secret:    "secret",
+		EventID:   "event-1",
+		EventType: entities.EventTypes.TransactionCompleted,
+		Body:      body,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNoContent, status)
+	assert.Equal(t, body, receivedBody)
+	assert.Equal(t, "event-1", received.Header.Get(webhooks.ID_HEADER))
+	assert.Equal(t, "transaction.completed", received.Header.Get(webhooks.EVENT_HEADER))
+
+	timestamp := received.Header.Get(webhooks.TIMESTAMP_HEADER)
+	_, err = strconv.ParseInt(timestamp, 10, 64)
+	assert.Nil(t, err)
+	assert.True(t, webhooks.Verify("secret", timestamp, "event-1", body, received.Header.Get(webhooks.SIGNATURE_HEADER)))
+}
+
+func TestSend_RejectedByEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	status, err := localSender().Send(&webhooks.Message{URL: server.URL, Secret: "secret", EventID: "event-1", Body: []byte(`{}`)})
+
+	assert.ErrorIs(t, err, webhooks.ErrRejected)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+}
+
+func TestSend_DoesNotFollowRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://example.com/", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	status, err := localSender().Send(&webhooks.Message{URL: server.URL, Secret: "secret", EventID: "event-1", Body: []byte(`{}`)})
+
+	assert.ErrorIs(t, err, webhooks.ErrRejected)
+	assert.Equal(t, http.StatusTemporaryRedirect, status)
+}
+
+func TestSend_RefusesPrivateAddress(t *testing.T) {
+	delivered := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered = true
+	}))
+	defer server.Close()
+
+	status, err := webhooks.NewSender().Send(&webhooks.Message{URL: server.URL, Secret: "secret", EventID: "event-1", Body: []byte(`{}`)})
+
+	assert.ErrorIs(t, err, webhooks.ErrForbiddenDestination)
+	assert.Equal(t, 0, status)
+	assert.False(t, delivered)
+}
+
+// localSender delivers to the loopback test servers.
+func localSender() webhooks.Sender {
+	return webhooks.NewSenderAllowing(webhooks.AnyAddr)
+}
@@ -0,0 +1,47 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	ID_HEADER        = "X-Webhook-ID"
+	EVENT_HEADER     = "X-Webhook-Event"
+	TIMESTAMP_HEADER = "X-Webhook-Timestamp"
+	SIGNATURE_HEADER = "X-Webhook-Signature"
+
+	secretPrefix = "whsec_"
+)
+
+// NewSecret generates the secret an endpoint's deliveries are signed with.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// Sign computes the hex HMAC-SHA256 sent with a delivery. The timestamp and
+// event ID are signed together with the raw body, the same way providers sign
+// their callbacks, so that neither can be replaced on a replayed request.
+func Sign(secret string, timestamp string, eventID string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write([]byte(eventID))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of a delivery, comparing
+// in constant time. Receivers can use it to check deliveries.
+func Verify(secret string, timestamp string, eventID string, body []byte, signature string) bool {
+	expected := Sign(secret, timestamp, eventID, body)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
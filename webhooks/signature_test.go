@@ -0,0 +1,30 @@
+package webhooks_test
+
+import (
+	"banking-system/webhooks"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign_VerifiesOnlyTheSignedDelivery(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	signature := webhooks.Sign("secret", "1700000000", "event-1", body)
+
+	assert.True(t, webhooks.Verify("secret", "1700000000", "event-1", body, signature))
+	assert.False(t, webhooks.Verify("other", "1700000000", "event-1", body, signature))
+	assert.False(t, webhooks.Verify("secret", "1700000001", "event-1", body, signature))
+	assert.False(t, webhooks.Verify("secret", "1700000000", "event-2", body, signature))
+	assert.False(t, webhooks.Verify("secret", "1700000000", "event-1", []byte(`{"id":"2"}`), signature))
+}
+
+func TestNewSecret_IsRandom(t *testing.T) {
+	first, err := webhooks.NewSecret()
+	assert.Nil(t, err)
+	second, err := webhooks.NewSecret()
+	assert.Nil(t, err)
+
+	assert.NotEqual(t, first, second)
+	assert.True(t, strings.HasPrefix(first, "whsec_"))
+}